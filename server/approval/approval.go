@@ -0,0 +1,372 @@
+// Package approval is the state machine of the requests which have to be
+// approved by a portal admin or the approver group of their route (e.g. the
+// smtp relay). A request is created pending, approved or rejected by an
+// approver or expires after the SLA. Approved requests are applied by the kind
+// which registered them.
+package approval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	StatePending  = "pending"
+	StateApproved = "approved"
+	StateRejected = "rejected"
+	StateExpired  = "expired"
+	StateApplied  = "applied"
+
+	requestsCollection = "approval_requests"
+	defaultSLAHours    = 72
+)
+
+// transitions are the allowed changes of the state
+var transitions = map[string][]string{
+	StatePending:  {StateApproved, StateRejected, StateExpired},
+	StateApproved: {StateApplied},
+}
+
+// Request is a request of a user which has to be approved
+type Request struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	State       string          `json:"state"`
+	ClusterId   string          `json:"clusterid"`
+	Project     string          `json:"project"`
+	Reason      string          `json:"reason"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	RequestedBy string          `json:"requestedBy"`
+	RequestedAt time.Time       `json:"requestedAt"`
+	// ApproverGroup and Approvers are set by the routes. Without them the
+	// portal admins decide
+	ApproverGroup string     `json:"approverGroup,omitempty"`
+	Approvers     []string   `json:"approvers,omitempty"`
+	Deadline      *time.Time `json:"deadline,omitempty"`
+	// RemindedAt and EscalatedAt are set by the SLA timer
+	RemindedAt  *time.Time   `json:"remindedAt,omitempty"`
+	EscalatedAt *time.Time   `json:"escalatedAt,omitempty"`
+	DecidedBy   string       `json:"decidedBy,omitempty"`
+	DecidedAt   *time.Time   `json:"decidedAt,omitempty"`
+	Comment     string       `json:"comment,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	History     []Transition `json:"history"`
+	// Attachments are justification files, e.g. required by a change board
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Transition is an entry of the history of a request
+type Transition struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	By   string    `json:"by"`
+	At   time.Time `json:"at"`
+}
+
+// Kind is a type of request, e.g. smtp-relay
+type Kind struct {
+	Name string
+	// Apply executes an approved request. The request stays approved if it fails
+	Apply func(r Request) error
+	// Notify is called after every change of the state, e.g. to send mails
+	Notify func(r Request)
+	// SLA is the time until pending requests expire. Defaults to 'approval.sla_hours'
+	SLA time.Duration
+	// SeparateApprover enforces the two-person rule: the request must be
+	// approved by someone else than the requester
+	SeparateApprover bool
+	// AdminsOnly lets only the portal admins decide the requests, the
+	// approval routes don't apply
+	AdminsOnly bool
+}
+
+var kinds = make(map[string]Kind)
+
+// RegisterKind adds a type of request. It must be called at startup
+func RegisterKind(k Kind) {
+	if _, ok := kinds[k.Name]; ok {
+		panic(fmt.Sprintf("approval: kind %v registered twice", k.Name))
+	}
+	kinds[k.Name] = k
+}
+
+// transition changes the state if it's allowed and records it in the history
+func (r *Request) transition(to, by string, at time.Time) error {
+	for _, allowed := range transitions[r.State] {
+		if allowed == to {
+			r.History = append(r.History, Transition{From: r.State, To: to, By: by, At: at})
+			r.State = to
+			return nil
+		}
+	}
+	if r.State == StatePending {
+		return fmt.Errorf("Der Antrag kann nicht von %v nach %v wechseln", r.State, to)
+	}
+	return errors.New("Der Antrag wurde bereits bearbeitet")
+}
+
+func slaOf(k Kind) time.Duration {
+	if k.SLA > 0 {
+		return k.SLA
+	}
+	hours := config.Config().GetInt("approval.sla_hours")
+	if hours <= 0 {
+		hours = defaultSLAHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// Create saves a new pending request of the kind
+func Create(kind, clusterId, project, requester, reason string, payload interface{}) (*Request, error) {
+	k, ok := kinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown kind of request %v", kind)
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	deadline := now.Add(slaOf(k))
+	r := &Request{
+		ID:          id.String(),
+		Kind:        kind,
+		State:       StatePending,
+		ClusterId:   clusterId,
+		Project:     project,
+		Reason:      reason,
+		RequestedBy: requester,
+		RequestedAt: now,
+		Deadline:    &deadline,
+		History:     []Transition{},
+	}
+	if payload != nil {
+		if r.Payload, err = json.Marshal(payload); err != nil {
+			return nil, err
+		}
+	}
+	if !k.AdminsOnly {
+		r.ApproverGroup, r.Approvers = routeOf(*r)
+	}
+	if err := store.Put(requestsCollection, r.ID, r); err != nil {
+		return nil, err
+	}
+
+	log.Printf("%v created the %v request %v for project %v on cluster %v", requester, kind, r.ID, project, clusterId)
+	notify(k, *r)
+	notifyApprovers(*r)
+	return r, nil
+}
+
+// Get returns the request with the id
+func Get(id string) (*Request, bool, error) {
+	var r Request
+	found, err := store.Get(requestsCollection, id, &r)
+	return &r, found, err
+}
+
+// List returns the requests matching the filter, newest first
+func List(filter func(Request) bool) ([]Request, error) {
+	requests := []Request{}
+	err := store.List(requestsCollection, func(id string, data []byte) error {
+		var r Request
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		if filter(r) {
+			requests = append(requests, r)
+		}
+		return nil
+	})
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].RequestedAt.After(requests[j].RequestedAt)
+	})
+	return requests, err
+}
+
+// Approve approves the request and applies it. The request is applied only
+// by the call which approved it or, after a failed apply, by the call which
+// approved it again
+func Approve(id, username, comment string) (*Request, error) {
+	r, k, err := decide(id, StateApproved, username, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	var applyErr error
+	if k.Apply != nil {
+		applyErr = k.Apply(*r)
+	}
+	err = store.Update(requestsCollection, id, r, func(exists bool) error {
+		if !exists {
+			return errRequestNotFound
+		}
+		if applyErr != nil {
+			r.Error = applyErr.Error()
+			return nil
+		}
+		r.Error = ""
+		return r.transition(StateApplied, username, time.Now())
+	})
+	if applyErr != nil {
+		if err != nil {
+			log.Printf("Error saving request %v: %v", id, err)
+		}
+		return r, applyErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	notify(k, *r)
+	return r, nil
+}
+
+// Reject rejects the request
+func Reject(id, username, comment string) (*Request, error) {
+	r, _, err := decide(id, StateRejected, username, comment)
+	return r, err
+}
+
+var (
+	errRequestNotFound = errors.New("Der Antrag existiert nicht")
+	errRequestApplying = errors.New("Der Antrag wird bereits angewendet")
+)
+
+// decide changes the state of the request under the lock of the store, so
+// concurrent decisions and the SLA timer can't both succeed
+func decide(id, state, username, comment string) (*Request, Kind, error) {
+	var r Request
+	var k Kind
+	changed := false
+	err := store.Update(requestsCollection, id, &r, func(exists bool) error {
+		if !exists {
+			return errRequestNotFound
+		}
+		var ok bool
+		if k, ok = kinds[r.Kind]; !ok {
+			return fmt.Errorf("unknown kind of request %v", r.Kind)
+		}
+		if err := checkApprover(k, &r, state, username); err != nil {
+			return err
+		}
+
+		// An approved request which failed to apply can be approved again.
+		// Clearing the error claims the apply for this call
+		if state == StateApproved && r.State == StateApproved {
+			if r.Error == "" {
+				return errRequestApplying
+			}
+			r.Error = ""
+			return nil
+		}
+		now := time.Now()
+		if err := r.transition(state, username, now); err != nil {
+			return err
+		}
+		r.DecidedBy = username
+		r.DecidedAt = &now
+		r.Comment = comment
+		changed = true
+		return nil
+	})
+	if err != nil {
+		return nil, k, err
+	}
+	if changed {
+		notify(k, r)
+	}
+
+	log.Printf("%v %v the %v request %v of project %v on cluster %v", username, state, r.Kind, r.ID, r.Project, r.ClusterId)
+	return &r, k, nil
+}
+
+// checkApprover allows only the approvers of the request to decide and
+// enforces the two-person rule of the kind. A requester who is an approver
+// can still reject (withdraw) the request
+func checkApprover(k Kind, r *Request, state, username string) error {
+	if !CanDecide(*r, username) {
+		return errors.New("Du darfst diesen Antrag nicht bearbeiten")
+	}
+	if state == StateApproved && k.SeparateApprover && strings.ToLower(r.RequestedBy) == strings.ToLower(username) {
+		return errors.New("Der Antrag muss von einer zweiten Person bestätigt werden")
+	}
+	return nil
+}
+
+// StartSLATimer reminds the approvers of pending requests, escalates and
+// expires the requests after their deadline every hour
+func StartSLATimer() {
+	go func() {
+		for {
+			checkPendingRequests(time.Now())
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+func checkPendingRequests(at time.Time) {
+	requests, err := List(func(r Request) bool {
+		return r.State == StatePending
+	})
+	if err != nil {
+		log.Printf("Error reading approval requests: %v", err)
+		return
+	}
+
+	for _, r := range requests {
+		switch {
+		case r.Deadline != nil && r.Deadline.Before(at):
+			if !escalate(r, at) {
+				expire(r, at)
+			}
+		case r.RemindedAt == nil && remindAfter() > 0 && at.Sub(r.RequestedAt) >= remindAfter():
+			remind(r, at)
+		}
+	}
+}
+
+func expire(r Request, at time.Time) {
+	err := store.Update(requestsCollection, r.ID, &r, func(exists bool) error {
+		if !exists {
+			return errRequestNotFound
+		}
+		// The request may have been decided since it was listed
+		return r.transition(StateExpired, "system", at)
+	})
+	if err != nil {
+		if r.State == StatePending {
+			log.Printf("Error saving request %v: %v", r.ID, err)
+		}
+		return
+	}
+	log.Printf("The %v request %v of project %v expired", r.Kind, r.ID, r.Project)
+	notify(kinds[r.Kind], r)
+}
+
+// Import saves an existing request, e.g. when migrating from an older collection
+func Import(r Request) error {
+	if _, ok := kinds[r.Kind]; !ok {
+		return fmt.Errorf("unknown kind of request %v", r.Kind)
+	}
+	if r.History == nil {
+		r.History = []Transition{}
+	}
+	return store.Put(requestsCollection, r.ID, r)
+}
+
+func notify(k Kind, r Request) {
+	if k.Notify != nil {
+		k.Notify(r)
+	}
+	sendWebhooks(r)
+}
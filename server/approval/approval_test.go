@@ -0,0 +1,106 @@
+package approval
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store/storetest"
+)
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		from    string
+		to      string
+		allowed bool
+	}{
+		{StatePending, StateApproved, true},
+		{StatePending, StateRejected, true},
+		{StatePending, StateExpired, true},
+		{StatePending, StateApplied, false},
+		{StateApproved, StateApplied, true},
+		{StateApproved, StateRejected, false},
+		{StateRejected, StateApproved, false},
+		{StateExpired, StateApproved, false},
+		{StateApplied, StateExpired, false},
+	}
+	for _, test := range tests {
+		r := Request{State: test.from}
+		err := r.transition(test.to, "admin", time.Now())
+		if test.allowed && (err != nil || r.State != test.to || len(r.History) != 1) {
+			t.Errorf("expected %v -> %v to be allowed, got %v", test.from, test.to, err)
+		}
+		if !test.allowed && (err == nil || r.State != test.from) {
+			t.Errorf("expected %v -> %v to be rejected", test.from, test.to)
+		}
+	}
+}
+
+func TestSeparateApprover(t *testing.T) {
+	config.Init("test")
+	config.Config().Set("portal_admins", []string{"admin1", "admin2"})
+	RegisterKind(Kind{Name: "test-two-person", SeparateApprover: true})
+	r := &Request{Kind: "test-two-person", State: StatePending, RequestedBy: "Admin1"}
+	if err := checkApprover(kinds[r.Kind], r, StateApproved, "admin1"); err == nil {
+		t.Error("expected the requester not to be allowed to approve")
+	}
+	if err := checkApprover(kinds[r.Kind], r, StateRejected, "admin1"); err != nil {
+		t.Errorf("expected the requester to be allowed to withdraw, got %v", err)
+	}
+	if err := checkApprover(kinds[r.Kind], r, StateApproved, "admin2"); err != nil {
+		t.Errorf("expected a second admin to be allowed to approve, got %v", err)
+	}
+}
+
+func TestApproveAppliesOnce(t *testing.T) {
+	defer storetest.Setup(t)()
+	config.Config().Set("portal_admins", []string{"admin1", "admin2"})
+	var applied int32
+	failing := false
+	RegisterKind(Kind{Name: "test-apply-once", Apply: func(r Request) error {
+		atomic.AddInt32(&applied, 1)
+		if failing {
+			return errors.New("failed")
+		}
+		return nil
+	}})
+
+	r, err := Create("test-apply-once", "fake", "project", "u123", "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for _, admin := range []string{"admin1", "admin2"} {
+		wg.Add(1)
+		go func(admin string) {
+			defer wg.Done()
+			Approve(r.ID, admin, "")
+		}(admin)
+	}
+	wg.Wait()
+	if applied != 1 {
+		t.Fatalf("expected the request to be applied once, got %v", applied)
+	}
+
+	failing = true
+	r, err = Create("test-apply-once", "fake", "project", "u123", "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Approve(r.ID, "admin1", ""); err == nil {
+		t.Fatal("expected the apply to fail")
+	}
+	// The SLA timer must not expire the request decided since it was listed
+	expire(*r, time.Now())
+	failing = false
+	got, err := Approve(r.ID, "admin2", "")
+	if err != nil || got.State != StateApplied || applied != 3 {
+		t.Errorf("expected the failed request to be applied again, got %+v %v", got, err)
+	}
+	if _, err := Approve(r.ID, "admin1", ""); err == nil || applied != 3 {
+		t.Error("expected an applied request not to be applied again")
+	}
+}
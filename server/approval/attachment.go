@@ -0,0 +1,202 @@
+package approval
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxAttachmentMB = 10
+	maxAttachments         = 5
+	attachmentsDir         = "attachments"
+	pdfMagic               = "%PDF-"
+)
+
+// Attachment is a justification file of a request, e.g. the decision of a
+// change board. The file itself is kept in the AttachmentStorage
+type Attachment struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Size       int       `json:"size"`
+	SHA256     string    `json:"sha256"`
+	Key        string    `json:"key"`
+	UploadedBy string    `json:"uploadedBy"`
+	UploadedAt time.Time `json:"uploadedAt"`
+}
+
+// AttachmentStorage keeps the files attached to requests
+type AttachmentStorage interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+}
+
+// Attachments is the storage of the files. It is replaced at startup by an
+// object storage (e.g. s3) if one is configured
+var Attachments AttachmentStorage = fileStorage{}
+
+// fileStorage keeps the files in a directory of the store
+type fileStorage struct{}
+
+func (fileStorage) path(key string) string {
+	return filepath.Join(store.Dir(), attachmentsDir, filepath.FromSlash(filepath.Clean("/"+key)))
+}
+
+func (s fileStorage) Put(key string, data []byte) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+func (s fileStorage) Get(key string) ([]byte, error) {
+	return ioutil.ReadFile(s.path(key))
+}
+
+// MaxAttachmentBytes is the size limit of a file in 'approval.attachments.max_mb'
+func MaxAttachmentBytes() int {
+	mb := config.Config().GetInt("approval.attachments.max_mb")
+	if mb <= 0 {
+		mb = defaultMaxAttachmentMB
+	}
+	return mb << 20
+}
+
+// AddAttachment checks, scans and stores a pdf and adds it to the pending
+// request. Only the requester and portal admins can add files
+func AddAttachment(id, username, name string, data []byte) (*Request, *Attachment, error) {
+	r, found, err := Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		return nil, nil, errors.New("Der Antrag existiert nicht")
+	}
+	if !strings.EqualFold(r.RequestedBy, username) && !common.IsPortalAdmin(username) {
+		return nil, nil, errors.New("Nur der Antragsteller kann Dateien anhängen")
+	}
+	if r.State != StatePending {
+		return nil, nil, errors.New("Der Antrag wurde bereits bearbeitet")
+	}
+	if len(r.Attachments) >= maxAttachments {
+		return nil, nil, fmt.Errorf("Es können höchstens %v Dateien angehängt werden", maxAttachments)
+	}
+	if err := validateAttachment(name, data); err != nil {
+		return nil, nil, err
+	}
+	if err := scanAttachment(name, data); err != nil {
+		return nil, nil, err
+	}
+
+	attachmentId, err := uuid.NewV4()
+	if err != nil {
+		return nil, nil, err
+	}
+	sum := sha256.Sum256(data)
+	a := Attachment{
+		ID:         attachmentId.String(),
+		Name:       filepath.Base(name),
+		Size:       len(data),
+		SHA256:     hex.EncodeToString(sum[:]),
+		Key:        r.ID + "/" + attachmentId.String() + ".pdf",
+		UploadedBy: username,
+		UploadedAt: time.Now(),
+	}
+	if err := Attachments.Put(a.Key, data); err != nil {
+		log.Printf("Error storing attachment %v of request %v: %v", a.Key, r.ID, err)
+		return nil, nil, errors.New("Die Datei konnte nicht gespeichert werden. Bitte erstelle ein Ticket")
+	}
+	r.Attachments = append(r.Attachments, a)
+	if err := store.Put(requestsCollection, r.ID, r); err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("%v attached %v (%v bytes) to request %v", username, a.Name, a.Size, r.ID)
+	return r, &a, nil
+}
+
+// GetAttachment returns the file of a request. It can be read by the
+// requester, the approvers and the auditors
+func GetAttachment(id, attachmentId, username string) (*Attachment, []byte, error) {
+	r, found, err := Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		return nil, nil, errors.New("Der Antrag existiert nicht")
+	}
+	if !strings.EqualFold(r.RequestedBy, username) && !CanDecide(*r, username) && !common.IsPortalAuditor(username) {
+		return nil, nil, errors.New("Du darfst die Dateien dieses Antrags nicht lesen")
+	}
+	for _, a := range r.Attachments {
+		if a.ID != attachmentId {
+			continue
+		}
+		data, err := Attachments.Get(a.Key)
+		if err != nil {
+			log.Printf("Error reading attachment %v of request %v: %v", a.Key, r.ID, err)
+			return nil, nil, errors.New("Die Datei konnte nicht gelesen werden. Bitte erstelle ein Ticket")
+		}
+		return &a, data, nil
+	}
+	return nil, nil, errors.New("Die Datei existiert nicht")
+}
+
+// validateAttachment allows only pdfs up to the size limit
+func validateAttachment(name string, data []byte) error {
+	if len(data) == 0 {
+		return errors.New("Die Datei ist leer")
+	}
+	if len(data) > MaxAttachmentBytes() {
+		return fmt.Errorf("Die Datei ist grösser als %v MB", MaxAttachmentBytes()>>20)
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".pdf") || !bytes.HasPrefix(data, []byte(pdfMagic)) ||
+		http.DetectContentType(data) != "application/pdf" {
+		return errors.New("Es können nur PDF-Dateien angehängt werden")
+	}
+	return nil
+}
+
+// scanAttachment sends the file to the virus scanner in
+// 'approval.attachments.scan_url'. Every answer except 200 rejects the file.
+// Without scanner the files aren't scanned
+func scanAttachment(name string, data []byte) error {
+	scanURL := config.Config().GetString("approval.attachments.scan_url")
+	if scanURL == "" {
+		return nil
+	}
+
+	req, err := http.NewRequest("POST", scanURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/pdf")
+	req.Header.Set("X-Filename", filepath.Base(name))
+	resp, err := common.HTTPClient("virusscan").Do(req)
+	if err != nil {
+		log.Printf("Error calling the virus scanner: %v", err)
+		return errors.New("Die Datei konnte nicht auf Viren geprüft werden. Bitte versuche es später nochmals")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("The virus scanner rejected %v: %v %v", name, resp.StatusCode, string(body))
+		return errors.New("Die Datei wurde vom Virenscanner abgelehnt")
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package approval
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store/storetest"
+)
+
+func TestAddAttachment(t *testing.T) {
+	defer storetest.Setup(t)()
+	scanned := 0
+	scanner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scanned++
+		body, _ := ioutil.ReadAll(r.Body)
+		if bytes.Contains(body, []byte("EICAR")) {
+			w.WriteHeader(http.StatusNotAcceptable)
+		}
+	}))
+	defer scanner.Close()
+	cfg := config.Config()
+	cfg.Set("portal_admins", []string{"admin"})
+	cfg.Set("portal_auditors", []string{"auditor"})
+	cfg.Set("approval.attachments.max_mb", 1)
+	cfg.Set("approval.attachments.scan_url", scanner.URL)
+	RegisterKind(Kind{Name: "test-attachment"})
+
+	r, err := Create("test-attachment", "fake", "project", "u123", "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := []byte("%PDF-1.4\n1 0 obj\n<<>>\nendobj\n%%EOF\n")
+
+	tests := []struct {
+		user string
+		name string
+		data []byte
+	}{
+		{"u456", "board.pdf", pdf},
+		{"u123", "board.txt", pdf},
+		{"u123", "board.pdf", []byte("not a pdf")},
+		{"u123", "board.pdf", append(pdf, bytes.Repeat([]byte("x"), 1<<20)...)},
+		{"u123", "virus.pdf", append(pdf, []byte("EICAR")...)},
+	}
+	for _, test := range tests {
+		if _, _, err := AddAttachment(r.ID, test.user, test.name, test.data); err == nil {
+			t.Errorf("expected %v of %v to be rejected", test.name, test.user)
+		}
+	}
+
+	_, a, err := AddAttachment(r.ID, "u123", "board.pdf", pdf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if scanned != 2 {
+		t.Errorf("expected the valid pdfs to be scanned, got %v scans", scanned)
+	}
+	stored, _, _ := Get(r.ID)
+	if len(stored.Attachments) != 1 || stored.Attachments[0].Key != r.ID+"/"+a.ID+".pdf" || stored.Attachments[0].Size != len(pdf) {
+		t.Fatalf("expected the attachment to be saved with the request, got %+v", stored.Attachments)
+	}
+
+	for user, allowed := range map[string]bool{"u123": true, "admin": true, "auditor": true, "u456": false} {
+		_, data, err := GetAttachment(r.ID, a.ID, user)
+		if allowed && (err != nil || !bytes.Equal(data, pdf)) {
+			t.Errorf("expected %v to read the attachment, got %v", user, err)
+		}
+		if !allowed && err == nil {
+			t.Errorf("expected %v not to read the attachment", user)
+		}
+	}
+
+	if _, err := Reject(r.ID, "admin", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := AddAttachment(r.ID, "u123", "late.pdf", pdf); err == nil {
+		t.Error("expected no attachments after the decision")
+	}
+}
@@ -0,0 +1,79 @@
+package approval
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// kindMetrics is the aging of the pending requests of a kind
+type kindMetrics struct {
+	pending    int
+	overdue    int
+	escalated  int
+	oldestSecs float64
+}
+
+// MetricsHandler exposes the aging of the pending requests in the text format
+// of Prometheus
+func MetricsHandler(c *gin.Context) {
+	requests, err := List(func(r Request) bool {
+		return r.State == StatePending
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4", []byte(formatMetrics(requests, time.Now())))
+}
+
+func formatMetrics(requests []Request, now time.Time) string {
+	byKind := make(map[string]*kindMetrics)
+	for name := range kinds {
+		byKind[name] = &kindMetrics{}
+	}
+	for _, r := range requests {
+		m, ok := byKind[r.Kind]
+		if !ok {
+			m = &kindMetrics{}
+			byKind[r.Kind] = m
+		}
+		m.pending++
+		if r.Deadline != nil && r.Deadline.Before(now) {
+			m.overdue++
+		}
+		if r.EscalatedAt != nil {
+			m.escalated++
+		}
+		if age := now.Sub(r.RequestedAt).Seconds(); age > m.oldestSecs {
+			m.oldestSecs = age
+		}
+	}
+	names := []string{}
+	for name := range byKind {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	metric := func(name, help string, value func(m *kindMetrics) float64) {
+		fmt.Fprintf(&b, "# HELP %v %v\n# TYPE %v gauge\n", name, help, name)
+		for _, kind := range names {
+			fmt.Fprintf(&b, "%v{kind=%q} %v\n", name, kind, value(byKind[kind]))
+		}
+	}
+	metric("ssp_approval_pending_requests", "Number of pending approval requests",
+		func(m *kindMetrics) float64 { return float64(m.pending) })
+	metric("ssp_approval_overdue_requests", "Number of pending approval requests after their deadline",
+		func(m *kindMetrics) float64 { return float64(m.overdue) })
+	metric("ssp_approval_escalated_requests", "Number of pending approval requests which were escalated",
+		func(m *kindMetrics) float64 { return float64(m.escalated) })
+	metric("ssp_approval_oldest_pending_seconds", "Age of the oldest pending approval request",
+		func(m *kindMetrics) float64 { return m.oldestSecs })
+	return b.String()
+}
@@ -0,0 +1,143 @@
+package approval
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const wrongAPIUsageError = "Ungültiger API-Aufruf: Die Argumente stimmen nicht mit der definition überein. Bitte erstelle eine Ticket"
+
+func RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/approvals", getOwnRequestsHandler)
+	r.POST("/approvals/:id/approve", approveHandler)
+	r.POST("/approvals/:id/reject", rejectHandler)
+	r.POST("/approvals/:id/attachments", uploadAttachmentHandler)
+	r.GET("/approvals/:id/attachments/:attachment", getAttachmentHandler)
+
+	admin := r.Group("/admin", common.RequirePortalAdmin())
+	admin.GET("/approvals", getRequestsHandler)
+	admin.POST("/approvals/:id/approve", approveHandler)
+	admin.POST("/approvals/:id/reject", rejectHandler)
+}
+
+// getOwnRequestsHandler lists the requests of the user or with ?assigned=true
+// the requests the user has to approve as member of an approver group
+func getOwnRequestsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	assigned := c.Query("assigned") == "true"
+
+	requests, err := List(func(r Request) bool {
+		if assigned {
+			return containsFold(r.Approvers, username)
+		}
+		return strings.ToLower(r.RequestedBy) == strings.ToLower(username)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, requests)
+}
+
+// getRequestsHandler lists the requests, optionally filtered by ?kind= and ?state=
+func getRequestsHandler(c *gin.Context) {
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	kind := c.Query("kind")
+	state := c.Query("state")
+
+	requests, err := List(func(r Request) bool {
+		return (kind == "" || r.Kind == kind) && (state == "" || r.State == state) &&
+			listParams.Matches(r.Kind, r.Project, r.RequestedBy)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	start, end, next := listParams.Page(len(requests))
+	c.JSON(http.StatusOK, common.ListResponse{
+		Items:    requests[start:end],
+		Total:    len(requests),
+		Continue: next,
+	})
+}
+
+func approveHandler(c *gin.Context) {
+	decideHandler(c, Approve)
+}
+
+func rejectHandler(c *gin.Context) {
+	decideHandler(c, Reject)
+}
+
+func decideHandler(c *gin.Context, decide func(id, username, comment string) (*Request, error)) {
+	username := common.GetUserName(c)
+
+	var data common.DecisionCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	request, err := decide(c.Param("id"), username, data.Comment)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, request)
+}
+
+// uploadAttachmentHandler attaches the pdf in the multipart field "file" to a
+// pending request. The audit entry of the upload links to the file
+func uploadAttachmentHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Es muss eine Datei hochgeladen werden"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	defer file.Close()
+	// One byte more than allowed to recognize too large files
+	data, err := ioutil.ReadAll(io.LimitReader(file, int64(MaxAttachmentBytes())+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	request, attachment, err := AddAttachment(c.Param("id"), username, header.Filename, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Link(c, fmt.Sprintf("/api/approvals/%v/attachments/%v", request.ID, attachment.ID))
+	c.JSON(http.StatusOK, request)
+}
+
+func getAttachmentHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	attachment, data, err := GetAttachment(c.Param("id"), c.Param("attachment"), username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", attachment.Name))
+	c.Data(http.StatusOK, "application/pdf", data)
+}
@@ -0,0 +1,143 @@
+package approval
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Route sends the requests which match all its set conditions to an approver
+// group instead of the portal admins
+type Route struct {
+	Kinds    []string          `mapstructure:"kinds"`
+	Clusters []string          `mapstructure:"clusters"`
+	Labels   map[string]string `mapstructure:"labels"`
+	Group    string            `mapstructure:"group"`
+}
+
+// ProjectLabels returns the labels of the project of a request. It is set by
+// the package which knows the projects
+var ProjectLabels func(clusterId, project string) (map[string]string, error)
+
+// routeOf returns the approver group and its members of the first matching
+// route in 'approval.routes'. Requests without route go to the portal admins
+func routeOf(r Request) (string, []string) {
+	cfg := config.Config()
+	routes := []Route{}
+	if err := cfg.UnmarshalKey("approval.routes", &routes); err != nil {
+		log.Printf("Error reading the approval routes: %v", err)
+		return "", nil
+	}
+
+	var labels map[string]string
+	for _, route := range routes {
+		if len(route.Kinds) > 0 && !containsFold(route.Kinds, r.Kind) {
+			continue
+		}
+		if len(route.Clusters) > 0 && !containsFold(route.Clusters, r.ClusterId) {
+			continue
+		}
+		if len(route.Labels) > 0 {
+			if labels == nil {
+				labels = requestLabels(r)
+			}
+			if !matchesLabels(labels, route.Labels) {
+				continue
+			}
+		}
+		approvers := cfg.GetStringSlice("approval.groups." + route.Group)
+		if len(approvers) == 0 {
+			log.Printf("WARNING: the approver group %v has no members", route.Group)
+			continue
+		}
+		return route.Group, approvers
+	}
+	return "", nil
+}
+
+func requestLabels(r Request) map[string]string {
+	if ProjectLabels == nil || r.Project == "" {
+		return map[string]string{}
+	}
+	labels, err := ProjectLabels(r.ClusterId, r.Project)
+	if err != nil {
+		log.Printf("Error getting the labels of project %v for the approval routes: %v", r.Project, err)
+		return map[string]string{}
+	}
+	return labels
+}
+
+func matchesLabels(labels, required map[string]string) bool {
+	for k, v := range required {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// CanDecide is true for the approvers of the request and the portal admins.
+// Only the portal admins decide the requests of kinds which are AdminsOnly
+func CanDecide(r Request, username string) bool {
+	if common.IsPortalAdmin(username) {
+		return true
+	}
+	return !kinds[r.Kind].AdminsOnly && containsFold(r.Approvers, username)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, l := range list {
+		if strings.ToLower(strings.TrimSpace(l)) == strings.ToLower(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyApprovers sends a mail about a new request to the approver group.
+// The portal admins are informed by the kinds and webhooks
+func notifyApprovers(r Request) {
+	if len(r.Approvers) == 0 {
+		return
+	}
+	mailApprovers(r, r.Approvers, fmt.Sprintf("Neuer Antrag: %v", r.Kind),
+		fmt.Sprintf("%v hat einen Antrag (%v) für das Projekt %v auf Cluster %v gestellt, den die Gruppe %v bewilligen muss.",
+			r.RequestedBy, r.Kind, r.Project, r.ClusterId, r.ApproverGroup))
+}
+
+// approversOf returns the approvers of the request or the portal admins
+func approversOf(r Request) []string {
+	if len(r.Approvers) > 0 {
+		return r.Approvers
+	}
+	return config.Config().GetStringSlice("portal_admins")
+}
+
+func mailApprovers(r Request, approvers []string, subject, text string) {
+	mails := []string{}
+	for _, a := range approvers {
+		if mail := common.GetMailForUser(strings.TrimSpace(a)); mail != "" {
+			mails = append(mails, mail)
+		}
+	}
+	if len(mails) == 0 {
+		return
+	}
+	err := common.SendMail(mails, subject, fmt.Sprintf(`
+	Hallo
+	<br><br>
+	%v
+	<br><br>
+	Grund: %v
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, text, r.Reason))
+	if err != nil {
+		log.Printf("Can't send e-mail about request %v to the approvers: %v", r.ID, err)
+	}
+}
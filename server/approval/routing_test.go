@@ -0,0 +1,76 @@
+package approval
+
+import (
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store/storetest"
+)
+
+func TestRouteOf(t *testing.T) {
+	config.Init("test")
+	cfg := config.Config()
+	cfg.Set("portal_admins", []string{"admin"})
+	cfg.Set("approval.groups", map[string]interface{}{
+		"network": []string{"net1", "net2"},
+		"finance": []string{"fin1"},
+	})
+	cfg.Set("approval.routes", []map[string]interface{}{
+		{"kinds": []string{"egress-ip"}, "clusters": []string{"prod"}, "group": "network"},
+		{"labels": map[string]interface{}{"org": "finance"}, "group": "finance"},
+	})
+	ProjectLabels = func(clusterId, project string) (map[string]string, error) {
+		return map[string]string{"org": project}, nil
+	}
+	defer func() { ProjectLabels = nil }()
+
+	tests := []struct {
+		request Request
+		group   string
+	}{
+		{Request{Kind: "egress-ip", ClusterId: "prod"}, "network"},
+		{Request{Kind: "egress-ip", ClusterId: "dev"}, ""},
+		{Request{Kind: "quota", ClusterId: "dev", Project: "finance"}, "finance"},
+		{Request{Kind: "quota", ClusterId: "dev", Project: "sales"}, ""},
+	}
+	for _, test := range tests {
+		if group, _ := routeOf(test.request); group != test.group {
+			t.Errorf("%+v: expected group '%v', got '%v'", test.request, test.group, group)
+		}
+	}
+
+	r := Request{Kind: "egress-ip", ClusterId: "prod"}
+	r.ApproverGroup, r.Approvers = routeOf(r)
+	for user, allowed := range map[string]bool{"net1": true, "NET2": true, "admin": true, "fin1": false} {
+		if CanDecide(r, user) != allowed {
+			t.Errorf("%v: expected CanDecide %v", user, allowed)
+		}
+	}
+}
+
+func TestRouteOfAdminsOnly(t *testing.T) {
+	defer storetest.Setup(t)()
+	cfg := config.Config()
+	cfg.Set("portal_admins", []string{"admin"})
+	cfg.Set("approval.groups", map[string]interface{}{"ops": []string{"ops1"}})
+	cfg.Set("approval.routes", []map[string]interface{}{
+		{"clusters": []string{"prod"}, "group": "ops"},
+	})
+	RegisterKind(Kind{Name: "test-admins-only", SeparateApprover: true, AdminsOnly: true})
+
+	r, err := Create("test-admins-only", "prod", "p1", "admin", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ApproverGroup != "" || len(r.Approvers) > 0 {
+		t.Errorf("expected no approver group, got '%v' %v", r.ApproverGroup, r.Approvers)
+	}
+
+	// requests which were routed before are still decided by the admins only
+	r.ApproverGroup, r.Approvers = routeOf(*r)
+	for user, allowed := range map[string]bool{"ops1": false, "admin": true} {
+		if CanDecide(*r, user) != allowed {
+			t.Errorf("%v: expected CanDecide %v", user, allowed)
+		}
+	}
+}
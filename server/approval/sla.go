@@ -0,0 +1,93 @@
+package approval
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// errNotPending stops the SLA timer from changing a request which was decided
+// since it was listed
+var errNotPending = errors.New("request is not pending anymore")
+
+// remindAfter is the time in pending after which the approvers are reminded
+// once. Reminders are off without 'approval.remind_hours'
+func remindAfter() time.Duration {
+	return time.Duration(config.Config().GetInt("approval.remind_hours")) * time.Hour
+}
+
+func remind(r Request, at time.Time) {
+	err := store.Update(requestsCollection, r.ID, &r, func(exists bool) error {
+		if !exists || r.State != StatePending {
+			return errNotPending
+		}
+		r.RemindedAt = &at
+		return nil
+	})
+	if err != nil {
+		if err != errNotPending {
+			log.Printf("Error saving request %v: %v", r.ID, err)
+		}
+		return
+	}
+	log.Printf("Reminding the approvers of the %v request %v of project %v", r.Kind, r.ID, r.Project)
+	mailApprovers(r, approversOf(r), fmt.Sprintf("Erinnerung: Antrag %v", r.Kind),
+		fmt.Sprintf("Der Antrag (%v) von %v für das Projekt %v auf Cluster %v wartet seit %v Stunden auf eine Entscheidung.",
+			r.Kind, r.RequestedBy, r.Project, r.ClusterId, int(at.Sub(r.RequestedAt).Hours())))
+}
+
+// escalate adds the members of 'approval.escalation.group' to the approvers
+// of a request which breached its SLA and gives them
+// 'approval.escalation.hours' to decide. Escalated requests expire. Returns
+// false if the request isn't escalated
+func escalate(r Request, at time.Time) bool {
+	cfg := config.Config()
+	group := cfg.GetString("approval.escalation.group")
+	if group == "" || r.EscalatedAt != nil {
+		return false
+	}
+	members := cfg.GetStringSlice("approval.groups." + group)
+	if len(members) == 0 {
+		log.Printf("WARNING: the escalation group %v has no members", group)
+		return false
+	}
+
+	hours := cfg.GetInt("approval.escalation.hours")
+	if hours <= 0 {
+		hours = defaultSLAHours
+	}
+	deadline := at.Add(time.Duration(hours) * time.Hour)
+	var previous []string
+	err := store.Update(requestsCollection, r.ID, &r, func(exists bool) error {
+		if !exists || r.State != StatePending || r.EscalatedAt != nil {
+			return errNotPending
+		}
+		previous = approversOf(r)
+		for _, m := range members {
+			if !containsFold(r.Approvers, m) {
+				r.Approvers = append(r.Approvers, m)
+			}
+		}
+		r.EscalatedAt = &at
+		r.Deadline = &deadline
+		return nil
+	})
+	if err == errNotPending {
+		// Decided since it was listed, nothing to expire either
+		return true
+	}
+	if err != nil {
+		log.Printf("Error saving request %v: %v", r.ID, err)
+		return false
+	}
+
+	log.Printf("The %v request %v of project %v breached its SLA and was escalated to %v", r.Kind, r.ID, r.Project, group)
+	mailApprovers(r, append(members, previous...), fmt.Sprintf("Eskalation: Antrag %v", r.Kind),
+		fmt.Sprintf("Der Antrag (%v) von %v für das Projekt %v auf Cluster %v wurde nicht rechtzeitig bearbeitet und an die Gruppe %v eskaliert. Er läuft am %v ab.",
+			r.Kind, r.RequestedBy, r.Project, r.ClusterId, group, deadline.Format("02.01.2006 15:04")))
+	return true
+}
@@ -0,0 +1,58 @@
+package approval
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store/storetest"
+)
+
+func TestCheckPendingRequests(t *testing.T) {
+	defer storetest.Setup(t)()
+	cfg := config.Config()
+	cfg.Set("approval.sla_hours", 72)
+	cfg.Set("approval.remind_hours", 24)
+	cfg.Set("approval.groups", map[string]interface{}{"leads": []string{"lead1"}})
+	cfg.Set("approval.escalation", map[string]interface{}{"group": "leads", "hours": 24})
+	RegisterKind(Kind{Name: "test-sla"})
+
+	r, err := Create("test-sla", "fake", "project", "u123", "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func() *Request {
+		r, _, err := Get(r.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	checkPendingRequests(r.RequestedAt.Add(25 * time.Hour))
+	if got := get(); got.RemindedAt == nil || got.EscalatedAt != nil {
+		t.Errorf("expected a reminder after 25 hours, got %+v", got)
+	}
+
+	checkPendingRequests(r.RequestedAt.Add(73 * time.Hour))
+	escalated := get()
+	if escalated.State != StatePending || escalated.EscalatedAt == nil || !CanDecide(*escalated, "lead1") {
+		t.Errorf("expected the request to be escalated to lead1 after the SLA, got %+v", escalated)
+	}
+	metrics := formatMetrics([]Request{*escalated}, r.RequestedAt.Add(74*time.Hour))
+	for _, line := range []string{
+		`ssp_approval_pending_requests{kind="test-sla"} 1`,
+		`ssp_approval_escalated_requests{kind="test-sla"} 1`,
+		`ssp_approval_oldest_pending_seconds{kind="test-sla"} 266400`,
+	} {
+		if !strings.Contains(metrics, line) {
+			t.Errorf("expected the metrics to contain %v, got\n%v", line, metrics)
+		}
+	}
+
+	checkPendingRequests(r.RequestedAt.Add(98 * time.Hour))
+	if got := get(); got.State != StateExpired {
+		t.Errorf("expected the escalated request to expire, got %v", got.State)
+	}
+}
@@ -0,0 +1,55 @@
+package approval
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/webhooks"
+	log "github.com/sirupsen/logrus"
+)
+
+const webhookTimeout = 10 * time.Second
+
+type webhookEvent struct {
+	Event   string  `json:"event"`
+	Request Request `json:"request"`
+}
+
+// sendWebhooks posts the request to all urls in 'approval.webhooks' and to
+// the subscriptions of the approval events after every change of the
+// state. Errors are only logged
+func sendWebhooks(r Request) {
+	webhooks.Publish(webhooks.Event{
+		Type: webhooks.EventApproval + "." + r.State, ClusterId: r.ClusterId, Project: r.Project, Data: r,
+	})
+
+	urls := config.Config().GetStringSlice("approval.webhooks")
+	if len(urls) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(webhookEvent{Event: "approval." + r.State, Request: r})
+	if err != nil {
+		log.Printf("Error marshalling webhook of request %v: %v", r.ID, err)
+		return
+	}
+
+	client := common.HTTPClient("webhook")
+	client.Timeout = webhookTimeout
+	for _, url := range urls {
+		go func(url string) {
+			resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+			if err != nil {
+				log.Printf("Error calling webhook %v: %v", url, err)
+				return
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				log.Printf("Webhook %v returned %v", url, resp.StatusCode)
+			}
+		}(url)
+	}
+}
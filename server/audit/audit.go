@@ -0,0 +1,332 @@
+// Package audit records every changing request of the api: who did what in
+// which project with which payload and result. The entries are appended to a
+// journal of the store per month and can be queried by portal admins and
+// auditors on /auditlog.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	collectionPrefix = "audit_log_"
+	monthFormat      = "2006-01"
+	// maxPayloadBytes limits the payload which is kept per entry
+	maxPayloadBytes = 64 * 1024
+	defaultMonths   = 3
+	redacted        = "***"
+	linksKey        = "audit.links"
+	descriptionsKey = "audit.descriptions"
+	redactKey       = "audit.redact"
+)
+
+// Entry is a changing request of a user
+type Entry struct {
+	ID        string          `json:"id"`
+	Time      time.Time       `json:"time"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	ClusterId string          `json:"clusterid,omitempty"`
+	Project   string          `json:"project,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	DryRun    bool            `json:"dryRun,omitempty"`
+	Status    int             `json:"status"`
+	// Result is the message of the response
+	Result string `json:"result,omitempty"`
+	// Links are references to data which isn't kept in the entry, e.g. uploaded files
+	Links []string `json:"links,omitempty"`
+	// Descriptions say in words what the request did, see Describe
+	Descriptions []string `json:"descriptions,omitempty"`
+	// RequestID correlates the entry with the log lines of the request
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// Query selects the entries between From and To. Empty fields match all
+type Query struct {
+	ClusterId string
+	Project   string
+	Actor     string
+	Action    string
+	From      time.Time
+	To        time.Time
+}
+
+func collection(t time.Time) string {
+	return collectionPrefix + t.In(common.Location()).Format(monthFormat)
+}
+
+// Record appends the entry to the journal of its month and exports it to the
+// SIEM if configured
+func Record(e Entry) error {
+	if e.ID == "" {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+		e.ID = id.String()
+	}
+	if err := store.Append(collection(e.Time), e); err != nil {
+		return err
+	}
+	if exporter != nil {
+		exporter.export(e)
+	}
+	return nil
+}
+
+// Find returns the matching entries, the newest first
+func Find(q Query) ([]Entry, error) {
+	entries := []Entry{}
+	add := func(data []byte) error {
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		if q.matches(e) {
+			entries = append(entries, e)
+		}
+		return nil
+	}
+	from := time.Date(q.From.Year(), q.From.Month(), 1, 0, 0, 0, 0, q.From.Location())
+	for month := from; !month.After(q.To); month = month.AddDate(0, 1, 0) {
+		if err := store.Scan(collection(month), add); err != nil {
+			return nil, err
+		}
+		// Entries of older versions are documents of a collection
+		err := store.List(collection(month), func(id string, data []byte) error {
+			return add(data)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+	return entries, nil
+}
+
+func (q Query) matches(e Entry) bool {
+	return (q.ClusterId == "" || q.ClusterId == e.ClusterId) &&
+		(q.Project == "" || q.Project == e.Project) &&
+		(q.Actor == "" || strings.EqualFold(q.Actor, e.Actor)) &&
+		(q.Action == "" || strings.Contains(e.Action, q.Action)) &&
+		!e.Time.Before(q.From) && !e.Time.After(q.To)
+}
+
+// responseRecorder keeps the start of the response to read its message
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.body.Len() < maxPayloadBytes {
+		r.body.Write(data)
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+// Link adds a reference, e.g. the url of an uploaded file, to the audit
+// entry of the current request
+func Link(c *gin.Context, ref string) {
+	c.Set(linksKey, append(c.GetStringSlice(linksKey), ref))
+}
+
+// Describe says in words what the request did, e.g. "u123 gave u456 the
+// role edit in project x on cluster y". It's logged with the request id and
+// kept in the audit entry of the request. Reading requests are recorded too
+// if they are described, e.g. exports
+func Describe(c *gin.Context, format string, args ...interface{}) {
+	description := fmt.Sprintf(format, args...)
+	common.Logger(c).Print(description)
+	c.Set(descriptionsKey, append(c.GetStringSlice(descriptionsKey), description))
+}
+
+// RedactPayload is added to the routes whose body is secret, e.g. the data of
+// secrets or the password of pull secrets. Their entries keep only cluster
+// and project instead of redacting single fields
+func RedactPayload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(redactKey, true)
+	}
+}
+
+// Middleware records all requests which aren't reading
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			if descriptions := c.GetStringSlice(descriptionsKey); len(descriptions) > 0 {
+				record(c, Entry{
+					Action:       c.Request.Method + " " + c.Request.URL.Path,
+					ClusterId:    c.Query("clusterid"),
+					Project:      c.Query("project"),
+					Status:       c.Writer.Status(),
+					Descriptions: descriptions,
+				})
+			}
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = ioutil.ReadAll(c.Request.Body)
+			c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		entry := Entry{
+			Action:       c.Request.Method + " " + c.Request.URL.Path,
+			DryRun:       common.IsDryRun(c),
+			Status:       recorder.Status(),
+			Links:        c.GetStringSlice(linksKey),
+			Descriptions: c.GetStringSlice(descriptionsKey),
+		}
+		entry.ClusterId, entry.Project, entry.Payload = parsePayload(body)
+		if c.GetBool(redactKey) && entry.Payload != nil {
+			entry.Payload = json.RawMessage(`"` + redacted + `"`)
+		}
+		if entry.ClusterId == "" {
+			entry.ClusterId = c.Query("clusterid")
+		}
+		if entry.Project == "" {
+			entry.Project = c.Query("project")
+		}
+		var response common.ApiResponse
+		if json.Unmarshal(recorder.body.Bytes(), &response) == nil {
+			entry.Result = response.Message
+		}
+		record(c, entry)
+	}
+}
+
+// record adds the user and the request id to the entry and records it
+func record(c *gin.Context, entry Entry) {
+	entry.Time = common.Now()
+	entry.Actor = common.GetUserName(c)
+	entry.RequestID = common.GetRequestID(c)
+	if err := Record(entry); err != nil {
+		common.Logger(c).Printf("Error recording the audit entry of %v by %v: %v", entry.Action, entry.Actor, err)
+	}
+}
+
+// parsePayload reads cluster and project of a json body and redacts the
+// credentials. Other bodies aren't kept
+func parsePayload(body []byte) (clusterId, project string, payload json.RawMessage) {
+	var fields map[string]interface{}
+	if len(body) == 0 || json.Unmarshal(body, &fields) != nil {
+		return "", "", nil
+	}
+	clusterId, _ = fields["clusterid"].(string)
+	project, _ = fields["project"].(string)
+
+	redact(fields)
+	payload, _ = json.Marshal(fields)
+	if len(payload) > maxPayloadBytes {
+		payload, _ = json.Marshal(map[string]string{"truncated": fmt.Sprintf("%v bytes", len(payload))})
+	}
+	return clusterId, project, payload
+}
+
+func redact(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if isCredential(key) {
+				v[key] = redacted
+				continue
+			}
+			redact(field)
+		}
+	case []interface{}:
+		for _, item := range v {
+			redact(item)
+		}
+	}
+}
+
+func isCredential(key string) bool {
+	key = strings.ToLower(key)
+	if strings.HasSuffix(key, "name") {
+		return false
+	}
+	for _, s := range []string{"password", "token", "secret", "credential", "privatekey"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/auditlog", common.RequirePortalAuditor(), getAuditLogHandler)
+}
+
+// getAuditLogHandler queries the entries of the last 'months' (default 3)
+// or between ?from and ?to (2006-01-02)
+func getAuditLogHandler(c *gin.Context) {
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	now := common.Now()
+	query := Query{
+		ClusterId: c.Query("clusterid"),
+		Project:   c.Query("project"),
+		Actor:     c.Query("actor"),
+		Action:    c.Query("action"),
+		From:      now.AddDate(0, -defaultMonths, 0),
+		To:        now,
+	}
+	for param, t := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", value, common.Location())
+			if err != nil {
+				c.JSON(http.StatusBadRequest, common.ApiResponse{Message: fmt.Sprintf("Ungültiges Datum %v: %v", param, value)})
+				return
+			}
+			*t = parsed
+		}
+	}
+	if c.Query("to") != "" {
+		// The whole day of ?to is included
+		query.To = query.To.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	entries, err := Find(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	matching := []Entry{}
+	for _, e := range entries {
+		if listParams.Matches(e.Actor, e.Action, e.Project, e.Result) {
+			matching = append(matching, e)
+		}
+	}
+	start, end, next := listParams.Page(len(matching))
+	c.JSON(http.StatusOK, common.ListResponse{
+		Items:    matching[start:end],
+		Total:    len(matching),
+		Continue: next,
+	})
+}
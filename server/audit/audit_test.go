@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store/storetest"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware(t *testing.T) {
+	defer storetest.Setup(t)()
+	config.Config().Set("portal_auditors", []string{"auditor"})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(gin.AuthUserKey, c.GetHeader("X-User")) })
+	router.Use(Middleware())
+	router.POST("/api/ose/secret", RedactPayload(), func(c *gin.Context) {
+		Link(c, "/api/ose/secret/db")
+		c.JSON(http.StatusOK, common.ApiResponse{Message: "Das Secret wurde angelegt"})
+	})
+	router.POST("/api/ose/project/members", func(c *gin.Context) {
+		Describe(c, "%v added %v to project own", "u123", "u456")
+		c.JSON(http.StatusOK, common.ApiResponse{Message: "Der Benutzer wurde hinzugefügt"})
+	})
+	router.GET("/api/ose/projects", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/ose/project/export", func(c *gin.Context) {
+		Describe(c, "%v exported project own", "u123")
+		c.Status(http.StatusOK)
+	})
+	RegisterRoutes(router.Group("/api"))
+
+	request := func(method, path, user, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("X-User", user)
+		router.ServeHTTP(w, r)
+		return w
+	}
+	request("POST", "/api/ose/secret", "u123", `{"clusterid": "fake", "project": "own", "name": "db", "data": {"DB_PASSWORD": "geheim"}}`)
+	request("POST", "/api/ose/project/members", "u123", `{"clusterid": "fake", "project": "own", "username": "u456", "password": "geheim"}`)
+	request("GET", "/api/ose/projects", "u123", "")
+	request("GET", "/api/ose/project/export?clusterid=fake&project=own", "u123", "")
+
+	entries, err := Find(Query{From: common.Now().AddDate(0, -1, 0), To: common.Now()})
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("expected the POSTs and the described GET to be recorded, got %+v %v", entries, err)
+	}
+	actions := map[string]Entry{}
+	for _, e := range entries {
+		actions[e.Action] = e
+	}
+	e := actions["POST /api/ose/secret"]
+	if e.Actor != "u123" || e.ClusterId != "fake" || e.Project != "own" || e.Status != http.StatusOK || e.Result != "Das Secret wurde angelegt" ||
+		len(e.Links) != 1 || e.Links[0] != "/api/ose/secret/db" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if strings.Contains(string(e.Payload), "geheim") || strings.Contains(string(e.Payload), "DB_PASSWORD") {
+		t.Errorf("expected the data of the secret to be redacted, got %s", e.Payload)
+	}
+	e = actions["POST /api/ose/project/members"]
+	var payload map[string]interface{}
+	json.Unmarshal(e.Payload, &payload)
+	if payload["password"] != redacted || payload["username"] != "u456" || len(e.Descriptions) != 1 || e.Descriptions[0] != "u123 added u456 to project own" {
+		t.Errorf("expected the password to be redacted and the description kept, got %+v %v", e, payload)
+	}
+	if e := actions["GET /api/ose/project/export"]; e.Project != "own" || len(e.Descriptions) != 1 {
+		t.Errorf("expected the described GET to be recorded, got %+v", e)
+	}
+
+	if w := request("GET", "/api/auditlog?project=own", "u123", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected users not to see the audit log, got %v", w.Code)
+	}
+	w := request("GET", "/api/auditlog?project=own", "auditor", "")
+	var response struct{ Total int }
+	if json.Unmarshal(w.Body.Bytes(), &response); w.Code != http.StatusOK || response.Total != 3 {
+		t.Errorf("expected the entries of project own, got %v %v", w.Code, w.Body.String())
+	}
+	w = request("GET", "/api/auditlog?project=other", "auditor", "")
+	if json.Unmarshal(w.Body.Bytes(), &response); response.Total != 0 {
+		t.Errorf("expected no entries of project other, got %v", w.Body.String())
+	}
+}
+
+func TestFindReadsEntriesOfOlderVersions(t *testing.T) {
+	defer storetest.Setup(t)()
+
+	now := common.Now()
+	if err := store.Put(collection(now), "old", Entry{ID: "old", Time: now, Actor: "u123"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Record(Entry{Time: now, Actor: "u456"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Find(Query{From: now.AddDate(0, -1, 0), To: now.Add(time.Minute)})
+	if err != nil || len(entries) != 2 {
+		t.Errorf("expected the old and the new entry, got %+v %v", entries, err)
+	}
+}
@@ -0,0 +1,276 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	siemSyslog = "syslog"
+	siemSplunk = "splunk"
+
+	defaultSIEMBuffer    = 10000
+	defaultSIEMBatchSize = 100
+	siemTimeout          = 10 * time.Second
+	siemMaxBackoff       = time.Minute
+	// syslog facility local0, severity notice/warning
+	syslogNotice  = 16*8 + 5
+	syslogWarning = 16*8 + 4
+)
+
+// siemExporter ships the recorded entries to the SIEM in the background.
+// The entries are buffered while the SIEM isn't reachable, a batch is
+// retried until it's delivered. Entries are dropped if the buffer is full.
+// send returns the number of entries which were delivered before an error
+type siemExporter struct {
+	queue     chan Entry
+	send      func([]Entry) (int, error)
+	batchSize int
+	backoff   time.Duration
+}
+
+var exporter *siemExporter
+
+// StartSIEMExport starts the export of the audit log to the SIEM in
+// 'audit.siem.type': syslog (CEF over udp, tcp or tls) or splunk (HTTP
+// event collector)
+func StartSIEMExport() {
+	cfg := config.Config()
+	kind := cfg.GetString("audit.siem.type")
+	if kind == "" {
+		return
+	}
+	send, err := newSIEMSender(kind)
+	if err != nil {
+		log.Printf("WARNING: the audit log isn't exported: %v", err)
+		return
+	}
+	buffer := cfg.GetInt("audit.siem.buffer")
+	if buffer <= 0 {
+		buffer = defaultSIEMBuffer
+	}
+	batchSize := cfg.GetInt("audit.siem.batch_size")
+	if batchSize <= 0 {
+		batchSize = defaultSIEMBatchSize
+	}
+	exporter = newSIEMExporter(send, buffer, batchSize)
+	go exporter.run()
+	log.Printf("Exporting the audit log to %v", kind)
+}
+
+func newSIEMExporter(send func([]Entry) (int, error), buffer, batchSize int) *siemExporter {
+	return &siemExporter{queue: make(chan Entry, buffer), send: send, batchSize: batchSize, backoff: time.Second}
+}
+
+func newSIEMSender(kind string) (func([]Entry) (int, error), error) {
+	cfg := config.Config()
+	switch kind {
+	case siemSyslog:
+		address := cfg.GetString("audit.siem.address")
+		if address == "" {
+			return nil, errors.New("audit.siem.address is missing")
+		}
+		protocol := cfg.GetString("audit.siem.protocol")
+		if protocol == "" {
+			protocol = "udp"
+		}
+		return func(entries []Entry) (int, error) { return sendSyslog(protocol, address, entries) }, nil
+	case siemSplunk:
+		url := cfg.GetString("audit.siem.url")
+		if url == "" {
+			return nil, errors.New("audit.siem.url is missing")
+		}
+		// The client is reused, so the connection to splunk is kept alive
+		client := common.HTTPClient("siem")
+		client.Timeout = siemTimeout
+		return func(entries []Entry) (int, error) {
+			return sendSplunk(client, url, cfg.GetString("audit.siem.token"), cfg.GetString("audit.siem.index"), entries)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown siem type %v, must be %v or %v", kind, siemSyslog, siemSplunk)
+}
+
+// export queues the entry without blocking the request
+func (x *siemExporter) export(e Entry) {
+	select {
+	case x.queue <- e:
+	default:
+		log.Printf("WARNING: siem buffer is full, dropped the audit entry %v of %v", e.ID, e.Actor)
+	}
+}
+
+func (x *siemExporter) run() {
+	for {
+		batch := []Entry{<-x.queue}
+	collect:
+		for len(batch) < x.batchSize {
+			select {
+			case e := <-x.queue:
+				batch = append(batch, e)
+			default:
+				break collect
+			}
+		}
+		x.deliver(batch)
+	}
+}
+
+// deliver retries the batch with an exponential backoff until it's sent.
+// The entries which were already written to a stream aren't sent again
+func (x *siemExporter) deliver(batch []Entry) {
+	backoff := x.backoff
+	for {
+		sent, err := x.send(batch)
+		if err == nil {
+			return
+		}
+		batch = batch[sent:]
+		log.Printf("Error exporting %v audit entries to the siem, retrying in %v: %v", len(batch), backoff, err)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > siemMaxBackoff {
+			backoff = siemMaxBackoff
+		}
+	}
+}
+
+// sendSyslog writes one message per entry and returns the number of written
+// messages
+func sendSyslog(protocol, address string, entries []Entry) (int, error) {
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: siemTimeout}
+	if protocol == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, nil)
+	} else {
+		conn, err = dialer.Dial(protocol, address)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(siemTimeout))
+
+	hostname, _ := os.Hostname()
+	for i, e := range entries {
+		// Datagrams carry one message, streams are separated by newlines
+		message := syslogMessage(hostname, e)
+		if protocol != "udp" {
+			message += "\n"
+		}
+		if _, err := conn.Write([]byte(message)); err != nil {
+			return i, err
+		}
+	}
+	return len(entries), nil
+}
+
+// syslogMessage is the entry as CEF in a RFC 5424 syslog message
+func syslogMessage(hostname string, e Entry) string {
+	priority := syslogNotice
+	if e.Status >= 400 {
+		priority = syslogWarning
+	}
+	return fmt.Sprintf("<%v>1 %v %v ssp-backend - - - %v", priority, e.Time.UTC().Format(time.RFC3339), hostname, cefMessage(e))
+}
+
+func cefMessage(e Entry) string {
+	severity, outcome := 3, "success"
+	if e.Status >= 400 {
+		severity, outcome = 6, "failure"
+	}
+	extension := []string{
+		"rt=" + fmt.Sprint(e.Time.UnixNano()/int64(time.Millisecond)),
+		"suser=" + cefValue(e.Actor),
+		"act=" + cefValue(e.Action),
+		"outcome=" + outcome,
+		"cn1Label=httpStatus",
+		"cn1=" + fmt.Sprint(e.Status),
+		"cs1Label=cluster",
+		"cs1=" + cefValue(e.ClusterId),
+		"cs2Label=project",
+		"cs2=" + cefValue(e.Project),
+		"externalId=" + cefValue(e.ID),
+	}
+	if e.DryRun {
+		extension = append(extension, "cs3Label=dryRun", "cs3=true")
+	}
+	if e.Result != "" {
+		extension = append(extension, "msg="+cefValue(e.Result))
+	}
+	return fmt.Sprintf("CEF:0|SBB|Cloud SSP|1.0|%v|%v|%v|%v",
+		cefHeader(e.Action), cefHeader(e.Action), severity, strings.Join(extension, " "))
+}
+
+var (
+	cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefValueEscaper  = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+func cefHeader(s string) string {
+	return cefHeaderEscaper.Replace(s)
+}
+
+func cefValue(s string) string {
+	return cefValueEscaper.Replace(s)
+}
+
+type splunkEvent struct {
+	Time       float64 `json:"time"`
+	Host       string  `json:"host,omitempty"`
+	Source     string  `json:"source"`
+	Sourcetype string  `json:"sourcetype"`
+	Index      string  `json:"index,omitempty"`
+	Event      Entry   `json:"event"`
+}
+
+// sendSplunk posts the entries as one batch to the HTTP event collector. The
+// batch is delivered completely or not at all
+func sendSplunk(client *http.Client, url, token, index string, entries []Entry) (int, error) {
+	hostname, _ := os.Hostname()
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, e := range entries {
+		event := splunkEvent{
+			Time:       float64(e.Time.UnixNano()) / float64(time.Second),
+			Host:       hostname,
+			Source:     "ssp-backend",
+			Sourcetype: "ssp:audit",
+			Index:      index,
+			Event:      e,
+		}
+		if err := encoder.Encode(event); err != nil {
+			return 0, err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, &body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Splunk "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		return 0, fmt.Errorf("splunk returned %v %v", resp.StatusCode, string(errMsg))
+	}
+	return len(entries), nil
+}
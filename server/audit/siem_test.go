@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestCEFMessage(t *testing.T) {
+	e := Entry{
+		ID: "1", Time: time.Unix(1500000000, 0), Actor: "u123", Action: "POST /api/ose/quotas|x",
+		ClusterId: "prod", Project: "shop", Status: 400, Result: "CPU=4\nfehlgeschlagen",
+	}
+	expected := `CEF:0|SBB|Cloud SSP|1.0|POST /api/ose/quotas\|x|POST /api/ose/quotas\|x|6|rt=1500000000000 suser=u123 ` +
+		`act=POST /api/ose/quotas|x outcome=failure cn1Label=httpStatus cn1=400 cs1Label=cluster cs1=prod ` +
+		`cs2Label=project cs2=shop externalId=1 msg=CPU\=4\nfehlgeschlagen`
+	if message := cefMessage(e); message != expected {
+		t.Errorf("expected\n%v\ngot\n%v", expected, message)
+	}
+}
+
+func TestSplunkExport(t *testing.T) {
+	config.Init("test")
+	config.Config().Set("proxy.siem", "direct")
+	var mutex sync.Mutex
+	calls := 0
+	received := []Entry{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Authorization") != "Splunk secret" {
+			t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var event splunkEvent
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				t.Errorf("unexpected event %v: %v", scanner.Text(), err)
+			}
+			received = append(received, event.Event)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{}
+	x := newSIEMExporter(func(entries []Entry) (int, error) {
+		return sendSplunk(client, server.URL, "secret", "", entries)
+	}, 10, 10)
+	x.backoff = time.Millisecond
+	for _, actor := range []string{"u123", "u456"} {
+		x.export(Entry{Actor: actor, Action: "POST /api/ose/quotas", Time: time.Now()})
+	}
+	go x.run()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mutex.Lock()
+		done := len(received) == 2
+		mutex.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(received) != 2 || received[0].Actor != "u123" || calls != 2 {
+		t.Errorf("expected both entries to be delivered after a retry, got %+v after %v calls", received, calls)
+	}
+
+	full := newSIEMExporter(func(entries []Entry) (int, error) { return len(entries), nil }, 1, 1)
+	full.export(Entry{Actor: "u123"})
+	full.export(Entry{Actor: "u456"})
+	if len(full.queue) != 1 {
+		t.Errorf("expected entries to be dropped if the buffer is full, got %v", len(full.queue))
+	}
+}
+
+func TestDeliverSkipsSentEntries(t *testing.T) {
+	calls := [][]string{}
+	x := newSIEMExporter(func(entries []Entry) (int, error) {
+		actors := []string{}
+		for _, e := range entries {
+			actors = append(actors, e.Actor)
+		}
+		calls = append(calls, actors)
+		if len(calls) == 1 {
+			// The connection broke after the first entry
+			return 1, errors.New("broken pipe")
+		}
+		return len(entries), nil
+	}, 10, 10)
+	x.backoff = time.Millisecond
+
+	x.deliver([]Entry{{Actor: "u123"}, {Actor: "u456"}, {Actor: "u789"}})
+	if len(calls) != 2 || strings.Join(calls[1], ",") != "u456,u789" {
+		t.Errorf("expected only the unsent entries to be retried, got %v", calls)
+	}
+}
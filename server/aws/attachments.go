@@ -0,0 +1,65 @@
+package aws
+
+import (
+	"bytes"
+	"io/ioutil"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/approval"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	log "github.com/sirupsen/logrus"
+)
+
+// s3AttachmentStorage keeps the attachments of the approval requests in a bucket
+type s3AttachmentStorage struct {
+	bucket string
+	stage  string
+}
+
+func (s s3AttachmentStorage) Put(key string, data []byte) error {
+	svc, err := GetS3Client(s.stage)
+	if err != nil {
+		return err
+	}
+	_, err = svc.PutObject(&s3.PutObjectInput{
+		Bucket:               aws.String(s.bucket),
+		Key:                  aws.String(key),
+		Body:                 bytes.NewReader(data),
+		ContentType:          aws.String("application/pdf"),
+		ServerSideEncryption: aws.String(s3.ServerSideEncryptionAes256),
+	})
+	return err
+}
+
+func (s s3AttachmentStorage) Get(key string) ([]byte, error) {
+	svc, err := GetS3Client(s.stage)
+	if err != nil {
+		return nil, err
+	}
+	object, err := svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Body.Close()
+	return ioutil.ReadAll(object.Body)
+}
+
+// RegisterAttachmentStorage stores the attachments of the approval requests
+// in the bucket 'approval.attachments.bucket' if it's set
+func RegisterAttachmentStorage() {
+	cfg := config.Config()
+	bucket := cfg.GetString("approval.attachments.bucket")
+	if bucket == "" {
+		return
+	}
+	stage := cfg.GetString("approval.attachments.stage")
+	if stage == "" {
+		stage = stageProd
+	}
+	log.Printf("Storing the attachments of approval requests in bucket %v (%v)", bucket, stage)
+	approval.Attachments = s3AttachmentStorage{bucket: bucket, stage: stage}
+}
@@ -0,0 +1,62 @@
+package aws
+
+import (
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/catalog"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var s3BucketOffering = catalog.Offering{
+	Name:        "s3-bucket",
+	Title:       "AWS S3 Bucket",
+	Description: "S3 Bucket auf AWS. Benutzer für den Bucket können danach erstellt werden",
+	Schema: []byte(`{
+		"type": "object",
+		"required": ["project", "bucketname", "billing", "stage"],
+		"properties": {
+			"project": {"type": "string", "title": "Projekt"},
+			"bucketname": {"type": "string", "title": "Bucketname", "pattern": "^[a-zA-Z0-9-]+$"},
+			"billing": {"type": "string", "title": "Kontierungsnummer"},
+			"stage": {"type": "string", "title": "Umgebung", "enum": ["dev", "test", "int", "prod"]}
+		}
+	}`),
+}
+
+// s3BucketProvisioner doesn't delete buckets, because they could still
+// contain data
+type s3BucketProvisioner struct {
+	catalog.Unsupported
+}
+
+func (s3BucketProvisioner) Provision(r catalog.Request) (*catalog.Result, error) {
+	var data common.NewS3BucketCommand
+	if err := r.Decode(&data); err != nil {
+		return nil, err
+	}
+	name, err := newS3Bucket(r.Username, data)
+	if err != nil {
+		return nil, err
+	}
+	return &catalog.Result{ID: name, Message: "Es wurde ein neuer S3 Bucket erstellt: " + name}, nil
+}
+
+func (s3BucketProvisioner) Status(i catalog.Instance) (*catalog.Status, error) {
+	stage, _ := i.Parameters["stage"].(string)
+	svc, err := GetS3Client(stage)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := svc.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(i.ResourceID)}); err != nil {
+		log.Print("Error getting status of bucket " + i.ResourceID + ": " + err.Error())
+		return &catalog.Status{Ready: false, Message: "Der Bucket ist nicht erreichbar"}, nil
+	}
+	return &catalog.Status{Ready: true, Message: "Der Bucket ist bereit"}, nil
+}
+
+// RegisterCatalogOfferings adds the AWS services to the self-service catalog
+func RegisterCatalogOfferings() {
+	catalog.Register(s3BucketOffering, s3BucketProvisioner{})
+}
@@ -2,7 +2,6 @@ package aws
 
 import (
 	"errors"
-	"log"
 	"net/http"
 	"strings"
 
@@ -10,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -21,7 +21,7 @@ const (
 func listEC2InstancesHandler(c *gin.Context) {
 	username := common.GetUserName(c)
 
-	log.Println(username + " lists EC2 Instances")
+	common.Logger(c).Println(username + " lists EC2 Instances")
 
 	instances, err := listEC2InstancesByUsername(username)
 	if err != nil {
@@ -40,7 +40,7 @@ func deleteEC2InstanceSnapshotHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericAwsAPIError})
 		return
 	}
-	log.Println(username + " deleted snapshot " + snapshotid)
+	common.Logger(c).Println(username + " deleted snapshot " + snapshotid)
 	c.JSON(http.StatusOK, common.ApiResponse{Message: "Der Snapshot wurde erfolgreich gelöscht"})
 }
 
@@ -50,11 +50,11 @@ func createEC2InstanceSnapshotHandler(c *gin.Context) {
 	if c.BindJSON(&data) == nil {
 		snapshot, err := createSnapshot(data.VolumeId, data.InstanceId, data.Description, data.Account)
 		if err != nil {
-			log.Println(err)
+			common.Logger(c).Println(err)
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericAwsAPIError})
 			return
 		}
-		log.Println(username + " snapshots volume " + data.VolumeId + " in instance " + data.InstanceId)
+		common.Logger(c).Println(username + " snapshots volume " + data.VolumeId + " in instance " + data.InstanceId)
 		c.JSON(http.StatusOK, common.SnapshotApiResponse{Message: "Der Snapshot wurde erfolgreich erstellt: " + data.Description, Snapshot: *snapshot})
 		return
 	}
@@ -65,7 +65,7 @@ func setEC2InstanceStateHandler(c *gin.Context) {
 	username := common.GetUserName(c)
 	instanceid := c.Param("instanceid")
 	state := c.Param("state")
-	log.Print(username + " requested instance " + instanceid + " to " + state)
+	common.Logger(c).Print(username + " requested instance " + instanceid + " to " + state)
 	instance, err := getInstance(instanceid, username)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
@@ -2,7 +2,6 @@ package aws
 
 import (
 	"errors"
-	"log"
 	"regexp"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -10,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/iam"
 
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"html"
-	"log"
 	"net/http"
 	"regexp"
 	"strings"
@@ -19,6 +18,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -74,7 +74,7 @@ func validateNewS3Bucket(projectname string, bucketname string, billing string,
 func listS3BucketsHandler(c *gin.Context) {
 	username := common.GetUserName(c)
 
-	log.Print(username + " lists S3 buckets")
+	common.Logger(c).Print(username + " lists S3 buckets")
 
 	myBuckets, err := listS3BucketByUsername(username)
 	if err != nil {
@@ -89,21 +89,9 @@ func newS3BucketHandler(c *gin.Context) {
 
 	var data common.NewS3BucketCommand
 	if c.BindJSON(&data) == nil {
-		newbucketname, err := generateS3Bucketname(data.BucketName, data.Stage)
+		newbucketname, err := newS3Bucket(username, data)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
-			return
-		}
-
-		if err := validateNewS3Bucket(data.Project, newbucketname, data.Billing, data.Stage); err != nil {
-			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
-			return
-		}
-
-		log.Print("Creating new bucket " + newbucketname + " for " + username)
-
-		if err := createNewS3Bucket(username, data.Project, newbucketname, data.Billing, data.Stage); err != nil {
-			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 		} else {
 			c.JSON(http.StatusOK, common.ApiResponse{
 				Message: "Es wurde ein neuer S3 Bucket erstellt: " + newbucketname +
@@ -115,6 +103,22 @@ func newS3BucketHandler(c *gin.Context) {
 	}
 }
 
+// newS3Bucket validates and creates the bucket. Returns the generated name
+func newS3Bucket(username string, data common.NewS3BucketCommand) (string, error) {
+	newbucketname, err := generateS3Bucketname(data.BucketName, data.Stage)
+	if err != nil {
+		return "", err
+	}
+
+	if err := validateNewS3Bucket(data.Project, newbucketname, data.Billing, data.Stage); err != nil {
+		return "", err
+	}
+
+	log.Print("Creating new bucket " + newbucketname + " for " + username)
+
+	return newbucketname, createNewS3Bucket(username, data.Project, newbucketname, data.Billing, data.Stage)
+}
+
 func newS3UserHandler(c *gin.Context) {
 	username := common.GetUserName(c)
 	bucketName := c.Param("bucketname")
@@ -141,7 +145,7 @@ func newS3UserHandler(c *gin.Context) {
 		return
 	}
 
-	log.Print(username + " creates a new user (" + data.UserName + ") for " + bucketName + " , readonly: " + strconv.FormatBool(data.IsReadonly))
+	common.Logger(c).Print(username + " creates a new user (" + data.UserName + ") for " + bucketName + " , readonly: " + strconv.FormatBool(data.IsReadonly))
 
 	credentials, err := createNewS3User(bucketName, data.UserName, stage, data.IsReadonly)
 	if err != nil {
@@ -2,7 +2,6 @@ package aws
 
 import (
 	"errors"
-	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -16,6 +15,7 @@ import (
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
 
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -150,7 +150,8 @@ func getAwsSession(account string) (*session.Session, error) {
 
 	sess, err := session.NewSession(&aws.Config{
 		Credentials: credentials.NewStaticCredentials(accessKeyID, accessSecret, ""),
-		Region:      aws.String(region)},
+		Region:      aws.String(region),
+		HTTPClient:  common.HTTPClient("aws")},
 	)
 
 	if err != nil {
@@ -0,0 +1,135 @@
+// Package catalog is the self-service catalog of the portal. Every offering
+// (e.g. S3 bucket, Logsene app) declares a json schema, which the frontend
+// renders as form, and a provisioner which creates the service.
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const wrongAPIUsageError = "Ungültiger API-Aufruf: Die Argumente stimmen nicht mit der definition überein. Bitte erstelle eine Ticket"
+
+type provisionResponse struct {
+	Message  string    `json:"message"`
+	Instance *Instance `json:"instance,omitempty"`
+}
+
+// Offering is an entry of the catalog
+type Offering struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Schema is the json schema of the parameters
+	Schema json.RawMessage `json:"schema"`
+}
+
+// Request are the parameters of the form, validated against the schema
+type Request struct {
+	Username   string
+	Mail       string
+	Parameters map[string]interface{}
+}
+
+// Decode converts the parameters into the command of the provisioner
+func (r Request) Decode(target interface{}) error {
+	data, err := json.Marshal(r.Parameters)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+type entry struct {
+	offering    Offering
+	provisioner Provisioner
+}
+
+var offerings = make(map[string]entry)
+
+// Register adds the offering to the catalog. It must be called at startup
+// and panics if the name is used twice or the schema is invalid
+func Register(o Offering, p Provisioner) {
+	if _, ok := offerings[o.Name]; ok {
+		panic(fmt.Sprintf("catalog: offering %v registered twice", o.Name))
+	}
+	if _, err := parseSchema(o.Schema); err != nil {
+		panic(fmt.Sprintf("catalog: invalid schema of offering %v: %v", o.Name, err))
+	}
+	offerings[o.Name] = entry{offering: o, provisioner: p}
+}
+
+func RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/catalog", getOfferingsHandler)
+	r.GET("/catalog/:name", getOfferingHandler)
+	r.POST("/catalog/:name", provisionHandler)
+	r.GET("/catalog/:name/instances", getInstancesHandler)
+	r.GET("/catalog/:name/instances/:id", getInstanceHandler)
+	r.PUT("/catalog/:name/instances/:id", updateInstanceHandler)
+	r.DELETE("/catalog/:name/instances/:id", deprovisionInstanceHandler)
+}
+
+func getOfferingsHandler(c *gin.Context) {
+	list := []Offering{}
+	for _, e := range offerings {
+		list = append(list, e.offering)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	c.JSON(http.StatusOK, list)
+}
+
+func getOfferingHandler(c *gin.Context) {
+	e, ok := offerings[c.Param("name")]
+	if !ok {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: "Das Angebot existiert nicht"})
+		return
+	}
+	c.JSON(http.StatusOK, e.offering)
+}
+
+func provisionHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	e, ok := offerings[c.Param("name")]
+	if !ok {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: "Das Angebot existiert nicht"})
+		return
+	}
+
+	var parameters map[string]interface{}
+	if c.BindJSON(&parameters) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateParameters(e.offering.Schema, parameters); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	result, err := e.provisioner.Provision(Request{
+		Username:   username,
+		Mail:       common.GetUserMail(c),
+		Parameters: parameters,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	instance, err := saveInstance(e.offering.Name, result.ID, username, parameters)
+	if err != nil {
+		// The service exists, but can't be managed by the catalog
+		common.Logger(c).Printf("Error saving instance %v of %v: %v", result.ID, e.offering.Name, err)
+	}
+
+	audit.Describe(c, "%v provisioned %v from the catalog", username, e.offering.Name)
+	c.JSON(http.StatusOK, provisionResponse{Message: result.Message, Instance: instance})
+}
@@ -0,0 +1,30 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testProvisioner struct {
+	Unsupported
+}
+
+func (testProvisioner) Provision(r Request) (*Result, error) {
+	return &Result{ID: "test"}, nil
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	// The router panics if the routes conflict
+	RegisterRoutes(gin.New().Group("/api"))
+}
+
+func TestUnsupportedOperations(t *testing.T) {
+	var p Provisioner = testProvisioner{}
+	if _, err := p.Status(Instance{}); err != ErrNotSupported {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+	if err := p.Deprovision(Instance{}, Request{}); err != ErrNotSupported {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
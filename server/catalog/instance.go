@@ -0,0 +1,183 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+const instancesCollection = "catalog_instances"
+
+// Instance is a service provisioned from the catalog
+type Instance struct {
+	ID         string                 `json:"id"`
+	Offering   string                 `json:"offering"`
+	ResourceID string                 `json:"resourceId"`
+	Owner      string                 `json:"owner"`
+	Parameters map[string]interface{} `json:"parameters"`
+	CreatedAt  time.Time              `json:"createdAt"`
+	UpdatedAt  *time.Time             `json:"updatedAt,omitempty"`
+}
+
+type instanceResponse struct {
+	Instance
+	Status *Status `json:"status,omitempty"`
+}
+
+func saveInstance(offering, resourceID, owner string, parameters map[string]interface{}) (*Instance, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	instance := &Instance{
+		ID:         id.String(),
+		Offering:   offering,
+		ResourceID: resourceID,
+		Owner:      strings.ToLower(owner),
+		Parameters: parameters,
+		CreatedAt:  time.Now(),
+	}
+	return instance, store.Put(instancesCollection, instance.ID, instance)
+}
+
+// getOwnInstance returns the instance of the offering and its provisioner if
+// the user owns it
+func getOwnInstance(offering, id, username string) (*Instance, Provisioner, error) {
+	var instance Instance
+	found, err := store.Get(instancesCollection, id, &instance)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found || instance.Offering != offering || (instance.Owner != strings.ToLower(username) && !common.IsPortalAdmin(username)) {
+		return nil, nil, errors.New("Die Instanz existiert nicht oder gehört dir nicht")
+	}
+	e, ok := offerings[instance.Offering]
+	if !ok {
+		return nil, nil, errors.New("Das Angebot der Instanz existiert nicht mehr")
+	}
+	return &instance, e.provisioner, nil
+}
+
+func getInstancesHandler(c *gin.Context) {
+	username := strings.ToLower(common.GetUserName(c))
+
+	instances := []Instance{}
+	err := store.List(instancesCollection, func(id string, data []byte) error {
+		var i Instance
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+		if i.Offering == c.Param("name") && i.Owner == username {
+			instances = append(instances, i)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].CreatedAt.After(instances[j].CreatedAt)
+	})
+	c.JSON(http.StatusOK, instances)
+}
+
+func getInstanceHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	instance, provisioner, err := getOwnInstance(c.Param("name"), c.Param("id"), username)
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	response := instanceResponse{Instance: *instance}
+	status, err := provisioner.Status(*instance)
+	if err != nil && err != ErrNotSupported {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	response.Status = status
+	c.JSON(http.StatusOK, response)
+}
+
+func updateInstanceHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	instance, provisioner, err := getOwnInstance(c.Param("name"), c.Param("id"), username)
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	var parameters map[string]interface{}
+	if c.BindJSON(&parameters) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateParameters(offerings[instance.Offering].offering.Schema, parameters); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	result, err := provisioner.Update(*instance, Request{
+		Username:   username,
+		Mail:       common.GetUserMail(c),
+		Parameters: parameters,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	updatedAt := time.Now()
+	instance.Parameters = parameters
+	instance.UpdatedAt = &updatedAt
+	if result.ID != "" {
+		instance.ResourceID = result.ID
+	}
+	if err := store.Put(instancesCollection, instance.ID, instance); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v updated the instance %v of %v", username, instance.ID, instance.Offering)
+	c.JSON(http.StatusOK, provisionResponse{Message: result.Message, Instance: instance})
+}
+
+func deprovisionInstanceHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	instance, provisioner, err := getOwnInstance(c.Param("name"), c.Param("id"), username)
+	if err != nil {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	err = provisioner.Deprovision(*instance, Request{
+		Username:   username,
+		Mail:       common.GetUserMail(c),
+		Parameters: instance.Parameters,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err := store.Delete(instancesCollection, instance.ID); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v deprovisioned the instance %v of %v", username, instance.ID, instance.Offering)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: "Die Instanz wurde gelöscht"})
+}
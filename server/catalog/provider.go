@@ -0,0 +1,45 @@
+package catalog
+
+import "errors"
+
+// ErrNotSupported is returned by providers for operations of the lifecycle
+// they don't implement
+var ErrNotSupported = errors.New("Diese Aktion wird für dieses Angebot nicht unterstützt")
+
+// Result is returned by the provisioner to the user
+type Result struct {
+	// ID identifies the service at the backend, e.g. the name of the bucket
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// Status is the state of a service at the backend
+type Status struct {
+	Ready   bool   `json:"ready"`
+	Message string `json:"message"`
+}
+
+// Provisioner manages the lifecycle of the services of an offering. New
+// backends implement it in their own package and call Register at startup
+type Provisioner interface {
+	Provision(r Request) (*Result, error)
+	Update(i Instance, r Request) (*Result, error)
+	Deprovision(i Instance, r Request) error
+	Status(i Instance) (*Status, error)
+}
+
+// Unsupported can be embedded by provisioners which only implement a part of
+// the lifecycle. The missing operations return ErrNotSupported
+type Unsupported struct{}
+
+func (Unsupported) Update(i Instance, r Request) (*Result, error) {
+	return nil, ErrNotSupported
+}
+
+func (Unsupported) Deprovision(i Instance, r Request) error {
+	return ErrNotSupported
+}
+
+func (Unsupported) Status(i Instance) (*Status, error) {
+	return nil, ErrNotSupported
+}
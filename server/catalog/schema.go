@@ -0,0 +1,129 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+)
+
+// schema is the subset of json schema which is supported for the forms:
+// an object with properties of the types string, integer, number and boolean
+type schema struct {
+	Type       string              `json:"type"`
+	Required   []string            `json:"required"`
+	Properties map[string]property `json:"properties"`
+}
+
+type property struct {
+	Type      string        `json:"type"`
+	Title     string        `json:"title"`
+	Enum      []interface{} `json:"enum"`
+	Pattern   string        `json:"pattern"`
+	MinLength *int          `json:"minLength"`
+	MaxLength *int          `json:"maxLength"`
+	Minimum   *float64      `json:"minimum"`
+	Maximum   *float64      `json:"maximum"`
+}
+
+func parseSchema(data []byte) (*schema, error) {
+	var s schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.Type != "object" {
+		return nil, errors.New("the type of the schema must be object")
+	}
+	for name, p := range s.Properties {
+		switch p.Type {
+		case "string", "integer", "number", "boolean":
+		default:
+			return nil, fmt.Errorf("unsupported type %v of property %v", p.Type, name)
+		}
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern of property %v: %v", name, err)
+		}
+	}
+	return &s, nil
+}
+
+// validateParameters checks the parameters against the schema. Unknown
+// parameters are not allowed
+func validateParameters(data []byte, parameters map[string]interface{}) error {
+	s, err := parseSchema(data)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range s.Required {
+		if v, ok := parameters[name]; !ok || v == nil || v == "" {
+			return fmt.Errorf("Das Feld %v muss angegeben werden", s.Properties[name].label(name))
+		}
+	}
+
+	for name, value := range parameters {
+		p, ok := s.Properties[name]
+		if !ok {
+			return fmt.Errorf("Das Feld %v ist nicht erlaubt", name)
+		}
+		if err := p.validate(value); err != nil {
+			return fmt.Errorf("Das Feld %v %v", p.label(name), err.Error())
+		}
+	}
+	return nil
+}
+
+func (p property) label(name string) string {
+	if p.Title != "" {
+		return p.Title
+	}
+	return name
+}
+
+func (p property) validate(value interface{}) error {
+	switch p.Type {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return errors.New("muss ein Text sein")
+		}
+		if p.MinLength != nil && len(s) < *p.MinLength {
+			return fmt.Errorf("muss mindestens %v Zeichen lang sein", *p.MinLength)
+		}
+		if p.MaxLength != nil && len(s) > *p.MaxLength {
+			return fmt.Errorf("darf höchstens %v Zeichen lang sein", *p.MaxLength)
+		}
+		if p.Pattern != "" && !regexp.MustCompile(p.Pattern).MatchString(s) {
+			return errors.New("hat ein ungültiges Format")
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return errors.New("muss eine Zahl sein")
+		}
+		if p.Type == "integer" && n != math.Trunc(n) {
+			return errors.New("muss eine ganze Zahl sein")
+		}
+		if p.Minimum != nil && n < *p.Minimum {
+			return fmt.Errorf("muss mindestens %v sein", *p.Minimum)
+		}
+		if p.Maximum != nil && n > *p.Maximum {
+			return fmt.Errorf("darf höchstens %v sein", *p.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return errors.New("muss ja oder nein sein")
+		}
+	}
+
+	if len(p.Enum) > 0 {
+		for _, e := range p.Enum {
+			if e == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("muss einer der Werte %v sein", p.Enum)
+	}
+	return nil
+}
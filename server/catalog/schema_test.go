@@ -0,0 +1,51 @@
+package catalog
+
+import "testing"
+
+var testSchema = []byte(`{
+	"type": "object",
+	"required": ["name", "stage"],
+	"properties": {
+		"name": {"type": "string", "title": "Name", "pattern": "^[a-z0-9-]+$", "maxLength": 10},
+		"stage": {"type": "string", "enum": ["dev", "prod"]},
+		"size": {"type": "integer", "minimum": 1, "maximum": 100},
+		"backup": {"type": "boolean"}
+	}
+}`)
+
+func TestValidateParameters(t *testing.T) {
+	tests := []struct {
+		parameters map[string]interface{}
+		valid      bool
+	}{
+		{map[string]interface{}{"name": "abc", "stage": "dev"}, true},
+		{map[string]interface{}{"name": "abc", "stage": "prod", "size": 10.0, "backup": true}, true},
+		{map[string]interface{}{"name": "abc"}, false},
+		{map[string]interface{}{"name": "", "stage": "dev"}, false},
+		{map[string]interface{}{"name": "ABC", "stage": "dev"}, false},
+		{map[string]interface{}{"name": "abcdefghijk", "stage": "dev"}, false},
+		{map[string]interface{}{"name": "abc", "stage": "int"}, false},
+		{map[string]interface{}{"name": "abc", "stage": "dev", "size": 1.5}, false},
+		{map[string]interface{}{"name": "abc", "stage": "dev", "size": 101.0}, false},
+		{map[string]interface{}{"name": "abc", "stage": "dev", "backup": "yes"}, false},
+		{map[string]interface{}{"name": "abc", "stage": "dev", "unknown": 1.0}, false},
+	}
+	for _, test := range tests {
+		err := validateParameters(testSchema, test.parameters)
+		if test.valid && err != nil {
+			t.Errorf("expected %v to be valid, got %v", test.parameters, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("expected %v to be invalid", test.parameters)
+		}
+	}
+}
+
+func TestParseSchemaRejectsUnsupportedTypes(t *testing.T) {
+	if _, err := parseSchema([]byte(`{"type": "object", "properties": {"a": {"type": "array"}}}`)); err == nil {
+		t.Error("expected an error for type array")
+	}
+	if _, err := parseSchema([]byte(`{"type": "string"}`)); err == nil {
+		t.Error("expected an error for a schema which isn't an object")
+	}
+}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	noPortalAdminError   = "Diese Funktion ist nur für Administratoren des Portals verfügbar"
+	noPortalAuditorError = "Diese Funktion ist nur für Auditoren des Portals verfügbar"
+	readOnlyError        = "Auditoren haben nur lesenden Zugriff"
+)
+
+// IsPortalAdmin returns true if the user is listed in 'portal_admins'
+func IsPortalAdmin(username string) bool {
+	return isListedIn("portal_admins", username)
+}
+
+// IsPortalAuditor returns true if the user is listed in 'portal_auditors'.
+// Auditors can read everything but can't change anything
+func IsPortalAuditor(username string) bool {
+	return isListedIn("portal_auditors", username)
+}
+
+func isListedIn(key, username string) bool {
+	for _, user := range config.Config().GetStringSlice(key) {
+		if strings.ToLower(strings.TrimSpace(user)) == strings.ToLower(username) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePortalAdmin is a gin middleware which only allows portal admins
+func RequirePortalAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username := GetUserName(c)
+		if !IsPortalAdmin(username) {
+			Logger(c).Warnf("%v tried to call admin endpoint %v", username, c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, ApiResponse{Message: noPortalAdminError})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePortalAuditor is a gin middleware which only allows reading requests
+// of portal auditors and admins
+func RequirePortalAuditor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username := GetUserName(c)
+		if !IsPortalAuditor(username) && !IsPortalAdmin(username) {
+			Logger(c).Warnf("%v tried to call audit endpoint %v", username, c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, ApiResponse{Message: noPortalAuditorError})
+			return
+		}
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.AbortWithStatusJSON(http.StatusForbidden, ApiResponse{Message: readOnlyError})
+			return
+		}
+		c.Next()
+	}
+}
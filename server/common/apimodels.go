@@ -37,12 +37,175 @@ type NewProjectCommand struct {
 	OpenshiftBase
 	Billing string `json:"billing"`
 	MegaId  string `json:"megaId"`
+	// Template of the catalog (project_templates.namespace) which is
+	// instantiated in the new project
+	Template   string            `json:"template"`
+	Parameters map[string]string `json:"parameters"`
+	// CPU and Memory are the initial quota. Without them the project gets
+	// the default quota of the cluster
+	CPU    int `json:"cpu"`
+	Memory int `json:"memory"`
+}
+
+// ProjectDependencyCommand declares that the project depends on the target
+// project or on the external service
+type ProjectDependencyCommand struct {
+	OpenshiftBase
+	TargetClusterId string `json:"targetClusterid"`
+	TargetProject   string `json:"targetProject"`
+	Service         string `json:"service"`
+	Description     string `json:"description"`
 }
 
 type NewTestProjectCommand struct {
 	OpenshiftBase
 }
 
+type NewSandboxProjectCommand struct {
+	OpenshiftBase
+}
+
+type LegalHoldCommand struct {
+	OpenshiftBase
+	Reason string `json:"reason"`
+}
+
+type ClassificationCommand struct {
+	OpenshiftBase
+	Classification string `json:"classification"`
+}
+
+type ClassificationResponse struct {
+	Classification string   `json:"classification"`
+	Violations     []string `json:"violations"`
+}
+
+// MegaIdCommand sets the MEGA ID of a project, an empty MEGA ID clears it
+type MegaIdCommand struct {
+	OpenshiftBase
+	MegaId string `json:"megaId"`
+}
+
+// BreakGlassCommand requests temporary admin access to a project during an incident
+type BreakGlassCommand struct {
+	OpenshiftBase
+	Incident string `json:"incident"`
+	Reason   string `json:"reason"`
+}
+
+// VanityURLCommand maps the short path to a route of the project
+type VanityURLCommand struct {
+	OpenshiftBase
+	Path  string `json:"path"`
+	Route string `json:"route"`
+}
+
+// CustomResourceCommand enables a whitelisted custom resource in the project
+type CustomResourceCommand struct {
+	OpenshiftBase
+	Name string `json:"name"`
+}
+
+type SmtpRelayRequestCommand struct {
+	OpenshiftBase
+	Reason string `json:"reason"`
+}
+
+type AdoptProjectCommand struct {
+	OpenshiftBase
+	Reason string `json:"reason"`
+}
+
+// DeleteProjectCommand deletes an own project. The project name has to be
+// typed again as confirmation
+type DeleteProjectCommand struct {
+	OpenshiftBase
+	Confirmation string `json:"confirmation"`
+}
+
+type AdminProjectDeletionCommand struct {
+	OpenshiftBase
+	Reason string `json:"reason"`
+}
+
+// AddGroupPermissionCommand grants the role (admin if empty) to an
+// OpenShift group, e.g. synchronized from the LDAP
+type AddGroupPermissionCommand struct {
+	OpenshiftBase
+	Group string `json:"group"`
+	Role  string `json:"role"`
+}
+
+// ProjectMemberCommand grants or revokes the role (admin, edit or view)
+type ProjectMemberCommand struct {
+	OpenshiftBase
+	User string `json:"user"`
+	Role string `json:"role"`
+}
+
+type ProjectMembersResponse struct {
+	Admins  []string `json:"admins"`
+	Editors []string `json:"editors"`
+	Viewers []string `json:"viewers"`
+}
+
+// TeamMembersCommand adds or removes a user or group in all projects of a
+// team. The team is selected by a namespace label (e.g. team=abc) or billing
+type TeamMembersCommand struct {
+	ClusterId string `json:"clusterid"`
+	Label     string `json:"label"`
+	Billing   string `json:"billing"`
+	User      string `json:"user"`
+	Group     string `json:"group"`
+	Role      string `json:"role"`
+	Remove    bool   `json:"remove"`
+}
+
+type TeamMemberResult struct {
+	ClusterId string `json:"clusterid"`
+	Project   string `json:"project"`
+	Success   bool   `json:"success"`
+	Message   string `json:"message,omitempty"`
+}
+
+type TeamMembersResponse struct {
+	Message string             `json:"message"`
+	Results []TeamMemberResult `json:"results"`
+	Errors  []string           `json:"errors,omitempty"`
+}
+
+type OffboardingCommand struct {
+	User   string `json:"user"`
+	Reason string `json:"reason"`
+}
+
+type DecisionCommand struct {
+	Comment string `json:"comment"`
+}
+
+type ReserveEgressIPCommand struct {
+	OpenshiftBase
+	Purpose string `json:"purpose"`
+}
+
+type ProjectBudgetCommand struct {
+	OpenshiftBase
+	Amount             float64 `json:"amount"`
+	BlockQuotaIncrease bool    `json:"blockQuotaIncrease"`
+}
+
+type AccessReviewDecision struct {
+	User    string `json:"user"`
+	Role    string `json:"role"`
+	Confirm bool   `json:"confirm"`
+}
+
+type AccessReviewCommand struct {
+	OpenshiftBase
+	Campaign  string                 `json:"campaign"`
+	Decisions []AccessReviewDecision `json:"decisions"`
+}
+
 type EditLogseneBillingDataCommand struct {
 	OpenshiftBase
 	Billing string `json:"billing"`
@@ -72,16 +235,99 @@ type EditQuotasCommand struct {
 	Memory int `json:"memory"`
 }
 
+// LimitRangeCommand has the defaults of the containers as quantities, e.g.
+// cpu "500m" and memory "512Mi"
+type LimitRangeCommand struct {
+	OpenshiftBase
+	LimitRange
+}
+
+type LimitRange struct {
+	DefaultCPU           string `json:"defaultCpu"`
+	DefaultMemory        string `json:"defaultMemory"`
+	DefaultRequestCPU    string `json:"defaultRequestCpu"`
+	DefaultRequestMemory string `json:"defaultRequestMemory"`
+}
+
+// LimitRangeResponse has the maxima in cores and Gi
+type LimitRangeResponse struct {
+	LimitRange
+	MaxCPU    float64 `json:"maxCpu"`
+	MaxMemory float64 `json:"maxMemory"`
+}
+
+// QuotasResponse has the cpu in cores and the memory in Gi
+type QuotasResponse struct {
+	CPU        float64 `json:"cpu"`
+	Memory     float64 `json:"memory"`
+	UsedCPU    float64 `json:"usedCpu"`
+	UsedMemory float64 `json:"usedMemory"`
+	MaxCPU     int     `json:"maxCpu"`
+	MaxMemory  int     `json:"maxMemory"`
+}
+
+// LoadTestWindowCommand boosts the quota of the project to CPU and Memory
+// between Start and End
+type LoadTestWindowCommand struct {
+	OpenshiftBase
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	CPU    int       `json:"cpu"`
+	Memory int       `json:"memory"`
+}
+
 type NewServiceAccountCommand struct {
 	OpenshiftBase
 	ServiceAccount  string `json:"serviceAccount"`
 	OrganizationKey string `json:"organizationKey"`
+	// EditRole binds the service account to the edit role of the project
+	EditRole bool `json:"editRole"`
+	// Token returns a token of the service account, e.g. for a ci pipeline
+	Token bool `json:"token"`
+}
+
+// KubeconfigCommand selects the projects of the kubeconfig, all projects the
+// user administrates on the cluster if empty. Mode is serviceaccount (a token
+// of a service account per project, the default) or user (the user's token)
+type KubeconfigCommand struct {
+	ClusterId string   `json:"clusterid"`
+	Projects  []string `json:"projects"`
+	Mode      string   `json:"mode"`
+}
+
+type NewServiceAccountResponse struct {
+	Message   string     `json:"message"`
+	Token     string     `json:"token,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 type NewPullSecretCommand struct {
 	OpenshiftBase
 	Username string
 	Password string
+	// Name defaults to external-registry and Registry to 'docker_repository'
+	Name     string `json:"name"`
+	Registry string `json:"registry"`
+}
+
+type PullSecret struct {
+	Name       string   `json:"name"`
+	Registries []string `json:"registries"`
+	// Linked is true if the default service account uses the secret
+	Linked bool `json:"linked"`
+}
+
+type NewSecretCommand struct {
+	OpenshiftBase
+	Name    string            `json:"name"`
+	Data    map[string]string `json:"data"`
+	Expires string            `json:"expires"`
+}
+
+type SecretExpiryCommand struct {
+	OpenshiftBase
+	Name    string `json:"name"`
+	Expires string `json:"expires"`
 }
 
 type CreateSnapshotCommand struct {
@@ -238,3 +484,161 @@ type JsonPatch struct {
 	Path      string      `json:"path"`
 	Value     interface{} `json:"value"`
 }
+
+type PermissionCheck struct {
+	Project string `json:"project"`
+	Verb    string `json:"verb"`
+}
+
+type PermissionChecksCommand struct {
+	ClusterId string            `json:"clusterid"`
+	Checks    []PermissionCheck `json:"checks"`
+}
+
+type PermissionCheckResult struct {
+	PermissionCheck
+	Allowed bool   `json:"allowed"`
+	Message string `json:"message,omitempty"`
+}
+
+type PermissionChecksResponse struct {
+	Results []PermissionCheckResult `json:"results"`
+}
+
+type RepairProjectCommand struct {
+	OpenshiftBase
+	Billing string `json:"billing"`
+	Owner   string `json:"owner"`
+}
+
+type RepairStep struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type RepairProjectResponse struct {
+	Project string       `json:"project"`
+	Steps   []RepairStep `json:"steps"`
+}
+
+// ImportProject is a namespace which wasn't created by the portal. Billing
+// and owner are read from the annotations if empty
+type ImportProject struct {
+	Project string `json:"project"`
+	Billing string `json:"billing"`
+	Owner   string `json:"owner"`
+}
+
+type ImportProjectsCommand struct {
+	ClusterId string          `json:"clusterid"`
+	Projects  []ImportProject `json:"projects"`
+}
+
+type ImportProjectResult struct {
+	Project  string       `json:"project"`
+	Imported bool         `json:"imported"`
+	Missing  []string     `json:"missing,omitempty"`
+	Message  string       `json:"message,omitempty"`
+	Steps    []RepairStep `json:"steps,omitempty"`
+}
+
+// UnmanagedProject is a namespace without the billing or owner of the portal
+type UnmanagedProject struct {
+	ClusterId string   `json:"clusterid"`
+	Project   string   `json:"project"`
+	Billing   string   `json:"billing"`
+	Owner     string   `json:"owner"`
+	Missing   []string `json:"missing"`
+}
+
+// MergeBillingCommand re-maps the billing account From to To
+type MergeBillingCommand struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// UpgradeImpactCommand selects the projects on ClusterId which are affected by
+// an upgrade: projects with pods on the nodes matching NodeSelector, volumes of
+// the StorageClasses or objects of the deprecated APIs, e.g.
+// "apis/extensions/v1beta1/deployments". Their admins get the Actions
+type UpgradeImpactCommand struct {
+	ClusterId      string   `json:"clusterid"`
+	NodeSelector   string   `json:"nodeSelector"`
+	StorageClasses []string `json:"storageClasses"`
+	APIs           []string `json:"apis"`
+	Subject        string   `json:"subject"`
+	Actions        string   `json:"actions"`
+}
+
+type UpgradeImpactProject struct {
+	ClusterId string   `json:"clusterid"`
+	Project   string   `json:"project"`
+	Reasons   []string `json:"reasons"`
+	Notified  bool     `json:"notified"`
+	Message   string   `json:"message,omitempty"`
+}
+
+type UpgradeImpactResponse struct {
+	Message  string                 `json:"message"`
+	Projects []UpgradeImpactProject `json:"projects"`
+}
+
+// BulkBillingCommand sets the billing account of all Projects
+type BulkBillingCommand struct {
+	Billing  string          `json:"billing"`
+	Projects []OpenshiftBase `json:"projects"`
+}
+
+type BulkBillingResult struct {
+	ClusterId string `json:"clusterid"`
+	Project   string `json:"project"`
+	Success   bool   `json:"success"`
+	Message   string `json:"message,omitempty"`
+}
+
+type BulkBillingResponse struct {
+	Message string              `json:"message"`
+	Results []BulkBillingResult `json:"results"`
+}
+
+type MergeBillingResponse struct {
+	Message string          `json:"message"`
+	Changes []PlannedChange `json:"changes"`
+}
+
+type NewWorkshopCommand struct {
+	ClusterId         string   `json:"clusterid"`
+	Name              string   `json:"name"`
+	Count             int      `json:"count"`
+	Template          string   `json:"template"`
+	TemplateNamespace string   `json:"templateNamespace"`
+	Attendees         []string `json:"attendees"`
+}
+
+// ProjectSpec is the declarative definition of a project managed by the portal
+type ProjectSpec struct {
+	Billing     string     `json:"billing,omitempty"`
+	MegaId      string     `json:"megaId,omitempty"`
+	Quota       *QuotaSpec `json:"quota,omitempty"`
+	Admins      []string   `json:"admins,omitempty"`
+	Editors     []string   `json:"editors,omitempty"`
+	AutoCorrect bool       `json:"autoCorrect"`
+}
+
+type QuotaSpec struct {
+	CPU    int `json:"cpu"`
+	Memory int `json:"memory"`
+}
+
+type ApplyProjectSpecCommand struct {
+	OpenshiftBase
+	Spec ProjectSpec `json:"spec"`
+}
+
+// DriftItem is a difference between the spec and the live state of a project
+type DriftItem struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
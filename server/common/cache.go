@@ -0,0 +1,165 @@
+package common
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/go-redis/redis"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultCacheTTLSeconds = 60
+
+// Cache is a simple key value cache for responses of backend apis
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	// Delete removes all entries whose key starts with prefix
+	Delete(prefix string)
+}
+
+var (
+	cache     Cache
+	cacheOnce sync.Once
+)
+
+// GetCache returns the configured cache. If 'cache_redis_url' is set,
+// redis is used, otherwise the entries are kept in memory
+func GetCache() Cache {
+	cacheOnce.Do(func() {
+		cfg := config.Config()
+		ttl := cfg.GetInt("cache_ttl_seconds")
+		if ttl == 0 {
+			ttl = defaultCacheTTLSeconds
+		}
+		// A negative ttl disables the cache
+		if ttl < 0 {
+			cache = noCache{}
+			return
+		}
+
+		redisURL := cfg.GetString("cache_redis_url")
+		if redisURL == "" {
+			cache = newMemoryCache(time.Duration(ttl) * time.Second)
+			return
+		}
+
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			log.Printf("WARNING: invalid cache_redis_url, falling back to in-memory cache: %v", err)
+			cache = newMemoryCache(time.Duration(ttl) * time.Second)
+			return
+		}
+		cache = &redisCache{
+			client: redis.NewClient(opts),
+			ttl:    time.Duration(ttl) * time.Second,
+		}
+	})
+	return cache
+}
+
+type noCache struct{}
+
+func (noCache) Get(key string) ([]byte, bool) { return nil, false }
+func (noCache) Set(key string, value []byte)  {}
+func (noCache) Delete(prefix string)          {}
+
+type cacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+type memoryCache struct {
+	sync.RWMutex
+	entries map[string]cacheEntry
+	ttl     time.Duration
+	// nextSweep is the time the expired entries are removed next
+	nextSweep time.Time
+}
+
+func newMemoryCache(ttl time.Duration) *memoryCache {
+	return &memoryCache{
+		entries: make(map[string]cacheEntry),
+		ttl:     ttl,
+	}
+}
+
+func (m *memoryCache) Get(key string) ([]byte, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	entry, ok := m.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (m *memoryCache) Set(key string, value []byte) {
+	m.Lock()
+	defer m.Unlock()
+
+	now := time.Now()
+	// Drop the expired entries once per ttl so the map doesn't grow forever
+	if now.After(m.nextSweep) {
+		for k, e := range m.entries {
+			if now.After(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+		m.nextSweep = now.Add(m.ttl)
+	}
+	m.entries[key] = cacheEntry{
+		value:   value,
+		expires: now.Add(m.ttl),
+	}
+}
+
+func (m *memoryCache) Delete(prefix string) {
+	m.Lock()
+	defer m.Unlock()
+
+	for k := range m.entries {
+		if strings.HasPrefix(k, prefix) {
+			delete(m.entries, k)
+		}
+	}
+}
+
+type redisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+const redisKeyPrefix = "ssp-backend:"
+
+func (r *redisCache) Get(key string) ([]byte, bool) {
+	value, err := r.client.Get(redisKeyPrefix + key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("Error reading from redis cache: %v", err)
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+func (r *redisCache) Set(key string, value []byte) {
+	if err := r.client.Set(redisKeyPrefix+key, value, r.ttl).Err(); err != nil {
+		log.Printf("Error writing to redis cache: %v", err)
+	}
+}
+
+func (r *redisCache) Delete(prefix string) {
+	iter := r.client.Scan(0, redisKeyPrefix+prefix+"*", 100).Iterator()
+	for iter.Next() {
+		if err := r.client.Del(iter.Val()).Err(); err != nil {
+			log.Printf("Error deleting from redis cache: %v", err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		log.Printf("Error scanning redis cache: %v", err)
+	}
+}
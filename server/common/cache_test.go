@@ -0,0 +1,57 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	cache := newMemoryCache(time.Minute)
+	cache.Set("rolebinding/fake/app", []byte("admins"))
+	cache.Set("rolebinding/fake/other", []byte("admins"))
+	cache.Set("namespace/fake/app", []byte("namespace"))
+
+	if value, ok := cache.Get("rolebinding/fake/app"); !ok || string(value) != "admins" {
+		t.Errorf("expected the cached value, got %q %v", value, ok)
+	}
+	if _, ok := cache.Get("rolebinding/fake/missing"); ok {
+		t.Error("expected no value for an unknown key")
+	}
+
+	cache.Delete("rolebinding/fake/")
+	if _, ok := cache.Get("rolebinding/fake/other"); ok {
+		t.Error("expected the entries with the prefix to be deleted")
+	}
+	if _, ok := cache.Get("namespace/fake/app"); !ok {
+		t.Error("expected the other entries to be kept")
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	cache := newMemoryCache(time.Minute)
+	cache.Set("old", []byte("value"))
+	cache.entries["old"] = cacheEntry{value: []byte("value"), expires: time.Now().Add(-time.Second)}
+
+	if _, ok := cache.Get("old"); ok {
+		t.Error("expected an expired entry not to be returned")
+	}
+
+	// The expired entries are only removed once per ttl
+	cache.Set("new", []byte("value"))
+	if _, ok := cache.entries["old"]; !ok {
+		t.Error("expected the expired entry to be kept until the next sweep")
+	}
+	cache.nextSweep = time.Now().Add(-time.Second)
+	cache.Set("new", []byte("value"))
+	if _, ok := cache.entries["old"]; ok {
+		t.Error("expected the expired entry to be removed by the sweep")
+	}
+}
+
+func TestNoCache(t *testing.T) {
+	var cache Cache = noCache{}
+	cache.Set("key", []byte("value"))
+	if _, ok := cache.Get("key"); ok {
+		t.Error("expected the disabled cache to keep nothing")
+	}
+}
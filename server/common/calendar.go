@@ -0,0 +1,108 @@
+package common
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const holidayDateFormat = "2006-01-02"
+
+var (
+	locationOnce sync.Once
+	location     *time.Location
+	holidaysOnce sync.Once
+	holidays     map[string]bool
+
+	// Matches the start date of all day events in iCalendar files (DTSTART;VALUE=DATE:20190801)
+	icsDateRegex = regexp.MustCompile(`^DTSTART[^:]*:(\d{8})`)
+)
+
+// Location returns the timezone of the installation from 'timezone'
+// (e.g. Europe/Zurich). Defaults to the timezone of the server
+func Location() *time.Location {
+	locationOnce.Do(func() {
+		location = time.Local
+		name := config.Config().GetString("timezone")
+		if name == "" {
+			return
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			log.Printf("WARNING: invalid timezone %v, using the timezone of the server: %v", name, err)
+			return
+		}
+		location = loc
+	})
+	return location
+}
+
+// Now returns the current time in the timezone of the installation. All
+// scheduled jobs should use it instead of time.Now()
+func Now() time.Time {
+	return time.Now().In(Location())
+}
+
+// IsHoliday returns true if the date is a public holiday. Holidays are read
+// from the list 'holidays.dates' (2019-08-01) and the file 'holidays.file',
+// which is either an iCalendar file or contains one date per line
+func IsHoliday(t time.Time) bool {
+	holidaysOnce.Do(loadHolidays)
+	return holidays[t.In(Location()).Format(holidayDateFormat)]
+}
+
+// IsWorkday returns true from monday to friday if it isn't a holiday
+func IsWorkday(t time.Time) bool {
+	weekday := t.In(Location()).Weekday()
+	if weekday == time.Saturday || weekday == time.Sunday {
+		return false
+	}
+	return !IsHoliday(t)
+}
+
+func loadHolidays() {
+	cfg := config.Config()
+	holidays = make(map[string]bool)
+	for _, d := range cfg.GetStringSlice("holidays.dates") {
+		addHoliday(d)
+	}
+
+	file := cfg.GetString("holidays.file")
+	if file == "" {
+		return
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		log.Printf("WARNING: can't read holidays from %v: %v", file, err)
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		addHoliday(scanner.Text())
+	}
+}
+
+func addHoliday(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	if m := icsDateRegex.FindStringSubmatch(line); m != nil {
+		if d, err := time.Parse("20060102", m[1]); err == nil {
+			holidays[d.Format(holidayDateFormat)] = true
+		}
+		return
+	}
+	if d, err := time.Parse(holidayDateFormat, line); err == nil {
+		holidays[d.Format(holidayDateFormat)] = true
+	}
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsWorkday(t *testing.T) {
+	// Don't read the config
+	locationOnce.Do(func() { location = time.UTC })
+	holidaysOnce.Do(func() {})
+	holidays = make(map[string]bool)
+	addHoliday("2019-08-01")
+	addHoliday("DTSTART;VALUE=DATE:20191225")
+	addHoliday("# comment")
+
+	tests := []struct {
+		date    string
+		workday bool
+	}{
+		{"2019-07-31", true},
+		{"2019-08-01", false},
+		{"2019-08-03", false},
+		{"2019-12-25", false},
+	}
+	for _, test := range tests {
+		d, _ := time.ParseInLocation(holidayDateFormat, test.date, Location())
+		if IsWorkday(d) != test.workday {
+			t.Errorf("IsWorkday(%v): expected %v", test.date, test.workday)
+		}
+	}
+}
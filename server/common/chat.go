@@ -0,0 +1,70 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Events the platform operators are told about in their chat channel
+const (
+	ChatProjectCreated = "project_created"
+	ChatQuotaIncreased = "quota_increased"
+	ChatVolumeFailed   = "volume_failed"
+)
+
+const chatTimeout = 10 * time.Second
+
+// chatMessage is understood by the incoming webhooks of Mattermost and Slack
+type chatMessage struct {
+	Text     string `json:"text"`
+	Channel  string `json:"channel,omitempty"`
+	Username string `json:"username,omitempty"`
+	IconURL  string `json:"icon_url,omitempty"`
+}
+
+// PostChat posts the message to the webhook in 'chat.webhook_url' if the
+// event isn't missing in 'chat.events'. Errors are only logged
+func PostChat(event, text string) {
+	cfg := config.Config()
+	if cfg.GetString("chat.webhook_url") == "" {
+		return
+	}
+	if events := cfg.GetStringSlice("chat.events"); len(events) > 0 && !containsString(events, event) {
+		return
+	}
+	go func() {
+		if err := sendChatMessage(text); err != nil {
+			log.Printf("Can't post the %v event to the chat: %v", event, err)
+		}
+	}()
+}
+
+func sendChatMessage(text string) error {
+	cfg := config.Config()
+	body, err := json.Marshal(chatMessage{
+		Text:     text,
+		Channel:  cfg.GetString("chat.channel"),
+		Username: cfg.GetString("chat.username"),
+		IconURL:  cfg.GetString("chat.icon_url"),
+	})
+	if err != nil {
+		return err
+	}
+
+	client := HTTPClient("chat")
+	client.Timeout = chatTimeout
+	resp, err := client.Post(cfg.GetString("chat.webhook_url"), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %v", resp.StatusCode)
+	}
+	return nil
+}
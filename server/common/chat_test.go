@@ -0,0 +1,41 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestSendChatMessage(t *testing.T) {
+	config.Init("test")
+	var received chatMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("unexpected body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.Config()
+	cfg.Set("chat.webhook_url", server.URL)
+	cfg.Set("chat.channel", "ssp-events")
+	cfg.Set("proxy.chat", "direct")
+	defer cfg.Set("chat.webhook_url", "")
+
+	if err := sendChatMessage("u123 created the project shop on cluster prod"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received.Text != "u123 created the project shop on cluster prod" || received.Channel != "ssp-events" {
+		t.Errorf("unexpected message %+v", received)
+	}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	if err := sendChatMessage("test"); err == nil {
+		t.Error("expected an error if the webhook fails")
+	}
+}
@@ -0,0 +1,82 @@
+package common
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gzipWriter compresses the body of the response. The gzip stream is only
+// started with the first write, so empty responses (e.g. 304) stay empty
+type gzipWriter struct {
+	gin.ResponseWriter
+	gz *gzip.Writer
+}
+
+func (w *gzipWriter) start() {
+	if w.gz != nil {
+		return
+	}
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Encoding", "gzip")
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	w.start()
+	return w.gz.Write(data)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends the rows written so far to the client, so streamed exports
+// arrive incrementally
+func (w *gzipWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *gzipWriter) close() {
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
+
+// Compress is a gin middleware for endpoints with large responses like
+// reports and exports. The body is gzip compressed if the client accepts it.
+// Brotli isn't offered as there is no encoder in our dependencies.
+// Used together with ETag, Compress must be the inner middleware
+func Compress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept-Encoding")
+		if !acceptsGzip(c.GetHeader("Accept-Encoding")) || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		w := &gzipWriter{ResponseWriter: original}
+		c.Writer = w
+		c.Next()
+		w.close()
+		c.Writer = original
+	}
+}
+
+// acceptsGzip checks if gzip is in the list of Accept-Encoding
+func acceptsGzip(acceptEncoding string) bool {
+	for _, encoding := range strings.Split(acceptEncoding, ",") {
+		parts := strings.Split(encoding, ";")
+		if strings.TrimSpace(parts[0]) != "gzip" {
+			continue
+		}
+		return len(parts) < 2 || strings.Replace(parts[1], " ", "", -1) != "q=0"
+	}
+	return false
+}
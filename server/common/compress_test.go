@@ -0,0 +1,89 @@
+package common
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCompress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/export", Compress(), func(c *gin.Context) {
+		c.Header("Content-Type", "text/csv")
+		c.Writer.WriteString("a,b\n")
+		c.Writer.Flush()
+		c.Writer.WriteString("c,d\n")
+	})
+	router.GET("/projects", ETag(), Compress(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, []string{"a", "b"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/export", nil))
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "a,b\nc,d\n" {
+		t.Fatalf("expected an uncompressed body, got %q %q", w.Header().Get("Content-Encoding"), w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/export", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzip body, got %q", w.Header().Get("Content-Encoding"))
+	}
+	if body := gunzip(t, w.Body.Bytes()); body != "a,b\nc,d\n" {
+		t.Errorf("expected both rows, got %q", body)
+	}
+
+	req = httptest.NewRequest("GET", "/projects", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+	if body := gunzip(t, w.Body.Bytes()); body != `["a","b"]` || etag == "" {
+		t.Fatalf("expected a compressed body with an etag, got %q %q", body, etag)
+	}
+
+	req = httptest.NewRequest("GET", "/projects", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected 304 without body, got %v %q", w.Code, w.Body.String())
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := map[string]bool{
+		"":                  false,
+		"gzip":              true,
+		"deflate, gzip":     true,
+		"gzip;q=0":          false,
+		"gzip; q=0.5, br":   true,
+		"identity, deflate": false,
+	}
+	for header, expected := range tests {
+		if acceptsGzip(header) != expected {
+			t.Errorf("acceptsGzip(%q) should be %v", header, expected)
+		}
+	}
+}
+
+func gunzip(t *testing.T, data []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	return string(body)
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	dryRunKey            = "dryRun"
+	dryRunNotSupported   = "Dieser Endpunkt unterstützt keinen Dry-Run (?dryRun=true)"
+	DryRunActionCreate   = "create"
+	DryRunActionUpdate   = "update"
+	DryRunActionDelete   = "delete"
+	DryRunActionApproval = "approval"
+)
+
+// PlannedChange is a change which would be made without ?dryRun=true
+type PlannedChange struct {
+	Action    string `json:"action"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	ClusterId string `json:"clusterid,omitempty"`
+	Project   string `json:"project,omitempty"`
+	Details   string `json:"details,omitempty"`
+	// Current and Proposed are the values of changed fields, e.g. annotations
+	Current  string `json:"current,omitempty"`
+	Proposed string `json:"proposed,omitempty"`
+}
+
+// DryRunResponse is returned by mutating endpoints called with ?dryRun=true
+type DryRunResponse struct {
+	DryRun  bool            `json:"dryRun"`
+	Message string          `json:"message"`
+	Changes []PlannedChange `json:"changes"`
+}
+
+var dryRunHandlers = make(map[string]bool)
+
+// HandlerName is the name of the handler function as in c.HandlerName()
+func HandlerName(h gin.HandlerFunc) string {
+	return runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+}
+
+// SupportsDryRun marks the handlers which check IsDryRun after their
+// validation and permission checks
+func SupportsDryRun(handlers ...gin.HandlerFunc) {
+	for _, h := range handlers {
+		dryRunHandlers[HandlerName(h)] = true
+	}
+}
+
+// SupportsDryRunHandler returns true if the handler with the name was
+// marked by SupportsDryRun
+func SupportsDryRunHandler(name string) bool {
+	return dryRunHandlers[name]
+}
+
+// DryRunMiddleware handles ?dryRun=true on mutating requests. Requests to
+// handlers which don't support it are rejected, so nothing is changed by accident
+func DryRunMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query(dryRunKey) != "true" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+		if !dryRunHandlers[c.HandlerName()] {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ApiResponse{Message: dryRunNotSupported})
+			return
+		}
+		c.Set(dryRunKey, true)
+		c.Next()
+	}
+}
+
+// IsDryRun returns true if the request should only return the planned changes
+func IsDryRun(c *gin.Context) bool {
+	return c.GetBool(dryRunKey)
+}
+
+// RespondDryRun returns the planned changes instead of executing them
+func RespondDryRun(c *gin.Context, message string, changes ...PlannedChange) {
+	if changes == nil {
+		changes = []PlannedChange{}
+	}
+	c.JSON(http.StatusOK, DryRunResponse{DryRun: true, Message: message, Changes: changes})
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func dryRunTestHandler(c *gin.Context) {
+	if IsDryRun(c) {
+		RespondDryRun(c, "planned")
+		return
+	}
+	c.JSON(http.StatusCreated, ApiResponse{Message: "created"})
+}
+
+func notDryRunTestHandler(c *gin.Context) {
+	c.JSON(http.StatusCreated, ApiResponse{Message: "created"})
+}
+
+func TestDryRunMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(DryRunMiddleware())
+	router.POST("/supported", dryRunTestHandler)
+	router.POST("/unsupported", notDryRunTestHandler)
+	SupportsDryRun(dryRunTestHandler)
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/supported", http.StatusCreated},
+		{"/supported?dryRun=true", http.StatusOK},
+		{"/unsupported", http.StatusCreated},
+		{"/unsupported?dryRun=true", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", test.path, nil))
+		if w.Code != test.expected {
+			t.Errorf("POST %v: expected %v, got %v", test.path, test.expected, w.Code)
+		}
+	}
+}
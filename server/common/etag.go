@@ -0,0 +1,72 @@
+package common
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etagWriter keeps the body of the response until the etag is known
+type etagWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// ETag is a gin middleware for read endpoints which are polled by the
+// frontend. It sets the ETag of the response and answers with 304 Not Modified
+// if it matches If-None-Match, so unchanged payloads aren't transferred again
+func ETag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		w := &etagWriter{ResponseWriter: original}
+		c.Writer = w
+		c.Next()
+		c.Writer = original
+
+		if w.Status() != http.StatusOK {
+			original.Write(w.body.Bytes())
+			return
+		}
+
+		sum := sha1.Sum(w.body.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:]) + `"`
+		original.Header().Set("ETag", etag)
+		original.Header().Set("Cache-Control", "private, no-cache")
+
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			original.Header().Del("Content-Type")
+			original.WriteHeader(http.StatusNotModified)
+			original.WriteHeaderNow()
+			return
+		}
+		original.Write(w.body.Bytes())
+	}
+}
+
+// etagMatches checks the etag against the list of If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
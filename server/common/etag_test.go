@@ -0,0 +1,49 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/projects", ETag(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, []string{"a", "b"})
+	})
+	router.GET("/error", ETag(), func(c *gin.Context) {
+		c.JSON(http.StatusBadRequest, ApiResponse{Message: "error"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/projects", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" || w.Body.String() != `["a","b"]` {
+		t.Fatalf("expected the body with an etag, got %v %q %q", w.Code, etag, w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/projects", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected 304 without body, got %v %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/projects", nil)
+	req.Header.Set("If-None-Match", `"outdated"`)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for an outdated etag, got %v", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/error", nil))
+	if w.Code != http.StatusBadRequest || w.Header().Get("ETag") != "" {
+		t.Errorf("expected errors without etag, got %v %q", w.Code, w.Header().Get("ETag"))
+	}
+}
@@ -3,10 +3,10 @@ package common
 import (
 	"crypto/rand"
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 	"gopkg.in/appleboy/gin-jwt.v2"
 )
 
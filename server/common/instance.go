@@ -0,0 +1,21 @@
+package common
+
+import (
+	"os"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+// InstanceID identifies this instance of the portal, e.g. as holder of the
+// lease of a background job. It's 'instance_id' or the hostname, which is
+// the name of the pod on OpenShift
+func InstanceID() string {
+	if id := config.Config().GetString("instance_id"); id != "" {
+		return id
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "ssp"
+	}
+	return hostname
+}
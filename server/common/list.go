@@ -0,0 +1,149 @@
+package common
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+	invalidListError = "Ungültige Parameter für die Liste (limit, offset, continue, sort, filter)"
+)
+
+// ListParams are the query parameters supported by all list endpoints:
+// ?limit=50&offset=100 or ?limit=50&continue=<token>, ?sort=-name, ?filter=abc
+type ListParams struct {
+	Limit  int
+	Offset int
+	// Sort is the name of the field to sort by. Descending if prefixed with '-'
+	Sort   string
+	Filter string
+}
+
+// ListResponse is the envelope returned by all list endpoints
+type ListResponse struct {
+	Items    interface{} `json:"items"`
+	Total    int         `json:"total"`
+	Continue string      `json:"continue,omitempty"`
+	// Errors lists the parts (e.g. clusters) which couldn't be read, the
+	// items are incomplete if set
+	Errors []string `json:"errors,omitempty"`
+}
+
+// listQueryParams are the query parameters of the lists
+var listQueryParams = []string{"limit", "offset", "continue", "sort", "filter"}
+
+// IsListRequest returns true if the request has one of the list parameters.
+// Endpoints which returned plain arrays before the envelope only use it then,
+// so existing clients still get the complete array
+func IsListRequest(c *gin.Context) bool {
+	for _, p := range listQueryParams {
+		if _, ok := c.GetQuery(p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseListParams reads the list query parameters from the request
+func ParseListParams(c *gin.Context) (ListParams, error) {
+	params := ListParams{
+		Limit:  defaultListLimit,
+		Sort:   c.Query("sort"),
+		Filter: strings.ToLower(c.Query("filter")),
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l <= 0 {
+			return params, errors.New(invalidListError)
+		}
+		if l > maxListLimit {
+			l = maxListLimit
+		}
+		params.Limit = l
+	}
+
+	// The continue token is an opaque version of the offset
+	offset := c.Query("offset")
+	if token := c.Query("continue"); token != "" {
+		offset = token
+	}
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil || o < 0 {
+			return params, errors.New(invalidListError)
+		}
+		params.Offset = o
+	}
+
+	return params, nil
+}
+
+// SortField returns the field to sort by and if the order is descending
+func (p ListParams) SortField(defaultField string) (string, bool) {
+	if p.Sort == "" {
+		return defaultField, false
+	}
+	if strings.HasPrefix(p.Sort, "-") {
+		return strings.TrimPrefix(p.Sort, "-"), true
+	}
+	return p.Sort, false
+}
+
+// Matches returns true if the filter is empty or one of the values contains it
+func (p ListParams) Matches(values ...string) bool {
+	if p.Filter == "" {
+		return true
+	}
+	for _, v := range values {
+		if strings.Contains(strings.ToLower(v), p.Filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// Page returns the bounds of the requested page for a list with total
+// elements and the continue token for the next page
+func (p ListParams) Page(total int) (int, int, string) {
+	start := p.Offset
+	if start > total {
+		start = total
+	}
+	end := start + p.Limit
+	if end >= total {
+		return start, total, ""
+	}
+	return start, end, strconv.Itoa(end)
+}
+
+// NewStringListResponse filters, sorts and pages a list of strings
+func NewStringListResponse(items []string, params ListParams) ListResponse {
+	filtered := []string{}
+	for _, i := range items {
+		if params.Matches(i) {
+			filtered = append(filtered, i)
+		}
+	}
+
+	_, desc := params.SortField("name")
+	sort.Slice(filtered, func(i, j int) bool {
+		if desc {
+			return filtered[i] > filtered[j]
+		}
+		return filtered[i] < filtered[j]
+	})
+
+	start, end, next := params.Page(len(filtered))
+	return ListResponse{
+		Items:    filtered[start:end],
+		Total:    len(filtered),
+		Continue: next,
+	}
+}
@@ -0,0 +1,67 @@
+package common
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func listContext(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/ose/projects?"+query, nil)
+	return c
+}
+
+func TestParseListParams(t *testing.T) {
+	params, err := ParseListParams(listContext("limit=5&continue=10&sort=-name&filter=Shop"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Limit != 5 || params.Offset != 10 || params.Filter != "shop" {
+		t.Errorf("unexpected params %+v", params)
+	}
+	if field, desc := params.SortField("id"); field != "name" || !desc {
+		t.Errorf("expected descending by name, got %v %v", field, desc)
+	}
+
+	if params, _ := ParseListParams(listContext("limit=5000")); params.Limit != maxListLimit {
+		t.Errorf("expected the limit to be capped, got %v", params.Limit)
+	}
+	for _, query := range []string{"limit=0", "limit=abc", "offset=-1", "continue=x"} {
+		if _, err := ParseListParams(listContext(query)); err == nil {
+			t.Errorf("expected an error for %v", query)
+		}
+	}
+}
+
+func TestIsListRequest(t *testing.T) {
+	if IsListRequest(listContext("clusterid=awsdev")) {
+		t.Error("expected a request without list parameters")
+	}
+	if !IsListRequest(listContext("clusterid=awsdev&limit=10")) {
+		t.Error("expected a list request")
+	}
+}
+
+func TestNewStringListResponse(t *testing.T) {
+	items := []string{"shop-test", "blog", "shop-prod", "wiki"}
+	params, _ := ParseListParams(listContext("limit=1&filter=shop&sort=-name"))
+
+	response := NewStringListResponse(items, params)
+	if !reflect.DeepEqual(response.Items, []string{"shop-test"}) || response.Total != 2 || response.Continue != "1" {
+		t.Fatalf("unexpected first page %+v", response)
+	}
+
+	params, _ = ParseListParams(listContext("limit=1&filter=shop&sort=-name&continue=" + response.Continue))
+	response = NewStringListResponse(items, params)
+	if !reflect.DeepEqual(response.Items, []string{"shop-prod"}) || response.Continue != "" {
+		t.Errorf("unexpected last page %+v", response)
+	}
+
+	params, _ = ParseListParams(listContext("offset=10"))
+	if response := NewStringListResponse(items, params); len(response.Items.([]string)) != 0 || response.Total != 4 {
+		t.Errorf("expected an empty page after the end, got %+v", response)
+	}
+}
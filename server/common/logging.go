@@ -0,0 +1,105 @@
+package common
+
+import (
+	stdlog "log"
+	"regexp"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// RequestIDHeader is read from the request and returned in the response
+	RequestIDHeader = "X-Request-Id"
+	requestIDKey    = "requestId"
+)
+
+// requestIDPattern are the ids accepted from the caller, e.g. of the router
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// sanitizingFormatter removes control characters from the message and the
+// fields, so user input in log lines can't forge other lines
+type sanitizingFormatter struct {
+	log.Formatter
+}
+
+func (f sanitizingFormatter) Format(e *log.Entry) ([]byte, error) {
+	clean := *e
+	clean.Message = SanitizeLogValue(e.Message)
+	clean.Data = make(log.Fields, len(e.Data))
+	for k, v := range e.Data {
+		if s, ok := v.(string); ok {
+			v = SanitizeLogValue(s)
+		}
+		clean.Data[k] = v
+	}
+	return f.Formatter.Format(&clean)
+}
+
+// ConfigureLogging writes json lines if 'log_format' is json. The lines of
+// libraries using the standard logger go through logrus too
+func ConfigureLogging() {
+	var formatter log.Formatter = &log.TextFormatter{}
+	if config.Config().GetString("log_format") == "json" {
+		formatter = &log.JSONFormatter{
+			TimestampFormat: time.RFC3339Nano,
+			FieldMap:        log.FieldMap{log.FieldKeyMsg: "message"},
+		}
+	}
+	log.SetFormatter(sanitizingFormatter{formatter})
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(log.StandardLogger().WriterLevel(log.InfoLevel))
+}
+
+// RequestIDMiddleware gives every request an id. It's taken from the
+// X-Request-Id header of the caller if valid, returned in the response and
+// logged with the result of the request
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			uid, err := uuid.NewV4()
+			if err != nil {
+				log.Printf("Error generating a request id: %v", err)
+			}
+			id = uid.String()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+
+		start := time.Now()
+		c.Next()
+
+		entry := Logger(c).WithFields(log.Fields{
+			"method":     c.Request.Method,
+			"path":       SanitizeLogValue(c.Request.URL.Path),
+			"status":     c.Writer.Status(),
+			"durationMs": time.Since(start).Nanoseconds() / int64(time.Millisecond),
+			"ip":         c.ClientIP(),
+		})
+		if c.Writer.Status() >= 500 {
+			entry.Warn("request failed")
+		} else {
+			entry.Info("request")
+		}
+	}
+}
+
+// GetRequestID returns the id of the request
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// Logger returns a logger which adds the request id and the user to the lines
+func Logger(c *gin.Context) *log.Entry {
+	fields := log.Fields{"requestId": GetRequestID(c)}
+	if user, ok := c.Get(gin.AuthUserKey); ok {
+		fields["user"] = user
+	} else if _, ok := c.Get("JWT_PAYLOAD"); ok {
+		fields["user"] = GetUserName(c)
+	}
+	return log.WithFields(fields)
+}
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	log.SetFormatter(&log.JSONFormatter{})
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFormatter(&log.TextFormatter{})
+	}()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	router.GET("/projects", func(c *gin.Context) {
+		Logger(c).Info("listing projects")
+		c.Status(http.StatusOK)
+	})
+	call := func(requestID string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/projects", nil)
+		req.Header.Set(RequestIDHeader, requestID)
+		router.ServeHTTP(w, req)
+		return w.Header().Get(RequestIDHeader)
+	}
+
+	if id := call("router-1234"); id != "router-1234" {
+		t.Errorf("expected the id of the caller, got %q", id)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected the line of the handler and of the request, got %v", lines)
+	}
+	for _, l := range lines {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(l), &fields); err != nil || fields["requestId"] != "router-1234" {
+			t.Errorf("expected the request id in %v", l)
+		}
+	}
+
+	if id := call("bad id\n"); id == "" || id == "bad id\n" {
+		t.Errorf("expected a generated id for an invalid header, got %q", id)
+	}
+}
+
+func TestSanitizingFormatter(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	log.SetFormatter(sanitizingFormatter{&log.TextFormatter{DisableTimestamp: true}})
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFormatter(&log.TextFormatter{})
+	}()
+
+	log.WithField("user", "u123\nlevel=error").Printf("Created project %v", "app\nlevel=error msg=forged")
+	if strings.Count(out.String(), "\n") != 1 || strings.Contains(out.String(), `\n`) {
+		t.Errorf("expected the line breaks to be removed, got %q", out.String())
+	}
+}
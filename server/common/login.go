@@ -2,14 +2,16 @@ package common
 
 import (
 	"errors"
-	"log"
+	"fmt"
 	"time"
 
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
 	"github.com/gin-gonic/gin"
 
 	"github.com/jtblin/go-ldap-client"
+	log "github.com/sirupsen/logrus"
 	"gopkg.in/appleboy/gin-jwt.v2"
+	ldapv2 "gopkg.in/ldap.v2"
 )
 
 type login struct {
@@ -18,8 +20,9 @@ type login struct {
 }
 
 type User struct {
-	UserId string
-	Email  string
+	UserId    string
+	Email     string
+	SessionID string
 }
 
 // GetAuthMiddleware returns a gin middleware for JWT with cookie based auth
@@ -29,12 +32,14 @@ func GetAuthMiddleware() *jwt.GinJWTMiddleware {
 		key = RandomString(64)
 	}
 
+	authenticator := loginAuthenticator()
+
 	return &jwt.GinJWTMiddleware{
 		Realm:         "CLOUD_SSP",
 		Key:           []byte(key),
-		Timeout:       time.Hour,
-		MaxRefresh:    time.Hour,
-		Authenticator: ldapAuthenticator,
+		Timeout:       sessionTimeout,
+		MaxRefresh:    sessionTimeout,
+		Authenticator: sessionAuthenticator(authenticator),
 		Authorizator: func(data interface{}, c *gin.Context) bool {
 			return true
 		},
@@ -53,8 +58,9 @@ func GetAuthMiddleware() *jwt.GinJWTMiddleware {
 func userPayloadFunc(data interface{}) jwt.MapClaims {
 	if v, ok := data.(*User); ok {
 		return jwt.MapClaims{
-			"id":   v.UserId,
-			"mail": v.Email,
+			"id":         v.UserId,
+			"mail":       v.Email,
+			sessionClaim: v.SessionID,
 		}
 	}
 
@@ -62,6 +68,39 @@ func userPayloadFunc(data interface{}) jwt.MapClaims {
 }
 
 func ldapAuthenticator(c *gin.Context) (interface{}, error) {
+	client, err := newLDAPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// It is the responsibility of the caller to close the connection
+	defer client.Close()
+
+	var loginVals login
+	if err := c.ShouldBind(&loginVals); err != nil {
+		return "", jwt.ErrMissingLoginValues
+	}
+	userID := loginVals.Username
+	password := loginVals.Password
+
+	ok, user, err := client.Authenticate(userID, password)
+	if err != nil {
+		Logger(c).Printf("Error authenticating user %s: %+v", userID, err)
+		return nil, jwt.ErrFailedAuthentication
+	}
+	if !ok {
+		Logger(c).Printf("Authenticating failed for user %s", userID)
+		return nil, jwt.ErrFailedAuthentication
+	}
+
+	return &User{
+		UserId: userID,
+		Email:  user["mail"],
+	}, nil
+}
+
+// newLDAPClient returns a client for the users in the ldap of the config
+func newLDAPClient() (*ldap.LDAPClient, error) {
 	cfg := config.Config()
 	ldapHost := cfg.GetString("ldap_url")
 	ldapBind := cfg.GetString("ldap_bind_dn")
@@ -74,7 +113,7 @@ func ldapAuthenticator(c *gin.Context) (interface{}, error) {
 	// may be empty
 	ldapSearchBase := cfg.GetString("ldap_search_base")
 
-	client := &ldap.LDAPClient{
+	return &ldap.LDAPClient{
 		Attributes:   []string{"givenName", "sn", "mail", "uid"},
 		Base:         ldapSearchBase,
 		Host:         ldapHost,
@@ -84,30 +123,33 @@ func ldapAuthenticator(c *gin.Context) (interface{}, error) {
 		BindDN:       ldapBind,
 		BindPassword: ldapBindPw,
 		UserFilter:   ldapFilter,
-	}
+	}, nil
+}
 
-	// It is the responsibility of the caller to close the connection
+// UserExists looks up the user with 'ldap_filter', e.g. to find users who
+// left the company
+func UserExists(username string) (bool, error) {
+	client, err := newLDAPClient()
+	if err != nil {
+		return false, err
+	}
 	defer client.Close()
 
-	var loginVals login
-	if err := c.ShouldBind(&loginVals); err != nil {
-		return "", jwt.ErrMissingLoginValues
+	if err := client.Connect(); err != nil {
+		return false, err
 	}
-	userID := loginVals.Username
-	password := loginVals.Password
-
-	ok, user, err := client.Authenticate(userID, password)
-	if err != nil {
-		log.Printf("Error authenticating user %s: %+v", userID, err)
-		return nil, jwt.ErrFailedAuthentication
+	if err := client.Conn.Bind(client.BindDN, client.BindPassword); err != nil {
+		return false, err
 	}
-	if !ok {
-		log.Printf("Authenticating failed for user %s", userID)
-		return nil, jwt.ErrFailedAuthentication
+	result, err := client.Conn.Search(ldapv2.NewSearchRequest(
+		client.Base,
+		ldapv2.ScopeWholeSubtree, ldapv2.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(client.UserFilter, ldapv2.EscapeFilter(username)),
+		[]string{"dn"},
+		nil,
+	))
+	if err != nil {
+		return false, err
 	}
-
-	return &User{
-		UserId: userID,
-		Email:  user["mail"],
-	}, nil
+	return len(result.Entries) > 0, nil
 }
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"gopkg.in/gomail.v2"
+)
+
+// Attachment is a file attached to a mail
+type Attachment struct {
+	Filename string
+	Content  []byte
+}
+
+// SendMail sends a html mail from 'MAIL_ADMIN_SENDER' over 'MAIL_SERVER'
+func SendMail(to []string, subject, body string) error {
+	return SendMailWithAttachments(to, subject, body)
+}
+
+// SendMailWithAttachments sends a html mail like SendMail with the files attached
+func SendMailWithAttachments(to []string, subject, body string, attachments ...Attachment) error {
+	cfg := config.Config()
+	mailServer := cfg.GetString("mail_server")
+	if mailServer == "" {
+		return errors.New("Error looking up MAIL_SERVER from environment.")
+	}
+
+	fromMail := cfg.GetString("mail_admin_sender")
+	if fromMail == "" {
+		return errors.New("Error looking up MAIL_ADMIN_SENDER from environment.")
+	}
+
+	if len(to) == 0 {
+		return errors.New("No recipients for mail: " + subject)
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", fromMail)
+	m.SetHeader("To", to...)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body)
+	for _, a := range attachments {
+		content := a.Content
+		m.Attach(a.Filename, gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(content)
+			return err
+		}))
+	}
+
+	port := cfg.GetInt("mail_port")
+	if port == 0 {
+		port = 25
+	}
+	d := gomail.Dialer{Host: mailServer, Port: port, Username: cfg.GetString("mail_username"), Password: cfg.GetString("mail_password")}
+	d.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+	return d.DialAndSend(m)
+}
+
+// GetMailForUser returns the mail address of a user based on 'MAIL_USER_DOMAIN'.
+// Returns an empty string if the domain isn't configured
+func GetMailForUser(username string) string {
+	domain := config.Config().GetString("mail_user_domain")
+	if domain == "" || username == "" {
+		return ""
+	}
+	if strings.Contains(username, "@") {
+		return username
+	}
+	return strings.ToLower(username) + "@" + domain
+}
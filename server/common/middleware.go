@@ -0,0 +1,159 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMaxRequestBodyBytes = 1 << 20
+	defaultMaxUploadBytes      = 16 << 20
+	requestTooLargeError       = "Die Anfrage ist zu gross"
+	invalidCharactersError     = "Die Anfrage enthält ungültige Zeichen"
+)
+
+// multiLineFields are the json fields whose string values may contain line
+// breaks and tabs, e.g. the pem data of secrets
+var multiLineFields = map[string]bool{
+	"data": true,
+}
+
+// zeroWidthRunes are invisible characters which can be used to create
+// project names or annotations that look identical to existing ones
+var zeroWidthRunes = map[rune]bool{
+	'\u200b': true, // zero width space
+	'\u200c': true, // zero width non-joiner
+	'\u200d': true, // zero width joiner
+	'\u2060': true, // word joiner
+	'\ufeff': true, // zero width no-break space / BOM
+}
+
+// RequestSanitizerMiddleware limits the size of request bodies and rejects
+// requests containing control characters or zero-width unicode in any
+// query parameter or json string value. Only the values of multiLineFields
+// may contain line breaks and tabs. File uploads (multipart) have their own limit
+func RequestSanitizerMiddleware() gin.HandlerFunc {
+	maxBodyBytes := config.Config().GetInt64("max_request_body_bytes")
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = defaultMaxRequestBodyBytes
+	}
+	maxUploadBytes := config.Config().GetInt64("max_upload_bytes")
+	if maxUploadBytes <= 0 {
+		maxUploadBytes = defaultMaxUploadBytes
+	}
+
+	return func(c *gin.Context) {
+		for key, values := range c.Request.URL.Query() {
+			for _, v := range append(values, key) {
+				if containsControlRunes(v) {
+					abortInvalidCharacters(c, SanitizeLogValue(key))
+					return
+				}
+			}
+		}
+
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		maxBytes := maxBodyBytes
+		if strings.HasPrefix(c.ContentType(), "multipart/") {
+			maxBytes = maxUploadBytes
+		}
+		if c.Request.ContentLength > maxBytes {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, ApiResponse{Message: requestTooLargeError})
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, ApiResponse{Message: requestTooLargeError})
+			return
+		}
+
+		if strings.Contains(c.ContentType(), "json") && len(body) > 0 {
+			var payload interface{}
+			// Invalid json is reported by the handlers themselves
+			if json.Unmarshal(body, &payload) == nil && containsForbiddenValue(payload, false) {
+				abortInvalidCharacters(c, c.Request.URL.Path)
+				return
+			}
+		}
+
+		// The handlers still need to bind the body
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+func abortInvalidCharacters(c *gin.Context, where string) {
+	Logger(c).Warnf("Rejected request with invalid characters from %v: %v", c.ClientIP(), where)
+	c.AbortWithStatusJSON(http.StatusBadRequest, ApiResponse{Message: invalidCharactersError})
+}
+
+// containsForbiddenValue checks all keys and string values of the json
+// value. multiLine allows line breaks and tabs in the strings
+func containsForbiddenValue(value interface{}, multiLine bool) bool {
+	switch v := value.(type) {
+	case string:
+		if multiLine {
+			return ContainsForbiddenRunes(v)
+		}
+		return containsControlRunes(v)
+	case []interface{}:
+		for _, e := range v {
+			if containsForbiddenValue(e, multiLine) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for k, e := range v {
+			if containsControlRunes(k) || containsForbiddenValue(e, multiLine || multiLineFields[k]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ContainsForbiddenRunes returns true if s contains control characters other
+// than line breaks and tabs, or zero-width unicode characters
+func ContainsForbiddenRunes(s string) bool {
+	for _, r := range s {
+		if (unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t') || zeroWidthRunes[r] {
+			return true
+		}
+	}
+	return false
+}
+
+// containsControlRunes returns true if s contains any control character or
+// zero-width unicode characters
+func containsControlRunes(s string) bool {
+	for _, r := range s {
+		if unicode.IsControl(r) || zeroWidthRunes[r] {
+			return true
+		}
+	}
+	return false
+}
+
+// SanitizeLogValue removes control and zero-width characters from user input,
+// so it can't be used to forge log lines. All log lines are sanitized by the
+// formatter of ConfigureLogging
+func SanitizeLogValue(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || zeroWidthRunes[r] {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,67 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestContainsForbiddenRunes(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected bool
+	}{
+		{"my-project", false},
+		{"Dieses Projekt wird gelöscht!", false},
+		{"-----BEGIN CERTIFICATE-----\r\nMIIB\n\tAB\n", false},
+		{"my-project\x1b[31m", true},
+		{"my\x00project", true},
+		{"my\u200bproject", true},
+		{"\ufeffmy-project", true},
+	}
+
+	for _, test := range tests {
+		if actual := ContainsForbiddenRunes(test.value); actual != test.expected {
+			t.Errorf("ContainsForbiddenRunes(%q): expected %v, got %v", test.value, test.expected, actual)
+		}
+	}
+}
+
+func TestSanitizeLogValue(t *testing.T) {
+	if s := SanitizeLogValue("user\r\nINFO fake\u200b"); s != "userINFO fake" {
+		t.Errorf("unexpected sanitized value: %q", s)
+	}
+}
+
+func TestRequestSanitizerMiddleware(t *testing.T) {
+	config.Init("test")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestSanitizerMiddleware())
+	r.POST("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		body     string
+		expected int
+	}{
+		{`{"project": "my-project"}`, http.StatusOK},
+		{`{"project": "my-project\nINFO fake"}`, http.StatusBadRequest},
+		{`{"project": "my\tproject"}`, http.StatusBadRequest},
+		{`{"labels": ["a\nb"]}`, http.StatusBadRequest},
+		{`{"name": "tls", "data": {"tls.crt": "-----BEGIN CERTIFICATE-----\r\nMIIB\n"}}`, http.StatusOK},
+		{`{"data": {"tls.crt": "MIIB\u200b"}}`, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.body, test.expected, w.Code)
+		}
+	}
+}
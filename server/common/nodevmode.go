@@ -0,0 +1,12 @@
+//go:build !mock
+// +build !mock
+
+package common
+
+import "github.com/gin-gonic/gin"
+
+// loginAuthenticator checks the password against the LDAP. The dev mode
+// login is only built with the tag 'mock'
+func loginAuthenticator() func(c *gin.Context) (interface{}, error) {
+	return ldapAuthenticator
+}
@@ -0,0 +1,153 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	texttemplate "text/template"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Events of the project lifecycle the requester is notified about
+const (
+	NotificationProjectCreated     = "project_created"
+	NotificationQuotaChanged       = "quota_changed"
+	NotificationTestProjectExpires = "testproject_expires"
+	NotificationProjectDeleted     = "project_deleted"
+)
+
+const notificationFooter = `
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP`
+
+// Notification is the data of the templates. Details are the values of the
+// event, e.g. the new quota
+type Notification struct {
+	Event     string
+	Username  string
+	ClusterId string
+	Project   string
+	Details   map[string]string
+}
+
+type notificationTemplate struct {
+	Subject string
+	Body    string
+}
+
+// defaultNotificationTemplates are used for the events without template in
+// 'notifications.templates'
+var defaultNotificationTemplates = map[string]notificationTemplate{
+	NotificationProjectCreated: {
+		Subject: "Projekt {{.Project}} wurde erstellt",
+		Body: `
+	Hallo {{.Username}},
+	<br><br>
+	Dein Projekt {{.Project}} auf Cluster {{.ClusterId}} wurde erstellt.` + notificationFooter,
+	},
+	NotificationQuotaChanged: {
+		Subject: "Quota von Projekt {{.Project}} wurde geändert",
+		Body: `
+	Hallo {{.Username}},
+	<br><br>
+	Die Quota deines Projekts {{.Project}} auf Cluster {{.ClusterId}} wurde von {{.Details.changedBy}} geändert:
+	<br><br>
+	CPU: {{.Details.cpu}}<br>
+	Memory: {{.Details.memory}} GB` + notificationFooter,
+	},
+	NotificationTestProjectExpires: {
+		Subject: "Testprojekt {{.Project}} wird gelöscht",
+		Body: `
+	Hallo {{.Username}},
+	<br><br>
+	Dein Testprojekt {{.Project}} auf Cluster {{.ClusterId}} wird am {{.Details.expires}} automatisch gelöscht.
+	<br><br>
+	Sichere bitte vorher alles, was du noch brauchst. Soll das Projekt länger bestehen, erstelle ein normales Projekt mit Kontierungsnummer.` + notificationFooter,
+	},
+	NotificationProjectDeleted: {
+		Subject: "Projekt {{.Project}} wurde gelöscht",
+		Body: `
+	Hallo {{.Username}},
+	<br><br>
+	Dein Projekt {{.Project}} auf Cluster {{.ClusterId}} wurde gelöscht.` + notificationFooter,
+	},
+}
+
+// Notify mails the notification to the user if 'notifications.enabled' is set
+// and the event isn't missing in 'notifications.events'. Errors are only logged
+func Notify(n Notification) {
+	cfg := config.Config()
+	if !cfg.GetBool("notifications.enabled") {
+		return
+	}
+	if events := cfg.GetStringSlice("notifications.events"); len(events) > 0 && !containsString(events, n.Event) {
+		return
+	}
+	go func() {
+		if err := SendNotification(n); err != nil {
+			log.Printf("Can't send the %v notification of project %v on cluster %v: %v", n.Event, n.Project, n.ClusterId, err)
+		}
+	}()
+}
+
+// SendNotification mails the notification to the user
+func SendNotification(n Notification) error {
+	mail := GetMailForUser(n.Username)
+	if mail == "" {
+		return fmt.Errorf("no mail address for '%v'", n.Username)
+	}
+	subject, body, err := renderNotification(n)
+	if err != nil {
+		return err
+	}
+	return SendMail([]string{mail}, subject, body)
+}
+
+// renderNotification executes the template of the event. The subject is
+// plain text, the body html
+func renderNotification(n Notification) (string, string, error) {
+	tmpl, ok := defaultNotificationTemplates[n.Event]
+	cfg := config.Config()
+	if subject := cfg.GetString("notifications.templates." + n.Event + ".subject"); subject != "" {
+		tmpl.Subject = subject
+	}
+	if body := cfg.GetString("notifications.templates." + n.Event + ".body"); body != "" {
+		tmpl.Body = body
+	}
+	if !ok && (tmpl.Subject == "" || tmpl.Body == "") {
+		return "", "", fmt.Errorf("no template for notification %v", n.Event)
+	}
+	if n.Details == nil {
+		n.Details = map[string]string{}
+	}
+
+	subject, err := texttemplate.New("subject").Parse(tmpl.Subject)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid subject of notification %v: %v", n.Event, err)
+	}
+	body, err := template.New("body").Parse(tmpl.Body)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid body of notification %v: %v", n.Event, err)
+	}
+	var s, b bytes.Buffer
+	if err := subject.Execute(&s, n); err != nil {
+		return "", "", err
+	}
+	if err := body.Execute(&b, n); err != nil {
+		return "", "", err
+	}
+	return s.String(), b.String(), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestRenderNotification(t *testing.T) {
+	config.Init("test")
+	n := Notification{
+		Event: NotificationQuotaChanged, Username: "u123", ClusterId: "prod", Project: "shop",
+		Details: map[string]string{"cpu": "4", "memory": "8", "changedBy": "<u456>"},
+	}
+	subject, body, err := renderNotification(n)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if subject != "Quota von Projekt shop wurde geändert" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	if !strings.Contains(body, "CPU: 4") || !strings.Contains(body, "&lt;u456&gt;") {
+		t.Errorf("expected the escaped details in the body, got %q", body)
+	}
+
+	cfg := config.Config()
+	cfg.Set("notifications.templates.quota_changed.subject", "[{{.ClusterId}}] Quota {{.Project}}")
+	defer cfg.Set("notifications.templates.quota_changed.subject", "")
+	if subject, _, _ := renderNotification(n); subject != "[prod] Quota shop" {
+		t.Errorf("expected the configured subject, got %q", subject)
+	}
+
+	if _, _, err := renderNotification(Notification{Event: "unknown"}); err == nil {
+		t.Error("expected an error for an event without template")
+	}
+}
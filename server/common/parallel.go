@@ -0,0 +1,51 @@
+package common
+
+import (
+	"sync"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+const defaultParallelWorkers = 8
+
+// parallelWorkers returns 'parallel_workers', the number of concurrent calls
+// to the backend apis for operations over many clusters or namespaces
+func parallelWorkers() int {
+	workers := config.Config().GetInt("parallel_workers")
+	if workers <= 0 {
+		return defaultParallelWorkers
+	}
+	return workers
+}
+
+// ForEachParallel calls fn for 0 to n-1 with a bounded pool of workers.
+// It waits for all calls and returns their errors by index, so the callers
+// can keep the partial results of the successful ones
+func ForEachParallel(n int, fn func(i int) error) []error {
+	return forEachParallel(n, parallelWorkers(), fn)
+}
+
+func forEachParallel(n, workers int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestForEachParallel(t *testing.T) {
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	results := make([]int, 20)
+
+	errs := forEachParallel(len(results), 4, func(i int) error {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+
+		time.Sleep(time.Millisecond)
+		results[i] = i * 2
+
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+		if i == 3 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	if maxRunning > 4 {
+		t.Errorf("expected at most 4 concurrent calls, got %v", maxRunning)
+	}
+	for i, err := range errs {
+		if (i == 3) != (err != nil) {
+			t.Errorf("unexpected error for %v: %v", i, err)
+		}
+		if results[i] != i*2 {
+			t.Errorf("expected the result of %v to be kept, got %v", i, results[i])
+		}
+	}
+
+	if errs := forEachParallel(0, 4, func(i int) error { return nil }); len(errs) != 0 {
+		t.Errorf("expected no errors without work, got %v", errs)
+	}
+}
@@ -0,0 +1,90 @@
+package common
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+// proxyDirect as 'proxy.<integration>' calls the integration without proxy
+const proxyDirect = "direct"
+
+// ProxyFor returns the proxy function for the http transport of an
+// integration (e.g. openshift, aws, newrelic). 'proxy.<integration>' overrides
+// the corporate proxy of 'https_proxy' and 'http_proxy', "direct" disables it.
+// Hosts in 'no_proxy' are always called directly
+func ProxyFor(integration string) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		cfg := config.Config()
+		if matchesNoProxy(cfg.GetString("no_proxy"), req.URL.Host) {
+			return nil, nil
+		}
+
+		proxy := cfg.GetString("proxy." + integration)
+		if proxy == "" {
+			proxy = cfg.GetString("https_proxy")
+			if req.URL.Scheme == "http" && cfg.GetString("http_proxy") != "" {
+				proxy = cfg.GetString("http_proxy")
+			}
+		}
+		if proxy == "" || proxy == proxyDirect {
+			return nil, nil
+		}
+		return parseProxy(proxy)
+	}
+}
+
+// HTTPClient returns a client which uses the proxy of the integration
+func HTTPClient(integration string) *http.Client {
+	return &http.Client{Transport: &http.Transport{Proxy: ProxyFor(integration)}}
+}
+
+// parseProxy accepts proxies with and without scheme (proxy.ch:9000)
+func parseProxy(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	return url.Parse(proxy)
+}
+
+// matchesNoProxy checks the host against the comma separated list of
+// 'no_proxy'. Supported are '*', domains (example.com matches its subdomains
+// too), ips, CIDRs and host:port
+func matchesNoProxy(noProxy, hostport string) bool {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	host = strings.ToLower(host)
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip := net.ParseIP(host); ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if entryHost, entryPort, err := net.SplitHostPort(entry); err == nil {
+			if entryPort != port {
+				continue
+			}
+			entry = entryHost
+		}
+		entry = strings.TrimPrefix(entry, "*")
+		entry = strings.TrimPrefix(entry, ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
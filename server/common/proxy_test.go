@@ -0,0 +1,59 @@
+package common
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestMatchesNoProxy(t *testing.T) {
+	noProxy := "localhost, .sbb.ch,example.com:8443,10.0.0.0/8"
+	tests := map[string]bool{
+		"localhost:8000":        true,
+		"master.sbb.ch":         true,
+		"sbb.ch":                true,
+		"notsbb.ch":             false,
+		"example.com:8443":      true,
+		"example.com:443":       false,
+		"10.1.2.3:443":          true,
+		"192.168.1.1":           false,
+		"insights-api.newrelic": false,
+	}
+	for host, expected := range tests {
+		if matchesNoProxy(noProxy, host) != expected {
+			t.Errorf("matchesNoProxy(%v) should be %v", host, expected)
+		}
+	}
+	if !matchesNoProxy("*", "anything.com") {
+		t.Error("expected * to match every host")
+	}
+}
+
+func TestProxyFor(t *testing.T) {
+	config.Init("test")
+	cfg := config.Config()
+	cfg.Set("https_proxy", "proxy.ch:9000")
+	cfg.Set("no_proxy", ".sbb.ch")
+	cfg.Set("proxy.openshift", "direct")
+	cfg.Set("proxy.aws", "http://aws-proxy.ch:8080")
+
+	tests := []struct {
+		integration, url, expected string
+	}{
+		{"newrelic", "https://insights-api.newrelic.com/v1", "http://proxy.ch:9000"},
+		{"newrelic", "https://api.sbb.ch/v1", ""},
+		{"openshift", "https://master.example.com:8443/api", ""},
+		{"aws", "https://s3.amazonaws.com", "http://aws-proxy.ch:8080"},
+	}
+	for _, test := range tests {
+		proxy, err := ProxyFor(test.integration)(httptest.NewRequest("GET", test.url, nil))
+		got := ""
+		if proxy != nil {
+			got = proxy.String()
+		}
+		if err != nil || got != test.expected {
+			t.Errorf("%v %v: expected proxy %q, got %q %v", test.integration, test.url, test.expected, got, err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	rateLimitError = "Zu viele Anfragen, bitte in %v Sekunden erneut versuchen"
+	// rateLimitSweep is the number of buckets after which the full ones are removed
+	rateLimitSweep = 10000
+)
+
+// RateLimit allows a user Requests per Period for the requests matching
+// Method (empty matches all) and Path. Paths ending with '*' are prefixes,
+// an empty path matches all requests
+type RateLimit struct {
+	Name     string `mapstructure:"name"`
+	Method   string `mapstructure:"method"`
+	Path     string `mapstructure:"path"`
+	Requests int    `mapstructure:"requests"`
+	Per      string `mapstructure:"per"`
+	period   time.Duration
+}
+
+// defaultRateLimits are used without 'rate_limit.rules'
+var defaultRateLimits = []RateLimit{
+	{Name: "api", Requests: 60, Per: "1m"},
+	{Name: "project_creation", Method: http.MethodPost, Path: "/api/ose/project", Requests: 5, Per: "1h"},
+	{Name: "testproject_creation", Method: http.MethodPost, Path: "/api/ose/testproject", Requests: 5, Per: "1h"},
+}
+
+func (l RateLimit) matches(r *http.Request) bool {
+	if l.Method != "" && !strings.EqualFold(l.Method, r.Method) {
+		return false
+	}
+	if strings.HasSuffix(l.Path, "*") {
+		return strings.HasPrefix(r.URL.Path, strings.TrimSuffix(l.Path, "*"))
+	}
+	return l.Path == "" || l.Path == strings.TrimSuffix(r.URL.Path, "/")
+}
+
+// tokenBucket holds up to the requests of its limit and is refilled
+// continuously over the period
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter keeps a bucket per limit and user in memory
+type rateLimiter struct {
+	limits  []RateLimit
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimiter(limits []RateLimit) *rateLimiter {
+	return &rateLimiter{limits: limits, buckets: make(map[string]*tokenBucket)}
+}
+
+// allow takes a token of every matching limit. If one is exhausted, nothing
+// is taken and the time until the next token is returned
+func (r *rateLimiter) allow(username string, req *http.Request, now time.Time) (bool, time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var matching []*tokenBucket
+	for _, l := range r.limits {
+		if !l.matches(req) {
+			continue
+		}
+		key := l.Name + "/" + username
+		b, ok := r.buckets[key]
+		if !ok {
+			b = &tokenBucket{tokens: float64(l.Requests), updated: now}
+			r.buckets[key] = b
+		}
+		rate := float64(l.Requests) / l.period.Seconds()
+		b.tokens = math.Min(float64(l.Requests), b.tokens+now.Sub(b.updated).Seconds()*rate)
+		b.updated = now
+		if b.tokens < 1 {
+			wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+			return false, wait
+		}
+		matching = append(matching, b)
+	}
+	for _, b := range matching {
+		b.tokens--
+	}
+	if len(r.buckets) > rateLimitSweep {
+		r.sweep(now)
+	}
+	return true, 0
+}
+
+// sweep removes the buckets which are full again, they're recreated on the
+// next request
+func (r *rateLimiter) sweep(now time.Time) {
+	periods := make(map[string]time.Duration)
+	for _, l := range r.limits {
+		periods[l.Name] = l.period
+	}
+	for key, b := range r.buckets {
+		name := key[:strings.Index(key, "/")]
+		if now.Sub(b.updated) >= periods[name] {
+			delete(r.buckets, key)
+		}
+	}
+}
+
+func getRateLimits() []RateLimit {
+	limits := []RateLimit{}
+	if err := config.Config().UnmarshalKey("rate_limit.rules", &limits); err != nil {
+		log.Printf("Error reading the rate limits, using the defaults: %v", err)
+	}
+	if len(limits) == 0 {
+		limits = defaultRateLimits
+	}
+	valid := []RateLimit{}
+	for _, l := range limits {
+		period, err := time.ParseDuration(l.Per)
+		if err != nil || period <= 0 || l.Requests <= 0 || l.Name == "" || strings.Contains(l.Name, "/") {
+			log.Printf("WARNING: ignoring the invalid rate limit %+v", l)
+			continue
+		}
+		l.period = period
+		valid = append(valid, l)
+	}
+	return valid
+}
+
+// RateLimitMiddleware limits the requests per user if 'rate_limit.enabled'
+// is set, to protect the clusters from scripts. It must run after the
+// authentication
+func RateLimitMiddleware() gin.HandlerFunc {
+	if !config.Config().GetBool("rate_limit.enabled") {
+		return func(c *gin.Context) { c.Next() }
+	}
+	limiter := newRateLimiter(getRateLimits())
+
+	return func(c *gin.Context) {
+		username := GetUserName(c)
+		allowed, wait := limiter.allow(username, c.Request, time.Now())
+		if !allowed {
+			seconds := int(math.Ceil(wait.Seconds()))
+			Logger(c).Warnf("Rate limited %v: %v %v", username, c.Request.Method, SanitizeLogValue(c.Request.URL.Path))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, ApiResponse{Message: fmt.Sprintf(rateLimitError, seconds)})
+			return
+		}
+		c.Next()
+	}
+}
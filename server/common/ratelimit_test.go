@@ -0,0 +1,39 @@
+package common
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter([]RateLimit{
+		{Name: "api", Requests: 3, period: time.Minute},
+		{Name: "project_creation", Method: "POST", Path: "/api/ose/project", Requests: 1, period: time.Hour},
+	})
+	now := time.Now()
+	get := httptest.NewRequest("GET", "/api/ose/projects", nil)
+	create := httptest.NewRequest("POST", "/api/ose/project", nil)
+
+	if ok, _ := limiter.allow("u123", create, now); !ok {
+		t.Fatal("expected the first project creation to be allowed")
+	}
+	if ok, wait := limiter.allow("u123", create, now); ok || wait != time.Hour {
+		t.Errorf("expected the second project creation to wait an hour, got %v %v", ok, wait)
+	}
+	// The project creation took a token of the api limit too
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.allow("u123", get, now); !ok {
+			t.Error("expected other requests to be allowed")
+		}
+	}
+	if ok, _ := limiter.allow("u123", get, now); ok {
+		t.Error("expected the api limit to be exhausted")
+	}
+	if ok, _ := limiter.allow("u456", get, now); !ok {
+		t.Error("expected the limits to be per user")
+	}
+	if ok, _ := limiter.allow("u123", get, now.Add(20*time.Second)); !ok {
+		t.Error("expected the bucket to be refilled")
+	}
+}
@@ -0,0 +1,224 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/appleboy/gin-jwt.v2"
+)
+
+const (
+	sessionsCollection = "sessions"
+	sessionClaim       = "sid"
+	sessionTimeout     = time.Hour
+	sessionEndedError  = "Die Sitzung ist abgelaufen oder wurde beendet. Bitte melde dich neu an"
+	// sessionCacheTTL is how long another instance of the portal accepts the
+	// token of a revoked session
+	sessionCacheTTL = 10 * time.Second
+)
+
+// sessionCache keeps the sessions read by RequireActiveSession, so not every
+// request reads the store
+var sessionCache = struct {
+	sync.Mutex
+	entries map[string]cachedSession
+}{entries: map[string]cachedSession{}}
+
+type cachedSession struct {
+	session Session
+	found   bool
+	readAt  time.Time
+}
+
+// Session is a login of a user. The token of the login is only accepted
+// while the session isn't revoked
+type Session struct {
+	ID        string     `json:"id"`
+	Username  string     `json:"username"`
+	IP        string     `json:"ip"`
+	UserAgent string     `json:"userAgent"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	RevokedBy string     `json:"revokedBy,omitempty"`
+}
+
+// Active is true if the session is neither expired nor revoked
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// sessionAuthenticator records a session for every successful login. The
+// expired sessions are removed at the same time
+func sessionAuthenticator(authenticate func(*gin.Context) (interface{}, error)) func(*gin.Context) (interface{}, error) {
+	return func(c *gin.Context) (interface{}, error) {
+		data, err := authenticate(c)
+		if err != nil {
+			return data, err
+		}
+		user, ok := data.(*User)
+		if !ok {
+			return data, err
+		}
+		now := time.Now()
+		session := Session{
+			ID:        RandomString(16),
+			Username:  strings.ToLower(user.UserId),
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			CreatedAt: now,
+			ExpiresAt: now.Add(sessionTimeout),
+		}
+		if err := store.Put(sessionsCollection, session.ID, session); err != nil {
+			log.Printf("Error recording the session of %v: %v", session.Username, err)
+			return nil, jwt.ErrFailedAuthentication
+		}
+		user.SessionID = session.ID
+		purgeExpiredSessions(now)
+		return user, nil
+	}
+}
+
+func purgeExpiredSessions(now time.Time) {
+	expired, err := GetSessions(func(s Session) bool { return !now.Before(s.ExpiresAt) })
+	if err != nil {
+		log.Printf("Error reading the expired sessions: %v", err)
+		return
+	}
+	for _, s := range expired {
+		if err := store.Delete(sessionsCollection, s.ID); err != nil {
+			log.Printf("Error deleting the expired session %v: %v", s.ID, err)
+		}
+	}
+}
+
+// GetSessions returns the matching sessions, the newest first
+func GetSessions(filter func(Session) bool) ([]Session, error) {
+	sessions := []Session{}
+	err := store.List(sessionsCollection, func(id string, data []byte) error {
+		var s Session
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if filter(s) {
+			sessions = append(sessions, s)
+		}
+		return nil
+	})
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions, err
+}
+
+// RevokeSessions ends the matching active sessions, their tokens are
+// rejected from now on. Returns the revoked sessions
+func RevokeSessions(filter func(Session) bool, revokedBy string) ([]Session, error) {
+	now := time.Now()
+	sessions, err := GetSessions(func(s Session) bool { return s.Active(now) && filter(s) })
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].RevokedAt = &now
+		sessions[i].RevokedBy = revokedBy
+		if err := store.Put(sessionsCollection, sessions[i].ID, sessions[i]); err != nil {
+			return nil, err
+		}
+		forgetSession(sessions[i].ID)
+		log.Printf("%v revoked the session %v of %v", revokedBy, sessions[i].ID, sessions[i].Username)
+	}
+	return sessions, nil
+}
+
+// RefreshHandler extends the session of the token by sessionTimeout and
+// returns a new token, see jwt.GinJWTMiddleware.RefreshHandler. It must run
+// after RequireActiveSession
+func RefreshHandler(mw *jwt.GinJWTMiddleware) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := GetSessionID(c)
+		now := time.Now()
+		var session Session
+		err := store.Update(sessionsCollection, id, &session, func(exists bool) error {
+			if !exists || !session.Active(now) {
+				return errors.New(sessionEndedError)
+			}
+			session.ExpiresAt = now.Add(sessionTimeout)
+			return nil
+		})
+		forgetSession(id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ApiResponse{Message: sessionEndedError})
+			return
+		}
+		mw.RefreshHandler(c)
+	}
+}
+
+// getSession reads the session from the store or the sessionCache
+func getSession(id string, now time.Time) (Session, bool, error) {
+	sessionCache.Lock()
+	cached, ok := sessionCache.entries[id]
+	sessionCache.Unlock()
+	if ok && now.Sub(cached.readAt) < sessionCacheTTL {
+		return cached.session, cached.found, nil
+	}
+
+	var session Session
+	found, err := store.Get(sessionsCollection, id, &session)
+	if err != nil {
+		return session, false, err
+	}
+	sessionCache.Lock()
+	defer sessionCache.Unlock()
+	for key, entry := range sessionCache.entries {
+		if now.Sub(entry.readAt) >= sessionCacheTTL {
+			delete(sessionCache.entries, key)
+		}
+	}
+	sessionCache.entries[id] = cachedSession{session: session, found: found, readAt: now}
+	return session, found, nil
+}
+
+func forgetSession(id string) {
+	sessionCache.Lock()
+	delete(sessionCache.entries, id)
+	sessionCache.Unlock()
+}
+
+// GetSessionID returns the session of the token of the request
+func GetSessionID(c *gin.Context) string {
+	id, _ := jwt.ExtractClaims(c)[sessionClaim].(string)
+	return id
+}
+
+// RequireActiveSession is a gin middleware which rejects the tokens of
+// revoked and unknown sessions. It must run after the authentication. The
+// sessions are cached for sessionCacheTTL
+func RequireActiveSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("JWT_PAYLOAD"); !ok {
+			c.Next()
+			return
+		}
+		now := time.Now()
+		session, found, err := getSession(GetSessionID(c), now)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ApiResponse{Message: err.Error()})
+			return
+		}
+		if !found || !session.Active(now) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ApiResponse{Message: sessionEndedError})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store/storetest"
+	"github.com/gin-gonic/gin"
+)
+
+// sessionRouter returns a router with the routes of the sessions, a login
+// and a call of a route with the token
+func sessionRouter(t *testing.T) (func() string, func(path, token string) (int, string)) {
+	gin.SetMode(gin.TestMode)
+	authMiddleware := GetAuthMiddleware()
+	// Accepts every login instead of asking the LDAP
+	authMiddleware.Authenticator = sessionAuthenticator(func(c *gin.Context) (interface{}, error) {
+		var loginVals login
+		c.ShouldBind(&loginVals)
+		return &User{UserId: loginVals.Username}, nil
+	})
+	router := gin.New()
+	router.POST("/login", authMiddleware.LoginHandler)
+	api := router.Group("/api", authMiddleware.MiddlewareFunc(), RequireActiveSession())
+	api.GET("/refresh_token", RefreshHandler(authMiddleware))
+	api.GET("/projects", func(c *gin.Context) { c.String(http.StatusOK, GetSessionID(c)) })
+
+	loginAs := func() string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(`{"username":"U123","password":"x"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		var response struct{ Token string }
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Token == "" {
+			t.Fatalf("login failed: %v %v", w.Code, w.Body.String())
+		}
+		return response.Token
+	}
+	call := func(path, token string) (int, string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+	return loginAs, call
+}
+
+func TestSessions(t *testing.T) {
+	defer storetest.Setup(t)()
+	login, callPath := sessionRouter(t)
+	call := func(token string) (int, string) { return callPath("/api/projects", token) }
+
+	token, other := login(), login()
+	code, sessionID := call(token)
+	if code != http.StatusOK || sessionID == "" {
+		t.Fatalf("expected the token of the session to be accepted, got %v %v", code, sessionID)
+	}
+	sessions, _ := GetSessions(func(s Session) bool { return s.Username == "u123" })
+	if len(sessions) != 2 {
+		t.Errorf("expected a session per login, got %+v", sessions)
+	}
+
+	revoked, err := RevokeSessions(func(s Session) bool { return s.ID == sessionID }, "admin")
+	if err != nil || len(revoked) != 1 || revoked[0].RevokedBy != "admin" {
+		t.Fatalf("expected the session to be revoked, got %+v %v", revoked, err)
+	}
+	if code, _ := call(token); code != http.StatusUnauthorized {
+		t.Errorf("expected the token of the revoked session to be rejected, got %v", code)
+	}
+	if code, _ := call(other); code != http.StatusOK {
+		t.Errorf("expected the other session to stay active, got %v", code)
+	}
+}
+
+func TestRefreshExtendsTheSession(t *testing.T) {
+	defer storetest.Setup(t)()
+	login, call := sessionRouter(t)
+
+	token := login()
+	_, id := call("/api/projects", token)
+	var session Session
+	store.Get(sessionsCollection, id, &session)
+	// The session is about to expire
+	session.ExpiresAt = time.Now().Add(time.Minute)
+	store.Put(sessionsCollection, id, session)
+	forgetSession(id)
+
+	code, body := call("/api/refresh_token", token)
+	var response struct{ Token string }
+	if err := json.Unmarshal([]byte(body), &response); err != nil || code != http.StatusOK || response.Token == "" {
+		t.Fatalf("expected a new token, got %v %v", code, body)
+	}
+	store.Get(sessionsCollection, id, &session)
+	if time.Until(session.ExpiresAt) < sessionTimeout-time.Minute {
+		t.Errorf("expected the session to be extended, got %v", session.ExpiresAt)
+	}
+	if code, refreshedID := call("/api/projects", response.Token); code != http.StatusOK || refreshedID != id {
+		t.Errorf("expected the new token to belong to the session, got %v %v", code, refreshedID)
+	}
+
+	RevokeSessions(func(s Session) bool { return s.ID == id }, "admin")
+	if code, _ := call("/api/refresh_token", response.Token); code != http.StatusUnauthorized {
+		t.Errorf("expected a revoked session not to be refreshed, got %v", code)
+	}
+}
@@ -1,9 +1,9 @@
 package config
 
 import (
-	"log"
 	"strings"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
@@ -22,12 +22,12 @@ const apiErrorDDC = "Fehler beim Aufruf der DDC-API. Bitte erstelle ein Ticket b
 
 // RegisterRoutes registers the routes for OpenShift
 func RegisterRoutes(r *gin.RouterGroup) {
-	r.GET("/ddc/billing", getDDCBillingHandler)
+	r.GET("/ddc/billing", common.ETag(), getDDCBillingHandler)
 }
 
 func getDDCBillingHandler(c *gin.Context) {
 	username := common.GetUserName(c)
-	log.Println("Called DDC Billing: ", username)
+	common.Logger(c).Println("Called DDC Billing: ", username)
 
 	rows, err := calculateDDCBilling()
 	result := createCSVReport(rows)
@@ -154,6 +154,7 @@ func getDDCClient() (*http.Client, *http.Request) {
 
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		Proxy:           common.ProxyFor("ddc"),
 	}
 	client := &http.Client{Transport: tr}
 
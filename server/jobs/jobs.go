@@ -0,0 +1,278 @@
+// Package jobs runs long operations like the project creation in the
+// background. The request gets the id of the job immediately and the client
+// polls the state on /jobs/:id. Jobs run in this process, the state is kept
+// in the store so it can be polled after a restart.
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+
+	collection            = "jobs"
+	defaultWorkers        = 4
+	defaultRetentionHours = 24
+
+	// The instances renew their lease every heartbeatInterval while they run.
+	// The running jobs of an instance whose lease expired are failed
+	heartbeatInterval = time.Minute
+	heartbeatTTL      = 5 * time.Minute
+)
+
+// Job is a long operation of a user
+type Job struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	State string `json:"state"`
+	// Message is the result of a succeeded job or the error of a failed one
+	Message    string     `json:"message,omitempty"`
+	CreatedBy  string     `json:"createdBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	// Instance is the instance of the portal which runs the job
+	Instance string `json:"instance,omitempty"`
+}
+
+// Response is returned by the endpoints which started a job
+type Response struct {
+	Message string `json:"message"`
+	Job     Job    `json:"job"`
+}
+
+// Done is true if the job succeeded or failed
+func (j Job) Done() bool {
+	return j.State == StateSucceeded || j.State == StateFailed
+}
+
+var (
+	workers     chan struct{}
+	workersOnce sync.Once
+)
+
+func slots() chan struct{} {
+	workersOnce.Do(func() {
+		n := config.Config().GetInt("jobs.workers")
+		if n <= 0 {
+			n = defaultWorkers
+		}
+		workers = make(chan struct{}, n)
+	})
+	return workers
+}
+
+// Submit stores a queued job and runs it in the background. At most
+// 'jobs.workers' jobs run at the same time. The message of run is the
+// message of the job
+func Submit(kind, username string, run func() (string, error)) (Job, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return Job{}, err
+	}
+	job := Job{
+		ID:        id.String(),
+		Kind:      kind,
+		State:     StateQueued,
+		CreatedBy: username,
+		CreatedAt: common.Now(),
+		Instance:  common.InstanceID(),
+	}
+	if err := store.Put(collection, job.ID, job); err != nil {
+		return Job{}, err
+	}
+
+	workers := slots()
+	go func(job Job) {
+		workers <- struct{}{}
+		defer func() { <-workers }()
+
+		started := common.Now()
+		job.State = StateRunning
+		job.StartedAt = &started
+		save(job)
+
+		message, err := run()
+		finished := common.Now()
+		job.FinishedAt = &finished
+		job.State, job.Message = StateSucceeded, message
+		if err != nil {
+			job.State, job.Message = StateFailed, err.Error()
+			log.Printf("Job %v (%v) of %v failed: %v", job.ID, job.Kind, job.CreatedBy, err)
+		}
+		save(job)
+	}(job)
+	return job, nil
+}
+
+func save(job Job) {
+	if err := store.Put(collection, job.ID, job); err != nil {
+		log.Printf("Error saving job %v: %v", job.ID, err)
+	}
+}
+
+// Get returns the job with the id. Returns false if there is none
+func Get(id string) (Job, bool, error) {
+	var job Job
+	found, err := store.Get(collection, id, &job)
+	return job, found, err
+}
+
+func list() ([]Job, error) {
+	jobs := []Job{}
+	err := store.List(collection, func(id string, data []byte) error {
+		var j Job
+		if err := json.Unmarshal(data, &j); err != nil {
+			return err
+		}
+		jobs = append(jobs, j)
+		return nil
+	})
+	return jobs, err
+}
+
+// Start fails the jobs which were interrupted by a restart and deletes the
+// finished jobs after 'jobs.retention_hours' every hour. The jobs of other
+// instances are failed once they stopped, see heartbeatInterval
+func Start() {
+	heartbeat()
+	if err := failInterrupted(true); err != nil {
+		log.Printf("Error failing the interrupted jobs: %v", err)
+	}
+	go func() {
+		for {
+			time.Sleep(heartbeatInterval)
+			heartbeat()
+		}
+	}()
+	go func() {
+		for {
+			if err := cleanup(common.Now()); err != nil {
+				log.Printf("Error deleting old jobs: %v", err)
+			}
+			time.Sleep(time.Hour)
+			if err := failInterrupted(false); err != nil {
+				log.Printf("Error failing the interrupted jobs: %v", err)
+			}
+		}
+	}()
+}
+
+func leaseName(instance string) string {
+	return "jobs/" + instance
+}
+
+func heartbeat() {
+	if _, err := store.TryLease(leaseName(common.InstanceID()), common.InstanceID(), heartbeatTTL); err != nil {
+		log.Printf("Error renewing the jobs lease of instance %v: %v", common.InstanceID(), err)
+	}
+}
+
+// instanceStopped is true if the instance didn't renew its lease. The lease
+// is taken over, but expires immediately
+func instanceStopped(instance string) (bool, error) {
+	return store.TryLease(leaseName(instance), common.InstanceID(), 0)
+}
+
+// failInterrupted fails the unfinished jobs of the instances which stopped.
+// With own the jobs of this instance are failed too, which are left from
+// before a restart. Jobs of older versions have no instance and are failed
+// with the own ones
+func failInterrupted(own bool) error {
+	jobs, err := list()
+	if err != nil {
+		return err
+	}
+	now := common.Now()
+	for _, j := range jobs {
+		if j.Done() {
+			continue
+		}
+		switch {
+		case j.Instance == "" || j.Instance == common.InstanceID():
+			if !own {
+				continue
+			}
+		default:
+			stopped, err := instanceStopped(j.Instance)
+			if err != nil {
+				return err
+			}
+			if !stopped {
+				continue
+			}
+		}
+
+		err := store.Update(collection, j.ID, &j, func(exists bool) error {
+			// The job may have finished since it was listed
+			if !exists || j.Done() {
+				return errJobDone
+			}
+			j.State = StateFailed
+			j.Message = "Der Auftrag wurde durch einen Neustart des Portals abgebrochen"
+			j.FinishedAt = &now
+			return nil
+		})
+		if err != nil && err != errJobDone {
+			return err
+		}
+	}
+	return nil
+}
+
+var errJobDone = errors.New("job is done")
+
+func cleanup(now time.Time) error {
+	hours := config.Config().GetInt("jobs.retention_hours")
+	if hours <= 0 {
+		hours = defaultRetentionHours
+	}
+	jobs, err := list()
+	if err != nil {
+		return err
+	}
+	for _, j := range jobs {
+		if j.Done() && j.FinishedAt.Add(time.Duration(hours)*time.Hour).Before(now) {
+			if err := store.Delete(collection, j.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/jobs/:id", getJobHandler)
+}
+
+// getJobHandler returns the job to its creator and the portal admins
+func getJobHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	job, found, err := Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !found || (job.CreatedBy != username && !common.IsPortalAdmin(username)) {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: fmt.Sprintf("Der Auftrag %v existiert nicht", c.Param("id"))})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
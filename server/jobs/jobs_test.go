@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store/storetest"
+	"github.com/gin-gonic/gin"
+)
+
+func waitFor(t *testing.T, id string) Job {
+	for i := 0; i < 100; i++ {
+		job, found, err := Get(id)
+		if err != nil || !found {
+			t.Fatalf("expected job %v, got %v %v", id, found, err)
+		}
+		if job.Done() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %v didn't finish", id)
+	return Job{}
+}
+
+func TestSubmit(t *testing.T) {
+	defer storetest.Setup(t)()
+
+	release := make(chan struct{})
+	job, err := Submit("project-creation", "u123", func() (string, error) {
+		<-release
+		return "Das Projekt wurde erstellt", nil
+	})
+	if err != nil || job.State != StateQueued {
+		t.Fatalf("expected a queued job, got %+v %v", job, err)
+	}
+	close(release)
+	if job = waitFor(t, job.ID); job.State != StateSucceeded || job.Message != "Das Projekt wurde erstellt" || job.FinishedAt == nil {
+		t.Errorf("expected the job to succeed, got %+v", job)
+	}
+
+	failed, _ := Submit("project-creation", "u123", func() (string, error) {
+		return "", errors.New("Der Cluster ist nicht erreichbar")
+	})
+	if failed = waitFor(t, failed.ID); failed.State != StateFailed || failed.Message != "Der Cluster ist nicht erreichbar" {
+		t.Errorf("expected the job to fail, got %+v", failed)
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(gin.AuthUserKey, c.GetHeader("X-User")) })
+	RegisterRoutes(router.Group("/api/"))
+	for user, status := range map[string]int{"u123": http.StatusOK, "u456": http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/api/jobs/"+job.ID, nil)
+		r.Header.Set("X-User", user)
+		router.ServeHTTP(w, r)
+		if w.Code != status {
+			t.Errorf("%v: expected %v, got %v %v", user, status, w.Code, w.Body.String())
+		}
+	}
+
+	config.Config().Set("instance_id", "pod-a")
+	defer config.Config().Set("instance_id", "")
+	if _, err := store.TryLease(leaseName("pod-b"), "pod-b", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for id, instance := range map[string]string{"own": "pod-a", "legacy": "", "running": "pod-b", "stopped": "pod-c"} {
+		if err := store.Put(collection, id, Job{ID: id, State: StateRunning, CreatedBy: "u123", Instance: instance}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := failInterrupted(false); err != nil {
+		t.Fatal(err)
+	}
+	for id, state := range map[string]string{"own": StateRunning, "legacy": StateRunning, "running": StateRunning, "stopped": StateFailed} {
+		if job, _, _ := Get(id); job.State != state {
+			t.Errorf("expected the job %v to be %v, got %+v", id, state, job)
+		}
+	}
+	// After a restart
+	if err := failInterrupted(true); err != nil {
+		t.Fatal(err)
+	}
+	for id, state := range map[string]string{"own": StateFailed, "legacy": StateFailed, "running": StateRunning} {
+		if job, _, _ := Get(id); job.State != state {
+			t.Errorf("expected the job %v to be %v after the restart, got %+v", id, state, job)
+		}
+	}
+	store.Delete(collection, "running")
+
+	if err := cleanup(time.Now().Add(25 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if jobs, _ := list(); len(jobs) != 0 {
+		t.Errorf("expected the finished jobs to be deleted, got %+v", jobs)
+	}
+}
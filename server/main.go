@@ -4,20 +4,32 @@ import (
 	log "github.com/sirupsen/logrus"
 	"net/http"
 
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/approval"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/aws"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/catalog"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/ddc"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/jobs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/maintenance"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/openapi"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/openshift"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/otc"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/selftest"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/sematext"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/user"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/webhooks"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
 	config.Init("bla")
+	startMock()
+	devMode := startDevMode()
 
+	common.ConfigureLogging()
 	log.SetReportCaller(true)
 
 	if config.Config().GetBool("debug") {
@@ -28,12 +40,16 @@ func main() {
 	}
 
 	router := gin.New()
+	router.Use(common.RequestIDMiddleware())
 	router.Use(gin.Recovery())
+	router.Use(common.RequestSanitizerMiddleware())
+	router.Use(common.DryRunMiddleware())
 
 	// Allow cors
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowAllOrigins = true
-	corsConfig.AddAllowHeaders("authorization", "*")
+	corsConfig.AddAllowHeaders("authorization", "if-none-match", "*")
+	corsConfig.AddExposeHeaders("etag", common.RequestIDHeader)
 	corsConfig.AddAllowMethods("DELETE")
 	router.Use(cors.New(corsConfig))
 
@@ -41,27 +57,69 @@ func main() {
 	authMiddleware := common.GetAuthMiddleware()
 	router.POST("/login", authMiddleware.LoginHandler)
 	router.GET("/features", featuresHandler)
+	router.GET("/metrics", approval.MetricsHandler)
 
 	// Protected routes
 	auth := router.Group("/api/")
 	auth.Use(authMiddleware.MiddlewareFunc())
+	auth.Use(common.RequireActiveSession())
+	auth.Use(common.RateLimitMiddleware())
+	auth.Use(audit.Middleware())
+	auth.Use(maintenance.Middleware())
 	{
+		// New token for the session, which is extended
+		auth.GET("/refresh_token", common.RefreshHandler(authMiddleware))
+
 		// Openshift routes
 		openshift.RegisterRoutes(auth)
 
 		// DDC routes
 		ddc.RegisterRoutes(auth)
 
-		// AWS routes
-		aws.RegisterRoutes(auth)
+		// AWS, OTC and Sematext can't be faked in dev mode
+		if !devMode {
+			// AWS routes
+			aws.RegisterRoutes(auth)
+
+			// OTC routes
+			otc.RegisterRoutes(auth)
+
+			// Sematext routes
+			sematext.RegisterRoutes(auth)
+
+			aws.RegisterCatalogOfferings()
+			sematext.RegisterCatalogOfferings()
+			aws.RegisterAttachmentStorage()
+		}
+
+		// Routes of the current user
+		user.RegisterRoutes(auth)
+
+		// Self-service catalog
+		catalog.RegisterRoutes(auth)
 
-		// OTC routes
-		otc.RegisterRoutes(auth)
+		// Requests which have to be approved by a portal admin
+		approval.RegisterRoutes(auth)
 
-		// Sematext routes
-		sematext.RegisterRoutes(auth)
+		// Selftest of the config and the dependencies
+		selftest.RegisterRoutes(auth)
+
+		// Endpoints disabled during maintenance
+		maintenance.RegisterRoutes(auth)
+
+		// State of the long operations which run in the background
+		jobs.RegisterRoutes(auth)
+
+		// Who changed what
+		audit.RegisterRoutes(auth)
+
+		// Webhooks of the teams
+		webhooks.RegisterRoutes(auth)
 	}
 
+	// OpenAPI specification and Swagger UI of all routes
+	openapi.RegisterRoutes(router)
+
 	secApiPassword := config.Config().GetString("sec_api_password")
 	if secApiPassword != "" {
 		log.Println("Activating secure api (basic auth)")
@@ -71,6 +129,32 @@ func main() {
 		log.Println("Secure api (basic auth) won't be activated, because SEC_API_PASSWORD isn't set")
 	}
 
+	openshift.RegisterApprovalKinds()
+
+	selftest.RegisterDefaultChecks()
+	openshift.RegisterSelfTests()
+	selftest.RunOnStartup()
+
+	// Background jobs
+	jobs.Start()
+	audit.StartSIEMExport()
+	approval.StartSLATimer()
+	openshift.StartCostAnomalyDetection()
+	openshift.StartSandboxJanitor()
+	openshift.StartTestProjectJanitor()
+	openshift.StartRecycleBinPurge()
+	openshift.StartLoadTestWindows()
+	openshift.StartDriftDetection()
+	openshift.StartReportScheduler()
+	openshift.StartBudgetCheck()
+	openshift.StartAccessReviews()
+	openshift.StartSecretExpiryReminders()
+	openshift.StartTokenRenewal()
+	openshift.StartKubeconfigExpiry()
+	openshift.StartOwnerlessProjectDetection()
+	openshift.StartBreakGlassRevocation()
+	openshift.StartUsageSnapshots()
+
 	log.Println("Cloud SSP is running")
 
 	port := config.Config().GetString("port")
@@ -0,0 +1,199 @@
+// Package maintenance lets portal admins disable single endpoints at runtime,
+// e.g. the project creation during a cluster upgrade. Disabled endpoints
+// answer with the message and the expected end of the maintenance.
+package maintenance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	collection     = "maintenance"
+	routePath      = "/admin/maintenance"
+	defaultMessage = "Diese Funktion ist wegen Wartungsarbeiten vorübergehend deaktiviert"
+
+	wrongAPIUsageError = "Ungültiger API-Aufruf: Die Argumente stimmen nicht mit der definition überein. Bitte erstelle eine Ticket"
+)
+
+// Toggle disables the requests matching Method and Path. An empty method
+// matches all methods, the path matches itself and everything below it
+type Toggle struct {
+	Method     string     `json:"method"`
+	Path       string     `json:"path"`
+	Message    string     `json:"message"`
+	ETA        *time.Time `json:"eta,omitempty"`
+	DisabledBy string     `json:"disabledBy"`
+	DisabledAt time.Time  `json:"disabledAt"`
+}
+
+// Response is returned by disabled endpoints
+type Response struct {
+	Message string     `json:"message"`
+	ETA     *time.Time `json:"eta,omitempty"`
+}
+
+func (t Toggle) id() string {
+	return t.Method + " " + t.Path
+}
+
+func (t Toggle) matches(method, path string) bool {
+	if t.Method != "" && t.Method != method {
+		return false
+	}
+	return path == t.Path || strings.HasPrefix(path, strings.TrimSuffix(t.Path, "/")+"/")
+}
+
+func normalize(t *Toggle) error {
+	t.Method = strings.ToUpper(strings.TrimSpace(t.Method))
+	t.Path = strings.TrimSpace(t.Path)
+	if t.Path == "" {
+		return errors.New("Der Pfad muss angegeben werden")
+	}
+	if !strings.HasPrefix(t.Path, "/") {
+		t.Path = "/" + t.Path
+	}
+	if strings.HasSuffix(t.Path, routePath) {
+		return errors.New("Die Wartungs-Endpunkte können nicht deaktiviert werden")
+	}
+	if t.Message == "" {
+		t.Message = defaultMessage
+	}
+	return nil
+}
+
+// List returns the active toggles sorted by path
+func List() ([]Toggle, error) {
+	toggles := []Toggle{}
+	err := store.List(collection, func(id string, data []byte) error {
+		var t Toggle
+		if err := json.Unmarshal(data, &t); err != nil {
+			return err
+		}
+		toggles = append(toggles, t)
+		return nil
+	})
+	sort.Slice(toggles, func(i, j int) bool {
+		return toggles[i].id() < toggles[j].id()
+	})
+	return toggles, err
+}
+
+// Disable stores the toggle, an existing toggle of the same endpoint is replaced
+func Disable(t Toggle) (*Toggle, error) {
+	if err := normalize(&t); err != nil {
+		return nil, err
+	}
+	t.DisabledAt = common.Now()
+	if err := store.Put(collection, t.id(), t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Enable removes the toggle of the endpoint
+func Enable(method, path string) error {
+	t := Toggle{Method: method, Path: path}
+	if err := normalize(&t); err != nil {
+		return err
+	}
+	found, err := store.Get(collection, t.id(), &Toggle{})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("Der Endpunkt %v ist nicht deaktiviert", strings.TrimSpace(t.id()))
+	}
+	return store.Delete(collection, t.id())
+}
+
+// Middleware rejects the requests to disabled endpoints with 503. The
+// endpoints to manage the toggles are never disabled
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if strings.HasSuffix(path, routePath) {
+			c.Next()
+			return
+		}
+		toggles, err := List()
+		if err != nil {
+			// The portal stays usable if the store is broken
+			log.Printf("Error reading the maintenance toggles: %v", err)
+			c.Next()
+			return
+		}
+		for _, t := range toggles {
+			if !t.matches(c.Request.Method, path) {
+				continue
+			}
+			if t.ETA != nil {
+				if seconds := int(t.ETA.Sub(common.Now()).Seconds()); seconds > 0 {
+					c.Header("Retry-After", fmt.Sprint(seconds))
+				}
+			}
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, Response{Message: t.Message, ETA: t.ETA})
+			return
+		}
+		c.Next()
+	}
+}
+
+func RegisterRoutes(r *gin.RouterGroup) {
+	admin := r.Group("/admin", common.RequirePortalAdmin())
+	admin.GET("/maintenance", listHandler)
+	admin.POST("/maintenance", disableHandler)
+	admin.DELETE("/maintenance", enableHandler)
+}
+
+func listHandler(c *gin.Context) {
+	toggles, err := List()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, toggles)
+}
+
+func disableHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data Toggle
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	data.DisabledBy = username
+
+	toggle, err := Disable(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v disabled %v for maintenance", username, strings.TrimSpace(toggle.id()))
+	c.JSON(http.StatusOK, toggle)
+}
+
+func enableHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	method := c.Query("method")
+	path := c.Query("path")
+
+	if err := Enable(method, path); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v enabled %v %v after maintenance", username, method, path)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: fmt.Sprintf("Der Endpunkt %v ist wieder aktiviert", path)})
+}
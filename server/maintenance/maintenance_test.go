@@ -0,0 +1,51 @@
+package maintenance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store/storetest"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware(t *testing.T) {
+	defer storetest.Setup(t)()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/api/ose/newproject", ok)
+	router.GET("/api/ose/projects", ok)
+	router.POST("/api/admin/maintenance", ok)
+
+	status := func(method, path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code
+	}
+
+	if _, err := Disable(Toggle{Method: "post", Path: "api/ose"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code := status("POST", "/api/ose/newproject"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected the project creation to be disabled, got %v", code)
+	}
+	if code := status("GET", "/api/ose/projects"); code != http.StatusOK {
+		t.Errorf("expected reading requests to work, got %v", code)
+	}
+	if _, err := Disable(Toggle{Path: "/api/admin/maintenance"}); err == nil {
+		t.Error("expected the maintenance endpoints not to be disableable")
+	}
+
+	if err := Enable("POST", "/api/ose"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code := status("POST", "/api/ose/newproject"); code != http.StatusOK {
+		t.Errorf("expected the project creation to be enabled again, got %v", code)
+	}
+	if err := Enable("POST", "/api/ose"); err == nil {
+		t.Error("expected an error for an endpoint which isn't disabled")
+	}
+}
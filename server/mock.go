@@ -0,0 +1,43 @@
+//go:build mock
+// +build mock
+
+package main
+
+import (
+	"flag"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/openshift"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/openshift/fakeapi"
+	log "github.com/sirupsen/logrus"
+)
+
+var mock = flag.Bool("mock", false, "Use an in-memory OpenShift api instead of the configured clusters")
+
+// startMock replaces the clusters in 'openshift' with a fake api if started
+// with --mock. Only available in builds with the tag 'mock', e.g.
+// 'go run -tags mock . --mock'. Nothing is persisted across restarts
+func startMock() {
+	flag.Parse()
+	if !*mock {
+		return
+	}
+
+	api := fakeapi.New()
+	config.Config().Set("openshift", []map[string]interface{}{api.Cluster("mock")})
+	log.Printf("WARNING: using the fake OpenShift api %v as cluster 'mock'", api.URL)
+}
+
+// startDevMode fakes the clusters and the store if 'dev_mode.enabled' is set
+// and disables the services which can't be faked (AWS, OTC, Sematext), so no
+// call of the dev mode reaches a real service. Returns true in dev mode
+func startDevMode() bool {
+	cfg := config.Config()
+	if !cfg.GetBool("dev_mode.enabled") {
+		return false
+	}
+	openshift.StartDevMode()
+	cfg.Set("otc_api", "")
+	log.Println("WARNING: dev mode is enabled, the AWS, OTC and Sematext routes are disabled")
+	return true
+}
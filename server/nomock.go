@@ -0,0 +1,20 @@
+//go:build !mock
+// +build !mock
+
+package main
+
+import (
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// startMock is a no-op, the fake api is only built with the tag 'mock'
+func startMock() {}
+
+// startDevMode is a no-op, the dev mode is only built with the tag 'mock'
+func startDevMode() bool {
+	if config.Config().GetBool("dev_mode.enabled") {
+		log.Println("WARNING: ignoring 'dev_mode.enabled', the dev mode is only available in builds with the tag 'mock'")
+	}
+	return false
+}
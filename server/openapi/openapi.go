@@ -0,0 +1,255 @@
+// Package openapi generates the OpenAPI 3 specification of the api from the
+// routes of the router, so integrators can script against the portal. Every
+// route is listed with its path parameters. Handlers can be described with
+// a summary, their query parameters and json bodies, whose schemas are
+// derived from the go types.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultSwaggerUIURL = "https://unpkg.com/swagger-ui-dist@3"
+
+// Operation describes a handler
+type Operation struct {
+	Summary string
+	// Query are the names of the query parameters
+	Query []string
+	// Body and Response are values of the types of the json bodies
+	Body     interface{}
+	Response interface{}
+}
+
+var operations = make(map[string]Operation)
+
+// Describe adds the description of the handler to the specification. It
+// must be called at startup
+func Describe(h gin.HandlerFunc, op Operation) {
+	operations[common.HandlerName(h)] = op
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas collects the named types of the bodies as components
+type schemas map[string]interface{}
+
+func (s schemas) of(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawJSONType || t.Kind() == reflect.Interface:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := s[t.Name()]; !ok {
+			// Set before the fields, so recursive types end
+			s[t.Name()] = map[string]interface{}{}
+			s[t.Name()] = s.object(t)
+		}
+		return ref
+	}
+	return map[string]interface{}{}
+}
+
+func (s schemas) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	s.addFields(t, properties)
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// addFields adds the exported fields by their json names. The fields of
+// embedded structs are added to the outer object like encoding/json does
+func (s schemas) addFields(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" || (f.PkgPath != "" && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			s.addFields(f.Type, properties)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = s.of(f.Type)
+	}
+}
+
+// Spec returns the specification of the routes
+func Spec(routes gin.RoutesInfo) map[string]interface{} {
+	components := make(schemas)
+	apiResponse := components.of(reflect.TypeOf(common.ApiResponse{}))
+	paths := make(map[string]map[string]interface{})
+
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Path < routes[j].Path
+	})
+	for _, route := range routes {
+		path, parameters := pathParameters(route.Path)
+		op := operations[route.Handler]
+
+		summary := op.Summary
+		if summary == "" {
+			summary = route.Handler[strings.LastIndex(route.Handler, ".")+1:]
+		}
+		for _, q := range op.Query {
+			parameters = append(parameters, map[string]interface{}{
+				"name": q, "in": "query", "schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		if common.SupportsDryRunHandler(route.Handler) {
+			parameters = append(parameters, map[string]interface{}{
+				"name": "dryRun", "in": "query", "description": "Returns the planned changes without changing anything",
+				"schema": map[string]interface{}{"type": "boolean"},
+			})
+		}
+
+		ok := map[string]interface{}{"description": "OK"}
+		if op.Response != nil {
+			ok["content"] = jsonContent(components.of(reflect.TypeOf(op.Response)))
+		}
+		operation := map[string]interface{}{
+			"summary":    summary,
+			"tags":       []string{tagOf(route.Handler)},
+			"parameters": parameters,
+			"responses": map[string]interface{}{
+				"200": ok,
+				"400": map[string]interface{}{"description": "Error", "content": jsonContent(apiResponse)},
+			},
+		}
+		if op.Body != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(components.of(reflect.TypeOf(op.Body))),
+			}
+		}
+		switch {
+		case strings.HasPrefix(path, "/api/"):
+			operation["security"] = []map[string][]string{{"bearerAuth": {}}}
+		case strings.HasPrefix(path, "/sec/"):
+			operation["security"] = []map[string][]string{{"basicAuth": {}}}
+		}
+
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		paths[path][strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]interface{}{
+			"title":       "SSP Backend",
+			"description": "Api of the self-service portal. A token is returned by POST /login and renewed by GET /api/refresh_token",
+			"version":     "1",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"basicAuth":  map[string]interface{}{"type": "http", "scheme": "basic"},
+			},
+		},
+	}
+}
+
+// pathParameters converts the gin parameters (:id, *path) to {id}
+func pathParameters(path string) (string, []map[string]interface{}) {
+	parameters := []map[string]interface{}{}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if !strings.HasPrefix(s, ":") && !strings.HasPrefix(s, "*") {
+			continue
+		}
+		segments[i] = "{" + s[1:] + "}"
+		parameters = append(parameters, map[string]interface{}{
+			"name": s[1:], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	return strings.Join(segments, "/"), parameters
+}
+
+// tagOf groups the routes by the package of their handler
+func tagOf(handler string) string {
+	pkg := handler[strings.LastIndex(handler, "/")+1:]
+	return strings.Split(pkg, ".")[0]
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// RegisterRoutes serves the specification on /swagger.json and the Swagger
+// UI on /swagger. The UI is loaded from 'swagger.ui_url'
+func RegisterRoutes(router *gin.Engine) {
+	router.GET("/swagger.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, Spec(router.Routes()))
+	})
+	router.GET("/swagger", swaggerUIHandler)
+}
+
+func swaggerUIHandler(c *gin.Context) {
+	uiURL := config.Config().GetString("swagger.ui_url")
+	if uiURL == "" {
+		uiURL = defaultSwaggerUIURL
+	}
+	uiURL = strings.TrimSuffix(uiURL, "/")
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(`<!DOCTYPE html>
+<html>
+<head>
+	<title>SSP Backend API</title>
+	<link rel="stylesheet" href="`+uiURL+`/swagger-ui.css">
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script src="`+uiURL+`/swagger-ui-bundle.js"></script>
+	<script>
+		SwaggerUIBundle({url: "swagger.json", dom_id: "#swagger-ui"});
+	</script>
+</body>
+</html>
+`))
+}
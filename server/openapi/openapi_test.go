@@ -0,0 +1,106 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+type testVolume struct {
+	common.OpenshiftBase
+	Size      string         `json:"size"`
+	Labels    []string       `json:"labels,omitempty"`
+	Created   time.Time      `json:"created"`
+	Secret    string         `json:"-"`
+	Parent    *testVolume    `json:"parent,omitempty"`
+	Snapshots []testVolume   `json:"snapshots"`
+	Usage     map[string]int `json:"usage"`
+}
+
+func newTestVolumeHandler(c *gin.Context) {}
+
+func getTestVolumeHandler(c *gin.Context) {}
+
+func TestSpec(t *testing.T) {
+	config.Init("test")
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("/api")
+	api.POST("/volumes", newTestVolumeHandler)
+	api.GET("/volumes/:id", getTestVolumeHandler)
+	RegisterRoutes(router)
+	common.SupportsDryRun(newTestVolumeHandler)
+	Describe(newTestVolumeHandler, Operation{
+		Summary:  "Create a volume",
+		Query:    []string{"async"},
+		Body:     testVolume{},
+		Response: common.ApiResponse{},
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/swagger.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the spec, got %v", w.Code)
+	}
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Summary    string
+			Tags       []string
+			Parameters []struct {
+				Name string
+				In   string
+			}
+			RequestBody map[string]interface{}
+			Security    []map[string]interface{}
+		}
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]interface{}
+			}
+		}
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	create := spec.Paths["/api/volumes"]["post"]
+	if create.Summary != "Create a volume" || len(create.Tags) != 1 || create.Tags[0] != "openapi" || create.RequestBody == nil || len(create.Security) != 1 {
+		t.Errorf("unexpected operation %+v", create)
+	}
+	if len(create.Parameters) != 2 || create.Parameters[0].Name != "async" || create.Parameters[1].Name != "dryRun" {
+		t.Errorf("expected the query and dry-run parameters, got %+v", create.Parameters)
+	}
+	get, ok := spec.Paths["/api/volumes/{id}"]["get"]
+	if !ok || get.Summary != "getTestVolumeHandler" || len(get.Parameters) != 1 || get.Parameters[0].In != "path" {
+		t.Errorf("expected the path parameter of /api/volumes/{id}, got %+v", spec.Paths)
+	}
+	if _, ok := spec.Paths["/swagger.json"]["get"]; !ok {
+		t.Error("expected the public routes to be listed")
+	}
+
+	volume := spec.Components.Schemas["testVolume"].Properties
+	for _, field := range []string{"clusterid", "project", "size", "labels", "created", "parent", "snapshots", "usage"} {
+		if _, ok := volume[field]; !ok {
+			t.Errorf("expected the field %v in the schema, got %v", field, volume)
+		}
+	}
+	if _, ok := volume["Secret"]; ok {
+		t.Error("expected fields with json:\"-\" to be skipped")
+	}
+	if volume["created"]["format"] != "date-time" || volume["parent"]["$ref"] != "#/components/schemas/testVolume" {
+		t.Errorf("unexpected schema %v", volume)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/swagger", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "swagger.json") {
+		t.Errorf("expected the swagger ui, got %v", w.Code)
+	}
+}
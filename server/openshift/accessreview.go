@@ -0,0 +1,458 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	accessReviewCampaignsCollection = "access_review_campaigns"
+	accessReviewTasksCollection     = "access_review_tasks"
+	defaultAccessReviewDeadlineDays = 30
+	reviewDecisionPending           = "pending"
+	reviewDecisionConfirmed         = "confirmed"
+	reviewDecisionRemoved           = "removed"
+	accessReviewLease               = "access-reviews"
+)
+
+// reviewedRoles are the rolebindings which are part of the access review
+var reviewedRoles = []string{"admin", "edit"}
+
+// AccessReviewCampaign is one round of access reviews, e.g. 2019-Q1
+type AccessReviewCampaign struct {
+	ID        string     `json:"id"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Deadline  time.Time  `json:"deadline"`
+	ClosedAt  *time.Time `json:"closedAt,omitempty"`
+}
+
+// AccessReviewTask is the review of all members of a project by its owners
+type AccessReviewTask struct {
+	Campaign    string               `json:"campaign"`
+	ClusterId   string               `json:"clusterid"`
+	Project     string               `json:"project"`
+	Owners      []string             `json:"owners"`
+	Members     []AccessReviewMember `json:"members"`
+	CompletedBy string               `json:"completedBy,omitempty"`
+	CompletedAt *time.Time           `json:"completedAt,omitempty"`
+}
+
+type AccessReviewMember struct {
+	User     string `json:"user"`
+	Role     string `json:"role"`
+	Decision string `json:"decision"`
+}
+
+// AccessReviewReport is the compliance of a campaign
+type AccessReviewReport struct {
+	Campaign         AccessReviewCampaign `json:"campaign"`
+	Tasks            int                  `json:"tasks"`
+	CompletedTasks   int                  `json:"completedTasks"`
+	ConfirmedMembers int                  `json:"confirmedMembers"`
+	RemovedMembers   int                  `json:"removedMembers"`
+	PendingMembers   int                  `json:"pendingMembers"`
+	OpenProjects     []string             `json:"openProjects"`
+	OverdueSecrets   []ExpiringSecret     `json:"overdueSecrets"`
+}
+
+func accessReviewTaskID(campaign, clusterId, project string) string {
+	return fmt.Sprintf("%v/%v/%v", campaign, clusterId, project)
+}
+
+// campaignID returns the quarter of the date, e.g. 2019-Q1
+func campaignID(t time.Time) string {
+	return fmt.Sprintf("%v-Q%v", t.Year(), (int(t.Month())-1)/3+1)
+}
+
+func getMyAccessReviewTasksHandler(c *gin.Context) {
+	username := strings.ToLower(common.GetUserName(c))
+
+	tasks, err := getAccessReviewTasks(func(t AccessReviewTask) bool {
+		return t.CompletedAt == nil && contains(t.Owners, username)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+func reviewAccessHandler(c *gin.Context) {
+	username := strings.ToLower(common.GetUserName(c))
+
+	var data common.AccessReviewCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	task, err := reviewAccess(data, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+func newAccessReviewCampaignHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	audit.Describe(c, "%v started an access review campaign", username)
+	campaign, err := startAccessReviewCampaign(common.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, campaign)
+}
+
+func getAccessReviewReportHandler(c *gin.Context) {
+	report, err := getAccessReviewReport(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// reviewAccess saves the decisions of an owner. Removed members lose their
+// access immediately, but the last admin of a project can't be removed
+func reviewAccess(data common.AccessReviewCommand, username string) (*AccessReviewTask, error) {
+	var task AccessReviewTask
+	id := accessReviewTaskID(data.Campaign, data.ClusterId, data.Project)
+	err := store.Update(accessReviewTasksCollection, id, &task, func(exists bool) error {
+		if !exists || !contains(task.Owners, username) {
+			return errors.New("Die Überprüfung existiert nicht oder du bist nicht Besitzer des Projekts")
+		}
+		if task.CompletedAt != nil {
+			return errors.New("Die Überprüfung wurde bereits abgeschlossen")
+		}
+
+		for _, d := range data.Decisions {
+			for i, m := range task.Members {
+				if m.User != strings.ToLower(d.User) || m.Role != d.Role {
+					continue
+				}
+				if d.Confirm {
+					task.Members[i].Decision = reviewDecisionConfirmed
+					continue
+				}
+				if err := removeProjectMember(nil, task.ClusterId, task.Project, m.Role, m.User); err != nil {
+					return err
+				}
+				task.Members[i].Decision = reviewDecisionRemoved
+			}
+		}
+
+		if pendingMembers(task) == 0 {
+			completedAt := time.Now()
+			task.CompletedAt = &completedAt
+			task.CompletedBy = username
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("%v reviewed the access to project %v on cluster %v", username, task.Project, task.ClusterId)
+	return &task, nil
+}
+
+func pendingMembers(task AccessReviewTask) int {
+	pending := 0
+	for _, m := range task.Members {
+		if m.Decision == reviewDecisionPending {
+			pending++
+		}
+	}
+	return pending
+}
+
+// StartAccessReviews starts a campaign every quarter and closes campaigns
+// after their deadline if 'access_review.enabled' is set. Only the instance
+// holding the lease starts and closes the campaigns
+func StartAccessReviews() {
+	if !config.Config().GetBool("access_review.enabled") {
+		return
+	}
+
+	go func() {
+		for {
+			leader, err := store.TryLease(accessReviewLease, common.InstanceID(), 48*time.Hour)
+			if err != nil {
+				log.Printf("Error acquiring the lease of the access reviews: %v", err)
+			}
+			if leader {
+				runAccessReviews(common.Now())
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
+
+func runAccessReviews(at time.Time) {
+	if found, err := store.Get(accessReviewCampaignsCollection, campaignID(at), &AccessReviewCampaign{}); err == nil && !found {
+		if _, err := startAccessReviewCampaign(at); err != nil {
+			log.Printf("Error starting access review campaign: %v", err)
+		}
+	}
+	closeExpiredAccessReviewCampaigns(at)
+}
+
+// startAccessReviewCampaign creates a review task for every project with an
+// owner and notifies the owners
+func startAccessReviewCampaign(at time.Time) (*AccessReviewCampaign, error) {
+	days := config.Config().GetInt("access_review.deadline_days")
+	if days <= 0 {
+		days = defaultAccessReviewDeadlineDays
+	}
+	campaign := &AccessReviewCampaign{
+		ID:        campaignID(at),
+		CreatedAt: at,
+		Deadline:  at.AddDate(0, 0, days),
+	}
+	found, err := store.Get(accessReviewCampaignsCollection, campaign.ID, &AccessReviewCampaign{})
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return nil, fmt.Errorf("Die Kampagne %v existiert bereits", campaign.ID)
+	}
+	if err := store.Put(accessReviewCampaignsCollection, campaign.ID, campaign); err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string][]string)
+	for _, cluster := range getOpenshiftClusters("") {
+		namespaces, err := getAllNamespaces(cluster.ID)
+		if err != nil {
+			log.Printf("Error getting namespaces of cluster %v: %v", cluster.ID, err)
+			continue
+		}
+		for _, n := range namespaces {
+			// Only projects created by the portal have a requester
+			if getAnnotation(n.Path("metadata.annotations"), annotationRequester) == "" {
+				continue
+			}
+			project, _ := n.Path("metadata.name").Data().(string)
+			task, err := createAccessReviewTask(campaign.ID, cluster.ID, project)
+			if err != nil {
+				log.Printf("Error creating access review of project %v on cluster %v: %v", project, cluster.ID, err)
+				continue
+			}
+			for _, o := range task.Owners {
+				owners[o] = append(owners[o], fmt.Sprintf("%v (%v)", project, cluster.ID))
+			}
+		}
+	}
+
+	for owner, projects := range owners {
+		if err := sendAccessReviewMail(owner, campaign, projects); err != nil {
+			log.Printf("Can't send e-mail about access review to %v: %v", owner, err)
+		}
+	}
+	log.Printf("Started access review campaign %v for %v owners", campaign.ID, len(owners))
+	return campaign, nil
+}
+
+func createAccessReviewTask(campaign, clusterId, project string) (*AccessReviewTask, error) {
+	task := &AccessReviewTask{
+		Campaign:  campaign,
+		ClusterId: clusterId,
+		Project:   project,
+		Members:   []AccessReviewMember{},
+	}
+	for _, role := range reviewedRoles {
+		users, err := getRoleBindingUsers(clusterId, project, role)
+		if err != nil {
+			return nil, err
+		}
+		// Service accounts and other system users are managed by the
+		// project, not by the review
+		humans := []string{}
+		for _, u := range users {
+			if !strings.HasPrefix(u, "system:") {
+				humans = append(humans, u)
+			}
+		}
+		for _, u := range humans {
+			task.Members = append(task.Members, AccessReviewMember{User: u, Role: role, Decision: reviewDecisionPending})
+		}
+		if role == "admin" {
+			task.Owners = humans
+		}
+	}
+	if len(task.Owners) == 0 {
+		return nil, errors.New("project has no admins")
+	}
+	return task, store.Put(accessReviewTasksCollection, accessReviewTaskID(campaign, clusterId, project), task)
+}
+
+// closeExpiredAccessReviewCampaigns removes the access of all editors which
+// weren't confirmed until the deadline and sends the report to the portal
+// admins. Unconfirmed admins are only reported, so no project loses its owners
+func closeExpiredAccessReviewCampaigns(at time.Time) {
+	campaigns := []AccessReviewCampaign{}
+	err := store.List(accessReviewCampaignsCollection, func(id string, data []byte) error {
+		var c AccessReviewCampaign
+		if err := json.Unmarshal(data, &c); err != nil {
+			return err
+		}
+		if c.ClosedAt == nil && c.Deadline.Before(at) {
+			campaigns = append(campaigns, c)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error reading access review campaigns: %v", err)
+		return
+	}
+
+	for _, campaign := range campaigns {
+		tasks, err := getAccessReviewTasks(func(t AccessReviewTask) bool {
+			return t.Campaign == campaign.ID && t.CompletedAt == nil
+		})
+		if err != nil {
+			log.Printf("Error reading access review tasks: %v", err)
+			continue
+		}
+
+		for _, task := range tasks {
+			for i, m := range task.Members {
+				if m.Decision != reviewDecisionPending || m.Role == "admin" {
+					continue
+				}
+				if err := removeUsersFromRoleBinding(task.ClusterId, task.Project, m.Role, []string{m.User}); err != nil {
+					log.Printf("Error removing unconfirmed %v %v of project %v: %v", m.Role, m.User, task.Project, err)
+					continue
+				}
+				task.Members[i].Decision = reviewDecisionRemoved
+			}
+			if err := store.Put(accessReviewTasksCollection, accessReviewTaskID(task.Campaign, task.ClusterId, task.Project), task); err != nil {
+				log.Printf("Error saving access review task: %v", err)
+			}
+		}
+
+		closedAt := time.Now()
+		campaign.ClosedAt = &closedAt
+		if err := store.Put(accessReviewCampaignsCollection, campaign.ID, campaign); err != nil {
+			log.Printf("Error closing access review campaign %v: %v", campaign.ID, err)
+			continue
+		}
+		log.Printf("Closed access review campaign %v", campaign.ID)
+
+		if report, err := getAccessReviewReport(campaign.ID); err == nil {
+			if err := sendAccessReviewReportMail(report); err != nil {
+				log.Printf("Can't send e-mail with access review report: %v", err)
+			}
+		}
+	}
+}
+
+func getAccessReviewTasks(filter func(AccessReviewTask) bool) ([]AccessReviewTask, error) {
+	tasks := []AccessReviewTask{}
+	err := store.List(accessReviewTasksCollection, func(id string, data []byte) error {
+		var t AccessReviewTask
+		if err := json.Unmarshal(data, &t); err != nil {
+			return err
+		}
+		if filter(t) {
+			tasks = append(tasks, t)
+		}
+		return nil
+	})
+	return tasks, err
+}
+
+func getAccessReviewReport(id string) (*AccessReviewReport, error) {
+	report := &AccessReviewReport{OpenProjects: []string{}}
+	found, err := store.Get(accessReviewCampaignsCollection, id, &report.Campaign)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("Die Kampagne existiert nicht")
+	}
+
+	tasks, err := getAccessReviewTasks(func(t AccessReviewTask) bool {
+		return t.Campaign == id
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		report.Tasks++
+		if t.CompletedAt != nil {
+			report.CompletedTasks++
+		} else {
+			report.OpenProjects = append(report.OpenProjects, fmt.Sprintf("%v/%v", t.ClusterId, t.Project))
+		}
+		for _, m := range t.Members {
+			switch m.Decision {
+			case reviewDecisionConfirmed:
+				report.ConfirmedMembers++
+			case reviewDecisionRemoved:
+				report.RemovedMembers++
+			default:
+				report.PendingMembers++
+			}
+		}
+	}
+	report.OverdueSecrets = getOverdueSecrets(common.Now())
+	return report, nil
+}
+
+func sendAccessReviewMail(owner string, campaign *AccessReviewCampaign, projects []string) error {
+	mail := common.GetMailForUser(owner)
+	if mail == "" {
+		return errors.New("no mail address for " + owner)
+	}
+	return common.SendMail([]string{mail}, fmt.Sprintf("Überprüfung der Berechtigungen %v", campaign.ID), fmt.Sprintf(`
+	Hallo %v,
+	<br><br>
+	Bitte überprüfe bis am %v die Berechtigungen deiner Projekte im Cloud Self Service Portal:
+	<br><br>
+	%v
+	<br><br>
+	Nicht bestätigte Berechtigungen werden nach dieser Frist automatisch entfernt, ausser die der Admins.
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, owner, campaign.Deadline.Format("02.01.2006"), strings.Join(projects, "<br>")))
+}
+
+func sendAccessReviewReportMail(report *AccessReviewReport) error {
+	recipients := []string{}
+	for _, admin := range config.Config().GetStringSlice("portal_admins") {
+		if mail := common.GetMailForUser(strings.TrimSpace(admin)); mail != "" {
+			recipients = append(recipients, mail)
+		}
+	}
+	return common.SendMail(recipients, fmt.Sprintf("Bericht der Überprüfung der Berechtigungen %v", report.Campaign.ID), fmt.Sprintf(`
+	Sehr geehrte Damen und Herren,
+	<br><br>
+	Die Überprüfung der Berechtigungen %v wurde abgeschlossen:
+	<br><br>
+	Projekte: %v, davon überprüft: %v<br>
+	Bestätigte Berechtigungen: %v<br>
+	Entfernte Berechtigungen: %v<br>
+	Nicht überprüfte Projekte: %v<br>
+	Abgelaufene Secrets: %v
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, report.Campaign.ID, report.Tasks, report.CompletedTasks, report.ConfirmedMembers, report.RemovedMembers, strings.Join(report.OpenProjects, ", "), len(report.OverdueSecrets)))
+}
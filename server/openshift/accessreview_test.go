@@ -0,0 +1,56 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+)
+
+func TestCampaignID(t *testing.T) {
+	tests := map[time.Month]string{
+		time.January:  "2019-Q1",
+		time.March:    "2019-Q1",
+		time.April:    "2019-Q2",
+		time.December: "2019-Q4",
+	}
+	for month, expected := range tests {
+		if actual := campaignID(time.Date(2019, month, 15, 0, 0, 0, 0, time.UTC)); actual != expected {
+			t.Errorf("campaignID(%v): expected %v, got %v", month, expected, actual)
+		}
+	}
+}
+
+func TestAccessReview(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("shop", "u123")
+	if err := addUsersToRoleBinding("fake", "shop", "edit", []string{"u456", "system:serviceaccount:shop:deployer"}); err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := createAccessReviewTask("2019-Q1", "fake", "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(task.Members) != 2 || task.Members[0].User != "u123" || task.Members[1].User != "u456" {
+		t.Fatalf("expected the admin and the editor without service account, got %+v", task.Members)
+	}
+
+	_, err = reviewAccess(common.AccessReviewCommand{OpenshiftBase: common.OpenshiftBase{ClusterId: "fake", Project: "shop"}, Campaign: "2019-Q1",
+		Decisions: []common.AccessReviewDecision{{User: "u123", Role: "admin"}}}, "u123")
+	if err == nil || err.Error() != lastAdminError {
+		t.Errorf("expected the last admin not to be removable, got %v", err)
+	}
+
+	store.Put(accessReviewCampaignsCollection, "2019-Q1", AccessReviewCampaign{ID: "2019-Q1", Deadline: time.Now().Add(-time.Hour)})
+	closeExpiredAccessReviewCampaigns(time.Now())
+	common.GetCache().Delete(roleBindingCacheKey("fake", "shop"))
+	if admins, _ := getRoleBindingUsers("fake", "shop", "admin"); len(admins) != 1 {
+		t.Errorf("expected the unconfirmed admin to be kept, got %v", admins)
+	}
+	if editors, _ := getRoleBindingUsers("fake", "shop", "edit"); len(editors) != 1 || editors[0] != "system:serviceaccount:shop:deployer" {
+		t.Errorf("expected the unconfirmed editor to be removed and the service account kept, got %v", editors)
+	}
+}
@@ -0,0 +1,280 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/approval"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	projectAdoptionKind         = "project-adoption"
+	ownerlessProjectsCollection = "ownerless_projects"
+)
+
+// OwnerlessProject is a project whose requester isn't in the ldap anymore
+type OwnerlessProject struct {
+	ClusterId  string    `json:"clusterid"`
+	Project    string    `json:"project"`
+	Requester  string    `json:"requester"`
+	DetectedAt time.Time `json:"detectedAt"`
+}
+
+// userExists checks if the user is still in the ldap
+var userExists = common.UserExists
+
+func ownerlessProjectID(clusterId, project string) string {
+	return clusterId + "/" + project
+}
+
+func registerAdoptionApprovals() {
+	approval.RegisterKind(approval.Kind{
+		Name: projectAdoptionKind,
+		Apply: func(r approval.Request) error {
+			return adoptProject(r.ClusterId, r.Project, r.RequestedBy)
+		},
+		Notify: func(r approval.Request) {
+			if r.State == approval.StatePending || r.State == approval.StateApproved {
+				return
+			}
+			if err := sendAdoptionDecisionMail(r); err != nil {
+				log.Printf("Can't send e-mail about adoption request %v: %v", r.ID, err)
+			}
+		},
+	})
+}
+
+// StartOwnerlessProjectDetection checks the requesters of all projects
+// against the ldap once a day if 'adoption.enabled' is set
+func StartOwnerlessProjectDetection() {
+	if !config.Config().GetBool("adoption.enabled") {
+		return
+	}
+
+	go func() {
+		for {
+			if err := detectOwnerlessProjects(common.Now()); err != nil {
+				log.Printf("Error detecting ownerless projects: %v", err)
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
+
+// detectOwnerlessProjects stores the projects whose requester left and
+// removes the projects which got a new owner or were deleted
+func detectOwnerlessProjects(now time.Time) error {
+	clusters := getOpenshiftClusters("")
+	namespaces, failed, err := getNamespacesOfClusters(clusters)
+	if err != nil {
+		return err
+	}
+
+	found := make(map[string]bool)
+	existing := make(map[string]bool)
+	for i, cluster := range clusters {
+		for _, n := range namespaces[i] {
+			requester := strings.ToLower(getAnnotation(n.Path("metadata.annotations"), annotationRequester))
+			if requester == "" || strings.HasPrefix(requester, "system:") {
+				continue
+			}
+			exists, ok := existing[requester]
+			if !ok {
+				if exists, err = userExists(requester); err != nil {
+					log.Printf("Error looking up %v in the ldap: %v", requester, err)
+					continue
+				}
+				existing[requester] = exists
+			}
+			if exists {
+				continue
+			}
+
+			project, _ := n.Path("metadata.name").Data().(string)
+			id := ownerlessProjectID(cluster.ID, project)
+			found[id] = true
+			if ok, err := store.Get(ownerlessProjectsCollection, id, &OwnerlessProject{}); err != nil || ok {
+				continue
+			}
+			log.Printf("The requester %v of project %v on cluster %v left", requester, project, cluster.ID)
+			err := store.Put(ownerlessProjectsCollection, id, OwnerlessProject{
+				ClusterId:  cluster.ID,
+				Project:    project,
+				Requester:  requester,
+				DetectedAt: now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	projects, err := getOwnerlessProjects()
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		id := ownerlessProjectID(p.ClusterId, p.Project)
+		if !found[id] && !contains(failed, p.ClusterId) {
+			if err := store.Delete(ownerlessProjectsCollection, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func getOwnerlessProjects() ([]OwnerlessProject, error) {
+	projects := []OwnerlessProject{}
+	err := store.List(ownerlessProjectsCollection, func(id string, data []byte) error {
+		var p OwnerlessProject
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		projects = append(projects, p)
+		return nil
+	})
+	return projects, err
+}
+
+func getOwnerlessProjectsHandler(c *gin.Context) {
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	projects, err := getOwnerlessProjects()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	matching := []OwnerlessProject{}
+	for _, p := range projects {
+		if listParams.Matches(p.ClusterId, p.Project, p.Requester) {
+			matching = append(matching, p)
+		}
+	}
+
+	start, end, next := listParams.Page(len(matching))
+	c.JSON(http.StatusOK, common.ListResponse{
+		Items:    matching[start:end],
+		Total:    len(matching),
+		Continue: next,
+	})
+}
+
+// adoptProjectHandler requests to become the owner of an ownerless project.
+// The request has to be approved by a portal admin
+func adoptProjectHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.AdoptProjectCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if data.Reason == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Es muss ein Grund angegeben werden"})
+		return
+	}
+
+	found, err := store.Get(ownerlessProjectsCollection, ownerlessProjectID(data.ClusterId, data.Project), &OwnerlessProject{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: fmt.Sprintf("Das Projekt %v hat einen Besitzer und kann nicht übernommen werden", data.Project)})
+		return
+	}
+
+	pending, err := approval.List(func(r approval.Request) bool {
+		return r.Kind == projectAdoptionKind && r.ClusterId == data.ClusterId && r.Project == data.Project && r.State == approval.StatePending
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if len(pending) > 0 {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: fmt.Sprintf("%v hat bereits beantragt, das Projekt zu übernehmen", pending[0].RequestedBy)})
+		return
+	}
+
+	request, err := approval.Create(projectAdoptionKind, data.ClusterId, data.Project, username, data.Reason, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v requested to adopt project %v on cluster %v", username, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, request)
+}
+
+// adoptProject makes the user admin and requester of the project and
+// removes the previous requester from the admins
+func adoptProject(clusterId, project, username string) error {
+	var ownerless OwnerlessProject
+	id := ownerlessProjectID(clusterId, project)
+	found, err := store.Get(ownerlessProjectsCollection, id, &ownerless)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("Das Projekt %v wurde bereits übernommen", project)
+	}
+
+	if err := changeProjectPermission(nil, clusterId, project, username); err != nil {
+		return err
+	}
+	err = updateNamespaceAnnotations(clusterId, project, func(annotations *gabs.Container) {
+		setAnnotation(annotations, annotationRequester, username)
+	})
+	if err != nil {
+		return err
+	}
+	if err := removeUsersFromRoleBinding(clusterId, project, "admin", []string{ownerless.Requester}); err != nil {
+		return err
+	}
+	if err := store.Delete(ownerlessProjectsCollection, id); err != nil {
+		return err
+	}
+	log.Printf("%v adopted project %v on cluster %v from %v", username, project, clusterId, ownerless.Requester)
+	return nil
+}
+
+func sendAdoptionDecisionMail(request approval.Request) error {
+	mail := common.GetMailForUser(request.RequestedBy)
+	if mail == "" {
+		return errors.New("no mail address for " + request.RequestedBy)
+	}
+
+	result := "abgelehnt"
+	switch request.State {
+	case approval.StateApplied:
+		result = "bewilligt. Du bist jetzt Besitzer und Admin des Projekts"
+	case approval.StateExpired:
+		result = "nicht rechtzeitig bearbeitet und ist abgelaufen. Bitte stelle ihn neu"
+	}
+	return common.SendMail([]string{mail}, fmt.Sprintf("Übernahme von Projekt %v", request.Project), fmt.Sprintf(`
+	Hallo %v,
+	<br><br>
+	Dein Antrag, das Projekt %v auf Cluster %v zu übernehmen, wurde %v.
+	<br><br>
+	%v
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, request.RequestedBy, request.Project, request.ClusterId, result, request.Comment))
+}
@@ -0,0 +1,46 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+func TestAdoptProject(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	userExists = func(username string) (bool, error) { return username != "u123", nil }
+	defer func() { userExists = common.UserExists }()
+
+	for project, requester := range map[string]string{"left": "u123", "active": "u456"} {
+		if err := createNewProject(nil, "fake", project, requester, "12345", "", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := detectOwnerlessProjects(time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	projects, _ := getOwnerlessProjects()
+	if len(projects) != 1 || projects[0].Project != "left" || projects[0].Requester != "u123" {
+		t.Fatalf("expected project left to be ownerless, got %+v", projects)
+	}
+
+	if err := adoptProject("fake", "left", "u789"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	namespace, _ := api.Get("api/v1/namespaces/left")
+	if requester := getAnnotation(namespace.Path("metadata.annotations"), annotationRequester); requester != "u789" {
+		t.Errorf("expected u789 to be the requester, got %v", requester)
+	}
+	admins, _, _ := getProjectAdminsAndOperators("fake", "left")
+	if !contains(admins, "u789") || contains(admins, "u123") {
+		t.Errorf("expected u789 to replace u123 as admin, got %v", admins)
+	}
+	if projects, _ := getOwnerlessProjects(); len(projects) != 0 {
+		t.Errorf("expected the adopted project not to be ownerless anymore, got %+v", projects)
+	}
+	if err := adoptProject("fake", "left", "u000"); err == nil {
+		t.Error("expected an error for an adopted project")
+	}
+}
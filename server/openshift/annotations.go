@@ -0,0 +1,129 @@
+package openshift
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+// The names of the annotations can be changed in the config, e.g.:
+//
+//	annotations:
+//	  billing: example.com/cost-center
+//	  billing_legacy:
+//	    - openshift.io/kontierung-element
+//
+// Values of the legacy keys are still read, but removed on the next update
+const (
+	annotationBilling   = "billing"
+	annotationMegaId    = "megaid"
+	annotationRequester = "requester"
+	annotationContact   = "contact"
+)
+
+var defaultAnnotationKeys = map[string]string{
+	annotationBilling:   "openshift.io/kontierung-element",
+	annotationMegaId:    "openshift.io/MEGAID",
+	annotationRequester: "openshift.io/requester",
+	annotationContact:   "openshift.io/contact",
+}
+
+func annotationKey(name string) string {
+	key := config.Config().GetString("annotations." + name)
+	if key == "" {
+		return defaultAnnotationKeys[name]
+	}
+	return key
+}
+
+func legacyAnnotationKeys(name string) []string {
+	legacy := []string{}
+	for _, key := range config.Config().GetStringSlice("annotations." + name + "_legacy") {
+		if key != annotationKey(name) {
+			legacy = append(legacy, key)
+		}
+	}
+	return legacy
+}
+
+// getAnnotation returns the value of the annotation from the configured
+// key or from one of the legacy keys
+func getAnnotation(annotations *gabs.Container, name string) string {
+	for _, key := range append([]string{annotationKey(name)}, legacyAnnotationKeys(name)...) {
+		if value, ok := annotations.S(key).Data().(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// setAnnotation writes the value to the configured key and removes the legacy keys
+func setAnnotation(annotations *gabs.Container, name, value string) {
+	annotations.Set(value, annotationKey(name))
+	for _, key := range legacyAnnotationKeys(name) {
+		annotations.Delete(key)
+	}
+}
+
+// previewAnnotations applies update to a copy of the annotations of the
+// namespace and returns the changed annotations with their current and
+// proposed values. Nothing is saved
+func previewAnnotations(clusterId, project string, update func(annotations *gabs.Container)) ([]common.PlannedChange, error) {
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+	current := annotationValues(namespace.Path("metadata.annotations"))
+
+	proposed := gabs.New()
+	for key, value := range current {
+		proposed.Set(value, key)
+	}
+	update(proposed)
+	return diffAnnotations(clusterId, project, current, annotationValues(proposed)), nil
+}
+
+func annotationValues(annotations *gabs.Container) map[string]string {
+	values := make(map[string]string)
+	children, _ := annotations.ChildrenMap()
+	for key, value := range children {
+		values[key] = fmt.Sprint(value.Data())
+	}
+	return values
+}
+
+// diffAnnotations returns the changes from current to proposed sorted by key
+func diffAnnotations(clusterId, project string, current, proposed map[string]string) []common.PlannedChange {
+	keys := []string{}
+	for key := range current {
+		keys = append(keys, key)
+	}
+	for key := range proposed {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := []common.PlannedChange{}
+	for _, key := range keys {
+		before, existed := current[key]
+		after, exists := proposed[key]
+		change := common.PlannedChange{Kind: "Annotation", Name: key, ClusterId: clusterId, Project: project, Current: before, Proposed: after}
+		switch {
+		case !existed:
+			change.Action = common.DryRunActionCreate
+		case !exists:
+			change.Action = common.DryRunActionDelete
+		case before != after:
+			change.Action = common.DryRunActionUpdate
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
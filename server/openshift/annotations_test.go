@@ -0,0 +1,60 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestAnnotationsWithLegacyKeys(t *testing.T) {
+	config.Init("test")
+	cfg := config.Config()
+	cfg.Set("annotations.billing", "example.com/cost-center")
+	cfg.Set("annotations.billing_legacy", []string{"openshift.io/kontierung-element", "example.com/cost-center"})
+	defer func() {
+		cfg.Set("annotations.billing", "")
+		cfg.Set("annotations.billing_legacy", []string{})
+	}()
+
+	if key := annotationKey(annotationBilling); key != "example.com/cost-center" {
+		t.Errorf("expected the configured key, got %v", key)
+	}
+	if key := annotationKey(annotationRequester); key != "openshift.io/requester" {
+		t.Errorf("expected the default key, got %v", key)
+	}
+
+	annotations, _ := gabs.ParseJSON([]byte(`{"openshift.io/kontierung-element": "12345"}`))
+	if billing := getAnnotation(annotations, annotationBilling); billing != "12345" {
+		t.Errorf("expected the value of the legacy key, got %v", billing)
+	}
+
+	setAnnotation(annotations, annotationBilling, "67890")
+	if annotations.S("openshift.io/kontierung-element").Data() != nil {
+		t.Errorf("expected the legacy key to be removed, got %v", annotations)
+	}
+	if billing := getAnnotation(annotations, annotationBilling); billing != "67890" {
+		t.Errorf("expected the new value, got %v", billing)
+	}
+}
+
+func TestDiffAnnotations(t *testing.T) {
+	changes := diffAnnotations("fake", "shop",
+		map[string]string{"a": "1", "b": "2", "c": "3"},
+		map[string]string{"a": "1", "b": "4", "d": "5"})
+
+	expected := []struct{ name, action string }{
+		{"b", common.DryRunActionUpdate},
+		{"c", common.DryRunActionDelete},
+		{"d", common.DryRunActionCreate},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %v changes, got %+v", len(expected), changes)
+	}
+	for i, e := range expected {
+		if changes[i].Name != e.name || changes[i].Action != e.action {
+			t.Errorf("expected %v %v, got %+v", e.action, e.name, changes[i])
+		}
+	}
+}
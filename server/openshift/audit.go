@@ -0,0 +1,198 @@
+package openshift
+
+import (
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// ProjectMetadata is the portal managed metadata of a project. Contact and
+// Annotations are only returned for a single project
+type ProjectMetadata struct {
+	ClusterId   string             `json:"clusterid"`
+	Project     string             `json:"project"`
+	Billing     string             `json:"billing"`
+	MegaId      string             `json:"megaId"`
+	Requester   string             `json:"requester"`
+	Created     string             `json:"created"`
+	Contact     string             `json:"contact,omitempty"`
+	Annotations map[string]string  `json:"annotations,omitempty"`
+	Drift       []common.DriftItem `json:"drift,omitempty"`
+}
+
+func getAllProjectMetadataHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	clusters := getOpenshiftClusters("")
+	if clusterId := c.Query("clusterid"); clusterId != "" {
+		cluster, err := getOpenshiftCluster(clusterId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		clusters = []OpenshiftCluster{cluster}
+	}
+
+	audit.Describe(c, "%v has queried the metadata of all projects", username)
+	namespaces, failed, err := getNamespacesOfClusters(clusters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	drifts, err := getProjectDrifts()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	projects := []ProjectMetadata{}
+	for i, cluster := range clusters {
+		for _, n := range namespaces[i] {
+			p := newProjectMetadata(cluster.ID, n)
+			if listParams.Matches(p.Project, p.Billing, p.MegaId, p.Requester) {
+				p.Drift = drifts[projectSpecID(cluster.ID, p.Project)]
+				projects = append(projects, p)
+			}
+		}
+	}
+
+	field, desc := listParams.SortField("project")
+	sort.SliceStable(projects, func(i, j int) bool {
+		a, b := projects[i].sortValue(field), projects[j].sortValue(field)
+		if desc {
+			return a > b
+		}
+		return a < b
+	})
+
+	start, end, next := listParams.Page(len(projects))
+	c.JSON(http.StatusOK, common.ListResponse{
+		Items:    projects[start:end],
+		Total:    len(projects),
+		Continue: next,
+		Errors:   failed,
+	})
+}
+
+// exportProjectMetadataHandler streams the metadata of all projects as csv.
+// The rows are written cluster by cluster, so the inventory is never kept in
+// memory as a whole
+func exportProjectMetadataHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v has exported the metadata of all projects", username)
+	c.Header("Content-Disposition", "attachment; filename=projects.csv")
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	wr := csv.NewWriter(c.Writer)
+	wr.Write([]string{"clusterid", "project", "billing", "megaId", "requester", "created"})
+	for _, cluster := range getOpenshiftClusters("") {
+		namespaces, err := getAllNamespaces(cluster.ID)
+		if err != nil {
+			// The header is already sent, the error can only be logged
+			common.Logger(c).Printf("Error exporting the projects of cluster %v: %v", cluster.ID, err)
+			continue
+		}
+		for _, n := range namespaces {
+			p := newProjectMetadata(cluster.ID, n)
+			if listParams.Matches(p.Project, p.Billing, p.MegaId, p.Requester) {
+				wr.Write([]string{p.ClusterId, p.Project, p.Billing, p.MegaId, p.Requester, p.Created})
+			}
+		}
+		wr.Flush()
+		c.Writer.Flush()
+	}
+	if err := wr.Error(); err != nil {
+		common.Logger(c).Printf("Error writing the project export: %v", err)
+	}
+}
+
+// getNamespacesOfClusters reads the namespaces of all clusters concurrently.
+// The namespaces are returned in the order of the clusters together with the
+// ids of the clusters which failed. Only fails if no cluster could be read
+func getNamespacesOfClusters(clusters []OpenshiftCluster) ([][]*gabs.Container, []string, error) {
+	namespaces := make([][]*gabs.Container, len(clusters))
+	errs := common.ForEachParallel(len(clusters), func(i int) error {
+		var err error
+		namespaces[i], err = getAllNamespaces(clusters[i].ID)
+		return err
+	})
+
+	failed := []string{}
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("Error getting namespaces of cluster %v: %v", clusters[i].ID, err)
+			failed = append(failed, clusters[i].ID)
+		}
+	}
+	if len(failed) > 0 && len(failed) == len(clusters) {
+		return nil, nil, errs[0]
+	}
+	return namespaces, failed, nil
+}
+
+func newProjectMetadata(clusterId string, n *gabs.Container) ProjectMetadata {
+	annotations := n.Path("metadata.annotations")
+	p := ProjectMetadata{
+		ClusterId: clusterId,
+		Billing:   getAnnotation(annotations, annotationBilling),
+		MegaId:    getAnnotation(annotations, annotationMegaId),
+		Requester: getAnnotation(annotations, annotationRequester),
+	}
+	p.Project, _ = n.Path("metadata.name").Data().(string)
+	p.Created, _ = n.Path("metadata.creationTimestamp").Data().(string)
+	return p
+}
+
+func (p ProjectMetadata) sortValue(field string) string {
+	switch field {
+	case "billing":
+		return p.Billing
+	case "megaId":
+		return p.MegaId
+	case "requester":
+		return p.Requester
+	case "created":
+		return p.Created
+	case "clusterid":
+		return p.ClusterId + "/" + p.Project
+	}
+	return p.Project
+}
+
+func getBillingSnapshotHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	month, err := time.Parse(monthFormat, c.Query("month"))
+	cluster := Cluster(c.Query("cluster"))
+	if err != nil || (cluster != awsCluster && cluster != viasCluster) {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Cluster (aws oder vias) und Monat (z.B. 2019-01) müssen angegeben werden"})
+		return
+	}
+
+	audit.Describe(c, "%v has queried the billing of cluster %v in %v", username, cluster, month.Format(monthFormat))
+	snapshot, err := getBillingSnapshot(cluster, month)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
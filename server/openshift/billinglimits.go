@@ -0,0 +1,261 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// billingLimits caps the number of projects and the summed quotas per
+// Kontierungsnummer over all clusters. 0 means unlimited
+type billingLimits struct {
+	MaxProjects int `mapstructure:"max_projects"`
+	MaxCPU      int `mapstructure:"max_cpu"`
+	MaxMemory   int `mapstructure:"max_memory"`
+}
+
+// billingLimitsOverride are the limits of a single account. Fields which
+// aren't set are taken from the defaults
+type billingLimitsOverride struct {
+	MaxProjects *int `mapstructure:"max_projects"`
+	MaxCPU      *int `mapstructure:"max_cpu"`
+	MaxMemory   *int `mapstructure:"max_memory"`
+}
+
+type billingUsage struct {
+	Projects int
+	CPU      float64
+	Memory   float64
+}
+
+func getBillingLimits(billing string) billingLimits {
+	cfg := config.Config()
+	limits := billingLimits{
+		MaxProjects: cfg.GetInt("billing_limits.max_projects"),
+		MaxCPU:      cfg.GetInt("billing_limits.max_cpu"),
+		MaxMemory:   cfg.GetInt("billing_limits.max_memory"),
+	}
+
+	// Overrides for single accounts
+	overrides := map[string]billingLimitsOverride{}
+	if err := cfg.UnmarshalKey("billing_limits.accounts", &overrides); err != nil {
+		log.Printf("Error reading the billing limits of the accounts: %v", err)
+	}
+	override := overrides[billing]
+	if override.MaxProjects != nil {
+		limits.MaxProjects = *override.MaxProjects
+	}
+	if override.MaxCPU != nil {
+		limits.MaxCPU = *override.MaxCPU
+	}
+	if override.MaxMemory != nil {
+		limits.MaxMemory = *override.MaxMemory
+	}
+	return limits
+}
+
+// checkBillingLimitsForNewProject returns an error if the billing account
+// already holds the maximum number of projects
+func checkBillingLimitsForNewProject(billing string) error {
+	limits := getBillingLimits(billing)
+	if limits.MaxProjects == 0 {
+		return nil
+	}
+
+	usage, err := getBillingUsage(billing, "")
+	if err != nil {
+		return err
+	}
+	if usage.Projects >= limits.MaxProjects {
+		return fmt.Errorf("Die Kontierungsnummer %v hat bereits das Maximum von %v Projekten erreicht", billing, limits.MaxProjects)
+	}
+	return nil
+}
+
+// checkBillingLimitsForQuotas returns an error if the new quotas of the
+// project would exceed the summed quotas allowed for its billing account
+func checkBillingLimitsForQuotas(clusterId, project string, cpu, memory int) error {
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return err
+	}
+	billing := getAnnotation(namespace.Path("metadata.annotations"), annotationBilling)
+	return checkBillingLimitsForBillingQuotas(billing, clusterId, project, cpu, memory)
+}
+
+// checkBillingLimitsForBillingQuotas is checkBillingLimitsForQuotas for a
+// project which gets the billing account, e.g. by its spec
+func checkBillingLimitsForBillingQuotas(billing, clusterId, project string, cpu, memory int) error {
+	if billing == "" {
+		return nil
+	}
+
+	limits := getBillingLimits(billing)
+	if limits.MaxCPU == 0 && limits.MaxMemory == 0 {
+		return nil
+	}
+
+	usage, err := getBillingUsage(billing, clusterId+"/"+project)
+	if err != nil {
+		return err
+	}
+	if limits.MaxCPU > 0 && usage.CPU+float64(cpu) > float64(limits.MaxCPU) {
+		return fmt.Errorf("Die Kontierungsnummer %v darf insgesamt maximal %v CPU Quota haben. Bereits vergeben: %v", billing, limits.MaxCPU, usage.CPU)
+	}
+	if limits.MaxMemory > 0 && usage.Memory+float64(memory) > float64(limits.MaxMemory) {
+		return fmt.Errorf("Die Kontierungsnummer %v darf insgesamt maximal %vGi Memory Quota haben. Bereits vergeben: %vGi", billing, limits.MaxMemory, usage.Memory)
+	}
+	return nil
+}
+
+// getBillingUsage sums up the projects and quotas of a billing account on
+// all clusters. The project in the form 'clusterid/project' is excluded
+func getBillingUsage(billing, exclude string) (*billingUsage, error) {
+	usage := &billingUsage{}
+	for _, cluster := range getOpenshiftClusters("") {
+		projects, err := getProjectsWithBilling(cluster.ID, billing)
+		if err != nil {
+			return nil, err
+		}
+
+		quotas, err := getAllResourceQuotas(cluster.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range projects {
+			if cluster.ID+"/"+p == exclude {
+				continue
+			}
+			usage.Projects++
+			if q := projectQuota(quotas[p]); q != nil {
+				usage.CPU += parseCPUQuantity(q.Path("spec.hard.cpu").Data())
+				usage.Memory += parseMemoryQuantityGi(q.Path("spec.hard.memory").Data())
+			}
+		}
+	}
+	return usage, nil
+}
+
+func getProjectsWithBilling(clusterId, billing string) ([]string, error) {
+	index, err := getBillingIndex(clusterId)
+	if err != nil {
+		return nil, err
+	}
+	return index[billing], nil
+}
+
+func billingIndexCacheKey(clusterId string) string {
+	return fmt.Sprintf("billings/%v", clusterId)
+}
+
+// getBillingIndex returns the projects of the cluster by billing. It's
+// cached and reset when a project is created or deleted or its billing changes
+func getBillingIndex(clusterId string) (map[string][]string, error) {
+	cacheKey := billingIndexCacheKey(clusterId)
+	if cached, ok := common.GetCache().Get(cacheKey); ok {
+		index := make(map[string][]string)
+		if json.Unmarshal(cached, &index) == nil {
+			return index, nil
+		}
+	}
+
+	namespaces, err := getAllNamespaces(clusterId)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string][]string)
+	for _, n := range namespaces {
+		billing := getAnnotation(n.Path("metadata.annotations"), annotationBilling)
+		if name, ok := n.Path("metadata.name").Data().(string); ok && billing != "" {
+			index[billing] = append(index[billing], name)
+		}
+	}
+	if data, err := json.Marshal(index); err == nil {
+		common.GetCache().Set(cacheKey, data)
+	}
+	return index, nil
+}
+
+// getAllResourceQuotas returns all resourcequotas of the cluster by namespace
+func getAllResourceQuotas(clusterId string) (map[string][]*gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/resourcequotas", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+
+	quotas, err := json.S("items").Children()
+	if err != nil {
+		log.Println("Unable to parse resourcequota list", err.Error())
+		return nil, errors.New(genericAPIError)
+	}
+
+	result := make(map[string][]*gabs.Container)
+	for _, q := range quotas {
+		namespace, _ := q.Path("metadata.namespace").Data().(string)
+		result[namespace] = append(result[namespace], q)
+	}
+	return result, nil
+}
+
+// parseCPUQuantity converts a kubernetes cpu quantity (e.g. 2, "500m") to cores
+func parseCPUQuantity(quantity interface{}) float64 {
+	switch q := quantity.(type) {
+	case float64:
+		return q
+	case string:
+		if strings.HasSuffix(q, "m") {
+			v, _ := strconv.ParseFloat(strings.TrimSuffix(q, "m"), 64)
+			return v / 1000
+		}
+		v, _ := strconv.ParseFloat(q, 64)
+		return v
+	}
+	return 0
+}
+
+// parseMemoryQuantityGi converts a kubernetes memory quantity (e.g. "8Gi", "512Mi") to Gi
+func parseMemoryQuantityGi(quantity interface{}) float64 {
+	s, ok := quantity.(string)
+	if !ok {
+		if f, ok := quantity.(float64); ok {
+			return f / (1024 * 1024 * 1024)
+		}
+		return 0
+	}
+
+	units := []struct {
+		suffix string
+		factor float64
+	}{
+		{"Ki", 1.0 / (1024 * 1024)},
+		{"Mi", 1.0 / 1024},
+		{"Gi", 1},
+		{"Ti", 1024},
+		{"K", 1000.0 / (1024 * 1024 * 1024)},
+		{"M", 1000.0 * 1000 / (1024 * 1024 * 1024)},
+		{"G", 1000.0 * 1000 * 1000 / (1024 * 1024 * 1024)},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			v, _ := strconv.ParseFloat(strings.TrimSuffix(s, u.suffix), 64)
+			return v * u.factor
+		}
+	}
+	v, _ := strconv.ParseFloat(s, 64)
+	return v / (1024 * 1024 * 1024)
+}
@@ -0,0 +1,77 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestParseCPUQuantity(t *testing.T) {
+	tests := map[interface{}]float64{
+		"2":    2,
+		"500m": 0.5,
+		4.0:    4,
+		nil:    0,
+	}
+	for quantity, expected := range tests {
+		if actual := parseCPUQuantity(quantity); actual != expected {
+			t.Errorf("parseCPUQuantity(%v): expected %v, got %v", quantity, expected, actual)
+		}
+	}
+}
+
+func TestParseMemoryQuantityGi(t *testing.T) {
+	tests := map[interface{}]float64{
+		"8Gi":   8,
+		"512Mi": 0.5,
+		"1Ti":   1024,
+		nil:     0,
+	}
+	for quantity, expected := range tests {
+		if actual := parseMemoryQuantityGi(quantity); actual != expected {
+			t.Errorf("parseMemoryQuantityGi(%v): expected %v, got %v", quantity, expected, actual)
+		}
+	}
+}
+
+func TestGetBillingLimitsMergesOverrides(t *testing.T) {
+	config.Init("test")
+	cfg := config.Config()
+	cfg.Set("billing_limits.max_projects", 10)
+	cfg.Set("billing_limits.max_cpu", 50)
+	cfg.Set("billing_limits.max_memory", 100)
+	cfg.Set("billing_limits.accounts", map[string]interface{}{
+		"1234567": map[string]interface{}{"max_cpu": 200},
+		"7654321": map[string]interface{}{"max_projects": 0},
+	})
+
+	if limits := getBillingLimits("1234567"); limits != (billingLimits{MaxProjects: 10, MaxCPU: 200, MaxMemory: 100}) {
+		t.Errorf("expected the unset fields from the defaults, got %+v", limits)
+	}
+	if limits := getBillingLimits("7654321"); limits != (billingLimits{MaxProjects: 0, MaxCPU: 50, MaxMemory: 100}) {
+		t.Errorf("expected an explicit 0 to remove the limit, got %+v", limits)
+	}
+	if limits := getBillingLimits("other"); limits != (billingLimits{MaxProjects: 10, MaxCPU: 50, MaxMemory: 100}) {
+		t.Errorf("expected the defaults, got %+v", limits)
+	}
+}
+
+func TestCheckBillingLimitsForNewProject(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	config.Config().Set("billing_limits.max_projects", 1)
+
+	if err := checkBillingLimitsForNewProject("12345"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	api.AddProject("shop", "u123")
+	if err := createOrUpdateMetadata("fake", "shop", "12345", "", "u123", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkBillingLimitsForNewProject("12345"); err == nil {
+		t.Error("expected an error when the account has the maximum of projects")
+	}
+	if err := checkBillingLimitsForNewProject("67890"); err != nil {
+		t.Errorf("unexpected error for another account: %v", err)
+	}
+}
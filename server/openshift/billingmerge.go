@@ -0,0 +1,194 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+)
+
+// mergeBillingHandler re-maps a billing account to another one, e.g. after
+// a reorganization of the cost centers. Supports ?dryRun=true
+func mergeBillingHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.MergeBillingCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	data.From = strings.TrimSpace(data.From)
+	data.To = strings.TrimSpace(data.To)
+	if data.From == "" || data.To == "" || data.From == data.To {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Es müssen zwei verschiedene Kontierungsnummern angegeben werden"})
+		return
+	}
+
+	if common.IsDryRun(c) {
+		changes, err := mergeBilling(data.From, data.To, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		common.RespondDryRun(c, fmt.Sprintf("Die Kontierungsnummer %v würde durch %v ersetzt", data.From, data.To), changes...)
+		return
+	}
+
+	changes, err := mergeBilling(data.From, data.To, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v merged the billing account %v into %v: %v changes", username, data.From, data.To, len(changes))
+	c.JSON(http.StatusOK, common.MergeBillingResponse{
+		Message: fmt.Sprintf("Die Kontierungsnummer %v wurde durch %v ersetzt", data.From, data.To),
+		Changes: changes,
+	})
+}
+
+// mergeBilling replaces the billing account in the annotations of all
+// projects, in the stored billing snapshots, cost anomalies and imported
+// projects. Only the planned changes are returned if apply is false
+func mergeBilling(from, to string, apply bool) ([]common.PlannedChange, error) {
+	changes := []common.PlannedChange{}
+	change := func(kind, name, clusterId, project string) {
+		changes = append(changes, common.PlannedChange{
+			Action: common.DryRunActionUpdate, Kind: kind, Name: name, ClusterId: clusterId, Project: project, Current: from, Proposed: to,
+		})
+	}
+
+	clusters := getOpenshiftClusters("")
+	namespaces, failed, err := getNamespacesOfClusters(clusters)
+	if err != nil {
+		return nil, err
+	}
+	if len(failed) > 0 {
+		return nil, fmt.Errorf("Die Projekte der Cluster %v konnten nicht gelesen werden", strings.Join(failed, ", "))
+	}
+	for i, cluster := range clusters {
+		for _, n := range namespaces[i] {
+			if getAnnotation(n.Path("metadata.annotations"), annotationBilling) != from {
+				continue
+			}
+			project, _ := n.Path("metadata.name").Data().(string)
+			if apply {
+				err := updateNamespaceAnnotations(cluster.ID, project, func(annotations *gabs.Container) {
+					setAnnotation(annotations, annotationBilling, to)
+				})
+				if err != nil {
+					return changes, err
+				}
+			}
+			change("Namespace", project, cluster.ID, project)
+		}
+	}
+
+	snapshots := map[string]BillingSnapshot{}
+	err = store.List(billingSnapshotsCollection, func(id string, data []byte) error {
+		var s BillingSnapshot
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		snapshots[id] = s
+		return nil
+	})
+	if err != nil {
+		return changes, err
+	}
+	for id, s := range snapshots {
+		changed := false
+		for i, r := range s.Rows {
+			if getAccountAssignment(r) == from {
+				setAccountAssignment(&s.Rows[i], to)
+				change("BillingSnapshot", fmt.Sprintf("%v %v", s.Cluster, s.Month), "", r.Project)
+				changed = true
+			}
+		}
+		if changed && apply {
+			if err := store.Put(billingSnapshotsCollection, id, s); err != nil {
+				return changes, err
+			}
+		}
+	}
+
+	anomalies, err := getCostAnomalies()
+	if err != nil {
+		return changes, err
+	}
+	for _, a := range anomalies {
+		if a.Billing != from {
+			continue
+		}
+		change("CostAnomaly", a.ID, "", "")
+		if !apply {
+			continue
+		}
+		if err := store.Delete(costAnomaliesCollection, a.ID); err != nil {
+			return changes, err
+		}
+		a.Billing = to
+		a.ID = fmt.Sprintf("%v-%v", to, a.Month)
+		// An anomaly of the new account in the same month is kept
+		found, err := store.Get(costAnomaliesCollection, a.ID, &CostAnomaly{})
+		if err != nil {
+			return changes, err
+		}
+		if found {
+			continue
+		}
+		if err := store.Put(costAnomaliesCollection, a.ID, a); err != nil {
+			return changes, err
+		}
+	}
+
+	var managed []ManagedProject
+	err = store.List(managedProjectsCollection, func(id string, data []byte) error {
+		var p ManagedProject
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		managed = append(managed, p)
+		return nil
+	})
+	if err != nil {
+		return changes, err
+	}
+	for _, p := range managed {
+		if p.Billing != from {
+			continue
+		}
+		change("ManagedProject", p.Project, p.ClusterId, p.Project)
+		if apply {
+			p.Billing = to
+			if err := store.Put(managedProjectsCollection, managedProjectID(p.ClusterId, p.Project), p); err != nil {
+				return changes, err
+			}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, errors.New("Die Kontierungsnummer " + from + " wird nirgends verwendet")
+	}
+	return changes, nil
+}
+
+// setAccountAssignment writes the billing to the field of the chargeback row
+// which getAccountAssignment reads it from
+func setAccountAssignment(value *Resources, billing string) {
+	value.ReceptionAssignment, value.OrderReception, value.PspElement = "", "", ""
+	switch {
+	case strings.HasPrefix(billing, "77"):
+		value.ReceptionAssignment = billing
+	case strings.HasPrefix(billing, "70"):
+		value.OrderReception = billing
+	default:
+		value.PspElement = billing
+	}
+}
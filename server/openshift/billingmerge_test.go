@@ -0,0 +1,53 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+)
+
+func TestMergeBilling(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	for project, billing := range map[string]string{"old": "12345", "other": "99999"} {
+		if err := createNewProject(nil, "fake", project, "u123", billing, "", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshot := BillingSnapshot{Cluster: "fake", Month: "2019-01", Rows: []Resources{
+		{Project: "old", PspElement: "12345"},
+		{Project: "other", PspElement: "99999"},
+	}}
+	store.Put(billingSnapshotsCollection, "fake-2019-01", snapshot)
+	store.Put(costAnomaliesCollection, "12345-2019-01", CostAnomaly{ID: "12345-2019-01", Billing: "12345", Month: "2019-01"})
+
+	changes, err := mergeBilling("12345", "70001", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Errorf("expected the project, the snapshot row and the anomaly to change, got %+v", changes)
+	}
+	namespace, _ := api.Get("api/v1/namespaces/old")
+	if billing := getAnnotation(namespace.Path("metadata.annotations"), annotationBilling); billing != "12345" {
+		t.Errorf("expected the dry-run not to change the project, got %v", billing)
+	}
+
+	if _, err := mergeBilling("12345", "70001", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	namespace, _ = api.Get("api/v1/namespaces/old")
+	if billing := getAnnotation(namespace.Path("metadata.annotations"), annotationBilling); billing != "70001" {
+		t.Errorf("expected the project to be re-mapped, got %v", billing)
+	}
+	store.Get(billingSnapshotsCollection, "fake-2019-01", &snapshot)
+	if snapshot.Rows[0].OrderReception != "70001" || snapshot.Rows[0].PspElement != "" || snapshot.Rows[1].PspElement != "99999" {
+		t.Errorf("expected only the row of the merged account to change, got %+v", snapshot.Rows)
+	}
+	if found, _ := store.Get(costAnomaliesCollection, "70001-2019-01", &CostAnomaly{}); !found {
+		t.Error("expected the anomaly to be moved to the new account")
+	}
+	if _, err := mergeBilling("12345", "70001", true); err == nil {
+		t.Error("expected an error for an account which isn't used anymore")
+	}
+}
@@ -0,0 +1,154 @@
+package openshift
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// BillingReport is the quota usage and the costs per project and billing
+// number between two months, e.g. for the monthly chargeback of finance
+type BillingReport struct {
+	From string             `json:"from"`
+	To   string             `json:"to"`
+	Rows []BillingReportRow `json:"rows"`
+}
+
+// BillingReportRow sums up the months of a project charged to a billing number.
+// The resources are the averages of the months
+type BillingReportRow struct {
+	Cluster         Cluster `json:"cluster"`
+	Project         string  `json:"project"`
+	Billing         string  `json:"billing"`
+	Months          int     `json:"months"`
+	QuotaCpu        float64 `json:"quotaCpu"`
+	QuotaMemory     float64 `json:"quotaMemory"`
+	RequestedCpu    float64 `json:"requestedCpu"`
+	RequestedMemory float64 `json:"requestedMemory"`
+	UsedCpu         float64 `json:"usedCpu"`
+	UsedMemory      float64 `json:"usedMemory"`
+	Storage         float64 `json:"storage"`
+	Costs           float64 `json:"costs"`
+}
+
+// billingReportHandler returns the report between ?from and ?to (months) as
+// json or with ?format=csv as csv. Without ?billing only portal admins and
+// auditors get the report of all billing numbers
+func billingReportHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	billing := c.Query("billing")
+
+	from, to, err := parseShowbackRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if billing == "" && !common.IsPortalAdmin(username) && !common.IsPortalAuditor(username) {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Kontierungsnummer muss angegeben werden"})
+		return
+	}
+	if billing != "" {
+		if err := checkBillingPermissions(username, billing); err != nil {
+			c.JSON(http.StatusForbidden, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	}
+
+	audit.Describe(c, "%v queried the billing report from %v to %v for billing '%v'", username, from.Format(monthFormat), to.Format(monthFormat), billing)
+	report, err := getBillingReport(from, to, billing)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=billing-report-%v-%v.csv", report.From, report.To))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := writeBillingReportCSV(c.Writer, report); err != nil {
+		common.Logger(c).Printf("Error writing billing report csv: %v", err)
+	}
+}
+
+func getBillingReport(from, to time.Time, billing string) (*BillingReport, error) {
+	report := &BillingReport{From: from.Format(monthFormat), To: to.Format(monthFormat), Rows: []BillingReportRow{}}
+	rows := make(map[string]*BillingReportRow)
+
+	for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+		for _, cluster := range []Cluster{awsCluster, viasCluster} {
+			snapshot, err := getBillingSnapshot(cluster, month)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range snapshot.Rows {
+				assignment := getAccountAssignment(r)
+				if billing != "" && assignment != billing {
+					continue
+				}
+				key := fmt.Sprintf("%v/%v/%v", cluster, r.Project, assignment)
+				row, ok := rows[key]
+				if !ok {
+					row = &BillingReportRow{Cluster: cluster, Project: r.Project, Billing: assignment}
+					rows[key] = row
+				}
+				row.Months++
+				row.QuotaCpu += r.QuotaCpu
+				row.QuotaMemory += r.QuotaMemory
+				row.RequestedCpu += r.RequestedCpu
+				row.RequestedMemory += r.RequestedMemory
+				row.UsedCpu += r.UsedCpu
+				row.UsedMemory += r.UsedMemory
+				row.Storage += r.Storage
+				row.Costs += getTotalPrice(r)
+			}
+		}
+	}
+
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	for _, row := range rows {
+		months := float64(row.Months)
+		for _, v := range []*float64{&row.QuotaCpu, &row.QuotaMemory, &row.RequestedCpu, &row.RequestedMemory, &row.UsedCpu, &row.UsedMemory, &row.Storage} {
+			*v = round(*v / months)
+		}
+		row.Costs = round(row.Costs)
+		report.Rows = append(report.Rows, *row)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if a.Billing != b.Billing {
+			return a.Billing < b.Billing
+		}
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		return a.Cluster < b.Cluster
+	})
+	return report, nil
+}
+
+func writeBillingReportCSV(w io.Writer, report *BillingReport) error {
+	wr := csv.NewWriter(w)
+	wr.Write([]string{"Von", "Bis", "Kontierungsnummer", "Projekt", "Cluster", "Monate",
+		"Quota CPU", "Quota Memory", "Requested CPU", "Requested Memory", "Used CPU", "Used Memory", "Storage", "Kosten CHF"})
+
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	for _, r := range report.Rows {
+		wr.Write([]string{report.From, report.To, r.Billing, r.Project, string(r.Cluster), strconv.Itoa(r.Months),
+			format(r.QuotaCpu), format(r.QuotaMemory), format(r.RequestedCpu), format(r.RequestedMemory),
+			format(r.UsedCpu), format(r.UsedMemory), format(r.Storage), format(r.Costs)})
+	}
+	wr.Flush()
+	return wr.Error()
+}
@@ -0,0 +1,54 @@
+package openshift
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+)
+
+func TestGetBillingReport(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+	january := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	february := january.AddDate(0, 1, 0)
+	for _, cluster := range []Cluster{awsCluster, viasCluster} {
+		for _, month := range []time.Time{january, february} {
+			store.Put(billingSnapshotsCollection, billingSnapshotID(cluster, month), BillingSnapshot{Cluster: cluster, Month: month.Format(monthFormat), Rows: []Resources{}})
+		}
+	}
+	store.Put(billingSnapshotsCollection, billingSnapshotID(awsCluster, january), BillingSnapshot{Cluster: awsCluster, Month: "2019-01", Rows: []Resources{
+		{Project: "shop", PspElement: "12345", QuotaCpu: 4, Prices: Pricing{QuotaCpu: 10}},
+		{Project: "other", PspElement: "99999", QuotaCpu: 2, Prices: Pricing{QuotaCpu: 5}},
+	}})
+	store.Put(billingSnapshotsCollection, billingSnapshotID(awsCluster, february), BillingSnapshot{Cluster: awsCluster, Month: "2019-02", Rows: []Resources{
+		{Project: "shop", PspElement: "12345", QuotaCpu: 2, Prices: Pricing{QuotaCpu: 5.5, Storage: 1}},
+	}})
+
+	report, err := getBillingReport(january, february, "12345")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Rows) != 1 {
+		t.Fatalf("expected only the row of billing 12345, got %+v", report.Rows)
+	}
+	row := report.Rows[0]
+	if row.Project != "shop" || row.Months != 2 || row.QuotaCpu != 3 || row.Costs != 16.5 {
+		t.Errorf("expected the averaged quota and the summed costs, got %+v", row)
+	}
+
+	report, _ = getBillingReport(january, february, "")
+	if len(report.Rows) != 2 || report.Rows[0].Billing != "12345" || report.Rows[1].Billing != "99999" {
+		t.Errorf("expected the rows of all billing numbers, got %+v", report.Rows)
+	}
+	var b bytes.Buffer
+	if err := writeBillingReportCSV(&b, report); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 || lines[1] != "2019-01,2019-02,12345,shop,aws,2,3.00,0.00,0.00,0.00,0.00,0.00,0.00,16.50" {
+		t.Errorf("unexpected csv %q", b.String())
+	}
+}
@@ -0,0 +1,61 @@
+package openshift
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/jinzhu/now"
+)
+
+const billingSnapshotsCollection = "billing_snapshots"
+
+// BillingSnapshot is the chargeback of a cluster for one month. Snapshots of
+// completed months are stored, so statements don't change afterwards
+type BillingSnapshot struct {
+	Cluster   Cluster     `json:"cluster"`
+	Month     string      `json:"month"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Rows      []Resources `json:"rows"`
+}
+
+func billingSnapshotID(cluster Cluster, month time.Time) string {
+	return fmt.Sprintf("%v-%v", cluster, month.Format(monthFormat))
+}
+
+// getBillingSnapshot returns the stored snapshot of the month or calculates it
+func getBillingSnapshot(cluster Cluster, month time.Time) (*BillingSnapshot, error) {
+	month = now.New(month).BeginningOfMonth()
+	id := billingSnapshotID(cluster, month)
+
+	var snapshot BillingSnapshot
+	found, err := store.Get(billingSnapshotsCollection, id, &snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return &snapshot, nil
+	}
+
+	if err := checkNewrelicConfig(); err != nil {
+		return nil, err
+	}
+
+	snapshot = BillingSnapshot{
+		Cluster:   cluster,
+		Month:     month.Format(monthFormat),
+		CreatedAt: time.Now(),
+		Rows:      []Resources{},
+	}
+	for _, r := range getChargeback(month, "%", cluster) {
+		snapshot.Rows = append(snapshot.Rows, r)
+	}
+
+	// Only completed months are final
+	if now.New(month).EndOfMonth().Before(time.Now()) {
+		if err := store.Put(billingSnapshotsCollection, id, snapshot); err != nil {
+			return nil, err
+		}
+	}
+	return &snapshot, nil
+}
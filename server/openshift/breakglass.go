@@ -0,0 +1,391 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	breakGlassCollection       = "break_glass"
+	defaultBreakGlassHours     = 4
+	defaultBreakGlassIncidents = `^INC[0-9]{6,}$`
+	breakGlassRevocationLease  = "break-glass-revocation"
+)
+
+var errBreakGlassRevoked = errors.New("Der Notfallzugriff wurde bereits beendet")
+
+// BreakGlassAccess is a temporary admin access to a project during an
+// incident. It is revoked after ExpiresAt and the actions of the user are
+// collected in the report
+type BreakGlassAccess struct {
+	ID        string            `json:"id"`
+	ClusterId string            `json:"clusterid"`
+	Project   string            `json:"project"`
+	Username  string            `json:"username"`
+	Incident  string            `json:"incident"`
+	Reason    string            `json:"reason"`
+	GrantedAt time.Time         `json:"grantedAt"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+	RevokedAt *time.Time        `json:"revokedAt,omitempty"`
+	RevokedBy string            `json:"revokedBy,omitempty"`
+	Report    *BreakGlassReport `json:"report,omitempty"`
+}
+
+// BreakGlassReport is the post-incident report of a break-glass access
+type BreakGlassReport struct {
+	GeneratedAt time.Time     `json:"generatedAt"`
+	Duration    string        `json:"duration"`
+	Actions     []audit.Entry `json:"actions"`
+}
+
+func breakGlassConfig() (hours int, incidents *regexp.Regexp, err error) {
+	cfg := config.Config()
+	hours = cfg.GetInt("break_glass.hours")
+	if hours <= 0 {
+		hours = defaultBreakGlassHours
+	}
+	pattern := cfg.GetString("break_glass.incident_pattern")
+	if pattern == "" {
+		pattern = defaultBreakGlassIncidents
+	}
+	incidents, err = regexp.Compile(pattern)
+	return hours, incidents, err
+}
+
+// canBreakGlass allows the users in 'break_glass.users' (e.g. the on-call
+// engineers) to break the glass on the clusters in 'break_glass.clusters'
+func canBreakGlass(clusterId, username string) error {
+	cfg := config.Config()
+	if !containsFold(cfg.GetStringSlice("break_glass.users"), username) {
+		return errors.New("Du darfst keinen Notfallzugriff anfordern")
+	}
+	if clusters := cfg.GetStringSlice("break_glass.clusters"); len(clusters) > 0 && !containsFold(clusters, clusterId) {
+		return fmt.Errorf("Auf dem Cluster %v ist kein Notfallzugriff möglich", clusterId)
+	}
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, l := range list {
+		if strings.EqualFold(strings.TrimSpace(l), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// breakGlass makes the user admin of the project until the access expires
+// and informs the admins of the project
+func breakGlass(data common.BreakGlassCommand, username string, now time.Time) (*BreakGlassAccess, error) {
+	if err := canBreakGlass(data.ClusterId, username); err != nil {
+		return nil, err
+	}
+	hours, incidents, err := breakGlassConfig()
+	if err != nil {
+		return nil, err
+	}
+	if !incidents.MatchString(data.Incident) {
+		return nil, fmt.Errorf("Die Incident-Nummer %v ist ungültig", data.Incident)
+	}
+	if data.Reason == "" {
+		return nil, errors.New("Es muss ein Grund angegeben werden")
+	}
+
+	admins, _, err := getProjectAdminsAndOperators(data.ClusterId, data.Project)
+	if err != nil {
+		return nil, err
+	}
+	if contains(admins, strings.ToLower(username)) {
+		return nil, fmt.Errorf("Du bist bereits Admin des Projekts %v", data.Project)
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	access := &BreakGlassAccess{
+		ID:        id.String(),
+		ClusterId: data.ClusterId,
+		Project:   data.Project,
+		Username:  strings.ToLower(username),
+		Incident:  data.Incident,
+		Reason:    data.Reason,
+		GrantedAt: now,
+		ExpiresAt: now.Add(time.Duration(hours) * time.Hour),
+	}
+	// Recorded first, so the access is revoked even if the instance stops
+	// right after granting it
+	if err := store.Put(breakGlassCollection, access.ID, access); err != nil {
+		return nil, err
+	}
+	if err := addUsersToRoleBinding(data.ClusterId, data.Project, "admin", []string{access.Username}); err != nil {
+		if err := store.Delete(breakGlassCollection, access.ID); err != nil {
+			log.Printf("Error deleting break-glass access %v which wasn't granted: %v", access.ID, err)
+		}
+		return nil, err
+	}
+
+	log.Printf("WARNING: %v broke the glass of project %v on cluster %v for incident %v until %v: %v",
+		username, data.Project, data.ClusterId, data.Incident, access.ExpiresAt.Format(time.RFC3339), data.Reason)
+	if err := sendBreakGlassMail(*access, admins, false); err != nil {
+		log.Printf("Can't send e-mail about break-glass access %v: %v", access.ID, err)
+	}
+	return access, nil
+}
+
+// revokeBreakGlass removes the admin access and generates the report of the
+// actions of the user in the project. Removing the role can be repeated, so
+// if the user and the expiry revoke the access at the same time, only the
+// first one to mark it revoked reports it
+func revokeBreakGlass(access BreakGlassAccess, by string, now time.Time) (*BreakGlassAccess, error) {
+	if access.RevokedAt != nil {
+		return nil, errBreakGlassRevoked
+	}
+	if err := removeUsersFromRoleBinding(access.ClusterId, access.Project, "admin", []string{access.Username}); err != nil {
+		return nil, err
+	}
+
+	actions, err := audit.Find(audit.Query{
+		ClusterId: access.ClusterId,
+		Project:   access.Project,
+		Actor:     access.Username,
+		From:      access.GrantedAt,
+		To:        now,
+	})
+	if err != nil {
+		log.Printf("Error reading the actions of break-glass access %v: %v", access.ID, err)
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].Time.Before(actions[j].Time)
+	})
+	err = store.Update(breakGlassCollection, access.ID, &access, func(exists bool) error {
+		if !exists {
+			return errors.New("Der Notfallzugriff existiert nicht")
+		}
+		if access.RevokedAt != nil {
+			return errBreakGlassRevoked
+		}
+		access.RevokedAt = &now
+		access.RevokedBy = by
+		access.Report = &BreakGlassReport{
+			GeneratedAt: now,
+			Duration:    now.Sub(access.GrantedAt).Round(time.Minute).String(),
+			Actions:     actions,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Record(audit.Entry{
+		Time:      now,
+		Actor:     by,
+		Action:    "REVOKE break-glass",
+		ClusterId: access.ClusterId,
+		Project:   access.Project,
+		Status:    http.StatusOK,
+		Result:    fmt.Sprintf("Notfallzugriff von %v für Incident %v beendet", access.Username, access.Incident),
+		Links:     []string{"/api/audit/ose/breakglass?id=" + access.ID},
+	})
+	if err != nil {
+		log.Printf("Error recording the revocation of break-glass access %v: %v", access.ID, err)
+	}
+	log.Printf("%v revoked the break-glass access of %v to project %v on cluster %v after %v actions", by, access.Username, access.Project, access.ClusterId, len(actions))
+
+	admins, _, err := getProjectAdminsAndOperators(access.ClusterId, access.Project)
+	if err != nil {
+		log.Printf("Can't get the admins of project %v for the break-glass report: %v", access.Project, err)
+	}
+	if err := sendBreakGlassMail(access, admins, true); err != nil {
+		log.Printf("Can't send the report of break-glass access %v: %v", access.ID, err)
+	}
+	return &access, nil
+}
+
+func getBreakGlassAccesses(filter func(BreakGlassAccess) bool) ([]BreakGlassAccess, error) {
+	accesses := []BreakGlassAccess{}
+	err := store.List(breakGlassCollection, func(id string, data []byte) error {
+		var a BreakGlassAccess
+		if err := json.Unmarshal(data, &a); err != nil {
+			return err
+		}
+		if filter(a) {
+			accesses = append(accesses, a)
+		}
+		return nil
+	})
+	sort.Slice(accesses, func(i, j int) bool {
+		return accesses[i].GrantedAt.After(accesses[j].GrantedAt)
+	})
+	return accesses, err
+}
+
+// StartBreakGlassRevocation revokes the expired break-glass accesses every
+// minute on the instance holding the lease
+func StartBreakGlassRevocation() {
+	go func() {
+		for {
+			leader, err := store.TryLease(breakGlassRevocationLease, common.InstanceID(), 2*time.Minute)
+			if err != nil {
+				log.Printf("Error acquiring the lease of the break-glass revocation: %v", err)
+			}
+			if leader {
+				revokeExpiredBreakGlass(common.Now())
+			}
+			time.Sleep(time.Minute)
+		}
+	}()
+}
+
+func revokeExpiredBreakGlass(now time.Time) {
+	expired, err := getBreakGlassAccesses(func(a BreakGlassAccess) bool {
+		return a.RevokedAt == nil && !a.ExpiresAt.After(now)
+	})
+	if err != nil {
+		log.Printf("Error reading the break-glass accesses: %v", err)
+		return
+	}
+	for _, a := range expired {
+		if _, err := revokeBreakGlass(a, "system", now); err != nil {
+			log.Printf("Error revoking break-glass access %v of %v to project %v: %v", a.ID, a.Username, a.Project, err)
+		}
+	}
+}
+
+func sendBreakGlassMail(access BreakGlassAccess, admins []string, report bool) error {
+	recipients := []string{}
+	for _, user := range append(admins, access.Username) {
+		if mail := common.GetMailForUser(user); mail != "" {
+			recipients = append(recipients, mail)
+		}
+	}
+	if to := config.Config().GetString("break_glass.mail"); to != "" {
+		recipients = append(recipients, to)
+	}
+	if len(recipients) == 0 {
+		return errors.New("no recipients")
+	}
+
+	if !report {
+		return common.SendMail(common.RemoveDuplicates(recipients), fmt.Sprintf("Notfallzugriff auf Projekt %v", access.Project), fmt.Sprintf(`
+	Sehr geehrte Damen und Herren,
+	<br><br>
+	%v hat wegen Incident %v bis %v Admin-Zugriff auf das Projekt %v auf Cluster %v erhalten.
+	<br><br>
+	Grund: %v
+	<br><br>
+	Nach Ablauf des Zugriffs erhaltet ihr einen Bericht über alle Änderungen.
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, access.Username, html.EscapeString(access.Incident), access.ExpiresAt.In(common.Location()).Format("02.01.2006 15:04"), access.Project, access.ClusterId, html.EscapeString(access.Reason)))
+	}
+
+	actions := "Es wurden keine Änderungen über das Portal gemacht."
+	if len(access.Report.Actions) > 0 {
+		rows := []string{}
+		for _, a := range access.Report.Actions {
+			rows = append(rows, fmt.Sprintf("<tr><td>%v</td><td>%v</td><td>%v</td><td>%v</td></tr>",
+				a.Time.In(common.Location()).Format("02.01.2006 15:04:05"), html.EscapeString(a.Action), a.Status, html.EscapeString(a.Result)))
+		}
+		actions = "<table><tr><th>Zeit</th><th>Aktion</th><th>Status</th><th>Resultat</th></tr>" + strings.Join(rows, "") + "</table>"
+	}
+	return common.SendMail(common.RemoveDuplicates(recipients), fmt.Sprintf("Bericht Notfallzugriff auf Projekt %v", access.Project), fmt.Sprintf(`
+	Sehr geehrte Damen und Herren,
+	<br><br>
+	Der Notfallzugriff von %v auf das Projekt %v auf Cluster %v (Incident %v) wurde nach %v beendet.
+	<br><br>
+	%v
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, access.Username, access.Project, access.ClusterId, html.EscapeString(access.Incident), access.Report.Duration, actions))
+}
+
+func breakGlassHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.BreakGlassCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	access, err := breakGlass(data, username, common.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, access)
+}
+
+// getBreakGlassHandler lists the own break-glass accesses
+func getBreakGlassHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	accesses, err := getBreakGlassAccesses(func(a BreakGlassAccess) bool {
+		return strings.EqualFold(a.Username, username)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, accesses)
+}
+
+// revokeBreakGlassHandler ends a break-glass access before it expires. The
+// user can end the own access, portal admins all
+func revokeBreakGlassHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var access BreakGlassAccess
+	found, err := store.Get(breakGlassCollection, c.Query("id"), &access)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !found || (!strings.EqualFold(access.Username, username) && !common.IsPortalAdmin(username)) {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Der Notfallzugriff existiert nicht"})
+		return
+	}
+
+	revoked, err := revokeBreakGlass(access, username, common.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, revoked)
+}
+
+// getAllBreakGlassHandler lists the break-glass accesses with their reports,
+// optionally only ?id or those of ?clusterid and ?project
+func getAllBreakGlassHandler(c *gin.Context) {
+	id, clusterId, project := c.Query("id"), c.Query("clusterid"), c.Query("project")
+
+	accesses, err := getBreakGlassAccesses(func(a BreakGlassAccess) bool {
+		return (id == "" || a.ID == id) && (clusterId == "" || a.ClusterId == clusterId) && (project == "" || a.Project == project)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, accesses)
+}
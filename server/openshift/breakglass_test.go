@@ -0,0 +1,73 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestBreakGlass(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+	config.Config().Set("break_glass.users", []string{"oncall"})
+	if err := createNewProject(nil, "fake", "shop", "u123", "12345", "", false); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	command := func(incident string) common.BreakGlassCommand {
+		return common.BreakGlassCommand{
+			OpenshiftBase: common.OpenshiftBase{ClusterId: "fake", Project: "shop"},
+			Incident:      incident,
+			Reason:        "Shop ist down",
+		}
+	}
+
+	if _, err := breakGlass(command("INC1234567"), "u456", now); err == nil {
+		t.Error("expected users who aren't on call to be rejected")
+	}
+	if _, err := breakGlass(command("12345"), "oncall", now); err == nil {
+		t.Error("expected an invalid incident number to be rejected")
+	}
+	access, err := breakGlass(command("INC1234567"), "oncall", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if admins, _, _ := getProjectAdminsAndOperators("fake", "shop"); !contains(admins, "oncall") {
+		t.Errorf("expected oncall to be admin, got %v", admins)
+	}
+	if !access.ExpiresAt.Equal(now.Add(defaultBreakGlassHours * time.Hour)) {
+		t.Errorf("expected the access to expire after %v hours, got %v", defaultBreakGlassHours, access.ExpiresAt)
+	}
+
+	audit.Record(audit.Entry{Time: now.Add(time.Minute), Actor: "oncall", Action: "POST /api/ose/quotas", ClusterId: "fake", Project: "shop", Status: 200})
+	audit.Record(audit.Entry{Time: now.Add(time.Minute), Actor: "oncall", Action: "POST /api/ose/quotas", ClusterId: "fake", Project: "other", Status: 200})
+
+	revokeExpiredBreakGlass(now.Add(time.Hour))
+	if admins, _, _ := getProjectAdminsAndOperators("fake", "shop"); !contains(admins, "oncall") {
+		t.Error("expected the access not to be revoked before it expires")
+	}
+	revokeExpiredBreakGlass(access.ExpiresAt)
+	if admins, _, _ := getProjectAdminsAndOperators("fake", "shop"); contains(admins, "oncall") {
+		t.Errorf("expected the expired access to be revoked, got %v", admins)
+	}
+
+	accesses, _ := getBreakGlassAccesses(func(BreakGlassAccess) bool { return true })
+	if len(accesses) != 1 || accesses[0].RevokedBy != "system" || accesses[0].Report == nil {
+		t.Fatalf("expected the revoked access with its report, got %+v", accesses)
+	}
+	if actions := accesses[0].Report.Actions; len(actions) != 1 || actions[0].Action != "POST /api/ose/quotas" {
+		t.Errorf("expected the quota change in shop in the report, got %+v", actions)
+	}
+	if _, err := revokeBreakGlass(accesses[0], "oncall", now); err == nil {
+		t.Error("expected an error for a revoked access")
+	}
+	// The handler read the access before the expiry revoked it
+	stale := accesses[0]
+	stale.RevokedAt = nil
+	if _, err := revokeBreakGlass(stale, "oncall", now); err != errBreakGlassRevoked {
+		t.Errorf("expected the access to be revoked only once, got %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package openshift
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	projectBudgetsCollection = "project_budgets"
+	budgetCheckLease         = "budget-check"
+)
+
+// budgetThresholds are the percentages of the budget which trigger a notification
+var budgetThresholds = []int{50, 80, 100}
+
+// ProjectBudget is the monthly budget of a project in CHF
+type ProjectBudget struct {
+	ClusterId          string     `json:"clusterid"`
+	Project            string     `json:"project"`
+	Amount             float64    `json:"amount"`
+	BlockQuotaIncrease bool       `json:"blockQuotaIncrease"`
+	SetBy              string     `json:"setBy"`
+	Month              string     `json:"month,omitempty"`
+	Spend              float64    `json:"spend"`
+	Notified           []int      `json:"notified"`
+	CheckedAt          *time.Time `json:"checkedAt,omitempty"`
+}
+
+// Overrun returns true if the spend of the current month reached the budget
+func (b ProjectBudget) Overrun() bool {
+	return b.Amount > 0 && b.Spend >= b.Amount
+}
+
+func projectBudgetID(clusterId, project string) string {
+	return clusterId + "/" + project
+}
+
+func getProjectBudgetHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	budget, found, err := getProjectBudget(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: "Für das Projekt ist kein Budget definiert"})
+		return
+	}
+	c.JSON(http.StatusOK, budget)
+}
+
+func setProjectBudgetHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.ProjectBudgetCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if data.Amount < 0 {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Das Budget darf nicht negativ sein"})
+		return
+	}
+
+	budget, _, err := getProjectBudget(data.ClusterId, data.Project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if data.Amount == 0 {
+		if err := store.Delete(projectBudgetsCollection, projectBudgetID(data.ClusterId, data.Project)); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		audit.Describe(c, "%v removed the budget of project %v on cluster %v", username, data.Project, data.ClusterId)
+		c.JSON(http.StatusOK, common.ApiResponse{Message: "Das Budget wurde entfernt"})
+		return
+	}
+
+	// Notifications are sent again if the budget changes
+	if budget.Amount != data.Amount {
+		budget.Notified = []int{}
+	}
+	budget.Amount = data.Amount
+	budget.BlockQuotaIncrease = data.BlockQuotaIncrease
+	budget.SetBy = username
+	if err := store.Put(projectBudgetsCollection, projectBudgetID(data.ClusterId, data.Project), budget); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v set the budget of project %v on cluster %v to %v", username, data.Project, data.ClusterId, data.Amount)
+	c.JSON(http.StatusOK, budget)
+}
+
+func getProjectBudget(clusterId, project string) (*ProjectBudget, bool, error) {
+	budget := &ProjectBudget{
+		ClusterId: clusterId,
+		Project:   project,
+		Notified:  []int{},
+	}
+	found, err := store.Get(projectBudgetsCollection, projectBudgetID(clusterId, project), budget)
+	return budget, found, err
+}
+
+// StartBudgetCheck compares the spend of the projects with their budget once
+// a day if 'budget.enabled' is set. Only the instance holding the lease
+// checks, so the owners aren't warned once per instance
+func StartBudgetCheck() {
+	if !config.Config().GetBool("budget.enabled") {
+		return
+	}
+
+	go func() {
+		for {
+			leader, err := store.TryLease(budgetCheckLease, common.InstanceID(), 48*time.Hour)
+			if err != nil {
+				log.Printf("Error acquiring the lease of the budget check: %v", err)
+			}
+			if leader {
+				checkProjectBudgets(common.Now())
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
+
+func checkProjectBudgets(at time.Time) {
+	if err := checkNewrelicConfig(); err != nil {
+		log.Printf("Can't check budgets: %v", err)
+		return
+	}
+
+	budgets := []ProjectBudget{}
+	err := store.List(projectBudgetsCollection, func(id string, data []byte) error {
+		var b ProjectBudget
+		if err := json.Unmarshal(data, &b); err != nil {
+			return err
+		}
+		budgets = append(budgets, b)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error reading budgets: %v", err)
+		return
+	}
+
+	for _, b := range budgets {
+		if err := checkProjectBudget(&b, at); err != nil {
+			log.Printf("Error checking budget of project %v on cluster %v: %v", b.Project, b.ClusterId, err)
+		}
+	}
+}
+
+func checkProjectBudget(budget *ProjectBudget, at time.Time) error {
+	cluster, err := getOpenshiftCluster(budget.ClusterId)
+	if err != nil {
+		return err
+	}
+	if cluster.Chargeback == "" {
+		return fmt.Errorf("cluster %v has no chargeback cluster configured", cluster.ID)
+	}
+
+	month := at.Format(monthFormat)
+	if budget.Month != month {
+		budget.Month = month
+		budget.Notified = []int{}
+	}
+	budget.Spend = getTotalPrice(getChargeback(at, budget.Project, cluster.Chargeback)[budget.Project])
+	checkedAt := time.Now()
+	budget.CheckedAt = &checkedAt
+
+	if threshold := nextBudgetThreshold(*budget); threshold > 0 {
+		if err := sendBudgetMail(*budget, threshold); err != nil {
+			log.Printf("Can't send e-mail about budget of project %v: %v", budget.Project, err)
+		}
+		for _, t := range budgetThresholds {
+			if t <= threshold && !containsInt(budget.Notified, t) {
+				budget.Notified = append(budget.Notified, t)
+			}
+		}
+	}
+	return store.Put(projectBudgetsCollection, projectBudgetID(budget.ClusterId, budget.Project), budget)
+}
+
+// nextBudgetThreshold returns the highest reached threshold which wasn't
+// notified yet or 0
+func nextBudgetThreshold(budget ProjectBudget) int {
+	if budget.Amount <= 0 {
+		return 0
+	}
+	percent := budget.Spend / budget.Amount * 100
+	reached := 0
+	for _, t := range budgetThresholds {
+		if percent >= float64(t) && !containsInt(budget.Notified, t) {
+			reached = t
+		}
+	}
+	return reached
+}
+
+func containsInt(list []int, search int) bool {
+	for _, i := range list {
+		if i == search {
+			return true
+		}
+	}
+	return false
+}
+
+func sendBudgetMail(budget ProjectBudget, threshold int) error {
+	admins, _, err := getProjectAdminsAndOperators(budget.ClusterId, budget.Project)
+	if err != nil {
+		return err
+	}
+	recipients := []string{}
+	for _, a := range admins {
+		if mail := common.GetMailForUser(a); mail != "" {
+			recipients = append(recipients, mail)
+		}
+	}
+
+	action := ""
+	if threshold >= 100 && budget.BlockQuotaIncrease {
+		action = "Die Quotas des Projekts können bis Ende Monat nicht mehr erhöht werden."
+	}
+	return common.SendMail(common.RemoveDuplicates(recipients), fmt.Sprintf("Budget des Projekts %v zu %v%% aufgebraucht", budget.Project, threshold), fmt.Sprintf(`
+	Sehr geehrte Damen und Herren,
+	<br><br>
+	Das Projekt %v auf Cluster %v hat im Monat %v bereits %.2f CHF von %.2f CHF Budget verbraucht.
+	%v
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, budget.Project, budget.ClusterId, budget.Month, budget.Spend, budget.Amount, action))
+}
+
+// checkBudgetForQuotas blocks quota increases of projects which overran
+// their budget if the owners chose so
+func checkBudgetForQuotas(clusterId, project string, cpu, memory int) error {
+	budget, found, err := getProjectBudget(clusterId, project)
+	if err != nil || !found || !budget.BlockQuotaIncrease || !budget.Overrun() {
+		return err
+	}
+	if budget.Month != common.Now().Format(monthFormat) {
+		return nil
+	}
+
+	quotas, err := listObjects(clusterId, fmt.Sprintf("api/v1/namespaces/%v/resourcequotas", project))
+	if err != nil {
+		return err
+	}
+	var currentCPU, currentMemory float64
+	if q := projectQuota(quotas); q != nil {
+		currentCPU = parseCPUQuantity(q.Path("spec.hard.cpu").Data())
+		currentMemory = parseMemoryQuantityGi(q.Path("spec.hard.memory").Data())
+	}
+	if float64(cpu) > currentCPU || float64(memory) > currentMemory {
+		log.Printf("Blocked quota increase of project %v on cluster %v because of budget overrun", project, clusterId)
+		return fmt.Errorf("Das Budget von %.2f CHF ist aufgebraucht. Die Quotas können diesen Monat nicht erhöht werden", budget.Amount)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package openshift
+
+import "testing"
+
+func TestNextBudgetThreshold(t *testing.T) {
+	tests := []struct {
+		spend    float64
+		notified []int
+		expected int
+	}{
+		{10, []int{}, 0},
+		{55, []int{}, 50},
+		{85, []int{}, 80},
+		{85, []int{50, 80}, 0},
+		{120, []int{50}, 100},
+	}
+	for _, tt := range tests {
+		b := ProjectBudget{Amount: 100, Spend: tt.spend, Notified: tt.notified}
+		if actual := nextBudgetThreshold(b); actual != tt.expected {
+			t.Errorf("nextBudgetThreshold(%v, %v): expected %v, got %v", tt.spend, tt.notified, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package openshift
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// bulkBillingHandler sets the billing account of many projects at once. Each
+// project is checked and changed on its own, so the result tells per project
+// whether it was changed
+func bulkBillingHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.BulkBillingCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	data.Billing = strings.TrimSpace(data.Billing)
+	if data.Billing == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Kontierungsnummer muss angegeben werden"})
+		return
+	}
+	if len(data.Projects) == 0 {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Es muss mindestens ein Projekt angegeben werden"})
+		return
+	}
+
+	dryRun := common.IsDryRun(c)
+	results := make([]common.BulkBillingResult, len(data.Projects))
+	changes := make([][]common.PlannedChange, len(data.Projects))
+	common.ForEachParallel(len(data.Projects), func(i int) error {
+		p := data.Projects[i]
+		results[i] = common.BulkBillingResult{ClusterId: p.ClusterId, Project: p.Project}
+		var err error
+		changes[i], err = changeProjectBilling(p.ClusterId, p.Project, username, data.Billing, !dryRun)
+		if err != nil {
+			results[i].Message = err.Error()
+			return err
+		}
+		results[i].Success = true
+		return nil
+	})
+
+	if dryRun {
+		planned := []common.PlannedChange{}
+		for _, change := range changes {
+			planned = append(planned, change...)
+		}
+		common.RespondDryRun(c, fmt.Sprintf("Die Kontierungsnummer von %v Projekten würde auf %v geändert", len(planned), data.Billing), planned...)
+		return
+	}
+
+	succeeded := 0
+	for _, r := range results {
+		if r.Success {
+			succeeded++
+		}
+	}
+	audit.Describe(c, "%v changed the billing of %v of %v projects to %v", username, succeeded, len(results), data.Billing)
+	c.JSON(http.StatusOK, common.BulkBillingResponse{
+		Message: fmt.Sprintf("Die Kontierungsnummer wurde in %v von %v Projekten auf %v geändert", succeeded, len(results), data.Billing),
+		Results: results,
+	})
+}
+
+// changeProjectBilling sets the billing annotation of the project if the user
+// is admin of it. Only the planned change is returned if apply is false
+func changeProjectBilling(clusterId, project, username, billing string, apply bool) ([]common.PlannedChange, error) {
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		return nil, err
+	}
+	update := func(annotations *gabs.Container) {
+		setAnnotation(annotations, annotationBilling, billing)
+	}
+	if !apply {
+		return previewAnnotations(clusterId, project, update)
+	}
+	if err := updateNamespaceAnnotations(clusterId, project, update); err != nil {
+		return nil, err
+	}
+	log.Printf("%v changed the billing of project %v on cluster %v to %v", username, project, clusterId, billing)
+	return nil, nil
+}
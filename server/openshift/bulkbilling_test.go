@@ -0,0 +1,36 @@
+package openshift
+
+import "testing"
+
+func TestChangeProjectBilling(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	for _, project := range []string{"shop", "other"} {
+		if err := createNewProject(nil, "fake", project, "u123", "12345", "", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes, err := changeProjectBilling("fake", "shop", "u123", "70001", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Current != "12345" || changes[0].Proposed != "70001" {
+		t.Errorf("expected the planned change of the billing, got %+v", changes)
+	}
+	namespace, _ := api.Get("api/v1/namespaces/shop")
+	if billing := getAnnotation(namespace.Path("metadata.annotations"), annotationBilling); billing != "12345" {
+		t.Errorf("expected the dry-run not to change the project, got %v", billing)
+	}
+
+	if _, err := changeProjectBilling("fake", "shop", "u123", "70001", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	namespace, _ = api.Get("api/v1/namespaces/shop")
+	if billing := getAnnotation(namespace.Path("metadata.annotations"), annotationBilling); billing != "70001" {
+		t.Errorf("expected the billing to be changed, got %v", billing)
+	}
+	if _, err := changeProjectBilling("fake", "other", "u456", "70001", true); err == nil {
+		t.Error("expected users who aren't admin to be rejected")
+	}
+}
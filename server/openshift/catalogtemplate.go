@@ -0,0 +1,286 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	catalogTemplatesCollection = "catalog_templates"
+	templateKindProject        = "project"
+	templateKindQuota          = "quota"
+	templateKindPipeline       = "pipeline"
+	templateStateDraft         = "draft"
+	templateStatePublished     = "published"
+	templateStateArchived      = "archived"
+)
+
+// CatalogTemplate is a template of the creation flows with all its versions.
+// Only the published version is used, drafts can be edited until they are published
+type CatalogTemplate struct {
+	Kind     string                   `json:"kind"`
+	Name     string                   `json:"name"`
+	Versions []CatalogTemplateVersion `json:"versions"`
+}
+
+type CatalogTemplateVersion struct {
+	Version     int                    `json:"version"`
+	State       string                 `json:"state"`
+	Description string                 `json:"description"`
+	Content     CatalogTemplateContent `json:"content"`
+	CreatedBy   string                 `json:"createdBy"`
+	CreatedAt   time.Time              `json:"createdAt"`
+	PublishedBy string                 `json:"publishedBy,omitempty"`
+	PublishedAt *time.Time             `json:"publishedAt,omitempty"`
+}
+
+// CatalogTemplateContent are the settings of a template. Project and pipeline
+// templates reference an OpenShift template, quota templates set the quota
+type CatalogTemplateContent struct {
+	Template          string            `json:"template,omitempty"`
+	TemplateNamespace string            `json:"templateNamespace,omitempty"`
+	Parameters        map[string]string `json:"parameters,omitempty"`
+	QuotaCpu          int               `json:"quotaCpu,omitempty"`
+	QuotaMemory       int               `json:"quotaMemory,omitempty"`
+}
+
+type catalogTemplateCommand struct {
+	Description string                 `json:"description"`
+	Content     CatalogTemplateContent `json:"content"`
+}
+
+func catalogTemplateID(kind, name string) string {
+	return kind + "/" + name
+}
+
+// Published returns the published version or nil
+func (t CatalogTemplate) Published() *CatalogTemplateVersion {
+	for i := range t.Versions {
+		if t.Versions[i].State == templateStatePublished {
+			return &t.Versions[i]
+		}
+	}
+	return nil
+}
+
+// saveDraft replaces the current draft or adds a new draft version
+func (t *CatalogTemplate) saveDraft(description string, content CatalogTemplateContent, username string) CatalogTemplateVersion {
+	draft := CatalogTemplateVersion{
+		Version:     len(t.Versions) + 1,
+		State:       templateStateDraft,
+		Description: description,
+		Content:     content,
+		CreatedBy:   username,
+		CreatedAt:   time.Now(),
+	}
+	if last := len(t.Versions) - 1; last >= 0 && t.Versions[last].State == templateStateDraft {
+		draft.Version = t.Versions[last].Version
+		t.Versions[last] = draft
+		return draft
+	}
+	t.Versions = append(t.Versions, draft)
+	return draft
+}
+
+// publish publishes the draft and archives the previously published version
+func (t *CatalogTemplate) publish(username string) (*CatalogTemplateVersion, error) {
+	last := len(t.Versions) - 1
+	if last < 0 || t.Versions[last].State != templateStateDraft {
+		return nil, errors.New("Es gibt keinen Entwurf, der veröffentlicht werden kann")
+	}
+	for i := range t.Versions {
+		if t.Versions[i].State == templateStatePublished {
+			t.Versions[i].State = templateStateArchived
+		}
+	}
+	publishedAt := time.Now()
+	t.Versions[last].State = templateStatePublished
+	t.Versions[last].PublishedBy = username
+	t.Versions[last].PublishedAt = &publishedAt
+	return &t.Versions[last], nil
+}
+
+func validateCatalogTemplate(kind string, content CatalogTemplateContent) error {
+	switch kind {
+	case templateKindProject, templateKindPipeline:
+		if content.Template == "" {
+			return errors.New("Der Name des OpenShift-Templates muss angegeben werden")
+		}
+	case templateKindQuota:
+		if content.QuotaCpu <= 0 || content.QuotaMemory <= 0 {
+			return errors.New("CPU und Memory der Quota müssen angegeben werden")
+		}
+	default:
+		return fmt.Errorf("Ungültiger Typ %v. Erlaubt sind: %v, %v, %v", kind, templateKindProject, templateKindQuota, templateKindPipeline)
+	}
+	return nil
+}
+
+func getCatalogTemplatesHandler(c *gin.Context) {
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	templates, err := getCatalogTemplates()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	filtered := []CatalogTemplate{}
+	for _, t := range templates {
+		if (c.Query("kind") == "" || c.Query("kind") == t.Kind) && listParams.Matches(t.Kind, t.Name) {
+			filtered = append(filtered, t)
+		}
+	}
+
+	start, end, next := listParams.Page(len(filtered))
+	c.JSON(http.StatusOK, common.ListResponse{
+		Items:    filtered[start:end],
+		Total:    len(filtered),
+		Continue: next,
+	})
+}
+
+func getCatalogTemplateHandler(c *gin.Context) {
+	template, found, err := getCatalogTemplate(c.Param("kind"), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: "Das Template existiert nicht"})
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+func saveCatalogTemplateDraftHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	kind := c.Param("kind")
+	name := c.Param("name")
+
+	var data catalogTemplateCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateCatalogTemplate(kind, data.Content); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	template, found, err := getCatalogTemplate(kind, name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !found {
+		template = &CatalogTemplate{Kind: kind, Name: name, Versions: []CatalogTemplateVersion{}}
+	}
+
+	draft := template.saveDraft(data.Description, data.Content, username)
+	if err := store.Put(catalogTemplatesCollection, catalogTemplateID(kind, name), template); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v saved draft version %v of %v template %v", username, draft.Version, kind, name)
+	c.JSON(http.StatusOK, template)
+}
+
+func publishCatalogTemplateHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	kind := c.Param("kind")
+	name := c.Param("name")
+
+	template, found, err := getCatalogTemplate(kind, name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: "Das Template existiert nicht"})
+		return
+	}
+
+	version, err := template.publish(username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err := store.Put(catalogTemplatesCollection, catalogTemplateID(kind, name), template); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v published version %v of %v template %v", username, version.Version, kind, name)
+	c.JSON(http.StatusOK, template)
+}
+
+func deleteCatalogTemplateHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	kind := c.Param("kind")
+	name := c.Param("name")
+
+	if err := store.Delete(catalogTemplatesCollection, catalogTemplateID(kind, name)); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v deleted the %v template %v", username, kind, name)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: "Das Template wurde gelöscht"})
+}
+
+func getCatalogTemplates() ([]CatalogTemplate, error) {
+	templates := []CatalogTemplate{}
+	err := store.List(catalogTemplatesCollection, func(id string, data []byte) error {
+		var t CatalogTemplate
+		if err := json.Unmarshal(data, &t); err != nil {
+			return err
+		}
+		templates = append(templates, t)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error reading catalog templates: %v", err)
+		return nil, errors.New(genericAPIError)
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return catalogTemplateID(templates[i].Kind, templates[i].Name) < catalogTemplateID(templates[j].Kind, templates[j].Name)
+	})
+	return templates, nil
+}
+
+func getCatalogTemplate(kind, name string) (*CatalogTemplate, bool, error) {
+	template := &CatalogTemplate{}
+	found, err := store.Get(catalogTemplatesCollection, catalogTemplateID(kind, name), template)
+	return template, found, err
+}
+
+// getPublishedTemplateContent returns the content of the published version
+// of the template. Returns false if there is none
+func getPublishedTemplateContent(kind, name string) (*CatalogTemplateContent, bool) {
+	template, found, err := getCatalogTemplate(kind, name)
+	if err != nil || !found {
+		return nil, false
+	}
+	published := template.Published()
+	if published == nil {
+		return nil, false
+	}
+	return &published.Content, true
+}
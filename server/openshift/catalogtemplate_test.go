@@ -0,0 +1,41 @@
+package openshift
+
+import "testing"
+
+func TestCatalogTemplateVersions(t *testing.T) {
+	template := &CatalogTemplate{Kind: templateKindQuota, Name: "small"}
+
+	if _, err := template.publish("admin"); err == nil {
+		t.Error("expected an error when publishing without a draft")
+	}
+
+	template.saveDraft("first", CatalogTemplateContent{QuotaCpu: 1, QuotaMemory: 2}, "admin")
+	template.saveDraft("first, fixed", CatalogTemplateContent{QuotaCpu: 2, QuotaMemory: 4}, "admin")
+	if len(template.Versions) != 1 {
+		t.Fatalf("expected the draft to be replaced, got %v versions", len(template.Versions))
+	}
+	if template.Published() != nil {
+		t.Error("expected no published version")
+	}
+
+	if _, err := template.publish("admin"); err != nil {
+		t.Fatal(err)
+	}
+	template.saveDraft("second", CatalogTemplateContent{QuotaCpu: 4, QuotaMemory: 8}, "admin")
+	if len(template.Versions) != 2 || template.Versions[1].Version != 2 {
+		t.Fatalf("expected a new draft version 2, got %+v", template.Versions)
+	}
+	if published := template.Published(); published == nil || published.Content.QuotaCpu != 2 {
+		t.Errorf("expected version 1 to stay published, got %+v", published)
+	}
+
+	if _, err := template.publish("admin"); err != nil {
+		t.Fatal(err)
+	}
+	if template.Versions[0].State != templateStateArchived {
+		t.Errorf("expected version 1 to be archived, got %v", template.Versions[0].State)
+	}
+	if published := template.Published(); published == nil || published.Version != 2 {
+		t.Errorf("expected version 2 to be published, got %+v", published)
+	}
+}
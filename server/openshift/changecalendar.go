@@ -0,0 +1,217 @@
+package openshift
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/maintenance"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	changeTypeProjectDeletion = "project-deletion"
+	changeTypeQuota           = "quota-change"
+	changeTypeMaintenance     = "maintenance"
+
+	changeStateScheduled = "scheduled"
+	changeStateCompleted = "completed"
+
+	icalTimeFormat = "20060102T150405Z"
+)
+
+// auditedChanges maps the actions of the audit log to the types of changes
+var auditedChanges = map[string]string{
+	"DELETE /api/ose/project":            changeTypeProjectDeletion,
+	"POST /api/admin/ose/project/delete": changeTypeProjectDeletion,
+	"POST /api/ose/quotas":               changeTypeQuota,
+	"PUT /api/ose/quotas":                changeTypeQuota,
+}
+
+// ChangeEvent is a scheduled or completed change of the change calendar
+type ChangeEvent struct {
+	UID       string    `json:"uid"`
+	Type      string    `json:"type"`
+	State     string    `json:"state"`
+	Summary   string    `json:"summary"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	ClusterId string    `json:"clusterid,omitempty"`
+	Project   string    `json:"project,omitempty"`
+	By        string    `json:"by,omitempty"`
+}
+
+// getChangeCalendarHandler returns the changes between ?from and ?to
+// (default the last 30 and the next 90 days) as json or with ?format=ical
+// as calendar to subscribe to. ?billing or ?label select the projects of a team
+func getChangeCalendarHandler(c *gin.Context) {
+	now := common.Now()
+	from, to := now.AddDate(0, 0, -30), now.AddDate(0, 0, 90)
+	for param, t := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", value, common.Location())
+			if err != nil {
+				c.JSON(http.StatusBadRequest, common.ApiResponse{Message: fmt.Sprintf("Ungültiges Datum %v: %v", param, value)})
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	var team map[string]bool
+	if c.Query("billing") != "" || c.Query("label") != "" {
+		projects, _, err := getTeamProjects(getOpenshiftClusters(""), c.Query("label"), c.Query("billing"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		team = make(map[string]bool)
+		for _, p := range projects {
+			team[projectNode(p.ClusterId, p.Project)] = true
+		}
+	}
+
+	events, err := getChangeEvents(from, to, team)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if c.Query("format") == "ical" {
+		c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(toICal(events, now)))
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// getChangeEvents collects the completed changes of the audit log, the
+// purges of the recycle bin, the load test windows and the maintenance of
+// endpoints. With team only the changes of its projects and the
+// maintenance are returned. Changes of projects which don't exist anymore
+// can't be assigned to a team
+func getChangeEvents(from, to time.Time, team map[string]bool) ([]ChangeEvent, error) {
+	events := []ChangeEvent{}
+	add := func(e ChangeEvent) {
+		if e.End.Before(from) || e.Start.After(to) {
+			return
+		}
+		if team != nil && e.Type != changeTypeMaintenance && !team[projectNode(e.ClusterId, e.Project)] {
+			return
+		}
+		events = append(events, e)
+	}
+
+	entries, err := audit.Find(audit.Query{From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		changeType, ok := auditedChanges[e.Action]
+		if !ok || e.DryRun || e.Status >= http.StatusBadRequest {
+			continue
+		}
+		add(ChangeEvent{
+			UID: "audit-" + e.ID, Type: changeType, State: changeStateCompleted,
+			Summary: changeSummary(changeType, e.Project), Start: e.Time, End: e.Time,
+			ClusterId: e.ClusterId, Project: e.Project, By: e.Actor,
+		})
+	}
+
+	recycled, err := getRecycledProjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range recycled {
+		add(ChangeEvent{
+			UID: "purge-" + recycledProjectID(p.ClusterId, p.Project), Type: changeTypeProjectDeletion, State: changeStateScheduled,
+			Summary: fmt.Sprintf("Projekt %v wird endgültig gelöscht", p.Project), Start: p.PurgeAt, End: p.PurgeAt,
+			ClusterId: p.ClusterId, Project: p.Project,
+		})
+	}
+
+	windows, err := getLoadTestWindows(func(w LoadTestWindow) bool {
+		return w.State != loadTestStateCanceled && w.State != loadTestStateFailed
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range windows {
+		state := changeStateScheduled
+		if w.State == loadTestStateDone {
+			state = changeStateCompleted
+		}
+		add(ChangeEvent{
+			UID: "loadtest-" + w.ID, Type: changeTypeQuota, State: state,
+			Summary: fmt.Sprintf("Lasttest in Projekt %v: Quota %v CPU / %v GB", w.Project, w.CPU, w.Memory), Start: w.Start, End: w.End,
+			ClusterId: w.ClusterId, Project: w.Project, By: w.RequestedBy,
+		})
+	}
+
+	toggles, err := maintenance.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range toggles {
+		end := t.DisabledAt
+		if t.ETA != nil {
+			end = *t.ETA
+		}
+		add(ChangeEvent{
+			UID: "maintenance-" + strings.TrimSpace(t.Method+" "+t.Path), Type: changeTypeMaintenance, State: changeStateScheduled,
+			Summary: fmt.Sprintf("Wartung %v: %v", t.Path, t.Message), Start: t.DisabledAt, End: end, By: t.DisabledBy,
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Start.Before(events[j].Start)
+	})
+	return events, nil
+}
+
+func changeSummary(changeType, project string) string {
+	if changeType == changeTypeQuota {
+		return fmt.Sprintf("Quota von Projekt %v geändert", project)
+	}
+	return fmt.Sprintf("Projekt %v gelöscht", project)
+}
+
+// toICal renders the events as iCalendar (RFC 5545)
+func toICal(events []ChangeEvent, now time.Time) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//SBB//SSP Portal//DE",
+		"X-WR-CALNAME:Changes Cloud Platform",
+	}
+	for _, e := range events {
+		description := e.State
+		if e.ClusterId != "" {
+			description += ", Cluster " + e.ClusterId
+		}
+		if e.By != "" {
+			description += ", " + e.By
+		}
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+icalEscape(e.UID)+"@ssp",
+			"DTSTAMP:"+now.UTC().Format(icalTimeFormat),
+			"DTSTART:"+e.Start.UTC().Format(icalTimeFormat),
+			"DTEND:"+e.End.UTC().Format(icalTimeFormat),
+			"SUMMARY:"+icalEscape(e.Summary),
+			"DESCRIPTION:"+icalEscape(description),
+			"CATEGORIES:"+e.Type,
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func icalEscape(s string) string {
+	return icalEscaper.Replace(s)
+}
@@ -0,0 +1,57 @@
+package openshift
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/maintenance"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+)
+
+func TestChangeEvents(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+	now := time.Now()
+
+	entries := []audit.Entry{
+		{Time: now.Add(-2 * time.Hour), Actor: "u123", Action: "DELETE /api/ose/project", ClusterId: "fake", Project: "old", Status: 200},
+		{Time: now.Add(-time.Hour), Actor: "u123", Action: "POST /api/ose/quotas", ClusterId: "fake", Project: "app", Status: 200, DryRun: true},
+		{Time: now.Add(-time.Hour), Actor: "u123", Action: "POST /api/ose/quotas", ClusterId: "fake", Project: "app", Status: 400},
+		{Time: now.Add(-time.Hour), Actor: "u123", Action: "POST /api/ose/quotas", ClusterId: "fake", Project: "app", Status: 200},
+	}
+	for _, e := range entries {
+		if err := audit.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Put(recycleBinCollection, recycledProjectID("fake", "app"), RecycledProject{ClusterId: "fake", Project: "app", PurgeAt: now.AddDate(0, 0, 7)})
+	eta := now.Add(2 * time.Hour)
+	if _, err := maintenance.Disable(maintenance.Toggle{Path: "/api/ose/project", Message: "Cluster-Upgrade", ETA: &eta}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := getChangeEvents(now.AddDate(0, 0, -1), now.AddDate(0, 0, 30), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := []string{}
+	for _, e := range events {
+		types = append(types, e.Type+"/"+e.State)
+	}
+	expected := "project-deletion/completed quota-change/completed maintenance/scheduled project-deletion/scheduled"
+	if strings.Join(types, " ") != expected {
+		t.Errorf("expected %v, got %v", expected, types)
+	}
+
+	events, _ = getChangeEvents(now.AddDate(0, 0, -1), now.AddDate(0, 0, 30), map[string]bool{"fake/app": true})
+	if len(events) != 3 {
+		t.Errorf("expected the changes of app and the maintenance, got %+v", events)
+	}
+
+	ical := toICal(events, now)
+	if strings.Count(ical, "BEGIN:VEVENT") != 3 || !strings.Contains(ical, `SUMMARY:Wartung /api/ose/project: Cluster-Upgrade`) || !strings.HasSuffix(ical, "END:VCALENDAR\r\n") {
+		t.Errorf("unexpected calendar %v", ical)
+	}
+}
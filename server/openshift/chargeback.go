@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/now"
-	"log"
+	"io"
 	"math"
 	"net/http"
 	"net/url"
@@ -17,6 +19,8 @@ import (
 	"strings"
 	"text/template"
 	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
 type OpenshiftChargebackCommand struct {
@@ -123,6 +127,18 @@ const awsSourceUsage = "`ec2Tag_Environment` = 'prod' OR hostname like 'node%'"
 
 const dateFormat = "2006-01-02 15:04:05"
 
+var unitprices = Pricing{
+	QuotaCpu:        10.0,
+	QuotaMemory:     2.5,
+	RequestedCpu:    40.0,
+	RequestedMemory: 10,
+	UsedCpu:         40,
+	UsedMemory:      10,
+	Storage:         1.0,
+}
+
+const managementFee = 1.0625
+
 // Templates
 const quotaQueryTemplate = "SELECT average(cpuHard) AS CpuQuota, average(cpuUsed) AS CpuRequests, average(memoryHard) AS MemoryQuota, average(memoryUsed) AS MemoryRequests, average(storage) AS Storage " +
 	"FROM {{.Source}} FACET project WHERE project LIKE '{{.Search}}' SINCE '{{.Since}}' UNTIL '{{.Until}}' LIMIT 1000"
@@ -134,18 +150,8 @@ const assignmentQueryTemplate = "SELECT latest(accountAssignment), latest(megaId
 
 func chargebackHandler(c *gin.Context) {
 	username := common.GetUserName(c)
-	var unitprices = Pricing{
-		QuotaCpu:        10.0,
-		QuotaMemory:     2.5,
-		RequestedCpu:    40.0,
-		RequestedMemory: 10,
-		UsedCpu:         40,
-		UsedMemory:      10,
-		Storage:         1.0,
-	}
-	const managementFee = 1.0625
 
-	log.Printf("%v called openshift chargeback", username)
+	audit.Describe(c, "%v called openshift chargeback", username)
 	var data OpenshiftChargebackCommand
 	if err := c.BindJSON(&data); err != nil {
 		fmt.Println(err.Error())
@@ -153,18 +159,55 @@ func chargebackHandler(c *gin.Context) {
 		return
 	}
 
+	resourceMap := getChargeback(data.Date, data.ProjectContains, data.Cluster)
+
+	report := createCSVReport(resourceMap, data.Date)
+
+	v := make([]Resources, 0, len(resourceMap))
+	for _, value := range resourceMap {
+		v = append(v, value)
+	}
+	c.JSON(http.StatusOK, ApiResponse{
+		CSV:  report,
+		Rows: v,
+	})
+}
+
+// chargebackCSVHandler streams the chargeback of the month as csv file
+func chargebackCSVHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data OpenshiftChargebackCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	audit.Describe(c, "%v exported the openshift chargeback of %v", username, data.Date.Format(monthFormat))
+	resourceMap := getChargeback(data.Date, data.ProjectContains, data.Cluster)
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=chargeback-%v-%v.csv", data.Cluster, data.Date.Format(monthFormat)))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := writeCSVReport(c.Writer, resourceMap, data.Date); err != nil {
+		common.Logger(c).Printf("Error writing chargeback csv: %v", err)
+	}
+}
+
+// getChargeback returns the used resources and prices by project for the month of date
+func getChargeback(date time.Time, projectContains string, cluster Cluster) map[string]Resources {
 	// Programm
 	var resourceMap = make(map[string]Resources)
 
-	client := &http.Client{}
+	client := common.HTTPClient("newrelic")
 
 	quota := new(Quota)
 	usage := new(Usage)
 	assignment := new(Assignment)
-	start := now.New(data.Date).BeginningOfMonth()
-	end := now.New(data.Date).EndOfMonth()
+	start := now.New(date).BeginningOfMonth()
+	end := now.New(date).EndOfMonth()
 
-	queries := computeQueries(start, end, data.ProjectContains, data.Cluster)
+	queries := computeQueries(start, end, projectContains, cluster)
 
 	/* fmt.Println(queries.assignmentQuery)
 	fmt.Println(queries.quotaQuery)
@@ -183,16 +226,7 @@ func chargebackHandler(c *gin.Context) {
 	normalizedResourceUsage(resourceMap, float64(len(queries.usageQueries)))
 	computeResourcePrices(resourceMap, unitprices, managementFee)
 
-	report := createCSVReport(resourceMap, data.Date)
-
-	v := make([]Resources, 0, len(resourceMap))
-	for _, value := range resourceMap {
-		v = append(v, value)
-	}
-	c.JSON(http.StatusOK, ApiResponse{
-		CSV:  report,
-		Rows: v,
-	})
+	return resourceMap
 }
 
 func computeQueries(start time.Time, end time.Time, searchString string, cluster Cluster) Queries {
@@ -239,6 +273,17 @@ func computeQueries(start time.Time, end time.Time, searchString string, cluster
 	return Queries{quotaQuery: quotaQuery.String(), assignmentQuery: assignmentQuery.String(), usageQueries: usageQueries}
 }
 
+// checkNewrelicConfig must be called before getJson outside of the chargebackHandler,
+// because getJson stops the server if newrelic isn't configured
+func checkNewrelicConfig() error {
+	cfg := config.Config()
+	if cfg.GetString("newrelic_api_token") == "" || cfg.GetString("newrelic_api_account") == "" {
+		log.Println("WARNING: Env variables 'NEWRELIC_API_TOKEN' and 'NEWRELIC_API_ACCOUNT' must be specified")
+		return errors.New(common.ConfigNotSetError)
+	}
+	return nil
+}
+
 func getJson(client *http.Client, query string, target interface{}) error {
 	cfg := config.Config()
 	newrelic_api_token := cfg.GetString("newrelic_api_token")
@@ -253,7 +298,7 @@ func getJson(client *http.Client, query string, target interface{}) error {
 	var url = fmt.Sprintf("https://insights-api.newrelic.com/v1/accounts/%v/query?nrql=%v", newrelic_api_account, url.QueryEscape(query))
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
 
 	req.Header.Add("Accept", "application/json")
@@ -261,9 +306,12 @@ func getJson(client *http.Client, query string, target interface{}) error {
 
 	resp, err := client.Do(req)
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("newrelic returned %v", resp.StatusCode)
+	}
 
 	return json.NewDecoder(resp.Body).Decode(target)
 }
@@ -385,11 +433,31 @@ func computeResourcePrices(resourceMap map[string]Resources, unitprices Pricing,
 }
 
 func getConsolidatedPrice(value Resources) string {
-	s := value.Prices.QuotaCpu + value.Prices.RequestedCpu + value.Prices.QuotaMemory + value.Prices.RequestedMemory + value.Prices.UsedCpu + value.Prices.UsedMemory + value.Prices.Storage
-	return strconv.FormatFloat(s, 'g', 6, 64)
+	return strconv.FormatFloat(getTotalPrice(value), 'g', 6, 64)
+}
+
+func getTotalPrice(value Resources) float64 {
+	return value.Prices.QuotaCpu + value.Prices.RequestedCpu + value.Prices.QuotaMemory + value.Prices.RequestedMemory + value.Prices.UsedCpu + value.Prices.UsedMemory + value.Prices.Storage
+}
+
+// getAccountAssignment returns the billing number the project was charged to
+func getAccountAssignment(value Resources) string {
+	for _, a := range []string{value.ReceptionAssignment, value.OrderReception, value.PspElement} {
+		if a != "" {
+			return a
+		}
+	}
+	return ""
 }
 
 func createCSVReport(resourceMap map[string]Resources, date time.Time) string {
+	b := &bytes.Buffer{}
+	writeCSVReport(b, resourceMap, date)
+	return b.String()
+}
+
+// writeCSVReport writes the chargeback rows one by one to w
+func writeCSVReport(w io.Writer, resourceMap map[string]Resources, date time.Time) error {
 	LMDateFormat := "0601"
 	cfg := config.Config()
 	sender := cfg.GetString("openshift_chargeback_sender")
@@ -405,8 +473,7 @@ func createCSVReport(resourceMap map[string]Resources, date time.Time) string {
 		currency = "CHF"
 	}
 
-	b := &bytes.Buffer{}
-	wr := csv.NewWriter(b)
+	wr := csv.NewWriter(w)
 	//wr.Comma = ';'
 
 	// Title row
@@ -416,7 +483,9 @@ func createCSVReport(resourceMap map[string]Resources, date time.Time) string {
 		"EmpfStelle", "EmpfAuftrag", "Empfaenger-PSP-Element",
 		"EmpfKdAuft", "EmpPos", "EmpfNetzplan", "Evrg",
 		"Menge gesamt", "ME", "PersNr", "Text", "Sys ID"}
-	wr.Write(title)
+	if err := wr.Write(title); err != nil {
+		return err
+	}
 
 	for key, value := range resourceMap {
 		price := getConsolidatedPrice(value)
@@ -425,10 +494,11 @@ func createCSVReport(resourceMap map[string]Resources, date time.Time) string {
 			value.ReceptionAssignment, value.OrderReception, value.PspElement,
 			"", "", "", "", "1", "ST", "", "LM" + date.Format(LMDateFormat) + " NCS " + key}
 
-		wr.Write(row)
+		if err := wr.Write(row); err != nil {
+			return err
+		}
 	}
 
 	wr.Flush()
-
-	return b.String()
+	return wr.Error()
 }
@@ -0,0 +1,166 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	classificationLabel        = "openshift.io/classification"
+	classificationPublic       = "public"
+	classificationInternal     = "internal"
+	classificationConfidential = "confidential"
+)
+
+// classificationPolicy is a rule a project with the classification must follow
+type classificationPolicy func(clusterId, project string) error
+
+// classificationPolicies are checked before a classification is set and for
+// the violations of a project
+var classificationPolicies = map[string][]classificationPolicy{
+	classificationConfidential: {requireNetworkPolicies, denyPublicRoutes},
+}
+
+func getClassificationHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	classification, err := getClassification(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, common.ClassificationResponse{
+		Classification: classification,
+		Violations:     checkClassificationPolicies(clusterId, project, classification),
+	})
+}
+
+func updateClassificationHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.ClassificationCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !contains([]string{classificationPublic, classificationInternal, classificationConfidential}, data.Classification) {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Die Klassifizierung muss public, internal oder confidential sein"})
+		return
+	}
+
+	if violations := checkClassificationPolicies(data.ClusterId, data.Project, data.Classification); len(violations) > 0 {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{
+			Message: fmt.Sprintf("Das Projekt erfüllt die Vorgaben für %v nicht: %v", data.Classification, strings.Join(violations, ", ")),
+		})
+		return
+	}
+
+	err := updateNamespace(data.ClusterId, data.Project, func(namespace *gabs.Container) {
+		if namespace.Path("metadata.labels").Data() == nil {
+			namespace.SetP(map[string]interface{}{}, "metadata.labels")
+		}
+		namespace.Path("metadata.labels").Set(data.Classification, classificationLabel)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v classified project %v on cluster %v as %v", username, data.Project, data.ClusterId, data.Classification)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Das Projekt %v ist jetzt als %v klassifiziert", data.Project, data.Classification),
+	})
+}
+
+func getClassification(clusterId, project string) (string, error) {
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return "", err
+	}
+	classification, _ := namespace.Path("metadata.labels").S(classificationLabel).Data().(string)
+	return classification, nil
+}
+
+// checkClassificationPolicies returns the violated policies of the classification
+func checkClassificationPolicies(clusterId, project, classification string) []string {
+	violations := []string{}
+	for _, policy := range classificationPolicies[classification] {
+		if err := policy(clusterId, project); err != nil {
+			violations = append(violations, err.Error())
+		}
+	}
+	return violations
+}
+
+func requireNetworkPolicies(clusterId, project string) error {
+	policies, err := listObjects(clusterId, fmt.Sprintf("apis/networking.k8s.io/v1/namespaces/%v/networkpolicies", project))
+	if err != nil {
+		return err
+	}
+	if len(policies) == 0 {
+		return errors.New("Das Projekt hat keine NetworkPolicies")
+	}
+	return nil
+}
+
+func denyPublicRoutes(clusterId, project string) error {
+	routes, err := listObjects(clusterId, fmt.Sprintf("oapi/v1/namespaces/%v/routes", project))
+	if err != nil {
+		return err
+	}
+	for _, r := range routes {
+		host, _ := r.Path("spec.host").Data().(string)
+		if isPublicHost(host) {
+			return fmt.Errorf("Die Route %v ist öffentlich erreichbar", host)
+		}
+	}
+	return nil
+}
+
+// isPublicHost returns true if the host ends with one of 'classification.public_route_suffixes'
+func isPublicHost(host string) bool {
+	for _, suffix := range config.Config().GetStringSlice("classification.public_route_suffixes") {
+		if suffix != "" && strings.HasSuffix(strings.ToLower(host), strings.ToLower(suffix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRouteClassification is the hook for routes created by the portal.
+// Confidential projects can't get public routes
+func checkRouteClassification(clusterId, project string, route *gabs.Container) error {
+	host, _ := route.Path("spec.host").Data().(string)
+	if !isPublicHost(host) {
+		return nil
+	}
+	classification, err := getClassification(clusterId, project)
+	if err != nil {
+		return err
+	}
+	if classification == classificationConfidential {
+		log.Printf("WARNING: blocked public route %v in confidential project %v on cluster %v", host, project, clusterId)
+		return fmt.Errorf("Vertrauliche Projekte dürfen keine öffentliche Route (%v) haben", host)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package openshift
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestClassificationPolicies(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	config.Config().Set("classification.public_route_suffixes", []string{".example.com"})
+	api.AddProject("shop", "u123")
+	route, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "web"}, "spec": {"host": "shop.example.com"}}`))
+	api.Set("oapi/v1/namespaces/shop/routes/web", route)
+
+	if violations := checkClassificationPolicies("fake", "shop", classificationInternal); len(violations) != 0 {
+		t.Errorf("expected no policies for internal projects, got %v", violations)
+	}
+	if violations := checkClassificationPolicies("fake", "shop", classificationConfidential); len(violations) != 2 {
+		t.Errorf("expected the network policies and the public route to be violated, got %v", violations)
+	}
+
+	w := jsonRequest(updateClassificationHandler, "POST", "/ose/project/classification", "u123", `{"clusterid": "fake", "project": "shop", "classification": "confidential"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected the confidential classification to be rejected, got %v", w.Code)
+	}
+
+	policy, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "deny-all"}}`))
+	api.Set("apis/networking.k8s.io/v1/namespaces/shop/networkpolicies/deny-all", policy)
+	route.SetP("shop.intern.local", "spec.host")
+	api.Set("oapi/v1/namespaces/shop/routes/web", route)
+
+	if w := jsonRequest(updateClassificationHandler, "POST", "/ose/project/classification", "u456", `{"clusterid": "fake", "project": "shop", "classification": "confidential"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected users who aren't admin to be rejected, got %v", w.Code)
+	}
+	w = jsonRequest(updateClassificationHandler, "POST", "/ose/project/classification", "u123", `{"clusterid": "fake", "project": "shop", "classification": "confidential"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the project to be classified, got %v %v", w.Code, w.Body.String())
+	}
+	if classification, _ := getClassification("fake", "shop"); classification != classificationConfidential {
+		t.Errorf("expected the label to be set, got %v", classification)
+	}
+
+	public, _ := gabs.ParseJSON([]byte(`{"spec": {"host": "new.example.com"}}`))
+	if err := checkRouteClassification("fake", "shop", public); err == nil {
+		t.Error("expected public routes of confidential projects to be rejected")
+	}
+	if err := checkRouteClassification("fake", "shop", route); err != nil {
+		t.Errorf("expected internal routes to be allowed, got %v", err)
+	}
+}
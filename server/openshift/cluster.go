@@ -2,11 +2,12 @@ package openshift
 
 import (
 	"errors"
-	"log"
+	"fmt"
 	"net/http"
 
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 )
 
 type OpenshiftCluster struct {
@@ -18,6 +19,26 @@ type OpenshiftCluster struct {
 	URL        string      `json:"url"`
 	GlusterApi *GlusterApi `json:"-"`
 	NfsApi     *NfsApi     `json:"-"`
+	// Storage is the technology of new volumes if the request has none
+	Storage string `json:"-"`
+	// IPs or CIDRs which can be reserved as static egress IPs by projects
+	EgressIPs []string `json:"-"`
+	// Chargeback is the cluster in the chargeback data (aws or vias)
+	Chargeback Cluster `json:"-"`
+	// DryRun is true if the api supports server-side dry-run (?dryRun=All)
+	DryRun bool `json:"-"`
+	// ServiceAccount (namespace/name) of the token, used to renew it
+	ServiceAccount string `json:"-"`
+	// CABundle is the file with the CAs of the api in addition to the system CAs
+	CABundle string `json:"-"`
+	// ClientCert and ClientKey are the files of the client certificate for mTLS
+	ClientCert string `json:"-"`
+	ClientKey  string `json:"-"`
+	// Proxy for the api of this cluster, see common.ProxyFor for the default
+	Proxy string `json:"-"`
+	// AuthMode impersonate or passthrough makes the calls of users with their
+	// identity, so the rbac of the cluster decides. Default is the token
+	AuthMode string `json:"-"`
 }
 
 type GlusterApi struct {
@@ -65,9 +86,11 @@ func contains(list []string, search string) bool {
 	return false
 }
 
+// getOpenshiftCluster returns the cluster of the registry 'openshift' with
+// the id the commands select with 'clusterid'
 func getOpenshiftCluster(clusterId string) (OpenshiftCluster, error) {
 	if clusterId == "" {
-		return OpenshiftCluster{}, errors.New(genericAPIError)
+		return OpenshiftCluster{}, errors.New("Es muss ein Cluster angegeben werden")
 	}
 	clusters := getOpenshiftClusters("")
 	for _, cluster := range clusters {
@@ -76,31 +99,5 @@ func getOpenshiftCluster(clusterId string) (OpenshiftCluster, error) {
 		}
 	}
 	log.Printf("WARNING: Cluster %v not found", clusterId)
-	return OpenshiftCluster{}, errors.New(genericAPIError)
-}
-
-func getStorageClass(clusterId, technology string) (string, error) {
-
-	cluster, err := getOpenshiftCluster(clusterId)
-	if err != nil {
-		return "", err
-	}
-	var storageclass string
-
-	if technology == "nfs" {
-		if cluster.NfsApi == nil {
-			log.Printf("WARNING: NfsApi is not configured for cluster %v", clusterId)
-			return "", nil
-		}
-		storageclass = cluster.NfsApi.StorageClass
-
-	} else {
-		if cluster.GlusterApi == nil {
-			log.Printf("WARNING: GlusterApi is not configured for cluster %v", clusterId)
-			return "", nil
-		}
-
-		storageclass = cluster.GlusterApi.StorageClass
-	}
-	return storageclass, nil
+	return OpenshiftCluster{}, fmt.Errorf("Der Cluster %v existiert nicht", clusterId)
 }
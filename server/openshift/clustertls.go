@@ -0,0 +1,175 @@
+package openshift
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// CertificateInfo describes one certificate of the chain of a cluster
+type CertificateInfo struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	DNSNames  []string  `json:"dnsNames,omitempty"`
+}
+
+// ClusterTLSReport is the result of the tls check of a cluster api
+type ClusterTLSReport struct {
+	ClusterId string            `json:"clusterid"`
+	URL       string            `json:"url"`
+	Verified  bool              `json:"verified"`
+	MutualTLS bool              `json:"mutualTls"`
+	Error     string            `json:"error,omitempty"`
+	Chain     []CertificateInfo `json:"chain"`
+}
+
+var (
+	clusterClients     = make(map[string]*http.Client)
+	clusterClientsLock sync.Mutex
+)
+
+// clusterTLSConfig trusts the system CAs plus 'cabundle' of the cluster and
+// presents 'clientcert'/'clientkey' if configured
+func clusterTLSConfig(cluster OpenshiftCluster) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if cluster.CABundle != "" {
+		roots, err := systemCertPoolWith(cluster.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("cabundle of cluster %v: %v", cluster.ID, err)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if cluster.ClientCert != "" || cluster.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(cluster.ClientCert, cluster.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate of cluster %v: %v", cluster.ID, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// systemCertPoolWith returns the system CAs plus the CAs of the pem file
+func systemCertPoolWith(caBundle string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(caBundle)
+	if err != nil {
+		return nil, fmt.Errorf("can't read %v: %v", caBundle, err)
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil || roots == nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%v contains no certificates", caBundle)
+	}
+	return roots, nil
+}
+
+// clusterHTTPClient returns the client for the api of the cluster. The clients
+// are reused, so connections are kept alive. 'proxy' of the cluster overrides
+// the proxy of the openshift integration
+func clusterHTTPClient(cluster OpenshiftCluster) (*http.Client, error) {
+	key := cluster.ID + "|" + cluster.CABundle + "|" + cluster.ClientCert + "|" + cluster.ClientKey + "|" + cluster.Proxy
+
+	clusterClientsLock.Lock()
+	defer clusterClientsLock.Unlock()
+	if client, ok := clusterClients[key]; ok {
+		return client, nil
+	}
+
+	tlsConfig, err := clusterTLSConfig(cluster)
+	if err != nil {
+		log.Printf("WARNING: %v", err)
+		return nil, errors.New(common.ConfigNotSetError)
+	}
+	proxy := common.ProxyFor("openshift")
+	if cluster.Proxy != "" {
+		proxyURL, err := url.Parse(cluster.Proxy)
+		if err != nil {
+			log.Printf("WARNING: invalid proxy of cluster %v: %v", cluster.ID, err)
+			return nil, errors.New(common.ConfigNotSetError)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: proxy}}
+	clusterClients[key] = client
+	return client, nil
+}
+
+func getClusterTLSHandler(c *gin.Context) {
+	clusters := getOpenshiftClusters("")
+	reports := make([]ClusterTLSReport, len(clusters))
+	common.ForEachParallel(len(clusters), func(i int) error {
+		reports[i] = checkClusterTLS(clusters[i])
+		return nil
+	})
+	c.JSON(http.StatusOK, reports)
+}
+
+// checkClusterTLS connects to the api of the cluster with its tls config and
+// reports the certificate chain. If the verification fails, the chain is
+// read without verification to show what the cluster presents
+func checkClusterTLS(cluster OpenshiftCluster) ClusterTLSReport {
+	report := ClusterTLSReport{
+		ClusterId: cluster.ID,
+		URL:       cluster.URL,
+		MutualTLS: cluster.ClientCert != "",
+		Chain:     []CertificateInfo{},
+	}
+
+	u, err := url.Parse(cluster.URL)
+	if err != nil || u.Scheme != "https" {
+		report.Error = "Die URL des Clusters verwendet kein https"
+		return report
+	}
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	tlsConfig, err := clusterTLSConfig(cluster)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	tlsConfig.ServerName = u.Hostname()
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	if err != nil {
+		report.Error = err.Error()
+		tlsConfig.InsecureSkipVerify = true
+		if conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig); err != nil {
+			return report
+		}
+	} else {
+		report.Verified = true
+	}
+	defer conn.Close()
+
+	for _, cert := range conn.ConnectionState().PeerCertificates {
+		report.Chain = append(report.Chain, CertificateInfo{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			DNSNames:  cert.DNSNames,
+		})
+	}
+	return report
+}
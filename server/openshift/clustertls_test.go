@@ -0,0 +1,82 @@
+package openshift
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestClusterTLS(t *testing.T) {
+	api := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items":[]}`))
+	}))
+	defer api.Close()
+
+	dir, _ := ioutil.TempDir("", "ssp-tls")
+	defer os.RemoveAll(dir)
+	bundle := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: api.Certificate().Raw}), 0600)
+
+	config.Init("test")
+	config.Config().Set("openshift", []map[string]interface{}{
+		{"id": "untrusted", "url": api.URL, "token": "t"},
+		{"id": "trusted", "url": api.URL, "token": "t", "cabundle": bundle},
+	})
+
+	if _, err := getOseHTTPClient("GET", "untrusted", "api/v1/namespaces", nil); err == nil {
+		t.Error("expected the certificate of the untrusted cluster to be rejected")
+	}
+	resp, err := getOseHTTPClient("GET", "trusted", "api/v1/namespaces", nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the cabundle to be trusted, got %v", err)
+	}
+	resp.Body.Close()
+
+	untrusted, _ := getOpenshiftCluster("untrusted")
+	report := checkClusterTLS(untrusted)
+	if report.Verified || report.Error == "" || len(report.Chain) != 1 {
+		t.Errorf("expected the unverified chain with an error, got %+v", report)
+	}
+	trusted, _ := getOpenshiftCluster("trusted")
+	if report := checkClusterTLS(trusted); !report.Verified || len(report.Chain) != 1 {
+		t.Errorf("expected the verified chain, got %+v", report)
+	}
+}
+
+func TestExternalAPITLS(t *testing.T) {
+	api := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+
+	dir, _ := ioutil.TempDir("", "ssp-tls")
+	defer os.RemoveAll(dir)
+	bundle := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: api.Certificate().Raw}), 0600)
+
+	config.Init("test")
+	cfg := config.Config()
+	cfg.Set("untrusted.api_url", api.URL)
+	cfg.Set("untrusted.api_secret", "s")
+	cfg.Set("trusted.api_url", api.URL)
+	cfg.Set("trusted.api_secret", "s")
+	cfg.Set("trusted.api_cabundle", bundle)
+
+	if _, err := getExternalAPIClient("untrusted", "GET", "", nil); err == nil {
+		t.Error("expected the certificate of the untrusted api to be rejected")
+	}
+	resp, err := getExternalAPIClient("trusted", "GET", "", nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the api_cabundle to be trusted, got %v", err)
+	}
+	resp.Body.Close()
+	if _, err := getExternalAPIClient("trusted", "GET", "%zz", nil); err == nil {
+		t.Error("expected an error for an invalid url")
+	}
+}
@@ -0,0 +1,172 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// authModeImpersonate calls the cluster with the portal's token and the
+	// impersonation headers of the user, the token needs the impersonate verb
+	authModeImpersonate = "impersonate"
+	// authModePassthrough calls the cluster with the user's own openshift
+	// token, forwarded by the oauth proxy in front of the portal
+	authModePassthrough = "passthrough"
+
+	forwardedTokenHeader = "X-Forwarded-Access-Token"
+)
+
+// clusterUser is the user on whose behalf a call to the cluster is made. On
+// clusters with 'authmode' impersonate or passthrough the cluster's rbac
+// decides if the user may do it, otherwise the portal's token is used.
+//
+// The calls which create or change objects of the project in the name of its
+// admins are made as user: project requests, secrets, service accounts and
+// the members in the rolebindings. Quotas and the metadata of the namespace
+// (billing, megaid, requester) are always changed with the portal's token:
+// the admins of a project may not change them on the cluster, the portal
+// checks the billing limits and the approvals instead
+type clusterUser struct {
+	Name  string
+	Token string
+
+	// groups are the openshift groups for the impersonation, read once per request
+	groups map[string][]string
+	// verified are the clusters which confirmed that Token belongs to Name
+	verified map[string]bool
+	// requestID is the id of the request of the api, see doClusterRequest
+	requestID string
+}
+
+func clusterUserFromContext(c *gin.Context) *clusterUser {
+	return &clusterUser{
+		Name:      common.GetUserName(c),
+		Token:     c.GetHeader(forwardedTokenHeader),
+		requestID: common.GetRequestID(c),
+	}
+}
+
+// getOseHTTPClientAs calls the cluster on behalf of the user. Without user
+// (background jobs) or on clusters without 'authmode' it is the same as
+// getOseHTTPClient. If the cluster denies the call, the user gets an error
+func getOseHTTPClientAs(user *clusterUser, method string, clusterId string, endURL string, body io.Reader) (*http.Response, error) {
+	cluster, err := getOpenshiftCluster(clusterId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return getOseHTTPClient(method, clusterId, endURL, body)
+	}
+	if cluster.AuthMode != authModeImpersonate && cluster.AuthMode != authModePassthrough {
+		return getOseHTTPClientOfRequest(user.requestID, method, clusterId, endURL, body)
+	}
+
+	var groups []string
+	if cluster.AuthMode == authModePassthrough {
+		if err := user.verifyToken(cluster); err != nil {
+			return nil, err
+		}
+	} else if groups, err = user.groupsOn(clusterId); err != nil {
+		return nil, err
+	}
+
+	resp, err := doClusterRequest(cluster, user.requestID, method, endURL, body, func(req *http.Request) error {
+		if cluster.AuthMode == authModePassthrough {
+			req.Header.Set("Authorization", "Bearer "+user.Token)
+			return nil
+		}
+		token := clusterToken(cluster)
+		if token == "" {
+			log.Printf("WARNING: Cluster token not found. Please see README for more details. ClusterId: %v", clusterId)
+			return errors.New(common.ConfigNotSetError)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Impersonate-User", user.Name)
+		for _, g := range groups {
+			req.Header.Add("Impersonate-Group", g)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusForbidden {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		log.Printf("Cluster %v denied %v %v for %v: %v", clusterId, method, endURL, user.Name, string(errMsg))
+		return nil, errors.New("Du hast auf dem Cluster keine Berechtigung für diese Aktion")
+	}
+	return resp, nil
+}
+
+// groupsOn returns the groups of the user on the cluster, which are needed
+// for the impersonation. Without them the rbac of the cluster would ignore
+// the group memberships of the user
+func (user *clusterUser) groupsOn(clusterId string) ([]string, error) {
+	if groups, ok := user.groups[clusterId]; ok {
+		return groups, nil
+	}
+	groups, err := getUserGroups(clusterId, user.Name)
+	if err != nil {
+		return nil, err
+	}
+	if user.groups == nil {
+		user.groups = make(map[string][]string)
+	}
+	user.groups[clusterId] = groups
+	return groups, nil
+}
+
+// verifyToken asks the cluster to whom the forwarded token belongs. The
+// header can be set by the client, so the token must be of the user of the
+// portal's session. Otherwise the cluster would decide with the rights of
+// someone else than the user in the audit log
+func (user *clusterUser) verifyToken(cluster OpenshiftCluster) error {
+	if user.Token == "" {
+		return fmt.Errorf("Für den Cluster %v wird dein OpenShift Token benötigt. Bitte melde dich neu an", cluster.ID)
+	}
+	if user.verified[cluster.ID] {
+		return nil
+	}
+
+	resp, err := doClusterRequest(cluster, user.requestID, "GET", "oapi/v1/users/~", nil, func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+user.Token)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Cluster %v rejected the forwarded token of %v: %v %v", cluster.ID, user.Name, resp.StatusCode, string(errMsg))
+		return fmt.Errorf("Dein OpenShift Token für den Cluster %v ist ungültig. Bitte melde dich neu an", cluster.ID)
+	}
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error parsing body of response:", err)
+		return errors.New(genericAPIError)
+	}
+	name, _ := json.Path("metadata.name").Data().(string)
+	if !strings.EqualFold(name, user.Name) {
+		log.Printf("WARNING: %v forwarded the token of %v to cluster %v", user.Name, name, cluster.ID)
+		return errors.New("Das OpenShift Token gehört nicht zu deinem Benutzer")
+	}
+
+	if user.verified == nil {
+		user.verified = make(map[string]bool)
+	}
+	user.verified[cluster.ID] = true
+	return nil
+}
@@ -0,0 +1,94 @@
+package openshift
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestGetOseHTTPClientAs(t *testing.T) {
+	var authorization, impersonate, requestID string
+	var groups []string
+	// The owners of the forwarded tokens
+	tokens := map[string]string{"user": "U123", "denied": "u999", "stolen": "u999"}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/oapi/v1/users/~":
+			name, ok := tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+			if !ok {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"metadata":{"name":%q}}`, name)
+			return
+		case r.URL.Path == "/oapi/v1/groups":
+			fmt.Fprint(w, `{"items":[{"metadata":{"name":"team"},"users":["u123"]}]}`)
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+		impersonate = r.Header.Get("Impersonate-User")
+		groups = r.Header["Impersonate-Group"]
+		requestID = r.Header.Get(common.RequestIDHeader)
+		if impersonate == "u999" || authorization == "Bearer denied" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+
+	config.Init("test")
+	config.Config().Set("openshift", []map[string]interface{}{
+		{"id": "token", "url": api.URL, "token": "portal"},
+		{"id": "impersonate", "url": api.URL, "token": "portal", "authmode": authModeImpersonate},
+		{"id": "passthrough", "url": api.URL, "token": "portal", "authmode": authModePassthrough},
+	})
+
+	tests := []struct {
+		clusterId                  string
+		user                       *clusterUser
+		authorization, impersonate string
+	}{
+		{"token", &clusterUser{Name: "u123", Token: "user", requestID: "r1"}, "Bearer portal", ""},
+		{"impersonate", &clusterUser{Name: "u123", requestID: "r1"}, "Bearer portal", "u123"},
+		{"impersonate", nil, "Bearer portal", ""},
+		{"passthrough", &clusterUser{Name: "u123", Token: "user", requestID: "r1"}, "Bearer user", ""},
+	}
+	for _, test := range tests {
+		resp, err := getOseHTTPClientAs(test.user, "GET", test.clusterId, "api/v1/namespaces/test", nil)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.clusterId, err)
+			continue
+		}
+		resp.Body.Close()
+		if authorization != test.authorization || impersonate != test.impersonate {
+			t.Errorf("%v: expected %q/%q, got %q/%q", test.clusterId, test.authorization, test.impersonate, authorization, impersonate)
+		}
+		if test.user != nil && requestID != test.user.requestID || test.user == nil && requestID != "" {
+			t.Errorf("%v: expected the request id of the user, got %q", test.clusterId, requestID)
+		}
+		if test.impersonate != "" && (len(groups) != 1 || groups[0] != "team") {
+			t.Errorf("%v: expected the groups of the user to be impersonated, got %v", test.clusterId, groups)
+		}
+	}
+
+	if _, err := getOseHTTPClientAs(&clusterUser{Name: "u123"}, "GET", "passthrough", "api/v1/namespaces/test", nil); err == nil {
+		t.Error("expected an error without forwarded token")
+	}
+	if _, err := getOseHTTPClientAs(&clusterUser{Name: "u999"}, "GET", "impersonate", "api/v1/namespaces/test", nil); err == nil {
+		t.Error("expected an error if the cluster denies the call")
+	}
+	if _, err := getOseHTTPClientAs(&clusterUser{Name: "u999", Token: "denied"}, "GET", "passthrough", "api/v1/namespaces/test", nil); err == nil {
+		t.Error("expected an error if the cluster denies the call")
+	}
+	for _, token := range []string{"stolen", "invalid"} {
+		if _, err := getOseHTTPClientAs(&clusterUser{Name: "u123", Token: token}, "GET", "passthrough", "api/v1/namespaces/test", nil); err == nil {
+			t.Errorf("expected the %v token not to be accepted for u123", token)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/now"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	costAnomaliesCollection        = "cost_anomalies"
+	defaultCostAnomalyThreshold    = 50.0
+	defaultCostAnomalyMinimumPrice = 100.0
+	monthFormat                    = "2006-01"
+)
+
+var errCostAnomalyNotFound = errors.New("Die Kostenabweichung existiert nicht")
+
+// CostAnomaly is a month over month cost jump of a billing account
+type CostAnomaly struct {
+	ID              string     `json:"id"`
+	Billing         string     `json:"billing"`
+	Month           string     `json:"month"`
+	PreviousCost    float64    `json:"previousCost"`
+	Cost            float64    `json:"cost"`
+	IncreasePercent float64    `json:"increasePercent"`
+	Projects        []string   `json:"projects"`
+	DetectedAt      time.Time  `json:"detectedAt"`
+	Acknowledged    bool       `json:"acknowledged"`
+	AcknowledgedBy  string     `json:"acknowledgedBy,omitempty"`
+	AcknowledgedAt  *time.Time `json:"acknowledgedAt,omitempty"`
+	Comment         string     `json:"comment,omitempty"`
+}
+
+type detectCostAnomaliesCommand struct {
+	Date time.Time `json:"date"`
+}
+
+type acknowledgeCostAnomalyCommand struct {
+	Comment string `json:"comment"`
+}
+
+func getCostAnomaliesHandler(c *gin.Context) {
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	includeAcknowledged := c.Query("all") == "true"
+
+	anomalies, err := getCostAnomalies()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	filtered := []CostAnomaly{}
+	for _, a := range anomalies {
+		if (includeAcknowledged || !a.Acknowledged) && listParams.Matches(a.Billing, a.Month) {
+			filtered = append(filtered, a)
+		}
+	}
+
+	start, end, next := listParams.Page(len(filtered))
+	c.JSON(http.StatusOK, common.ListResponse{
+		Items:    filtered[start:end],
+		Total:    len(filtered),
+		Continue: next,
+	})
+}
+
+func acknowledgeCostAnomalyHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	id := c.Param("id")
+
+	var data acknowledgeCostAnomalyCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	var anomaly CostAnomaly
+	err := store.Update(costAnomaliesCollection, id, &anomaly, func(exists bool) error {
+		if !exists {
+			return errCostAnomalyNotFound
+		}
+		acknowledgedAt := time.Now()
+		anomaly.Acknowledged = true
+		anomaly.AcknowledgedBy = username
+		anomaly.AcknowledgedAt = &acknowledgedAt
+		anomaly.Comment = data.Comment
+		return nil
+	})
+	if err == errCostAnomalyNotFound {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v acknowledged the cost anomaly %v", username, id)
+	c.JSON(http.StatusOK, anomaly)
+}
+
+func detectCostAnomaliesHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data detectCostAnomaliesCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if data.Date.IsZero() {
+		data.Date = now.New(common.Now()).BeginningOfMonth().AddDate(0, -1, 0)
+	}
+
+	audit.Describe(c, "%v started the cost anomaly detection for %v", username, data.Date.Format(monthFormat))
+	anomalies, err := detectCostAnomalies(data.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, anomalies)
+}
+
+// StartCostAnomalyDetection checks the last completed month once a day
+// if 'cost_anomaly.enabled' is set
+func StartCostAnomalyDetection() {
+	if !config.Config().GetBool("cost_anomaly.enabled") {
+		return
+	}
+
+	go func() {
+		for {
+			lastMonth := now.New(common.Now()).BeginningOfMonth().AddDate(0, -1, 0)
+			if _, err := detectCostAnomalies(lastMonth); err != nil {
+				log.Printf("Error detecting cost anomalies: %v", err)
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
+
+func getCostAnomalies() ([]CostAnomaly, error) {
+	anomalies := []CostAnomaly{}
+	err := store.List(costAnomaliesCollection, func(id string, data []byte) error {
+		var a CostAnomaly
+		if err := json.Unmarshal(data, &a); err != nil {
+			return err
+		}
+		anomalies = append(anomalies, a)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error reading cost anomalies: %v", err)
+		return nil, errors.New(genericAPIError)
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		return anomalies[i].DetectedAt.After(anomalies[j].DetectedAt)
+	})
+	return anomalies, nil
+}
+
+// getCostsByBilling sums up the chargeback of all clusters by billing number
+func getCostsByBilling(date time.Time) (map[string]float64, map[string][]string) {
+	costs := make(map[string]float64)
+	projects := make(map[string][]string)
+	for _, cluster := range []Cluster{awsCluster, viasCluster} {
+		for project, resources := range getChargeback(date, "%", cluster) {
+			billing := getAccountAssignment(resources)
+			if billing == "" {
+				continue
+			}
+			costs[billing] += getTotalPrice(resources)
+			projects[billing] = append(projects[billing], project)
+		}
+	}
+	return costs, projects
+}
+
+// detectCostAnomalies compares the costs of the month of date with the month
+// before and stores and notifies new anomalies
+func detectCostAnomalies(date time.Time) ([]CostAnomaly, error) {
+	cfg := config.Config()
+	if err := checkNewrelicConfig(); err != nil {
+		return nil, err
+	}
+
+	threshold := cfg.GetFloat64("cost_anomaly.threshold_percent")
+	if threshold <= 0 {
+		threshold = defaultCostAnomalyThreshold
+	}
+	minimum := cfg.GetFloat64("cost_anomaly.minimum_cost")
+	if minimum <= 0 {
+		minimum = defaultCostAnomalyMinimumPrice
+	}
+
+	month := now.New(date).BeginningOfMonth()
+	costs, projects := getCostsByBilling(month)
+	previousCosts, _ := getCostsByBilling(month.AddDate(0, -1, 0))
+
+	anomalies := []CostAnomaly{}
+	for billing, cost := range costs {
+		previous := previousCosts[billing]
+		if cost < minimum || previous <= 0 {
+			continue
+		}
+
+		increase := (cost - previous) / previous * 100
+		if increase < threshold {
+			continue
+		}
+
+		id := fmt.Sprintf("%v-%v", billing, month.Format(monthFormat))
+		// An anomaly that was already detected keeps its acknowledgment
+		var anomaly CostAnomaly
+		detected := false
+		err := store.Update(costAnomaliesCollection, id, &anomaly, func(exists bool) error {
+			if exists {
+				return nil
+			}
+			detected = true
+			anomaly = CostAnomaly{
+				ID:              id,
+				Billing:         billing,
+				Month:           month.Format(monthFormat),
+				PreviousCost:    previous,
+				Cost:            cost,
+				IncreasePercent: math.Round(increase),
+				Projects:        projects[billing],
+				DetectedAt:      time.Now(),
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !detected {
+			anomalies = append(anomalies, anomaly)
+			continue
+		}
+		log.Printf("Detected cost anomaly for billing %v in %v: %v -> %v", billing, anomaly.Month, previous, cost)
+
+		if inLoadTestWindow(billing, month) {
+			log.Printf("Cost anomaly %v isn't mailed because of a load test", id)
+		} else if err := sendCostAnomalyMail(anomaly); err != nil {
+			log.Printf("Can't send e-mail about cost anomaly %v: %v", id, err)
+		}
+		anomalies = append(anomalies, anomaly)
+	}
+	return anomalies, nil
+}
+
+// getBillingOwners returns the requesters of all projects with the billing number
+func getBillingOwners(billing string) []string {
+	owners := []string{}
+	namespaces, _, err := getNamespacesOfClusters(getOpenshiftClusters(""))
+	if err != nil {
+		return owners
+	}
+	for _, clusterNamespaces := range namespaces {
+		for _, n := range clusterNamespaces {
+			annotations := n.Path("metadata.annotations")
+			if getAnnotation(annotations, annotationBilling) != billing {
+				continue
+			}
+			if requester := getAnnotation(annotations, annotationRequester); requester != "" {
+				owners = append(owners, strings.ToLower(requester))
+			}
+		}
+	}
+	return common.RemoveDuplicates(owners)
+}
+
+func sendCostAnomalyMail(anomaly CostAnomaly) error {
+	recipients := []string{}
+	if finance := config.Config().GetString("cost_anomaly.finance_mail"); finance != "" {
+		recipients = append(recipients, finance)
+	}
+	for _, owner := range getBillingOwners(anomaly.Billing) {
+		if mail := common.GetMailForUser(owner); mail != "" {
+			recipients = append(recipients, mail)
+		}
+	}
+
+	return common.SendMail(recipients, fmt.Sprintf("Kostenanstieg auf OpenShift für Kontierungsnummer %v", anomaly.Billing), fmt.Sprintf(`
+	Sehr geehrte Damen und Herren,
+	<br><br>
+	Die Kosten der Kontierungsnummer %v sind im Monat %v um %v%% gestiegen:
+	<br><br>
+	Vormonat: %.2f CHF<br>
+	Aktueller Monat: %.2f CHF<br>
+	Projekte: %v
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, anomaly.Billing, anomaly.Month, anomaly.IncreasePercent, anomaly.PreviousCost, anomaly.Cost, strings.Join(anomaly.Projects, ", ")))
+}
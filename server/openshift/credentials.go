@@ -0,0 +1,246 @@
+package openshift
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	clusterTokensCollection  = "cluster_tokens"
+	defaultTokenRenewalDays  = 7
+	defaultTokenLifetimeDays = 30
+)
+
+// ClusterToken is a token of the portal's service account which was
+// requested by the credential manager and replaces the configured token
+type ClusterToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	RenewedAt time.Time `json:"renewedAt"`
+}
+
+var (
+	renewedTokens     map[string]ClusterToken
+	renewedTokensOnce sync.Once
+	renewedTokensLock sync.RWMutex
+	// tokenAlerts is the day of the last alert by cluster, so admins get
+	// one alert per day
+	tokenAlerts = make(map[string]string)
+)
+
+// loadRenewedTokens reads the renewed tokens, so they survive restarts
+func loadRenewedTokens() {
+	renewedTokens = make(map[string]ClusterToken)
+	err := store.List(clusterTokensCollection, func(id string, data []byte) error {
+		var t ClusterToken
+		if err := json.Unmarshal(data, &t); err != nil {
+			return err
+		}
+		renewedTokens[id] = t
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error reading renewed cluster tokens: %v", err)
+	}
+}
+
+// clusterToken returns the renewed token of the cluster if there is a valid
+// one, otherwise the configured token
+func clusterToken(cluster OpenshiftCluster) string {
+	renewedTokensOnce.Do(loadRenewedTokens)
+	renewedTokensLock.RLock()
+	defer renewedTokensLock.RUnlock()
+
+	if t, ok := renewedTokens[cluster.ID]; ok && t.ExpiresAt.After(time.Now()) {
+		return t.Token
+	}
+	return cluster.Token
+}
+
+// tokenExpiry reads the expiry from the claims of a jwt token without
+// verifying it. Returns false for tokens without expiry (e.g. legacy service
+// account tokens)
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+// StartTokenRenewal checks the tokens of the clusters every hour if
+// 'token_renewal.enabled' is set
+func StartTokenRenewal() {
+	if !config.Config().GetBool("token_renewal.enabled") {
+		return
+	}
+
+	go func() {
+		for {
+			renewClusterTokens(time.Now())
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+// renewClusterTokens requests new tokens for the clusters whose token expires
+// within 'token_renewal.days'. Admins are alerted if a token can't be renewed
+func renewClusterTokens(now time.Time) {
+	cfg := config.Config()
+	days := cfg.GetInt("token_renewal.days")
+	if days <= 0 {
+		days = defaultTokenRenewalDays
+	}
+
+	for _, cluster := range getOpenshiftClusters("") {
+		expires, ok := tokenExpiry(clusterToken(cluster))
+		if !ok || expires.Sub(now) > time.Duration(days)*24*time.Hour {
+			continue
+		}
+
+		if cluster.ServiceAccount == "" {
+			alertTokenExpiry(cluster.ID, expires, now, errors.New("'serviceaccount' is not configured, the token can't be renewed automatically"))
+			continue
+		}
+		if err := renewClusterToken(cluster, now); err != nil {
+			alertTokenExpiry(cluster.ID, expires, now, err)
+			continue
+		}
+		log.Printf("Renewed the token of cluster %v", cluster.ID)
+	}
+}
+
+// renewClusterToken requests a token for the service account of the cluster
+// with the TokenRequest api and stores it
+func renewClusterToken(cluster OpenshiftCluster, now time.Time) error {
+	parts := strings.Split(cluster.ServiceAccount, "/")
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid serviceaccount %v, expected namespace/name", cluster.ServiceAccount)
+	}
+	lifetime := config.Config().GetInt("token_renewal.lifetime_days")
+	if lifetime <= 0 {
+		lifetime = defaultTokenLifetimeDays
+	}
+
+	token, expiresAt, err := requestServiceAccountToken(cluster.ID, parts[0], parts[1], lifetime*24*60*60)
+	if err != nil {
+		return err
+	}
+
+	renewed := ClusterToken{Token: token, ExpiresAt: expiresAt, RenewedAt: now}
+	if err := store.Put(clusterTokensCollection, cluster.ID, renewed); err != nil {
+		return err
+	}
+	renewedTokensOnce.Do(loadRenewedTokens)
+	renewedTokensLock.Lock()
+	renewedTokens[cluster.ID] = renewed
+	renewedTokensLock.Unlock()
+	return nil
+}
+
+// alertTokenExpiry mails 'token_renewal.alert_mail' that the token has to be
+// replaced manually
+func alertTokenExpiry(clusterId string, expires, now time.Time, cause error) {
+	log.Printf("WARNING: the token of cluster %v expires at %v and can't be renewed: %v", clusterId, expires.Format(time.RFC3339), cause)
+
+	day := now.Format("2006-01-02")
+	if tokenAlerts[clusterId] == day {
+		return
+	}
+	tokenAlerts[clusterId] = day
+
+	recipients := config.Config().GetStringSlice("token_renewal.alert_mail")
+	if len(recipients) == 0 {
+		return
+	}
+	err := common.SendMail(recipients, fmt.Sprintf("Token für OpenShift Cluster %v läuft ab", clusterId), fmt.Sprintf(`
+	Sehr geehrte Damen und Herren,
+	<br><br>
+	Der Token des Self-Service-Portals für den Cluster %v läuft am %v ab und konnte nicht automatisch erneuert werden:
+	<br><br>
+	%v
+	<br><br>
+	Bitte erneuert den Token manuell in der Konfiguration des Portals.
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, clusterId, expires.In(common.Location()).Format("02.01.2006 15:04"), cause))
+	if err != nil {
+		log.Printf("Can't send e-mail about the token of cluster %v: %v", clusterId, err)
+	}
+}
+
+// checkTokenExpiry is the selftest of the token of the cluster
+func checkTokenExpiry(clusterId string) error {
+	cluster, err := getOpenshiftCluster(clusterId)
+	if err != nil {
+		return err
+	}
+	expires, ok := tokenExpiry(clusterToken(cluster))
+	if !ok {
+		return nil
+	}
+	if expires.Before(time.Now()) {
+		return fmt.Errorf("Der Token für den Cluster %v ist am %v abgelaufen", clusterId, expires.Format("02.01.2006"))
+	}
+	if cluster.ServiceAccount == "" && expires.Before(time.Now().AddDate(0, 0, defaultTokenRenewalDays)) {
+		return fmt.Errorf("Der Token für den Cluster %v läuft am %v ab und muss manuell erneuert werden", clusterId, expires.Format("02.01.2006"))
+	}
+	return nil
+}
+
+// requestServiceAccountToken creates a token of the service account with the
+// TokenRequest api, which expires after the seconds
+func requestServiceAccountToken(clusterId, namespace, serviceAccount string, seconds int) (string, time.Time, error) {
+	request := gabs.New()
+	request.Set("authentication.k8s.io/v1", "apiVersion")
+	request.Set("TokenRequest", "kind")
+	request.Set(seconds, "spec", "expirationSeconds")
+
+	resp, err := getOseHTTPClient("POST", clusterId, fmt.Sprintf("api/v1/namespaces/%v/serviceaccounts/%v/token", namespace, serviceAccount), bytes.NewReader(request.Bytes()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		return "", time.Time{}, fmt.Errorf("token request failed: %v %v", resp.StatusCode, string(errMsg))
+	}
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	token, _ := json.Path("status.token").Data().(string)
+	expiresAt, err := time.Parse(time.RFC3339, fmt.Sprint(json.Path("status.expirationTimestamp").Data()))
+	if token == "" || err != nil {
+		return "", time.Time{}, errors.New("token request returned no token")
+	}
+	return token, expiresAt, nil
+}
@@ -0,0 +1,50 @@
+package openshift
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func jwtToken(exp int64) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"system:serviceaccount:ssp:ssp-backend","exp":%v}`, exp)))
+	return "eyJhbGciOiJSUzI1NiJ9." + payload + ".c2lnbmF0dXJl"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	expires := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	if at, ok := tokenExpiry(jwtToken(expires.Unix())); !ok || !at.Equal(expires) {
+		t.Errorf("expected %v, got %v %v", expires, at, ok)
+	}
+	if _, ok := tokenExpiry(jwtToken(0)); ok {
+		t.Error("expected tokens without exp not to expire")
+	}
+	if _, ok := tokenExpiry("aeiaiesatehantehinartehinatenhiat"); ok {
+		t.Error("expected opaque tokens not to expire")
+	}
+}
+
+func TestRenewClusterTokens(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+
+	expiring := jwtToken(time.Now().Add(24 * time.Hour).Unix())
+	cluster := api.Cluster("fake")
+	cluster["token"] = expiring
+	cluster["serviceaccount"] = "ssp/ssp-backend"
+	config.Config().Set("openshift", []map[string]interface{}{cluster})
+
+	renewClusterTokens(time.Now())
+
+	c, _ := getOpenshiftCluster("fake")
+	token := clusterToken(c)
+	if token == expiring || token == "" {
+		t.Fatalf("expected the token to be renewed, got %v", token)
+	}
+	if err := checkTokenExpiry("fake"); err != nil {
+		t.Errorf("expected the renewed token to pass the selftest, got %v", err)
+	}
+}
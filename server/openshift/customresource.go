@@ -0,0 +1,230 @@
+package openshift
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const customResourceRolePrefix = "ssp-crd-"
+
+// CustomResource is a custom resource definition in 'custom_resources' which
+// project admins may enable in their projects, e.g. a ServiceMonitor
+type CustomResource struct {
+	Name        string   `mapstructure:"name" json:"name"`
+	Description string   `mapstructure:"description" json:"description"`
+	Group       string   `mapstructure:"group" json:"group"`
+	Resources   []string `mapstructure:"resources" json:"resources"`
+	Verbs       []string `mapstructure:"verbs" json:"verbs"`
+}
+
+// ProjectCustomResource is a whitelisted custom resource and whether it's
+// enabled in the project
+type ProjectCustomResource struct {
+	CustomResource
+	Enabled bool `json:"enabled"`
+}
+
+func getCustomResources() ([]CustomResource, error) {
+	resources := []CustomResource{}
+	if err := config.Config().UnmarshalKey("custom_resources", &resources); err != nil {
+		log.Printf("Error reading the custom resources: %v", err)
+		return nil, errors.New(genericAPIError)
+	}
+	return resources, nil
+}
+
+func getCustomResource(name string) (*CustomResource, error) {
+	resources, err := getCustomResources()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range resources {
+		if r.Name == name {
+			if r.Group == "" || len(r.Resources) == 0 {
+				log.Printf("WARNING: the custom resource %v has no group or resources", name)
+				return nil, errors.New(genericAPIError)
+			}
+			if len(r.Verbs) == 0 {
+				r.Verbs = []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+			}
+			return &r, nil
+		}
+	}
+	return nil, fmt.Errorf("Die Custom Resource %v ist nicht freigegeben", name)
+}
+
+func customResourceRole(name string) string {
+	return customResourceRolePrefix + name
+}
+
+// enableCustomResource grants the admins and operators of the project the
+// access to the custom resource. The cluster role only allows the api group
+// and resources of the definition and is bound in the project only
+func enableCustomResource(clusterId, project, name string) error {
+	resource, err := getCustomResource(name)
+	if err != nil {
+		return err
+	}
+	if err := applyCustomResourceRole(clusterId, resource); err != nil {
+		return err
+	}
+	admins, operators, err := getProjectAdminsAndOperators(clusterId, project)
+	if err != nil {
+		return err
+	}
+	return addUsersToRoleBinding(clusterId, project, customResourceRole(name), append(admins, operators...))
+}
+
+// disableCustomResource removes the rolebinding of the custom resource. The
+// cluster role stays as it may be bound in other projects
+func disableCustomResource(clusterId, project, name string) error {
+	if _, err := getCustomResource(name); err != nil {
+		return err
+	}
+	resp, err := getOseHTTPClient("DELETE", clusterId, fmt.Sprintf("oapi/v1/namespaces/%v/rolebindings/%v", project, customResourceRole(name)), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	common.GetCache().Delete(roleBindingCacheKey(clusterId, project))
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error deleting rolebinding:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
+
+// applyCustomResourceRole creates or updates the cluster role of the custom
+// resource, so changes of the whitelist are applied when it's enabled again
+func applyCustomResourceRole(clusterId string, resource *CustomResource) error {
+	role := newObjectRequest("ClusterRole", customResourceRole(resource.Name))
+	role.SetP("Managed by the ssp: "+resource.Description, "metadata.annotations.description")
+	rule := map[string]interface{}{
+		"apiGroups": []string{resource.Group},
+		"resources": resource.Resources,
+		"verbs":     resource.Verbs,
+	}
+	role.Set([]interface{}{rule}, "rules")
+
+	url := "oapi/v1/clusterroles"
+	resp, err := getOseHTTPClient("PUT", clusterId, url+"/"+customResourceRole(resource.Name), bytes.NewReader(role.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		resp, err = getOseHTTPClient("POST", clusterId, url, bytes.NewReader(role.Bytes()))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error updating the cluster role of a custom resource:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
+
+// getProjectCustomResources returns the whitelist with the custom resources
+// enabled in the project
+func getProjectCustomResources(clusterId, project string) ([]ProjectCustomResource, error) {
+	resources, err := getCustomResources()
+	if err != nil {
+		return nil, err
+	}
+	result := []ProjectCustomResource{}
+	for _, r := range resources {
+		users, err := getRoleBindingUsers(clusterId, project, customResourceRole(r.Name))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ProjectCustomResource{CustomResource: r, Enabled: len(users) > 0})
+	}
+	return result, nil
+}
+
+func getProjectCustomResourcesHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	resources, err := getProjectCustomResources(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resources)
+}
+
+func enableCustomResourceHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.CustomResourceCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	data.Name = strings.TrimSpace(data.Name)
+
+	if err := enableCustomResource(data.ClusterId, data.Project, data.Name); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v enabled the custom resource %v in project %v on cluster %v", username, data.Name, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Die Custom Resource %v kann jetzt im Projekt %v verwendet werden", data.Name, data.Project),
+	})
+}
+
+// disableCustomResourceHandler disables ?name in the project
+func disableCustomResourceHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+	name := c.Query("name")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err := disableCustomResource(clusterId, project, name); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v disabled the custom resource %v in project %v on cluster %v", username, name, project, clusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Die Custom Resource %v kann im Projekt %v nicht mehr verwendet werden", name, project),
+	})
+}
+
+// getCustomResourcesHandler lists the whitelist
+func getCustomResourcesHandler(c *gin.Context) {
+	resources, err := getCustomResources()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resources)
+}
@@ -0,0 +1,46 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestEnableCustomResource(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	config.Config().Set("custom_resources", []map[string]interface{}{
+		{"name": "servicemonitor", "group": "monitoring.coreos.com", "resources": []string{"servicemonitors"}},
+	})
+	if err := createNewProject(nil, "fake", "shop", "u123", "12345", "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := enableCustomResource("fake", "shop", "virtualmachine"); err == nil {
+		t.Error("expected a custom resource which isn't whitelisted to be rejected")
+	}
+	if err := enableCustomResource("fake", "shop", "servicemonitor"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	role, ok := api.Get("oapi/v1/clusterroles/ssp-crd-servicemonitor")
+	if !ok {
+		t.Fatal("expected the cluster role to be created")
+	}
+	if group := role.Path("rules.apiGroups").String(); group != `[["monitoring.coreos.com"]]` {
+		t.Errorf("expected the role to be scoped to the api group, got %v", group)
+	}
+	if users, _ := getRoleBindingUsers("fake", "shop", "ssp-crd-servicemonitor"); !contains(users, "u123") {
+		t.Errorf("expected the admins to be bound, got %v", users)
+	}
+
+	resources, err := getProjectCustomResources("fake", "shop")
+	if err != nil || len(resources) != 1 || !resources[0].Enabled {
+		t.Errorf("expected the enabled custom resource, got %+v %v", resources, err)
+	}
+	if err := disableCustomResource("fake", "shop", "servicemonitor"); err != nil {
+		t.Fatal(err)
+	}
+	if resources, _ := getProjectCustomResources("fake", "shop"); resources[0].Enabled {
+		t.Error("expected the custom resource to be disabled")
+	}
+}
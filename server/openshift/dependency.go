@@ -0,0 +1,270 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+)
+
+const dependenciesCollection = "project_dependencies"
+
+// ProjectDependency is an edge of the dependency graph: the project depends
+// on another project or on an external service
+type ProjectDependency struct {
+	ID              string    `json:"id"`
+	ClusterId       string    `json:"clusterid"`
+	Project         string    `json:"project"`
+	TargetClusterId string    `json:"targetClusterid,omitempty"`
+	TargetProject   string    `json:"targetProject,omitempty"`
+	Service         string    `json:"service,omitempty"`
+	Description     string    `json:"description,omitempty"`
+	CreatedBy       string    `json:"createdBy"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// ProjectDependencies are the dependencies of a project and the projects
+// which depend on it
+type ProjectDependencies struct {
+	Dependencies []ProjectDependency `json:"dependencies"`
+	Dependents   []ProjectDependency `json:"dependents"`
+}
+
+// DependencyImpact is a project which is affected by the maintenance of
+// another cluster or of a service. Path is the chain of dependencies
+type DependencyImpact struct {
+	ClusterId string   `json:"clusterid"`
+	Project   string   `json:"project"`
+	Path      []string `json:"path"`
+}
+
+func projectNode(clusterId, project string) string {
+	return clusterId + "/" + project
+}
+
+func (d ProjectDependency) target() string {
+	if d.Service != "" {
+		return "service:" + d.Service
+	}
+	return projectNode(d.TargetClusterId, d.TargetProject)
+}
+
+func dependencyID(d ProjectDependency) string {
+	return projectNode(d.ClusterId, d.Project) + "->" + d.target()
+}
+
+func validateDependency(d ProjectDependency) error {
+	if (d.Service == "") == (d.TargetProject == "") {
+		return errors.New("Es muss entweder ein Projekt oder ein externer Service angegeben werden")
+	}
+	if d.Service != "" {
+		return nil
+	}
+	if d.TargetClusterId == "" {
+		return errors.New("Der Cluster des Projekts muss angegeben werden")
+	}
+	if d.TargetClusterId == d.ClusterId && d.TargetProject == d.Project {
+		return errors.New("Ein Projekt kann nicht von sich selbst abhängen")
+	}
+	namespace, err := getNamespace(d.TargetClusterId, d.TargetProject)
+	if err != nil {
+		return err
+	}
+	if name, _ := namespace.Path("metadata.name").Data().(string); name != d.TargetProject {
+		return fmt.Errorf("Das Projekt %v existiert nicht auf Cluster %v", d.TargetProject, d.TargetClusterId)
+	}
+	return nil
+}
+
+func getDependencies() ([]ProjectDependency, error) {
+	dependencies := []ProjectDependency{}
+	err := store.List(dependenciesCollection, func(id string, data []byte) error {
+		var d ProjectDependency
+		if err := json.Unmarshal(data, &d); err != nil {
+			return err
+		}
+		dependencies = append(dependencies, d)
+		return nil
+	})
+	sort.Slice(dependencies, func(i, j int) bool {
+		return dependencies[i].ID < dependencies[j].ID
+	})
+	return dependencies, err
+}
+
+func getProjectDependencies(clusterId, project string) (*ProjectDependencies, error) {
+	all, err := getDependencies()
+	if err != nil {
+		return nil, err
+	}
+	result := &ProjectDependencies{Dependencies: []ProjectDependency{}, Dependents: []ProjectDependency{}}
+	for _, d := range all {
+		if d.ClusterId == clusterId && d.Project == project {
+			result.Dependencies = append(result.Dependencies, d)
+		}
+		if d.TargetClusterId == clusterId && d.TargetProject == project {
+			result.Dependents = append(result.Dependents, d)
+		}
+	}
+	return result, nil
+}
+
+// getDependencyImpact returns the projects of other clusters which depend
+// directly or indirectly on a project of the cluster or on the service
+func getDependencyImpact(clusterId, service string) ([]DependencyImpact, error) {
+	all, err := getDependencies()
+	if err != nil {
+		return nil, err
+	}
+	dependents := make(map[string][]ProjectDependency)
+	for _, d := range all {
+		dependents[d.target()] = append(dependents[d.target()], d)
+	}
+
+	affected := func(target string) bool {
+		if service != "" {
+			return target == "service:"+service
+		}
+		return strings.HasPrefix(target, clusterId+"/")
+	}
+	// Breadth first from the affected targets, every project is reported
+	// with its shortest path
+	paths := make(map[string][]string)
+	queue := []string{}
+	for target := range dependents {
+		if affected(target) {
+			paths[target] = []string{target}
+			queue = append(queue, target)
+		}
+	}
+	sort.Strings(queue)
+
+	impacts := []DependencyImpact{}
+	for len(queue) > 0 {
+		target := queue[0]
+		queue = queue[1:]
+		for _, d := range dependents[target] {
+			node := projectNode(d.ClusterId, d.Project)
+			if _, seen := paths[node]; seen || (service == "" && d.ClusterId == clusterId) {
+				continue
+			}
+			paths[node] = append([]string{node}, paths[target]...)
+			queue = append(queue, node)
+			impacts = append(impacts, DependencyImpact{ClusterId: d.ClusterId, Project: d.Project, Path: paths[node]})
+		}
+	}
+	return impacts, nil
+}
+
+func getProjectDependenciesHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	dependencies, err := getProjectDependencies(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dependencies)
+}
+
+func addProjectDependencyHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.ProjectDependencyCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	dependency := ProjectDependency{
+		ClusterId:       data.ClusterId,
+		Project:         data.Project,
+		TargetClusterId: data.TargetClusterId,
+		TargetProject:   data.TargetProject,
+		Service:         strings.TrimSpace(data.Service),
+		Description:     data.Description,
+		CreatedBy:       username,
+		CreatedAt:       common.Now(),
+	}
+	if err := validateDependency(dependency); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	dependency.ID = dependencyID(dependency)
+	if err := store.Put(dependenciesCollection, dependency.ID, dependency); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v added the dependency %v", username, dependency.ID)
+	c.JSON(http.StatusOK, dependency)
+}
+
+func removeProjectDependencyHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+	id := c.Query("id")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !strings.HasPrefix(id, projectNode(clusterId, project)+"->") {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Die Abhängigkeit gehört nicht zum Projekt"})
+		return
+	}
+	if err := store.Delete(dependenciesCollection, id); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v removed the dependency %v", username, id)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: "Die Abhängigkeit wurde entfernt"})
+}
+
+// getDependencyGraphHandler returns all dependencies
+func getDependencyGraphHandler(c *gin.Context) {
+	dependencies, err := getDependencies()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dependencies)
+}
+
+// getMaintenanceImpactHandler lists the projects affected by the maintenance
+// of the cluster (?clusterid) or the external service (?service)
+func getMaintenanceImpactHandler(c *gin.Context) {
+	clusterId := c.Query("clusterid")
+	service := c.Query("service")
+	if (clusterId == "") == (service == "") {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	impacts, err := getDependencyImpact(clusterId, service)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, impacts)
+}
@@ -0,0 +1,58 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+)
+
+func TestDependencyImpact(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("db", "u123")
+	api.AddProject("app", "u123")
+
+	invalid := []ProjectDependency{
+		{ClusterId: "fake", Project: "app"},
+		{ClusterId: "fake", Project: "app", TargetClusterId: "fake", TargetProject: "db", Service: "ldap"},
+		{ClusterId: "fake", Project: "app", TargetClusterId: "fake", TargetProject: "app"},
+		{ClusterId: "fake", Project: "app", TargetClusterId: "fake", TargetProject: "missing"},
+	}
+	for _, d := range invalid {
+		if err := validateDependency(d); err == nil {
+			t.Errorf("%+v: expected an error", d)
+		}
+	}
+
+	// web (other) -> app (fake) -> db (fake), frontend (other) -> web, app -> ldap
+	dependencies := []ProjectDependency{
+		{ClusterId: "fake", Project: "app", TargetClusterId: "fake", TargetProject: "db"},
+		{ClusterId: "other", Project: "web", TargetClusterId: "fake", TargetProject: "app"},
+		{ClusterId: "other", Project: "frontend", TargetClusterId: "other", TargetProject: "web"},
+		{ClusterId: "fake", Project: "app", Service: "ldap"},
+	}
+	for _, d := range dependencies {
+		d.ID = dependencyID(d)
+		if err := store.Put(dependenciesCollection, d.ID, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	project, err := getProjectDependencies("fake", "app")
+	if err != nil || len(project.Dependencies) != 2 || len(project.Dependents) != 1 {
+		t.Errorf("expected 2 dependencies and 1 dependent of app, got %+v %v", project, err)
+	}
+
+	impacts, err := getDependencyImpact("fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(impacts) != 2 || impacts[0].Project != "web" || impacts[1].Project != "frontend" || len(impacts[1].Path) != 3 {
+		t.Errorf("expected web and frontend to be affected by the maintenance of fake, got %+v", impacts)
+	}
+
+	impacts, _ = getDependencyImpact("", "ldap")
+	if len(impacts) != 3 || impacts[0].Project != "app" {
+		t.Errorf("expected app, web and frontend to be affected by the maintenance of ldap, got %+v", impacts)
+	}
+}
@@ -0,0 +1,195 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// DeprecatedAPI is an apiVersion of a kind which is removed by an upgrade.
+// The list can be replaced with 'deprecated_apis'
+type DeprecatedAPI struct {
+	APIVersion  string `mapstructure:"api_version" json:"apiVersion"`
+	Kind        string `mapstructure:"kind" json:"kind"`
+	Resource    string `mapstructure:"resource" json:"resource"`
+	Replacement string `mapstructure:"replacement" json:"replacement"`
+}
+
+var defaultDeprecatedAPIs = []DeprecatedAPI{
+	{"extensions/v1beta1", "Deployment", "deployments", "apps/v1"},
+	{"extensions/v1beta1", "DaemonSet", "daemonsets", "apps/v1"},
+	{"extensions/v1beta1", "ReplicaSet", "replicasets", "apps/v1"},
+	{"extensions/v1beta1", "NetworkPolicy", "networkpolicies", "networking.k8s.io/v1"},
+	{"extensions/v1beta1", "Ingress", "ingresses", "networking.k8s.io/v1"},
+	{"apps/v1beta1", "Deployment", "deployments", "apps/v1"},
+	{"apps/v1beta1", "StatefulSet", "statefulsets", "apps/v1"},
+	{"apps/v1beta2", "Deployment", "deployments", "apps/v1"},
+	{"apps/v1beta2", "StatefulSet", "statefulsets", "apps/v1"},
+	{"apps/v1beta2", "DaemonSet", "daemonsets", "apps/v1"},
+	{"batch/v1beta1", "CronJob", "cronjobs", "batch/v1"},
+	{"policy/v1beta1", "PodDisruptionBudget", "poddisruptionbudgets", "policy/v1"},
+}
+
+// DeprecatedAPIUsage is an object which was applied with a deprecated apiVersion
+type DeprecatedAPIUsage struct {
+	ClusterId   string `json:"clusterid"`
+	Project     string `json:"project"`
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	APIVersion  string `json:"apiVersion"`
+	Replacement string `json:"replacement"`
+}
+
+// ProjectDeprecations are the usages of deprecated apis in a project
+type ProjectDeprecations struct {
+	ClusterId string               `json:"clusterid"`
+	Project   string               `json:"project"`
+	Usages    []DeprecatedAPIUsage `json:"usages"`
+}
+
+func getDeprecatedAPIs() []DeprecatedAPI {
+	apis := []DeprecatedAPI{}
+	if err := config.Config().UnmarshalKey("deprecated_apis", &apis); err != nil {
+		log.Printf("Error reading the deprecated apis, using the defaults: %v", err)
+	}
+	if len(apis) == 0 {
+		return defaultDeprecatedAPIs
+	}
+	return apis
+}
+
+// scanDeprecatedAPIs returns the objects of the project, or of all projects
+// if project is empty, which were applied with a deprecated apiVersion. The
+// apiVersion is taken from the last applied configuration or the managed
+// fields, as the cluster returns every object in the version of the request
+func scanDeprecatedAPIs(clusterId, project string) ([]DeprecatedAPIUsage, error) {
+	usages := []DeprecatedAPIUsage{}
+	for _, api := range getDeprecatedAPIs() {
+		path := fmt.Sprintf("apis/%v/%v", api.APIVersion, api.Resource)
+		if project != "" {
+			path = fmt.Sprintf("apis/%v/namespaces/%v/%v", api.APIVersion, project, api.Resource)
+		}
+		objects, served, err := listServedObjects(clusterId, path)
+		if err != nil {
+			return nil, err
+		}
+		if !served {
+			continue
+		}
+		for _, o := range objects {
+			if !appliedWith(o, api.APIVersion) {
+				continue
+			}
+			usage := DeprecatedAPIUsage{ClusterId: clusterId, Kind: api.Kind, APIVersion: api.APIVersion, Replacement: api.Replacement}
+			usage.Project, _ = o.Path("metadata.namespace").Data().(string)
+			usage.Name, _ = o.Path("metadata.name").Data().(string)
+			usages = append(usages, usage)
+		}
+	}
+	sort.SliceStable(usages, func(i, j int) bool {
+		if usages[i].Project != usages[j].Project {
+			return usages[i].Project < usages[j].Project
+		}
+		return usages[i].Name < usages[j].Name
+	})
+	return usages, nil
+}
+
+// listServedObjects lists the objects like listObjects. served is false if
+// the cluster doesn't serve the api (anymore)
+func listServedObjects(clusterId, path string) ([]*gabs.Container, bool, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, path, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error listing %v: %v %v", path, resp.StatusCode, string(errMsg))
+		return nil, false, errors.New(genericAPIError)
+	}
+	list, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return nil, false, errors.New(genericAPIError)
+	}
+	items, _ := list.S("items").Children()
+	return items, true, nil
+}
+
+// appliedWith checks if the object was written with the apiVersion
+func appliedWith(object *gabs.Container, apiVersion string) bool {
+	if lastApplied, ok := object.Path("metadata.annotations").S(lastAppliedAnnotation).Data().(string); ok {
+		var applied struct {
+			APIVersion string `json:"apiVersion"`
+		}
+		if json.Unmarshal([]byte(lastApplied), &applied) == nil && applied.APIVersion == apiVersion {
+			return true
+		}
+	}
+	fields, _ := object.Path("metadata.managedFields").Children()
+	for _, f := range fields {
+		if v, _ := f.Path("apiVersion").Data().(string); v == apiVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// getProjectDeprecationsHandler returns the usages of deprecated apis in the project
+func getProjectDeprecationsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	usages, err := scanDeprecatedAPIs(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ProjectDeprecations{ClusterId: clusterId, Project: project, Usages: usages})
+}
+
+// getDeprecationsHandler returns the projects of ?clusterid which use
+// deprecated apis
+func getDeprecationsHandler(c *gin.Context) {
+	clusterId := c.Query("clusterid")
+	if _, err := getOpenshiftCluster(clusterId); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	usages, err := scanDeprecatedAPIs(clusterId, "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	projects := []ProjectDeprecations{}
+	for _, u := range usages {
+		if len(projects) == 0 || projects[len(projects)-1].Project != u.Project {
+			projects = append(projects, ProjectDeprecations{ClusterId: clusterId, Project: u.Project})
+		}
+		p := &projects[len(projects)-1]
+		p.Usages = append(p.Usages, u)
+	}
+	c.JSON(http.StatusOK, projects)
+}
@@ -0,0 +1,43 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs"
+)
+
+func TestScanDeprecatedAPIs(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	deployment := func(project, name string) *gabs.Container {
+		o := gabs.New()
+		o.SetP(project, "metadata.namespace")
+		o.SetP(name, "metadata.name")
+		return o
+	}
+	old := deployment("shop", "web")
+	old.Set(`{"apiVersion":"extensions/v1beta1","kind":"Deployment"}`, "metadata", "annotations", lastAppliedAnnotation)
+	api.Set("apis/extensions/v1beta1/namespaces/shop/deployments/web", old)
+	current := deployment("shop", "api")
+	current.Set(`{"apiVersion":"apps/v1","kind":"Deployment"}`, "metadata", "annotations", lastAppliedAnnotation)
+	api.Set("apis/extensions/v1beta1/namespaces/shop/deployments/api", current)
+	managed := deployment("other", "cron")
+	managed.SetP([]interface{}{map[string]interface{}{"manager": "oc", "apiVersion": "batch/v1beta1"}}, "metadata.managedFields")
+	api.Set("apis/batch/v1beta1/namespaces/other/cronjobs/cron", managed)
+
+	usages, err := scanDeprecatedAPIs("fake", "shop")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(usages) != 1 || usages[0].Name != "web" || usages[0].Replacement != "apps/v1" {
+		t.Errorf("expected only the deployment applied with extensions/v1beta1, got %+v", usages)
+	}
+
+	usages, err = scanDeprecatedAPIs("fake", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(usages) != 2 || usages[0].Project != "other" || usages[0].Kind != "CronJob" || usages[1].Project != "shop" {
+		t.Errorf("expected the usages of all projects, got %+v", usages)
+	}
+}
@@ -0,0 +1,114 @@
+//go:build mock
+// +build mock
+
+package openshift
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/openshift/fakeapi"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultDevFixtures = "dev/fixtures.json"
+
+// devFixtures is the fake data of the dev mode
+type devFixtures struct {
+	Clusters []struct {
+		ID         string   `json:"id"`
+		Name       string   `json:"name"`
+		Features   []string `json:"features"`
+		Chargeback Cluster  `json:"chargeback"`
+	} `json:"clusters"`
+	Projects []struct {
+		ClusterId string   `json:"clusterid"`
+		Name      string   `json:"name"`
+		Billing   string   `json:"billing"`
+		MegaId    string   `json:"megaId"`
+		Requester string   `json:"requester"`
+		Admins    []string `json:"admins"`
+		QuotaCpu  int      `json:"quotaCpu"`
+		// QuotaMemory in GiB
+		QuotaMemory int `json:"quotaMemory"`
+	} `json:"projects"`
+	Billing []BillingSnapshot `json:"billing"`
+}
+
+// StartDevMode replaces the configured clusters with in-memory apis and seeds
+// them and the billing snapshots from 'dev_mode.fixtures'. Frontend developers
+// can work with realistic data without cluster or NewRelic credentials.
+// Nothing is written to real clusters or the configured 'store_path', the
+// store is a temporary directory. Only available in builds with the tag 'mock'
+func StartDevMode() {
+	cfg := config.Config()
+	dir, err := ioutil.TempDir("", "ssp-dev-store")
+	if err != nil {
+		log.Fatalf("Error creating the dev mode store: %v", err)
+	}
+	cfg.Set("store_path", dir)
+
+	path := cfg.GetString("dev_mode.fixtures")
+	if path == "" {
+		path = defaultDevFixtures
+	}
+	if err := loadDevFixtures(path); err != nil {
+		log.Fatalf("Error loading the dev mode fixtures %v: %v", path, err)
+	}
+	log.Printf("WARNING: dev mode is enabled, all clusters are fake and seeded from %v, the store is %v", path, dir)
+}
+
+func loadDevFixtures(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var fixtures devFixtures
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return err
+	}
+
+	clusters := []map[string]interface{}{}
+	for _, c := range fixtures.Clusters {
+		cluster := fakeapi.New().Cluster(c.ID)
+		cluster["name"] = c.Name
+		cluster["features"] = c.Features
+		cluster["chargeback"] = c.Chargeback
+		clusters = append(clusters, cluster)
+	}
+	config.Config().Set("openshift", clusters)
+
+	// The projects are created with the functions of the portal, so they look
+	// exactly like projects created by users
+	for _, p := range fixtures.Projects {
+		if err := createNewProject(nil, p.ClusterId, p.Name, p.Requester, p.Billing, p.MegaId, false); err != nil {
+			return fmt.Errorf("project %v: %v", p.Name, err)
+		}
+		for _, admin := range p.Admins {
+			if err := changeProjectPermission(nil, p.ClusterId, p.Name, admin); err != nil {
+				return fmt.Errorf("project %v: %v", p.Name, err)
+			}
+		}
+		if p.QuotaCpu > 0 && p.QuotaMemory > 0 {
+			if err := setQuota(p.ClusterId, p.Name, p.QuotaCpu, p.QuotaMemory); err != nil {
+				return fmt.Errorf("project %v: %v", p.Name, err)
+			}
+		}
+	}
+
+	for _, snapshot := range fixtures.Billing {
+		month, err := time.Parse(monthFormat, snapshot.Month)
+		if err != nil {
+			return err
+		}
+		snapshot.CreatedAt = time.Now()
+		if err := store.Put(billingSnapshotsCollection, billingSnapshotID(snapshot.Cluster, month), snapshot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+//go:build mock
+// +build mock
+
+package openshift
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestLoadDevFixtures(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+
+	if err := loadDevFixtures("../dev/fixtures.json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clusters := getOpenshiftClusters("")
+	if len(clusters) != 2 || clusters[0].Chargeback != awsCluster {
+		t.Fatalf("expected the clusters of the fixtures, got %v", clusters)
+	}
+
+	namespace, err := getNamespace("dev-aws", "ticketshop")
+	if err != nil {
+		t.Fatalf("expected the project to be seeded: %v", err)
+	}
+	if getAnnotation(namespace.Path("metadata.annotations"), annotationBilling) != "70023456" {
+		t.Errorf("expected the billing of the fixture, got %v", namespace.Path("metadata.annotations"))
+	}
+	admins, _, _ := getProjectAdminsAndOperators("dev-aws", "ticketshop")
+	if !contains(admins, "u100003") || !contains(admins, "u100001") {
+		t.Errorf("expected requester and admins of the fixture, got %v", admins)
+	}
+
+	month, _ := time.Parse(monthFormat, "2019-01")
+	statement, err := createStatement("70012345", month)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statement.Rows) != 2 || statement.Total <= 0 {
+		t.Errorf("expected the billing of both projects, got %v", statement.Rows)
+	}
+}
+
+func TestStartDevModeUsesTemporaryStore(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+	storePath := config.Config().GetString("store_path")
+	config.Config().Set("dev_mode.fixtures", "../dev/fixtures.json")
+	defer config.Config().Set("dev_mode.fixtures", "")
+
+	StartDevMode()
+	dir := config.Config().GetString("store_path")
+	defer os.RemoveAll(dir)
+	if dir == storePath {
+		t.Fatal("expected the dev mode not to use the configured store")
+	}
+	if files, _ := ioutil.ReadDir(storePath); len(files) != 0 {
+		t.Errorf("expected nothing to be written to the configured store, got %v files", len(files))
+	}
+}
@@ -0,0 +1,159 @@
+package openshift
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+// DirectoryEntry is the public information of a project. It must not
+// contain the billing or anything else only admins may see
+type DirectoryEntry struct {
+	ClusterId   string   `json:"clusterid"`
+	Project     string   `json:"project"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Owners      []string `json:"owners"`
+	Contact     string   `json:"contact,omitempty"`
+	// Tags are the labels of the namespace without the ones of the platform
+	Tags []string `json:"tags,omitempty"`
+}
+
+// getProjectDirectoryHandler lists the projects of all clusters for every
+// user if 'directory.enabled' is set
+func getProjectDirectoryHandler(c *gin.Context) {
+	if !config.Config().GetBool("directory.enabled") {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: common.ConfigNotSetError})
+		return
+	}
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	entries, failed, err := getProjectDirectory(getOpenshiftClusters(""))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	matching := []DirectoryEntry{}
+	for _, e := range entries {
+		if listParams.Matches(append([]string{e.Project, e.DisplayName, e.Description}, e.Owners...)...) {
+			matching = append(matching, e)
+		}
+	}
+	start, end, next := listParams.Page(len(matching))
+	c.JSON(http.StatusOK, common.ListResponse{
+		Items:    matching[start:end],
+		Total:    len(matching),
+		Continue: next,
+		Errors:   failed,
+	})
+}
+
+// getProjectDirectory returns the requested projects with the admins as
+// owners, sorted by cluster and project
+func getProjectDirectory(clusters []OpenshiftCluster) ([]DirectoryEntry, []string, error) {
+	namespaces, failed, err := getNamespacesOfClusters(clusters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	owners := make([]map[string][]string, len(clusters))
+	errs := common.ForEachParallel(len(clusters), func(i int) error {
+		if namespaces[i] == nil {
+			return nil
+		}
+		var err error
+		owners[i], err = getProjectOwners(clusters[i].ID)
+		return err
+	})
+	for i, err := range errs {
+		if err != nil && namespaces[i] != nil {
+			failed = append(failed, clusters[i].ID)
+			namespaces[i] = nil
+		}
+	}
+	if len(failed) > 0 && len(failed) == len(clusters) {
+		return nil, nil, errors.New(genericAPIError)
+	}
+
+	entries := []DirectoryEntry{}
+	for i, cluster := range clusters {
+		for _, n := range namespaces[i] {
+			annotations := n.Path("metadata.annotations")
+			// Namespaces of the platform have no requester
+			requester := getAnnotation(annotations, annotationRequester)
+			if requester == "" {
+				continue
+			}
+			e := DirectoryEntry{ClusterId: cluster.ID, Owners: []string{}}
+			e.Project, _ = n.Path("metadata.name").Data().(string)
+			e.DisplayName, _ = annotations.S("openshift.io/display-name").Data().(string)
+			e.Description, _ = annotations.S("openshift.io/description").Data().(string)
+			if o, ok := owners[i][e.Project]; ok {
+				e.Owners = o
+			}
+			e.Contact = getAnnotation(annotations, annotationContact)
+			if e.Contact == "" {
+				e.Contact = common.GetMailForUser(requester)
+			}
+			e.Tags = namespaceTags(n)
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ClusterId+"/"+entries[i].Project < entries[j].ClusterId+"/"+entries[j].Project
+	})
+	return entries, failed, nil
+}
+
+// getProjectOwners returns the users of the admin rolebindings of all
+// projects of the cluster with one call. Service accounts are left out
+func getProjectOwners(clusterId string) (map[string][]string, error) {
+	roleBindings, err := listObjects(clusterId, "oapi/v1/rolebindings")
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string][]string)
+	for _, rb := range roleBindings {
+		if name, _ := rb.Path("metadata.name").Data().(string); name != "admin" {
+			continue
+		}
+		project, _ := rb.Path("metadata.namespace").Data().(string)
+		users := []string{}
+		names, _ := rb.S("userNames").Children()
+		for _, n := range names {
+			if user, ok := n.Data().(string); ok && !strings.HasPrefix(user, "system:") {
+				users = append(users, strings.ToLower(user))
+			}
+		}
+		users = common.RemoveDuplicates(users)
+		sort.Strings(users)
+		owners[project] = users
+	}
+	return owners, nil
+}
+
+// namespaceTags returns the labels as key=value, sorted. Labels with a
+// prefix (e.g. openshift.io/) are set by the platform and left out
+func namespaceTags(namespace *gabs.Container) []string {
+	var tags []string
+	labels, _ := namespace.Path("metadata.labels").ChildrenMap()
+	for k, v := range labels {
+		if value, ok := v.Data().(string); ok && !strings.Contains(k, "/") {
+			tags = append(tags, k+"="+value)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
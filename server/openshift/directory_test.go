@@ -0,0 +1,44 @@
+package openshift
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestGetProjectDirectory(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	config.Config().Set("mail_user_domain", "example.com")
+
+	if err := createNewProject(nil, "fake", "ticketshop", "U123", "12345", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateNamespaceAnnotations("fake", "ticketshop", func(annotations *gabs.Container) {
+		annotations.Set("Ticket Shop", "openshift.io/display-name")
+		annotations.Set("team@example.com", "openshift.io/contact")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Namespaces without requester aren't projects of users
+	infra := gabs.New()
+	infra.Set("openshift-infra", "metadata", "name")
+	api.Set("api/v1/namespaces/openshift-infra", infra)
+
+	entries, failed, err := getProjectDirectory(getOpenshiftClusters(""))
+	if err != nil || len(failed) > 0 {
+		t.Fatalf("unexpected error: %v %v", err, failed)
+	}
+	expected := []DirectoryEntry{{
+		ClusterId:   "fake",
+		Project:     "ticketshop",
+		DisplayName: "Ticket Shop",
+		Owners:      []string{"u123"},
+		Contact:     "team@example.com",
+	}}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected %+v, got %+v", expected, entries)
+	}
+}
@@ -0,0 +1,264 @@
+package openshift
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	egressIPsCollection = "egress_ips"
+	maxEgressPoolSize   = 4096
+)
+
+// egressIPMutex makes sure an ip is only reserved once
+var egressIPMutex sync.Mutex
+
+// EgressIPReservation is an egress ip of the pool of the cluster reserved by a project
+type EgressIPReservation struct {
+	IP         string    `json:"ip"`
+	ClusterId  string    `json:"clusterid"`
+	Project    string    `json:"project"`
+	Purpose    string    `json:"purpose"`
+	ReservedBy string    `json:"reservedBy"`
+	ReservedAt time.Time `json:"reservedAt"`
+}
+
+func egressIPID(clusterId, ip string) string {
+	return clusterId + "/" + ip
+}
+
+func getEgressIPsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	reservations, err := getEgressIPReservations(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, reservations)
+}
+
+func getAllEgressIPsHandler(c *gin.Context) {
+	reservations, err := getEgressIPReservations(c.Query("clusterid"), "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, reservations)
+}
+
+func reserveEgressIPHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.ReserveEgressIPCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	reservation, err := reserveEgressIP(data.ClusterId, data.Project, data.Purpose, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, reservation)
+}
+
+func releaseEgressIPHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+	ip := c.Query("ip")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := releaseEgressIP(clusterId, project, ip, username); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Die Egress-IP %v wurde freigegeben", ip),
+	})
+}
+
+// getEgressIPReservations returns the reservations of the cluster and project.
+// Empty values match all
+func getEgressIPReservations(clusterId, project string) ([]EgressIPReservation, error) {
+	reservations := []EgressIPReservation{}
+	err := store.List(egressIPsCollection, func(id string, data []byte) error {
+		var r EgressIPReservation
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		if (clusterId == "" || r.ClusterId == clusterId) && (project == "" || r.Project == project) {
+			reservations = append(reservations, r)
+		}
+		return nil
+	})
+	return reservations, err
+}
+
+func reserveEgressIP(clusterId, project, purpose, username string) (*EgressIPReservation, error) {
+	cluster, err := getOpenshiftCluster(clusterId)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := expandIPPool(cluster.EgressIPs)
+	if err != nil {
+		log.Printf("WARNING: invalid egress ip pool of cluster %v: %v", clusterId, err)
+		return nil, errors.New(common.ConfigNotSetError)
+	}
+
+	egressIPMutex.Lock()
+	defer egressIPMutex.Unlock()
+
+	reserved, err := getEgressIPReservations(clusterId, "")
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool)
+	projectIPs := []string{}
+	for _, r := range reserved {
+		used[r.IP] = true
+		if r.Project == project {
+			projectIPs = append(projectIPs, r.IP)
+		}
+	}
+
+	for _, ip := range pool {
+		if used[ip] {
+			continue
+		}
+
+		if err := setNetNamespaceEgressIPs(clusterId, project, append(projectIPs, ip)); err != nil {
+			return nil, err
+		}
+		reservation := &EgressIPReservation{
+			IP:         ip,
+			ClusterId:  clusterId,
+			Project:    project,
+			Purpose:    purpose,
+			ReservedBy: username,
+			ReservedAt: time.Now(),
+		}
+		if err := store.Put(egressIPsCollection, egressIPID(clusterId, ip), reservation); err != nil {
+			return nil, err
+		}
+		log.Printf("%v reserved the egress ip %v for project %v on cluster %v", username, ip, project, clusterId)
+		return reservation, nil
+	}
+	return nil, errors.New("Es sind keine Egress-IPs mehr verfügbar. Bitte erstelle ein Ticket")
+}
+
+func releaseEgressIP(clusterId, project, ip, username string) error {
+	egressIPMutex.Lock()
+	defer egressIPMutex.Unlock()
+
+	var reservation EgressIPReservation
+	found, err := store.Get(egressIPsCollection, egressIPID(clusterId, ip), &reservation)
+	if err != nil {
+		return err
+	}
+	if !found || reservation.Project != project {
+		return fmt.Errorf("Die Egress-IP %v ist nicht für das Projekt %v reserviert", ip, project)
+	}
+
+	reserved, err := getEgressIPReservations(clusterId, project)
+	if err != nil {
+		return err
+	}
+	remaining := []string{}
+	for _, r := range reserved {
+		if r.IP != ip {
+			remaining = append(remaining, r.IP)
+		}
+	}
+	if err := setNetNamespaceEgressIPs(clusterId, project, remaining); err != nil {
+		return err
+	}
+
+	log.Printf("%v released the egress ip %v of project %v on cluster %v", username, ip, project, clusterId)
+	return store.Delete(egressIPsCollection, egressIPID(clusterId, ip))
+}
+
+// setNetNamespaceEgressIPs sets the egress ips of the project on the cluster
+func setNetNamespaceEgressIPs(clusterId, project string, ips []string) error {
+	sort.Strings(ips)
+	// json patch: "add" replaces the field if it already exists
+	patch, _ := json.Marshal([]map[string]interface{}{{"op": "add", "path": "/egressIPs", "value": ips}})
+
+	resp, err := getOseHTTPClient("PATCH", clusterId, "apis/network.openshift.io/v1/netnamespaces/"+project, bytes.NewReader(patch))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error setting egress ips of project %v on cluster %v: %v %v", project, clusterId, resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
+
+// expandIPPool returns all ips of the pool. Entries are single ips or CIDRs
+func expandIPPool(entries []string) ([]string, error) {
+	ips := []string{}
+	for _, e := range entries {
+		if ip := net.ParseIP(e); ip != nil {
+			ips = append(ips, ip.String())
+			continue
+		}
+
+		ip, network, err := net.ParseCIDR(e)
+		if err != nil {
+			return nil, err
+		}
+		for ip := ip.Mask(network.Mask).To4(); ip != nil && network.Contains(ip); ip = nextIP(ip) {
+			ips = append(ips, ip.String())
+			if len(ips) > maxEgressPoolSize {
+				return nil, fmt.Errorf("pool is larger than %v ips", maxEgressPoolSize)
+			}
+		}
+	}
+	return common.RemoveDuplicates(ips), nil
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			return next
+		}
+	}
+	return nil
+}
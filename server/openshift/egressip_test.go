@@ -0,0 +1,28 @@
+package openshift
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestExpandIPPool(t *testing.T) {
+	ips, err := expandIPPool([]string{"10.0.0.1", "10.0.1.0/30", "10.0.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ips)
+
+	expected := []string{"10.0.0.1", "10.0.1.0", "10.0.1.1", "10.0.1.2", "10.0.1.3"}
+	if len(ips) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, ips)
+	}
+	for i := range expected {
+		if ips[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, ips)
+		}
+	}
+
+	if _, err := expandIPPool([]string{"no-ip"}); err == nil {
+		t.Error("expected an error for an invalid entry")
+	}
+}
@@ -0,0 +1,125 @@
+package openshift
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	egressProxySecretName    = "egress-proxy"
+	egressProxyConfigMapName = "egress-proxy-env"
+)
+
+type egressProxyCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// egressProxyCredentialsHandler issues new credentials for the outbound
+// proxy. Calling it again rotates the password
+func egressProxyCredentialsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.OpenshiftBase
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := createEgressProxyCredentials(data.ClusterId, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v issued egress proxy credentials for project %v on cluster %v", username, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Die Proxy-Zugangsdaten wurden im Secret %v gespeichert. Die empfohlenen Umgebungsvariablen sind in der ConfigMap %v", egressProxySecretName, egressProxyConfigMapName),
+	})
+}
+
+func createEgressProxyCredentials(clusterId, project string) error {
+	cfg := config.Config()
+	proxyHost := cfg.GetString("egress_proxy.host")
+	proxyPort := cfg.GetString("egress_proxy.port")
+	if proxyHost == "" || proxyPort == "" {
+		log.Println("WARNING: Env variables 'EGRESS_PROXY_HOST' and 'EGRESS_PROXY_PORT' must be specified")
+		return errors.New(common.ConfigNotSetError)
+	}
+
+	credentials, err := issueEgressProxyCredentials(fmt.Sprintf("%v-%v", clusterId, project))
+	if err != nil {
+		return err
+	}
+
+	proxyURL := url.URL{
+		Scheme: "http",
+		User:   url.UserPassword(credentials.Username, credentials.Password),
+		Host:   fmt.Sprintf("%v:%v", proxyHost, proxyPort),
+	}
+	secret := newObjectRequest("Secret", egressProxySecretName)
+	secret.Set("Opaque", "type")
+	secret.Set(map[string]interface{}{
+		"PROXY_USER":     credentials.Username,
+		"PROXY_PASSWORD": credentials.Password,
+		"HTTP_PROXY":     proxyURL.String(),
+		"HTTPS_PROXY":    proxyURL.String(),
+	}, "stringData")
+	if err := createOrReplaceObject(clusterId, project, secret); err != nil {
+		return err
+	}
+
+	configMap := newObjectRequest("ConfigMap", egressProxyConfigMapName)
+	configMap.Set(map[string]interface{}{
+		"PROXY_HOST": proxyHost,
+		"PROXY_PORT": proxyPort,
+		"NO_PROXY":   cfg.GetString("egress_proxy.no_proxy"),
+	}, "data")
+	return createOrReplaceObject(clusterId, project, configMap)
+}
+
+// issueEgressProxyCredentials creates or rotates the credentials of the user
+// on the api of the proxy
+func issueEgressProxyCredentials(proxyUser string) (*egressProxyCredentials, error) {
+	body, _ := json.Marshal(map[string]string{"username": proxyUser})
+	resp, err := getExternalAPIClient("egress_proxy", "POST", "credentials", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error issuing egress proxy credentials: %v %v", resp.StatusCode, string(errMsg))
+		return nil, errors.New("Fehler beim Erstellen der Proxy-Zugangsdaten. Bitte erstelle ein Ticket")
+	}
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return nil, errors.New(genericAPIError)
+	}
+	credentials := &egressProxyCredentials{}
+	credentials.Username, _ = json.S("username").Data().(string)
+	credentials.Password, _ = json.S("password").Data().(string)
+	if credentials.Username == "" || credentials.Password == "" {
+		log.Println("Egress proxy api returned no credentials")
+		return nil, errors.New(genericAPIError)
+	}
+	return credentials, nil
+}
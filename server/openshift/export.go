@@ -0,0 +1,177 @@
+package openshift
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const redactedValue = "REDACTED"
+
+// exportedObjects are the objects of a project managed by the portal
+var exportedObjects = []struct {
+	kind       string
+	apiVersion string
+	path       string
+}{
+	{"ResourceQuota", "v1", "api/v1/namespaces/%v/resourcequotas"},
+	{"LimitRange", "v1", "api/v1/namespaces/%v/limitranges"},
+	{"RoleBinding", "v1", "oapi/v1/namespaces/%v/rolebindings"},
+	{"NetworkPolicy", "networking.k8s.io/v1", "apis/networking.k8s.io/v1/namespaces/%v/networkpolicies"},
+	{"Secret", "v1", "api/v1/namespaces/%v/secrets"},
+}
+
+// metadataFields are set by the cluster and removed from the export
+var metadataFields = []string{"uid", "selfLink", "resourceVersion", "creationTimestamp", "generation", "namespace", "ownerReferences"}
+
+func exportProjectHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v exported project %v on cluster %v", username, project, clusterId)
+	manifests, err := exportProject(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%v.yaml", project))
+	c.Data(http.StatusOK, "application/x-yaml", manifests)
+}
+
+// exportProject returns the namespace and the objects in exportedObjects as
+// yaml documents. System objects are skipped and secret values redacted
+func exportProject(clusterId, project string) ([]byte, error) {
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+	if namespace.Path("metadata.name").Data() == nil {
+		return nil, errors.New("Das Projekt existiert nicht")
+	}
+
+	objects := []*gabs.Container{cleanObject(namespace, "Namespace", "v1")}
+	for _, e := range exportedObjects {
+		items, err := listObjects(clusterId, fmt.Sprintf(e.path, project))
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range items {
+			if isSystemObject(e.kind, i) {
+				continue
+			}
+			o := cleanObject(i, e.kind, e.apiVersion)
+			if e.kind == "Secret" {
+				redactSecret(o)
+			}
+			objects = append(objects, o)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, o := range objects {
+		var document interface{}
+		if err := json.Unmarshal(o.Bytes(), &document); err != nil {
+			log.Printf("Error converting %v to yaml: %v", o.Path("metadata.name").Data(), err)
+			return nil, errors.New(genericAPIError)
+		}
+		out, err := yaml.Marshal(document)
+		if err != nil {
+			log.Printf("Error converting %v to yaml: %v", o.Path("metadata.name").Data(), err)
+			return nil, errors.New(genericAPIError)
+		}
+		buf.WriteString("---\n")
+		buf.Write(out)
+	}
+	return buf.Bytes(), nil
+}
+
+func listObjects(clusterId, path string) ([]*gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error listing %v: %v %v", path, resp.StatusCode, string(errMsg))
+		return nil, errors.New(genericAPIError)
+	}
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return nil, errors.New(genericAPIError)
+	}
+	items, _ := json.S("items").Children()
+	return items, nil
+}
+
+// cleanObject removes the fields which are set by the cluster
+func cleanObject(object *gabs.Container, kind, apiVersion string) *gabs.Container {
+	clean, _ := gabs.ParseJSON(object.Bytes())
+	clean.Set(kind, "kind")
+	clean.Set(apiVersion, "apiVersion")
+	clean.Delete("status")
+	for _, f := range metadataFields {
+		clean.Delete("metadata", f)
+	}
+	// The last applied configuration contains the whole object, including
+	// the data of secrets
+	clean.Delete("metadata", "annotations", lastAppliedAnnotation)
+	if annotations, _ := clean.Path("metadata.annotations").ChildrenMap(); annotations != nil && len(annotations) == 0 {
+		clean.Delete("metadata", "annotations")
+	}
+	if kind == "Namespace" {
+		clean.Delete("spec")
+	}
+	return clean
+}
+
+// isSystemObject returns true for the objects created by OpenShift
+func isSystemObject(kind string, object *gabs.Container) bool {
+	name, _ := object.Path("metadata.name").Data().(string)
+	switch kind {
+	case "RoleBinding":
+		return strings.HasPrefix(name, "system:")
+	case "Secret":
+		secretType, _ := object.S("type").Data().(string)
+		if secretType == "kubernetes.io/service-account-token" {
+			return true
+		}
+		// The dockercfg secrets of the service accounts
+		_, ok := object.Path("metadata.annotations").S("kubernetes.io/service-account.name").Data().(string)
+		return ok
+	}
+	return false
+}
+
+func redactSecret(secret *gabs.Container) {
+	for _, field := range []string{"data", "stringData"} {
+		values, err := secret.S(field).ChildrenMap()
+		if err != nil {
+			continue
+		}
+		for key := range values {
+			secret.Set(redactedValue, field, key)
+		}
+	}
+}
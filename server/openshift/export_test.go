@@ -0,0 +1,47 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs"
+)
+
+func TestCleanObjectRedactsSecrets(t *testing.T) {
+	secret, _ := gabs.ParseJSON([]byte(`{
+		"metadata": {"name": "db", "namespace": "p", "uid": "1", "resourceVersion": "2",
+			"annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{\"data\":{\"password\":\"c2VjcmV0\"}}"}},
+		"type": "Opaque",
+		"data": {"password": "c2VjcmV0"}
+	}`))
+
+	if isSystemObject("Secret", secret) {
+		t.Fatal("opaque secret must be exported")
+	}
+
+	clean := cleanObject(secret, "Secret", "v1")
+	redactSecret(clean)
+
+	if clean.Path("metadata.uid").Data() != nil || clean.Path("metadata.namespace").Data() != nil {
+		t.Errorf("cluster fields were not removed: %v", clean.String())
+	}
+	if clean.Path("metadata.annotations").Data() != nil {
+		t.Errorf("the last applied configuration was not removed: %v", clean.String())
+	}
+	if clean.Path("data.password").Data() != redactedValue {
+		t.Errorf("secret was not redacted: %v", clean.String())
+	}
+	if secret.Path("data.password").Data() != "c2VjcmV0" {
+		t.Error("original object must not be changed")
+	}
+}
+
+func TestIsSystemObject(t *testing.T) {
+	token, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "builder-token-x"}, "type": "kubernetes.io/service-account-token"}`))
+	if !isSystemObject("Secret", token) {
+		t.Error("service account tokens must not be exported")
+	}
+	binding, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "system:deployers"}}`))
+	if !isSystemObject("RoleBinding", binding) {
+		t.Error("system rolebindings must not be exported")
+	}
+}
@@ -0,0 +1,391 @@
+// Package fakeapi is an in-memory OpenShift API for integration tests and
+// local development without a cluster. It stores all objects by their api path
+// and implements the generic verbs the portal uses (GET, POST, PUT, PATCH,
+// DELETE) plus project requests. There is no authorization, every token is
+// accepted. Subject access reviews are answered from the admin rolebindings.
+package fakeapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/gabs"
+)
+
+// ServiceAccount is the user the fake api creates projects as
+const ServiceAccount = "system:serviceaccount:ssp:ssp-backend"
+
+// Server is a running fake api. Use URL as cluster url
+type Server struct {
+	*httptest.Server
+	mutex   sync.Mutex
+	objects map[string]*gabs.Container
+}
+
+// New starts a fake api without any objects
+func New() *Server {
+	s := &Server{objects: make(map[string]*gabs.Container)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Cluster returns the entry of 'openshift' in the config for the fake api
+func (s *Server) Cluster(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       id,
+		"name":     id,
+		"url":      s.URL,
+		"token":    "fake",
+		"features": []string{},
+	}
+}
+
+// AddProject creates a project like a project request of the requester
+func (s *Server) AddProject(name, requester string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.createProject(name, requester)
+}
+
+// Set stores the object under the api path, e.g. api/v1/namespaces/a/secrets/b
+func (s *Server) Set(path string, object *gabs.Container) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.objects[normalize(path)] = object
+}
+
+// Get returns a copy of the object with the api path
+func (s *Server) Get(path string) (*gabs.Container, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	object, ok := s.objects[normalize(path)]
+	if !ok {
+		return nil, false
+	}
+	clone, _ := gabs.ParseJSON(object.Bytes())
+	return clone, true
+}
+
+// normalize removes slashes and maps the openshift project api to namespaces
+func normalize(path string) string {
+	path = strings.Trim(path, "/")
+	if strings.HasPrefix(path, "oapi/v1/projects") {
+		path = "api/v1/namespaces" + strings.TrimPrefix(path, "oapi/v1/projects")
+	}
+	return path
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	path := normalize(r.URL.Path)
+	var body *gabs.Container
+	if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
+		data, _ := ioutil.ReadAll(r.Body)
+		if r.Method == http.MethodPatch {
+			s.patch(w, path, data)
+			return
+		}
+		var err error
+		if body, err = gabs.ParseJSON(data); err != nil {
+			writeStatus(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// The portal never persists anything with server-side dry-run
+	dryRun := r.URL.Query().Get("dryRun") != ""
+
+	switch {
+	case r.Method == http.MethodPost && path == "oapi/v1/projectrequests":
+		name, _ := body.Path("metadata.name").Data().(string)
+		if _, ok := s.objects["api/v1/namespaces/"+name]; ok {
+			writeStatus(w, http.StatusConflict, "project "+name+" already exists")
+			return
+		}
+		if !dryRun {
+			s.createProject(name, ServiceAccount)
+		}
+		writeJSON(w, http.StatusCreated, body)
+
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/token"):
+		// TokenRequest of a service account
+		seconds, _ := body.Path("spec.expirationSeconds").Data().(float64)
+		body.Set("fake-token-"+time.Now().Format(time.RFC3339Nano), "status", "token")
+		body.Set(time.Now().Add(time.Duration(seconds)*time.Second).UTC().Format(time.RFC3339), "status", "expirationTimestamp")
+		writeJSON(w, http.StatusCreated, body)
+
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/localsubjectaccessreviews"):
+		body.Set(s.isAdmin(namespaceOf(path), body), "status", "allowed")
+		writeJSON(w, http.StatusCreated, body)
+
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/processedtemplates"):
+		// Templates are returned as they are, the parameters aren't replaced
+		writeJSON(w, http.StatusCreated, body)
+
+	case r.Method == http.MethodGet:
+		if object, ok := s.objects[path]; ok {
+			writeJSON(w, http.StatusOK, object)
+			return
+		}
+		items := s.list(path, r.URL.Query().Get("labelSelector"))
+		if items == nil {
+			writeStatus(w, http.StatusNotFound, path+" not found")
+			return
+		}
+		list := gabs.New()
+		list.Set("List", "kind")
+		list.Set(items, "items")
+		writeJSON(w, http.StatusOK, list)
+
+	case r.Method == http.MethodPost:
+		name, _ := body.Path("metadata.name").Data().(string)
+		if _, ok := s.objects[path+"/"+name]; ok {
+			writeStatus(w, http.StatusConflict, name+" already exists")
+			return
+		}
+		if namespace := namespaceOf(path); namespace != "" {
+			body.Set(namespace, "metadata", "namespace")
+		}
+		if !dryRun {
+			s.objects[path+"/"+name] = body
+		}
+		writeJSON(w, http.StatusCreated, body)
+
+	case r.Method == http.MethodPut:
+		if _, ok := s.objects[path]; !ok {
+			writeStatus(w, http.StatusNotFound, path+" not found")
+			return
+		}
+		if !dryRun {
+			s.objects[path] = body
+		}
+		writeJSON(w, http.StatusOK, body)
+
+	case r.Method == http.MethodDelete:
+		object, ok := s.objects[path]
+		if !ok {
+			writeStatus(w, http.StatusNotFound, path+" not found")
+			return
+		}
+		if !dryRun {
+			s.delete(path)
+		}
+		writeJSON(w, http.StatusOK, object)
+
+	default:
+		writeStatus(w, http.StatusMethodNotAllowed, r.Method+" is not supported")
+	}
+}
+
+// createProject creates the namespace with the admin rolebinding of the user
+func (s *Server) createProject(name, user string) {
+	namespace := gabs.New()
+	namespace.Set("Namespace", "kind")
+	namespace.Set("v1", "apiVersion")
+	namespace.Set(name, "metadata", "name")
+	namespace.Set(map[string]interface{}{"openshift.io/requester": user}, "metadata", "annotations")
+	namespace.Set("Active", "status", "phase")
+	s.objects["api/v1/namespaces/"+name] = namespace
+
+	admin := gabs.New()
+	admin.Set("RoleBinding", "kind")
+	admin.Set("v1", "apiVersion")
+	admin.Set("admin", "metadata", "name")
+	admin.Set(name, "metadata", "namespace")
+	admin.Set("admin", "roleRef", "name")
+	admin.Set([]interface{}{user}, "userNames")
+	admin.Set([]interface{}{}, "groupNames")
+	s.objects["oapi/v1/namespaces/"+name+"/rolebindings/admin"] = admin
+}
+
+// isAdmin answers a subject access review: the user or one of its groups
+// must be in a rolebinding to admin in the namespace or to cluster-admin
+func (s *Server) isAdmin(namespace string, review *gabs.Container) bool {
+	subjects := map[string]bool{}
+	if user, ok := review.Path("spec.user").Data().(string); ok {
+		subjects["user:"+user] = true
+	}
+	groups, _ := review.Path("spec.groups").Children()
+	for _, g := range groups {
+		subjects["group:"+g.Data().(string)] = true
+	}
+
+	for p, binding := range s.objects {
+		role, _ := binding.Path("roleRef.name").Data().(string)
+		inNamespace := strings.HasPrefix(p, "oapi/v1/namespaces/"+namespace+"/rolebindings/") && role == "admin"
+		if !inNamespace && !(strings.HasPrefix(p, "oapi/v1/clusterrolebindings/") && role == "cluster-admin") {
+			continue
+		}
+		for _, kind := range []string{"user", "group"} {
+			names, _ := binding.Path(kind + "Names").Children()
+			for _, n := range names {
+				if name, ok := n.Data().(string); ok && subjects[kind+":"+name] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// list returns the objects directly below path. Paths without namespace
+// (e.g. oapi/v1/rolebindings) list the objects of all namespaces.
+// Returns nil if path isn't a known collection
+func (s *Server) list(path, labelSelector string) []interface{} {
+	paths := []string{}
+	for p := range s.objects {
+		if isItemOf(p, path) {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	items := []interface{}{}
+	for _, p := range paths {
+		if matchesLabels(s.objects[p], labelSelector) {
+			items = append(items, s.objects[p].Data())
+		}
+	}
+	if len(items) == 0 && !isCollection(path) {
+		return nil
+	}
+	return items
+}
+
+// isItemOf checks if the object path p is in the collection path. Cluster
+// wide collections contain the objects of the resource in all namespaces
+func isItemOf(p, collection string) bool {
+	if strings.HasPrefix(p, collection+"/") && !strings.Contains(strings.TrimPrefix(p, collection+"/"), "/") {
+		return true
+	}
+	segments := strings.Split(collection, "/")
+	if len(segments) < 2 {
+		return false
+	}
+	group := strings.Join(segments[:len(segments)-1], "/")
+	resource := segments[len(segments)-1]
+	itemSegments := strings.Split(strings.TrimPrefix(p, group+"/"), "/")
+	return strings.HasPrefix(p, group+"/namespaces/") && len(itemSegments) == 4 && itemSegments[2] == resource
+}
+
+// isCollection checks if the path ends with a resource, not with a name
+func isCollection(path string) bool {
+	segments := strings.Split(path, "/")
+	versionIndex := 1
+	if segments[0] == "apis" {
+		versionIndex = 2
+	}
+	if len(segments) <= versionIndex+1 {
+		return false
+	}
+	rest := segments[versionIndex+1:]
+	if len(rest) > 0 && rest[0] == "namespaces" && len(rest) > 2 {
+		rest = rest[2:]
+	}
+	return len(rest) == 1
+}
+
+// namespaceOf returns the namespace of a namespaced path
+func namespaceOf(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if s == "namespaces" && i+2 < len(segments) {
+			return segments[i+1]
+		}
+	}
+	return ""
+}
+
+// matchesLabels supports selectors like 'a=b,c' (equality and existence)
+func matchesLabels(object *gabs.Container, selector string) bool {
+	if selector == "" {
+		return true
+	}
+	labels, _ := object.Path("metadata.labels").ChildrenMap()
+	for _, requirement := range strings.Split(selector, ",") {
+		parts := strings.SplitN(requirement, "=", 2)
+		value, ok := labels[parts[0]]
+		if !ok {
+			return false
+		}
+		if len(parts) == 2 && value.Data() != parts[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// delete removes the object and with namespaces all objects in them
+func (s *Server) delete(path string) {
+	delete(s.objects, path)
+	if !strings.HasPrefix(path, "api/v1/namespaces/") {
+		return
+	}
+	namespace := strings.TrimPrefix(path, "api/v1/namespaces/")
+	for p := range s.objects {
+		if namespaceOf(p) == namespace {
+			delete(s.objects, p)
+		}
+	}
+}
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// patch applies a json patch. Only paths to object fields are supported
+func (s *Server) patch(w http.ResponseWriter, path string, data []byte) {
+	object, ok := s.objects[path]
+	if !ok {
+		writeStatus(w, http.StatusNotFound, path+" not found")
+		return
+	}
+
+	var operations []patchOperation
+	if err := json.Unmarshal(data, &operations); err != nil {
+		writeStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, o := range operations {
+		keys := strings.Split(strings.TrimPrefix(o.Path, "/"), "/")
+		for i, k := range keys {
+			keys[i] = strings.Replace(strings.Replace(k, "~1", "/", -1), "~0", "~", -1)
+		}
+		switch o.Op {
+		case "add", "replace":
+			object.Set(o.Value, keys...)
+		case "remove":
+			object.Delete(keys...)
+		default:
+			writeStatus(w, http.StatusUnprocessableEntity, o.Op+" is not supported")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, object)
+}
+
+func writeJSON(w http.ResponseWriter, status int, object *gabs.Container) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(object.Bytes())
+}
+
+// writeStatus answers with a kubernetes status object
+func writeStatus(w http.ResponseWriter, code int, message string) {
+	status := gabs.New()
+	status.Set("Status", "kind")
+	status.Set("Failure", "status")
+	status.Set(message, "message")
+	status.Set(code, "code")
+	writeJSON(w, code, status)
+}
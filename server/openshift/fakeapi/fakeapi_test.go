@@ -0,0 +1,73 @@
+package fakeapi
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/Jeffail/gabs"
+)
+
+func do(t *testing.T, s *Server, method, path, body string) (int, *gabs.Container) {
+	req, _ := http.NewRequest(method, s.URL+"/"+path, bytes.NewBufferString(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	json, _ := gabs.ParseJSONBuffer(resp.Body)
+	return resp.StatusCode, json
+}
+
+func TestServer(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	if code, _ := do(t, s, "POST", "oapi/v1/projectrequests", `{"metadata":{"name":"a"}}`); code != http.StatusCreated {
+		t.Fatalf("expected the project to be created, got %v", code)
+	}
+	if code, _ := do(t, s, "POST", "oapi/v1/projectrequests", `{"metadata":{"name":"a"}}`); code != http.StatusConflict {
+		t.Errorf("expected a conflict, got %v", code)
+	}
+
+	code, _ := do(t, s, "POST", "api/v1/namespaces/a/secrets", `{"metadata":{"name":"s","labels":{"x":"y"}}}`)
+	if code != http.StatusCreated {
+		t.Fatalf("expected the secret to be created, got %v", code)
+	}
+	s.AddProject("b", "u123")
+
+	tests := map[string]int{
+		"api/v1/namespaces":                  2,
+		"oapi/v1/projects":                   2,
+		"api/v1/namespaces/a/secrets":        1,
+		"api/v1/secrets?labelSelector=x=y":   1,
+		"api/v1/secrets?labelSelector=x=z":   0,
+		"oapi/v1/rolebindings":               2,
+		"oapi/v1/namespaces/b/rolebindings":  1,
+		"api/v1/namespaces/b/resourcequotas": 0,
+	}
+	for path, expected := range tests {
+		code, json := do(t, s, "GET", path, "")
+		items, _ := json.S("items").Children()
+		if code != http.StatusOK || len(items) != expected {
+			t.Errorf("GET %v: expected %v items, got %v %v", path, expected, code, json)
+		}
+	}
+
+	if code, _ := do(t, s, "GET", "api/v1/namespaces/missing", ""); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing object, got %v", code)
+	}
+
+	code, _ = do(t, s, "PATCH", "api/v1/namespaces/a/secrets/s", `[{"op":"add","path":"/metadata/annotations/openshift.io~1expires","value":"2019-01-01"}]`)
+	secret, _ := s.Get("api/v1/namespaces/a/secrets/s")
+	if code != http.StatusOK || secret.Path("metadata.annotations").S("openshift.io/expires").Data() != "2019-01-01" {
+		t.Errorf("expected the patch to be applied, got %v %v", code, secret)
+	}
+
+	if code, _ := do(t, s, "DELETE", "oapi/v1/projects/a", ""); code != http.StatusOK {
+		t.Fatalf("expected the project to be deleted, got %v", code)
+	}
+	if _, ok := s.Get("api/v1/namespaces/a/secrets/s"); ok {
+		t.Error("expected the objects of the project to be deleted")
+	}
+}
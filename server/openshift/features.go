@@ -8,7 +8,7 @@ type Features struct {
 func GetFeatures(clusterId string) Features {
 	cluster, _ := getOpenshiftCluster(clusterId)
 	return Features{
-		Gluster: cluster.GlusterApi != nil,
-		Nfs:     cluster.NfsApi != nil,
+		Gluster: storageProviders["gluster"].Available(cluster),
+		Nfs:     storageProviders["nfs"].Available(cluster),
 	}
 }
@@ -0,0 +1,98 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+func addGroupPermissionHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	data, err := bindGroupPermission(c, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err := validateGroupExists(data.ClusterId, data.Group); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := addGroupsToRoleBinding(data.ClusterId, data.Project, data.Role, []string{data.Group}); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v gave the group %v the role %v in project %v on cluster %v", username, data.Group, data.Role, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Die Gruppe %v hat jetzt die Rolle %v im Projekt %v", data.Group, data.Role, data.Project),
+	})
+}
+
+func removeGroupPermissionHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	data, err := bindGroupPermission(c, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := removeGroupsFromRoleBinding(data.ClusterId, data.Project, data.Role, []string{data.Group}); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v removed the role %v of the group %v in project %v on cluster %v", username, data.Role, data.Group, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Die Gruppe %v hat die Rolle %v im Projekt %v nicht mehr", data.Group, data.Role, data.Project),
+	})
+}
+
+func bindGroupPermission(c *gin.Context, username string) (*common.AddGroupPermissionCommand, error) {
+	var data common.AddGroupPermissionCommand
+	if c.BindJSON(&data) != nil {
+		return nil, errors.New(wrongAPIUsageError)
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		return nil, err
+	}
+	data.Group = strings.TrimSpace(data.Group)
+	if data.Group == "" {
+		return nil, errors.New("Gruppe muss angegeben werden")
+	}
+	if data.Role == "" {
+		data.Role = "admin"
+	}
+	if !contains(memberRoles, data.Role) {
+		return nil, fmt.Errorf("Die Rolle muss eine von %v sein", strings.Join(memberRoles, ", "))
+	}
+	return &data, nil
+}
+
+// validateGroupExists checks the group on the cluster, as openshift accepts
+// rolebindings of groups which don't exist
+func validateGroupExists(clusterId, group string) error {
+	resp, err := getOseHTTPClient("GET", clusterId, "oapi/v1/groups/"+url.PathEscape(group), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("Die Gruppe %v existiert auf dem Cluster %v nicht", group, clusterId)
+	}
+	errMsg, _ := ioutil.ReadAll(resp.Body)
+	log.Printf("Error getting group %v of cluster %v: %v %v", group, clusterId, resp.StatusCode, string(errMsg))
+	return errors.New(genericAPIError)
+}
@@ -0,0 +1,36 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs"
+)
+
+func TestValidateGroupExists(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	group := gabs.New()
+	group.Set("team-a", "metadata", "name")
+	api.Set("oapi/v1/groups/team-a", group)
+
+	if err := validateGroupExists("fake", "team-a"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, group := range []string{"team-b", "team-a?watch=1", "team-a#x"} {
+		if err := validateGroupExists("fake", group); err == nil {
+			t.Errorf("expected an error for the group %v which doesn't exist", group)
+		}
+	}
+
+	if err := createNewProject(nil, "fake", "own", "u123", "12345", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := addGroupsToRoleBinding("fake", "own", "edit", []string{"team-a"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	binding, _ := api.Get("oapi/v1/namespaces/own/rolebindings/edit")
+	groups, _ := binding.Path(groupNamesField).Children()
+	if len(groups) != 1 || groups[0].Data().(string) != "team-a" {
+		t.Errorf("expected team-a to be bound to edit, got %v", binding.Path(groupNamesField))
+	}
+}
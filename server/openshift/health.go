@@ -0,0 +1,128 @@
+package openshift
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// ProjectHealth rates the project from 0 (needs attention) to 100 (healthy)
+type ProjectHealth struct {
+	Score         int `json:"score"`
+	Restarts      int `json:"restarts"`
+	FailingProbes int `json:"failingProbes"`
+	PendingPods   int `json:"pendingPods"`
+	// QuotaSaturation is the percentage of the cpu or memory quota in use,
+	// whichever is higher
+	QuotaSaturation int      `json:"quotaSaturation"`
+	Reasons         []string `json:"reasons"`
+}
+
+// ProjectListItem is a project of the project list with ?health=true
+type ProjectListItem struct {
+	Name   string         `json:"name"`
+	Health *ProjectHealth `json:"health"`
+}
+
+func getProjectHealthHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	health, err := getProjectHealth(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, health)
+}
+
+func getProjectHealth(clusterId, project string) (*ProjectHealth, error) {
+	pods, err := listObjects(clusterId, fmt.Sprintf("api/v1/namespaces/%v/pods", project))
+	if err != nil {
+		return nil, err
+	}
+	quotas, err := getQuotas(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+	return rateProjectHealth(pods, quotas), nil
+}
+
+// rateProjectHealth subtracts a capped penalty per problem from 100
+func rateProjectHealth(pods []*gabs.Container, quotas *common.QuotasResponse) *ProjectHealth {
+	health := &ProjectHealth{Reasons: []string{}}
+	for _, p := range pods {
+		phase, _ := p.Path("status.phase").Data().(string)
+		if phase == "Pending" {
+			health.PendingPods++
+		}
+		containers, _ := p.Path("status.containerStatuses").Children()
+		for _, c := range containers {
+			restarts, _ := c.S("restartCount").Data().(float64)
+			health.Restarts += int(restarts)
+			// A running container which isn't ready fails its readiness probe
+			if ready, _ := c.S("ready").Data().(bool); !ready && c.Exists("state", "running") {
+				health.FailingProbes++
+			}
+		}
+	}
+	health.QuotaSaturation = quotaSaturation(quotas)
+
+	score := 100
+	penalty := func(count, each, max int, reason string) {
+		if count > 0 {
+			score -= int(math.Min(float64(count*each), float64(max)))
+			health.Reasons = append(health.Reasons, reason)
+		}
+	}
+	penalty(health.Restarts, 2, 30, fmt.Sprintf("%v Neustarts von Containern", health.Restarts))
+	penalty(health.FailingProbes, 10, 30, fmt.Sprintf("%v Container sind nicht bereit", health.FailingProbes))
+	penalty(health.PendingPods, 10, 20, fmt.Sprintf("%v Pods können nicht gestartet werden", health.PendingPods))
+	switch {
+	case health.QuotaSaturation >= 90:
+		penalty(1, 20, 20, fmt.Sprintf("Die Quota ist zu %v%% ausgeschöpft", health.QuotaSaturation))
+	case health.QuotaSaturation >= 75:
+		penalty(1, 10, 10, fmt.Sprintf("Die Quota ist zu %v%% ausgeschöpft", health.QuotaSaturation))
+	}
+	if score < 0 {
+		score = 0
+	}
+	health.Score = score
+	return health
+}
+
+func quotaSaturation(quotas *common.QuotasResponse) int {
+	saturation := 0.0
+	if quotas.CPU > 0 {
+		saturation = quotas.UsedCPU / quotas.CPU
+	}
+	if quotas.Memory > 0 {
+		saturation = math.Max(saturation, quotas.UsedMemory/quotas.Memory)
+	}
+	return int(math.Round(saturation * 100))
+}
+
+// withProjectHealth adds the health to the projects of the page. Projects
+// whose health can't be read have none
+func withProjectHealth(clusterId string, projects []string) []ProjectListItem {
+	items := []ProjectListItem{}
+	for _, p := range projects {
+		health, err := getProjectHealth(clusterId, p)
+		if err != nil {
+			log.Printf("Can't get the health of project %v on cluster %v: %v", p, clusterId, err)
+		}
+		items = append(items, ProjectListItem{Name: p, Health: health})
+	}
+	return items
+}
@@ -0,0 +1,41 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+func TestRateProjectHealth(t *testing.T) {
+	pod := func(json string) *gabs.Container {
+		p, err := gabs.ParseJSON([]byte(json))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	healthy := pod(`{"status": {"phase": "Running", "containerStatuses": [{"ready": true, "restartCount": 0, "state": {"running": {}}}]}}`)
+	unready := pod(`{"status": {"phase": "Running", "containerStatuses": [{"ready": false, "restartCount": 3, "state": {"running": {}}}]}}`)
+	pending := pod(`{"status": {"phase": "Pending"}}`)
+
+	tests := []struct {
+		pods     []*gabs.Container
+		quotas   common.QuotasResponse
+		expected int
+	}{
+		{[]*gabs.Container{healthy}, common.QuotasResponse{CPU: 4, Memory: 8, UsedCPU: 1, UsedMemory: 2}, 100},
+		{[]*gabs.Container{healthy, unready}, common.QuotasResponse{}, 84},
+		{[]*gabs.Container{pending, pending, pending}, common.QuotasResponse{CPU: 4, Memory: 8, UsedCPU: 3.2, UsedMemory: 2}, 70},
+		{[]*gabs.Container{unready, unready, unready, unready, pending, pending}, common.QuotasResponse{Memory: 8, UsedMemory: 8}, 6},
+	}
+	for i, test := range tests {
+		health := rateProjectHealth(test.pods, &test.quotas)
+		if health.Score != test.expected {
+			t.Errorf("%v: expected score %v, got %+v", i, test.expected, health)
+		}
+		if (health.Score == 100) != (len(health.Reasons) == 0) {
+			t.Errorf("%v: expected reasons for a score below 100, got %+v", i, health)
+		}
+	}
+}
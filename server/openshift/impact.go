@@ -0,0 +1,192 @@
+package openshift
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultDbaasLabel = "dbaas.sbb.ch/instance"
+
+// ProjectImpact is what would be lost by deleting a project
+type ProjectImpact struct {
+	RunningPods []string       `json:"runningPods"`
+	Routes      []RouteImpact  `json:"routes"`
+	Volumes     []VolumeImpact `json:"volumes"`
+	Databases   []string       `json:"databases"`
+	Warnings    []string       `json:"warnings,omitempty"`
+}
+
+type RouteImpact struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+	// Requests of the last 7 days, nil if unknown
+	Requests *int `json:"requests"`
+}
+
+type VolumeImpact struct {
+	Name         string `json:"name"`
+	Size         string `json:"size"`
+	StorageClass string `json:"storageClass,omitempty"`
+}
+
+type routeTraffic struct {
+	Facets []struct {
+		Name    string
+		Results []struct {
+			Count float64
+		}
+	}
+}
+
+func getProjectImpactHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	impact, err := getProjectImpact(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, impact)
+}
+
+// getProjectImpact collects the running pods, routes, bound volumes and
+// DBaaS instances of the project. The traffic of the routes is only known
+// if 'deletion_impact.route_traffic_nrql' is set
+func getProjectImpact(clusterId, project string) (*ProjectImpact, error) {
+	impact := &ProjectImpact{
+		RunningPods: []string{},
+		Routes:      []RouteImpact{},
+		Volumes:     []VolumeImpact{},
+		Databases:   []string{},
+	}
+
+	pods, err := listObjects(clusterId, fmt.Sprintf("api/v1/namespaces/%v/pods", project))
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pods {
+		if phase, _ := p.Path("status.phase").Data().(string); phase == "Running" {
+			name, _ := p.Path("metadata.name").Data().(string)
+			impact.RunningPods = append(impact.RunningPods, name)
+		}
+	}
+
+	routes, err := listObjects(clusterId, fmt.Sprintf("oapi/v1/namespaces/%v/routes", project))
+	if err != nil {
+		return nil, err
+	}
+	traffic, err := getRouteTraffic(project)
+	if err != nil {
+		log.Printf("Can't get the traffic of the routes of project %v: %v", project, err)
+		impact.Warnings = append(impact.Warnings, "Der Traffic der Routen konnte nicht ermittelt werden")
+	}
+	for _, r := range routes {
+		route := RouteImpact{}
+		route.Name, _ = r.Path("metadata.name").Data().(string)
+		route.Host, _ = r.Path("spec.host").Data().(string)
+		if traffic != nil {
+			requests := traffic[strings.ToLower(route.Host)]
+			route.Requests = &requests
+		}
+		impact.Routes = append(impact.Routes, route)
+	}
+
+	pvcs, err := listObjects(clusterId, fmt.Sprintf("api/v1/namespaces/%v/persistentvolumeclaims", project))
+	if err != nil {
+		return nil, err
+	}
+	for _, pvc := range pvcs {
+		if phase, _ := pvc.Path("status.phase").Data().(string); phase != "Bound" {
+			continue
+		}
+		volume := VolumeImpact{}
+		volume.Name, _ = pvc.Path("metadata.name").Data().(string)
+		volume.StorageClass, _ = pvc.Path("spec.storageClassName").Data().(string)
+		if volume.Size, _ = pvc.Path("status.capacity.storage").Data().(string); volume.Size == "" {
+			volume.Size, _ = pvc.Path("spec.resources.requests.storage").Data().(string)
+		}
+		impact.Volumes = append(impact.Volumes, volume)
+	}
+
+	label := config.Config().GetString("deletion_impact.dbaas_label")
+	if label == "" {
+		label = defaultDbaasLabel
+	}
+	secrets, err := listObjects(clusterId, fmt.Sprintf("api/v1/namespaces/%v/secrets?labelSelector=%v", project, url.QueryEscape(label)))
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range secrets {
+		if instance, ok := s.Path("metadata.labels").S(label).Data().(string); ok {
+			impact.Databases = append(impact.Databases, instance)
+		}
+	}
+	impact.Databases = common.RemoveDuplicates(impact.Databases)
+
+	return impact, nil
+}
+
+// getRouteTraffic returns the requests per host of the last 7 days. The nrql
+// query gets the project and must facet by host
+func getRouteTraffic(project string) (map[string]int, error) {
+	query := config.Config().GetString("deletion_impact.route_traffic_nrql")
+	if query == "" {
+		return nil, nil
+	}
+	if err := checkNewrelicConfig(); err != nil {
+		return nil, err
+	}
+
+	traffic := new(routeTraffic)
+	if err := getJson(common.HTTPClient("newrelic"), fmt.Sprintf(query, project), traffic); err != nil {
+		return nil, err
+	}
+	requests := make(map[string]int)
+	for _, f := range traffic.Facets {
+		if len(f.Results) > 0 {
+			requests[strings.ToLower(f.Name)] = int(f.Results[0].Count)
+		}
+	}
+	return requests, nil
+}
+
+// impactChanges lists the impact as planned changes of a dry-run deletion
+func impactChanges(clusterId, project string, impact *ProjectImpact) []common.PlannedChange {
+	changes := []common.PlannedChange{}
+	change := func(kind, name, details string) {
+		changes = append(changes, common.PlannedChange{
+			Action: common.DryRunActionDelete, Kind: kind, Name: name, ClusterId: clusterId, Project: project, Details: details,
+		})
+	}
+	for _, p := range impact.RunningPods {
+		change("Pod", p, "läuft")
+	}
+	for _, r := range impact.Routes {
+		details := r.Host
+		if r.Requests != nil {
+			details = fmt.Sprintf("%v: %v Requests in den letzten 7 Tagen", r.Host, *r.Requests)
+		}
+		change("Route", r.Name, details)
+	}
+	for _, v := range impact.Volumes {
+		change("PersistentVolumeClaim", v.Name, v.Size)
+	}
+	for _, d := range impact.Databases {
+		change("DBaaS", d, "ist mit dem Projekt verbunden")
+	}
+	return changes
+}
@@ -0,0 +1,49 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs"
+)
+
+func TestGetProjectImpact(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("shop", "u123")
+
+	for path, object := range map[string]string{
+		"api/v1/namespaces/shop/pods/web-1":                   `{"metadata": {"name": "web-1"}, "status": {"phase": "Running"}}`,
+		"api/v1/namespaces/shop/pods/migrate-1":               `{"metadata": {"name": "migrate-1"}, "status": {"phase": "Succeeded"}}`,
+		"oapi/v1/namespaces/shop/routes/web":                  `{"metadata": {"name": "web"}, "spec": {"host": "shop.example.com"}}`,
+		"api/v1/namespaces/shop/persistentvolumeclaims/data":  `{"metadata": {"name": "data"}, "spec": {"resources": {"requests": {"storage": "1Gi"}}}, "status": {"phase": "Bound", "capacity": {"storage": "2Gi"}}}`,
+		"api/v1/namespaces/shop/persistentvolumeclaims/spare": `{"metadata": {"name": "spare"}, "status": {"phase": "Pending"}}`,
+		"api/v1/namespaces/shop/secrets/db":                   `{"metadata": {"name": "db", "labels": {"dbaas.sbb.ch/instance": "shop-pg"}}}`,
+		"api/v1/namespaces/shop/secrets/other":                `{"metadata": {"name": "other"}}`,
+	} {
+		json, err := gabs.ParseJSON([]byte(object))
+		if err != nil {
+			t.Fatal(err)
+		}
+		api.Set(path, json)
+	}
+
+	impact, err := getProjectImpact("fake", "shop")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(impact.RunningPods) != 1 || impact.RunningPods[0] != "web-1" {
+		t.Errorf("expected only web-1 to be running, got %v", impact.RunningPods)
+	}
+	if len(impact.Routes) != 1 || impact.Routes[0].Host != "shop.example.com" || impact.Routes[0].Requests != nil {
+		t.Errorf("expected the route without traffic, got %+v", impact.Routes)
+	}
+	if len(impact.Volumes) != 1 || impact.Volumes[0].Name != "data" || impact.Volumes[0].Size != "2Gi" {
+		t.Errorf("expected only the bound volume data with 2Gi, got %+v", impact.Volumes)
+	}
+	if len(impact.Databases) != 1 || impact.Databases[0] != "shop-pg" {
+		t.Errorf("expected the database shop-pg, got %v", impact.Databases)
+	}
+	if changes := impactChanges("fake", "shop", impact); len(changes) != 4 {
+		t.Errorf("expected 4 planned changes, got %+v", changes)
+	}
+}
@@ -0,0 +1,208 @@
+package openshift
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	managedProjectsCollection = "managed_projects"
+	importMissingBilling      = "billing"
+	importMissingOwner        = "owner"
+)
+
+// ManagedProject is a project which was imported into the portal
+type ManagedProject struct {
+	ClusterId  string    `json:"clusterid"`
+	Project    string    `json:"project"`
+	Billing    string    `json:"billing"`
+	Owner      string    `json:"owner"`
+	ImportedBy string    `json:"importedBy"`
+	ImportedAt time.Time `json:"importedAt"`
+}
+
+func managedProjectID(clusterId, project string) string {
+	return clusterId + "/" + project
+}
+
+// isSystemNamespace returns true for the namespaces of OpenShift itself,
+// which are never managed by the portal
+func isSystemNamespace(name string) bool {
+	return name == "default" || strings.HasPrefix(name, "openshift") || strings.HasPrefix(name, "kube-")
+}
+
+// getUnmanagedProjects lists the namespaces of the cluster without billing
+// or owner annotation
+func getUnmanagedProjects(clusterId string) ([]common.UnmanagedProject, error) {
+	namespaces, err := getAllNamespaces(clusterId)
+	if err != nil {
+		return nil, err
+	}
+
+	projects := []common.UnmanagedProject{}
+	for _, n := range namespaces {
+		name, _ := n.Path("metadata.name").Data().(string)
+		if isSystemNamespace(name) {
+			continue
+		}
+		annotations := n.Path("metadata.annotations")
+		p := common.UnmanagedProject{
+			ClusterId: clusterId,
+			Project:   name,
+			Billing:   getAnnotation(annotations, annotationBilling),
+			Owner:     getAnnotation(annotations, annotationRequester),
+		}
+		p.Missing = missingImportData(p.Billing, p.Owner)
+		if len(p.Missing) > 0 {
+			projects = append(projects, p)
+		}
+	}
+	return projects, nil
+}
+
+func missingImportData(billing, owner string) []string {
+	missing := []string{}
+	if billing == "" {
+		missing = append(missing, importMissingBilling)
+	}
+	if owner == "" {
+		missing = append(missing, importMissingOwner)
+	}
+	return missing
+}
+
+// importProject writes the annotations, makes the owner admin and creates the
+// default quota like for a new project, then adds it to the managed projects.
+// Nothing is changed if the billing or the owner is unknown
+func importProject(clusterId string, p common.ImportProject, username string) common.ImportProjectResult {
+	result := common.ImportProjectResult{Project: p.Project}
+	if isSystemNamespace(p.Project) {
+		result.Message = "Systemprojekte können nicht importiert werden"
+		return result
+	}
+
+	namespace, err := getNamespace(clusterId, p.Project)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	annotations := namespace.Path("metadata.annotations")
+	if p.Billing == "" {
+		p.Billing = getAnnotation(annotations, annotationBilling)
+	}
+	if p.Owner == "" {
+		p.Owner = getAnnotation(annotations, annotationRequester)
+	}
+	if result.Missing = missingImportData(p.Billing, p.Owner); len(result.Missing) > 0 {
+		result.Message = "Kontierungsnummer und Besitzer müssen angegeben werden"
+		return result
+	}
+
+	result.Steps = repairProject(clusterId, p.Project, p.Billing, p.Owner, username)
+	for _, s := range result.Steps {
+		if s.Status == repairStatusFailed {
+			result.Message = fmt.Sprintf("Der Schritt %v ist fehlgeschlagen", s.Name)
+			return result
+		}
+	}
+
+	err = store.Put(managedProjectsCollection, managedProjectID(clusterId, p.Project), ManagedProject{
+		ClusterId:  clusterId,
+		Project:    p.Project,
+		Billing:    p.Billing,
+		Owner:      strings.ToLower(p.Owner),
+		ImportedBy: username,
+		ImportedAt: common.Now(),
+	})
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Imported = true
+	log.Printf("%v imported project %v on cluster %v with owner %v and billing %v", username, p.Project, clusterId, p.Owner, p.Billing)
+	return result
+}
+
+// parseImportCSV reads the columns project, billing and owner. The first
+// line is the header
+func parseImportCSV(r io.Reader) ([]common.ImportProject, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Ungültiges CSV: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("Das CSV ist leer")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["project"]; !ok {
+		return nil, errors.New("Das CSV braucht die Spalten project, billing und owner")
+	}
+	value := func(row []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	projects := []common.ImportProject{}
+	for _, row := range rows[1:] {
+		projects = append(projects, common.ImportProject{
+			Project: value(row, "project"),
+			Billing: value(row, "billing"),
+			Owner:   value(row, "owner"),
+		})
+	}
+	return projects, nil
+}
+
+func getUnmanagedProjectsHandler(c *gin.Context) {
+	projects, err := getUnmanagedProjects(c.Query("clusterid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, projects)
+}
+
+// importProjectsHandler imports the projects of the json body or of a csv
+// body (Content-Type text/csv) with ?clusterid=
+func importProjectsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.ImportProjectsCommand
+	if strings.Contains(c.ContentType(), "csv") {
+		projects, err := parseImportCSV(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		data = common.ImportProjectsCommand{ClusterId: c.Query("clusterid"), Projects: projects}
+	} else if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if _, err := getOpenshiftCluster(data.ClusterId); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	results := []common.ImportProjectResult{}
+	for _, p := range data.Projects {
+		results = append(results, importProject(data.ClusterId, p, username))
+	}
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,52 @@
+package openshift
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+)
+
+func TestImportProjects(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("legacy", "u123")
+	api.AddProject("orphan", "")
+	api.AddProject("openshift-monitoring", "")
+
+	unmanaged, err := getUnmanagedProjects("fake")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(unmanaged) != 2 {
+		t.Fatalf("expected legacy and orphan to be unmanaged, got %+v", unmanaged)
+	}
+
+	projects, err := parseImportCSV(strings.NewReader("project,billing,owner\nlegacy,12345,\norphan,,\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	results := []common.ImportProjectResult{}
+	for _, p := range projects {
+		results = append(results, importProject("fake", p, "admin"))
+	}
+	if !results[0].Imported {
+		t.Errorf("expected legacy to be imported with the owner of the annotation, got %+v", results[0])
+	}
+	if results[1].Imported || len(results[1].Missing) != 2 {
+		t.Errorf("expected orphan to miss billing and owner, got %+v", results[1])
+	}
+
+	namespace, _ := api.Get("api/v1/namespaces/legacy")
+	if billing := getAnnotation(namespace.Path("metadata.annotations"), annotationBilling); billing != "12345" {
+		t.Errorf("expected the billing to be written, got %v", billing)
+	}
+	var managed ManagedProject
+	if found, _ := store.Get(managedProjectsCollection, managedProjectID("fake", "legacy"), &managed); !found || managed.Owner != "u123" {
+		t.Errorf("expected legacy to be in the managed projects, got %+v", managed)
+	}
+	if unmanaged, _ := getUnmanagedProjects("fake"); len(unmanaged) != 1 || unmanaged[0].Project != "orphan" {
+		t.Errorf("expected only orphan to be unmanaged, got %+v", unmanaged)
+	}
+}
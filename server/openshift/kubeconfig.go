@@ -0,0 +1,413 @@
+package openshift
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ed25519"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// kubeconfigModeServiceAccount issues a token of a service account with
+	// the role edit per project
+	kubeconfigModeServiceAccount = "serviceaccount"
+	// kubeconfigModeUser uses the user's own openshift token, forwarded by
+	// the oauth proxy in front of the portal
+	kubeconfigModeUser = "user"
+
+	defaultKubeconfigTokenHours = 8
+	kubeconfigSignatureHeader   = "X-SSP-Signature"
+	kubeconfigGrantsCollection  = "kubeconfig_grants"
+	kubeconfigExpiryLease       = "kubeconfig-expiry"
+)
+
+var kubeconfigNameInvalidChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// kubeconfig is the file read by oc and kubectl
+type kubeconfig struct {
+	APIVersion     string              `yaml:"apiVersion"`
+	Kind           string              `yaml:"kind"`
+	Clusters       []kubeconfigCluster `yaml:"clusters"`
+	Users          []kubeconfigUser    `yaml:"users"`
+	Contexts       []kubeconfigContext `yaml:"contexts"`
+	CurrentContext string              `yaml:"current-context"`
+	Preferences    map[string]string   `yaml:"preferences"`
+}
+
+type kubeconfigCluster struct {
+	Name    string `yaml:"name"`
+	Cluster struct {
+		Server                   string `yaml:"server"`
+		CertificateAuthorityData string `yaml:"certificate-authority-data,omitempty"`
+	} `yaml:"cluster"`
+}
+
+type kubeconfigUser struct {
+	Name string `yaml:"name"`
+	User struct {
+		Token string `yaml:"token"`
+	} `yaml:"user"`
+}
+
+type kubeconfigContext struct {
+	Name    string `yaml:"name"`
+	Context struct {
+		Cluster   string `yaml:"cluster"`
+		Namespace string `yaml:"namespace"`
+		User      string `yaml:"user"`
+	} `yaml:"context"`
+}
+
+// KubeconfigGrant is the role edit of the service account of a user in a
+// project. It's removed when the last token expires or the user revokes it
+type KubeconfigGrant struct {
+	ClusterId      string    `json:"clusterid"`
+	Project        string    `json:"project"`
+	ServiceAccount string    `json:"serviceAccount"`
+	Username       string    `json:"username"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+}
+
+func (g KubeconfigGrant) id() string {
+	return g.ClusterId + "/" + g.Project + "/" + g.ServiceAccount
+}
+
+type kubeconfigSigningKeyResponse struct {
+	PublicKey string `json:"publicKey"`
+}
+
+// kubeconfigTokenHours is the lifetime of the service account tokens in
+// the kubeconfig
+func kubeconfigTokenHours() int {
+	if hours := config.Config().GetInt("kubeconfig.token_hours"); hours > 0 {
+		return hours
+	}
+	return defaultKubeconfigTokenHours
+}
+
+// kubeconfigServiceAccountName is the service account of the user in the
+// projects, e.g. kubeconfig-u123-1a2b3c4d. The hash of the username keeps
+// the names of users apart which only differ in invalid characters or after
+// the 63rd character
+func kubeconfigServiceAccountName(username string) string {
+	username = strings.ToLower(username)
+	hash := sha256.Sum256([]byte(username))
+	suffix := "-" + hex.EncodeToString(hash[:4])
+	name := "kubeconfig-" + strings.Trim(kubeconfigNameInvalidChars.ReplaceAllString(username, "-"), "-")
+	if len(name) > 63-len(suffix) {
+		name = name[:63-len(suffix)]
+	}
+	return strings.TrimRight(name, "-") + suffix
+}
+
+// kubeconfigSigningKey is the private key of 'kubeconfig.signing_key', the
+// base64 encoded seed of an ed25519 key. It's nil without key
+func kubeconfigSigningKey() ed25519.PrivateKey {
+	encoded := config.Config().GetString("kubeconfig.signing_key")
+	if encoded == "" {
+		return nil
+	}
+	seed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		log.Printf("WARNING: kubeconfig.signing_key must be the base64 encoded seed of %v bytes of an ed25519 key", ed25519.SeedSize)
+		return nil
+	}
+	return ed25519.NewKeyFromSeed(seed)
+}
+
+// signKubeconfig returns the ed25519 signature of the kubeconfig, so scripts
+// can check with the public key of /ose/kubeconfig/signingkey that it was
+// issued by the portal. It's empty without key
+func signKubeconfig(data []byte) string {
+	key := kubeconfigSigningKey()
+	if key == nil {
+		return ""
+	}
+	return "ed25519=" + base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+}
+
+// getKubeconfigSigningKeyHandler returns the public key of the signatures
+func getKubeconfigSigningKeyHandler(c *gin.Context) {
+	key := kubeconfigSigningKey()
+	if key == nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Die Kubeconfigs werden nicht signiert"})
+		return
+	}
+	c.JSON(http.StatusOK, kubeconfigSigningKeyResponse{
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+	})
+}
+
+// kubeconfigHandler returns a kubeconfig with a context for every project
+// of the command, or for all projects the user administrates on the cluster
+func kubeconfigHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.KubeconfigCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if data.Mode == "" {
+		data.Mode = kubeconfigModeServiceAccount
+	}
+
+	projects, err := validateKubeconfig(data, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	user := clusterUserFromContext(c)
+	if data.Mode == kubeconfigModeUser && user.Token == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Dein OpenShift Token ist nicht verfügbar. Bitte melde dich neu an oder wähle einen Token eines Service Accounts"})
+		return
+	}
+
+	file, err := createKubeconfig(user, data.ClusterId, projects, data.Mode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	common.Logger(c).Printf("%v downloaded a kubeconfig (%v) for the projects %v on cluster %v", username, data.Mode, strings.Join(projects, ", "), data.ClusterId)
+
+	if signature := signKubeconfig(file); signature != "" {
+		c.Header(kubeconfigSignatureHeader, signature)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=kubeconfig-%v", data.ClusterId))
+	c.Data(http.StatusOK, "application/x-yaml", file)
+}
+
+// validateKubeconfig returns the projects of the kubeconfig. The user must
+// be admin of all of them
+func validateKubeconfig(data common.KubeconfigCommand, username string) ([]string, error) {
+	if data.Mode != kubeconfigModeServiceAccount && data.Mode != kubeconfigModeUser {
+		return nil, fmt.Errorf("Der Modus muss %v oder %v sein", kubeconfigModeServiceAccount, kubeconfigModeUser)
+	}
+	if data.ClusterId == "" {
+		return nil, errors.New("Cluster muss angegeben werden")
+	}
+	if _, err := getOpenshiftCluster(data.ClusterId); err != nil {
+		return nil, err
+	}
+
+	if len(data.Projects) == 0 {
+		adminProjects, err := getAdminProjects(data.ClusterId, username)
+		if err != nil {
+			return nil, err
+		}
+		for project := range adminProjects {
+			if !isSystemNamespace(project) {
+				data.Projects = append(data.Projects, project)
+			}
+		}
+		if len(data.Projects) == 0 {
+			return nil, fmt.Errorf("Du bist auf dem Cluster %v in keinem Projekt Admin", data.ClusterId)
+		}
+		sort.Strings(data.Projects)
+		return data.Projects, nil
+	}
+	for _, project := range data.Projects {
+		if err := validateAdminAccess(data.ClusterId, username, project); err != nil {
+			return nil, err
+		}
+	}
+	return data.Projects, nil
+}
+
+// createKubeconfig returns the kubeconfig as yaml. With the mode
+// serviceaccount every project gets its own token, which can only edit the
+// project and expires after 'kubeconfig.token_hours'
+func createKubeconfig(user *clusterUser, clusterId string, projects []string, mode string) ([]byte, error) {
+	cluster, err := getOpenshiftCluster(clusterId)
+	if err != nil {
+		return nil, err
+	}
+
+	k := kubeconfig{APIVersion: "v1", Kind: "Config", Preferences: map[string]string{}}
+	clusterEntry := kubeconfigCluster{Name: clusterId}
+	clusterEntry.Cluster.Server = cluster.URL
+	if cluster.CABundle != "" {
+		pem, err := ioutil.ReadFile(cluster.CABundle)
+		if err != nil {
+			log.Printf("Error reading the ca bundle of cluster %v: %v", clusterId, err)
+			return nil, errors.New(genericAPIError)
+		}
+		clusterEntry.Cluster.CertificateAuthorityData = base64.StdEncoding.EncodeToString(pem)
+	}
+	k.Clusters = append(k.Clusters, clusterEntry)
+
+	if mode == kubeconfigModeUser {
+		userEntry := kubeconfigUser{Name: user.Name + "/" + clusterId}
+		userEntry.User.Token = user.Token
+		k.Users = append(k.Users, userEntry)
+	}
+
+	for _, project := range projects {
+		userName := user.Name + "/" + clusterId
+		if mode == kubeconfigModeServiceAccount {
+			serviceAccount := kubeconfigServiceAccountName(user.Name)
+			token, err := issueKubeconfigToken(user, clusterId, project, serviceAccount)
+			if err != nil {
+				return nil, err
+			}
+			userName = serviceAccount + "/" + project + "/" + clusterId
+			userEntry := kubeconfigUser{Name: userName}
+			userEntry.User.Token = token
+			k.Users = append(k.Users, userEntry)
+		}
+
+		context := kubeconfigContext{Name: project + "/" + clusterId}
+		context.Context.Cluster = clusterId
+		context.Context.Namespace = project
+		context.Context.User = userName
+		k.Contexts = append(k.Contexts, context)
+	}
+	k.CurrentContext = k.Contexts[0].Name
+
+	return yaml.Marshal(k)
+}
+
+// issueKubeconfigToken creates the service account of the user in the
+// project if needed, gives it the role edit and requests a token
+func issueKubeconfigToken(user *clusterUser, clusterId, project, serviceAccount string) (string, error) {
+	existing, err := getServiceAccount(clusterId, project, serviceAccount)
+	if err != nil {
+		return "", err
+	}
+	if existing.Path("metadata.name").Data() == nil {
+		if err := createNewServiceAccount(user, clusterId, project, serviceAccount); err != nil {
+			return "", err
+		}
+	}
+	// Recorded first, so the role is removed even if the token fails
+	if err := recordKubeconfigGrant(KubeconfigGrant{
+		ClusterId: clusterId, Project: project, ServiceAccount: serviceAccount, Username: user.Name,
+		ExpiresAt: time.Now().Add(time.Duration(kubeconfigTokenHours()) * time.Hour),
+	}); err != nil {
+		return "", err
+	}
+	if err := addUsersToRoleBinding(clusterId, project, "edit", []string{serviceAccountUserName(project, serviceAccount)}); err != nil {
+		return "", err
+	}
+
+	token, _, err := requestServiceAccountToken(clusterId, project, serviceAccount, kubeconfigTokenHours()*60*60)
+	if err != nil {
+		log.Printf("Error requesting a token of service account %v in project %v on cluster %v: %v", serviceAccount, project, clusterId, err)
+		return "", errors.New(genericAPIError)
+	}
+	return token, nil
+}
+
+// recordKubeconfigGrant keeps the grant until its last token expires
+func recordKubeconfigGrant(grant KubeconfigGrant) error {
+	var existing KubeconfigGrant
+	return store.Update(kubeconfigGrantsCollection, grant.id(), &existing, func(exists bool) error {
+		if exists && existing.ExpiresAt.After(grant.ExpiresAt) {
+			grant.ExpiresAt = existing.ExpiresAt
+		}
+		existing = grant
+		return nil
+	})
+}
+
+// getKubeconfigGrants returns the matching grants
+func getKubeconfigGrants(filter func(KubeconfigGrant) bool) ([]KubeconfigGrant, error) {
+	grants := []KubeconfigGrant{}
+	err := store.List(kubeconfigGrantsCollection, func(id string, data []byte) error {
+		var g KubeconfigGrant
+		if err := json.Unmarshal(data, &g); err != nil {
+			return err
+		}
+		if filter(g) {
+			grants = append(grants, g)
+		}
+		return nil
+	})
+	return grants, err
+}
+
+// revokeKubeconfigGrant removes the role edit of the service account. Its
+// tokens can't change the project anymore
+func revokeKubeconfigGrant(grant KubeconfigGrant) error {
+	if err := removeUsersFromRoleBinding(grant.ClusterId, grant.Project, "edit", []string{serviceAccountUserName(grant.Project, grant.ServiceAccount)}); err != nil {
+		return err
+	}
+	return store.Delete(kubeconfigGrantsCollection, grant.id())
+}
+
+// revokeKubeconfigHandler revokes the kubeconfigs of the user for the
+// projects of the command, or for all projects on the cluster
+func revokeKubeconfigHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.KubeconfigCommand
+	if c.BindJSON(&data) != nil || data.ClusterId == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	grants, err := getKubeconfigGrants(func(g KubeconfigGrant) bool {
+		return g.Username == username && g.ClusterId == data.ClusterId && (len(data.Projects) == 0 || contains(data.Projects, g.Project))
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	for _, grant := range grants {
+		if err := revokeKubeconfigGrant(grant); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		audit.Describe(c, "%v revoked the kubeconfig of project %v on cluster %v", username, grant.Project, grant.ClusterId)
+	}
+	c.JSON(http.StatusOK, common.ApiResponse{Message: fmt.Sprintf("%v Kubeconfig-Berechtigungen wurden entfernt", len(grants))})
+}
+
+// StartKubeconfigExpiry removes the role edit of the kubeconfig service
+// accounts every hour once their tokens expired. Only the instance holding
+// the lease removes them
+func StartKubeconfigExpiry() {
+	go func() {
+		for {
+			leader, err := store.TryLease(kubeconfigExpiryLease, common.InstanceID(), 2*time.Hour)
+			if err != nil {
+				log.Printf("Error acquiring the lease of the kubeconfig expiry: %v", err)
+			}
+			if leader {
+				revokeExpiredKubeconfigGrants(time.Now())
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+func revokeExpiredKubeconfigGrants(now time.Time) {
+	expired, err := getKubeconfigGrants(func(g KubeconfigGrant) bool { return !now.Before(g.ExpiresAt) })
+	if err != nil {
+		log.Printf("Error reading the kubeconfig grants: %v", err)
+		return
+	}
+	for _, grant := range expired {
+		if err := revokeKubeconfigGrant(grant); err != nil {
+			log.Printf("Error revoking the expired kubeconfig of %v in project %v on cluster %v: %v", grant.Username, grant.Project, grant.ClusterId, err)
+			continue
+		}
+		log.Printf("Revoked the expired kubeconfig of %v in project %v on cluster %v", grant.Username, grant.Project, grant.ClusterId)
+	}
+}
@@ -0,0 +1,133 @@
+package openshift
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ed25519"
+	"gopkg.in/yaml.v2"
+)
+
+func TestKubeconfigHandler(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("own", "u123")
+	api.AddProject("other", "u456")
+	config.Config().Set("kubeconfig.signing_key", base64.StdEncoding.EncodeToString(make([]byte, ed25519.SeedSize)))
+	defer config.Config().Set("kubeconfig.signing_key", "")
+	serviceAccount := kubeconfigServiceAccountName("u123")
+
+	download := func(body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/ose/kubeconfig", strings.NewReader(body))
+		c.Request.Header.Set(forwardedTokenHeader, token)
+		c.Set(gin.AuthUserKey, "u123")
+		kubeconfigHandler(c)
+		return w
+	}
+
+	w := download(`{"clusterid": "fake"}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v %v", w.Code, w.Body.String())
+	}
+	var k kubeconfig
+	if err := yaml.Unmarshal(w.Body.Bytes(), &k); err != nil {
+		t.Fatal(err)
+	}
+	if len(k.Contexts) != 1 || k.Contexts[0].Context.Namespace != "own" || k.CurrentContext != "own/fake" {
+		t.Errorf("expected a context for the own project, got %+v", k.Contexts)
+	}
+	if len(k.Users) != 1 || k.Users[0].User.Token == "" || k.Clusters[0].Cluster.Server == "" {
+		t.Errorf("expected a service account token and the cluster, got %+v %+v", k.Users, k.Clusters)
+	}
+	key := listRequest(getKubeconfigSigningKeyHandler, "/ose/kubeconfig/signingkey")
+	var response kubeconfigSigningKeyResponse
+	json.Unmarshal(key.Body.Bytes(), &response)
+	publicKey, _ := base64.StdEncoding.DecodeString(response.PublicKey)
+	signature, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(w.Header().Get(kubeconfigSignatureHeader), "ed25519="))
+	if len(publicKey) != ed25519.PublicKeySize || !ed25519.Verify(publicKey, w.Body.Bytes(), signature) {
+		t.Errorf("expected the kubeconfig to be signed, got %v %v", w.Header().Get(kubeconfigSignatureHeader), key.Body.String())
+	}
+	editors, _ := getRoleBindingUsers("fake", "own", "edit")
+	if !contains(editors, serviceAccountUserName("own", serviceAccount)) {
+		t.Errorf("expected the service account to be editor, got %v", editors)
+	}
+
+	w = download(`{"clusterid": "fake", "projects": ["own"], "mode": "user"}`, "user-token")
+	k = kubeconfig{}
+	if err := yaml.Unmarshal(w.Body.Bytes(), &k); err != nil || len(k.Users) != 1 || k.Users[0].User.Token != "user-token" {
+		t.Errorf("expected the user's token, got %v %v", w.Code, w.Body.String())
+	}
+
+	if w := download(`{"clusterid": "fake", "projects": ["other"]}`, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a project of another user, got %v", w.Code)
+	}
+	if w := download(`{"clusterid": "fake", "mode": "user"}`, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without the user's token, got %v", w.Code)
+	}
+}
+
+func TestKubeconfigServiceAccountName(t *testing.T) {
+	if name := kubeconfigServiceAccountName("Max.Muster@Example.com"); !strings.HasPrefix(name, "kubeconfig-max-muster-example-com-") || !serviceAccountNamePattern.MatchString(name) {
+		t.Errorf("unexpected name %v", name)
+	}
+	if name := kubeconfigServiceAccountName(strings.Repeat("a", 100)); len(name) != 63 || !serviceAccountNamePattern.MatchString(name) {
+		t.Errorf("expected a valid name of 63 characters, got %v", name)
+	}
+	if kubeconfigServiceAccountName("U123") != kubeconfigServiceAccountName("u123") {
+		t.Error("expected the name not to depend on the case")
+	}
+	for _, names := range [][2]string{{"max.muster", "max_muster"}, {strings.Repeat("a", 100) + "1", strings.Repeat("a", 100) + "2"}} {
+		if kubeconfigServiceAccountName(names[0]) == kubeconfigServiceAccountName(names[1]) {
+			t.Errorf("expected different service accounts for %v and %v", names[0], names[1])
+		}
+	}
+}
+
+func TestKubeconfigGrantsAreRevoked(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("own", "u123")
+	api.AddProject("shop", "u123")
+	serviceAccount := kubeconfigServiceAccountName("u123")
+	isEditor := func(project string) bool {
+		editors, _ := getRoleBindingUsers("fake", project, "edit")
+		return contains(editors, serviceAccountUserName(project, serviceAccount))
+	}
+
+	if _, err := createKubeconfig(&clusterUser{Name: "u123"}, "fake", []string{"own", "shop"}, kubeconfigModeServiceAccount); err != nil {
+		t.Fatal(err)
+	}
+	if !isEditor("own") || !isEditor("shop") {
+		t.Fatal("expected the service account to be editor of both projects")
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("DELETE", "/ose/kubeconfig", strings.NewReader(`{"clusterid": "fake", "projects": ["shop"]}`))
+	c.Set(gin.AuthUserKey, "u123")
+	revokeKubeconfigHandler(c)
+	if w.Code != http.StatusOK || isEditor("shop") || !isEditor("own") {
+		t.Errorf("expected only the role in shop to be revoked, got %v %v", w.Code, w.Body.String())
+	}
+
+	revokeExpiredKubeconfigGrants(time.Now())
+	if !isEditor("own") {
+		t.Error("expected the role to be kept until the token expires")
+	}
+	revokeExpiredKubeconfigGrants(time.Now().Add(time.Duration(kubeconfigTokenHours()) * time.Hour))
+	if isEditor("own") {
+		t.Error("expected the role to be removed after the token expired")
+	}
+	if grants, _ := getKubeconfigGrants(func(KubeconfigGrant) bool { return true }); len(grants) != 0 {
+		t.Errorf("expected no grants, got %+v", grants)
+	}
+}
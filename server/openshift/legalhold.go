@@ -0,0 +1,161 @@
+package openshift
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	legalHoldAnnotation             = "openshift.io/legal-hold"
+	legalHoldByAnnotation           = "openshift.io/legal-hold-by"
+	legalHoldSinceAnnotation        = "openshift.io/legal-hold-since"
+	testProjectDeletionAnnotation   = "openshift.io/testproject-daystodeletion"
+	legalHoldDeletionDaysAnnotation = "openshift.io/legal-hold-daystodeletion"
+	legalHoldAttemptsCollection     = "legal_hold_attempts"
+	legalHoldError                  = "Das Projekt %v steht unter Legal Hold und kann nicht verändert werden"
+	maxLegalHoldAttempts            = 100
+)
+
+// LegalHoldAttempt is a blocked action on a project under legal hold.
+// Repeated attempts of the same action, e.g. by the hourly janitors, are
+// counted instead of stored again
+type LegalHoldAttempt struct {
+	Action string     `json:"action"`
+	At     time.Time  `json:"at"`
+	Count  int        `json:"count,omitempty"`
+	LastAt *time.Time `json:"lastAt,omitempty"`
+}
+
+func placeLegalHoldHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.LegalHoldCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if data.ClusterId == "" || data.Project == "" || data.Reason == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Cluster, Projekt und Grund müssen angegeben werden"})
+		return
+	}
+
+	err := updateNamespaceAnnotations(data.ClusterId, data.Project, func(annotations *gabs.Container) {
+		annotations.Set(data.Reason, legalHoldAnnotation)
+		annotations.Set(username, legalHoldByAnnotation)
+		annotations.Set(time.Now().Format(time.RFC3339), legalHoldSinceAnnotation)
+		// Stop the deletion of test projects until the hold is lifted
+		if days, ok := annotations.S(testProjectDeletionAnnotation).Data().(string); ok {
+			annotations.Set(days, legalHoldDeletionDaysAnnotation)
+			annotations.Delete(testProjectDeletionAnnotation)
+		}
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v placed project %v on cluster %v under legal hold: %v", username, data.Project, data.ClusterId, data.Reason)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Das Projekt %v steht jetzt unter Legal Hold", data.Project),
+	})
+}
+
+func liftLegalHoldHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+	if clusterId == "" || project == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	err := updateNamespaceAnnotations(clusterId, project, func(annotations *gabs.Container) {
+		annotations.Delete(legalHoldAnnotation)
+		annotations.Delete(legalHoldByAnnotation)
+		annotations.Delete(legalHoldSinceAnnotation)
+		if days, ok := annotations.S(legalHoldDeletionDaysAnnotation).Data().(string); ok {
+			annotations.Set(days, testProjectDeletionAnnotation)
+			annotations.Delete(legalHoldDeletionDaysAnnotation)
+		}
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v lifted the legal hold of project %v on cluster %v", username, project, clusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Der Legal Hold für das Projekt %v wurde aufgehoben", project),
+	})
+}
+
+func getLegalHoldAttemptsHandler(c *gin.Context) {
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+	if clusterId == "" || project == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if _, err := getOpenshiftCluster(clusterId); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	attempts := []LegalHoldAttempt{}
+	if _, err := store.Get(legalHoldAttemptsCollection, clusterId+"/"+project, &attempts); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, attempts)
+}
+
+// checkLegalHold returns an error if the project is under legal hold. Blocked
+// attempts are logged and stored
+func checkLegalHold(clusterId, project, action string) error {
+	// Never decide on a cached namespace
+	common.GetCache().Delete(namespaceCacheKey(clusterId, project))
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return err
+	}
+	reason, ok := namespace.Path("metadata.annotations").S(legalHoldAnnotation).Data().(string)
+	if !ok {
+		return nil
+	}
+
+	log.Printf("WARNING: blocked %v of project %v on cluster %v because of legal hold: %v", action, project, clusterId, reason)
+	if err := recordLegalHoldAttempt(clusterId+"/"+project, action, time.Now()); err != nil {
+		log.Printf("Error saving legal hold attempt: %v", err)
+	}
+	return fmt.Errorf(legalHoldError, project)
+}
+
+// recordLegalHoldAttempt stores the attempt. A repetition of the last action
+// increases its count. Only the last maxLegalHoldAttempts are kept
+func recordLegalHoldAttempt(id, action string, at time.Time) error {
+	attempts := []LegalHoldAttempt{}
+	return store.Update(legalHoldAttemptsCollection, id, &attempts, func(exists bool) error {
+		if n := len(attempts); n > 0 && attempts[n-1].Action == action {
+			last := &attempts[n-1]
+			if last.Count == 0 {
+				last.Count = 1
+			}
+			last.Count++
+			last.LastAt = &at
+			return nil
+		}
+		attempts = append(attempts, LegalHoldAttempt{Action: action, At: at, Count: 1})
+		if len(attempts) > maxLegalHoldAttempts {
+			attempts = attempts[len(attempts)-maxLegalHoldAttempts:]
+		}
+		return nil
+	})
+}
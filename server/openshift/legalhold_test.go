@@ -0,0 +1,70 @@
+package openshift
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/gabs"
+)
+
+func TestCheckLegalHold(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("evidence", "u123")
+	api.AddProject("shop", "u123")
+	err := updateNamespaceAnnotations("fake", "evidence", func(annotations *gabs.Container) {
+		annotations.Set("Untersuchung", legalHoldAnnotation)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkLegalHold("fake", "shop", "deletion"); err != nil {
+		t.Errorf("expected projects without legal hold to be changeable, got %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := checkLegalHold("fake", "evidence", "deletion"); err == nil {
+			t.Fatal("expected the deletion to be blocked")
+		}
+	}
+	checkLegalHold("fake", "evidence", "quota change")
+
+	w := listRequest(getLegalHoldAttemptsHandler, "/ose/project/legalhold/attempts?clusterid=fake&project=evidence")
+	var attempts []LegalHoldAttempt
+	if err := json.Unmarshal(w.Body.Bytes(), &attempts); err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 2 || attempts[0].Action != "deletion" || attempts[0].Count != 3 || attempts[0].LastAt == nil || attempts[1].Count != 1 {
+		t.Errorf("expected the repeated deletions to be counted, got %+v", attempts)
+	}
+
+	for _, url := range []string{"/ose/project/legalhold/attempts?project=evidence", "/ose/project/legalhold/attempts?clusterid=unknown&project=evidence"} {
+		if w := listRequest(getLegalHoldAttemptsHandler, url); w.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected bad request, got %v", url, w.Code)
+		}
+	}
+}
+
+func TestRecordLegalHoldAttemptIsCapped(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+
+	at := time.Now()
+	for i := 0; i < maxLegalHoldAttempts+10; i++ {
+		action := "deletion"
+		if i%2 == 1 {
+			action = "quota change"
+		}
+		if err := recordLegalHoldAttempt("fake/evidence", action, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := listRequest(getLegalHoldAttemptsHandler, "/ose/project/legalhold/attempts?clusterid=fake&project=evidence")
+	var attempts []LegalHoldAttempt
+	json.Unmarshal(w.Body.Bytes(), &attempts)
+	if len(attempts) != maxLegalHoldAttempts {
+		t.Errorf("expected %v attempts, got %v", maxLegalHoldAttempts, len(attempts))
+	}
+}
@@ -0,0 +1,193 @@
+package openshift
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	cpuQuantityPattern    = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?m?$`)
+	memoryQuantityPattern = regexp.MustCompile(`^[0-9]+(Mi|Gi)$`)
+)
+
+func getLimitRangeHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	_, _, limitRange, err := limitRangeRequest(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	container := containerLimits(limitRange)
+	response := common.LimitRangeResponse{}
+	response.DefaultCPU, _ = container.Path("default.cpu").Data().(string)
+	response.DefaultMemory, _ = container.Path("default.memory").Data().(string)
+	response.DefaultRequestCPU, _ = container.Path("defaultRequest.cpu").Data().(string)
+	response.DefaultRequestMemory, _ = container.Path("defaultRequest.memory").Data().(string)
+	response.MaxCPU, response.MaxMemory = limitRangeMaxima()
+	c.JSON(http.StatusOK, response)
+}
+
+func updateLimitRangeHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.LimitRangeCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err := validateLimitRange(data.LimitRange); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	method, url, limitRange, err := limitRangeRequest(data.ClusterId, data.Project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	setContainerLimits(limitRange, data.LimitRange)
+
+	if common.IsDryRun(c) {
+		if err := dryRunOnCluster(data.ClusterId, method, url, limitRange); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		action := common.DryRunActionUpdate
+		if method == "POST" {
+			action = common.DryRunActionCreate
+		}
+		name, _ := limitRange.Path("metadata.name").Data().(string)
+		common.RespondDryRun(c, "Die Standardwerte der Container würden geändert", common.PlannedChange{
+			Action: action, Kind: "LimitRange", Name: name, ClusterId: data.ClusterId, Project: data.Project,
+			Details: limitRangeDetails(data.LimitRange),
+		})
+		return
+	}
+
+	resp, err := getOseHTTPClient(method, data.ClusterId, url, bytes.NewReader(limitRange.Bytes()))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		common.Logger(c).Println("Error updating limitRange:", resp.StatusCode, string(errMsg))
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericAPIError})
+		return
+	}
+
+	audit.Describe(c, "%v changed the limitrange of project %v on cluster %v: %v", username, data.Project, data.ClusterId, limitRangeDetails(data.LimitRange))
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Die Standardwerte der Container wurden gespeichert: %v", limitRangeDetails(data.LimitRange)),
+	})
+}
+
+// limitRangeMaxima are the highest defaults in cores and Gi a project admin
+// can set
+func limitRangeMaxima() (float64, float64) {
+	cfg := config.Config()
+	return cfg.GetFloat64("limit_range.max_cpu"), cfg.GetFloat64("limit_range.max_memory")
+}
+
+func validateLimitRange(limits common.LimitRange) error {
+	maxCPU, maxMemory := limitRangeMaxima()
+	if maxCPU == 0 || maxMemory == 0 {
+		log.Println("WARNING: Env variables 'LIMIT_RANGE_MAX_CPU' and 'LIMIT_RANGE_MAX_MEMORY' must be specified")
+		return errors.New(common.ConfigNotSetError)
+	}
+
+	for _, cpu := range []string{limits.DefaultCPU, limits.DefaultRequestCPU} {
+		if !cpuQuantityPattern.MatchString(cpu) || parseCPUQuantity(cpu) <= 0 {
+			return fmt.Errorf("Ungültige CPU '%v'. Format muss Kerne (z.B. 1) oder Millicores (z.B. 500m) sein", cpu)
+		}
+	}
+	for _, memory := range []string{limits.DefaultMemory, limits.DefaultRequestMemory} {
+		if !memoryQuantityPattern.MatchString(memory) || parseMemoryQuantityGi(memory) <= 0 {
+			return fmt.Errorf("Ungültiges Memory '%v'. Format muss Zahl gefolgt von Mi/Gi sein (z.B. 512Mi)", memory)
+		}
+	}
+
+	if parseCPUQuantity(limits.DefaultRequestCPU) > parseCPUQuantity(limits.DefaultCPU) {
+		return errors.New("Der CPU Request darf nicht grösser als das Limit sein")
+	}
+	if parseMemoryQuantityGi(limits.DefaultRequestMemory) > parseMemoryQuantityGi(limits.DefaultMemory) {
+		return errors.New("Der Memory Request darf nicht grösser als das Limit sein")
+	}
+	if parseCPUQuantity(limits.DefaultCPU) > maxCPU {
+		return fmt.Errorf("Der Maximalwert für CPU ist: %v", maxCPU)
+	}
+	if parseMemoryQuantityGi(limits.DefaultMemory) > maxMemory {
+		return fmt.Errorf("Der Maximalwert für Memory ist: %vGi", maxMemory)
+	}
+	return nil
+}
+
+// limitRangeRequest returns the method, url and first limitrange of the
+// project or a new one if the project has none
+func limitRangeRequest(clusterId, project string) (string, string, *gabs.Container, error) {
+	limitRanges, err := listObjects(clusterId, "api/v1/namespaces/"+project+"/limitranges")
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	url := "api/v1/namespaces/" + project + "/limitranges"
+	if len(limitRanges) == 0 {
+		return "POST", url, newObjectRequest("LimitRange", "default"), nil
+	}
+	name, _ := limitRanges[0].Path("metadata.name").Data().(string)
+	return "PUT", url + "/" + name, limitRanges[0], nil
+}
+
+// containerLimits returns the limits of the type Container
+func containerLimits(limitRange *gabs.Container) *gabs.Container {
+	limits, _ := limitRange.Path("spec.limits").Children()
+	for _, l := range limits {
+		if l.Path("type").Data() == "Container" {
+			return l
+		}
+	}
+	return gabs.New()
+}
+
+// setContainerLimits changes the defaults of the containers. The other
+// limits, e.g. of pods, are kept
+func setContainerLimits(limitRange *gabs.Container, limits common.LimitRange) {
+	container := containerLimits(limitRange)
+	container.SetP(limits.DefaultCPU, "default.cpu")
+	container.SetP(limits.DefaultMemory, "default.memory")
+	container.SetP(limits.DefaultRequestCPU, "defaultRequest.cpu")
+	container.SetP(limits.DefaultRequestMemory, "defaultRequest.memory")
+	if container.Path("type").Data() == nil {
+		container.Set("Container", "type")
+		limitRange.ArrayAppendP(container.Data(), "spec.limits")
+	}
+}
+
+func limitRangeDetails(limits common.LimitRange) string {
+	return fmt.Sprintf("CPU: %v/%v, Memory: %v/%v (Request/Limit)",
+		limits.DefaultRequestCPU, limits.DefaultCPU, limits.DefaultRequestMemory, limits.DefaultMemory)
+}
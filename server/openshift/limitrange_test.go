@@ -0,0 +1,67 @@
+package openshift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateLimitRange(t *testing.T) {
+	config.Init("test")
+	config.Config().Set("limit_range.max_cpu", 2)
+	config.Config().Set("limit_range.max_memory", 4)
+
+	tests := []struct {
+		limits common.LimitRange
+		valid  bool
+	}{
+		{common.LimitRange{DefaultCPU: "1", DefaultMemory: "1Gi", DefaultRequestCPU: "100m", DefaultRequestMemory: "256Mi"}, true},
+		{common.LimitRange{DefaultCPU: "2", DefaultMemory: "4Gi", DefaultRequestCPU: "2", DefaultRequestMemory: "4Gi"}, true},
+		{common.LimitRange{DefaultCPU: "3", DefaultMemory: "1Gi", DefaultRequestCPU: "100m", DefaultRequestMemory: "256Mi"}, false},
+		{common.LimitRange{DefaultCPU: "1", DefaultMemory: "5Gi", DefaultRequestCPU: "100m", DefaultRequestMemory: "256Mi"}, false},
+		{common.LimitRange{DefaultCPU: "500m", DefaultMemory: "1Gi", DefaultRequestCPU: "1", DefaultRequestMemory: "256Mi"}, false},
+		{common.LimitRange{DefaultCPU: "1", DefaultMemory: "1G", DefaultRequestCPU: "100m", DefaultRequestMemory: "256Mi"}, false},
+		{common.LimitRange{DefaultCPU: "0", DefaultMemory: "1Gi", DefaultRequestCPU: "0", DefaultRequestMemory: "256Mi"}, false},
+	}
+	for _, test := range tests {
+		if err := validateLimitRange(test.limits); (err == nil) != test.valid {
+			t.Errorf("%+v: expected valid %v, got %v", test.limits, test.valid, err)
+		}
+	}
+}
+
+func TestUpdateLimitRangeHandler(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	config.Config().Set("limit_range.max_cpu", 2)
+	config.Config().Set("limit_range.max_memory", 4)
+	api.AddProject("own", "u123")
+	existing, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "limits"}, "spec": {"limits": [{"type": "Pod", "max": {"cpu": "4"}}]}}`))
+	api.Set("api/v1/namespaces/own/limitranges/limits", existing)
+
+	body := `{"clusterid": "fake", "project": "own", "defaultCpu": "1", "defaultMemory": "1Gi", "defaultRequestCpu": "200m", "defaultRequestMemory": "512Mi"}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("PUT", "/ose/limitrange", strings.NewReader(body))
+	c.Set(gin.AuthUserKey, "u123")
+	updateLimitRangeHandler(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v %v", w.Code, w.Body.String())
+	}
+
+	limitRange, _ := api.Get("api/v1/namespaces/own/limitranges/limits")
+	limits, _ := limitRange.Path("spec.limits").Children()
+	if len(limits) != 2 {
+		t.Fatalf("expected the pod limits to be kept and container limits to be added, got %v", limitRange)
+	}
+	container := containerLimits(limitRange)
+	if container.Path("default.cpu").Data() != "1" || container.Path("defaultRequest.memory").Data() != "512Mi" {
+		t.Errorf("expected the new container defaults, got %v", container)
+	}
+}
@@ -0,0 +1,309 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	loadTestWindowsCollection = "load_test_windows"
+	defaultLoadTestMaxHours   = 72
+
+	loadTestStatePlanned  = "planned"
+	loadTestStateActive   = "active"
+	loadTestStateDone     = "done"
+	loadTestStateFailed   = "failed"
+	loadTestStateCanceled = "canceled"
+)
+
+// LoadTestWindow boosts the quota of a project during a load test. The
+// previous quota is restored afterwards and cost anomalies of the billing
+// account in the months of the window aren't mailed
+type LoadTestWindow struct {
+	ID             string    `json:"id"`
+	ClusterId      string    `json:"clusterid"`
+	Project        string    `json:"project"`
+	Billing        string    `json:"billing"`
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	CPU            int       `json:"cpu"`
+	Memory         int       `json:"memory"`
+	PreviousCPU    int       `json:"previousCpu,omitempty"`
+	PreviousMemory int       `json:"previousMemory,omitempty"`
+	State          string    `json:"state"`
+	Message        string    `json:"message,omitempty"`
+	RequestedBy    string    `json:"requestedBy"`
+}
+
+func loadTestWindowID(clusterId, project string, start time.Time) string {
+	return fmt.Sprintf("%v/%v/%v", clusterId, project, start.Unix())
+}
+
+func getLoadTestWindowsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	windows, err := getLoadTestWindows(func(w LoadTestWindow) bool {
+		return w.ClusterId == clusterId && w.Project == project
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, windows)
+}
+
+func newLoadTestWindowHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.LoadTestWindowCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateLoadTestWindow(username, data, common.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	namespace, err := getNamespace(data.ClusterId, data.Project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	window := LoadTestWindow{
+		ID:          loadTestWindowID(data.ClusterId, data.Project, data.Start),
+		ClusterId:   data.ClusterId,
+		Project:     data.Project,
+		Billing:     getAnnotation(namespace.Path("metadata.annotations"), annotationBilling),
+		Start:       data.Start,
+		End:         data.End,
+		CPU:         data.CPU,
+		Memory:      data.Memory,
+		State:       loadTestStatePlanned,
+		RequestedBy: username,
+	}
+	if err := store.Put(loadTestWindowsCollection, window.ID, window); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v registered a load test window for project %v on cluster %v from %v to %v", username, data.Project, data.ClusterId, data.Start, data.End)
+
+	// A window starting now is applied immediately
+	applyLoadTestWindows(common.Now())
+	c.JSON(http.StatusOK, window)
+}
+
+func cancelLoadTestWindowHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var window LoadTestWindow
+	found, err := store.Get(loadTestWindowsCollection, c.Query("id"), &window)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Das Lasttest-Fenster existiert nicht"})
+		return
+	}
+	if err := validateAdminAccess(window.ClusterId, username, window.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := cancelLoadTestWindow(window); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v canceled the load test window %v", username, window.ID)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: "Das Lasttest-Fenster wurde abgebrochen"})
+}
+
+// loadTestMaxima returns the maximal boosted quota. Defaults to the maximal
+// quota of the project admins
+func loadTestMaxima() (cpu, memory int) {
+	cfg := config.Config()
+	if cpu = cfg.GetInt("load_test.max_cpu"); cpu <= 0 {
+		cpu = cfg.GetInt("max_quota_cpu")
+	}
+	if memory = cfg.GetInt("load_test.max_memory"); memory <= 0 {
+		memory = cfg.GetInt("max_quota_memory")
+	}
+	return cpu, memory
+}
+
+func validateLoadTestWindow(username string, data common.LoadTestWindowCommand, now time.Time) error {
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		return err
+	}
+
+	maxHours := config.Config().GetInt("load_test.max_hours")
+	if maxHours <= 0 {
+		maxHours = defaultLoadTestMaxHours
+	}
+	if !data.End.After(data.Start) || data.End.Before(now) {
+		return errors.New("Das Ende des Lasttests muss nach dem Start und in der Zukunft liegen")
+	}
+	if data.End.Sub(data.Start) > time.Duration(maxHours)*time.Hour {
+		return fmt.Errorf("Ein Lasttest darf höchstens %v Stunden dauern", maxHours)
+	}
+
+	maxCPU, maxMemory := loadTestMaxima()
+	if data.CPU < 1 || data.Memory < 1 {
+		return errors.New("CPU und Memory müssen mindestens 1 sein")
+	}
+	if data.CPU > maxCPU || data.Memory > maxMemory {
+		return fmt.Errorf("Die Maximalwerte für einen Lasttest sind CPU: %v, Memory: %v", maxCPU, maxMemory)
+	}
+
+	overlapping, err := getLoadTestWindows(func(w LoadTestWindow) bool {
+		return w.ClusterId == data.ClusterId && w.Project == data.Project &&
+			(w.State == loadTestStatePlanned || w.State == loadTestStateActive) &&
+			w.Start.Before(data.End) && data.Start.Before(w.End)
+	})
+	if err != nil {
+		return err
+	}
+	if len(overlapping) > 0 {
+		return fmt.Errorf("Es gibt bereits einen Lasttest von %v bis %v", overlapping[0].Start.In(common.Location()).Format("02.01.2006 15:04"), overlapping[0].End.In(common.Location()).Format("02.01.2006 15:04"))
+	}
+	return nil
+}
+
+func getLoadTestWindows(filter func(LoadTestWindow) bool) ([]LoadTestWindow, error) {
+	windows := []LoadTestWindow{}
+	err := store.List(loadTestWindowsCollection, func(id string, data []byte) error {
+		var w LoadTestWindow
+		if err := json.Unmarshal(data, &w); err != nil {
+			return err
+		}
+		if filter(w) {
+			windows = append(windows, w)
+		}
+		return nil
+	})
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].Start.Before(windows[j].Start)
+	})
+	return windows, err
+}
+
+// StartLoadTestWindows boosts and restores the quotas of the load test
+// windows every five minutes
+func StartLoadTestWindows() {
+	go func() {
+		for {
+			applyLoadTestWindows(common.Now())
+			time.Sleep(5 * time.Minute)
+		}
+	}()
+}
+
+func applyLoadTestWindows(now time.Time) {
+	windows, err := getLoadTestWindows(func(w LoadTestWindow) bool {
+		return w.State == loadTestStatePlanned || w.State == loadTestStateActive
+	})
+	if err != nil {
+		log.Printf("Error reading the load test windows: %v", err)
+		return
+	}
+
+	for _, w := range windows {
+		switch {
+		case !w.End.After(now):
+			if w.State == loadTestStateActive {
+				if err := restoreLoadTestQuota(&w); err != nil {
+					log.Printf("Error restoring the quota after load test %v: %v", w.ID, err)
+					continue
+				}
+			}
+			w.State = loadTestStateDone
+		case w.State == loadTestStatePlanned && !w.Start.After(now):
+			if err := boostLoadTestQuota(&w); err != nil {
+				log.Printf("Error boosting the quota for load test %v: %v", w.ID, err)
+				w.State = loadTestStateFailed
+				w.Message = err.Error()
+			}
+		default:
+			continue
+		}
+		if err := store.Put(loadTestWindowsCollection, w.ID, w); err != nil {
+			log.Printf("Error saving load test window %v: %v", w.ID, err)
+		}
+	}
+}
+
+// boostLoadTestQuota remembers the quota of the project and sets the one of
+// the load test
+func boostLoadTestQuota(w *LoadTestWindow) error {
+	quotas, err := getQuotas(w.ClusterId, w.Project)
+	if err != nil {
+		return err
+	}
+	if quotas.CPU == 0 || quotas.Memory == 0 {
+		return errors.New("Das Projekt hat keine Quota")
+	}
+	w.PreviousCPU = int(math.Ceil(quotas.CPU))
+	w.PreviousMemory = int(math.Ceil(quotas.Memory))
+	if err := setQuota(w.ClusterId, w.Project, w.CPU, w.Memory); err != nil {
+		return err
+	}
+	w.State = loadTestStateActive
+	log.Printf("Boosted the quota of project %v on cluster %v for load test %v. CPU: %v Mem: %v", w.Project, w.ClusterId, w.ID, w.CPU, w.Memory)
+	return nil
+}
+
+func restoreLoadTestQuota(w *LoadTestWindow) error {
+	if err := setQuota(w.ClusterId, w.Project, w.PreviousCPU, w.PreviousMemory); err != nil {
+		return err
+	}
+	log.Printf("Restored the quota of project %v on cluster %v after load test %v. CPU: %v Mem: %v", w.Project, w.ClusterId, w.ID, w.PreviousCPU, w.PreviousMemory)
+	return nil
+}
+
+func cancelLoadTestWindow(w LoadTestWindow) error {
+	switch w.State {
+	case loadTestStateActive:
+		if err := restoreLoadTestQuota(&w); err != nil {
+			return err
+		}
+	case loadTestStatePlanned:
+	default:
+		return errors.New("Der Lasttest ist bereits beendet")
+	}
+	w.State = loadTestStateCanceled
+	return store.Put(loadTestWindowsCollection, w.ID, w)
+}
+
+// inLoadTestWindow returns true if a project of the billing account had a
+// load test in the month
+func inLoadTestWindow(billing string, month time.Time) bool {
+	end := month.AddDate(0, 1, 0)
+	windows, err := getLoadTestWindows(func(w LoadTestWindow) bool {
+		return w.Billing == billing && w.State != loadTestStateCanceled && w.State != loadTestStateFailed &&
+			w.Start.Before(end) && w.End.After(month)
+	})
+	if err != nil {
+		log.Printf("Error reading the load test windows: %v", err)
+		return false
+	}
+	return len(windows) > 0
+}
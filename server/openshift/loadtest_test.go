@@ -0,0 +1,71 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+)
+
+func TestLoadTestWindow(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+	config.Config().Set("max_quota_cpu", 8)
+	config.Config().Set("max_quota_memory", 16)
+	config.Config().Set("load_test.max_cpu", 32)
+	if err := createNewProject(nil, "fake", "own", "u123", "12345", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := setQuota("fake", "own", 2, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2019, 3, 4, 10, 0, 0, 0, time.UTC)
+	data := common.LoadTestWindowCommand{
+		OpenshiftBase: common.OpenshiftBase{ClusterId: "fake", Project: "own"},
+		Start:         now.Add(time.Hour),
+		End:           now.Add(5 * time.Hour),
+		CPU:           24,
+		Memory:        16,
+	}
+	if err := validateLoadTestWindow("u123", data, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	window := LoadTestWindow{ID: "w1", ClusterId: "fake", Project: "own", Billing: "12345", Start: data.Start, End: data.End, CPU: data.CPU, Memory: data.Memory, State: loadTestStatePlanned}
+	store.Put(loadTestWindowsCollection, window.ID, window)
+
+	data.Start, data.End = now.Add(4*time.Hour), now.Add(8*time.Hour)
+	if err := validateLoadTestWindow("u123", data, now); err == nil {
+		t.Error("expected an error for an overlapping window")
+	}
+	data.Start, data.End = now.Add(6*time.Hour), now.Add(80*time.Hour)
+	if err := validateLoadTestWindow("u123", data, now); err == nil {
+		t.Error("expected an error for a window longer than 72 hours")
+	}
+
+	quotaOf := func() (float64, float64) {
+		quotas, err := getQuotas("fake", "own")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return quotas.CPU, quotas.Memory
+	}
+	applyLoadTestWindows(now.Add(2 * time.Hour))
+	if cpu, memory := quotaOf(); cpu != 24 || memory != 16 {
+		t.Errorf("expected the boosted quota during the window, got %v %v", cpu, memory)
+	}
+	if !inLoadTestWindow("12345", time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)) || inLoadTestWindow("12345", time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("expected cost anomalies to be muted only in the month of the load test")
+	}
+
+	applyLoadTestWindows(now.Add(6 * time.Hour))
+	if cpu, memory := quotaOf(); cpu != 2 || memory != 4 {
+		t.Errorf("expected the previous quota after the window, got %v %v", cpu, memory)
+	}
+	store.Get(loadTestWindowsCollection, "w1", &window)
+	if window.State != loadTestStateDone {
+		t.Errorf("expected the window to be done, got %v", window.State)
+	}
+}
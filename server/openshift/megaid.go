@@ -0,0 +1,230 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const megaIdHistoryCollection = "megaid_history"
+
+// megaIdPattern are the characters an annotation value of a MEGA ID may have
+var megaIdPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$`)
+
+// MegaIdChange is an entry of the history of the MEGA ID of a project
+type MegaIdChange struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	By   string    `json:"by"`
+	At   time.Time `json:"at"`
+}
+
+type MegaIdResponse struct {
+	MegaId  string         `json:"megaId"`
+	History []MegaIdChange `json:"history"`
+}
+
+func megaIdHistoryID(clusterId, project string) string {
+	return clusterId + "/" + project
+}
+
+func getMegaIdHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	history, err := getMegaIdHistory(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, MegaIdResponse{
+		MegaId:  getAnnotation(namespace.Path("metadata.annotations"), annotationMegaId),
+		History: history,
+	})
+}
+
+func updateMegaIdHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.MegaIdCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	data.MegaId = strings.TrimSpace(data.MegaId)
+	if err := validateMegaId(data.MegaId); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if common.IsDryRun(c) {
+		changes, err := previewAnnotations(data.ClusterId, data.Project, func(annotations *gabs.Container) {
+			setMegaIdAnnotation(annotations, data.MegaId)
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		common.RespondDryRun(c, fmt.Sprintf("Die MEGA ID von Projekt %v würde geändert", data.Project), changes...)
+		return
+	}
+
+	if err := setMegaId(data.ClusterId, data.Project, data.MegaId, username); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	message := fmt.Sprintf("Die MEGA ID von Projekt %v wurde auf %v gesetzt", data.Project, data.MegaId)
+	if data.MegaId == "" {
+		message = fmt.Sprintf("Die MEGA ID von Projekt %v wurde entfernt", data.Project)
+	}
+	c.JSON(http.StatusOK, common.ApiResponse{Message: message})
+}
+
+// validateMegaId accepts an empty MEGA ID to clear it. Otherwise it must
+// match 'megaid.pattern' and, if 'megaid.api_url' is set, exist in the MEGA
+// architecture repository. Unknown MEGA IDs are only rejected with
+// 'megaid.strict'
+func validateMegaId(megaId string) error {
+	if megaId == "" {
+		return nil
+	}
+	if !megaIdPattern.MatchString(megaId) {
+		return errors.New("Die MEGA ID darf nur Buchstaben, Zahlen, '.', '-' und '_' enthalten und höchstens 63 Zeichen lang sein")
+	}
+
+	cfg := config.Config()
+	if pattern := cfg.GetString("megaid.pattern"); pattern != "" {
+		format, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("Invalid megaid.pattern %v: %v", pattern, err)
+			return errors.New(genericAPIError)
+		}
+		if !format.MatchString(megaId) {
+			return fmt.Errorf("Die MEGA ID %v hat nicht das erwartete Format", megaId)
+		}
+	}
+
+	strict := cfg.GetBool("megaid.strict")
+	exists, err := lookupMegaId(megaId)
+	if err != nil {
+		log.Printf("Can't look up MEGA ID %v: %v", megaId, err)
+		if strict {
+			return errors.New("Die MEGA ID konnte nicht im MEGA-Repository geprüft werden. Bitte versuche es später nochmals")
+		}
+		return nil
+	}
+	if !exists {
+		log.Printf("WARNING: the MEGA ID %v doesn't exist in the MEGA repository", megaId)
+		if strict {
+			return fmt.Errorf("Die MEGA ID %v existiert nicht im MEGA-Repository", megaId)
+		}
+	}
+	return nil
+}
+
+// lookupMegaId checks if the application exists in the MEGA architecture
+// repository. The api returns 200 for known and 404 for unknown ids. Without
+// 'megaid.api_url' every MEGA ID exists
+func lookupMegaId(megaId string) (bool, error) {
+	cfg := config.Config()
+	apiURL := cfg.GetString("megaid.api_url")
+	if apiURL == "" {
+		return true, nil
+	}
+
+	req, err := http.NewRequest("GET", strings.TrimSuffix(apiURL, "/")+"/"+url.PathEscape(megaId), nil)
+	if err != nil {
+		return false, err
+	}
+	if token := cfg.GetString("megaid.api_token"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	client := common.HTTPClient("mega")
+	client.Timeout = 10 * time.Second
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected status %v of the MEGA api", resp.StatusCode)
+}
+
+// setMegaId writes or removes the MEGA ID annotation and records the change.
+// The other annotations of the namespace are not changed
+func setMegaId(clusterId, project, megaId, username string) error {
+	var previous string
+	err := updateNamespaceAnnotations(clusterId, project, func(annotations *gabs.Container) {
+		previous = getAnnotation(annotations, annotationMegaId)
+		setMegaIdAnnotation(annotations, megaId)
+	})
+	if err != nil {
+		return err
+	}
+	if previous == megaId {
+		return nil
+	}
+
+	history, err := getMegaIdHistory(clusterId, project)
+	if err != nil {
+		return err
+	}
+	history = append(history, MegaIdChange{From: previous, To: megaId, By: username, At: common.Now()})
+	if err := store.Put(megaIdHistoryCollection, megaIdHistoryID(clusterId, project), history); err != nil {
+		return err
+	}
+	log.Printf("%v changed the MEGA ID of project %v on cluster %v from '%v' to '%v'", username, project, clusterId, previous, megaId)
+	return nil
+}
+
+func setMegaIdAnnotation(annotations *gabs.Container, megaId string) {
+	if megaId == "" {
+		annotations.Delete(annotationKey(annotationMegaId))
+		for _, key := range legacyAnnotationKeys(annotationMegaId) {
+			annotations.Delete(key)
+		}
+		return
+	}
+	setAnnotation(annotations, annotationMegaId, megaId)
+}
+
+func getMegaIdHistory(clusterId, project string) ([]MegaIdChange, error) {
+	history := []MegaIdChange{}
+	if _, err := store.Get(megaIdHistoryCollection, megaIdHistoryID(clusterId, project), &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
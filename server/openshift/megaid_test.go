@@ -0,0 +1,81 @@
+package openshift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestSetMegaId(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+
+	if err := createNewProject(nil, "fake", "test-project", "u123", "12345", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := setMegaId("fake", "test-project", "ABC-1", "u123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := setMegaId("fake", "test-project", "", "u456"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	namespace, _ := api.Get("api/v1/namespaces/test-project")
+	annotations := namespace.Path("metadata.annotations")
+	if annotations.Exists(annotationKey(annotationMegaId)) {
+		t.Error("expected the MEGA ID to be removed")
+	}
+	if billing := getAnnotation(annotations, annotationBilling); billing != "12345" {
+		t.Errorf("expected the billing to stay, got %v", billing)
+	}
+
+	history, _ := getMegaIdHistory("fake", "test-project")
+	if len(history) != 2 || history[0].To != "ABC-1" || history[1].From != "ABC-1" || history[1].By != "u456" {
+		t.Errorf("unexpected history %+v", history)
+	}
+}
+
+func TestValidateMegaId(t *testing.T) {
+	for megaId, valid := range map[string]bool{"": true, "ABC-1": true, "a.b_c": true, "with space": false, "-abc": false} {
+		if err := validateMegaId(megaId); (err == nil) != valid {
+			t.Errorf("validateMegaId(%q): expected valid=%v, got %v", megaId, valid, err)
+		}
+	}
+}
+
+func TestLookupMegaId(t *testing.T) {
+	mega := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/applications/APP-0001" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mega.Close()
+	config.Init("test")
+	cfg := config.Config()
+	cfg.Set("megaid.pattern", `^APP-[0-9]{4}$`)
+	cfg.Set("megaid.api_url", mega.URL+"/applications/")
+
+	tests := []struct {
+		megaId string
+		strict bool
+		valid  bool
+	}{
+		{"APP-0001", true, true},
+		{"APP-1", false, false},
+		{"APP-0002", false, true},
+		{"APP-0002", true, false},
+	}
+	for _, test := range tests {
+		cfg.Set("megaid.strict", test.strict)
+		if err := validateMegaId(test.megaId); (err == nil) != test.valid {
+			t.Errorf("validateMegaId(%q) with strict=%v: expected valid=%v, got %v", test.megaId, test.strict, test.valid, err)
+		}
+	}
+
+	cfg.Set("megaid.api_url", "http://localhost:1")
+	if err := validateMegaId("APP-0001"); err == nil {
+		t.Error("expected an unreachable MEGA api to reject the MEGA ID in strict mode")
+	}
+}
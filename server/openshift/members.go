@@ -0,0 +1,134 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const lastAdminError = "Der letzte Admin kann nicht entfernt werden. Füge zuerst einen weiteren Admin hinzu"
+
+func getProjectMembersHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	members, err := getProjectMembers(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+func addProjectMemberHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	data, err := bindProjectMember(c, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	user := clusterUserFromContext(c)
+	if data.Role == "admin" {
+		err = changeProjectPermission(user, data.ClusterId, data.Project, data.User)
+	} else {
+		err = addUsersToRoleBindingAs(user, data.ClusterId, data.Project, data.Role, []string{data.User})
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v gave %v the role %v in project %v on cluster %v", username, data.User, data.Role, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("%v hat jetzt die Rolle %v im Projekt %v", data.User, data.Role, data.Project),
+	})
+}
+
+func removeProjectMemberHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	data, err := bindProjectMember(c, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := removeProjectMember(clusterUserFromContext(c), data.ClusterId, data.Project, data.Role, data.User); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v removed the role %v of %v in project %v on cluster %v", username, data.Role, data.User, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("%v hat die Rolle %v im Projekt %v nicht mehr", data.User, data.Role, data.Project),
+	})
+}
+
+// bindProjectMember reads and validates the command. Only admins of the
+// project can change its members
+func bindProjectMember(c *gin.Context, username string) (*common.ProjectMemberCommand, error) {
+	var data common.ProjectMemberCommand
+	if c.BindJSON(&data) != nil {
+		return nil, errors.New(wrongAPIUsageError)
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		return nil, err
+	}
+	data.User = strings.ToLower(strings.TrimSpace(data.User))
+	if data.User == "" {
+		return nil, errors.New("Benutzer muss angegeben werden")
+	}
+	if !contains(memberRoles, data.Role) {
+		return nil, fmt.Errorf("Die Rolle muss eine von %v sein", strings.Join(memberRoles, ", "))
+	}
+	return &data, nil
+}
+
+func getProjectMembers(clusterId, project string) (*common.ProjectMembersResponse, error) {
+	members := &common.ProjectMembersResponse{}
+	var err error
+	if members.Admins, err = getRoleBindingUsers(clusterId, project, "admin"); err != nil {
+		return nil, err
+	}
+	if members.Editors, err = getRoleBindingUsers(clusterId, project, "edit"); err != nil {
+		return nil, err
+	}
+	if members.Viewers, err = getRoleBindingUsers(clusterId, project, "view"); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// removeProjectMember revokes the role of the member on behalf of user (nil
+// for the portal). A project always keeps at least one admin
+func removeProjectMember(user *clusterUser, clusterId, project, role, member string) error {
+	if role == "admin" {
+		// Never decide on a cached rolebinding
+		common.GetCache().Delete(roleBindingCacheKey(clusterId, project))
+		admins, err := getRoleBindingUsers(clusterId, project, "admin")
+		if err != nil {
+			return err
+		}
+		remaining := 0
+		for _, a := range admins {
+			if a != member && !strings.HasPrefix(a, "system:") {
+				remaining++
+			}
+		}
+		if remaining == 0 {
+			return errors.New(lastAdminError)
+		}
+	}
+	return removeUsersFromRoleBindingAs(user, clusterId, project, role, []string{member})
+}
@@ -0,0 +1,36 @@
+package openshift
+
+import "testing"
+
+func TestRemoveProjectMember(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+	if err := createNewProject(nil, "fake", "own", "u123", "12345", "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removeProjectMember(nil, "fake", "own", "admin", "u123"); err == nil || err.Error() != lastAdminError {
+		t.Errorf("expected the last admin not to be removable, got %v", err)
+	}
+
+	if err := changeProjectPermission(nil, "fake", "own", "u456"); err != nil {
+		t.Fatal(err)
+	}
+	if err := addUsersToRoleBinding("fake", "own", "view", []string{"u789"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeProjectMember(nil, "fake", "own", "admin", "u123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	members, err := getProjectMembers("fake", "own")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !contains(members.Admins, "u456") || contains(members.Admins, "u123") {
+		t.Errorf("expected u456 to replace u123 as admin, got %v", members.Admins)
+	}
+	if len(members.Editors) != 0 || len(members.Viewers) != 1 || members.Viewers[0] != "u789" {
+		t.Errorf("expected u789 to be the only viewer, got %+v", members)
+	}
+}
@@ -0,0 +1,65 @@
+package openshift
+
+import (
+	"net/http"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// portalAnnotationKeys are the annotations written by the portal
+func portalAnnotationKeys() []string {
+	keys := []string{
+		legalHoldAnnotation,
+		legalHoldByAnnotation,
+		legalHoldSinceAnnotation,
+		legalHoldDeletionDaysAnnotation,
+		testProjectDeletionAnnotation,
+		testProjectExpiresAnnotation,
+		testProjectWarnedAnnotation,
+		recycleBinAnnotation,
+		sandboxExpiresAnnotation,
+	}
+	for name := range defaultAnnotationKeys {
+		keys = append(keys, annotationKey(name))
+		keys = append(keys, legacyAnnotationKeys(name)...)
+	}
+	return keys
+}
+
+func getProjectMetadata(clusterId, project string) (*ProjectMetadata, error) {
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+	metadata := newProjectMetadata(clusterId, namespace)
+	annotations := namespace.Path("metadata.annotations")
+	metadata.Contact = getAnnotation(annotations, annotationContact)
+	metadata.Annotations = make(map[string]string)
+	values := annotationValues(annotations)
+	for _, key := range portalAnnotationKeys() {
+		if value, ok := values[key]; ok {
+			metadata.Annotations[key] = value
+		}
+	}
+	return &metadata, nil
+}
+
+// getProjectMetadataHandler returns the metadata of the project, e.g. to
+// prefill the form of /ose/project/info
+func getProjectMetadataHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	metadata, err := getProjectMetadata(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, metadata)
+}
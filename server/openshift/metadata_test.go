@@ -0,0 +1,34 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestGetProjectMetadata(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	config.Config().Set("annotations.billing_legacy", []string{"example.com/cost-center"})
+	if err := createNewProject(nil, "fake", "shop", "u123", "12345", "M1234", false); err != nil {
+		t.Fatal(err)
+	}
+	namespace, _ := api.Get("api/v1/namespaces/shop")
+	namespace.Set("Audit 2019", "metadata", "annotations", legalHoldAnnotation)
+	namespace.Set("foreign", "metadata", "annotations", "example.com/team")
+	api.Set("api/v1/namespaces/shop", namespace)
+
+	metadata, err := getProjectMetadata("fake", "shop")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if metadata.Billing != "12345" || metadata.MegaId != "M1234" || metadata.Requester != "u123" {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+	if metadata.Annotations[legalHoldAnnotation] != "Audit 2019" || metadata.Annotations[annotationKey(annotationBilling)] != "12345" {
+		t.Errorf("expected the annotations of the portal, got %v", metadata.Annotations)
+	}
+	if _, ok := metadata.Annotations["example.com/team"]; ok {
+		t.Error("expected other annotations not to be returned")
+	}
+}
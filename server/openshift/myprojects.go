@@ -0,0 +1,125 @@
+package openshift
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// getMyProjectsHandler lists the projects of all clusters the user is admin
+// of, with their metadata
+func getMyProjectsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	projects, failed, err := getMyProjects(getOpenshiftClusters(""), username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	matching := []ProjectMetadata{}
+	for _, p := range projects {
+		if listParams.Matches(p.Project, p.Billing, p.MegaId, p.Requester) {
+			matching = append(matching, p)
+		}
+	}
+	field, desc := listParams.SortField("clusterid")
+	sort.SliceStable(matching, func(i, j int) bool {
+		a, b := matching[i].sortValue(field), matching[j].sortValue(field)
+		if desc {
+			return a > b
+		}
+		return a < b
+	})
+
+	start, end, next := listParams.Page(len(matching))
+	c.JSON(http.StatusOK, common.ListResponse{
+		Items:    matching[start:end],
+		Total:    len(matching),
+		Continue: next,
+		Errors:   failed,
+	})
+}
+
+// getMyProjects returns the projects where the user or one of its groups is
+// in the admin rolebinding
+func getMyProjects(clusters []OpenshiftCluster, username string) ([]ProjectMetadata, []string, error) {
+	namespaces, failed, err := getNamespacesOfClusters(clusters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	admin := make([]map[string]bool, len(clusters))
+	errs := common.ForEachParallel(len(clusters), func(i int) error {
+		if namespaces[i] == nil {
+			return nil
+		}
+		var err error
+		admin[i], err = getAdminProjects(clusters[i].ID, username)
+		return err
+	})
+	for i, err := range errs {
+		if err != nil && namespaces[i] != nil {
+			failed = append(failed, clusters[i].ID)
+			namespaces[i] = nil
+		}
+	}
+	if len(failed) > 0 && len(failed) == len(clusters) {
+		return nil, nil, errors.New(genericAPIError)
+	}
+
+	projects := []ProjectMetadata{}
+	for i, cluster := range clusters {
+		for _, n := range namespaces[i] {
+			p := newProjectMetadata(cluster.ID, n)
+			if admin[i][p.Project] {
+				projects = append(projects, p)
+			}
+		}
+	}
+	return projects, failed, nil
+}
+
+// getAdminProjects reads the admin rolebindings of all projects of the
+// cluster with one call
+func getAdminProjects(clusterId, username string) (map[string]bool, error) {
+	roleBindings, err := listObjects(clusterId, "oapi/v1/rolebindings")
+	if err != nil {
+		return nil, err
+	}
+	groups, err := getUserGroups(clusterId, username)
+	if err != nil {
+		log.Printf("Error getting the groups of %v on cluster %v: %v", username, clusterId, err)
+	}
+
+	projects := make(map[string]bool)
+	for _, rb := range roleBindings {
+		if name, _ := rb.Path("metadata.name").Data().(string); name != "admin" {
+			continue
+		}
+		project, _ := rb.Path("metadata.namespace").Data().(string)
+		users, _ := rb.S("userNames").Children()
+		for _, u := range users {
+			if user, ok := u.Data().(string); ok && strings.EqualFold(user, username) {
+				projects[project] = true
+			}
+		}
+		groupNames, _ := rb.S("groupNames").Children()
+		for _, g := range groupNames {
+			if group, ok := g.Data().(string); ok && contains(groups, group) {
+				projects[project] = true
+			}
+		}
+	}
+	return projects, nil
+}
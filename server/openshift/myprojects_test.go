@@ -0,0 +1,40 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs"
+)
+
+func TestGetMyProjects(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	for project, requester := range map[string]string{"own": "u123", "foreign": "u456", "team": "u456"} {
+		if err := createNewProject(nil, "fake", project, requester, "12345", "MEGA-1", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	group, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "team-a"}, "users": ["u123"]}`))
+	api.Set("oapi/v1/groups/team-a", group)
+	if err := addGroupsToRoleBinding("fake", "team", "admin", []string{"team-a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	projects, failed, err := getMyProjects(getOpenshiftClusters(""), "u123")
+	if err != nil || len(failed) > 0 {
+		t.Fatalf("unexpected error: %v %v", err, failed)
+	}
+	found := map[string]ProjectMetadata{}
+	for _, p := range projects {
+		found[p.Project] = p
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected the projects own and team, got %+v", projects)
+	}
+	if p := found["own"]; p.Billing != "12345" || p.MegaId != "MEGA-1" || p.Requester != "u123" {
+		t.Errorf("expected the metadata of project own, got %+v", p)
+	}
+	if _, ok := found["team"]; !ok {
+		t.Error("expected the project of the group to be listed")
+	}
+}
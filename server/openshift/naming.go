@@ -0,0 +1,262 @@
+package openshift
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultNamingLabel = "org"
+
+// namingKinds are the kinds with naming conventions and their api path
+var namingKinds = map[string]string{
+	"Route":     "oapi/v1/%vroutes",
+	"Service":   "api/v1/%vservices",
+	"ConfigMap": "api/v1/%vconfigmaps",
+}
+
+// defaultNamingExemptions are created by the cluster in every project
+var defaultNamingExemptions = []string{"kube-root-ca.crt", "openshift-service-ca.crt"}
+
+// NamingConvention is a pattern the names of a kind must match in the
+// projects of an organization ('naming_conventions.label' of the namespace).
+// Conventions without org apply to all projects
+type NamingConvention struct {
+	Org         string `mapstructure:"org" json:"org"`
+	Kind        string `mapstructure:"kind" json:"kind"`
+	Pattern     string `mapstructure:"pattern" json:"pattern"`
+	Description string `mapstructure:"description" json:"description"`
+}
+
+// NamingViolation is an existing object which doesn't match a convention
+type NamingViolation struct {
+	ClusterId  string           `json:"clusterid"`
+	Project    string           `json:"project"`
+	Kind       string           `json:"kind"`
+	Name       string           `json:"name"`
+	Convention NamingConvention `json:"convention"`
+}
+
+// ProjectNaming are the conventions of a project and its violations
+type ProjectNaming struct {
+	ClusterId   string             `json:"clusterid"`
+	Project     string             `json:"project"`
+	Org         string             `json:"org"`
+	Conventions []NamingConvention `json:"conventions"`
+	Violations  []NamingViolation  `json:"violations"`
+}
+
+func getNamingConventions() []NamingConvention {
+	conventions := []NamingConvention{}
+	if err := config.Config().UnmarshalKey("naming_conventions.rules", &conventions); err != nil {
+		log.Printf("Error reading the naming conventions: %v", err)
+	}
+	return conventions
+}
+
+func getNamingLabel() string {
+	if label := config.Config().GetString("naming_conventions.label"); label != "" {
+		return label
+	}
+	return defaultNamingLabel
+}
+
+func isNamingExempt(name string) bool {
+	exemptions := config.Config().GetStringSlice("naming_conventions.exempt")
+	if len(exemptions) == 0 {
+		exemptions = defaultNamingExemptions
+	}
+	return contains(exemptions, name)
+}
+
+// conventionsOfOrg returns the conventions which apply to the projects of the org
+func conventionsOfOrg(conventions []NamingConvention, org string) []NamingConvention {
+	result := []NamingConvention{}
+	for _, c := range conventions {
+		if c.Org == "" || c.Org == org {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// violatedConvention returns the first convention of the kind the name
+// doesn't match. The pattern must match the whole name
+func violatedConvention(conventions []NamingConvention, kind, name string) *NamingConvention {
+	for i, c := range conventions {
+		if c.Kind != kind {
+			continue
+		}
+		pattern, err := regexp.Compile("^(?:" + c.Pattern + ")$")
+		if err != nil {
+			log.Printf("WARNING: ignoring the naming convention %v of %v with the invalid pattern %v: %v", c.Kind, c.Org, c.Pattern, err)
+			continue
+		}
+		if !pattern.MatchString(name) {
+			return &conventions[i]
+		}
+	}
+	return nil
+}
+
+func getProjectOrg(clusterId, project string) (string, error) {
+	labels, err := getProjectLabels(clusterId, project)
+	if err != nil {
+		return "", err
+	}
+	return labels[getNamingLabel()], nil
+}
+
+// checkResourceName is the hook for objects created by the portal. The name
+// must match the conventions of the organization of the project
+func checkResourceName(clusterId, project, kind, name string) error {
+	if _, ok := namingKinds[kind]; !ok {
+		return nil
+	}
+	conventions := getNamingConventions()
+	if len(conventions) == 0 {
+		return nil
+	}
+	org, err := getProjectOrg(clusterId, project)
+	if err != nil {
+		return err
+	}
+	if c := violatedConvention(conventionsOfOrg(conventions, org), kind, name); c != nil {
+		log.Printf("Rejected %v %v in project %v on cluster %v: doesn't match %v", kind, name, project, clusterId, c.Pattern)
+		return fmt.Errorf("Der Name %v (%v) entspricht nicht der Namenskonvention: %v", name, kind, c.Description)
+	}
+	return nil
+}
+
+// findNamingViolations returns the objects of the project, or of all
+// projects if project is empty, which don't match the conventions
+func findNamingViolations(clusterId, project string) ([]NamingViolation, error) {
+	violations := []NamingViolation{}
+	conventions := getNamingConventions()
+	if len(conventions) == 0 {
+		return violations, nil
+	}
+
+	orgs := make(map[string]string)
+	if project != "" {
+		org, err := getProjectOrg(clusterId, project)
+		if err != nil {
+			return nil, err
+		}
+		orgs[project] = org
+	} else {
+		namespaces, err := getAllNamespaces(clusterId)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range namespaces {
+			name, _ := n.Path("metadata.name").Data().(string)
+			org, _ := n.Path("metadata.labels").S(getNamingLabel()).Data().(string)
+			orgs[name] = org
+		}
+	}
+
+	scope := ""
+	if project != "" {
+		scope = fmt.Sprintf("namespaces/%v/", project)
+	}
+	for kind, path := range namingKinds {
+		objects, err := listObjects(clusterId, fmt.Sprintf(path, scope))
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objects {
+			namespace, _ := o.Path("metadata.namespace").Data().(string)
+			name, _ := o.Path("metadata.name").Data().(string)
+			if namespace == "" {
+				namespace = project
+			}
+			org, ok := orgs[namespace]
+			if !ok || isSystemNamespace(namespace) || isNamingExempt(name) || o.Path("metadata.ownerReferences").Data() != nil {
+				continue
+			}
+			if c := violatedConvention(conventionsOfOrg(conventions, org), kind, name); c != nil {
+				violations = append(violations, NamingViolation{ClusterId: clusterId, Project: namespace, Kind: kind, Name: name, Convention: *c})
+			}
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return violations, nil
+}
+
+// getProjectNamingHandler returns the naming conventions of the project and
+// the existing objects violating them
+func getProjectNamingHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	org, err := getProjectOrg(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	violations, err := findNamingViolations(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ProjectNaming{
+		ClusterId:   clusterId,
+		Project:     project,
+		Org:         org,
+		Conventions: conventionsOfOrg(getNamingConventions(), org),
+		Violations:  violations,
+	})
+}
+
+// getNamingViolationsHandler returns the objects of all projects of
+// ?clusterid which violate the naming conventions
+func getNamingViolationsHandler(c *gin.Context) {
+	clusterId := c.Query("clusterid")
+	if _, err := getOpenshiftCluster(clusterId); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	violations, err := findNamingViolations(clusterId, "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, violations)
+}
+
+// createNamingViolationsReport lists the violations of the naming
+// conventions on all clusters for the compliance report
+func createNamingViolationsReport() ([]common.Attachment, error) {
+	rows := [][]string{{"Cluster", "Projekt", "Typ", "Name", "Organisation", "Konvention"}}
+	for _, cluster := range getOpenshiftClusters("") {
+		violations, err := findNamingViolations(cluster.ID, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range violations {
+			rows = append(rows, []string{v.ClusterId, v.Project, v.Kind, v.Name, v.Convention.Org, v.Convention.Description})
+		}
+	}
+	return csvAttachment("naming-violations.csv", rows)
+}
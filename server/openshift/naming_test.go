@@ -0,0 +1,73 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestNamingConventions(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	config.Config().Set("naming_conventions.rules", []map[string]interface{}{
+		{"kind": "Route", "pattern": "[a-z-]+-(web|api)", "description": "Routes enden auf -web oder -api"},
+		{"org": "finance", "kind": "ConfigMap", "pattern": "fin-[a-z-]+", "description": "ConfigMaps beginnen mit fin-"},
+	})
+	defer config.Config().Set("naming_conventions.rules", nil)
+	namespace := func(name, org string) {
+		n := gabs.New()
+		n.SetP(name, "metadata.name")
+		n.Set(org, "metadata", "labels", "org")
+		api.Set("api/v1/namespaces/"+name, n)
+	}
+	namespace("ledger", "finance")
+	namespace("shop", "sales")
+	object := func(path, project, name string) *gabs.Container {
+		o := gabs.New()
+		o.SetP(project, "metadata.namespace")
+		o.SetP(name, "metadata.name")
+		api.Set(path+name, o)
+		return o
+	}
+	object("oapi/v1/namespaces/shop/routes/", "shop", "shop-web")
+	object("oapi/v1/namespaces/shop/routes/", "shop", "shop")
+	object("api/v1/namespaces/shop/configmaps/", "shop", "settings")
+	object("api/v1/namespaces/ledger/configmaps/", "ledger", "settings")
+	object("api/v1/namespaces/ledger/configmaps/", "ledger", "kube-root-ca.crt")
+	generated := object("api/v1/namespaces/ledger/configmaps/", "ledger", "generated")
+	generated.SetP([]interface{}{map[string]interface{}{"kind": "Deployment", "name": "web"}}, "metadata.ownerReferences")
+	api.Set("api/v1/namespaces/ledger/configmaps/generated", generated)
+
+	if err := checkResourceName("fake", "shop", "Route", "shop-api"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := checkResourceName("fake", "shop", "Route", "shop"); err == nil {
+		t.Error("expected a route without suffix to be rejected")
+	}
+	if err := checkResourceName("fake", "shop", "ConfigMap", "settings"); err != nil {
+		t.Errorf("expected the convention of finance not to apply to sales, got %v", err)
+	}
+	if err := checkResourceName("fake", "ledger", "ConfigMap", "settings"); err == nil {
+		t.Error("expected a configmap without prefix to be rejected in finance")
+	}
+	if err := checkResourceName("fake", "ledger", "Secret", "settings"); err != nil {
+		t.Errorf("expected kinds without conventions to be allowed, got %v", err)
+	}
+
+	violations, err := findNamingViolations("fake", "shop")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 1 || violations[0].Kind != "Route" || violations[0].Name != "shop" {
+		t.Errorf("expected the route shop to violate the convention, got %+v", violations)
+	}
+
+	violations, err = findNamingViolations("fake", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 2 || violations[0].Project != "ledger" || violations[0].Name != "settings" || violations[1].Project != "shop" {
+		t.Errorf("expected the violations of all projects, got %+v", violations)
+	}
+}
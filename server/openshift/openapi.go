@@ -0,0 +1,213 @@
+package openshift
+
+import (
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/openapi"
+)
+
+// listQuery are the parameters of the paged lists
+var listQuery = []string{"filter", "sort", "limit", "offset", "continue"}
+
+// describeRoutes documents the projects, billing, quotas and volumes in the
+// OpenAPI specification
+func describeRoutes() {
+	// Projects
+	openapi.Describe(newProjectHandler, openapi.Operation{
+		Summary:  "Create a project. With ?async=true it's created by a background job",
+		Query:    []string{"async"},
+		Body:     common.NewProjectCommand{},
+		Response: common.ApiResponse{},
+	})
+	openapi.Describe(deleteProjectHandler, openapi.Operation{
+		Summary:  "Delete a project",
+		Body:     common.DeleteProjectCommand{},
+		Response: common.ApiResponse{},
+	})
+	openapi.Describe(getProjectsHandler, openapi.Operation{
+		Summary:  "List the projects of a cluster. Without list parameters and health the names are returned as plain array",
+		Query:    append([]string{"clusterid", "health"}, listQuery...),
+		Response: common.ListResponse{},
+	})
+	openapi.Describe(getPodsHandler, openapi.Operation{
+		Summary:  "List the pods of a project",
+		Query:    append([]string{"clusterid", "project"}, listQuery...),
+		Response: common.ListResponse{},
+	})
+	openapi.Describe(getMyProjectsHandler, openapi.Operation{
+		Summary:  "List the projects of the user on all clusters",
+		Query:    listQuery,
+		Response: common.ListResponse{},
+	})
+	openapi.Describe(searchHandler, openapi.Operation{
+		Summary:  "Search the projects and the audit log. Umlauts and accents are ignored, the results are ranked",
+		Query:    append([]string{"q"}, listQuery...),
+		Response: common.ListResponse{},
+	})
+	openapi.Describe(getProjectInformationHandler, openapi.Operation{
+		Summary:  "Get the billing, megaid and requester of a project",
+		Query:    []string{"clusterid", "project"},
+		Response: ProjectInformation{},
+	})
+	openapi.Describe(getProjectMetadataHandler, openapi.Operation{
+		Summary:  "Get the billing, megaid, requester, contact and all annotations managed by the portal of a project",
+		Query:    []string{"clusterid", "project"},
+		Response: ProjectMetadata{},
+	})
+	openapi.Describe(updateProjectInformationHandler, openapi.Operation{
+		Summary:  "Change the billing and megaid of a project",
+		Body:     common.UpdateProjectInformationCommand{},
+		Response: common.ApiResponse{},
+	})
+	openapi.Describe(getProjectAdminsHandler, openapi.Operation{
+		Summary:  "List the admins of a project",
+		Query:    []string{"clusterid", "project"},
+		Response: common.AdminList{},
+	})
+	openapi.Describe(newTestProjectHandler, openapi.Operation{
+		Summary:  "Create a test project which is deleted automatically",
+		Body:     common.NewTestProjectCommand{},
+		Response: common.ApiResponse{},
+	})
+	openapi.Describe(getMegaIdHandler, openapi.Operation{
+		Summary:  "Get the megaid of a project",
+		Query:    []string{"clusterid", "project"},
+		Response: MegaIdResponse{},
+	})
+	openapi.Describe(updateMegaIdHandler, openapi.Operation{
+		Summary:  "Change the megaid of a project",
+		Body:     common.MegaIdCommand{},
+		Response: common.ApiResponse{},
+	})
+	openapi.Describe(clustersHandler, openapi.Operation{
+		Summary:  "List the clusters, optionally only those with a feature",
+		Query:    []string{"feature"},
+		Response: []OpenshiftCluster{},
+	})
+
+	// Billing
+	openapi.Describe(statementHandler, openapi.Operation{
+		Summary:  "Get the monthly statement (2006-01) of a billing number",
+		Query:    []string{"billing", "month"},
+		Response: Statement{},
+	})
+	openapi.Describe(showbackHandler, openapi.Operation{
+		Summary:  "Get the costs of a billing number between from and to",
+		Query:    []string{"billing", "groupBy", "from", "to"},
+		Response: Showback{},
+	})
+	openapi.Describe(getUsageTimelineHandler, openapi.Operation{
+		Summary:  "Get the daily quotas, usage and storage of a project between from and to (YYYY-MM-DD)",
+		Query:    []string{"clusterid", "project", "from", "to"},
+		Response: UsageTimeline{},
+	})
+	openapi.Describe(getProjectDeprecationsHandler, openapi.Operation{
+		Summary:  "List the objects of a project which were applied with a deprecated apiVersion",
+		Query:    []string{"clusterid", "project"},
+		Response: ProjectDeprecations{},
+	})
+	openapi.Describe(getDeprecationsHandler, openapi.Operation{
+		Summary:  "List the projects of a cluster with objects of deprecated apiVersions",
+		Query:    []string{"clusterid"},
+		Response: []ProjectDeprecations{},
+	})
+	openapi.Describe(kubeconfigHandler, openapi.Operation{
+		Summary: "Download a kubeconfig for projects of the user with a token of a service account per project or the user's token",
+		Body:    common.KubeconfigCommand{},
+	})
+	openapi.Describe(revokeKubeconfigHandler, openapi.Operation{
+		Summary:  "Revoke the role of the kubeconfig service account of the user in the projects, all projects on the cluster if empty",
+		Body:     common.KubeconfigCommand{},
+		Response: common.ApiResponse{},
+	})
+	openapi.Describe(getKubeconfigSigningKeyHandler, openapi.Operation{
+		Summary:  "Get the ed25519 public key of the kubeconfig signatures",
+		Response: kubeconfigSigningKeyResponse{},
+	})
+	openapi.Describe(getProjectNamingHandler, openapi.Operation{
+		Summary:  "Get the naming conventions of a project and the objects violating them",
+		Query:    []string{"clusterid", "project"},
+		Response: ProjectNaming{},
+	})
+	openapi.Describe(getNamingViolationsHandler, openapi.Operation{
+		Summary:  "List the objects of a cluster violating the naming conventions",
+		Query:    []string{"clusterid"},
+		Response: []NamingViolation{},
+	})
+	openapi.Describe(upgradeImpactHandler, openapi.Operation{
+		Summary:  "Notify the admins of the projects affected by a cluster maintenance about the required actions",
+		Body:     common.UpgradeImpactCommand{},
+		Response: common.UpgradeImpactResponse{},
+	})
+	openapi.Describe(bulkBillingHandler, openapi.Operation{
+		Summary:  "Change the billing of many projects, the result tells per project whether it was changed",
+		Body:     common.BulkBillingCommand{},
+		Response: common.BulkBillingResponse{},
+	})
+	openapi.Describe(billingReportHandler, openapi.Operation{
+		Summary:  "Get the quota usage and costs per project and billing number between from and to, with ?format=csv as csv",
+		Query:    []string{"billing", "from", "to", "format"},
+		Response: BillingReport{},
+	})
+	openapi.Describe(getProjectBudgetHandler, openapi.Operation{
+		Summary:  "Get the budget of a project",
+		Query:    []string{"clusterid", "project"},
+		Response: ProjectBudget{},
+	})
+	openapi.Describe(setProjectBudgetHandler, openapi.Operation{
+		Summary:  "Set the monthly budget of a project",
+		Body:     common.ProjectBudgetCommand{},
+		Response: common.ApiResponse{},
+	})
+	openapi.Describe(chargebackHandler, openapi.Operation{
+		Summary: "Calculate the chargeback of the projects",
+		Body:    OpenshiftChargebackCommand{},
+	})
+	openapi.Describe(mergeBillingHandler, openapi.Operation{
+		Summary:  "Move all projects from one billing number to another",
+		Body:     common.MergeBillingCommand{},
+		Response: common.MergeBillingResponse{},
+	})
+
+	// Quotas
+	openapi.Describe(getQuotasHandler, openapi.Operation{
+		Summary:  "Get the quotas of a project",
+		Query:    []string{"clusterid", "project"},
+		Response: common.QuotasResponse{},
+	})
+	openapi.Describe(editQuotasHandler, openapi.Operation{
+		Summary:  "Change the quotas of a project",
+		Body:     common.EditQuotasCommand{},
+		Response: common.ApiResponse{},
+	})
+	openapi.Describe(getLimitRangeHandler, openapi.Operation{
+		Summary:  "Get the limit range of a project",
+		Query:    []string{"clusterid", "project"},
+		Response: common.LimitRangeResponse{},
+	})
+	openapi.Describe(updateLimitRangeHandler, openapi.Operation{
+		Summary:  "Change the limit range of a project",
+		Body:     common.LimitRangeCommand{},
+		Response: common.ApiResponse{},
+	})
+
+	// Volumes
+	openapi.Describe(newVolumeHandler, openapi.Operation{
+		Summary:  "Create a persistent volume and its claim",
+		Body:     common.NewVolumeCommand{},
+		Response: common.NewVolumeApiResponse{},
+	})
+	openapi.Describe(growVolumeHandler, openapi.Operation{
+		Summary:  "Grow a persistent volume",
+		Body:     common.GrowVolumeCommand{},
+		Response: common.ApiResponse{},
+	})
+	openapi.Describe(fixVolumeHandler, openapi.Operation{
+		Summary:  "Recreate the endpoints of a gluster volume",
+		Body:     common.FixVolumeCommand{},
+		Response: common.ApiResponse{},
+	})
+	openapi.Describe(jobStatusHandler, openapi.Operation{
+		Summary: "Get the progress of the creation of a nfs volume",
+		Query:   []string{"clusterid", "job"},
+	})
+}
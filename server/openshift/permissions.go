@@ -0,0 +1,78 @@
+package openshift
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const maxPermissionChecks = 200
+
+// adminVerbs are the actions in the portal which require admin rights on the project
+var adminVerbs = map[string]bool{
+	"admin":          true,
+	"billing":        true,
+	"quotas":         true,
+	"serviceaccount": true,
+	"volume":         true,
+	"pullsecret":     true,
+	"delete":         true,
+}
+
+func checkPermissionsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.PermissionChecksCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if data.ClusterId == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Cluster muss angegeben werden"})
+		return
+	}
+	if len(data.Checks) > maxPermissionChecks {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Zu viele Berechtigungsprüfungen in einer Anfrage"})
+		return
+	}
+
+	common.Logger(c).Printf("%v checks %v permissions on cluster %v", username, len(data.Checks), data.ClusterId)
+
+	c.JSON(http.StatusOK, common.PermissionChecksResponse{
+		Results: checkPermissions(data.ClusterId, username, data.Checks),
+	})
+}
+
+func checkPermissions(clusterId, username string, checks []common.PermissionCheck) []common.PermissionCheckResult {
+	// Only ask the api once per project
+	adminErrors := make(map[string]error)
+	results := []common.PermissionCheckResult{}
+
+	for _, check := range checks {
+		result := common.PermissionCheckResult{PermissionCheck: check}
+
+		var err error
+		if !adminVerbs[check.Verb] {
+			err = errors.New("Unbekannte Aktion: " + check.Verb)
+		} else if check.Project == "" {
+			err = errors.New("Projektname muss angegeben werden")
+		} else {
+			var checked bool
+			err, checked = adminErrors[check.Project]
+			if !checked {
+				err = checkAdminPermissions(clusterId, username, check.Project)
+				adminErrors[check.Project] = err
+			}
+		}
+
+		result.Allowed = err == nil
+		if err != nil {
+			result.Message = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,68 @@
+package openshift
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("own", "u123")
+	api.AddProject("other", "u456")
+
+	results := checkPermissions("fake", "u123", []common.PermissionCheck{
+		{Project: "own", Verb: "quotas"},
+		{Project: "own", Verb: "delete"},
+		{Project: "other", Verb: "quotas"},
+		{Project: "own", Verb: "fly"},
+		{Project: "", Verb: "quotas"},
+	})
+	expected := []bool{true, true, false, false, false}
+	if len(results) != len(expected) {
+		t.Fatalf("expected a result per check, got %+v", results)
+	}
+	for i, r := range results {
+		if r.Allowed != expected[i] {
+			t.Errorf("check %+v: expected allowed %v, got %+v", r.PermissionCheck, expected[i], r)
+		}
+		if !r.Allowed && r.Message == "" {
+			t.Errorf("check %+v: expected the reason of the denial", r.PermissionCheck)
+		}
+	}
+}
+
+func TestCheckPermissionsHandler(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/ose/permissions/check", strings.NewReader(body))
+		c.Set(gin.AuthUserKey, "u123")
+		checkPermissionsHandler(c)
+		return w
+	}
+
+	if w := post(`{"checks": [{"project": "own", "verb": "quotas"}]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without cluster, got %v", w.Code)
+	}
+	checks := strings.TrimSuffix(strings.Repeat(`{"project": "own", "verb": "quotas"},`, maxPermissionChecks+1), ",")
+	if w := post(fmt.Sprintf(`{"clusterid": "fake", "checks": [%v]}`, checks)); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for too many checks, got %v", w.Code)
+	}
+
+	w := post(`{"clusterid": "fake", "checks": [{"project": "missing", "verb": "quotas"}]}`)
+	var response common.PermissionChecksResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || len(response.Results) != 1 || response.Results[0].Allowed {
+		t.Errorf("expected a denied check, got %v %v", w.Code, w.Body.String())
+	}
+}
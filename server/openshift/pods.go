@@ -0,0 +1,95 @@
+package openshift
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// Pod is a pod of a project in the pod list
+type Pod struct {
+	Name     string `json:"name"`
+	Phase    string `json:"phase"`
+	Node     string `json:"node"`
+	Restarts int    `json:"restarts"`
+	Created  string `json:"created"`
+}
+
+// getPodsHandler lists the pods of the project with the list parameters,
+// sortable by name, phase, restarts and created
+func getPodsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	pods, err := getPods(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	matching := []Pod{}
+	for _, p := range pods {
+		if listParams.Matches(p.Name, p.Phase, p.Node) {
+			matching = append(matching, p)
+		}
+	}
+	field, desc := listParams.SortField("name")
+	sort.SliceStable(matching, func(i, j int) bool {
+		a, b := matching[i], matching[j]
+		if desc {
+			a, b = b, a
+		}
+		switch field {
+		case "phase":
+			return a.Phase < b.Phase
+		case "restarts":
+			return a.Restarts < b.Restarts
+		case "created":
+			return a.Created < b.Created
+		}
+		return a.Name < b.Name
+	})
+
+	start, end, next := listParams.Page(len(matching))
+	c.JSON(http.StatusOK, common.ListResponse{
+		Items:    matching[start:end],
+		Total:    len(matching),
+		Continue: next,
+	})
+}
+
+func getPods(clusterId, project string) ([]Pod, error) {
+	objects, err := listObjects(clusterId, fmt.Sprintf("api/v1/namespaces/%v/pods", project))
+	if err != nil {
+		return nil, err
+	}
+	pods := []Pod{}
+	for _, o := range objects {
+		pod := Pod{}
+		pod.Name, _ = o.Path("metadata.name").Data().(string)
+		pod.Created, _ = o.Path("metadata.creationTimestamp").Data().(string)
+		pod.Phase, _ = o.Path("status.phase").Data().(string)
+		pod.Node, _ = o.Path("spec.nodeName").Data().(string)
+		statuses, _ := o.Path("status.containerStatuses").Children()
+		for _, s := range statuses {
+			if restarts, ok := s.Path("restartCount").Data().(float64); ok {
+				pod.Restarts += int(restarts)
+			}
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
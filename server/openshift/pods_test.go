@@ -0,0 +1,89 @@
+package openshift
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/gabs"
+	"github.com/gin-gonic/gin"
+)
+
+func listRequest(handler gin.HandlerFunc, url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", url, nil)
+	c.Set(gin.AuthUserKey, "u123")
+	handler(c)
+	return w
+}
+
+// jsonRequest calls the handler as the user with the json body
+func jsonRequest(handler gin.HandlerFunc, method, url, username, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, url, strings.NewReader(body))
+	c.Set(gin.AuthUserKey, username)
+	handler(c)
+	return w
+}
+
+func TestGetPodsHandler(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("shop", "u123")
+	for _, pod := range []string{
+		`{"metadata": {"name": "web-1"}, "status": {"phase": "Running", "containerStatuses": [{"restartCount": 1}, {"restartCount": 2}]}}`,
+		`{"metadata": {"name": "web-2"}, "status": {"phase": "Pending"}}`,
+		`{"metadata": {"name": "db-1"}, "status": {"phase": "Running"}}`,
+	} {
+		p, _ := gabs.ParseJSON([]byte(pod))
+		api.Set("api/v1/namespaces/shop/pods/"+p.Path("metadata.name").Data().(string), p)
+	}
+
+	w := listRequest(getPodsHandler, "/ose/project/pods?clusterid=fake&project=shop&filter=web&sort=-restarts&limit=1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v %v", w.Code, w.Body.String())
+	}
+	var response struct {
+		Items    []Pod  `json:"items"`
+		Total    int    `json:"total"`
+		Continue string `json:"continue"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Total != 2 || len(response.Items) != 1 || response.Items[0].Name != "web-1" || response.Items[0].Restarts != 3 || response.Continue != "1" {
+		t.Errorf("unexpected response %+v", response)
+	}
+
+	api.AddProject("other", "u456")
+	if w := listRequest(getPodsHandler, "/ose/project/pods?clusterid=fake&project=other"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a project of another user, got %v", w.Code)
+	}
+}
+
+func TestGetProjectsHandlerIsCompatible(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("shop", "u123")
+	api.AddProject("blog", "u123")
+
+	var names []string
+	w := listRequest(getProjectsHandler, "/ose/projects?clusterid=fake")
+	if err := json.Unmarshal(w.Body.Bytes(), &names); err != nil || len(names) != 2 {
+		t.Errorf("expected the plain array without list parameters, got %v", w.Body.String())
+	}
+
+	var page struct {
+		Items    []string `json:"items"`
+		Total    int      `json:"total"`
+		Continue string   `json:"continue"`
+	}
+	w = listRequest(getProjectsHandler, "/ose/projects?clusterid=fake&limit=1")
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || page.Total != 2 || len(page.Items) != 1 || page.Continue == "" {
+		t.Errorf("expected a page with continue token, got %v", w.Body.String())
+	}
+}
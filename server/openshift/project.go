@@ -4,22 +4,24 @@ import (
 	"bytes"
 	"errors"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"strings"
 
 	"fmt"
 
-	"crypto/tls"
 	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/jobs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/webhooks"
 	"github.com/gin-gonic/gin"
-	"gopkg.in/gomail.v2"
-	"os"
+	log "github.com/sirupsen/logrus"
 )
 
 func newProjectHandler(c *gin.Context) {
 	username := common.GetUserName(c)
+	user := clusterUserFromContext(c)
 
 	var data common.NewProjectCommand
 	if c.BindJSON(&data) == nil {
@@ -27,26 +29,122 @@ func newProjectHandler(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 			return
 		}
+		data.MegaId = strings.TrimSpace(data.MegaId)
+		if err := validateMegaId(data.MegaId); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		if err := validateProjectTemplate(data.ClusterId, data.Template, data.Parameters); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		if err := validateInitialQuota(data.CPU, data.Memory); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
 
-		if err := createNewProject(data.ClusterId, data.Project, username, data.Billing, data.MegaId, false); err != nil {
+		// Only approved requests are created on the cluster
+		reasons, err := projectApprovalReasons(data)
+		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
-		} else {
-			err := sendNewProjectMail(data.ClusterId, data.Project, username, data.MegaId)
+			return
+		}
+		if len(reasons) > 0 {
+			message := fmt.Sprintf(projectApprovalPendingResponse, data.Project, data.ClusterId)
+			if common.IsDryRun(c) {
+				common.RespondDryRun(c, message, common.PlannedChange{
+					Action: common.DryRunActionApproval, Kind: "Project", Name: strings.ToLower(data.Project), ClusterId: data.ClusterId,
+					Project: strings.ToLower(data.Project), Details: strings.Join(reasons, ", "),
+				})
+				return
+			}
+			request, err := requestProjectApproval(data, username, reasons)
 			if err != nil {
-				log.Printf("Can't send e-mail about new project (%v) on cluster %v.", err, data.ClusterId)
+				c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+				return
 			}
+			c.JSON(http.StatusAccepted, projectApprovalResponse{Message: message, Request: request})
+			return
+		}
 
-			c.JSON(http.StatusOK, common.ApiResponse{
-				Message: fmt.Sprintf("Das Projekt %v wurde erstellt auf Cluster %v", data.Project, data.ClusterId),
+		if common.IsDryRun(c) {
+			if err := checkBillingLimitsForNewProject(data.Billing); err != nil {
+				c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+				return
+			}
+			if err := checkInitialQuota(data); err != nil {
+				c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+				return
+			}
+			changes := plannedNewProject(data.ClusterId, data.Project, username, data.Billing)
+			if data.Template != "" {
+				changes = append(changes, common.PlannedChange{
+					Action: common.DryRunActionCreate, Kind: "Template", Name: data.Template, ClusterId: data.ClusterId,
+					Project: strings.ToLower(data.Project), Details: "Objekte aus " + projectTemplateNamespace() + "/" + data.Template,
+				})
+			}
+			common.RespondDryRun(c, fmt.Sprintf("Das Projekt %v würde erstellt auf Cluster %v", data.Project, data.ClusterId), changes...)
+			return
+		}
+
+		// Slow clusters can take longer than the timeout of the client
+		if c.Query("async") == "true" {
+			job, err := jobs.Submit("project-creation", username, func() (string, error) {
+				return createProjectOfCommand(user, data, username)
 			})
+			if err != nil {
+				c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+				return
+			}
+			c.JSON(http.StatusAccepted, jobs.Response{
+				Message: fmt.Sprintf("Das Projekt %v wird erstellt auf Cluster %v", data.Project, data.ClusterId),
+				Job:     job,
+			})
+			return
+		}
+
+		message, err := createProjectOfCommand(user, data, username)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
 		}
+		c.JSON(http.StatusOK, common.ApiResponse{Message: message})
 	} else {
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
 	}
 }
 
+// createProjectOfCommand creates the project, informs the requester and
+// instantiates the template
+func createProjectOfCommand(user *clusterUser, data common.NewProjectCommand, username string) (string, error) {
+	if err := checkInitialQuota(data); err != nil {
+		return "", err
+	}
+	if err := createNewProject(user, data.ClusterId, data.Project, username, data.Billing, data.MegaId, false); err != nil {
+		return "", err
+	}
+	if data.CPU > 0 && data.Memory > 0 {
+		if err := updateQuotas(data.ClusterId, username, strings.ToLower(data.Project), data.CPU, data.Memory); err != nil {
+			return "", err
+		}
+	}
+	common.Notify(common.Notification{
+		Event: common.NotificationProjectCreated, Username: username, ClusterId: data.ClusterId, Project: strings.ToLower(data.Project),
+	})
+	if err := sendNewProjectMail(data.ClusterId, data.Project, username, data.MegaId); err != nil {
+		log.Printf("Can't send e-mail about new project (%v) on cluster %v.", err, data.ClusterId)
+	}
+	if data.Template != "" {
+		if err := instantiateProjectTemplate(data.ClusterId, data.Project, data.Template, data.Parameters); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("Das Projekt %v wurde erstellt auf Cluster %v", data.Project, data.ClusterId), nil
+}
+
 func newTestProjectHandler(c *gin.Context) {
 	username := common.GetUserName(c)
+	user := clusterUserFromContext(c)
 
 	var data common.NewTestProjectCommand
 	if c.BindJSON(&data) == nil {
@@ -59,7 +157,13 @@ func newTestProjectHandler(c *gin.Context) {
 			return
 		}
 
-		if err := createNewProject(data.ClusterId, data.Project, username, billing, "", true); err != nil {
+		if common.IsDryRun(c) {
+			common.RespondDryRun(c, fmt.Sprintf("Das Test-Projekt %v würde erstellt auf Cluster %v", data.Project, data.ClusterId),
+				plannedNewProject(data.ClusterId, data.Project, username, billing)...)
+			return
+		}
+
+		if err := createNewProject(user, data.ClusterId, data.Project, username, billing, "", true); err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 		} else {
 			c.JSON(http.StatusOK, common.ApiResponse{
@@ -71,6 +175,59 @@ func newTestProjectHandler(c *gin.Context) {
 	}
 }
 
+// plannedNewProject returns the changes of createNewProject
+func plannedNewProject(clusterId, project, username, billing string) []common.PlannedChange {
+	project = strings.ToLower(project)
+	return []common.PlannedChange{
+		{Action: common.DryRunActionCreate, Kind: "Project", Name: project, ClusterId: clusterId, Project: project},
+		{Action: common.DryRunActionUpdate, Kind: "RoleBinding", Name: "admin", ClusterId: clusterId, Project: project, Details: "admin: " + strings.ToLower(username)},
+		{Action: common.DryRunActionUpdate, Kind: "Namespace", Name: project, ClusterId: clusterId, Project: project, Details: "billing: " + billing},
+	}
+}
+
+// deleteProjectHandler lets the admins of a project delete it
+func deleteProjectHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.DeleteProjectCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if data.Confirmation != data.Project {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Zur Bestätigung muss der Projektname nochmals eingegeben werden"})
+		return
+	}
+
+	if common.IsDryRun(c) {
+		if err := checkLegalHold(data.ClusterId, data.Project, "dry-run deletion"); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		impact, err := getProjectImpact(data.ClusterId, data.Project)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		change := common.PlannedChange{Action: common.DryRunActionDelete, Kind: "Project", Name: data.Project, ClusterId: data.ClusterId, Project: data.Project}
+		common.RespondDryRun(c, fmt.Sprintf("Das Projekt %v würde gelöscht", data.Project), append([]common.PlannedChange{change}, impactChanges(data.ClusterId, data.Project, impact)...)...)
+		return
+	}
+
+	if err := deleteProject(data.ClusterId, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v deleted the project %v on cluster %v as project admin", username, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Das Projekt %v wurde gelöscht auf Cluster %v", data.Project, data.ClusterId),
+	})
+}
+
 func getProjectsHandler(c *gin.Context) {
 	username := common.GetUserName(c)
 	params := c.Request.URL.Query()
@@ -79,29 +236,33 @@ func getProjectsHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
 		return
 	}
-	log.Printf("%v has queried all his projects in clusterid: %v", username, clusterId)
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	common.Logger(c).Printf("%v has queried all his projects in clusterid: %v", username, clusterId)
 	projects, err := getUserProjects(clusterId, username)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
-	} else {
+	} else if !common.IsListRequest(c) && c.Query("health") != "true" {
+		// Clients without list parameters get the complete array as before
 		c.JSON(http.StatusOK, projects)
+	} else {
+		response := common.NewStringListResponse(projects, listParams)
+		if c.Query("health") == "true" {
+			response.Items = withProjectHealth(clusterId, response.Items.([]string))
+		}
+		c.JSON(http.StatusOK, response)
 	}
 }
 
 func getUserProjects(clusterid, username string) ([]string, error) {
 	// TODO: only return projects, where the user has access
-	resp, err := getOseHTTPClient("GET", clusterid, "oapi/v1/projects", nil)
+	json, err := getProjects(clusterid)
 	if err != nil {
 		return []string{}, err
 	}
-
-	defer resp.Body.Close()
-
-	json, err := gabs.ParseJSONBuffer(resp.Body)
-	if err != nil {
-		log.Println("error decoding json:", err, resp.StatusCode)
-		return []string{}, errors.New(genericAPIError)
-	}
 	projects, err := json.Search("items").Children()
 	if err != nil {
 		log.Println("error getting projects: ", err)
@@ -114,6 +275,32 @@ func getUserProjects(clusterid, username string) ([]string, error) {
 	return projectNames, nil
 }
 
+func getProjects(clusterId string) (*gabs.Container, error) {
+	cacheKey := projectsCacheKey(clusterId)
+	if cached, ok := common.GetCache().Get(cacheKey); ok {
+		if json, err := gabs.ParseJSON(cached); err == nil {
+			return json, nil
+		}
+	}
+
+	resp, err := getOseHTTPClient("GET", clusterId, "oapi/v1/projects", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return nil, errors.New(genericAPIError)
+	}
+	if resp.StatusCode == http.StatusOK {
+		common.GetCache().Set(cacheKey, json.Bytes())
+	}
+	return json, nil
+}
+
 func getProjectAdminsHandler(c *gin.Context) {
 	username := common.GetUserName(c)
 
@@ -126,7 +313,7 @@ func getProjectAdminsHandler(c *gin.Context) {
 		return
 	}
 
-	log.Printf("%v has queried all the admins of project %v on cluster %v", username, project, clusterId)
+	common.Logger(c).Printf("%v has queried all the admins of project %v on cluster %v", username, project, clusterId)
 
 	if admins, _, err := getProjectAdminsAndOperators(clusterId, project); err != nil {
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
@@ -167,6 +354,18 @@ func updateProjectInformationHandler(c *gin.Context) {
 			return
 		}
 
+		if common.IsDryRun(c) {
+			changes, err := previewAnnotations(data.ClusterId, data.Project, func(annotations *gabs.Container) {
+				setMetadataAnnotations(annotations, data.Billing, data.MegaID, username, false)
+			})
+			if err != nil {
+				c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+				return
+			}
+			common.RespondDryRun(c, fmt.Sprintf("Die Informationen für Projekt %v auf Cluster %v würden gespeichert", data.Project, data.ClusterId), changes...)
+			return
+		}
+
 		if err := createOrUpdateMetadata(data.ClusterId, data.Project, data.Billing, data.MegaID, username, false); err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 		} else {
@@ -221,6 +420,10 @@ func validateProjectInformation(data common.UpdateProjectInformationCommand, use
 		return errors.New("Kontierungsnummer muss angegeben werden")
 	}
 
+	if err := validateMegaId(strings.TrimSpace(data.MegaID)); err != nil {
+		return err
+	}
+
 	// Validate permissions
 	if err := checkAdminPermissions(data.ClusterId, username, data.Project); err != nil {
 		return err
@@ -230,29 +433,12 @@ func validateProjectInformation(data common.UpdateProjectInformationCommand, use
 }
 
 func sendNewProjectMail(clusterId string, projectName string, userName string, megaID string) error {
-
-	mailServer, ok := os.LookupEnv("MAIL_SERVER")
-	if !ok {
-		return errors.New("Error looking up MAIL_SERVER from environment.")
-	}
-
-	fromMail, ok := os.LookupEnv("MAIL_ADMIN_SENDER")
-	if !ok {
-		return errors.New("Error looking up MAIL_ADMIN_SENDER from environment.")
-	}
-
-	newProjectMail, ok := os.LookupEnv("MAIL_NEW_PROJECT_RECIPIENT")
-	if !ok {
+	newProjectMail := config.Config().GetString("mail_new_project_recipient")
+	if newProjectMail == "" {
 		return errors.New("Error looking up MAIL_NEW_PROJECT_RECIPIENT from environment.")
 	}
 
-	m := gomail.NewMessage()
-	m.SetHeader("From", fromMail)
-
-	m.SetHeader("To", newProjectMail)
-	m.SetHeader("Subject", fmt.Sprintf("Neues Projekt '%v' auf OpenShift", projectName))
-
-	m.SetBody("text/html", fmt.Sprintf(`
+	return common.SendMail([]string{newProjectMail}, fmt.Sprintf("Neues Projekt '%v' auf OpenShift", projectName), fmt.Sprintf(`
 	Sehr geehrte Damen und Herren,
 	<br><br>
 	Das folgende Projekt wurde auf OpenShift erstellt:
@@ -266,23 +452,23 @@ func sendNewProjectMail(clusterId string, projectName string, userName string, m
 	Euer Cloud Platforms Team<br>
 	IT-OM-SDL-CLP
 	`, clusterId, projectName, userName, megaID))
+}
 
-	d := gomail.Dialer{Host: mailServer, Port: 25}
-	d.TLSConfig = &tls.Config{InsecureSkipVerify: true}
-	err := d.DialAndSend(m)
+// createNewProject requests the project on behalf of user, see clusterUser.
+// The metadata is written with the portal's token, as users can't update
+// their namespace
+func createNewProject(user *clusterUser, clusterId string, project string, username string, billing string, megaid string, testProject bool) error {
+	project = strings.ToLower(project)
 
-	if err != nil {
-		return err
+	if !testProject {
+		if err := checkBillingLimitsForNewProject(billing); err != nil {
+			return err
+		}
 	}
 
-	return nil
-}
-
-func createNewProject(clusterId string, project string, username string, billing string, megaid string, testProject bool) error {
-	project = strings.ToLower(project)
 	p := newObjectRequest("ProjectRequest", project)
 
-	resp, err := getOseHTTPClient("POST", clusterId, "oapi/v1/projectrequests", bytes.NewReader(p.Bytes()))
+	resp, err := getOseHTTPClientAs(user, "POST", clusterId, "oapi/v1/projectrequests", bytes.NewReader(p.Bytes()))
 	if err != nil {
 		return err
 	}
@@ -290,14 +476,20 @@ func createNewProject(clusterId string, project string, username string, billing
 
 	if resp.StatusCode == http.StatusCreated {
 		log.Printf("%v created a new project: %v on cluster %v", username, project, clusterId)
+		common.GetCache().Delete(projectsCacheKey(clusterId))
 
-		if err := changeProjectPermission(clusterId, project, username); err != nil {
+		if err := changeProjectPermission(user, clusterId, project, username); err != nil {
 			return err
 		}
 
 		if err := createOrUpdateMetadata(clusterId, project, billing, megaid, username, testProject); err != nil {
 			return err
 		}
+		webhooks.Publish(webhooks.Event{
+			Type: webhooks.EventProjectCreated, ClusterId: clusterId, Project: project,
+			Data: map[string]string{"requester": username, "billing": billing},
+		})
+		common.PostChat(common.ChatProjectCreated, fmt.Sprintf("%v created the project %v on cluster %v (billing %v)", username, project, clusterId, billing))
 		return nil
 	}
 	if resp.StatusCode == http.StatusConflict {
@@ -310,7 +502,13 @@ func createNewProject(clusterId string, project string, username string, billing
 	return errors.New(genericAPIError)
 }
 
-func changeProjectPermission(clusterId string, project string, username string) error {
+// changeProjectPermission makes username admin of the project on behalf of
+// user (nil for the portal)
+func changeProjectPermission(user *clusterUser, clusterId string, project string, username string) error {
+	// Always update the current version of the rolebinding
+	common.GetCache().Delete(roleBindingCacheKey(clusterId, project))
+	defer common.GetCache().Delete(roleBindingCacheKey(clusterId, project))
+
 	adminRoleBinding, err := getAdminRoleBinding(clusterId, project)
 	if err != nil {
 		return err
@@ -320,7 +518,7 @@ func changeProjectPermission(clusterId string, project string, username string)
 	adminRoleBinding.ArrayAppend(strings.ToUpper(username), "userNames")
 
 	// Update the policyBindings on the api
-	resp, err := getOseHTTPClient("PUT",
+	resp, err := getOseHTTPClientAs(user, "PUT",
 		clusterId,
 		"oapi/v1/namespaces/"+project+"/rolebindings/admin",
 		bytes.NewReader(adminRoleBinding.Bytes()))
@@ -341,35 +539,22 @@ func changeProjectPermission(clusterId string, project string, username string)
 }
 
 type ProjectInformation struct {
-	Kontierungsnummer string `json:"kontierungsnummer"`
-	MegaID            string `json:"megaid"`
+	Kontierungsnummer string             `json:"kontierungsnummer"`
+	MegaID            string             `json:"megaid"`
+	Drift             []common.DriftItem `json:"drift,omitempty"`
 }
 
 func getProjectInformation(clusterId, project string) (*ProjectInformation, error) {
-	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces/"+project, nil)
+	json, err := getNamespace(clusterId, project)
 	if err != nil {
 		return nil, err
 	}
 
-	defer resp.Body.Close()
-
-	json, err := gabs.ParseJSONBuffer(resp.Body)
-	if err != nil {
-		log.Println("error decoding json:", err, resp.StatusCode)
-		return nil, errors.New(genericAPIError)
-	}
-
-	billing := json.Path("metadata.annotations").S("openshift.io/kontierung-element").Data()
-	if billing == nil {
-		billing = ""
-	}
-	megaid := json.Path("metadata.annotations").S("openshift.io/MEGAID").Data()
-	if megaid == nil {
-		megaid = ""
-	}
+	annotations := json.Path("metadata.annotations")
 	return &ProjectInformation{
-		Kontierungsnummer: billing.(string),
-		MegaID:            megaid.(string),
+		Kontierungsnummer: getAnnotation(annotations, annotationBilling),
+		MegaID:            getAnnotation(annotations, annotationMegaId),
+		Drift:             getProjectDrift(clusterId, project),
 	}, nil
 }
 
@@ -387,24 +572,16 @@ func createOrUpdateMetadata(clusterId, project string, billing string, megaid st
 		return errors.New(genericAPIError)
 	}
 
-	annotations := json.Path("metadata.annotations")
-	annotations.Set(billing, "openshift.io/kontierung-element")
-	annotations.Set(username, "openshift.io/requester")
-
-	if testProject {
-		annotations.Set(testProjectDeletionDays, "openshift.io/testproject-daystodeletion")
-		annotations.Set(fmt.Sprintf("Dieses Testprojekt wird in %v Tagen automatisch gelöscht!", testProjectDeletionDays), "openshift.io/description")
-	}
-
-	if len(megaid) > 0 {
-		annotations.Set(megaid, "openshift.io/MEGAID")
-	}
+	setMetadataAnnotations(json.Path("metadata.annotations"), billing, megaid, username, testProject)
 
 	resp, err = getOseHTTPClient("PUT", clusterId, "api/v1/namespaces/"+project, bytes.NewReader(json.Bytes()))
 	if err != nil {
 		return err
 	}
 
+	common.GetCache().Delete(namespaceCacheKey(clusterId, project))
+	common.GetCache().Delete(billingIndexCacheKey(clusterId))
+
 	if resp.StatusCode == http.StatusOK {
 		resp.Body.Close()
 		log.Println("User "+username+" changed config of project "+project+" on cluster "+clusterId+". Kontierungsnummer: "+billing, ", MegaID: "+megaid)
@@ -416,3 +593,73 @@ func createOrUpdateMetadata(clusterId, project string, billing string, megaid st
 
 	return errors.New(genericAPIError)
 }
+
+// setMetadataAnnotations writes the billing, requester and MEGA ID of the project
+func setMetadataAnnotations(annotations *gabs.Container, billing string, megaid string, username string, testProject bool) {
+	setAnnotation(annotations, annotationBilling, billing)
+	setAnnotation(annotations, annotationRequester, username)
+
+	if testProject {
+		setTestProjectExpiry(annotations, common.Now())
+	}
+
+	if len(megaid) > 0 {
+		setAnnotation(annotations, annotationMegaId, megaid)
+	}
+}
+
+// deleteProject deletes the project unless it is under legal hold. With a
+// recycle bin retention the project is only suspended until it is purged
+func deleteProject(clusterId, project string) error {
+	if err := checkLegalHold(clusterId, project, "deletion"); err != nil {
+		return err
+	}
+	var err error
+	if recycleBinRetention() > 0 {
+		err = recycleProject(clusterId, project, common.Now())
+	} else {
+		err = purgeProject(clusterId, project)
+	}
+	if err == nil {
+		webhooks.Publish(webhooks.Event{Type: webhooks.EventProjectDeleted, ClusterId: clusterId, Project: project})
+	}
+	return err
+}
+
+// purgeProject deletes the project on the cluster and notifies its requester
+func purgeProject(clusterId, project string) error {
+	requester := getProjectRequester(clusterId, project)
+	resp, err := getOseHTTPClient("DELETE", clusterId, "oapi/v1/projects/"+project, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	common.GetCache().Delete(projectsCacheKey(clusterId))
+	common.GetCache().Delete(namespaceCacheKey(clusterId, project))
+	common.GetCache().Delete(roleBindingCacheKey(clusterId, project))
+	common.GetCache().Delete(billingIndexCacheKey(clusterId))
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error deleting project:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	if requester != "" {
+		common.Notify(common.Notification{Event: common.NotificationProjectDeleted, Username: requester, ClusterId: clusterId, Project: project})
+	}
+	return nil
+}
+
+// getProjectRequester returns the requester of the project or an empty
+// string if it can't be read
+func getProjectRequester(clusterId, project string) string {
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return ""
+	}
+	return getAnnotation(namespace.Path("metadata.annotations"), annotationRequester)
+}
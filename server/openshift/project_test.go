@@ -0,0 +1,147 @@
+package openshift
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/openshift/fakeapi"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store/storetest"
+	"github.com/gin-gonic/gin"
+)
+
+// newFakeCluster configures the cluster 'fake' against an in-memory api
+func newFakeCluster(t *testing.T) (*fakeapi.Server, func()) {
+	cleanupStore := storetest.Setup(t)
+	api := fakeapi.New()
+
+	cfg := config.Config()
+	cfg.Set("openshift", []map[string]interface{}{api.Cluster("fake")})
+	cfg.Set("cache_ttl_seconds", -1)
+
+	return api, func() {
+		api.Close()
+		cleanupStore()
+	}
+}
+
+func TestCreateNewProject(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+
+	if err := createNewProject(nil, "fake", "Test-Project", "u123", "12345", "ABC", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	namespace, ok := api.Get("api/v1/namespaces/test-project")
+	if !ok {
+		t.Fatal("expected the project to be created")
+	}
+	annotations := namespace.Path("metadata.annotations")
+	if getAnnotation(annotations, annotationBilling) != "12345" || getAnnotation(annotations, annotationRequester) != "u123" {
+		t.Errorf("expected billing and requester to be set, got %v", annotations)
+	}
+	if annotations.S(testProjectDeletionAnnotation).Data() == nil {
+		t.Errorf("expected the test project to be marked for deletion, got %v", annotations)
+	}
+
+	admins, _, err := getProjectAdminsAndOperators("fake", "test-project")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !contains(admins, "u123") {
+		t.Errorf("expected u123 to be admin, got %v", admins)
+	}
+
+	if err := createNewProject(nil, "fake", "test-project", "u123", "12345", "", true); err == nil {
+		t.Error("expected an error for an existing project")
+	}
+}
+
+func TestChangeProjectPermission(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("existing", fakeapi.ServiceAccount)
+
+	if err := changeProjectPermission(nil, "fake", "existing", "u456"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	admin, _ := api.Get("oapi/v1/namespaces/existing/rolebindings/admin")
+	users, _ := admin.S("userNames").Children()
+	if len(users) != 3 {
+		t.Errorf("expected the user to be added in lower and upper case, got %v", admin.S("userNames"))
+	}
+
+	if err := changeProjectPermission(nil, "fake", "missing", "u456"); err == nil {
+		t.Error("expected an error for a missing project")
+	}
+}
+
+func TestPreviewAnnotations(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+
+	if err := createNewProject(nil, "fake", "test-project", "u123", "12345", "", false); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := previewAnnotations("fake", "test-project", func(annotations *gabs.Container) {
+		setMetadataAnnotations(annotations, "67890", "ABC", "u123", false)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []common.PlannedChange{
+		{Action: common.DryRunActionCreate, Kind: "Annotation", Name: "openshift.io/MEGAID", ClusterId: "fake", Project: "test-project", Proposed: "ABC"},
+		{Action: common.DryRunActionUpdate, Kind: "Annotation", Name: "openshift.io/kontierung-element", ClusterId: "fake", Project: "test-project", Current: "12345", Proposed: "67890"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, changes)
+	}
+
+	namespace, _ := api.Get("api/v1/namespaces/test-project")
+	if billing := getAnnotation(namespace.Path("metadata.annotations"), annotationBilling); billing != "12345" {
+		t.Errorf("expected the preview not to change the namespace, got billing %v", billing)
+	}
+}
+
+func TestDeleteProjectHandler(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("own", "u123")
+	api.AddProject("other", "u456")
+
+	tests := []struct {
+		project, confirmation string
+		status                int
+	}{
+		{"other", "other", http.StatusBadRequest},
+		{"own", "typo", http.StatusBadRequest},
+		{"own", "own", http.StatusOK},
+	}
+	for _, test := range tests {
+		body := fmt.Sprintf(`{"clusterid": "fake", "project": %q, "confirmation": %q}`, test.project, test.confirmation)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("DELETE", "/ose/project", strings.NewReader(body))
+		c.Set(gin.AuthUserKey, "u123")
+
+		deleteProjectHandler(c)
+		if w.Code != test.status {
+			t.Errorf("%v/%v: expected %v, got %v %v", test.project, test.confirmation, test.status, w.Code, w.Body.String())
+		}
+	}
+
+	if _, ok := api.Get("api/v1/namespaces/own"); ok {
+		t.Error("expected project own to be deleted")
+	}
+	if _, ok := api.Get("api/v1/namespaces/other"); !ok {
+		t.Error("expected project other to remain")
+	}
+}
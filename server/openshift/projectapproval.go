@@ -0,0 +1,163 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/approval"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	projectCreationKind             = "project-creation"
+	projectApprovalPendingResponse  = "Das Projekt %v muss bewilligt werden und wird danach auf Cluster %v erstellt"
+	projectApprovalDuplicateMessage = "Für das Projekt %v auf Cluster %v gibt es bereits einen offenen Antrag"
+)
+
+type projectApprovalResponse struct {
+	Message string            `json:"message"`
+	Request *approval.Request `json:"request"`
+}
+
+func registerProjectApprovals() {
+	approval.RegisterKind(approval.Kind{
+		Name: projectCreationKind,
+		Apply: func(r approval.Request) error {
+			var data common.NewProjectCommand
+			if err := json.Unmarshal(r.Payload, &data); err != nil {
+				return err
+			}
+			// The requester isn't logged in anymore, so the project is
+			// requested with the token of the portal
+			_, err := createProjectOfCommand(nil, data, r.RequestedBy)
+			return err
+		},
+		Notify: func(r approval.Request) {
+			if r.State == approval.StatePending || r.State == approval.StateApproved {
+				return
+			}
+			if err := sendProjectApprovalDecisionMail(r); err != nil {
+				log.Printf("Can't send e-mail about project request %v: %v", r.ID, err)
+			}
+		},
+	})
+}
+
+// projectApprovalReasons returns why the new project has to be approved. It's
+// empty if 'project_approval.enabled' isn't set or no rule matches
+func projectApprovalReasons(data common.NewProjectCommand) ([]string, error) {
+	cfg := config.Config()
+	if !cfg.GetBool("project_approval.enabled") {
+		return nil, nil
+	}
+	reasons := []string{}
+	if max := cfg.GetInt("project_approval.max_cpu"); max > 0 && data.CPU > max {
+		reasons = append(reasons, fmt.Sprintf("CPU-Quota %v ist grösser als %v", data.CPU, max))
+	}
+	if max := cfg.GetInt("project_approval.max_memory"); max > 0 && data.Memory > max {
+		reasons = append(reasons, fmt.Sprintf("Memory-Quota %v ist grösser als %v", data.Memory, max))
+	}
+	known, err := isKnownBilling(data.Billing)
+	if err != nil {
+		return nil, err
+	}
+	if !known {
+		reasons = append(reasons, fmt.Sprintf("Die Kontierungsnummer %v ist unbekannt", data.Billing))
+	}
+	return reasons, nil
+}
+
+// isKnownBilling checks if the billing number is in 'project_approval.billing'
+// or used by a project on one of the clusters, see getBillingIndex
+func isKnownBilling(billing string) (bool, error) {
+	if contains(config.Config().GetStringSlice("project_approval.billing"), billing) {
+		return true, nil
+	}
+	for _, cluster := range getOpenshiftClusters("") {
+		index, err := getBillingIndex(cluster.ID)
+		if err != nil {
+			return false, err
+		}
+		if len(index[billing]) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// requestProjectApproval puts the new project in the queue of the approvers
+func requestProjectApproval(data common.NewProjectCommand, username string, reasons []string) (*approval.Request, error) {
+	data.Project = strings.ToLower(data.Project)
+	pending, err := approval.List(func(r approval.Request) bool {
+		return r.Kind == projectCreationKind && r.State == approval.StatePending && r.ClusterId == data.ClusterId && r.Project == data.Project
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) > 0 {
+		return nil, fmt.Errorf(projectApprovalDuplicateMessage, data.Project, data.ClusterId)
+	}
+	return approval.Create(projectCreationKind, data.ClusterId, data.Project, username, strings.Join(reasons, ", "), data)
+}
+
+// validateInitialQuota checks the optional quota of a new project against
+// 'max_quota_cpu' and 'max_quota_memory'
+func validateInitialQuota(cpu, memory int) error {
+	if cpu == 0 && memory == 0 {
+		return nil
+	}
+	if cpu < 1 || memory < 1 {
+		return errors.New("CPU und Memory müssen mindestens 1 sein")
+	}
+	cfg := config.Config()
+	if max := cfg.GetInt("max_quota_cpu"); max > 0 && cpu > max {
+		return fmt.Errorf("Der Maximalwert für CPU ist: %v", max)
+	}
+	if max := cfg.GetInt("max_quota_memory"); max > 0 && memory > max {
+		return fmt.Errorf("Der Maximalwert für Memory ist: %v", max)
+	}
+	return nil
+}
+
+// checkInitialQuota checks the optional quota of a new project against the
+// limits of its billing account and the budget, like a change of the quota
+func checkInitialQuota(data common.NewProjectCommand) error {
+	if data.CPU == 0 && data.Memory == 0 {
+		return nil
+	}
+	project := strings.ToLower(data.Project)
+	if err := checkBillingLimitsForBillingQuotas(data.Billing, data.ClusterId, project, data.CPU, data.Memory); err != nil {
+		return err
+	}
+	return checkBudgetForQuotas(data.ClusterId, project, data.CPU, data.Memory)
+}
+
+func sendProjectApprovalDecisionMail(request approval.Request) error {
+	mail := common.GetMailForUser(request.RequestedBy)
+	if mail == "" {
+		return errors.New("no mail address for " + request.RequestedBy)
+	}
+
+	result := "abgelehnt"
+	switch request.State {
+	case approval.StateApplied:
+		result = "bewilligt und das Projekt wurde erstellt"
+	case approval.StateExpired:
+		result = "nicht rechtzeitig bearbeitet und ist abgelaufen. Bitte stelle ihn neu"
+	}
+	return common.SendMail([]string{mail}, fmt.Sprintf("Neues Projekt %v", request.Project), fmt.Sprintf(`
+	Hallo %v,
+	<br><br>
+	Dein Antrag für das Projekt %v auf Cluster %v wurde %v.
+	<br><br>
+	%v
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, request.RequestedBy, request.Project, request.ClusterId, result, request.Comment))
+}
@@ -0,0 +1,108 @@
+package openshift
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/approval"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+var registerProjectApprovalsOnce sync.Once
+
+func TestProjectApproval(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	registerProjectApprovalsOnce.Do(registerProjectApprovals)
+	cfg := config.Config()
+	cfg.Set("project_approval.enabled", true)
+	cfg.Set("project_approval.max_cpu", 10)
+	cfg.Set("project_approval.billing", []string{"70001"})
+	cfg.Set("portal_admins", []string{"admin"})
+	defer cfg.Set("project_approval.enabled", false)
+	if err := createNewProject(nil, "fake", "existing", "u123", "12345", "", false); err != nil {
+		t.Fatal(err)
+	}
+	command := func(billing string, cpu int) common.NewProjectCommand {
+		return common.NewProjectCommand{OpenshiftBase: common.OpenshiftBase{ClusterId: "fake", Project: "Shop"}, Billing: billing, CPU: cpu, Memory: 4}
+	}
+
+	for billing, expected := range map[string]int{"12345": 0, "70001": 0, "99999": 1} {
+		if reasons, err := projectApprovalReasons(command(billing, 4)); err != nil || len(reasons) != expected {
+			t.Errorf("expected %v reasons for billing %v, got %v %v", expected, billing, reasons, err)
+		}
+	}
+	reasons, err := projectApprovalReasons(command("99999", 20))
+	if err != nil || len(reasons) != 2 {
+		t.Fatalf("expected the quota and the billing to need an approval, got %v %v", reasons, err)
+	}
+
+	request, err := requestProjectApproval(command("99999", 20), "u456", reasons)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if request.Project != "shop" || request.State != approval.StatePending {
+		t.Errorf("unexpected request %+v", request)
+	}
+	if _, err := requestProjectApproval(command("99999", 20), "u456", reasons); err == nil {
+		t.Error("expected a second request for the project to be rejected")
+	}
+	if _, ok := api.Get("api/v1/namespaces/shop"); ok {
+		t.Fatal("expected the project not to be created before the approval")
+	}
+
+	if _, err := approval.Approve(request.ID, "admin", "ok"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := api.Get("api/v1/namespaces/shop"); !ok {
+		t.Error("expected the approved project to be created")
+	}
+	if quotas, _ := getQuotas("fake", "shop"); quotas.CPU != 20 {
+		t.Errorf("expected the requested quota, got %+v", quotas)
+	}
+	if admins, _, _ := getProjectAdminsAndOperators("fake", "shop"); !contains(admins, "u456") {
+		t.Errorf("expected the requester to be admin, got %v", admins)
+	}
+}
+
+func TestValidateInitialQuota(t *testing.T) {
+	config.Init("test")
+	config.Config().Set("max_quota_cpu", 30)
+	config.Config().Set("max_quota_memory", 50)
+	for _, q := range [][2]int{{0, 0}, {4, 8}, {30, 50}} {
+		if err := validateInitialQuota(q[0], q[1]); err != nil {
+			t.Errorf("unexpected error for %v: %v", q, err)
+		}
+	}
+	for _, q := range [][2]int{{4, 0}, {31, 8}, {4, 51}} {
+		if err := validateInitialQuota(q[0], q[1]); err == nil {
+			t.Errorf("expected %v to be rejected", q)
+		}
+	}
+}
+
+func TestCreateProjectChecksInitialQuota(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	config.Config().Set("billing_limits.max_cpu", 10)
+	defer config.Config().Set("billing_limits.max_cpu", 0)
+	if err := createNewProject(nil, "fake", "existing", "u123", "12345", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := setQuota("fake", "existing", 8, 8); err != nil {
+		t.Fatal(err)
+	}
+
+	command := common.NewProjectCommand{OpenshiftBase: common.OpenshiftBase{ClusterId: "fake", Project: "shop"}, Billing: "12345", CPU: 4, Memory: 4}
+	if _, err := createProjectOfCommand(nil, command, "u123"); err == nil {
+		t.Error("expected the initial quota to exceed the limit of the billing account")
+	}
+	if _, ok := api.Get("api/v1/namespaces/shop"); ok {
+		t.Error("expected the project not to be created")
+	}
+	command.CPU = 2
+	if _, err := createProjectOfCommand(nil, command, "u123"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,364 @@
+package openshift
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	projectSpecsCollection       = "project_specs"
+	defaultDriftIntervalMinutes  = 60
+	driftFieldBilling            = "billing"
+	driftFieldMegaId             = "megaId"
+	driftFieldQuotaCPU           = "quota.cpu"
+	driftFieldQuotaMemory        = "quota.memory"
+	driftFieldAdmins             = "admins"
+	driftFieldEditors            = "editors"
+	projectSpecNotFoundError     = "Für das Projekt ist keine Spezifikation gespeichert"
+	projectSpecAutoCorrectedUser = "drift-detection"
+)
+
+// StoredProjectSpec is the last applied spec of a project and the drift found
+// by the last check
+type StoredProjectSpec struct {
+	ClusterId string             `json:"clusterid"`
+	Project   string             `json:"project"`
+	Spec      common.ProjectSpec `json:"spec"`
+	AppliedBy string             `json:"appliedBy"`
+	AppliedAt time.Time          `json:"appliedAt"`
+	Drift     []common.DriftItem `json:"drift"`
+	CheckedAt *time.Time         `json:"checkedAt,omitempty"`
+}
+
+// projectState is the live state of the fields of a ProjectSpec
+type projectState struct {
+	Billing string
+	MegaId  string
+	CPU     float64
+	Memory  float64
+	Admins  []string
+	Editors []string
+}
+
+func projectSpecID(clusterId, project string) string {
+	return clusterId + "/" + project
+}
+
+func applyProjectSpecHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.ApplyProjectSpecCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err := validateProjectSpec(data.ClusterId, data.Project, data.Spec); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := applyProjectSpec(data.ClusterId, data.Project, data.Spec, username); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	stored := StoredProjectSpec{
+		ClusterId: data.ClusterId,
+		Project:   data.Project,
+		Spec:      data.Spec,
+		AppliedBy: username,
+		AppliedAt: time.Now(),
+		Drift:     []common.DriftItem{},
+	}
+	if err := store.Put(projectSpecsCollection, projectSpecID(data.ClusterId, data.Project), stored); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v applied the spec of project %v on cluster %v", username, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, stored)
+}
+
+func getProjectDriftHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	var stored StoredProjectSpec
+	found, err := store.Get(projectSpecsCollection, projectSpecID(clusterId, project), &stored)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: projectSpecNotFoundError})
+		return
+	}
+
+	// Check again, so the user sees the current state
+	if err := checkProjectDrift(&stored); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stored)
+}
+
+// validateProjectSpec runs the checks of changing the billing and the quotas
+// of a project, so applying a spec or correcting its drift can't bypass the
+// billing limits, the budget or the maximal quotas
+func validateProjectSpec(clusterId, project string, spec common.ProjectSpec) error {
+	if err := validateMegaId(spec.MegaId); err != nil {
+		return err
+	}
+
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return err
+	}
+	billing := getAnnotation(namespace.Path("metadata.annotations"), annotationBilling)
+	if spec.Billing != "" && spec.Billing != billing {
+		if err := checkBillingLimitsForNewProject(spec.Billing); err != nil {
+			return err
+		}
+		billing = spec.Billing
+	}
+
+	if q := spec.Quota; q != nil {
+		if err := validateQuotaValues(q.CPU, q.Memory); err != nil {
+			return err
+		}
+		if err := checkBillingLimitsForBillingQuotas(billing, clusterId, project, q.CPU, q.Memory); err != nil {
+			return err
+		}
+		return checkBudgetForQuotas(clusterId, project, q.CPU, q.Memory)
+	}
+	return nil
+}
+
+// applyProjectSpec changes the project to match the spec. Members are only
+// added, never removed. The requester of the project is kept
+func applyProjectSpec(clusterId, project string, spec common.ProjectSpec, username string) error {
+	if spec.Billing != "" || spec.MegaId != "" {
+		err := updateNamespaceAnnotations(clusterId, project, func(annotations *gabs.Container) {
+			if spec.Billing != "" {
+				setAnnotation(annotations, annotationBilling, spec.Billing)
+			}
+			if spec.MegaId != "" {
+				setAnnotation(annotations, annotationMegaId, spec.MegaId)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("%v changed the billing of project %v on cluster %v to %v, MegaID: %v", username, project, clusterId, spec.Billing, spec.MegaId)
+	}
+	if spec.Quota != nil {
+		if err := setQuota(clusterId, project, spec.Quota.CPU, spec.Quota.Memory); err != nil {
+			return err
+		}
+	}
+	if len(spec.Admins) > 0 {
+		if err := addUsersToRoleBinding(clusterId, project, "admin", spec.Admins); err != nil {
+			return err
+		}
+	}
+	if len(spec.Editors) > 0 {
+		if err := addUsersToRoleBinding(clusterId, project, "edit", spec.Editors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartDriftDetection compares the stored specs with the live state of the
+// projects every 'drift_detection.interval_minutes' if 'drift_detection.enabled' is set
+func StartDriftDetection() {
+	cfg := config.Config()
+	if !cfg.GetBool("drift_detection.enabled") {
+		return
+	}
+	interval := cfg.GetInt("drift_detection.interval_minutes")
+	if interval <= 0 {
+		interval = defaultDriftIntervalMinutes
+	}
+
+	go func() {
+		for {
+			detectDrift()
+			time.Sleep(time.Duration(interval) * time.Minute)
+		}
+	}()
+}
+
+func detectDrift() {
+	specs := []StoredProjectSpec{}
+	err := store.List(projectSpecsCollection, func(id string, data []byte) error {
+		var s StoredProjectSpec
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		specs = append(specs, s)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error reading project specs: %v", err)
+		return
+	}
+
+	for i := range specs {
+		if err := checkProjectDrift(&specs[i]); err != nil {
+			log.Printf("Error checking drift of project %v on cluster %v: %v", specs[i].Project, specs[i].ClusterId, err)
+		}
+	}
+}
+
+// checkProjectDrift updates the drift of the stored spec and corrects it if
+// the spec has autoCorrect set
+func checkProjectDrift(stored *StoredProjectSpec) error {
+	state, err := getProjectState(stored.ClusterId, stored.Project)
+	if err != nil {
+		return err
+	}
+
+	drift := compareProjectSpec(stored.Spec, state)
+	if len(drift) > 0 {
+		log.Printf("Project %v on cluster %v drifted from its spec: %v", stored.Project, stored.ClusterId, drift)
+		if stored.Spec.AutoCorrect {
+			// The limits may have changed since the spec was applied
+			if err := validateProjectSpec(stored.ClusterId, stored.Project, stored.Spec); err != nil {
+				log.Printf("Can't correct the drift of project %v on cluster %v: %v", stored.Project, stored.ClusterId, err)
+				return storeProjectDrift(stored, drift)
+			}
+			if err := applyProjectSpec(stored.ClusterId, stored.Project, stored.Spec, projectSpecAutoCorrectedUser); err != nil {
+				return err
+			}
+			log.Printf("Corrected the drift of project %v on cluster %v", stored.Project, stored.ClusterId)
+			drift = []common.DriftItem{}
+		}
+	}
+
+	return storeProjectDrift(stored, drift)
+}
+
+func storeProjectDrift(stored *StoredProjectSpec, drift []common.DriftItem) error {
+	checkedAt := time.Now()
+	stored.Drift = drift
+	stored.CheckedAt = &checkedAt
+	return store.Put(projectSpecsCollection, projectSpecID(stored.ClusterId, stored.Project), stored)
+}
+
+// getProjectDrift returns the drift of the last check or nil if the project
+// is not managed by a spec
+func getProjectDrift(clusterId, project string) []common.DriftItem {
+	var stored StoredProjectSpec
+	found, err := store.Get(projectSpecsCollection, projectSpecID(clusterId, project), &stored)
+	if err != nil || !found {
+		return nil
+	}
+	return stored.Drift
+}
+
+// getProjectDrifts returns the drift of the last check of all projects
+// managed by a spec by projectSpecID
+func getProjectDrifts() (map[string][]common.DriftItem, error) {
+	drifts := make(map[string][]common.DriftItem)
+	err := store.List(projectSpecsCollection, func(id string, data []byte) error {
+		var s StoredProjectSpec
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		drifts[id] = s.Drift
+		return nil
+	})
+	return drifts, err
+}
+
+func getProjectState(clusterId, project string) (*projectState, error) {
+	// The drift must be computed on the current state
+	common.GetCache().Delete(namespaceCacheKey(clusterId, project))
+	common.GetCache().Delete(roleBindingCacheKey(clusterId, project))
+
+	state := &projectState{}
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+	annotations := namespace.Path("metadata.annotations")
+	state.Billing = getAnnotation(annotations, annotationBilling)
+	state.MegaId = getAnnotation(annotations, annotationMegaId)
+
+	quotas, err := listObjects(clusterId, fmt.Sprintf("api/v1/namespaces/%v/resourcequotas", project))
+	if err != nil {
+		return nil, err
+	}
+	if q := projectQuota(quotas); q != nil {
+		state.CPU = parseCPUQuantity(q.Path("spec.hard.cpu").Data())
+		state.Memory = parseMemoryQuantityGi(q.Path("spec.hard.memory").Data())
+	}
+
+	if state.Admins, err = getRoleBindingUsers(clusterId, project, "admin"); err != nil {
+		return nil, err
+	}
+	if state.Editors, err = getRoleBindingUsers(clusterId, project, "edit"); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// compareProjectSpec returns the fields of the spec which differ from the state
+func compareProjectSpec(spec common.ProjectSpec, state *projectState) []common.DriftItem {
+	drift := []common.DriftItem{}
+	if spec.Billing != "" && spec.Billing != state.Billing {
+		drift = append(drift, common.DriftItem{Field: driftFieldBilling, Expected: spec.Billing, Actual: state.Billing})
+	}
+	if spec.MegaId != "" && spec.MegaId != state.MegaId {
+		drift = append(drift, common.DriftItem{Field: driftFieldMegaId, Expected: spec.MegaId, Actual: state.MegaId})
+	}
+	if q := spec.Quota; q != nil {
+		if float64(q.CPU) != state.CPU {
+			drift = append(drift, common.DriftItem{Field: driftFieldQuotaCPU, Expected: fmt.Sprint(q.CPU), Actual: fmt.Sprint(state.CPU)})
+		}
+		if float64(q.Memory) != state.Memory {
+			drift = append(drift, common.DriftItem{Field: driftFieldQuotaMemory, Expected: fmt.Sprint(q.Memory), Actual: fmt.Sprint(state.Memory)})
+		}
+	}
+	if missing := missingUsers(spec.Admins, state.Admins); len(missing) > 0 {
+		drift = append(drift, common.DriftItem{Field: driftFieldAdmins, Expected: strings.Join(missing, ", "), Actual: strings.Join(state.Admins, ", ")})
+	}
+	if missing := missingUsers(spec.Editors, state.Editors); len(missing) > 0 {
+		drift = append(drift, common.DriftItem{Field: driftFieldEditors, Expected: strings.Join(missing, ", "), Actual: strings.Join(state.Editors, ", ")})
+	}
+	return drift
+}
+
+func missingUsers(expected, actual []string) []string {
+	missing := []string{}
+	for _, u := range expected {
+		if !contains(actual, strings.ToLower(u)) {
+			missing = append(missing, strings.ToLower(u))
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
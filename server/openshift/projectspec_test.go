@@ -0,0 +1,94 @@
+package openshift
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+)
+
+func TestCompareProjectSpec(t *testing.T) {
+	spec := common.ProjectSpec{
+		Billing: "12345",
+		Quota:   &common.QuotaSpec{CPU: 4, Memory: 8},
+		Admins:  []string{"U123", "u456"},
+	}
+	state := &projectState{
+		Billing: "12345",
+		CPU:     4,
+		Memory:  16,
+		Admins:  []string{"u123", "u999"},
+	}
+
+	drift := compareProjectSpec(spec, state)
+	if len(drift) != 2 {
+		t.Fatalf("expected 2 drift items, got %v", drift)
+	}
+	if drift[0].Field != driftFieldQuotaMemory || drift[0].Expected != "8" {
+		t.Errorf("unexpected memory drift: %v", drift[0])
+	}
+	if drift[1].Field != driftFieldAdmins || drift[1].Expected != "u456" {
+		t.Errorf("unexpected admin drift: %v", drift[1])
+	}
+
+	state.Memory = 8
+	state.Admins = append(state.Admins, "u456")
+	if drift := compareProjectSpec(spec, state); len(drift) != 0 {
+		t.Errorf("expected no drift, got %v", drift)
+	}
+}
+
+func TestCheckProjectDriftKeepsRequesterAndLimits(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("own", "u123")
+	cfg := config.Config()
+	cfg.Set("max_quota_cpu", 8)
+	cfg.Set("max_quota_memory", 16)
+
+	stored := &StoredProjectSpec{
+		ClusterId: "fake",
+		Project:   "own",
+		Spec:      common.ProjectSpec{Billing: "99999", AutoCorrect: true},
+	}
+	if err := checkProjectDrift(stored); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	namespace, _ := api.Get("api/v1/namespaces/own")
+	annotations := namespace.Path("metadata.annotations")
+	if getAnnotation(annotations, annotationBilling) != "99999" || getAnnotation(annotations, annotationRequester) != "u123" {
+		t.Errorf("expected the billing to be corrected and the requester to be kept, got %v", annotations)
+	}
+	if len(stored.Drift) != 0 {
+		t.Errorf("expected the drift to be corrected, got %v", stored.Drift)
+	}
+
+	// The quota exceeds the maximum, so the drift isn't corrected
+	stored.Spec.Quota = &common.QuotaSpec{CPU: 16, Memory: 8}
+	if err := checkProjectDrift(stored); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stored.Drift) == 0 {
+		t.Error("expected the drift of the quota to be kept")
+	}
+	if quotas, _ := getQuotas("fake", "own"); quotas != nil && quotas.CPU == 16 {
+		t.Errorf("expected the quota not to be set, got %+v", quotas)
+	}
+}
+
+func TestGetProjectDrifts(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+	drift := []common.DriftItem{{Field: driftFieldBilling, Expected: "12345", Actual: "99999"}}
+	store.Put(projectSpecsCollection, projectSpecID("fake", "own"), StoredProjectSpec{ClusterId: "fake", Project: "own", Drift: drift})
+
+	drifts, err := getProjectDrifts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 1 || !reflect.DeepEqual(drifts[projectSpecID("fake", "own")], getProjectDrift("fake", "own")) {
+		t.Errorf("expected the drift of the project, got %v", drifts)
+	}
+}
@@ -0,0 +1,139 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultProjectTemplateNamespace = "openshift"
+
+// ProjectTemplate is an OpenShift template new projects can be created from
+type ProjectTemplate struct {
+	Name        string                     `json:"name"`
+	DisplayName string                     `json:"displayName,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Parameters  []ProjectTemplateParameter `json:"parameters"`
+}
+
+type ProjectTemplateParameter struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName,omitempty"`
+	Description string `json:"description,omitempty"`
+	Value       string `json:"value,omitempty"`
+	Required    bool   `json:"required"`
+	// Generated parameters get a random value if they aren't set
+	Generated bool `json:"generated"`
+}
+
+// projectTemplateNamespace is the namespace of the template catalog
+func projectTemplateNamespace() string {
+	if namespace := config.Config().GetString("project_templates.namespace"); namespace != "" {
+		return namespace
+	}
+	return defaultProjectTemplateNamespace
+}
+
+func getProjectTemplatesHandler(c *gin.Context) {
+	templates, err := getProjectTemplates(c.Query("clusterid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+func getProjectTemplates(clusterId string) ([]ProjectTemplate, error) {
+	if _, err := getOpenshiftCluster(clusterId); err != nil {
+		return nil, err
+	}
+	objects, err := listObjects(clusterId, fmt.Sprintf("oapi/v1/namespaces/%v/templates", projectTemplateNamespace()))
+	if err != nil {
+		return nil, err
+	}
+	templates := []ProjectTemplate{}
+	for _, o := range objects {
+		templates = append(templates, toProjectTemplate(o))
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+func toProjectTemplate(template *gabs.Container) ProjectTemplate {
+	t := ProjectTemplate{Parameters: []ProjectTemplateParameter{}}
+	t.Name, _ = template.Path("metadata.name").Data().(string)
+	annotations := template.Path("metadata.annotations")
+	t.DisplayName, _ = annotations.S("openshift.io/display-name").Data().(string)
+	t.Description, _ = annotations.S("description").Data().(string)
+
+	parameters, _ := template.S("parameters").Children()
+	for _, p := range parameters {
+		parameter := ProjectTemplateParameter{}
+		parameter.Name, _ = p.S("name").Data().(string)
+		parameter.DisplayName, _ = p.S("displayName").Data().(string)
+		parameter.Description, _ = p.S("description").Data().(string)
+		parameter.Value, _ = p.S("value").Data().(string)
+		parameter.Required, _ = p.S("required").Data().(bool)
+		parameter.Generated = p.Exists("generate")
+		t.Parameters = append(t.Parameters, parameter)
+	}
+	return t
+}
+
+// validateProjectTemplate checks that the template is in the catalog, that
+// it has all the parameters and that all required parameters have a value
+func validateProjectTemplate(clusterId, name string, parameters map[string]string) error {
+	if name == "" {
+		if len(parameters) > 0 {
+			return errors.New("Parameter können nur mit einem Template angegeben werden")
+		}
+		return nil
+	}
+	object, err := getTemplate(clusterId, projectTemplateNamespace(), name)
+	if err != nil {
+		return err
+	}
+	template := toProjectTemplate(object)
+
+	known := make(map[string]bool)
+	missing := []string{}
+	for _, p := range template.Parameters {
+		known[p.Name] = true
+		if p.Required && !p.Generated && p.Value == "" && parameters[p.Name] == "" {
+			missing = append(missing, p.Name)
+		}
+	}
+	unknown := []string{}
+	for p := range parameters {
+		if !known[p] {
+			unknown = append(unknown, p)
+		}
+	}
+	sort.Strings(unknown)
+
+	if len(unknown) > 0 {
+		return fmt.Errorf("Das Template %v hat keine Parameter %v", name, strings.Join(unknown, ", "))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("Die Parameter %v des Templates %v müssen angegeben werden", strings.Join(missing, ", "), name)
+	}
+	return nil
+}
+
+// instantiateProjectTemplate creates the objects of the template of the
+// catalog in the new project
+func instantiateProjectTemplate(clusterId, project, name string, parameters map[string]string) error {
+	if err := instantiateTemplate(clusterId, projectTemplateNamespace(), name, strings.ToLower(project), parameters); err != nil {
+		return fmt.Errorf("Das Projekt %v wurde erstellt, aber das Template %v konnte nicht angewendet werden: %v", project, name, err)
+	}
+	return nil
+}
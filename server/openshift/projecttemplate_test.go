@@ -0,0 +1,70 @@
+package openshift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/gabs"
+	"github.com/gin-gonic/gin"
+)
+
+const javaAppTemplate = `{
+	"kind": "Template",
+	"apiVersion": "v1",
+	"metadata": {"name": "java-app", "namespace": "openshift", "annotations": {"description": "Java Applikation"}},
+	"parameters": [
+		{"name": "APP_NAME", "required": true},
+		{"name": "JAVA_VERSION", "value": "11"},
+		{"name": "SECRET", "required": true, "generate": "expression", "from": "[a-z]{10}"}
+	],
+	"objects": [
+		{"kind": "Service", "apiVersion": "v1", "metadata": {"name": "app"}}
+	]
+}`
+
+func TestNewProjectFromTemplate(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	template, _ := gabs.ParseJSON([]byte(javaAppTemplate))
+	api.Set("oapi/v1/namespaces/openshift/templates/java-app", template)
+
+	templates, err := getProjectTemplates("fake")
+	if err != nil || len(templates) != 1 || templates[0].Description != "Java Applikation" || len(templates[0].Parameters) != 3 {
+		t.Fatalf("expected the java-app template, got %+v %v", templates, err)
+	}
+
+	tests := []struct {
+		template   string
+		parameters map[string]string
+		valid      bool
+	}{
+		{"", nil, true},
+		{"java-app", map[string]string{"APP_NAME": "app"}, true},
+		{"java-app", map[string]string{"APP_NAME": "app", "JAVA_VERSION": "17"}, true},
+		{"java-app", map[string]string{}, false},
+		{"java-app", map[string]string{"APP_NAME": "app", "DEBUG": "true"}, false},
+		{"static-site", map[string]string{}, false},
+		{"", map[string]string{"APP_NAME": "app"}, false},
+	}
+	for _, test := range tests {
+		err := validateProjectTemplate("fake", test.template, test.parameters)
+		if (err == nil) != test.valid {
+			t.Errorf("%+v: expected valid %v, got %v", test, test.valid, err)
+		}
+	}
+
+	body := `{"clusterid": "fake", "project": "java", "billing": "12345", "template": "java-app", "parameters": {"APP_NAME": "app"}}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/ose/project", strings.NewReader(body))
+	c.Set(gin.AuthUserKey, "u123")
+	newProjectHandler(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v %v", w.Code, w.Body.String())
+	}
+	if _, ok := api.Get("api/v1/namespaces/java/services/app"); !ok {
+		t.Error("expected the service of the template to be created in the new project")
+	}
+}
@@ -4,20 +4,25 @@ import (
 	"bytes"
 	"errors"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"strconv"
 
 	"fmt"
 
 	"github.com/Jeffail/gabs"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/webhooks"
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
 	getQuotasApiError = "Error getting quotas from ose-api: %v"
 	jsonDecodingError = "Error decoding json from ose api: %v"
+
+	// defaultQuotaName is the resourcequota created by the portal
+	defaultQuotaName = "default"
 )
 
 func editQuotasHandler(c *gin.Context) {
@@ -30,6 +35,16 @@ func editQuotasHandler(c *gin.Context) {
 			return
 		}
 
+		if common.IsDryRun(c) {
+			change, err := planQuota(data.ClusterId, data.Project, data.CPU, data.Memory)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+				return
+			}
+			common.RespondDryRun(c, "Die Quotas würden geändert", *change)
+			return
+		}
+
 		if err := updateQuotas(data.ClusterId, username, data.Project, data.CPU, data.Memory); err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 		} else {
@@ -43,14 +58,69 @@ func editQuotasHandler(c *gin.Context) {
 	}
 }
 
-func validateEditQuotas(clusterId, username, project string, cpu int, memory int) error {
+// getQuotasHandler returns the quota of the project and the maxima a
+// project admin can set
+func getQuotasHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	quotas, err := getQuotas(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, quotas)
+}
+
+// getQuotas returns the resourcequota of the project which the portal
+// manages, see projectQuota
+func getQuotas(clusterId, project string) (*common.QuotasResponse, error) {
+	quotas, err := listObjects(clusterId, "api/v1/namespaces/"+project+"/resourcequotas")
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := config.Config()
-	maxCPU := cfg.GetInt("max_quota_cpu")
-	maxMemory := cfg.GetInt("max_quota_memory")
+	response := &common.QuotasResponse{
+		MaxCPU:    cfg.GetInt("max_quota_cpu"),
+		MaxMemory: cfg.GetInt("max_quota_memory"),
+	}
+	if q := projectQuota(quotas); q != nil {
+		response.CPU = parseCPUQuantity(q.Path("spec.hard.cpu").Data())
+		response.Memory = parseMemoryQuantityGi(q.Path("spec.hard.memory").Data())
+		response.UsedCPU = parseCPUQuantity(q.Path("status.used.cpu").Data())
+		response.UsedMemory = parseMemoryQuantityGi(q.Path("status.used.memory").Data())
+	}
+	return response, nil
+}
 
-	if maxCPU == 0 || maxMemory == 0 {
-		log.Println("WARNING: Env variables 'MAX_QUOTA_MEMORY' and 'MAX_QUOTA_CPU' must be specified and valid integers")
-		return errors.New(common.ConfigNotSetError)
+// projectQuota returns the resourcequota which the portal reads and changes
+// among the quotas of a project: the one named default or, in older
+// projects, the first one with cpu or memory. Other quotas, e.g. of the
+// recycle bin, are ignored. nil if the project has none
+func projectQuota(quotas []*gabs.Container) *gabs.Container {
+	var first *gabs.Container
+	for _, q := range quotas {
+		name, _ := q.Path("metadata.name").Data().(string)
+		if name == defaultQuotaName {
+			return q
+		}
+		if first == nil && name != recycleBinQuota && (q.Exists("spec", "hard", "cpu") || q.Exists("spec", "hard", "memory")) {
+			first = q
+		}
+	}
+	return first
+}
+
+func validateEditQuotas(clusterId, username, project string, cpu int, memory int) error {
+	if err := validateQuotaValues(cpu, memory); err != nil {
+		return err
 	}
 
 	// Validate user input
@@ -62,6 +132,33 @@ func validateEditQuotas(clusterId, username, project string, cpu int, memory int
 		return errors.New("Projekt muss angegeben werden")
 	}
 
+	// Validate permissions
+	if err := checkAdminPermissions(clusterId, username, project); err != nil {
+		return err
+	}
+
+	if err := checkBillingLimitsForQuotas(clusterId, project, cpu, memory); err != nil {
+		return err
+	}
+
+	return checkBudgetForQuotas(clusterId, project, cpu, memory)
+}
+
+// validateQuotaValues checks the quotas against 'max_quota_cpu' and 'max_quota_memory'
+func validateQuotaValues(cpu int, memory int) error {
+	cfg := config.Config()
+	maxCPU := cfg.GetInt("max_quota_cpu")
+	maxMemory := cfg.GetInt("max_quota_memory")
+
+	if maxCPU == 0 || maxMemory == 0 {
+		log.Println("WARNING: Env variables 'MAX_QUOTA_MEMORY' and 'MAX_QUOTA_CPU' must be specified and valid integers")
+		return errors.New(common.ConfigNotSetError)
+	}
+
+	if cpu < 1 || memory < 1 {
+		return errors.New("CPU und Memory müssen mindestens 1 sein")
+	}
+
 	if cpu > maxCPU {
 		return fmt.Errorf("Der Maximalwert für CPU ist: %v", maxCPU)
 	}
@@ -69,44 +166,110 @@ func validateEditQuotas(clusterId, username, project string, cpu int, memory int
 	if memory > maxMemory {
 		return fmt.Errorf("Der Maximalwert für Memory ist: %v", maxMemory)
 	}
-
-	// Validate permissions
-	resp := checkAdminPermissions(clusterId, username, project)
-	return resp
+	return nil
 }
 
 func updateQuotas(clusterId, username, project string, cpu int, memory int) error {
-	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces/"+project+"/resourcequotas", nil)
+	previous, err := getQuotas(clusterId, project)
 	if err != nil {
+		log.Printf("Can't read the quotas of project %v on cluster %v before the change: %v", project, clusterId, err)
+	}
+	if err := setQuota(clusterId, project, cpu, memory); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	log.Printf("User %v changed quotas for the project %v on cluster %v. CPU: %v Mem: %v", username, project, clusterId, cpu, memory)
+	if previous != nil && (float64(cpu) > previous.CPU || float64(memory) > previous.Memory) {
+		common.PostChat(common.ChatQuotaIncreased, fmt.Sprintf("%v increased the quota of project %v on cluster %v: CPU %v -> %v, memory %v -> %v GB",
+			username, project, clusterId, previous.CPU, cpu, previous.Memory, memory))
+	}
+	if requester := getProjectRequester(clusterId, project); requester != "" {
+		common.Notify(common.Notification{
+			Event: common.NotificationQuotaChanged, Username: requester, ClusterId: clusterId, Project: project,
+			Details: map[string]string{"cpu": strconv.Itoa(cpu), "memory": strconv.Itoa(memory), "changedBy": username},
+		})
+	}
+	webhooks.Publish(webhooks.Event{
+		Type: webhooks.EventQuotaChanged, ClusterId: clusterId, Project: project,
+		Data: map[string]interface{}{"cpu": cpu, "memory": memory, "changedBy": username},
+	})
+	return nil
+}
 
-	json, err := gabs.ParseJSONBuffer(resp.Body)
+// setQuota updates the resourcequota of the project, see projectQuota, or
+// creates one if the project has none
+func setQuota(clusterId, project string, cpu int, memory int) error {
+	method, url, quota, err := quotaRequest(clusterId, project, cpu, memory)
 	if err != nil {
-		log.Printf(jsonDecodingError, err)
-		return errors.New(genericAPIError)
+		return err
 	}
-
-	firstQuota := json.S("items").Index(0)
-
-	firstQuota.SetP(cpu, "spec.hard.cpu")
-	firstQuota.SetP(fmt.Sprintf("%vGi", memory), "spec.hard.memory")
-
-	resp, err = getOseHTTPClient("PUT",
-		clusterId,
-		"api/v1/namespaces/"+project+"/resourcequotas/"+firstQuota.Path("metadata.name").Data().(string),
-		bytes.NewReader(firstQuota.Bytes()))
+	resp, err := getOseHTTPClient(method, clusterId, url, bytes.NewReader(quota.Bytes()))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		errMsg, _ := ioutil.ReadAll(resp.Body)
 		log.Println("Error updating resourceQuota:", resp.StatusCode, string(errMsg))
 		return errors.New(genericAPIError)
 	}
-	log.Printf("User %v changed quotas for the project %v on cluster %v. CPU: %v Mem: %v", username, clusterId, project, cpu, memory)
 	return nil
 }
+
+// planQuota returns the change setQuota would make and validates it on the
+// cluster if it supports dry-run
+func planQuota(clusterId, project string, cpu int, memory int) (*common.PlannedChange, error) {
+	method, url, quota, err := quotaRequest(clusterId, project, cpu, memory)
+	if err != nil {
+		return nil, err
+	}
+	if err := dryRunOnCluster(clusterId, method, url, quota); err != nil {
+		return nil, err
+	}
+
+	action := common.DryRunActionUpdate
+	if method == "POST" {
+		action = common.DryRunActionCreate
+	}
+	name, _ := quota.Path("metadata.name").Data().(string)
+	return &common.PlannedChange{
+		Action:    action,
+		Kind:      "ResourceQuota",
+		Name:      name,
+		ClusterId: clusterId,
+		Project:   project,
+		Details:   fmt.Sprintf("CPU: %v, Memory: %vGi", cpu, memory),
+	}, nil
+}
+
+// quotaRequest returns the method, url and body to set the quota of the project
+func quotaRequest(clusterId, project string, cpu int, memory int) (string, string, *gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces/"+project+"/resourcequotas", nil)
+	if err != nil {
+		return "", "", nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return "", "", nil, errors.New(genericAPIError)
+	}
+
+	method := "PUT"
+	items, _ := json.S("items").Children()
+	quota := projectQuota(items)
+	if quota == nil {
+		method = "POST"
+		quota = newObjectRequest("ResourceQuota", defaultQuotaName)
+	}
+
+	quota.SetP(cpu, "spec.hard.cpu")
+	quota.SetP(fmt.Sprintf("%vGi", memory), "spec.hard.memory")
+
+	url := "api/v1/namespaces/" + project + "/resourcequotas"
+	if method == "PUT" {
+		url += "/" + quota.Path("metadata.name").Data().(string)
+	}
+	return method, url, quota, nil
+}
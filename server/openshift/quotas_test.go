@@ -0,0 +1,77 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestEditQuotas(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("own", "u123")
+	config.Config().Set("max_quota_cpu", 8)
+	config.Config().Set("max_quota_memory", 16)
+
+	tests := []struct {
+		cpu, memory int
+		valid       bool
+	}{
+		{4, 8, true},
+		{8, 16, true},
+		{9, 8, false},
+		{4, 17, false},
+		{0, 8, false},
+	}
+	for _, test := range tests {
+		err := validateEditQuotas("fake", "u123", "own", test.cpu, test.memory)
+		if (err == nil) != test.valid {
+			t.Errorf("cpu %v, memory %v: expected valid %v, got %v", test.cpu, test.memory, test.valid, err)
+		}
+	}
+	if err := validateEditQuotas("fake", "u456", "own", 4, 8); err == nil {
+		t.Error("expected an error for a user who isn't admin")
+	}
+
+	if err := updateQuotas("fake", "u123", "own", 6, 12); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	quotas, err := getQuotas("fake", "own")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if quotas.CPU != 6 || quotas.Memory != 12 || quotas.MaxCPU != 8 || quotas.MaxMemory != 16 {
+		t.Errorf("expected cpu 6 and memory 12 with maxima 8 and 16, got %+v", quotas)
+	}
+}
+
+func TestQuotasReadAndWriteTheSameQuota(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("own", "u123")
+	// Quotas which the portal doesn't manage are listed before its own
+	for name, hard := range map[string]map[string]interface{}{
+		"a-extra":        {"cpu": "100", "memory": "200Gi"},
+		recycleBinQuota:  {"pods": "0"},
+		defaultQuotaName: {"cpu": "2", "memory": "4Gi"},
+	} {
+		quota := newObjectRequest("ResourceQuota", name)
+		quota.Set(hard, "spec", "hard")
+		api.Set("api/v1/namespaces/own/resourcequotas/"+name, quota)
+	}
+
+	if err := setQuota("fake", "own", 6, 12); err != nil {
+		t.Fatal(err)
+	}
+	quotas, err := getQuotas("fake", "own")
+	if err != nil || quotas.CPU != 6 || quotas.Memory != 12 {
+		t.Errorf("expected the quota set by the portal, got %+v %v", quotas, err)
+	}
+	extra, _ := api.Get("api/v1/namespaces/own/resourcequotas/a-extra")
+	if cpu, _ := extra.Path("spec.hard.cpu").Data().(string); cpu != "100" {
+		t.Errorf("expected the other quota to be unchanged, got %v", extra)
+	}
+	if state, err := getProjectState("fake", "own"); err != nil || state.CPU != 6 || state.Memory != 12 {
+		t.Errorf("expected the project state to read the same quota, got %+v %v", state, err)
+	}
+}
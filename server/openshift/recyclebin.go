@@ -0,0 +1,234 @@
+package openshift
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	recycleBinCollection = "recycle_bin"
+	recycleBinAnnotation = "openshift.io/recycle-bin-purge-at"
+	// recycleBinQuota stops the pods of a project in the recycle bin
+	recycleBinQuota = "recycle-bin"
+)
+
+// RecycledProject is a deleted project which can be restored until PurgeAt
+type RecycledProject struct {
+	ClusterId string    `json:"clusterid"`
+	Project   string    `json:"project"`
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt"`
+}
+
+func recycledProjectID(clusterId, project string) string {
+	return clusterId + "/" + project
+}
+
+// recycleBinRetention is 0 if 'recycle_bin.retention_days' isn't set and
+// projects are deleted immediately
+func recycleBinRetention() time.Duration {
+	return time.Duration(config.Config().GetInt("recycle_bin.retention_days")) * 24 * time.Hour
+}
+
+func inRecycleBin(annotations *gabs.Container) bool {
+	return annotations.Exists(recycleBinAnnotation)
+}
+
+// recycleProject suspends the project instead of deleting it. The pods are
+// deleted and a quota prevents new ones. The volumes and all other objects
+// are kept until the project is purged
+func recycleProject(clusterId, project string, now time.Time) error {
+	purgeAt := now.Add(recycleBinRetention())
+	already := false
+	err := updateNamespaceAnnotations(clusterId, project, func(annotations *gabs.Container) {
+		if already = inRecycleBin(annotations); !already {
+			annotations.Set(purgeAt.Format(time.RFC3339), recycleBinAnnotation)
+		}
+	})
+	if err != nil || already {
+		return err
+	}
+
+	quota := newObjectRequest("ResourceQuota", recycleBinQuota)
+	quota.SetP("0", "spec.hard.pods")
+	resp, err := getOseHTTPClient("POST", clusterId, "api/v1/namespaces/"+project+"/resourcequotas", bytes.NewReader(quota.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusConflict {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error creating the recycle bin quota:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+
+	pods, err := listObjects(clusterId, "api/v1/namespaces/"+project+"/pods")
+	if err != nil {
+		return err
+	}
+	for _, p := range pods {
+		name, _ := p.Path("metadata.name").Data().(string)
+		resp, err := getOseHTTPClient("DELETE", clusterId, "api/v1/namespaces/"+project+"/pods/"+name, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+
+	return store.Put(recycleBinCollection, recycledProjectID(clusterId, project), RecycledProject{
+		ClusterId: clusterId,
+		Project:   project,
+		DeletedAt: now,
+		PurgeAt:   purgeAt,
+	})
+}
+
+// restoreProject removes the project from the recycle bin. The pods are
+// started again by their controllers
+func restoreProject(clusterId, project string) error {
+	id := recycledProjectID(clusterId, project)
+	found, err := store.Get(recycleBinCollection, id, &RecycledProject{})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("Das Projekt %v ist nicht im Papierkorb", project)
+	}
+
+	resp, err := getOseHTTPClient("DELETE", clusterId, "api/v1/namespaces/"+project+"/resourcequotas/"+recycleBinQuota, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error deleting the recycle bin quota:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+
+	err = updateNamespaceAnnotations(clusterId, project, func(annotations *gabs.Container) {
+		annotations.Delete(recycleBinAnnotation)
+	})
+	if err != nil {
+		return err
+	}
+	return store.Delete(recycleBinCollection, id)
+}
+
+func getRecycledProjects() ([]RecycledProject, error) {
+	projects := []RecycledProject{}
+	err := store.List(recycleBinCollection, func(id string, data []byte) error {
+		var p RecycledProject
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		projects = append(projects, p)
+		return nil
+	})
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].PurgeAt.Before(projects[j].PurgeAt)
+	})
+	return projects, err
+}
+
+// StartRecycleBinPurge deletes the projects of the recycle bin after the
+// retention every hour
+func StartRecycleBinPurge() {
+	if recycleBinRetention() <= 0 {
+		return
+	}
+
+	go func() {
+		for {
+			purgeRecycleBin(common.Now())
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+func purgeRecycleBin(now time.Time) {
+	projects, err := getRecycledProjects()
+	if err != nil {
+		log.Printf("Error listing the recycle bin: %v", err)
+		return
+	}
+	for _, p := range projects {
+		if p.PurgeAt.After(now) {
+			continue
+		}
+		if err := checkLegalHold(p.ClusterId, p.Project, "purge"); err != nil {
+			continue
+		}
+		if err := purgeProject(p.ClusterId, p.Project); err != nil {
+			log.Printf("Error purging project %v on cluster %v: %v", p.Project, p.ClusterId, err)
+			continue
+		}
+		if err := store.Delete(recycleBinCollection, recycledProjectID(p.ClusterId, p.Project)); err != nil {
+			log.Printf("Error removing project %v on cluster %v from the recycle bin: %v", p.Project, p.ClusterId, err)
+			continue
+		}
+		log.Printf("Purged project %v on cluster %v from the recycle bin", p.Project, p.ClusterId)
+	}
+}
+
+// getRecycleBinHandler lists the projects of the recycle bin the user is
+// admin of. Portal admins see all
+func getRecycleBinHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	projects, err := getRecycledProjects()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if common.IsPortalAdmin(username) {
+		c.JSON(http.StatusOK, projects)
+		return
+	}
+	own := []RecycledProject{}
+	for _, p := range projects {
+		if checkAdminPermissions(p.ClusterId, username, p.Project) == nil {
+			own = append(own, p)
+		}
+	}
+	c.JSON(http.StatusOK, own)
+}
+
+func restoreProjectHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.OpenshiftBase
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if !common.IsPortalAdmin(username) {
+		if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	}
+
+	if err := restoreProject(data.ClusterId, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v restored project %v on cluster %v from the recycle bin", username, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Das Projekt %v wurde wiederhergestellt", data.Project),
+	})
+}
@@ -0,0 +1,58 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestRecycleBin(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	config.Config().Set("recycle_bin.retention_days", 7)
+	api.AddProject("shop", "u123")
+	pod, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "web-1"}}`))
+	api.Set("api/v1/namespaces/shop/pods/web-1", pod)
+
+	if err := deleteProject("fake", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	namespace, ok := api.Get("api/v1/namespaces/shop")
+	if !ok || !inRecycleBin(namespace.Path("metadata.annotations")) {
+		t.Fatalf("expected the project to be in the recycle bin, got %v", namespace)
+	}
+	if _, ok := api.Get("api/v1/namespaces/shop/pods/web-1"); ok {
+		t.Error("expected the pods to be stopped")
+	}
+	if _, ok := api.Get("api/v1/namespaces/shop/resourcequotas/" + recycleBinQuota); !ok {
+		t.Error("expected a quota to prevent new pods")
+	}
+
+	if err := restoreProject("fake", "shop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	namespace, _ = api.Get("api/v1/namespaces/shop")
+	if inRecycleBin(namespace.Path("metadata.annotations")) {
+		t.Error("expected the restored project not to be in the recycle bin")
+	}
+	if _, ok := api.Get("api/v1/namespaces/shop/resourcequotas/" + recycleBinQuota); ok {
+		t.Error("expected the quota of the recycle bin to be removed")
+	}
+
+	if err := deleteProject("fake", "shop"); err != nil {
+		t.Fatal(err)
+	}
+	purgeRecycleBin(time.Now().AddDate(0, 0, 6))
+	if _, ok := api.Get("api/v1/namespaces/shop"); !ok {
+		t.Error("expected the project to be kept during the retention")
+	}
+	purgeRecycleBin(time.Now().AddDate(0, 0, 8))
+	if _, ok := api.Get("api/v1/namespaces/shop"); ok {
+		t.Error("expected the project to be purged after the retention")
+	}
+	if projects, _ := getRecycledProjects(); len(projects) != 0 {
+		t.Errorf("expected an empty recycle bin, got %+v", projects)
+	}
+}
@@ -0,0 +1,224 @@
+package openshift
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	repairStatusOk      = "ok"
+	repairStatusFixed   = "fixed"
+	repairStatusSkipped = "skipped"
+	repairStatusFailed  = "failed"
+)
+
+func repairProjectHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.RepairProjectCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if data.ClusterId == "" || data.Project == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Cluster und Projekt müssen angegeben werden"})
+		return
+	}
+
+	audit.Describe(c, "%v started the repair of project %v on cluster %v", username, data.Project, data.ClusterId)
+
+	steps := repairProject(data.ClusterId, data.Project, data.Billing, data.Owner, username)
+	for _, s := range steps {
+		common.Logger(c).Printf("Repair of project %v on cluster %v: %v %v %v", data.Project, data.ClusterId, s.Name, s.Status, s.Message)
+	}
+
+	c.JSON(http.StatusOK, common.RepairProjectResponse{
+		Project: data.Project,
+		Steps:   steps,
+	})
+}
+
+// repairProject re-runs the steps after the creation of a project. Every step
+// only changes something if it is missing, so it can be called multiple times
+func repairProject(clusterId, project, billing, owner, username string) []common.RepairStep {
+	steps := []common.RepairStep{}
+
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return append(steps, repairStep("namespace", err))
+	}
+	annotations := namespace.Path("metadata.annotations")
+
+	if owner == "" {
+		owner = getAnnotation(annotations, annotationRequester)
+	}
+	steps = append(steps, repairAdminRoleBinding(clusterId, project, owner))
+
+	currentBilling := getAnnotation(annotations, annotationBilling)
+	steps = append(steps, repairBilling(clusterId, project, currentBilling, billing))
+
+	steps = append(steps, repairDefaultQuota(clusterId, project))
+	steps = append(steps, repairLimitRange(clusterId, project))
+	steps = append(steps, repairPullSecret(clusterId, project))
+
+	return steps
+}
+
+func repairStep(name string, err error) common.RepairStep {
+	if err != nil {
+		return common.RepairStep{Name: name, Status: repairStatusFailed, Message: err.Error()}
+	}
+	return common.RepairStep{Name: name, Status: repairStatusFixed}
+}
+
+func repairAdminRoleBinding(clusterId, project, owner string) common.RepairStep {
+	const step = "admin-rolebinding"
+	if owner == "" {
+		return common.RepairStep{Name: step, Status: repairStatusSkipped, Message: "Kein Besitzer bekannt"}
+	}
+
+	admins, _, err := getProjectAdminsAndOperators(clusterId, project)
+	if err != nil {
+		return repairStep(step, err)
+	}
+	if contains(admins, strings.ToLower(owner)) {
+		return common.RepairStep{Name: step, Status: repairStatusOk}
+	}
+
+	return repairStep(step, changeProjectPermission(nil, clusterId, project, owner))
+}
+
+// repairBilling sets the billing of the project. The requester and the other
+// annotations are kept
+func repairBilling(clusterId, project, currentBilling, billing string) common.RepairStep {
+	const step = "billing"
+	if currentBilling != "" && billing == "" {
+		return common.RepairStep{Name: step, Status: repairStatusOk}
+	}
+	if billing == "" {
+		return common.RepairStep{Name: step, Status: repairStatusFailed, Message: "Kontierungsnummer fehlt und muss angegeben werden"}
+	}
+	if billing == currentBilling {
+		return common.RepairStep{Name: step, Status: repairStatusOk}
+	}
+
+	return repairStep(step, updateNamespaceAnnotations(clusterId, project, func(annotations *gabs.Container) {
+		setAnnotation(annotations, annotationBilling, billing)
+	}))
+}
+
+func repairDefaultQuota(clusterId, project string) common.RepairStep {
+	const step = "quota"
+	cfg := config.Config()
+	cpu := cfg.GetInt("default_quota_cpu")
+	memory := cfg.GetInt("default_quota_memory")
+	if cpu == 0 || memory == 0 {
+		return common.RepairStep{Name: step, Status: repairStatusSkipped, Message: "'DEFAULT_QUOTA_CPU' und 'DEFAULT_QUOTA_MEMORY' sind nicht konfiguriert"}
+	}
+
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces/"+project+"/resourcequotas", nil)
+	if err != nil {
+		return repairStep(step, err)
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return repairStep(step, errors.New(genericAPIError))
+	}
+	if quotas, _ := json.S("items").Children(); projectQuota(quotas) != nil {
+		return common.RepairStep{Name: step, Status: repairStatusOk}
+	}
+
+	quota := newObjectRequest("ResourceQuota", defaultQuotaName)
+	quota.SetP(cpu, "spec.hard.cpu")
+	quota.SetP(fmt.Sprintf("%vGi", memory), "spec.hard.memory")
+
+	resp, err = getOseHTTPClient("POST", clusterId, "api/v1/namespaces/"+project+"/resourcequotas", bytes.NewReader(quota.Bytes()))
+	if err != nil {
+		return repairStep(step, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error creating resourceQuota:", resp.StatusCode, string(errMsg))
+		return repairStep(step, errors.New(genericAPIError))
+	}
+	return repairStep(step, nil)
+}
+
+// repairLimitRange creates the limitrange with the defaults in 'limit_range'
+// if the project has none
+func repairLimitRange(clusterId, project string) common.RepairStep {
+	const step = "limitrange"
+	cfg := config.Config()
+	defaults := common.LimitRange{
+		DefaultCPU:           cfg.GetString("limit_range.default_cpu"),
+		DefaultMemory:        cfg.GetString("limit_range.default_memory"),
+		DefaultRequestCPU:    cfg.GetString("limit_range.default_request_cpu"),
+		DefaultRequestMemory: cfg.GetString("limit_range.default_request_memory"),
+	}
+	if defaults.DefaultCPU == "" || defaults.DefaultMemory == "" || defaults.DefaultRequestCPU == "" || defaults.DefaultRequestMemory == "" {
+		return common.RepairStep{Name: step, Status: repairStatusSkipped, Message: "Die Standardwerte in 'limit_range' sind nicht konfiguriert"}
+	}
+
+	method, url, limitRange, err := limitRangeRequest(clusterId, project)
+	if err != nil {
+		return repairStep(step, err)
+	}
+	if method != "POST" {
+		return common.RepairStep{Name: step, Status: repairStatusOk}
+	}
+	setContainerLimits(limitRange, defaults)
+
+	resp, err := getOseHTTPClient(method, clusterId, url, bytes.NewReader(limitRange.Bytes()))
+	if err != nil {
+		return repairStep(step, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error creating limitRange:", resp.StatusCode, string(errMsg))
+		return repairStep(step, errors.New(genericAPIError))
+	}
+	return repairStep(step, nil)
+}
+
+func repairPullSecret(clusterId, project string) common.RepairStep {
+	const step = "pull-secret"
+
+	secret, err := getSecret(clusterId, project, "external-registry")
+	if err != nil {
+		return repairStep(step, err)
+	}
+	if secret.Path("metadata.name").Data() == nil {
+		return common.RepairStep{Name: step, Status: repairStatusSkipped, Message: "Kein Pull-Secret vorhanden"}
+	}
+
+	sa, err := getServiceAccount(clusterId, project, "default")
+	if err != nil {
+		return repairStep(step, err)
+	}
+	pullSecrets, _ := sa.S("imagePullSecrets").Children()
+	for _, s := range pullSecrets {
+		if s.Path("name").Data() == "external-registry" {
+			return common.RepairStep{Name: step, Status: repairStatusOk}
+		}
+	}
+
+	return repairStep(step, addPullSecretToServiceaccount(nil, clusterId, project, "default"))
+}
@@ -0,0 +1,71 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func repairStatus(steps []common.RepairStep, name string) string {
+	for _, s := range steps {
+		if s.Name == name {
+			return s.Status
+		}
+	}
+	return ""
+}
+
+func TestRepairProject(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	cfg := config.Config()
+	cfg.Set("default_quota_cpu", 4)
+	cfg.Set("default_quota_memory", 8)
+	cfg.Set("limit_range.default_cpu", "500m")
+	cfg.Set("limit_range.default_memory", "1Gi")
+	cfg.Set("limit_range.default_request_cpu", "100m")
+	cfg.Set("limit_range.default_request_memory", "256Mi")
+	api.AddProject("shop", "u123")
+
+	steps := repairProject("fake", "shop", "12345", "", "admin1")
+	for name, status := range map[string]string{
+		"admin-rolebinding": repairStatusOk,
+		"billing":           repairStatusFixed,
+		"quota":             repairStatusFixed,
+		"limitrange":        repairStatusFixed,
+		"pull-secret":       repairStatusSkipped,
+	} {
+		if s := repairStatus(steps, name); s != status {
+			t.Errorf("expected step %v to be %v, got %+v", name, status, steps)
+		}
+	}
+
+	namespace, _ := api.Get("api/v1/namespaces/shop")
+	annotations := namespace.Path("metadata.annotations")
+	if getAnnotation(annotations, annotationBilling) != "12345" || getAnnotation(annotations, annotationRequester) != "u123" {
+		t.Errorf("expected the billing to be set and the requester to be kept, got %v", annotations)
+	}
+	limitRange, ok := api.Get("api/v1/namespaces/shop/limitranges/default")
+	if !ok || containerLimits(limitRange).Path("default.memory").Data() != "1Gi" {
+		t.Errorf("expected the default limitrange, got %v", limitRange)
+	}
+
+	// A second run doesn't change anything
+	steps = repairProject("fake", "shop", "", "", "admin1")
+	for _, s := range steps {
+		if s.Status != repairStatusOk && s.Status != repairStatusSkipped {
+			t.Errorf("expected nothing to repair, got %+v", s)
+		}
+	}
+}
+
+func TestRepairLimitRangeWithoutDefaults(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("shop", "u123")
+
+	if step := repairLimitRange("fake", "shop"); step.Status != repairStatusSkipped {
+		t.Errorf("expected the step to be skipped without defaults, got %+v", step)
+	}
+}
@@ -0,0 +1,483 @@
+package openshift
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/now"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	reportSchedulesCollection = "report_schedules"
+	cadenceDaily              = "daily"
+	cadenceWeekly             = "weekly"
+	cadenceMonthly            = "monthly"
+	idleCPUThreshold          = 0.01
+	idleMemoryThreshold       = 0.05
+	reportSchedulerLease      = "report-scheduler"
+	complianceChecks          = 6
+	defaultCertificateDays    = 30
+)
+
+var errReportScheduleNotFound = errors.New("Der Report existiert nicht")
+
+// reportGenerators create the attachments of the reports by name
+var reportGenerators = map[string]func() ([]common.Attachment, error){
+	"billing":               createBillingReport,
+	"idle-projects":         createIdleProjectsReport,
+	"cost-anomalies":        createCostAnomaliesReport,
+	"naming":                createNamingViolationsReport,
+	"compliance":            createComplianceReport,
+	"expiring-certificates": createExpiringCertificatesReport,
+}
+
+// ReportSchedule sends a report to the recipients in the cadence
+type ReportSchedule struct {
+	ID         string     `json:"id"`
+	Report     string     `json:"report"`
+	Cadence    string     `json:"cadence"`
+	Recipients []string   `json:"recipients"`
+	CreatedBy  string     `json:"createdBy"`
+	LastRun    *time.Time `json:"lastRun,omitempty"`
+	LastError  string     `json:"lastError,omitempty"`
+}
+
+func getReportSchedulesHandler(c *gin.Context) {
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	schedules, err := getReportSchedules()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	matching := []ReportSchedule{}
+	for _, s := range schedules {
+		if listParams.Matches(s.Report, s.Cadence, s.CreatedBy, strings.Join(s.Recipients, " ")) {
+			matching = append(matching, s)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return matching[i].Report+matching[i].ID < matching[j].Report+matching[j].ID
+	})
+
+	start, end, next := listParams.Page(len(matching))
+	c.JSON(http.StatusOK, common.ListResponse{
+		Items:    matching[start:end],
+		Total:    len(matching),
+		Continue: next,
+	})
+}
+
+func newReportScheduleHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data ReportSchedule
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateReportSchedule(data); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		common.Logger(c).Printf("Error generating id: %v", err)
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericAPIError})
+		return
+	}
+
+	schedule := ReportSchedule{
+		ID:         id.String(),
+		Report:     data.Report,
+		Cadence:    data.Cadence,
+		Recipients: data.Recipients,
+		CreatedBy:  username,
+	}
+	if err := store.Put(reportSchedulesCollection, schedule.ID, schedule); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v scheduled the report %v %v for %v", username, schedule.Report, schedule.Cadence, strings.Join(schedule.Recipients, ", "))
+	c.JSON(http.StatusOK, schedule)
+}
+
+func deleteReportScheduleHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	id := c.Param("id")
+
+	if err := store.Delete(reportSchedulesCollection, id); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v deleted the report schedule %v", username, id)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: "Der Report wurde gelöscht"})
+}
+
+func runReportScheduleHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	id := c.Param("id")
+
+	var schedule ReportSchedule
+	found, err := store.Get(reportSchedulesCollection, id, &schedule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: errReportScheduleNotFound.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v started the report %v", username, id)
+	if err := runReportSchedule(&schedule, common.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+func validateReportSchedule(data ReportSchedule) error {
+	if _, ok := reportGenerators[data.Report]; !ok {
+		names := []string{}
+		for name := range reportGenerators {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("Unbekannter Report. Möglich sind: %v", strings.Join(names, ", "))
+	}
+	if _, ok := cadenceDuration(data.Cadence); !ok {
+		return errors.New("Der Rhythmus muss daily, weekly oder monthly sein")
+	}
+	if len(data.Recipients) == 0 {
+		return errors.New("Es muss mindestens ein Empfänger angegeben werden")
+	}
+	return nil
+}
+
+func cadenceDuration(cadence string) (time.Duration, bool) {
+	switch cadence {
+	case cadenceDaily:
+		return 24 * time.Hour, true
+	case cadenceWeekly:
+		return 7 * 24 * time.Hour, true
+	case cadenceMonthly:
+		return 0, true
+	}
+	return 0, false
+}
+
+// isReportDue returns true if the report wasn't sent in the current period.
+// Monthly reports are sent once per calendar month
+func isReportDue(schedule ReportSchedule, at time.Time) bool {
+	if schedule.LastRun == nil {
+		return true
+	}
+	if schedule.Cadence == cadenceMonthly {
+		return schedule.LastRun.Before(now.New(at).BeginningOfMonth())
+	}
+	d, _ := cadenceDuration(schedule.Cadence)
+	return !schedule.LastRun.Add(d).After(at)
+}
+
+// StartReportScheduler checks every hour which reports are due. Times are in
+// the timezone of the installation. Only the instance holding the lease
+// sends the reports, so they aren't sent once per instance
+func StartReportScheduler() {
+	go func() {
+		for {
+			leader, err := store.TryLease(reportSchedulerLease, common.InstanceID(), 2*time.Hour)
+			if err != nil {
+				log.Printf("Error acquiring the lease of the report scheduler: %v", err)
+			}
+			if leader {
+				sendDueReports(common.Now())
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+func sendDueReports(at time.Time) {
+	// Reports due on weekends and holidays are sent on the next workday
+	if !common.IsWorkday(at) {
+		return
+	}
+	schedules, err := getReportSchedules()
+	if err != nil {
+		log.Printf("Error reading report schedules: %v", err)
+		return
+	}
+	for i := range schedules {
+		if !isReportDue(schedules[i], at) {
+			continue
+		}
+		if err := runReportSchedule(&schedules[i], at); err != nil {
+			log.Printf("Error sending report %v: %v", schedules[i].ID, err)
+		}
+	}
+}
+
+func getReportSchedules() ([]ReportSchedule, error) {
+	schedules := []ReportSchedule{}
+	err := store.List(reportSchedulesCollection, func(id string, data []byte) error {
+		var s ReportSchedule
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		schedules = append(schedules, s)
+		return nil
+	})
+	return schedules, err
+}
+
+func runReportSchedule(schedule *ReportSchedule, at time.Time) error {
+	attachments, err := reportGenerators[schedule.Report]()
+	if err == nil {
+		err = common.SendMailWithAttachments(schedule.Recipients, fmt.Sprintf("OpenShift Report %v", schedule.Report), fmt.Sprintf(`
+	Sehr geehrte Damen und Herren,
+	<br><br>
+	Im Anhang findet ihr den Report '%v' vom %v.
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, schedule.Report, at.Format("02.01.2006")), attachments...)
+	}
+
+	// A failed report stays due, so it's sent again in the next hour
+	var stored ReportSchedule
+	updateErr := store.Update(reportSchedulesCollection, schedule.ID, &stored, func(exists bool) error {
+		if !exists {
+			return errReportScheduleNotFound
+		}
+		stored.LastError = ""
+		if err != nil {
+			stored.LastError = err.Error()
+		} else {
+			stored.LastRun = &at
+		}
+		return nil
+	})
+	if updateErr != nil {
+		return updateErr
+	}
+	*schedule = stored
+	return err
+}
+
+// createBillingReport returns the chargeback csv of the last month per cluster
+func createBillingReport() ([]common.Attachment, error) {
+	lastMonth := now.New(common.Now()).BeginningOfMonth().AddDate(0, -1, 0)
+	attachments := []common.Attachment{}
+	for _, cluster := range []Cluster{awsCluster, viasCluster} {
+		snapshot, err := getBillingSnapshot(cluster, lastMonth)
+		if err != nil {
+			return nil, err
+		}
+		resourceMap := make(map[string]Resources)
+		for _, r := range snapshot.Rows {
+			resourceMap[r.Project] = r
+		}
+		attachments = append(attachments, common.Attachment{
+			Filename: fmt.Sprintf("billing-%v-%v.csv", cluster, snapshot.Month),
+			Content:  []byte(createCSVReport(resourceMap, lastMonth)),
+		})
+	}
+	return attachments, nil
+}
+
+// createIdleProjectsReport lists the projects which used almost no resources
+// in the last month
+func createIdleProjectsReport() ([]common.Attachment, error) {
+	lastMonth := now.New(common.Now()).BeginningOfMonth().AddDate(0, -1, 0)
+	rows := [][]string{{"Cluster", "Projekt", "Kontierungsnummer", "Quota CPU", "Quota Memory"}}
+	for _, cluster := range []Cluster{awsCluster, viasCluster} {
+		snapshot, err := getBillingSnapshot(cluster, lastMonth)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range snapshot.Rows {
+			if r.UsedCpu >= idleCPUThreshold || r.UsedMemory >= idleMemoryThreshold {
+				continue
+			}
+			rows = append(rows, []string{string(cluster), r.Project, getAccountAssignment(r),
+				fmt.Sprintf("%.2f", r.QuotaCpu), fmt.Sprintf("%.2f", r.QuotaMemory)})
+		}
+	}
+	return csvAttachment(fmt.Sprintf("idle-projects-%v.csv", lastMonth.Format(monthFormat)), rows)
+}
+
+// createCostAnomaliesReport lists the cost anomalies which weren't acknowledged
+func createCostAnomaliesReport() ([]common.Attachment, error) {
+	anomalies, err := getCostAnomalies()
+	if err != nil {
+		return nil, err
+	}
+	rows := [][]string{{"Kontierungsnummer", "Monat", "Vormonat (CHF)", "Kosten (CHF)", "Anstieg (%)"}}
+	for _, a := range anomalies {
+		if a.Acknowledged {
+			continue
+		}
+		rows = append(rows, []string{a.Billing, a.Month, fmt.Sprintf("%.2f", a.PreviousCost), fmt.Sprintf("%.2f", a.Cost), fmt.Sprint(a.IncreasePercent)})
+	}
+	return csvAttachment("cost-anomalies.csv", rows)
+}
+
+func csvAttachment(filename string, rows [][]string) ([]common.Attachment, error) {
+	b := &bytes.Buffer{}
+	wr := csv.NewWriter(b)
+	if err := wr.WriteAll(rows); err != nil {
+		log.Printf("Error writing csv %v: %v", filename, err)
+		return nil, errors.New(genericAPIError)
+	}
+	return []common.Attachment{{Filename: filename, Content: b.Bytes()}}, nil
+}
+
+// createComplianceReport returns the compliance score of every project: the
+// percentage of the checks billing, classification, classification
+// policies, spec drift, overdue secrets and naming conventions it passes
+func createComplianceReport() ([]common.Attachment, error) {
+	drifts, err := getProjectDrifts()
+	if err != nil {
+		return nil, err
+	}
+	overdue := make(map[string]bool)
+	for _, s := range getOverdueSecrets(common.Now()) {
+		overdue[projectSpecID(s.ClusterId, s.Project)] = true
+	}
+
+	rows := [][]string{{"Cluster", "Projekt", "Kontierungsnummer", "Score (%)", "Verletzungen"}}
+	for _, cluster := range getOpenshiftClusters("") {
+		namespaces, err := getAllNamespaces(cluster.ID)
+		if err != nil {
+			return nil, err
+		}
+		namingViolations, err := findNamingViolations(cluster.ID, "")
+		if err != nil {
+			return nil, err
+		}
+		naming := make(map[string]bool)
+		for _, v := range namingViolations {
+			naming[v.Project] = true
+		}
+
+		for _, n := range namespaces {
+			project, _ := n.Path("metadata.name").Data().(string)
+			if isSystemNamespace(project) {
+				continue
+			}
+			id := projectSpecID(cluster.ID, project)
+			violations := complianceViolations(cluster.ID, project, n, len(drifts[id]) > 0, overdue[id], naming[project])
+			score := (complianceChecks - len(violations)) * 100 / complianceChecks
+			rows = append(rows, []string{cluster.ID, project, getAnnotation(n.Path("metadata.annotations"), annotationBilling),
+				fmt.Sprint(score), strings.Join(violations, "; ")})
+		}
+	}
+	return csvAttachment(fmt.Sprintf("compliance-%v.csv", common.Now().Format(expiryDateFormat)), rows)
+}
+
+// complianceViolations returns one entry per failed check of the project
+func complianceViolations(clusterId, project string, namespace *gabs.Container, drift, overdueSecrets, naming bool) []string {
+	violations := []string{}
+	if getAnnotation(namespace.Path("metadata.annotations"), annotationBilling) == "" {
+		violations = append(violations, "Keine Kontierungsnummer")
+	}
+	classification, _ := namespace.Path("metadata.labels").S(classificationLabel).Data().(string)
+	if classification == "" {
+		violations = append(violations, "Nicht klassifiziert")
+	} else if policies := checkClassificationPolicies(clusterId, project, classification); len(policies) > 0 {
+		violations = append(violations, strings.Join(policies, ", "))
+	}
+	if drift {
+		violations = append(violations, "Abweichung von der Spezifikation")
+	}
+	if overdueSecrets {
+		violations = append(violations, "Abgelaufene Secrets")
+	}
+	if naming {
+		violations = append(violations, "Verletzung der Namenskonvention")
+	}
+	return violations
+}
+
+// createExpiringCertificatesReport lists the certificates of routes and the
+// secrets with an expiry which expire within 'reports.certificate_days'
+func createExpiringCertificatesReport() ([]common.Attachment, error) {
+	days := config.Config().GetInt("reports.certificate_days")
+	if days <= 0 {
+		days = defaultCertificateDays
+	}
+	until := common.Now().AddDate(0, 0, days)
+
+	expiring := []ExpiringSecret{}
+	kinds := make(map[ExpiringSecret]string)
+	for _, s := range getExpiringSecrets() {
+		if s.Expires.Before(until) {
+			expiring = append(expiring, s)
+			kinds[s] = "Secret"
+		}
+	}
+	for _, cluster := range getOpenshiftClusters("") {
+		routes, err := listObjects(cluster.ID, "oapi/v1/routes")
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range routes {
+			expires, ok := routeCertificateExpiry(r)
+			if !ok || !expires.Before(until) {
+				continue
+			}
+			route := ExpiringSecret{ClusterId: cluster.ID, Expires: expires}
+			route.Project, _ = r.Path("metadata.namespace").Data().(string)
+			route.Name, _ = r.Path("metadata.name").Data().(string)
+			expiring = append(expiring, route)
+			kinds[route] = "Route"
+		}
+	}
+	sort.Slice(expiring, func(i, j int) bool {
+		return expiring[i].Expires.Before(expiring[j].Expires)
+	})
+
+	rows := [][]string{{"Cluster", "Projekt", "Typ", "Name", "Ablaufdatum"}}
+	for _, e := range expiring {
+		rows = append(rows, []string{e.ClusterId, e.Project, kinds[e], e.Name, e.Expires.Format("02.01.2006")})
+	}
+	return csvAttachment("expiring-certificates.csv", rows)
+}
+
+// routeCertificateExpiry returns the expiry of the certificate of the route
+func routeCertificateExpiry(route *gabs.Container) (time.Time, bool) {
+	data, _ := route.Path("spec.tls.certificate").Data().(string)
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return time.Time{}, false
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return certificate.NotAfter, true
+}
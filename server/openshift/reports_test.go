@@ -0,0 +1,109 @@
+package openshift
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+)
+
+func TestIsReportDue(t *testing.T) {
+	at := time.Date(2019, 3, 15, 10, 0, 0, 0, time.Local)
+	yesterday := at.Add(-24 * time.Hour)
+	lastMonth := time.Date(2019, 2, 28, 10, 0, 0, 0, time.Local)
+	thisMonth := time.Date(2019, 3, 1, 10, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		schedule ReportSchedule
+		due      bool
+	}{
+		{ReportSchedule{Cadence: cadenceDaily}, true},
+		{ReportSchedule{Cadence: cadenceDaily, LastRun: &yesterday}, true},
+		{ReportSchedule{Cadence: cadenceWeekly, LastRun: &yesterday}, false},
+		{ReportSchedule{Cadence: cadenceMonthly, LastRun: &lastMonth}, true},
+		{ReportSchedule{Cadence: cadenceMonthly, LastRun: &thisMonth}, false},
+	}
+	for _, tt := range tests {
+		if due := isReportDue(tt.schedule, at); due != tt.due {
+			t.Errorf("isReportDue(%v) = %v, expected %v", tt.schedule.Cadence, due, tt.due)
+		}
+	}
+}
+
+func TestRunReportScheduleSetsLastRunOnSuccess(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+	reportGenerators["test"] = func() ([]common.Attachment, error) { return nil, nil }
+	defer delete(reportGenerators, "test")
+
+	schedule := ReportSchedule{ID: "1", Report: "test", Cadence: cadenceDaily, Recipients: []string{"a@example.com"}}
+	store.Put(reportSchedulesCollection, schedule.ID, schedule)
+
+	// Without mail server the report can't be sent
+	if err := runReportSchedule(&schedule, time.Now()); err == nil {
+		t.Fatal("expected an error without mail server")
+	}
+	if schedule.LastRun != nil || schedule.LastError == "" {
+		t.Errorf("expected the failed report to stay due, got %+v", schedule)
+	}
+
+	store.Delete(reportSchedulesCollection, schedule.ID)
+	if err := runReportSchedule(&schedule, time.Now()); err != errReportScheduleNotFound {
+		t.Errorf("expected the deleted schedule not to be stored again, got %v", err)
+	}
+	if found, _ := store.Get(reportSchedulesCollection, schedule.ID, &ReportSchedule{}); found {
+		t.Error("expected the deleted schedule not to be stored again")
+	}
+}
+
+func TestCreateExpiringCertificatesReport(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("shop", "u123")
+	api.Set("oapi/v1/namespaces/shop/routes/expiring", routeWithCertificate(t, "expiring", time.Now().AddDate(0, 0, 10)))
+	api.Set("oapi/v1/namespaces/shop/routes/valid", routeWithCertificate(t, "valid", time.Now().AddDate(1, 0, 0)))
+
+	attachments, err := createExpiringCertificatesReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(attachments[0].Content)
+	if !strings.Contains(report, "fake,shop,Route,expiring") || strings.Contains(report, "valid") {
+		t.Errorf("expected only the expiring certificate, got %v", report)
+	}
+}
+
+func routeWithCertificate(t *testing.T, name string, expires time.Time) *gabs.Container {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now(), NotAfter: expires}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := gabs.New()
+	route.Set(name, "metadata", "name")
+	route.Set("shop", "metadata", "namespace")
+	route.Set(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), "spec", "tls", "certificate")
+	return route
+}
+
+func TestComplianceViolations(t *testing.T) {
+	namespace, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "shop", "annotations": {"openshift.io/kontierung-element": "12345"}}}`))
+
+	violations := complianceViolations("fake", "shop", namespace, true, false, false)
+	if len(violations) != 2 || violations[0] != "Nicht klassifiziert" || violations[1] != "Abweichung von der Spezifikation" {
+		t.Errorf("expected the classification and the drift to be violated, got %v", violations)
+	}
+}
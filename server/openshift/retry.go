@@ -0,0 +1,137 @@
+package openshift
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	clusterUnreachableError = "Der Cluster %v ist im Moment nicht erreichbar. Bitte versuche es später nochmals"
+	defaultRetries          = 3
+	defaultRetryBackoff     = 200 * time.Millisecond
+	defaultRequestTimeout   = 30 * time.Second
+	defaultRetryMaxDuration = 60 * time.Second
+)
+
+// retrySleep waits between the attempts and retryNow is the clock of the
+// retries, both replaced by the tests
+var (
+	retrySleep = time.Sleep
+	retryNow   = time.Now
+)
+
+// retryPolicy are the retries of the calls of the cluster apis in
+// 'openshift_retry'. The backoff doubles after every attempt. Timeout limits
+// an attempt and MaxDuration all attempts of a call
+type retryPolicy struct {
+	Retries     int
+	Backoff     time.Duration
+	Timeout     time.Duration
+	MaxDuration time.Duration
+}
+
+func getRetryPolicy() retryPolicy {
+	cfg := config.Config()
+	p := retryPolicy{Retries: defaultRetries, Backoff: defaultRetryBackoff, Timeout: defaultRequestTimeout, MaxDuration: defaultRetryMaxDuration}
+	if cfg.IsSet("openshift_retry.retries") {
+		p.Retries = cfg.GetInt("openshift_retry.retries")
+	}
+	if ms := cfg.GetInt("openshift_retry.backoff_ms"); ms > 0 {
+		p.Backoff = time.Duration(ms) * time.Millisecond
+	}
+	if seconds := cfg.GetInt("openshift_retry.timeout_seconds"); seconds > 0 {
+		p.Timeout = time.Duration(seconds) * time.Second
+	}
+	if seconds := cfg.GetInt("openshift_retry.max_seconds"); seconds > 0 {
+		p.MaxDuration = time.Duration(seconds) * time.Second
+	}
+	return p
+}
+
+// isIdempotent methods can be sent again even if the api may have
+// processed the first attempt
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// isRetryableError returns true if the call can be repeated after the
+// error. Other methods than the idempotent ones are only repeated if the
+// connection couldn't be opened, so a creation is never sent twice
+func isRetryableError(method string, err error) bool {
+	if isIdempotent(method) {
+		return true
+	}
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	opErr, ok := err.(*net.OpError)
+	return ok && opErr.Op == "dial"
+}
+
+// isRetryableStatus returns true for the answers of an overloaded or
+// restarting master. A proxy in front of the master may have forwarded the
+// call anyway, so only idempotent methods are retried
+func isRetryableStatus(method string, status int) bool {
+	if !isIdempotent(method) {
+		return false
+	}
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// doWithRetry sends the request created by newRequest until it succeeds,
+// fails permanently, the retries are used up or the next attempt would end
+// after MaxDuration. The last response of a retryable status is returned to
+// the caller
+func doWithRetry(client *http.Client, clusterId, method string, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	policy := getRetryPolicy()
+	deadline := retryNow().Add(policy.MaxDuration)
+	timeoutClient := *client
+
+	backoff := policy.Backoff
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		timeoutClient.Timeout = policy.Timeout
+		if remaining := deadline.Sub(retryNow()); remaining < timeoutClient.Timeout {
+			// A timeout of 0 would wait forever
+			timeoutClient.Timeout = time.Second
+			if remaining > time.Second {
+				timeoutClient.Timeout = remaining
+			}
+		}
+		resp, err := timeoutClient.Do(req)
+		last := attempt >= policy.Retries || !retryNow().Add(backoff).Before(deadline)
+
+		if err != nil {
+			if last || !isRetryableError(method, err) {
+				log.Printf("Error calling %v %v on cluster %v after %v attempts: %v", method, req.URL.Path, clusterId, attempt+1, err)
+				return nil, fmt.Errorf(clusterUnreachableError, clusterId)
+			}
+			log.Printf("Error calling %v %v on cluster %v, retrying in %v: %v", method, req.URL.Path, clusterId, backoff, err)
+		} else {
+			if last || !isRetryableStatus(method, resp.StatusCode) {
+				return resp, nil
+			}
+			resp.Body.Close()
+			log.Printf("Cluster %v answered %v %v with %v, retrying in %v", clusterId, method, req.URL.Path, resp.StatusCode, backoff)
+		}
+		retrySleep(backoff)
+		backoff *= 2
+	}
+}
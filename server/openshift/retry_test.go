@@ -0,0 +1,88 @@
+package openshift
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestDoWithRetry(t *testing.T) {
+	config.Init("test")
+	var sleeps []time.Duration
+	retrySleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	defer func() { retrySleep = time.Sleep }()
+
+	var statuses []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[0]
+		statuses = statuses[1:]
+		w.WriteHeader(status)
+	}))
+	call := func(method string) (*http.Response, error) {
+		return doWithRetry(http.DefaultClient, "fake", method, func() (*http.Request, error) {
+			return http.NewRequest(method, server.URL, nil)
+		})
+	}
+
+	statuses = []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}
+	resp, err := call("PUT")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the update to succeed after two retries, got %v %v", resp, err)
+	}
+	if fmt.Sprint(sleeps) != "[200ms 400ms]" {
+		t.Errorf("expected an exponential backoff, got %v", sleeps)
+	}
+
+	for _, method := range []string{"POST", "PATCH"} {
+		for _, status := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+			statuses = []int{status, http.StatusOK}
+			if resp, _ := call(method); resp.StatusCode != status {
+				t.Errorf("expected %v of %v not to be retried, got %v", status, method, resp.StatusCode)
+			}
+		}
+	}
+	statuses = []int{http.StatusGatewayTimeout, http.StatusOK}
+	if resp, _ := call("GET"); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected a gateway timeout of a read to be retried, got %v", resp.StatusCode)
+	}
+
+	statuses = []int{503, 503, 503, 503, 200}
+	if resp, _ := call("GET"); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected the last answer after the retries, got %v", resp.StatusCode)
+	}
+
+	server.Close()
+	sleeps = nil
+	if _, err := call("POST"); err == nil || err.Error() != fmt.Sprintf(clusterUnreachableError, "fake") || len(sleeps) != defaultRetries {
+		t.Errorf("expected the cluster to be unreachable after %v retries, got %v %v", defaultRetries, err, sleeps)
+	}
+}
+
+func TestDoWithRetryStopsAfterMaxDuration(t *testing.T) {
+	config.Init("test")
+	config.Config().Set("openshift_retry.backoff_ms", 400)
+	config.Config().Set("openshift_retry.max_seconds", 1)
+	defer config.Init("test")
+	now := time.Now()
+	retryNow = func() time.Time { return now }
+	retrySleep = func(d time.Duration) { now = now.Add(d) }
+	defer func() { retrySleep, retryNow = time.Sleep, time.Now }()
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	resp, err := doWithRetry(http.DefaultClient, "fake", "GET", func() (*http.Request, error) {
+		return http.NewRequest("GET", server.URL, nil)
+	})
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable || attempts != 2 {
+		t.Errorf("expected the retries to stop before a second, got %v attempts: %v %v", attempts, resp, err)
+	}
+}
@@ -0,0 +1,207 @@
+package openshift
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	userNamesField  = "userNames"
+	groupNamesField = "groupNames"
+)
+
+// subjectName lower cases users. Group names are case sensitive
+func subjectName(field, name string) string {
+	if field == userNamesField {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// addUsersToRoleBinding grants the cluster role to the users in the project.
+// The rolebinding has the same name as the role and is created if needed
+func addUsersToRoleBinding(clusterId, project, role string, users []string) error {
+	return addToRoleBinding(nil, clusterId, project, role, userNamesField, users)
+}
+
+// addUsersToRoleBindingAs is addUsersToRoleBinding on behalf of an admin of
+// the project, see clusterUser
+func addUsersToRoleBindingAs(user *clusterUser, clusterId, project, role string, users []string) error {
+	return addToRoleBinding(user, clusterId, project, role, userNamesField, users)
+}
+
+// addGroupsToRoleBinding grants the cluster role to the groups in the project
+func addGroupsToRoleBinding(clusterId, project, role string, groups []string) error {
+	return addToRoleBinding(nil, clusterId, project, role, groupNamesField, groups)
+}
+
+// addToRoleBinding adds the names to the userNames or groupNames of the
+// rolebinding
+func addToRoleBinding(user *clusterUser, clusterId, project, role, field string, names []string) error {
+	url := fmt.Sprintf("oapi/v1/namespaces/%v/rolebindings/%v", project, role)
+	resp, err := getOseHTTPClientAs(user, "GET", clusterId, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	method := "PUT"
+	var roleBinding *gabs.Container
+	switch resp.StatusCode {
+	case http.StatusOK:
+		roleBinding, err = gabs.ParseJSONBuffer(resp.Body)
+		if err != nil {
+			log.Println("error parsing body of response:", err)
+			return errors.New(genericAPIError)
+		}
+	case http.StatusNotFound:
+		method = "POST"
+		url = fmt.Sprintf("oapi/v1/namespaces/%v/rolebindings", project)
+		roleBinding = newObjectRequest("RoleBinding", role)
+		roleBinding.SetP(role, "roleRef.name")
+		roleBinding.Array("userNames")
+	default:
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error getting rolebinding:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+
+	existing := []string{}
+	if children, err := roleBinding.S(field).Children(); err == nil {
+		for _, n := range children {
+			if name, ok := n.Data().(string); ok {
+				existing = append(existing, subjectName(field, name))
+			}
+		}
+	}
+	for _, n := range names {
+		if !contains(existing, subjectName(field, n)) {
+			roleBinding.ArrayAppend(subjectName(field, n), field)
+		}
+	}
+
+	resp, err = getOseHTTPClientAs(user, method, clusterId, url, bytes.NewReader(roleBinding.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	common.GetCache().Delete(roleBindingCacheKey(clusterId, project))
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error updating rolebinding:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	log.Printf("%v %v are now %v of project %v on cluster %v", field, strings.Join(names, ", "), role, project, clusterId)
+	return nil
+}
+
+// getRoleBindingUsers returns the lower case users of the rolebinding with
+// the name of the role. The list is empty if the rolebinding doesn't exist
+func getRoleBindingUsers(clusterId, project, role string) ([]string, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, fmt.Sprintf("oapi/v1/namespaces/%v/rolebindings/%v", project, role), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return []string{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error getting rolebinding:", resp.StatusCode, string(errMsg))
+		return nil, errors.New(genericAPIError)
+	}
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error parsing body of response:", err)
+		return nil, errors.New(genericAPIError)
+	}
+
+	users := []string{}
+	names, _ := json.S("userNames").Children()
+	for _, n := range names {
+		if name, ok := n.Data().(string); ok {
+			users = append(users, strings.ToLower(name))
+		}
+	}
+	users = common.RemoveDuplicates(users)
+	runShadow(shadowRoleBindingUsers, users, func() (interface{}, error) {
+		return getRBACRoleBindingUsers(clusterId, project, role)
+	})
+	return users, nil
+}
+
+// removeUsersFromRoleBinding revokes the role of the users in the project
+func removeUsersFromRoleBinding(clusterId, project, role string, users []string) error {
+	return removeFromRoleBinding(nil, clusterId, project, role, userNamesField, users)
+}
+
+// removeUsersFromRoleBindingAs is removeUsersFromRoleBinding on behalf of an
+// admin of the project, see clusterUser
+func removeUsersFromRoleBindingAs(user *clusterUser, clusterId, project, role string, users []string) error {
+	return removeFromRoleBinding(user, clusterId, project, role, userNamesField, users)
+}
+
+// removeGroupsFromRoleBinding revokes the role of the groups in the project
+func removeGroupsFromRoleBinding(clusterId, project, role string, groups []string) error {
+	return removeFromRoleBinding(nil, clusterId, project, role, groupNamesField, groups)
+}
+
+func removeFromRoleBinding(user *clusterUser, clusterId, project, role, field string, names []string) error {
+	url := fmt.Sprintf("oapi/v1/namespaces/%v/rolebindings/%v", project, role)
+	resp, err := getOseHTTPClientAs(user, "GET", clusterId, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	roleBinding, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error parsing body of response:", err)
+		return errors.New(genericAPIError)
+	}
+
+	remove := make(map[string]bool)
+	for _, n := range names {
+		remove[subjectName(field, n)] = true
+	}
+	remaining := []interface{}{}
+	children, _ := roleBinding.S(field).Children()
+	for _, n := range children {
+		if name, ok := n.Data().(string); ok && !remove[subjectName(field, name)] {
+			remaining = append(remaining, name)
+		}
+	}
+	roleBinding.Set(remaining, field)
+	// subjects are computed from userNames and groupNames by the api
+	roleBinding.Delete("subjects")
+
+	resp, err = getOseHTTPClientAs(user, "PUT", clusterId, url, bytes.NewReader(roleBinding.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	common.GetCache().Delete(roleBindingCacheKey(clusterId, project))
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error updating rolebinding:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	log.Printf("%v %v are no longer %v of project %v on cluster %v", field, strings.Join(names, ", "), role, project, clusterId)
+	return nil
+}
@@ -0,0 +1,105 @@
+package openshift
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultRouteStatsHours = 24
+	// The query gets the project and the hours, must facet by host and
+	// select the requests and the failed requests
+	defaultRouteStatsNrql = "SELECT count(*), filter(count(*), WHERE statusCode >= 500) FROM RouterRequest WHERE namespace = '%v' FACET host SINCE %v hours ago LIMIT 1000"
+)
+
+// RouteStats is the traffic of a route in the last Hours
+type RouteStats struct {
+	Name              string  `json:"name"`
+	Host              string  `json:"host"`
+	Hours             int     `json:"hours"`
+	Requests          int     `json:"requests"`
+	RequestsPerMinute float64 `json:"requestsPerMinute"`
+	Errors            int     `json:"errors"`
+	// ErrorRate is the percentage of requests which failed with 5xx
+	ErrorRate float64 `json:"errorRate"`
+}
+
+func routeStatsConfig() (nrql string, hours int) {
+	cfg := config.Config()
+	if nrql = cfg.GetString("route_stats.nrql"); nrql == "" {
+		nrql = defaultRouteStatsNrql
+	}
+	if hours = cfg.GetInt("route_stats.hours"); hours <= 0 {
+		hours = defaultRouteStatsHours
+	}
+	return nrql, hours
+}
+
+func getRouteStatsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	stats, err := getRouteStats(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// getRouteStats gets the traffic of the routes of the project from the
+// router metrics in newrelic
+func getRouteStats(clusterId, project string) ([]RouteStats, error) {
+	if err := checkNewrelicConfig(); err != nil {
+		return nil, err
+	}
+	routes, err := listObjects(clusterId, fmt.Sprintf("oapi/v1/namespaces/%v/routes", project))
+	if err != nil {
+		return nil, err
+	}
+
+	nrql, hours := routeStatsConfig()
+	traffic := new(routeTraffic)
+	if err := getJson(common.HTTPClient("newrelic"), fmt.Sprintf(nrql, project, hours), traffic); err != nil {
+		return nil, err
+	}
+	return routeStatsOf(routes, traffic, hours), nil
+}
+
+func routeStatsOf(routes []*gabs.Container, traffic *routeTraffic, hours int) []RouteStats {
+	requests := make(map[string][2]int)
+	for _, f := range traffic.Facets {
+		var counts [2]int
+		for i := 0; i < len(f.Results) && i < len(counts); i++ {
+			counts[i] = int(f.Results[i].Count)
+		}
+		requests[strings.ToLower(f.Name)] = counts
+	}
+
+	stats := []RouteStats{}
+	for _, r := range routes {
+		s := RouteStats{Hours: hours}
+		s.Name, _ = r.Path("metadata.name").Data().(string)
+		s.Host, _ = r.Path("spec.host").Data().(string)
+		counts := requests[strings.ToLower(s.Host)]
+		s.Requests, s.Errors = counts[0], counts[1]
+		s.RequestsPerMinute = float64(s.Requests) / float64(hours*60)
+		if s.Requests > 0 {
+			s.ErrorRate = float64(s.Errors) * 100 / float64(s.Requests)
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
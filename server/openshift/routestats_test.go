@@ -0,0 +1,36 @@
+package openshift
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Jeffail/gabs"
+)
+
+func TestRouteStatsOf(t *testing.T) {
+	traffic := new(routeTraffic)
+	err := json.Unmarshal([]byte(`{"facets": [
+		{"name": "App.example.com", "results": [{"count": 1440}, {"count": 36}]},
+		{"name": "other.example.com", "results": [{"count": 10}, {"count": 0}]}
+	]}`), traffic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := func(name, host string) *gabs.Container {
+		r := gabs.New()
+		r.SetP(name, "metadata.name")
+		r.SetP(host, "spec.host")
+		return r
+	}
+
+	stats := routeStatsOf([]*gabs.Container{route("app", "app.example.com"), route("unused", "unused.example.com")}, traffic, 24)
+	if len(stats) != 2 {
+		t.Fatalf("expected the stats of 2 routes, got %+v", stats)
+	}
+	if s := stats[0]; s.Requests != 1440 || s.Errors != 36 || s.RequestsPerMinute != 1 || s.ErrorRate != 2.5 {
+		t.Errorf("unexpected stats of route app: %+v", s)
+	}
+	if s := stats[1]; s.Requests != 0 || s.ErrorRate != 0 {
+		t.Errorf("expected no traffic on route unused, got %+v", s)
+	}
+}
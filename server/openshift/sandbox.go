@@ -0,0 +1,182 @@
+package openshift
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	sandboxExpiresAnnotation = "openshift.io/sandbox-expires"
+	sandboxLabel             = "openshift.io/sandbox"
+	defaultSandboxDays       = 14
+	defaultSandboxCPU        = 1
+	defaultSandboxMemory     = 2
+)
+
+// sandboxConfig returns the settings of 'sandbox' with defaults
+func sandboxConfig() (days, cpu, memory int) {
+	cfg := config.Config()
+	days = cfg.GetInt("sandbox.days")
+	if days <= 0 {
+		days = defaultSandboxDays
+	}
+	cpu = cfg.GetInt("sandbox.quota_cpu")
+	if cpu <= 0 {
+		cpu = defaultSandboxCPU
+	}
+	memory = cfg.GetInt("sandbox.quota_memory")
+	if memory <= 0 {
+		memory = defaultSandboxMemory
+	}
+
+	// The published quota template 'sandbox' of the catalog takes precedence
+	if quota, ok := getPublishedTemplateContent(templateKindQuota, "sandbox"); ok {
+		cpu, memory = quota.QuotaCpu, quota.QuotaMemory
+	}
+	return days, cpu, memory
+}
+
+// sandboxTemplate returns the sample app from the published project template
+// 'sandbox' of the catalog or from 'sandbox.template'
+func sandboxTemplate() (template, namespace string, parameters map[string]string) {
+	cfg := config.Config()
+	template = cfg.GetString("sandbox.template")
+	namespace = cfg.GetString("sandbox.template_namespace")
+	parameters = map[string]string{}
+	if content, ok := getPublishedTemplateContent(templateKindProject, "sandbox"); ok {
+		template, namespace = content.Template, content.TemplateNamespace
+		if content.Parameters != nil {
+			parameters = content.Parameters
+		}
+	}
+	if namespace == "" {
+		namespace = "openshift"
+	}
+	return template, namespace, parameters
+}
+
+func newSandboxProjectHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.NewSandboxProjectCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	data.Project = username + "-" + data.Project
+	if err := validateNewProject(data.Project, "", true); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	expires, err := createSandboxProject(data.ClusterId, strings.ToLower(data.Project), username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Das Sandbox-Projekt %v wurde erstellt auf Cluster %v. Es wird am %v automatisch gelöscht", data.Project, data.ClusterId, expires.Format("02.01.2006")),
+	})
+}
+
+// createSandboxProject creates a test project with a small quota, marks it
+// to expire and instantiates the sample app from 'sandbox.template'
+func createSandboxProject(clusterId, project, username string) (time.Time, error) {
+	days, cpu, memory := sandboxConfig()
+	expires := common.Now().AddDate(0, 0, days)
+
+	if err := createNewProject(nil, clusterId, project, username, "keine-verrechnung", "", true); err != nil {
+		return expires, err
+	}
+
+	if err := setSandboxExpiry(clusterId, project, expires); err != nil {
+		return expires, err
+	}
+
+	if err := setQuota(clusterId, project, cpu, memory); err != nil {
+		return expires, err
+	}
+
+	template, templateNamespace, parameters := sandboxTemplate()
+	if template == "" {
+		log.Println("WARNING: 'sandbox.template' is not set, sandbox projects are created without sample app")
+		return expires, nil
+	}
+
+	if err := instantiateTemplate(clusterId, templateNamespace, template, project, parameters); err != nil {
+		return expires, fmt.Errorf("Das Sandbox-Projekt wurde erstellt, aber die Beispiel-Applikation konnte nicht angelegt werden: %v", err)
+	}
+
+	log.Printf("%v created the sandbox project %v on cluster %v, expires %v", username, project, clusterId, expires.Format(time.RFC3339))
+	return expires, nil
+}
+
+// setSandboxExpiry marks the project with the label, so the janitor only
+// lists the sandboxes, and the expiry
+func setSandboxExpiry(clusterId, project string, expires time.Time) error {
+	return updateNamespace(clusterId, project, func(namespace *gabs.Container) {
+		namespace.Set("true", "metadata", "labels", sandboxLabel)
+		if namespace.Path("metadata.annotations").Data() == nil {
+			namespace.SetP(map[string]interface{}{}, "metadata.annotations")
+		}
+		annotations := namespace.Path("metadata.annotations")
+		annotations.Set(expires.Format(time.RFC3339), sandboxExpiresAnnotation)
+		annotations.Set(fmt.Sprintf("Dieses Sandbox-Projekt wird am %v automatisch gelöscht!", expires.Format("02.01.2006")), "openshift.io/description")
+		// The sandbox janitor deletes the project, not the test project janitor
+		annotations.Delete(testProjectDeletionAnnotation)
+		annotations.Delete(testProjectExpiresAnnotation)
+	})
+}
+
+// StartSandboxJanitor deletes expired sandbox projects every hour on
+// workdays, so the owners can still react on weekends and holidays
+func StartSandboxJanitor() {
+	go func() {
+		for {
+			if at := common.Now(); common.IsWorkday(at) {
+				deleteExpiredSandboxes(at)
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+func deleteExpiredSandboxes(now time.Time) {
+	for _, cluster := range getOpenshiftClusters("") {
+		namespaces, err := listObjects(cluster.ID, "api/v1/namespaces?labelSelector="+url.QueryEscape(sandboxLabel+"=true"))
+		if err != nil {
+			log.Printf("Error getting sandboxes of cluster %v: %v", cluster.ID, err)
+			continue
+		}
+
+		for _, n := range namespaces {
+			annotations := n.Path("metadata.annotations")
+			expires, ok := annotations.S(sandboxExpiresAnnotation).Data().(string)
+			if !ok || inRecycleBin(annotations) {
+				continue
+			}
+			expiresAt, err := time.Parse(time.RFC3339, expires)
+			if err != nil || expiresAt.After(now) {
+				continue
+			}
+
+			project, _ := n.Path("metadata.name").Data().(string)
+			if err := deleteProject(cluster.ID, project); err != nil {
+				log.Printf("Error deleting expired sandbox %v on cluster %v: %v", project, cluster.ID, err)
+				continue
+			}
+			log.Printf("Deleted expired sandbox %v on cluster %v", project, cluster.ID)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeleteExpiredSandboxes(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	now := time.Now()
+	api.AddProject("u123-expired", "u123")
+	api.AddProject("u123-running", "u123")
+	api.AddProject("u123-project", "u123")
+	if err := setSandboxExpiry("fake", "u123-expired", now.AddDate(0, 0, -1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := setSandboxExpiry("fake", "u123-running", now.AddDate(0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	namespace, _ := api.Get("api/v1/namespaces/u123-expired")
+	if label, _ := namespace.Path("metadata.labels").S(sandboxLabel).Data().(string); label != "true" {
+		t.Errorf("expected the sandbox to be labeled, got %v", namespace)
+	}
+
+	deleteExpiredSandboxes(now)
+	if _, ok := api.Get("api/v1/namespaces/u123-expired"); ok {
+		t.Error("expected the expired sandbox to be deleted")
+	}
+	if _, ok := api.Get("api/v1/namespaces/u123-running"); !ok {
+		t.Error("expected the sandbox to be kept until it expires")
+	}
+	if _, ok := api.Get("api/v1/namespaces/u123-project"); !ok {
+		t.Error("expected other projects to be kept")
+	}
+}
@@ -0,0 +1,211 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	searchTypeProject    = "project"
+	searchTypeAudit      = "audit"
+	defaultSearchMonths  = 3
+	minSearchQueryLength = 2
+)
+
+// searchFolding folds the umlauts and accents of German, French and Italian,
+// so "zurich" finds "Zürich" and "Zuerich"
+var searchFolding = strings.NewReplacer(
+	"ä", "a", "ö", "o", "ü", "u", "ae", "a", "oe", "o", "ue", "u", "ß", "ss",
+	"à", "a", "â", "a", "á", "a", "ç", "c", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "í", "i", "ì", "i", "ô", "o", "ó", "o", "ò", "o", "û", "u", "ú", "u", "ù", "u",
+)
+
+// Weights of the fields of a project. A term matching the start of a word
+// counts twice
+var searchWeights = map[string]int{
+	"project":     10,
+	"displayName": 8,
+	"tags":        6,
+	"owners":      4,
+	"contact":     4,
+	"description": 2,
+}
+
+// SearchResult is a project or audit entry matching all terms of the query
+type SearchResult struct {
+	Type      string     `json:"type"`
+	ClusterId string     `json:"clusterid,omitempty"`
+	Project   string     `json:"project,omitempty"`
+	Title     string     `json:"title"`
+	Summary   string     `json:"summary,omitempty"`
+	Time      *time.Time `json:"time,omitempty"`
+	Score     int        `json:"score"`
+}
+
+// searchHandler searches ?q in the projects the user may see and in the
+// audit log. The results are ranked and paged like all lists
+func searchHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	terms := searchTerms(c.Query("q"))
+	if len(terms) == 0 {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{
+			Message: fmt.Sprintf("Der Suchbegriff muss mindestens %v Zeichen lang sein", minSearchQueryLength),
+		})
+		return
+	}
+
+	results, failed, err := search(terms, username, common.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	start, end, next := listParams.Page(len(results))
+	c.JSON(http.StatusOK, common.ListResponse{
+		Items:    results[start:end],
+		Total:    len(results),
+		Continue: next,
+		Errors:   failed,
+	})
+}
+
+// searchTerms splits the query into the folded terms. Too short terms are
+// left out
+func searchTerms(query string) []string {
+	terms := []string{}
+	for _, t := range strings.Fields(normalizeSearchText(query)) {
+		if len([]rune(t)) >= minSearchQueryLength {
+			terms = append(terms, t)
+		}
+	}
+	return common.RemoveDuplicates(terms)
+}
+
+func normalizeSearchText(s string) string {
+	return searchFolding.Replace(strings.ToLower(s))
+}
+
+// scoreFields returns the score of the fields if every term matches at
+// least one of them, otherwise 0
+func scoreFields(terms []string, fields map[string][]string) int {
+	score := 0
+	for _, term := range terms {
+		termScore := 0
+		for field, values := range fields {
+			for _, v := range values {
+				text := normalizeSearchText(v)
+				if text == "" || !strings.Contains(text, term) {
+					continue
+				}
+				weight := searchWeights[field]
+				if weight == 0 {
+					weight = 1
+				}
+				if text == term {
+					weight *= 4
+				} else if strings.HasPrefix(text, term) || strings.Contains(text, " "+term) || strings.Contains(text, "-"+term) {
+					weight *= 2
+				}
+				termScore += weight
+			}
+		}
+		if termScore == 0 {
+			return 0
+		}
+		score += termScore
+	}
+	return score
+}
+
+// search returns the results sorted by score. Users find the projects of
+// the directory if 'directory.enabled' is set, otherwise only the projects
+// they own, and their own audit entries. Portal admins and auditors find
+// everything
+func search(terms []string, username string, now time.Time) ([]SearchResult, []string, error) {
+	privileged := common.IsPortalAdmin(username) || common.IsPortalAuditor(username)
+	username = strings.ToLower(username)
+
+	entries, failed, err := getProjectDirectory(getOpenshiftClusters(""))
+	if err != nil {
+		return nil, nil, err
+	}
+	results := []SearchResult{}
+	for _, e := range entries {
+		if !privileged && !config.Config().GetBool("directory.enabled") && !contains(e.Owners, username) {
+			continue
+		}
+		score := scoreFields(terms, map[string][]string{
+			"project":     {e.Project},
+			"displayName": {e.DisplayName},
+			"description": {e.Description},
+			"owners":      e.Owners,
+			"contact":     {e.Contact},
+			"tags":        e.Tags,
+		})
+		if score > 0 {
+			title := e.Project
+			if e.DisplayName != "" {
+				title = fmt.Sprintf("%v (%v)", e.DisplayName, e.Project)
+			}
+			results = append(results, SearchResult{
+				Type: searchTypeProject, ClusterId: e.ClusterId, Project: e.Project, Title: title, Summary: e.Description, Score: score,
+			})
+		}
+	}
+
+	months := config.Config().GetInt("search.audit_months")
+	if months <= 0 {
+		months = defaultSearchMonths
+	}
+	query := audit.Query{From: now.AddDate(0, -months, 0), To: now}
+	if !privileged {
+		query.Actor = username
+	}
+	auditEntries, err := audit.Find(query)
+	if err != nil {
+		return nil, nil, errors.New(genericAPIError)
+	}
+	for i, a := range auditEntries {
+		score := scoreFields(terms, map[string][]string{
+			"project": {a.Project},
+			"actor":   {a.Actor},
+			"action":  {a.Action},
+			"result":  {a.Result},
+		})
+		if score > 0 {
+			results = append(results, SearchResult{
+				Type: searchTypeAudit, ClusterId: a.ClusterId, Project: a.Project, Title: a.Action,
+				Summary: fmt.Sprintf("%v: %v", a.Actor, a.Result), Time: &auditEntries[i].Time, Score: score,
+			})
+		}
+	}
+
+	// Projects before audit entries of the same score, newer entries first
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Type != b.Type {
+			return a.Type == searchTypeProject
+		}
+		if a.Time != nil && b.Time != nil {
+			return a.Time.After(*b.Time)
+		}
+		return a.ClusterId+"/"+a.Project < b.ClusterId+"/"+b.Project
+	})
+	return results, failed, nil
+}
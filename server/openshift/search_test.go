@@ -0,0 +1,51 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestSearch(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+	for project, owner := range map[string]string{"ticketshop": "u123", "zuerich-api": "u456"} {
+		if err := createNewProject(nil, "fake", project, owner, "12345", "", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := updateNamespaceAnnotations("fake", "ticketshop", func(annotations *gabs.Container) {
+		annotations.Set("Billette Zürich", "openshift.io/display-name")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	audit.Record(audit.Entry{Time: now.Add(-time.Hour), Actor: "u123", Action: "POST /api/ose/quotas", ClusterId: "fake", Project: "ticketshop", Result: "Zürich quota"})
+	audit.Record(audit.Entry{Time: now.Add(-time.Hour), Actor: "u456", Action: "POST /api/ose/quotas", ClusterId: "fake", Project: "zuerich-api"})
+
+	results, _, err := search(searchTerms("ZURICH"), "u123", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[0].Type != searchTypeProject || results[0].Project != "ticketshop" || results[1].Type != searchTypeAudit {
+		t.Errorf("expected the own project before the own audit entry, got %+v", results)
+	}
+
+	config.Config().Set("directory.enabled", true)
+	defer config.Config().Set("directory.enabled", false)
+	results, _, _ = search(searchTerms("zürich api"), "u123", now)
+	if len(results) == 0 || results[0].Project != "zuerich-api" {
+		t.Fatalf("expected the project of the directory matching all terms first, got %+v", results)
+	}
+	for _, r := range results {
+		if r.Type == searchTypeProject && r.Project == "ticketshop" {
+			t.Errorf("expected projects matching only some terms to be left out, got %+v", results)
+		}
+	}
+	if results, _, _ := search(searchTerms("x"), "u123", now); len(results) != 0 {
+		t.Errorf("expected too short terms to be ignored, got %+v", results)
+	}
+}
@@ -4,16 +4,19 @@ import (
 	"bytes"
 	"errors"
 	"io/ioutil"
-	"log"
 	"net/http"
 
 	"fmt"
 
+	"encoding/base64"
 	"encoding/json"
 	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"sort"
 )
 
 type DockerConfig struct {
@@ -24,66 +27,221 @@ type Auth struct {
 	Auth []byte `json:"auth"`
 }
 
-func newPullSecretHandler(c *gin.Context) {
+const (
+	defaultPullSecretName = "external-registry"
+	pullSecretType        = "kubernetes.io/dockerconfigjson"
+)
+
+func getPullSecretsHandler(c *gin.Context) {
 	username := common.GetUserName(c)
-	cfg := config.Config()
-	dockerRepository := cfg.GetString("docker_repository")
-	if dockerRepository == "" {
-		log.Println("Env variable 'docker_repository' must be specified")
-		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: common.ConfigNotSetError})
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 		return
 	}
+	secrets, err := getPullSecrets(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, secrets)
+}
+
+func newPullSecretHandler(c *gin.Context) {
+	savePullSecret(c, false)
+}
+
+func updatePullSecretHandler(c *gin.Context) {
+	savePullSecret(c, true)
+}
+
+// savePullSecret creates or updates the pull secret and links it to the
+// default service account, so the pods can pull from the private registry
+func savePullSecret(c *gin.Context, update bool) {
+	username := common.GetUserName(c)
+	user := clusterUserFromContext(c)
 
 	var data common.NewPullSecretCommand
 	if c.BindJSON(&data) != nil {
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
 		return
 	}
-	secret := newObjectRequest("Secret", "external-registry")
+	if err := validatePullSecret(username, &data); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	secret := newPullSecret(data)
+	var err error
+	if update {
+		err = updatePullSecret(user, data.ClusterId, data.Project, secret)
+	} else {
+		err = createSecret(user, data.ClusterId, data.Project, secret)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err := linkPullSecret(user, data.ClusterId, data.Project, "default", data.Name); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if update {
+		audit.Describe(c, "%v updated the pull secret %v for %v on project %v on cluster %v", username, data.Name, data.Registry, data.Project, data.ClusterId)
+		c.JSON(http.StatusOK, common.ApiResponse{Message: fmt.Sprintf("Das Pull-Secret %v wurde aktualisiert", data.Name)})
+		return
+	}
+	audit.Describe(c, "%v created the pull secret %v for %v on project %v on cluster %v", username, data.Name, data.Registry, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: "Das Pull-Secret wurde angelegt"})
+}
+
+func validatePullSecret(username string, data *common.NewPullSecretCommand) error {
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		return err
+	}
+	if data.Name == "" {
+		data.Name = defaultPullSecretName
+	}
+	if !serviceAccountNamePattern.MatchString(data.Name) {
+		return errors.New("Der Name des Secrets darf nur Kleinbuchstaben, Zahlen und - enthalten")
+	}
+	if data.Registry == "" {
+		data.Registry = config.Config().GetString("docker_repository")
+	}
+	if data.Registry == "" {
+		log.Println("Env variable 'docker_repository' must be specified")
+		return errors.New(common.ConfigNotSetError)
+	}
+	if data.Username == "" || data.Password == "" {
+		return errors.New("Benutzername und Passwort müssen angegeben werden")
+	}
+	return nil
+}
+
+func newPullSecret(data common.NewPullSecretCommand) *gabs.Container {
+	secret := newObjectRequest("Secret", data.Name)
 	dockerConfig := DockerConfig{
 		Auths: make(map[string]*Auth),
 	}
 	auth := Auth{
 		Auth: []byte(fmt.Sprintf("%v:%v", data.Username, data.Password)),
 	}
-	dockerConfig.Auths[dockerRepository] = &auth
+	dockerConfig.Auths[data.Registry] = &auth
 	secretData, _ := json.Marshal(dockerConfig)
 
 	secret.Set(secretData, "data", ".dockerconfigjson")
-	secret.Set("kubernetes.io/dockerconfigjson", "type")
-	if err := createSecret(data.ClusterId, data.Project, secret); err != nil {
-		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
-		return
+	secret.Set(pullSecretType, "type")
+	return secret
+}
+
+func updatePullSecret(user *clusterUser, clusterId, namespace string, secret *gabs.Container) error {
+	name, _ := secret.Path("metadata.name").Data().(string)
+	existing, err := getSecret(clusterId, namespace, name)
+	if err != nil {
+		return err
 	}
-	if err := addPullSecretToServiceaccount(data.ClusterId, data.Project, "default"); err != nil {
-		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
-		return
+	if existing.Path("type").Data() != pullSecretType {
+		return fmt.Errorf("Das Pull-Secret %v existiert nicht", name)
 	}
-	log.Printf("%v created a new pull secret to default serviceaccount on project %v on cluster %v", username, data.Project, data.ClusterId)
-	c.JSON(http.StatusOK, common.ApiResponse{Message: "Das Pull-Secret wurde angelegt"})
+	existing.Set(secret.Path("data").Data(), "data")
+
+	url := fmt.Sprintf("api/v1/namespaces/%v/secrets/%v", namespace, name)
+	resp, err := getOseHTTPClientAs(user, "PUT", clusterId, url, bytes.NewReader(existing.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error updating secret on cluster %v: StatusCode: %v, Nachricht: %v", clusterId, resp.StatusCode, string(bodyBytes))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
+
+// getPullSecrets lists the docker config secrets of the project with their
+// registries. The credentials aren't returned
+func getPullSecrets(clusterId, project string) ([]common.PullSecret, error) {
+	secrets, err := listObjects(clusterId, fmt.Sprintf("api/v1/namespaces/%v/secrets", project))
+	if err != nil {
+		return nil, err
+	}
+	sa, err := getServiceAccount(clusterId, project, "default")
+	if err != nil {
+		return nil, err
+	}
+	linked := []string{}
+	pullSecrets, _ := sa.S("imagePullSecrets").Children()
+	for _, s := range pullSecrets {
+		if name, ok := s.Path("name").Data().(string); ok {
+			linked = append(linked, name)
+		}
+	}
+
+	result := []common.PullSecret{}
+	for _, s := range secrets {
+		if s.Path("type").Data() != pullSecretType {
+			continue
+		}
+		p := common.PullSecret{Registries: []string{}}
+		p.Name, _ = s.Path("metadata.name").Data().(string)
+		p.Linked = contains(linked, p.Name)
+		encoded, _ := s.S("data", ".dockerconfigjson").Data().(string)
+		var dockerConfig struct {
+			Auths map[string]interface{} `json:"auths"`
+		}
+		if data, err := base64.StdEncoding.DecodeString(encoded); err == nil && json.Unmarshal(data, &dockerConfig) == nil {
+			for registry := range dockerConfig.Auths {
+				p.Registries = append(p.Registries, registry)
+			}
+			sort.Strings(p.Registries)
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+func addPullSecretToServiceaccount(user *clusterUser, clusterId, namespace string, serviceaccount string) error {
+	return linkPullSecret(user, clusterId, namespace, serviceaccount, defaultPullSecretName)
 }
 
-func addPullSecretToServiceaccount(clusterId, namespace string, serviceaccount string) error {
+// linkPullSecret adds the secret to the imagePullSecrets of the service
+// account if it isn't there yet
+func linkPullSecret(user *clusterUser, clusterId, namespace, serviceaccount, secret string) error {
 	url := fmt.Sprintf("api/v1/namespaces/%v/serviceaccounts/%v", namespace, serviceaccount)
-	patch := []common.JsonPatch{
-		{
-			Operation: "add",
-			Path:      "/imagePullSecrets/0",
-			Value: struct {
-				Name string `json:"name"`
-			}{
-				Name: "external-registry",
-			},
-		},
-	}
-
-	patchBytes, err := json.Marshal(patch)
+	resp, err := getOseHTTPClientAs(user, "GET", clusterId, url, nil)
 	if err != nil {
-		log.Printf("Error marshalling patch: %v", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error getting service account on cluster %v: StatusCode: %v, Nachricht: %v", clusterId, resp.StatusCode, string(bodyBytes))
+		return errors.New(genericAPIError)
+	}
+	sa, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println(err.Error())
 		return errors.New(genericAPIError)
 	}
 
-	resp, err := getOseHTTPClient("PATCH", clusterId, url, bytes.NewBuffer(patchBytes))
+	pullSecrets, _ := sa.S("imagePullSecrets").Children()
+	for _, s := range pullSecrets {
+		if s.Path("name").Data() == secret {
+			return nil
+		}
+	}
+	if !sa.Exists("imagePullSecrets") {
+		sa.Array("imagePullSecrets")
+	}
+	sa.ArrayAppend(map[string]string{"name": secret}, "imagePullSecrets")
+
+	resp, err = getOseHTTPClientAs(user, "PUT", clusterId, url, bytes.NewReader(sa.Bytes()))
 	if err != nil {
 		return err
 	}
@@ -94,15 +252,13 @@ func addPullSecretToServiceaccount(clusterId, namespace string, serviceaccount s
 		log.Printf("Error adding pull secret to service account on cluster %v: StatusCode: %v, Nachricht: %v", clusterId, resp.StatusCode, string(bodyBytes))
 		return errors.New(genericAPIError)
 	}
-
 	return nil
-
 }
 
-func createSecret(clusterId, namespace string, secret *gabs.Container) error {
+func createSecret(user *clusterUser, clusterId, namespace string, secret *gabs.Container) error {
 	url := fmt.Sprintf("api/v1/namespaces/%v/secrets", namespace)
 
-	resp, err := getOseHTTPClient("POST", clusterId, url, bytes.NewReader(secret.Bytes()))
+	resp, err := getOseHTTPClientAs(user, "POST", clusterId, url, bytes.NewReader(secret.Bytes()))
 	if err != nil {
 		return err
 	}
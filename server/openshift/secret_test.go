@@ -0,0 +1,44 @@
+package openshift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/gabs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPullSecretHandlers(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("own", "u123")
+	sa, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "default"}, "imagePullSecrets": [{"name": "default-dockercfg-abc"}]}`))
+	api.Set("api/v1/namespaces/own/serviceaccounts/default", sa)
+
+	save := func(handler gin.HandlerFunc, body string) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/ose/secret/pull", strings.NewReader(body))
+		c.Set(gin.AuthUserKey, "u123")
+		handler(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %v %v", w.Code, w.Body.String())
+		}
+	}
+	save(newPullSecretHandler, `{"clusterid": "fake", "project": "own", "name": "quay", "registry": "quay.io", "username": "robot", "password": "secret"}`)
+	save(updatePullSecretHandler, `{"clusterid": "fake", "project": "own", "name": "quay", "registry": "registry.example.com", "username": "robot", "password": "new"}`)
+
+	secrets, err := getPullSecrets("fake", "own")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(secrets) != 1 || !secrets[0].Linked || len(secrets[0].Registries) != 1 || secrets[0].Registries[0] != "registry.example.com" {
+		t.Errorf("expected the updated and linked pull secret, got %+v", secrets)
+	}
+	sa, _ = api.Get("api/v1/namespaces/own/serviceaccounts/default")
+	if pullSecrets, _ := sa.S("imagePullSecrets").Children(); len(pullSecrets) != 2 {
+		t.Errorf("expected the pull secret to be linked once, got %v", sa.S("imagePullSecrets"))
+	}
+}
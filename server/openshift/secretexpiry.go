@@ -0,0 +1,343 @@
+package openshift
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	secretExpiresAnnotation   = "openshift.io/expires"
+	secretExpiryLabel         = "openshift.io/has-expiry"
+	secretRemindersCollection = "secret_reminders"
+	expiryDateFormat          = "2006-01-02"
+	secretExpiryLease         = "secret-expiry-reminders"
+)
+
+// defaultSecretReminderDays are the days before the expiry on which the
+// owners are reminded
+var defaultSecretReminderDays = []int{30, 7, 1}
+
+// ExpiringSecret is a secret with an expiry date, e.g. a certificate or an api key
+type ExpiringSecret struct {
+	ClusterId string    `json:"clusterid"`
+	Project   string    `json:"project"`
+	Name      string    `json:"name"`
+	Expires   time.Time `json:"expires"`
+}
+
+type secretReminder struct {
+	Expires  string `json:"expires"`
+	Notified []int  `json:"notified"`
+}
+
+func newSecretHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.NewSecretCommand
+	if c.BindJSON(&data) != nil || data.Name == "" || len(data.Data) == 0 {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	expires, err := parseSecretExpiry(data.Expires)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	secret := newObjectRequest("Secret", data.Name)
+	secret.Set("Opaque", "type")
+	secret.Set(data.Data, "stringData")
+	if expires != nil {
+		setSecretExpiry(secret, expires)
+	}
+
+	if common.IsDryRun(c) {
+		if err := dryRunOnCluster(data.ClusterId, "POST", fmt.Sprintf("api/v1/namespaces/%v/secrets", data.Project), secret); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		common.RespondDryRun(c, fmt.Sprintf("Das Secret %v würde angelegt", data.Name), common.PlannedChange{
+			Action: common.DryRunActionCreate, Kind: "Secret", Name: data.Name, ClusterId: data.ClusterId, Project: data.Project, Details: "expires: " + data.Expires,
+		})
+		return
+	}
+	if err := createSecret(clusterUserFromContext(c), data.ClusterId, data.Project, secret); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v created the secret %v in project %v on cluster %v", username, data.Name, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: fmt.Sprintf("Das Secret %v wurde angelegt", data.Name)})
+}
+
+func updateSecretExpiryHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.SecretExpiryCommand
+	if c.BindJSON(&data) != nil || data.Name == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	expires, err := parseSecretExpiry(data.Expires)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := updateSecret(data.ClusterId, data.Project, data.Name, func(secret *gabs.Container) {
+		setSecretExpiry(secret, expires)
+	}); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v set the expiry of secret %v in project %v on cluster %v to %v", username, data.Name, data.Project, data.ClusterId, data.Expires)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: "Das Ablaufdatum des Secrets wurde gespeichert"})
+}
+
+func getOverdueSecretsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, getOverdueSecrets(common.Now()))
+}
+
+// parseSecretExpiry parses the date (e.g. 2019-12-31). An empty date removes the expiry
+func parseSecretExpiry(date string) (*time.Time, error) {
+	if date == "" {
+		return nil, nil
+	}
+	expires, err := time.ParseInLocation(expiryDateFormat, date, common.Location())
+	if err != nil {
+		return nil, errors.New("Das Ablaufdatum muss im Format JJJJ-MM-TT angegeben werden")
+	}
+	return &expires, nil
+}
+
+// setSecretExpiry sets or removes the expiry annotation. The label is used
+// to find the secrets with an expiry on the cluster
+func setSecretExpiry(secret *gabs.Container, expires *time.Time) {
+	if expires == nil {
+		secret.Delete("metadata", "annotations", secretExpiresAnnotation)
+		secret.Delete("metadata", "labels", secretExpiryLabel)
+		return
+	}
+	secret.Set(expires.Format(expiryDateFormat), "metadata", "annotations", secretExpiresAnnotation)
+	secret.Set("true", "metadata", "labels", secretExpiryLabel)
+}
+
+func updateSecret(clusterId, project, name string, update func(*gabs.Container)) error {
+	url := fmt.Sprintf("api/v1/namespaces/%v/secrets/%v", project, name)
+	resp, err := getOseHTTPClient("GET", clusterId, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("Das Secret %v existiert nicht", name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error getting secret %v: %v %v", name, resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+
+	secret, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return errors.New(genericAPIError)
+	}
+	update(secret)
+
+	resp, err = getOseHTTPClient("PUT", clusterId, url, bytes.NewReader(secret.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error updating secret %v: %v %v", name, resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
+
+// getExpiringSecrets returns all secrets with an expiry on all clusters
+func getExpiringSecrets() []ExpiringSecret {
+	secrets := []ExpiringSecret{}
+	for _, cluster := range getOpenshiftClusters("") {
+		items, err := listObjects(cluster.ID, "api/v1/secrets?labelSelector="+secretExpiryLabel+"%3Dtrue")
+		if err != nil {
+			log.Printf("Error getting secrets of cluster %v: %v", cluster.ID, err)
+			continue
+		}
+		for _, s := range items {
+			date, _ := s.Path("metadata.annotations").S(secretExpiresAnnotation).Data().(string)
+			expires, err := time.ParseInLocation(expiryDateFormat, date, common.Location())
+			if err != nil {
+				continue
+			}
+			secret := ExpiringSecret{ClusterId: cluster.ID, Expires: expires}
+			secret.Project, _ = s.Path("metadata.namespace").Data().(string)
+			secret.Name, _ = s.Path("metadata.name").Data().(string)
+			secrets = append(secrets, secret)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Expires.Before(secrets[j].Expires)
+	})
+	return secrets
+}
+
+// getOverdueSecrets returns the secrets which expired before at
+func getOverdueSecrets(at time.Time) []ExpiringSecret {
+	overdue := []ExpiringSecret{}
+	for _, s := range getExpiringSecrets() {
+		if s.Expires.Before(at) {
+			overdue = append(overdue, s)
+		}
+	}
+	return overdue
+}
+
+// StartSecretExpiryReminders reminds the owners of expiring secrets once a
+// day if 'secret_expiry.enabled' is set. Only the instance holding the lease
+// sends them, so the owners don't get one reminder per instance
+func StartSecretExpiryReminders() {
+	if !config.Config().GetBool("secret_expiry.enabled") {
+		return
+	}
+
+	go func() {
+		for {
+			leader, err := store.TryLease(secretExpiryLease, common.InstanceID(), 48*time.Hour)
+			if err != nil {
+				log.Printf("Error acquiring the lease of the secret expiry reminders: %v", err)
+			}
+			if leader {
+				sendSecretExpiryReminders(common.Now())
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
+
+func secretReminderDays() []int {
+	days := []int{}
+	for _, d := range config.Config().GetStringSlice("secret_expiry.reminder_days") {
+		var day int
+		if _, err := fmt.Sscan(d, &day); err == nil && day > 0 {
+			days = append(days, day)
+		}
+	}
+	if len(days) == 0 {
+		return defaultSecretReminderDays
+	}
+	return days
+}
+
+func sendSecretExpiryReminders(at time.Time) {
+	reminderDays := secretReminderDays()
+	for _, s := range getExpiringSecrets() {
+		id := fmt.Sprintf("%v/%v/%v", s.ClusterId, s.Project, s.Name)
+		var reminder secretReminder
+		if _, err := store.Get(secretRemindersCollection, id, &reminder); err != nil {
+			log.Printf("Error reading secret reminder %v: %v", id, err)
+			continue
+		}
+		// A new expiry date starts the reminders again
+		if reminder.Expires != s.Expires.Format(expiryDateFormat) {
+			reminder = secretReminder{Expires: s.Expires.Format(expiryDateFormat), Notified: []int{}}
+		}
+
+		day := nextSecretReminder(daysUntil(at, s.Expires), reminderDays, reminder.Notified)
+		if day < 0 {
+			continue
+		}
+		if err := sendSecretExpiryMail(s); err != nil {
+			log.Printf("Can't send e-mail about expiry of secret %v: %v", id, err)
+			continue
+		}
+		for _, d := range append([]int{0}, reminderDays...) {
+			if d >= day && !containsInt(reminder.Notified, d) {
+				reminder.Notified = append(reminder.Notified, d)
+			}
+		}
+		if err := store.Put(secretRemindersCollection, id, reminder); err != nil {
+			log.Printf("Error saving secret reminder %v: %v", id, err)
+		}
+	}
+}
+
+// daysUntil returns the number of calendar days from at until expires
+func daysUntil(at, expires time.Time) int {
+	from := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(expires.Year(), expires.Month(), expires.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// nextSecretReminder returns the smallest reached reminder day which wasn't
+// notified yet, 0 if the secret is overdue and wasn't notified about that, or -1
+func nextSecretReminder(days int, reminderDays, notified []int) int {
+	if days <= 0 {
+		if containsInt(notified, 0) {
+			return -1
+		}
+		return 0
+	}
+	next := -1
+	for _, d := range reminderDays {
+		if days <= d && !containsInt(notified, d) && (next < 0 || d < next) {
+			next = d
+		}
+	}
+	return next
+}
+
+func sendSecretExpiryMail(secret ExpiringSecret) error {
+	admins, _, err := getProjectAdminsAndOperators(secret.ClusterId, secret.Project)
+	if err != nil {
+		return err
+	}
+	recipients := []string{}
+	for _, a := range admins {
+		if mail := common.GetMailForUser(a); mail != "" {
+			recipients = append(recipients, mail)
+		}
+	}
+
+	state := fmt.Sprintf("läuft am %v ab", secret.Expires.Format("02.01.2006"))
+	if !secret.Expires.After(common.Now()) {
+		state = fmt.Sprintf("ist am %v abgelaufen", secret.Expires.Format("02.01.2006"))
+	}
+	return common.SendMail(common.RemoveDuplicates(recipients), fmt.Sprintf("Secret %v im Projekt %v %v", secret.Name, secret.Project, state), fmt.Sprintf(`
+	Sehr geehrte Damen und Herren,
+	<br><br>
+	Das Secret %v im Projekt %v auf Cluster %v %v.
+	Bitte ersetze das Zertifikat bzw. den Schlüssel rechtzeitig und setze das neue Ablaufdatum im Cloud Self Service Portal.
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, secret.Name, secret.Project, secret.ClusterId, state))
+}
@@ -0,0 +1,39 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDaysUntil(t *testing.T) {
+	at := time.Date(2019, 3, 30, 23, 0, 0, 0, time.UTC)
+	if d := daysUntil(at, time.Date(2019, 4, 2, 0, 0, 0, 0, time.UTC)); d != 3 {
+		t.Errorf("expected 3 days, got %v", d)
+	}
+	if d := daysUntil(at, time.Date(2019, 3, 29, 0, 0, 0, 0, time.UTC)); d != -1 {
+		t.Errorf("expected -1 days, got %v", d)
+	}
+}
+
+func TestNextSecretReminder(t *testing.T) {
+	reminderDays := []int{30, 7, 1}
+	tests := []struct {
+		days     int
+		notified []int
+		expected int
+	}{
+		{60, []int{}, -1},
+		{30, []int{}, 30},
+		{20, []int{30}, -1},
+		{5, []int{30}, 7},
+		{5, []int{}, 7},
+		{1, []int{30, 7}, 1},
+		{0, []int{30, 7, 1}, 0},
+		{-3, []int{30, 7, 1, 0}, -1},
+	}
+	for _, test := range tests {
+		if actual := nextSecretReminder(test.days, reminderDays, test.notified); actual != test.expected {
+			t.Errorf("nextSecretReminder(%v, %v): expected %v, got %v", test.days, test.notified, test.expected, actual)
+		}
+	}
+}
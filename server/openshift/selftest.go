@@ -0,0 +1,65 @@
+package openshift
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/selftest"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// RegisterSelfTests checks the connection and the expiry of the token of
+// every cluster
+// and the migrations of the store
+func RegisterSelfTests() {
+	for _, cluster := range getOpenshiftClusters("") {
+		clusterId := cluster.ID
+		selftest.Register("openshift/"+clusterId, true, func() error {
+			return checkClusterAccess(clusterId)
+		})
+		selftest.Register("token/"+clusterId, false, func() error {
+			return checkTokenExpiry(clusterId)
+		})
+	}
+	selftest.Register("migration/smtp-relay", false, checkSmtpRelayMigration)
+}
+
+// checkClusterAccess lists one namespace, which needs a valid token
+func checkClusterAccess(clusterId string) error {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces?limit=1", nil)
+	if err != nil {
+		return fmt.Errorf("Der Cluster %v ist nicht erreichbar: %v", clusterId, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		return fmt.Errorf("Der Token für den Cluster %v ist ungültig oder abgelaufen", clusterId)
+	case http.StatusForbidden:
+		return fmt.Errorf("Der Token für den Cluster %v hat zu wenig Berechtigungen", clusterId)
+	}
+	errMsg, _ := ioutil.ReadAll(resp.Body)
+	log.Printf("Selftest of cluster %v failed: %v %v", clusterId, resp.StatusCode, string(errMsg))
+	return fmt.Errorf("Der Cluster %v antwortet mit Status %v", clusterId, resp.StatusCode)
+}
+
+// checkSmtpRelayMigration fails if requests of the old collection couldn't be
+// migrated to the approval state machine
+func checkSmtpRelayMigration() error {
+	pending := 0
+	err := store.List(smtpRelayRequestsCollection, func(id string, data []byte) error {
+		pending++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("%v Smtp-Relay Anträge wurden nicht migriert", pending)
+	}
+	return nil
+}
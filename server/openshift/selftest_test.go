@@ -0,0 +1,17 @@
+package openshift
+
+import "testing"
+
+func TestCheckClusterAccess(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+
+	if err := checkClusterAccess("fake"); err != nil {
+		t.Errorf("expected the fake cluster to be reachable, got %v", err)
+	}
+
+	api.Close()
+	if err := checkClusterAccess("fake"); err == nil {
+		t.Error("expected an error for an unreachable cluster")
+	}
+}
@@ -4,21 +4,27 @@ import (
 	"bytes"
 	"errors"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"regexp"
 
 	"fmt"
 
 	"encoding/base64"
 	"encoding/json"
 	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 	"strings"
 	"time"
 )
 
+const defaultServiceAccountTokenDays = 90
+
+var serviceAccountNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
 type newJenkinsCredentialsCommand struct {
 	OrganizationKey string `json:"organizationKey"`
 	Secret          string `json:"secret"`
@@ -26,11 +32,6 @@ type newJenkinsCredentialsCommand struct {
 }
 
 func newServiceAccountHandler(c *gin.Context) {
-	jenkinsUrl := config.Config().GetString("jenkins_url")
-	if jenkinsUrl == "" {
-		log.Fatal("Env variable 'JENKINS_URL' must be specified")
-	}
-
 	username := common.GetUserName(c)
 
 	var data common.NewServiceAccountCommand
@@ -44,33 +45,77 @@ func newServiceAccountHandler(c *gin.Context) {
 		return
 	}
 
-	if err := createNewServiceAccount(data.ClusterId, username, data.Project, data.ServiceAccount); err != nil {
+	jenkinsUrl := config.Config().GetString("jenkins_url")
+	if len(data.OrganizationKey) > 0 && jenkinsUrl == "" {
+		common.Logger(c).Println("Env variable 'JENKINS_URL' must be specified to create jenkins credentials")
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericAPIError})
+		return
+	}
+
+	user := clusterUserFromContext(c)
+	if err := createNewServiceAccount(user, data.ClusterId, data.Project, data.ServiceAccount); err != nil {
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 		return
 	}
 
-	if len(data.OrganizationKey) > 0 {
+	response := common.NewServiceAccountResponse{
+		Message: fmt.Sprintf("Der Service Account %v wurde angelegt", data.ServiceAccount),
+	}
 
+	if data.EditRole {
+		subject := serviceAccountUserName(data.Project, data.ServiceAccount)
+		if err := addUsersToRoleBindingAs(user, data.ClusterId, data.Project, "edit", []string{subject}); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		audit.Describe(c, "%v gave the service account %v the role edit in project %v on cluster %v", username, data.ServiceAccount, data.Project, data.ClusterId)
+		response.Message += " und hat die Rolle edit"
+	}
+
+	if data.Token {
+		token, expiresAt, err := requestServiceAccountToken(data.ClusterId, data.Project, data.ServiceAccount, serviceAccountTokenDays()*24*60*60)
+		if err != nil {
+			common.Logger(c).Printf("Error requesting a token of service account %v in project %v on cluster %v: %v", data.ServiceAccount, data.Project, data.ClusterId, err)
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericAPIError})
+			return
+		}
+		audit.Describe(c, "%v requested a token of service account %v in project %v on cluster %v", username, data.ServiceAccount, data.Project, data.ClusterId)
+		response.Token = token
+		response.ExpiresAt = &expiresAt
+	}
+
+	if len(data.OrganizationKey) > 0 {
 		if err := createJenkinsCredential(data.ClusterId, data.Project, data.ServiceAccount, data.OrganizationKey); err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, common.ApiResponse{
-			Message: fmt.Sprintf(`Der Service Account %v wurde angelegt und im Jenkins hinterlegt. Du findest das Credential & die CredentialId im Jenkins hier: <a href='%v' target='_blank'>Jenkins</a>`,
-				data.ServiceAccount, jenkinsUrl+"/job/"+data.OrganizationKey+"/credentials"),
-		})
+		response.Message = fmt.Sprintf(`Der Service Account %v wurde angelegt und im Jenkins hinterlegt. Du findest das Credential & die CredentialId im Jenkins hier: <a href='%v' target='_blank'>Jenkins</a>`,
+			data.ServiceAccount, jenkinsUrl+"/job/"+data.OrganizationKey+"/credentials")
+	}
+
+	c.JSON(http.StatusOK, response)
+}
 
-	} else {
-		c.JSON(http.StatusOK, common.ApiResponse{
-			Message: fmt.Sprintf("Der Service Account %v wurde angelegt", data.ServiceAccount),
-		})
+// serviceAccountUserName is the user of the service account in rolebindings
+func serviceAccountUserName(project, serviceAccount string) string {
+	return fmt.Sprintf("system:serviceaccount:%v:%v", project, serviceAccount)
+}
+
+// serviceAccountTokenDays is the lifetime of the tokens returned on creation
+func serviceAccountTokenDays() int {
+	if days := config.Config().GetInt("service_accounts.token_days"); days > 0 {
+		return days
 	}
+	return defaultServiceAccountTokenDays
 }
 
 func validateNewServiceAccount(clusterId, username string, project string, serviceAccountName string) error {
 	if len(serviceAccountName) == 0 {
 		return errors.New("Service Account muss angegeben werden")
 	}
+	if !serviceAccountNamePattern.MatchString(serviceAccountName) {
+		return errors.New("Der Name des Service Accounts darf nur Kleinbuchstaben, Zahlen und - enthalten")
+	}
 
 	// Validate permissions
 	if err := checkAdminPermissions(clusterId, username, project); err != nil {
@@ -80,10 +125,10 @@ func validateNewServiceAccount(clusterId, username string, project string, servi
 	return nil
 }
 
-func createNewServiceAccount(clusterId, username, project, serviceaccount string) error {
+func createNewServiceAccount(user *clusterUser, clusterId, project, serviceaccount string) error {
 	p := newObjectRequest("ServiceAccount", serviceaccount)
 
-	resp, err := getOseHTTPClient("POST", clusterId, "api/v1/namespaces/"+project+"/serviceaccounts", bytes.NewReader(p.Bytes()))
+	resp, err := getOseHTTPClientAs(user, "POST", clusterId, "api/v1/namespaces/"+project+"/serviceaccounts", bytes.NewReader(p.Bytes()))
 	if err != nil {
 		return err
 	}
@@ -99,7 +144,7 @@ func createNewServiceAccount(clusterId, username, project, serviceaccount string
 		return errors.New(genericAPIError)
 	}
 
-	log.Print(username + " created a new service account: " + serviceaccount + " on project " + project)
+	log.Print(user.Name + " created a new service account: " + serviceaccount + " on project " + project)
 
 	return nil
 }
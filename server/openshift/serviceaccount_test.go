@@ -0,0 +1,59 @@
+package openshift
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewServiceAccountHandler(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("own", "u123")
+
+	body := `{"clusterid": "fake", "project": "own", "serviceAccount": "ci", "editRole": true, "token": true}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/ose/serviceaccount", strings.NewReader(body))
+	c.Set(gin.AuthUserKey, "u123")
+	newServiceAccountHandler(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v %v", w.Code, w.Body.String())
+	}
+
+	var response common.NewServiceAccountResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Token == "" || response.ExpiresAt == nil {
+		t.Errorf("expected a token, got %+v", response)
+	}
+	if _, ok := api.Get("api/v1/namespaces/own/serviceaccounts/ci"); !ok {
+		t.Error("expected the service account to be created")
+	}
+	editors, _ := getRoleBindingUsers("fake", "own", "edit")
+	if !contains(editors, "system:serviceaccount:own:ci") {
+		t.Errorf("expected the service account to be editor, got %v", editors)
+	}
+}
+
+func TestValidateNewServiceAccount(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("own", "u123")
+
+	if err := validateNewServiceAccount("fake", "u123", "own", "CI_Deployer"); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+	if err := validateNewServiceAccount("fake", "u456", "own", "ci"); err == nil {
+		t.Error("expected an error for a user who isn't admin")
+	}
+	if err := validateNewServiceAccount("fake", "u123", "own", "ci"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
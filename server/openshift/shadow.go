@@ -0,0 +1,102 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Code paths which can be run in shadow mode with 'shadow_mode.paths'
+const (
+	shadowRoleBindingUsers = "rolebinding-users"
+)
+
+func shadowModeEnabled(path string) bool {
+	return contains(config.Config().GetStringSlice("shadow_mode.paths"), path)
+}
+
+// runShadow runs the new implementation of the code path next to the old one
+// and logs if the results differ. The result of the old implementation is
+// always used, so the new one must only read from the cluster. It runs in
+// the background and doesn't slow down the request
+func runShadow(path string, old interface{}, shadow func() (interface{}, error)) {
+	if !shadowModeEnabled(path) {
+		return
+	}
+	go compareShadow(path, old, shadow)
+}
+
+// compareShadow returns the difference of the results, empty if they are equal
+func compareShadow(path string, old interface{}, shadow func() (interface{}, error)) string {
+	result, err := shadow()
+	if err != nil {
+		log.Printf("Shadow mode %v: the new implementation failed: %v", path, err)
+		return err.Error()
+	}
+	if shadowEqual(old, result) {
+		return ""
+	}
+	diff := fmt.Sprintf("old: %v, new: %v", old, result)
+	log.Printf("Shadow mode %v: the results differ, %v", path, diff)
+	return diff
+}
+
+// shadowEqual ignores the order of string lists
+func shadowEqual(a, b interface{}) bool {
+	as, aok := a.([]string)
+	bs, bok := b.([]string)
+	if aok && bok {
+		as = append([]string{}, as...)
+		bs = append([]string{}, bs...)
+		sort.Strings(as)
+		sort.Strings(bs)
+		return reflect.DeepEqual(as, bs)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// getRBACRoleBindingUsers reads the users of the rolebinding from the
+// rbac api of OpenShift 4 instead of the oapi
+func getRBACRoleBindingUsers(clusterId, project, role string) ([]string, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, fmt.Sprintf("apis/rbac.authorization.k8s.io/v1/namespaces/%v/rolebindings/%v", project, role), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return []string{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error getting rbac rolebinding:", resp.StatusCode, string(errMsg))
+		return nil, errors.New(genericAPIError)
+	}
+
+	roleBinding, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error parsing body of response:", err)
+		return nil, errors.New(genericAPIError)
+	}
+
+	users := []string{}
+	subjects, _ := roleBinding.S("subjects").Children()
+	for _, s := range subjects {
+		if kind, _ := s.S("kind").Data().(string); kind != "User" {
+			continue
+		}
+		if name, ok := s.S("name").Data().(string); ok {
+			users = append(users, strings.ToLower(name))
+		}
+	}
+	return common.RemoveDuplicates(users), nil
+}
@@ -0,0 +1,43 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs"
+)
+
+func TestCompareShadowRoleBindingUsers(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	if err := createNewProject(nil, "fake", "own", "u123", "12345", "", false); err != nil {
+		t.Fatal(err)
+	}
+	users, err := getRoleBindingUsers("fake", "own", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shadow := func() (interface{}, error) {
+		return getRBACRoleBindingUsers("fake", "own", "admin")
+	}
+
+	roleBinding := gabs.New()
+	roleBinding.Array("subjects")
+	for _, u := range users {
+		subject := gabs.New()
+		subject.Set("User", "kind")
+		subject.Set(u, "name")
+		roleBinding.ArrayAppend(subject.Data(), "subjects")
+	}
+	group := gabs.New()
+	group.Set("Group", "kind")
+	group.Set("operator", "name")
+	roleBinding.ArrayAppend(group.Data(), "subjects")
+	api.Set("apis/rbac.authorization.k8s.io/v1/namespaces/own/rolebindings/admin", roleBinding)
+
+	if diff := compareShadow(shadowRoleBindingUsers, users, shadow); diff != "" {
+		t.Errorf("expected the same users, got %v", diff)
+	}
+	if diff := compareShadow(shadowRoleBindingUsers, append(users, "u456"), shadow); diff == "" {
+		t.Error("expected a difference for the missing user")
+	}
+}
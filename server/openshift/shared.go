@@ -1,41 +1,140 @@
 package openshift
 
 import (
+	"bytes"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/approval"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/webhooks"
 	"github.com/gin-gonic/gin"
 )
 
 const (
-	genericAPIError         = "Fehler beim Aufruf der OpenShift-API. Bitte erstelle ein Ticket"
-	wrongAPIUsageError      = "Invalid api call - parameters did not match to method definition"
-	testProjectDeletionDays = "30"
+	genericAPIError    = "Fehler beim Aufruf der OpenShift-API. Bitte erstelle ein Ticket"
+	wrongAPIUsageError = "Invalid api call - parameters did not match to method definition"
 )
 
 // RegisterRoutes registers the routes for OpenShift
 func RegisterRoutes(r *gin.RouterGroup) {
+	// Project webhooks can be managed by the project admins
+	webhooks.CanManageProject = validateAdminAccess
+
+	// Handlers which return the planned changes with ?dryRun=true
+	common.SupportsDryRun(
+		newProjectHandler,
+		newTestProjectHandler,
+		editQuotasHandler,
+		newSecretHandler,
+		adminDeleteProjectHandler,
+		updateProjectInformationHandler,
+		updateMegaIdHandler,
+		deleteProjectHandler,
+		teamMembersHandler,
+		updateLimitRangeHandler,
+		mergeBillingHandler,
+		bulkBillingHandler,
+		upgradeImpactHandler,
+	)
+	describeRoutes()
+
 	// OpenShift
 	r.POST("/ose/project", newProjectHandler)
-	r.GET("/ose/projects", getProjectsHandler)
+	r.DELETE("/ose/project", deleteProjectHandler)
+	r.GET("/ose/project/impact", getProjectImpactHandler)
+	r.GET("/ose/project/health", getProjectHealthHandler)
+	r.GET("/ose/project/pods", getPodsHandler)
+	r.GET("/ose/project/usage", common.ETag(), getUsageTimelineHandler)
+	r.GET("/ose/project/routes/stats", getRouteStatsHandler)
+	r.GET("/ose/project/dependencies", getProjectDependenciesHandler)
+	r.POST("/ose/project/dependencies", addProjectDependencyHandler)
+	r.DELETE("/ose/project/dependencies", removeProjectDependencyHandler)
+	r.GET("/ose/project/templates", getProjectTemplatesHandler)
+	r.GET("/ose/project/loadtest", getLoadTestWindowsHandler)
+	r.POST("/ose/project/loadtest", newLoadTestWindowHandler)
+	r.DELETE("/ose/project/loadtest", cancelLoadTestWindowHandler)
+	r.GET("/ose/project/breakglass", getBreakGlassHandler)
+	r.POST("/ose/project/breakglass", breakGlassHandler)
+	r.DELETE("/ose/project/breakglass", revokeBreakGlassHandler)
+	r.GET("/ose/project/vanityurls", getVanityURLsHandler)
+	r.POST("/ose/project/vanityurls", claimVanityURLHandler)
+	r.DELETE("/ose/project/vanityurls", releaseVanityURLHandler)
+	r.GET("/ose/customresources", getCustomResourcesHandler)
+	r.GET("/ose/project/customresources", getProjectCustomResourcesHandler)
+	r.POST("/ose/project/customresources", enableCustomResourceHandler)
+	r.DELETE("/ose/project/customresources", disableCustomResourceHandler)
+	r.GET("/ose/recyclebin", getRecycleBinHandler)
+	r.POST("/ose/recyclebin/restore", restoreProjectHandler)
+	r.GET("/ose/projects", common.ETag(), getProjectsHandler)
+	r.GET("/projects", common.ETag(), getMyProjectsHandler)
 	r.GET("/ose/project/admins", getProjectAdminsHandler)
+	r.GET("/ose/project/members", getProjectMembersHandler)
+	r.POST("/ose/project/members", addProjectMemberHandler)
+	r.DELETE("/ose/project/members", removeProjectMemberHandler)
+	r.POST("/ose/project/groups", addGroupPermissionHandler)
+	r.DELETE("/ose/project/groups", removeGroupPermissionHandler)
+	r.POST("/ose/team/members", teamMembersHandler)
 	r.POST("/ose/testproject", newTestProjectHandler)
-	r.POST("/ose/serviceaccount", newServiceAccountHandler)
-	r.GET("/ose/project/info", getProjectInformationHandler)
+	r.POST("/ose/sandboxproject", newSandboxProjectHandler)
+	r.POST("/ose/serviceaccount", audit.RedactPayload(), newServiceAccountHandler)
+	r.POST("/ose/kubeconfig", audit.RedactPayload(), kubeconfigHandler)
+	r.DELETE("/ose/kubeconfig", revokeKubeconfigHandler)
+	r.GET("/ose/kubeconfig/signingkey", getKubeconfigSigningKeyHandler)
+	r.GET("/ose/project/info", common.ETag(), getProjectInformationHandler)
 	r.POST("/ose/project/info", updateProjectInformationHandler)
+	r.GET("/ose/project/metadata", common.ETag(), getProjectMetadataHandler)
+	r.GET("/ose/project/export", exportProjectHandler)
+	r.POST("/ose/project/spec", applyProjectSpecHandler)
+	r.GET("/ose/project/drift", getProjectDriftHandler)
+	r.GET("/ose/project/deprecations", getProjectDeprecationsHandler)
+	r.GET("/ose/project/naming", getProjectNamingHandler)
+	r.GET("/ose/project/classification", getClassificationHandler)
+	r.POST("/ose/project/classification", updateClassificationHandler)
+	r.GET("/ose/projects/ownerless", getOwnerlessProjectsHandler)
+	r.POST("/ose/projects/billing", bulkBillingHandler)
+	r.POST("/ose/project/adopt", adoptProjectHandler)
+	r.GET("/ose/directory", common.ETag(), common.Compress(), getProjectDirectoryHandler)
+	r.GET("/search", common.Compress(), searchHandler)
+	r.GET("/ose/project/megaid", getMegaIdHandler)
+	r.POST("/ose/project/megaid", updateMegaIdHandler)
+	r.GET("/ose/quotas", getQuotasHandler)
 	r.POST("/ose/quotas", editQuotasHandler)
-	r.POST("/ose/chargeback", chargebackHandler)
-	r.POST("/ose/secret/pull", newPullSecretHandler)
+	r.PUT("/ose/quotas", editQuotasHandler)
+	r.GET("/ose/limitrange", getLimitRangeHandler)
+	r.PUT("/ose/limitrange", updateLimitRangeHandler)
+	r.POST("/ose/chargeback", common.Compress(), chargebackHandler)
+	r.POST("/ose/chargeback/csv", common.Compress(), chargebackCSVHandler)
+	r.GET("/billing/statement", common.ETag(), statementHandler)
+	r.GET("/billing/showback", common.ETag(), showbackHandler)
+	r.GET("/billing/report", common.ETag(), billingReportHandler)
+	r.POST("/ose/secret", audit.RedactPayload(), newSecretHandler)
+	r.POST("/ose/secret/expiry", updateSecretExpiryHandler)
+	r.GET("/ose/secret/pull", getPullSecretsHandler)
+	r.POST("/ose/secret/pull", audit.RedactPayload(), newPullSecretHandler)
+	r.PUT("/ose/secret/pull", audit.RedactPayload(), updatePullSecretHandler)
+	r.POST("/ose/proxy/credentials", audit.RedactPayload(), egressProxyCredentialsHandler)
+	r.POST("/ose/smtprelay/requests", newSmtpRelayRequestHandler)
+	r.GET("/ose/project/budget", common.ETag(), getProjectBudgetHandler)
+	r.POST("/ose/project/budget", setProjectBudgetHandler)
+	r.GET("/ose/accessreviews", getMyAccessReviewTasksHandler)
+	r.POST("/ose/accessreviews", reviewAccessHandler)
+	r.GET("/ose/egressips", getEgressIPsHandler)
+	r.POST("/ose/egressips", reserveEgressIPHandler)
+	r.DELETE("/ose/egressips", releaseEgressIPHandler)
+	r.POST("/ose/permissions/check", checkPermissionsHandler)
 
 	// Volumes (Gluster and NFS)
 	r.POST("/ose/volume", newVolumeHandler)
@@ -44,12 +143,91 @@ func RegisterRoutes(r *gin.RouterGroup) {
 	// Get job status for NFS volumes because it takes a while
 	r.GET("/ose/volume/jobs", jobStatusHandler)
 	r.GET("/ose/clusters", clustersHandler)
+
+	// Portal administration
+	admin := r.Group("/admin", common.RequirePortalAdmin())
+	admin.POST("/ose/project/repair", repairProjectHandler)
+	admin.GET("/ose/projects/unmanaged", getUnmanagedProjectsHandler)
+	admin.POST("/ose/projects/import", importProjectsHandler)
+	admin.POST("/ose/upgrade/notify", upgradeImpactHandler)
+	admin.GET("/ose/deprecations", getDeprecationsHandler)
+	admin.POST("/ose/project/delete", adminDeleteProjectHandler)
+	admin.POST("/ose/offboarding", offboardingHandler)
+	admin.POST("/ose/project/legalhold", placeLegalHoldHandler)
+	admin.DELETE("/ose/project/legalhold", liftLegalHoldHandler)
+	admin.GET("/ose/project/legalhold/attempts", getLegalHoldAttemptsHandler)
+	admin.GET("/ose/workshops", getWorkshopsHandler)
+	admin.POST("/ose/workshops", newWorkshopHandler)
+	admin.DELETE("/ose/workshops/:name", deleteWorkshopHandler)
+	admin.GET("/templates", getCatalogTemplatesHandler)
+	admin.GET("/templates/:kind/:name", getCatalogTemplateHandler)
+	admin.POST("/templates/:kind/:name", saveCatalogTemplateDraftHandler)
+	admin.POST("/templates/:kind/:name/publish", publishCatalogTemplateHandler)
+	admin.DELETE("/templates/:kind/:name", deleteCatalogTemplateHandler)
+	admin.GET("/reports", getReportSchedulesHandler)
+	admin.POST("/reports", newReportScheduleHandler)
+	admin.DELETE("/reports/:id", deleteReportScheduleHandler)
+	admin.POST("/reports/:id/run", runReportScheduleHandler)
+	admin.GET("/ose/smtprelay/requests", getSmtpRelayRequestsHandler)
+	admin.GET("/ose/egressips", getAllEgressIPsHandler)
+	admin.GET("/ose/clusters/tls", getClusterTLSHandler)
+	admin.GET("/ose/dependencies", getDependencyGraphHandler)
+	admin.GET("/ose/vanityurls", getAllVanityURLsHandler)
+	admin.GET("/ose/dependencies/impact", getMaintenanceImpactHandler)
+	admin.POST("/ose/accessreviews", newAccessReviewCampaignHandler)
+	admin.GET("/ose/accessreviews/:id/report", common.ETag(), getAccessReviewReportHandler)
+	admin.POST("/ose/smtprelay/requests/:id/approve", approveSmtpRelayRequestHandler)
+	admin.POST("/ose/smtprelay/requests/:id/reject", rejectSmtpRelayRequestHandler)
+	admin.GET("/billing/anomalies", common.ETag(), getCostAnomaliesHandler)
+	admin.POST("/billing/anomalies", detectCostAnomaliesHandler)
+	admin.POST("/billing/merge", mergeBillingHandler)
+	admin.POST("/billing/anomalies/:id/ack", acknowledgeCostAnomalyHandler)
+
+	// Read only access for auditors (and portal admins)
+	auditor := r.Group("/audit", common.RequirePortalAuditor())
+	auditor.GET("/ose/projects", common.ETag(), common.Compress(), getAllProjectMetadataHandler)
+	auditor.GET("/ose/projects/export", common.Compress(), exportProjectMetadataHandler)
+	auditor.GET("/billing/snapshots", common.ETag(), common.Compress(), getBillingSnapshotHandler)
+	auditor.GET("/billing/statement", common.ETag(), statementHandler)
+	auditor.GET("/billing/report", common.ETag(), billingReportHandler)
+	auditor.GET("/billing/anomalies", common.ETag(), getCostAnomaliesHandler)
+	auditor.GET("/ose/accessreviews/:id/report", common.ETag(), getAccessReviewReportHandler)
+	auditor.GET("/ose/secrets/overdue", getOverdueSecretsHandler)
+	auditor.GET("/ose/naming/violations", getNamingViolationsHandler)
+	auditor.GET("/changes", getChangeCalendarHandler)
+	auditor.GET("/ose/breakglass", getAllBreakGlassHandler)
 }
 
 func RegisterSecRoutes(r *gin.RouterGroup) {
 	r.POST("/gluster/volume/fix", fixVolumeHandler)
 }
 
+// RegisterApprovalKinds registers the requests which have to be approved by
+// a portal admin
+func RegisterApprovalKinds() {
+	registerSmtpRelayApprovals()
+	registerTwoPersonApprovals()
+	registerAdoptionApprovals()
+	registerProjectApprovals()
+	approval.ProjectLabels = getProjectLabels
+}
+
+// getProjectLabels returns the labels of the namespace for the approval routes
+func getProjectLabels(clusterId, project string) (map[string]string, error) {
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[string]string)
+	children, _ := namespace.Path("metadata.labels").ChildrenMap()
+	for k, v := range children {
+		if value, ok := v.Data().(string); ok {
+			labels[k] = value
+		}
+	}
+	return labels, nil
+}
+
 func getProjectAdminsAndOperators(clusterId, project string) ([]string, []string, error) {
 	adminRoleBinding, err := getAdminRoleBinding(clusterId, project)
 	if err != nil {
@@ -98,14 +276,14 @@ func getProjectAdminsAndOperators(clusterId, project string) ([]string, []string
 	return common.RemoveDuplicates(admins), operators, nil
 }
 
+// checkAdminPermissions asks the cluster with a LocalSubjectAccessReview if
+// the user may manage the rolebindings of the project, so group memberships
+// and cluster roles are taken into account. The members of the operator
+// group keep their access to the projects with the group in the admin
+// rolebinding, as the group is one of their groups
 func checkAdminPermissions(clusterId, username, project string) error {
-	// Check if user has admin-access
-	hasAccess := false
-	admins, operators, err := getProjectAdminsAndOperators(clusterId, project)
-	if err != nil {
-		return err
-	}
-
+	// The portal adds every user in lower and upper case to the rolebindings,
+	// so the review finds the lowercase name
 	username = strings.ToLower(username)
 
 	// allow full access via basic auth
@@ -113,24 +291,18 @@ func checkAdminPermissions(clusterId, username, project string) error {
 		return nil
 	}
 
-	// Access for admins
-	for _, a := range admins {
-		if username == a {
-			hasAccess = true
-		}
-	}
-
-	// Access for operators
-	for _, o := range operators {
-		if username == o {
-			hasAccess = true
-		}
+	allowed, err := canManageProject(clusterId, username, project)
+	if err != nil {
+		return err
 	}
-
-	if hasAccess {
+	if allowed {
 		return nil
 	}
 
+	admins, _, err := getProjectAdminsAndOperators(clusterId, project)
+	if err != nil {
+		return err
+	}
 	return fmt.Errorf("Du hast keine Admin Rechte auf das Projekt: %v. Bestehende Admins sind folgende Benutzer: %v", project, strings.Join(admins, ", "))
 }
 
@@ -151,6 +323,13 @@ func getOperatorGroup(clusterId string) (*gabs.Container, error) {
 }
 
 func getAdminRoleBinding(clusterId, project string) (*gabs.Container, error) {
+	cacheKey := roleBindingCacheKey(clusterId, project)
+	if cached, ok := common.GetCache().Get(cacheKey); ok {
+		if json, err := gabs.ParseJSON(cached); err == nil {
+			return json, nil
+		}
+	}
+
 	resp, err := getOseHTTPClient("GET", clusterId, "oapi/v1/namespaces/"+project+"/rolebindings/admin", nil)
 	if err != nil {
 		return nil, err
@@ -170,48 +349,219 @@ func getAdminRoleBinding(clusterId, project string) (*gabs.Container, error) {
 		log.Println("error parsing body of response:", err)
 		return nil, errors.New(genericAPIError)
 	}
+	// Errors of the api aren't cached, they may be transient
+	if resp.StatusCode == http.StatusOK {
+		common.GetCache().Set(cacheKey, json.Bytes())
+	}
+
+	return json, nil
+}
+
+func roleBindingCacheKey(clusterId, project string) string {
+	return fmt.Sprintf("rolebinding/%v/%v", clusterId, project)
+}
+
+func namespaceCacheKey(clusterId, project string) string {
+	return fmt.Sprintf("namespace/%v/%v", clusterId, project)
+}
+
+func projectsCacheKey(clusterId string) string {
+	return fmt.Sprintf("projects/%v", clusterId)
+}
+
+// getNamespace returns the namespace of the project. The result may be
+// cached, so it must not be used as base for updates
+func getNamespace(clusterId, project string) (*gabs.Container, error) {
+	cacheKey := namespaceCacheKey(clusterId, project)
+	if cached, ok := common.GetCache().Get(cacheKey); ok {
+		if json, err := gabs.ParseJSON(cached); err == nil {
+			return json, nil
+		}
+	}
+
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces/"+project, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return nil, errors.New(genericAPIError)
+	}
+	if resp.StatusCode == http.StatusOK {
+		common.GetCache().Set(cacheKey, json.Bytes())
+	}
 
 	return json, nil
 }
 
+// updateNamespaceAnnotations reads the current namespace, lets update change
+// the annotations and saves it
+func updateNamespaceAnnotations(clusterId, project string, update func(annotations *gabs.Container)) error {
+	return updateNamespace(clusterId, project, func(namespace *gabs.Container) {
+		if namespace.Path("metadata.annotations").Data() == nil {
+			namespace.SetP(map[string]interface{}{}, "metadata.annotations")
+		}
+		update(namespace.Path("metadata.annotations"))
+	})
+}
+
+// updateNamespace reads the current namespace, lets update change it and saves it
+func updateNamespace(clusterId, project string, update func(namespace *gabs.Container)) error {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces/"+project, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.New("Das Projekt existiert nicht")
+	}
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return errors.New(genericAPIError)
+	}
+
+	update(json)
+
+	resp, err = getOseHTTPClient("PUT", clusterId, "api/v1/namespaces/"+project, bytes.NewReader(json.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	common.GetCache().Delete(namespaceCacheKey(clusterId, project))
+	common.GetCache().Delete(billingIndexCacheKey(clusterId))
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error updating namespace:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
+
+// getAllNamespaces returns all namespaces of the cluster
+func getAllNamespaces(clusterId string) ([]*gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return nil, errors.New(genericAPIError)
+	}
+
+	namespaces, err := json.S("items").Children()
+	if err != nil {
+		log.Println("Unable to parse namespace list", err.Error())
+		return nil, errors.New(genericAPIError)
+	}
+	return namespaces, nil
+}
+
 func getOseHTTPClient(method string, clusterId string, endURL string, body io.Reader) (*http.Response, error) {
+	return getOseHTTPClientOfRequest("", method, clusterId, endURL, body)
+}
+
+// getOseHTTPClientOfRequest is getOseHTTPClient for a request of the api,
+// the request id is sent to the cluster
+func getOseHTTPClientOfRequest(requestID string, method string, clusterId string, endURL string, body io.Reader) (*http.Response, error) {
 	cluster, err := getOpenshiftCluster(clusterId)
 	if err != nil {
 		return nil, err
 	}
 
-	token := cluster.Token
+	token := clusterToken(cluster)
 	if token == "" {
 		log.Printf("WARNING: Cluster token not found. Please see README for more details. ClusterId: %v", clusterId)
 		return nil, errors.New(common.ConfigNotSetError)
 	}
+	return doClusterRequest(cluster, requestID, method, endURL, body, func(req *http.Request) error {
+		req.Header.Add("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// doClusterRequest calls the api of the cluster, authenticate sets the
+// credentials of the request. The request id of the api, if any, is sent in
+// the header common.RequestIDHeader, so the calls can be found in the audit
+// log of the cluster
+func doClusterRequest(cluster OpenshiftCluster, requestID string, method string, endURL string, body io.Reader, authenticate func(*http.Request) error) (*http.Response, error) {
 	base := cluster.URL
 	if base == "" {
-		log.Printf("WARNING: Cluster URL not found. Please see README for more details. ClusterId: %v", clusterId)
+		log.Printf("WARNING: Cluster URL not found. Please see README for more details. ClusterId: %v", cluster.ID)
 		return nil, errors.New(common.ConfigNotSetError)
 	}
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	client, err := clusterHTTPClient(cluster)
+	if err != nil {
+		return nil, err
 	}
-	client := &http.Client{Transport: tr}
 
-	req, _ := http.NewRequest(method, base+"/"+endURL, body)
+	// The body is sent again by the retries
+	var payload []byte
+	if body != nil {
+		if payload, err = ioutil.ReadAll(body); err != nil {
+			return nil, err
+		}
+	}
 
-	log.Debugf("Calling %v", req.URL.String())
+	return doWithRetry(client, cluster.ID, method, func() (*http.Request, error) {
+		var reqBody io.Reader
+		if payload != nil {
+			reqBody = bytes.NewReader(payload)
+		}
+		req, _ := http.NewRequest(method, base+"/"+endURL, reqBody)
 
-	req.Header.Add("Authorization", "Bearer "+token)
+		log.Debugf("Calling %v", req.URL.String())
 
-	if method == "PATCH" {
-		req.Header.Set("Content-Type", "application/json-patch+json")
+		if err := authenticate(req); err != nil {
+			return nil, err
+		}
+		if requestID != "" {
+			req.Header.Set(common.RequestIDHeader, requestID)
+		}
+
+		if method == "PATCH" {
+			req.Header.Set("Content-Type", "application/json-patch+json")
+		}
+		return req, nil
+	})
+}
+
+// dryRunOnCluster sends the change with ?dryRun=All, so the api validates it
+// without persisting it. Clusters without server-side dry-run are skipped
+func dryRunOnCluster(clusterId, method, url string, object *gabs.Container) error {
+	cluster, err := getOpenshiftCluster(clusterId)
+	if err != nil {
+		return err
+	}
+	if !cluster.DryRun {
+		return nil
 	}
 
-	resp, err := client.Do(req)
+	resp, err := getOseHTTPClient(method, clusterId, url+"?dryRun=All", bytes.NewReader(object.Bytes()))
 	if err != nil {
-		log.Println("Error from server: ", err.Error())
-		return nil, errors.New(genericAPIError)
+		return err
 	}
-	return resp, nil
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		status, err := gabs.ParseJSONBuffer(resp.Body)
+		if err != nil {
+			log.Println("error decoding json:", err, resp.StatusCode)
+			return errors.New(genericAPIError)
+		}
+		message, _ := status.S("message").Data().(string)
+		return fmt.Errorf("Die Änderung wurde vom Cluster abgelehnt: %v", message)
+	}
+	return nil
 }
 
 func getWZUBackendClient(method string, endUrl string, body io.Reader) (*http.Response, error) {
@@ -225,6 +575,7 @@ func getWZUBackendClient(method string, endUrl string, body io.Reader) (*http.Re
 
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		Proxy:           common.ProxyFor("wzubackend"),
 	}
 	client := &http.Client{Transport: tr}
 	req, _ := http.NewRequest(method, wzuBackendUrl+"/"+endUrl, body)
@@ -242,6 +593,70 @@ func getWZUBackendClient(method string, endUrl string, body io.Reader) (*http.Re
 	return resp, nil
 }
 
+var (
+	externalAPIClients     = make(map[string]*http.Client)
+	externalAPIClientsLock sync.Mutex
+)
+
+// externalAPIClient returns the reused client for the api of another system.
+// It trusts the system CAs plus the pem file of '<prefix>.api_cabundle'
+func externalAPIClient(prefix string) (*http.Client, error) {
+	caBundle := config.Config().GetString(prefix + ".api_cabundle")
+	key := prefix + "|" + caBundle
+
+	externalAPIClientsLock.Lock()
+	defer externalAPIClientsLock.Unlock()
+	if client, ok := externalAPIClients[key]; ok {
+		return client, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if caBundle != "" {
+		roots, err := systemCertPoolWith(caBundle)
+		if err != nil {
+			log.Printf("WARNING: api_cabundle of %v: %v", prefix, err)
+			return nil, errors.New(common.ConfigNotSetError)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: common.ProxyFor(prefix)}}
+	externalAPIClients[key] = client
+	return client, nil
+}
+
+// getExternalAPIClient calls an api of another system with basic auth. The url
+// and the secret are read from the config keys '<prefix>.api_url' and '<prefix>.api_secret'
+func getExternalAPIClient(prefix, method, apiPath string, body io.Reader) (*http.Response, error) {
+	cfg := config.Config()
+	apiURL := cfg.GetString(prefix + ".api_url")
+	apiSecret := cfg.GetString(prefix + ".api_secret")
+	if apiURL == "" || apiSecret == "" {
+		log.Printf("WARNING: Env variables '%v_API_URL' and '%v_API_SECRET' must be specified", strings.ToUpper(prefix), strings.ToUpper(prefix))
+		return nil, errors.New(common.ConfigNotSetError)
+	}
+
+	client, err := externalAPIClient(prefix)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, apiURL+"/"+apiPath, body)
+	if err != nil {
+		log.Printf("Error creating the request to the %v api: %v", prefix, err)
+		return nil, errors.New(genericAPIError)
+	}
+	req.SetBasicAuth("CLOUD_SSP", apiSecret)
+	req.Header.Set("Content-Type", "application/json")
+
+	log.Debugf("Calling %v", req.URL.String())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Error from %v api: %v", prefix, err.Error())
+		return nil, errors.New(genericAPIError)
+	}
+	return resp, nil
+}
+
 func getGlusterHTTPClient(clusterId string, url string, body io.Reader) (*http.Response, error) {
 	cluster, err := getOpenshiftCluster(clusterId)
 	if err != nil {
@@ -261,7 +676,7 @@ func getGlusterHTTPClient(clusterId string, url string, body io.Reader) (*http.R
 		return nil, errors.New(common.ConfigNotSetError)
 	}
 
-	client := &http.Client{}
+	client := common.HTTPClient("gluster")
 	req, _ := http.NewRequest("POST", fmt.Sprintf("%v/%v", apiUrl, url), body)
 
 	log.Debugf("Calling %v", req.URL.String())
@@ -300,7 +715,7 @@ func getNfsHTTPClient(method, clusterId, apiPath string, body io.Reader) (*http.
 	// https://blog.abhi.host/blog/2016/02/27/golang-creating-https-connection-via/
 	proxyURL, err := url.Parse(nfsProxy)
 	if err != nil {
-		log.Printf(err.Error())
+		log.Println(err.Error())
 	}
 
 	transport := http.Transport{
@@ -311,7 +726,7 @@ func getNfsHTTPClient(method, clusterId, apiPath string, body io.Reader) (*http.
 	client := &http.Client{Transport: &transport}
 	req, err := http.NewRequest(method, fmt.Sprintf("%v/%v", apiUrl, apiPath), body)
 	if err != nil {
-		log.Printf(err.Error())
+		log.Println(err.Error())
 	}
 
 	log.Debugf("Calling %v", req.URL.String())
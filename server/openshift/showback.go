@@ -0,0 +1,150 @@
+package openshift
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxShowbackMonths = 24
+	groupByProject    = "project"
+	groupByBilling    = "billing"
+	groupByCluster    = "cluster"
+)
+
+// Showback are the costs per month grouped for charting
+type Showback struct {
+	GroupBy string           `json:"groupBy"`
+	Months  []string         `json:"months"`
+	Series  []ShowbackSeries `json:"series"`
+}
+
+// ShowbackSeries are the costs in CHF of one group. Values[i] is the cost in Months[i]
+type ShowbackSeries struct {
+	Key    string    `json:"key"`
+	Values []float64 `json:"values"`
+	Total  float64   `json:"total"`
+}
+
+func showbackHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	billing := c.Query("billing")
+	groupBy := c.DefaultQuery("groupBy", groupByProject)
+
+	from, to, err := parseShowbackRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if groupBy != groupByProject && groupBy != groupByBilling && groupBy != groupByCluster {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "groupBy muss project, billing oder cluster sein"})
+		return
+	}
+
+	// Only portal admins and auditors can see the costs of all billing numbers
+	if billing == "" && !common.IsPortalAdmin(username) && !common.IsPortalAuditor(username) {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Kontierungsnummer muss angegeben werden"})
+		return
+	}
+	if billing != "" {
+		if err := checkBillingPermissions(username, billing); err != nil {
+			c.JSON(http.StatusForbidden, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	}
+
+	audit.Describe(c, "%v queried the showback from %v to %v for billing '%v'", username, from.Format(monthFormat), to.Format(monthFormat), billing)
+	showback, err := getShowback(from, to, billing, c.Query("project"), groupBy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, showback)
+}
+
+// parseShowbackRange parses the months from and to. The default is the last
+// twelve completed months
+func parseShowbackRange(fromParam, toParam string) (time.Time, time.Time, error) {
+	rangeError := errors.New("from und to müssen Monate sein (z.B. 2019-01)")
+	current := common.Now()
+	to := time.Date(current.Year(), current.Month(), 1, 0, 0, 0, 0, current.Location()).AddDate(0, -1, 0)
+	if toParam != "" {
+		t, err := time.ParseInLocation(monthFormat, toParam, current.Location())
+		if err != nil {
+			return to, to, rangeError
+		}
+		to = t
+	}
+	from := to.AddDate(0, -11, 0)
+	if fromParam != "" {
+		f, err := time.ParseInLocation(monthFormat, fromParam, current.Location())
+		if err != nil {
+			return from, to, rangeError
+		}
+		from = f
+	}
+
+	if from.After(to) || from.AddDate(0, maxShowbackMonths, 0).Before(to) {
+		return from, to, errors.New("Der Zeitraum muss zwischen 1 und 24 Monaten sein")
+	}
+	return from, to, nil
+}
+
+func getShowback(from, to time.Time, billing, project, groupBy string) (*Showback, error) {
+	showback := &Showback{GroupBy: groupBy, Months: []string{}, Series: []ShowbackSeries{}}
+	series := make(map[string][]float64)
+
+	for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+		i := len(showback.Months)
+		showback.Months = append(showback.Months, month.Format(monthFormat))
+
+		for _, cluster := range []Cluster{awsCluster, viasCluster} {
+			snapshot, err := getBillingSnapshot(cluster, month)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range snapshot.Rows {
+				assignment := getAccountAssignment(r)
+				if (billing != "" && assignment != billing) || (project != "" && r.Project != project) {
+					continue
+				}
+
+				key := r.Project
+				switch groupBy {
+				case groupByBilling:
+					key = assignment
+				case groupByCluster:
+					key = string(cluster)
+				}
+				for len(series[key]) <= i {
+					series[key] = append(series[key], 0)
+				}
+				series[key][i] += getTotalPrice(r)
+			}
+		}
+	}
+
+	for key, values := range series {
+		for len(values) < len(showback.Months) {
+			values = append(values, 0)
+		}
+		s := ShowbackSeries{Key: key, Values: values}
+		for i := range s.Values {
+			s.Values[i] = math.Round(s.Values[i]*100) / 100
+			s.Total += s.Values[i]
+		}
+		s.Total = math.Round(s.Total*100) / 100
+		showback.Series = append(showback.Series, s)
+	}
+	sort.Slice(showback.Series, func(i, j int) bool {
+		return showback.Series[i].Total > showback.Series[j].Total
+	})
+	return showback, nil
+}
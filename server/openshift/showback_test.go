@@ -0,0 +1,71 @@
+package openshift
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+)
+
+func TestGetShowback(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+	january := time.Date(2019, 1, 1, 0, 0, 0, 0, time.Local)
+	february := january.AddDate(0, 1, 0)
+	snapshots := []BillingSnapshot{
+		{Cluster: awsCluster, Month: "2019-01", Rows: []Resources{
+			{Project: "shop", PspElement: "12345", Prices: Pricing{QuotaCpu: 10, Storage: 0.5}},
+			{Project: "blog", PspElement: "99999", Prices: Pricing{QuotaCpu: 3}},
+		}},
+		{Cluster: viasCluster, Month: "2019-01", Rows: []Resources{}},
+		{Cluster: awsCluster, Month: "2019-02", Rows: []Resources{}},
+		{Cluster: viasCluster, Month: "2019-02", Rows: []Resources{
+			{Project: "shop-db", PspElement: "12345", Prices: Pricing{QuotaMemory: 20}},
+		}},
+	}
+	for _, s := range snapshots {
+		month, _ := time.ParseInLocation(monthFormat, s.Month, time.Local)
+		store.Put(billingSnapshotsCollection, billingSnapshotID(s.Cluster, month), s)
+	}
+
+	showback, err := getShowback(january, february, "12345", "", groupByProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(showback.Months) != 2 || len(showback.Series) != 2 {
+		t.Fatalf("expected two months of the two projects of the billing, got %+v", showback)
+	}
+	if s := showback.Series[0]; s.Key != "shop-db" || s.Values[0] != 0 || s.Values[1] != 20 {
+		t.Errorf("expected the most expensive project first, got %+v", s)
+	}
+	if s := showback.Series[1]; s.Key != "shop" || s.Values[0] != 10.5 || s.Total != 10.5 {
+		t.Errorf("expected the costs of shop in january, got %+v", s)
+	}
+
+	showback, _ = getShowback(january, february, "", "", groupByCluster)
+	if len(showback.Series) != 2 || showback.Series[0].Key != string(viasCluster) || showback.Series[1].Total != 13.5 {
+		t.Errorf("expected the costs per cluster, got %+v", showback.Series)
+	}
+}
+
+func TestParseShowbackRange(t *testing.T) {
+	from, to, err := parseShowbackRange("2019-01", "2019-03")
+	if err != nil || from.Month() != time.January || to.Month() != time.March {
+		t.Errorf("expected january to march, got %v %v %v", from, to, err)
+	}
+	for _, r := range [][2]string{{"2019-03", "2019-01"}, {"2017-01", "2019-03"}, {"2019", ""}} {
+		if _, _, err := parseShowbackRange(r[0], r[1]); err == nil {
+			t.Errorf("%v: expected an error", r)
+		}
+	}
+}
+
+func TestShowbackHandlerRequiresBilling(t *testing.T) {
+	_, cleanup := newFakeCluster(t)
+	defer cleanup()
+
+	if w := listRequest(showbackHandler, "/billing/showback"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected users to see only the costs of a billing number, got %v", w.Code)
+	}
+}
@@ -0,0 +1,225 @@
+package openshift
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/approval"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	smtpRelayKind               = "smtp-relay"
+	smtpRelayRequestsCollection = "smtp_relay_requests"
+	smtpRelaySecretName         = "smtp-relay"
+)
+
+// SmtpRelayRequest is the request of a project to send mails over the relay
+// as it was stored before the approval package
+type SmtpRelayRequest struct {
+	ID          string     `json:"id"`
+	ClusterId   string     `json:"clusterid"`
+	Project     string     `json:"project"`
+	Reason      string     `json:"reason"`
+	Status      string     `json:"status"`
+	RequestedBy string     `json:"requestedBy"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	DecidedBy   string     `json:"decidedBy,omitempty"`
+	DecidedAt   *time.Time `json:"decidedAt,omitempty"`
+	Comment     string     `json:"comment,omitempty"`
+}
+
+// registerSmtpRelayApprovals registers the smtp relay requests in the
+// approval state machine and migrates the requests of the old collection
+func registerSmtpRelayApprovals() {
+	approval.RegisterKind(approval.Kind{
+		Name: smtpRelayKind,
+		Apply: func(r approval.Request) error {
+			return enableSmtpRelay(r.ClusterId, r.Project)
+		},
+		Notify: func(r approval.Request) {
+			if r.State == approval.StatePending || r.State == approval.StateApproved {
+				return
+			}
+			if err := sendSmtpRelayDecisionMail(r); err != nil {
+				log.Printf("Can't send e-mail about smtp relay request %v: %v", r.ID, err)
+			}
+		},
+	})
+
+	old := []SmtpRelayRequest{}
+	err := store.List(smtpRelayRequestsCollection, func(id string, data []byte) error {
+		var r SmtpRelayRequest
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		old = append(old, r)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error reading smtp relay requests: %v", err)
+		return
+	}
+	for _, r := range old {
+		state := r.Status
+		// Approved requests were enabled right away
+		if state == approval.StateApproved {
+			state = approval.StateApplied
+		}
+		err := approval.Import(approval.Request{
+			ID:          r.ID,
+			Kind:        smtpRelayKind,
+			State:       state,
+			ClusterId:   r.ClusterId,
+			Project:     r.Project,
+			Reason:      r.Reason,
+			RequestedBy: r.RequestedBy,
+			RequestedAt: r.RequestedAt,
+			DecidedBy:   r.DecidedBy,
+			DecidedAt:   r.DecidedAt,
+			Comment:     r.Comment,
+		})
+		if err != nil {
+			log.Printf("Error migrating smtp relay request %v: %v", r.ID, err)
+			continue
+		}
+		if err := store.Delete(smtpRelayRequestsCollection, r.ID); err != nil {
+			log.Printf("Error deleting migrated smtp relay request %v: %v", r.ID, err)
+		}
+	}
+}
+
+func newSmtpRelayRequestHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.SmtpRelayRequestCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if data.Reason == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Es muss ein Grund angegeben werden"})
+		return
+	}
+
+	request, err := approval.Create(smtpRelayKind, data.ClusterId, data.Project, username, data.Reason, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, request)
+}
+
+func getSmtpRelayRequestsHandler(c *gin.Context) {
+	listParams, err := common.ParseListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	status := c.Query("status")
+
+	requests, err := approval.List(func(r approval.Request) bool {
+		return r.Kind == smtpRelayKind && (status == "" || r.State == status) && listParams.Matches(r.Project, r.RequestedBy)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	start, end, next := listParams.Page(len(requests))
+	c.JSON(http.StatusOK, common.ListResponse{
+		Items:    requests[start:end],
+		Total:    len(requests),
+		Continue: next,
+	})
+}
+
+func approveSmtpRelayRequestHandler(c *gin.Context) {
+	decideSmtpRelayRequest(c, approval.Approve)
+}
+
+func rejectSmtpRelayRequestHandler(c *gin.Context) {
+	decideSmtpRelayRequest(c, approval.Reject)
+}
+
+func decideSmtpRelayRequest(c *gin.Context, decide func(id, username, comment string) (*approval.Request, error)) {
+	username := common.GetUserName(c)
+
+	var data common.DecisionCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	request, err := decide(c.Param("id"), username, data.Comment)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, request)
+}
+
+// enableSmtpRelay adds the namespace to the allowlist of the relay and saves
+// the connection details in a secret
+func enableSmtpRelay(clusterId, project string) error {
+	body, _ := json.Marshal(map[string]string{"cluster": clusterId, "namespace": project})
+	resp, err := getExternalAPIClient("smtp_relay", "POST", "allowlist", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error adding %v/%v to the smtp relay allowlist: %v %v", clusterId, project, resp.StatusCode, string(errMsg))
+		return errors.New("Fehler beim Freischalten des Mail-Relays. Bitte erstelle ein Ticket")
+	}
+
+	cfg := config.Config()
+	secret := newObjectRequest("Secret", smtpRelaySecretName)
+	secret.Set("Opaque", "type")
+	secret.Set(map[string]interface{}{
+		"SMTP_HOST": cfg.GetString("smtp_relay.host"),
+		"SMTP_PORT": cfg.GetString("smtp_relay.port"),
+	}, "stringData")
+	return createOrReplaceObject(clusterId, project, secret)
+}
+
+func sendSmtpRelayDecisionMail(request approval.Request) error {
+	mail := common.GetMailForUser(request.RequestedBy)
+	if mail == "" {
+		return errors.New("no mail address for " + request.RequestedBy)
+	}
+
+	result := "abgelehnt"
+	switch request.State {
+	case approval.StateApplied:
+		result = fmt.Sprintf("bewilligt. Die Verbindungsdaten sind im Secret %v", smtpRelaySecretName)
+	case approval.StateExpired:
+		result = "nicht rechtzeitig bearbeitet und ist abgelaufen. Bitte stelle ihn neu"
+	}
+	return common.SendMail([]string{mail}, fmt.Sprintf("Mail-Relay für Projekt %v", request.Project), fmt.Sprintf(`
+	Hallo %v,
+	<br><br>
+	Dein Antrag für das Mail-Relay im Projekt %v auf Cluster %v wurde %v.
+	<br><br>
+	%v
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, request.RequestedBy, request.Project, request.ClusterId, result, request.Comment))
+}
@@ -0,0 +1,64 @@
+package openshift
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/approval"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+)
+
+func TestSmtpRelayRequest(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	api.AddProject("shop", "u123")
+
+	allowed := []string{}
+	relayAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		allowed = append(allowed, body["cluster"]+"/"+body["namespace"])
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer relayAPI.Close()
+	cfg := config.Config()
+	cfg.Set("portal_admins", []string{"admin"})
+	cfg.Set("smtp_relay.host", "smtp.example.com")
+	cfg.Set("smtp_relay.port", "25")
+	cfg.Set("smtp_relay.api_url", relayAPI.URL)
+	cfg.Set("smtp_relay.api_secret", "s")
+
+	// Requests stored before the approval package are migrated
+	store.Put(smtpRelayRequestsCollection, "old", SmtpRelayRequest{ID: "old", ClusterId: "fake", Project: "shop", Status: approval.StateApproved, RequestedAt: time.Now()})
+	registerSmtpRelayApprovals()
+	if r, found, _ := approval.Get("old"); !found || r.Kind != smtpRelayKind || r.State != approval.StateApplied {
+		t.Errorf("expected the old request to be migrated as applied, got %+v", r)
+	}
+	if found, _ := store.Get(smtpRelayRequestsCollection, "old", &SmtpRelayRequest{}); found {
+		t.Error("expected the old request to be deleted after the migration")
+	}
+
+	if w := jsonRequest(newSmtpRelayRequestHandler, "POST", "/ose/project/smtprelay", "u123", `{"clusterid": "fake", "project": "shop"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a request without reason to be rejected, got %v", w.Code)
+	}
+	w := jsonRequest(newSmtpRelayRequestHandler, "POST", "/ose/project/smtprelay", "u123", `{"clusterid": "fake", "project": "shop", "reason": "Newsletter"}`)
+	var request approval.Request
+	if err := json.Unmarshal(w.Body.Bytes(), &request); err != nil || w.Code != http.StatusOK || request.State != approval.StatePending {
+		t.Fatalf("expected a pending request, got %v %v", w.Code, w.Body.String())
+	}
+
+	if _, err := approval.Approve(request.ID, "admin", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(allowed) != 1 || allowed[0] != "fake/shop" {
+		t.Errorf("expected the project to be added to the allowlist, got %v", allowed)
+	}
+	secret, ok := api.Get("api/v1/namespaces/shop/secrets/" + smtpRelaySecretName)
+	if !ok || secret.Path("stringData.SMTP_HOST").Data() != "smtp.example.com" {
+		t.Errorf("expected the connection details in the secret, got %v", secret)
+	}
+}
@@ -0,0 +1,201 @@
+package openshift
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Statement is the monthly invoice of one billing number over all clusters
+type Statement struct {
+	Billing    string
+	Month      string
+	Rows       []StatementRow
+	UnitPrices Pricing
+	Total      float64
+	CreatedAt  time.Time
+}
+
+type StatementRow struct {
+	Cluster Cluster
+	Resources
+	Total float64
+}
+
+var statementTemplate = template.Must(template.New("statement").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>OpenShift Abrechnung {{.Billing}} {{.Month}}</title>
+<style>
+body { font-family: sans-serif; font-size: 12px; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #999; padding: 4px; text-align: right; }
+td:first-child, td:nth-child(2), th { text-align: left; }
+</style>
+</head>
+<body>
+<h1>OpenShift Abrechnung</h1>
+<p>Kontierungsnummer: {{.Billing}}<br>Monat: {{.Month}}<br>Erstellt: {{.CreatedAt.Format "02.01.2006"}}</p>
+<table>
+<tr><th>Cluster</th><th>Projekt</th><th>Quota CPU</th><th>Quota Memory (GB)</th><th>Verwendet CPU</th><th>Verwendet Memory (GB)</th><th>Storage (GB)</th><th>Betrag (CHF)</th></tr>
+{{range .Rows}}<tr><td>{{.Cluster}}</td><td>{{.Project}}</td><td>{{printf "%.2f" .QuotaCpu}}</td><td>{{printf "%.2f" .QuotaMemory}}</td><td>{{printf "%.2f" .UsedCpu}}</td><td>{{printf "%.2f" .UsedMemory}}</td><td>{{printf "%.2f" .Storage}}</td><td>{{printf "%.2f" .Total}}</td></tr>
+{{end}}<tr><th colspan="7">Total</th><th>{{printf "%.2f" .Total}}</th></tr>
+</table>
+<h2>Preise pro Einheit</h2>
+<p>Quota CPU: {{.UnitPrices.QuotaCpu}} CHF, Quota Memory: {{.UnitPrices.QuotaMemory}} CHF,
+Verwendet CPU: {{.UnitPrices.UsedCpu}} CHF, Verwendet Memory: {{.UnitPrices.UsedMemory}} CHF,
+Storage: {{.UnitPrices.Storage}} CHF (zzgl. Management Fee)</p>
+</body>
+</html>
+`))
+
+func statementHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	billing := c.Query("billing")
+	month, err := time.Parse(monthFormat, c.Query("month"))
+	if billing == "" || err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Kontierungsnummer und Monat (z.B. 2019-01) müssen angegeben werden"})
+		return
+	}
+
+	if err := checkBillingPermissions(username, billing); err != nil {
+		c.JSON(http.StatusForbidden, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v created the statement for billing %v in %v", username, billing, month.Format(monthFormat))
+	statement, err := createStatement(billing, month)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("openshift-%v-%v", billing, statement.Month)
+	if c.Query("format") == "pdf" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%v.pdf", filename))
+		c.Header("Content-Type", "application/pdf")
+		if err := writeStatementPDF(c.Writer, statement); err != nil {
+			common.Logger(c).Printf("Error creating statement pdf: %v", err)
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%v.html", filename))
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := statementTemplate.Execute(c.Writer, statement); err != nil {
+		common.Logger(c).Printf("Error creating statement html: %v", err)
+	}
+}
+
+// checkBillingPermissions allows portal admins, auditors and the requesters of
+// projects with the billing number
+func checkBillingPermissions(username, billing string) error {
+	if common.IsPortalAdmin(username) || common.IsPortalAuditor(username) {
+		return nil
+	}
+	if contains(getBillingOwners(billing), username) {
+		return nil
+	}
+	return fmt.Errorf("Du hast keine Berechtigung für die Kontierungsnummer %v", billing)
+}
+
+func createStatement(billing string, month time.Time) (*Statement, error) {
+	statement := &Statement{
+		Billing:    billing,
+		Month:      month.Format(monthFormat),
+		Rows:       []StatementRow{},
+		UnitPrices: unitprices,
+		CreatedAt:  time.Now(),
+	}
+
+	for _, cluster := range []Cluster{awsCluster, viasCluster} {
+		snapshot, err := getBillingSnapshot(cluster, month)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range snapshot.Rows {
+			if getAccountAssignment(r) != billing {
+				continue
+			}
+			total := getTotalPrice(r)
+			statement.Rows = append(statement.Rows, StatementRow{
+				Cluster:   cluster,
+				Resources: r,
+				Total:     total,
+			})
+			statement.Total += total
+		}
+	}
+
+	sort.Slice(statement.Rows, func(i, j int) bool {
+		return statement.Rows[i].Project < statement.Rows[j].Project
+	})
+	return statement, nil
+}
+
+func writeStatementPDF(w http.ResponseWriter, statement *Statement) error {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.Cell(0, 10, "OpenShift Abrechnung")
+	pdf.Ln(12)
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.Cell(0, 6, tr(fmt.Sprintf("Kontierungsnummer: %v", statement.Billing)))
+	pdf.Ln(6)
+	pdf.Cell(0, 6, fmt.Sprintf("Monat: %v", statement.Month))
+	pdf.Ln(6)
+	pdf.Cell(0, 6, fmt.Sprintf("Erstellt: %v", statement.CreatedAt.Format("02.01.2006")))
+	pdf.Ln(10)
+
+	header := []string{"Cluster", "Projekt", "Quota CPU", "Quota Memory", "Verw. CPU", "Verw. Memory", "Storage", "Betrag (CHF)"}
+	widths := []float64{25, 80, 25, 28, 25, 28, 25, 30}
+	pdf.SetFont("Helvetica", "B", 9)
+	for i, h := range header {
+		pdf.CellFormat(widths[i], 7, h, "1", 0, "L", false, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 9)
+	for _, r := range statement.Rows {
+		values := []string{
+			string(r.Cluster),
+			tr(r.Project),
+			fmt.Sprintf("%.2f", r.QuotaCpu),
+			fmt.Sprintf("%.2f", r.QuotaMemory),
+			fmt.Sprintf("%.2f", r.UsedCpu),
+			fmt.Sprintf("%.2f", r.UsedMemory),
+			fmt.Sprintf("%.2f", r.Storage),
+			fmt.Sprintf("%.2f", r.Total),
+		}
+		for i, v := range values {
+			align := "R"
+			if i < 2 {
+				align = "L"
+			}
+			pdf.CellFormat(widths[i], 6, v, "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.CellFormat(widths[0]+widths[1]+widths[2]+widths[3]+widths[4]+widths[5]+widths[6], 7, "Total", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(widths[7], 7, fmt.Sprintf("%.2f", statement.Total), "1", 0, "R", false, 0, "")
+	pdf.Ln(12)
+
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.MultiCell(0, 5, tr(fmt.Sprintf("Preise pro Einheit: Quota CPU %v CHF, Quota Memory %v CHF, Verwendet CPU %v CHF, Verwendet Memory %v CHF, Storage %v CHF (zzgl. Management Fee)",
+		statement.UnitPrices.QuotaCpu, statement.UnitPrices.QuotaMemory, statement.UnitPrices.UsedCpu, statement.UnitPrices.UsedMemory, statement.UnitPrices.Storage)), "", "L", false)
+
+	return pdf.Output(w)
+}
@@ -0,0 +1,161 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+// StorageProvider provisions the volumes of one storage technology. The
+// technology is chosen per request or by 'storage' of the cluster
+type StorageProvider interface {
+	// Available is true if the api of the provider is configured for the cluster
+	Available(cluster OpenshiftCluster) bool
+	StorageClass(cluster OpenshiftCluster) string
+	// Create provisions the volume. The pv and pvc are created afterwards
+	Create(clusterId, project, pvcName, size, username string) (*common.NewVolumeResponse, error)
+	// SetSource writes the technology specific part of the pv
+	SetSource(pv *gabs.Container, volume *common.NewVolumeResponse)
+	// Owns is true if the pv was provisioned by the provider
+	Owns(pv *gabs.Container) bool
+	Grow(clusterId string, pv *gabs.Container, newSize, username string) error
+	// CreatedMessage is empty if the creation only starts a job whose
+	// progress is polled by the client
+	CreatedMessage() string
+}
+
+var storageProviders = map[string]StorageProvider{
+	"gluster": glusterProvider{},
+	"nfs":     nfsProvider{},
+}
+
+func storageTechnologies() []string {
+	technologies := []string{}
+	for t := range storageProviders {
+		technologies = append(technologies, t)
+	}
+	sort.Strings(technologies)
+	return technologies
+}
+
+// getStorageProvider returns the provider of the technology. Without
+// technology the 'storage' of the cluster is used, or the only provider
+// which is available on the cluster
+func getStorageProvider(clusterId, technology string) (string, StorageProvider, error) {
+	cluster, err := getOpenshiftCluster(clusterId)
+	if err != nil {
+		return "", nil, err
+	}
+	if technology == "" {
+		technology = cluster.Storage
+	}
+	if technology == "" {
+		available := []string{}
+		for _, t := range storageTechnologies() {
+			if storageProviders[t].Available(cluster) {
+				available = append(available, t)
+			}
+		}
+		if len(available) != 1 {
+			return "", nil, fmt.Errorf("Die Technologie muss angegeben werden: %v", strings.Join(available, ", "))
+		}
+		technology = available[0]
+	}
+
+	provider, ok := storageProviders[technology]
+	if !ok {
+		return "", nil, checkTechnology(technology)
+	}
+	if !provider.Available(cluster) {
+		return "", nil, fmt.Errorf("Der Cluster %v unterstützt keine %v Volumes", clusterId, technology)
+	}
+	return technology, provider, nil
+}
+
+// getStorageProviderOf returns the provider which provisioned the pv
+func getStorageProviderOf(pv *gabs.Container) (StorageProvider, error) {
+	for _, t := range storageTechnologies() {
+		if storageProviders[t].Owns(pv) {
+			return storageProviders[t], nil
+		}
+	}
+	return nil, errors.New("Wrong pv name")
+}
+
+type glusterProvider struct{}
+
+func (glusterProvider) Available(cluster OpenshiftCluster) bool {
+	return cluster.GlusterApi != nil
+}
+
+func (glusterProvider) StorageClass(cluster OpenshiftCluster) string {
+	return cluster.GlusterApi.StorageClass
+}
+
+// Create also adds the gluster service and endpoints to the project
+func (glusterProvider) Create(clusterId, project, pvcName, size, username string) (*common.NewVolumeResponse, error) {
+	volume, err := createGlusterVolume(clusterId, project, size, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := createOpenShiftGlusterService(clusterId, project, username); err != nil {
+		return nil, err
+	}
+	if err := createOpenShiftGlusterEndpoint(clusterId, project, username); err != nil {
+		return nil, err
+	}
+	return volume, nil
+}
+
+func (glusterProvider) SetSource(pv *gabs.Container, volume *common.NewVolumeResponse) {
+	pv.SetP("glusterfs-cluster", "spec.glusterfs.endpoints")
+	pv.SetP(volume.Path, "spec.glusterfs.path")
+	pv.SetP(false, "spec.glusterfs.readOnly")
+}
+
+func (glusterProvider) Owns(pv *gabs.Container) bool {
+	return pv.ExistsP("spec.glusterfs")
+}
+
+func (glusterProvider) Grow(clusterId string, pv *gabs.Container, newSize, username string) error {
+	return growGlusterVolume(clusterId, pv, newSize, username)
+}
+
+func (glusterProvider) CreatedMessage() string {
+	return "Das Volume wurde erstellt. Deinem Projekt wurde das PVC, und der Gluster Service & Endpunkte hinzugefügt."
+}
+
+type nfsProvider struct{}
+
+func (nfsProvider) Available(cluster OpenshiftCluster) bool {
+	return cluster.NfsApi != nil
+}
+
+func (nfsProvider) StorageClass(cluster OpenshiftCluster) string {
+	return cluster.NfsApi.StorageClass
+}
+
+func (nfsProvider) Create(clusterId, project, pvcName, size, username string) (*common.NewVolumeResponse, error) {
+	return createNfsVolume(clusterId, project, pvcName, size, username)
+}
+
+func (nfsProvider) SetSource(pv *gabs.Container, volume *common.NewVolumeResponse) {
+	pv.SetP(volume.Path, "spec.nfs.path")
+	pv.SetP(volume.Server, "spec.nfs.server")
+}
+
+func (nfsProvider) Owns(pv *gabs.Container) bool {
+	return pv.ExistsP("spec.nfs")
+}
+
+func (nfsProvider) Grow(clusterId string, pv *gabs.Container, newSize, username string) error {
+	return growNfsVolume(clusterId, pv, newSize, username)
+}
+
+func (nfsProvider) CreatedMessage() string {
+	return ""
+}
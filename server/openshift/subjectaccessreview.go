@@ -0,0 +1,78 @@
+package openshift
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	log "github.com/sirupsen/logrus"
+)
+
+// canManageProject checks with a LocalSubjectAccessReview if the user may
+// update the rolebindings of the project, which is what the admin role allows
+func canManageProject(clusterId, username, project string) (bool, error) {
+	groups, err := getUserGroups(clusterId, username)
+	if err != nil {
+		return false, err
+	}
+
+	review := gabs.New()
+	review.Set("authorization.k8s.io/v1", "apiVersion")
+	review.Set("LocalSubjectAccessReview", "kind")
+	review.Set(project, "metadata", "namespace")
+	review.Set(username, "spec", "user")
+	review.Set(groups, "spec", "groups")
+	review.Set(project, "spec", "resourceAttributes", "namespace")
+	review.Set("update", "spec", "resourceAttributes", "verb")
+	review.Set("rbac.authorization.k8s.io", "spec", "resourceAttributes", "group")
+	review.Set("rolebindings", "spec", "resourceAttributes", "resource")
+
+	resp, err := getOseHTTPClient("POST", clusterId, fmt.Sprintf("apis/authorization.k8s.io/v1/namespaces/%v/localsubjectaccessreviews", project), bytes.NewReader(review.Bytes()))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error reviewing access of %v to project %v on cluster %v: %v %v", username, project, clusterId, resp.StatusCode, string(errMsg))
+		return false, errors.New(genericAPIError)
+	}
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error parsing body of response:", err)
+		return false, errors.New(genericAPIError)
+	}
+	allowed, _ := json.Path("status.allowed").Data().(bool)
+	return allowed, nil
+}
+
+// getUserGroups returns the openshift groups with the user as member. The
+// review doesn't resolve them for us, as the user isn't authenticated in the
+// request. The groups field of the user object is deprecated and not filled
+// by openshift, so the members of all groups are checked. They are read with
+// every call, so a removed member loses its rights immediately
+func getUserGroups(clusterId, username string) ([]string, error) {
+	items, err := listObjects(clusterId, "oapi/v1/groups")
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []string{}
+	for _, group := range items {
+		users, _ := group.S("users").Children()
+		for _, u := range users {
+			if user, ok := u.Data().(string); ok && strings.EqualFold(user, username) {
+				if name, ok := group.Path("metadata.name").Data().(string); ok {
+					groups = append(groups, name)
+				}
+				break
+			}
+		}
+	}
+	return groups, nil
+}
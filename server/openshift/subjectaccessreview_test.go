@@ -0,0 +1,62 @@
+package openshift
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/gabs"
+)
+
+func TestCheckAdminPermissions(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+
+	api.AddProject("team-project", "u123")
+	binding, _ := api.Get("oapi/v1/namespaces/team-project/rolebindings/admin")
+	binding.Set([]interface{}{"team"}, "groupNames")
+	api.Set("oapi/v1/namespaces/team-project/rolebindings/admin", binding)
+
+	// u456 has access only as member of the group team
+	team := gabs.New()
+	team.Set("team", "metadata", "name")
+	team.Set([]interface{}{"u456"}, "users")
+	api.Set("oapi/v1/groups/team", team)
+	if err := checkAdminPermissions("fake", "U456", "team-project"); err != nil {
+		t.Errorf("expected the member of the group to be admin, got %v", err)
+	}
+
+	// Operators are admins of the projects with the operator group
+	api.AddProject("operated", "u999")
+	binding, _ = api.Get("oapi/v1/namespaces/operated/rolebindings/admin")
+	binding.Set([]interface{}{"operator"}, "groupNames")
+	api.Set("oapi/v1/namespaces/operated/rolebindings/admin", binding)
+	operator := gabs.New()
+	operator.Set("operator", "metadata", "name")
+	operator.Set([]interface{}{"u321"}, "users")
+	api.Set("oapi/v1/groups/operator", operator)
+	if err := checkAdminPermissions("fake", "U321", "operated"); err != nil {
+		t.Errorf("expected the operator to be admin, got %v", err)
+	}
+	if err := checkAdminPermissions("fake", "u321", "team-project"); err == nil {
+		t.Error("expected the operator not to be admin of projects without the operator group")
+	}
+
+	clusterAdmins := gabs.New()
+	clusterAdmins.Set("cluster-admin", "roleRef", "name")
+	clusterAdmins.Set([]interface{}{"u789"}, "userNames")
+	api.Set("oapi/v1/clusterrolebindings/cluster-admins", clusterAdmins)
+
+	for _, user := range []string{"u123", "U123", "u456", "u789", "sec_api"} {
+		if err := checkAdminPermissions("fake", user, "team-project"); err != nil {
+			t.Errorf("expected %v to be admin, got %v", user, err)
+		}
+	}
+
+	err := checkAdminPermissions("fake", "u000", "team-project")
+	if err == nil || !strings.Contains(err.Error(), "u123") {
+		t.Errorf("expected an error with the admins, got %v", err)
+	}
+	if err := checkAdminPermissions("fake", "u123", "missing"); err == nil {
+		t.Error("expected an error for a missing project")
+	}
+}
@@ -0,0 +1,172 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// memberRoles are the roles project admins can grant
+var memberRoles = []string{"admin", "edit", "view"}
+
+// TeamProject is a project of a team
+type TeamProject struct {
+	ClusterId string
+	Project   string
+}
+
+// teamMembersHandler adds or removes the user or group in all projects of the
+// team. Projects the caller isn't admin of are reported as failed
+func teamMembersHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.TeamMembersCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateTeamMembers(data); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	clusters := getOpenshiftClusters("")
+	if data.ClusterId != "" {
+		cluster, err := getOpenshiftCluster(data.ClusterId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		clusters = []OpenshiftCluster{cluster}
+	}
+	projects, failed, err := getTeamProjects(clusters, data.Label, data.Billing)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if len(projects) == 0 {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Es wurden keine Projekte des Teams gefunden"})
+		return
+	}
+
+	subject := data.User
+	if subject == "" {
+		subject = data.Group
+	}
+	dryRun := common.IsDryRun(c)
+	results := make([]common.TeamMemberResult, len(projects))
+	common.ForEachParallel(len(projects), func(i int) error {
+		p := projects[i]
+		results[i] = common.TeamMemberResult{ClusterId: p.ClusterId, Project: p.Project}
+		err := checkAdminPermissions(p.ClusterId, username, p.Project)
+		if err == nil && !dryRun {
+			err = changeTeamMember(p, data)
+		}
+		if err != nil {
+			results[i].Message = err.Error()
+			return err
+		}
+		results[i].Success = true
+		return nil
+	})
+
+	if dryRun {
+		changes := []common.PlannedChange{}
+		for _, r := range results {
+			if !r.Success {
+				continue
+			}
+			details := fmt.Sprintf("%v erhält die Rolle %v", subject, data.Role)
+			if data.Remove {
+				details = fmt.Sprintf("%v verliert die Rolle %v", subject, data.Role)
+			}
+			changes = append(changes, common.PlannedChange{
+				Action: common.DryRunActionUpdate, Kind: "RoleBinding", Name: data.Role, ClusterId: r.ClusterId, Project: r.Project, Details: details,
+			})
+		}
+		common.RespondDryRun(c, fmt.Sprintf("Die Rolle von %v würde in %v Projekten geändert", subject, len(changes)), changes...)
+		return
+	}
+
+	succeeded := 0
+	for _, r := range results {
+		if r.Success {
+			succeeded++
+		}
+	}
+	action := "added to"
+	if data.Remove {
+		action = "removed from"
+	}
+	team := data.Label
+	if team == "" {
+		team = data.Billing
+	}
+	common.Logger(c).Printf("%v %v %v the role %v in %v of %v projects of team %v", username, action, subject, data.Role, succeeded, len(results), team)
+	c.JSON(http.StatusOK, common.TeamMembersResponse{
+		Message: fmt.Sprintf("Die Rolle von %v wurde in %v von %v Projekten geändert", subject, succeeded, len(results)),
+		Results: results,
+		Errors:  failed,
+	})
+}
+
+func validateTeamMembers(data common.TeamMembersCommand) error {
+	if (data.Label == "") == (data.Billing == "") {
+		return errors.New("Das Team muss entweder mit einem Label oder einer Kontierungsnummer angegeben werden")
+	}
+	if (data.User == "") == (data.Group == "") {
+		return errors.New("Es muss entweder ein Benutzer oder eine Gruppe angegeben werden")
+	}
+	if !contains(memberRoles, data.Role) {
+		return fmt.Errorf("Die Rolle muss eine von %v sein", strings.Join(memberRoles, ", "))
+	}
+	return nil
+}
+
+func changeTeamMember(p TeamProject, data common.TeamMembersCommand) error {
+	switch {
+	case data.User != "" && data.Remove:
+		return removeUsersFromRoleBinding(p.ClusterId, p.Project, data.Role, []string{data.User})
+	case data.User != "":
+		return addUsersToRoleBinding(p.ClusterId, p.Project, data.Role, []string{data.User})
+	case data.Remove:
+		return removeGroupsFromRoleBinding(p.ClusterId, p.Project, data.Role, []string{data.Group})
+	default:
+		return addGroupsToRoleBinding(p.ClusterId, p.Project, data.Role, []string{data.Group})
+	}
+}
+
+// getTeamProjects returns the projects of the clusters which have the label
+// (key=value or only key) or the billing
+func getTeamProjects(clusters []OpenshiftCluster, label, billing string) ([]TeamProject, []string, error) {
+	namespaces, failed, err := getNamespacesOfClusters(clusters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	projects := []TeamProject{}
+	for i, cluster := range clusters {
+		for _, n := range namespaces[i] {
+			if billing != "" && getAnnotation(n.Path("metadata.annotations"), annotationBilling) != billing {
+				continue
+			}
+			if label != "" && !hasLabel(n, label) {
+				continue
+			}
+			project, _ := n.Path("metadata.name").Data().(string)
+			projects = append(projects, TeamProject{ClusterId: cluster.ID, Project: project})
+		}
+	}
+	return projects, failed, nil
+}
+
+func hasLabel(object *gabs.Container, label string) bool {
+	parts := strings.SplitN(label, "=", 2)
+	value, ok := object.Path("metadata.labels").S(parts[0]).Data().(string)
+	return ok && (len(parts) == 1 || value == parts[1])
+}
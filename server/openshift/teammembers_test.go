@@ -0,0 +1,79 @@
+package openshift
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTeamMembersHandler(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	for project, requester := range map[string]string{"team-a": "u123", "team-b": "u123", "foreign": "u456", "other": "u123"} {
+		api.AddProject(project, requester)
+		labels := map[string]interface{}{"team": "t1"}
+		if project == "other" {
+			labels = map[string]interface{}{"team": "t2"}
+		}
+		namespace, _ := api.Get("api/v1/namespaces/" + project)
+		namespace.SetP(labels, "metadata.labels")
+		api.Set("api/v1/namespaces/"+project, namespace)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/ose/team/members", strings.NewReader(`{"label": "team=t1", "group": "Team-T1", "role": "edit"}`))
+	c.Set(gin.AuthUserKey, "u123")
+	teamMembersHandler(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v %v", w.Code, w.Body.String())
+	}
+
+	var response common.TeamMembersResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Results) != 3 {
+		t.Fatalf("expected results for the 3 projects of team t1, got %+v", response.Results)
+	}
+	for _, r := range response.Results {
+		if r.Success == (r.Project == "foreign") {
+			t.Errorf("unexpected result %+v", r)
+		}
+	}
+	roleBinding, _ := api.Get("oapi/v1/namespaces/team-a/rolebindings/edit")
+	if groups, _ := roleBinding.S("groupNames").Children(); len(groups) != 1 || groups[0].Data() != "Team-T1" {
+		t.Errorf("expected group Team-T1 in the edit rolebinding of team-a, got %v", roleBinding)
+	}
+
+	if err := removeGroupsFromRoleBinding("fake", "team-a", "edit", []string{"Team-T1"}); err != nil {
+		t.Fatal(err)
+	}
+	roleBinding, _ = api.Get("oapi/v1/namespaces/team-a/rolebindings/edit")
+	if groups, _ := roleBinding.S("groupNames").Children(); len(groups) != 0 {
+		t.Errorf("expected the group to be removed, got %v", roleBinding)
+	}
+}
+
+func TestValidateTeamMembers(t *testing.T) {
+	tests := []struct {
+		data  common.TeamMembersCommand
+		valid bool
+	}{
+		{common.TeamMembersCommand{Billing: "123", User: "u1", Role: "admin"}, true},
+		{common.TeamMembersCommand{User: "u1", Role: "admin"}, false},
+		{common.TeamMembersCommand{Label: "team", Billing: "123", User: "u1", Role: "admin"}, false},
+		{common.TeamMembersCommand{Label: "team", User: "u1", Group: "g", Role: "admin"}, false},
+		{common.TeamMembersCommand{Label: "team", User: "u1", Role: "cluster-admin"}, false},
+	}
+	for _, test := range tests {
+		if err := validateTeamMembers(test.data); (err == nil) != test.valid {
+			t.Errorf("%+v: expected valid %v, got %v", test.data, test.valid, err)
+		}
+	}
+}
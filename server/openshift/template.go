@@ -0,0 +1,173 @@
+package openshift
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/gabs"
+	log "github.com/sirupsen/logrus"
+)
+
+// objectEndpoints maps the kinds which can be created from templates to
+// their api path below the namespace
+var objectEndpoints = map[string]string{
+	"BuildConfig":           "oapi/v1/namespaces/%v/buildconfigs",
+	"DeploymentConfig":      "oapi/v1/namespaces/%v/deploymentconfigs",
+	"ImageStream":           "oapi/v1/namespaces/%v/imagestreams",
+	"Route":                 "oapi/v1/namespaces/%v/routes",
+	"ConfigMap":             "api/v1/namespaces/%v/configmaps",
+	"PersistentVolumeClaim": "api/v1/namespaces/%v/persistentvolumeclaims",
+	"Secret":                "api/v1/namespaces/%v/secrets",
+	"Service":               "api/v1/namespaces/%v/services",
+	"ServiceAccount":        "api/v1/namespaces/%v/serviceaccounts",
+}
+
+func getTemplate(clusterId, namespace, name string) (*gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, fmt.Sprintf("oapi/v1/namespaces/%v/templates/%v", namespace, name), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("Das Template %v existiert nicht", name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error getting template %v/%v: %v %v", namespace, name, resp.StatusCode, string(errMsg))
+		return nil, errors.New(genericAPIError)
+	}
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return nil, errors.New(genericAPIError)
+	}
+	return json, nil
+}
+
+// instantiateTemplate processes the template with the parameters and creates
+// all the resulting objects in the project
+func instantiateTemplate(clusterId, templateNamespace, templateName, project string, parameters map[string]string) error {
+	template, err := getTemplate(clusterId, templateNamespace, templateName)
+	if err != nil {
+		return err
+	}
+
+	// Set the values of the parameters the user specified
+	templateParameters, _ := template.S("parameters").Children()
+	for _, p := range templateParameters {
+		name, _ := p.S("name").Data().(string)
+		if value, ok := parameters[name]; ok {
+			p.Set(value, "value")
+		}
+	}
+	template.Delete("metadata", "namespace")
+	template.Delete("metadata", "resourceVersion")
+
+	resp, err := getOseHTTPClient("POST", clusterId, fmt.Sprintf("oapi/v1/namespaces/%v/processedtemplates", project), bytes.NewReader(template.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error processing template %v/%v: %v %v", templateNamespace, templateName, resp.StatusCode, string(errMsg))
+		return fmt.Errorf("Das Template %v konnte nicht verarbeitet werden", templateName)
+	}
+
+	processed, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return errors.New(genericAPIError)
+	}
+
+	objects, _ := processed.S("objects").Children()
+	for _, o := range objects {
+		if err := createObject(clusterId, project, o); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Instantiated template %v/%v in project %v on cluster %v", templateNamespace, templateName, project, clusterId)
+	return nil
+}
+
+func createObject(clusterId, project string, object *gabs.Container) error {
+	kind, _ := object.S("kind").Data().(string)
+	name, _ := object.Path("metadata.name").Data().(string)
+	endpoint, ok := objectEndpoints[kind]
+	if !ok {
+		log.Printf("WARNING: objects of kind %v can't be created from templates", kind)
+		return fmt.Errorf("Objekte vom Typ %v werden nicht unterstützt", kind)
+	}
+
+	if kind == "Route" {
+		if err := checkRouteClassification(clusterId, project, object); err != nil {
+			return err
+		}
+	}
+	if err := checkResourceName(clusterId, project, kind, name); err != nil {
+		return err
+	}
+
+	// The apiVersion of the object must match the api group of the endpoint
+	if strings.HasPrefix(endpoint, "oapi") {
+		object.Set("v1", "apiVersion")
+	}
+
+	resp, err := getOseHTTPClient("POST", clusterId, fmt.Sprintf(endpoint, project), bytes.NewReader(object.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		log.Printf("%v %v already existed in project %v, skipping", kind, name, project)
+		return nil
+	}
+	if resp.StatusCode != http.StatusCreated {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error creating %v %v: %v %v", kind, name, resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
+
+// createOrReplaceObject creates the object in the project or replaces the
+// existing object with the same name
+func createOrReplaceObject(clusterId, project string, object *gabs.Container) error {
+	kind, _ := object.S("kind").Data().(string)
+	name, _ := object.Path("metadata.name").Data().(string)
+	endpoint, ok := objectEndpoints[kind]
+	if !ok {
+		return fmt.Errorf("Objekte vom Typ %v werden nicht unterstützt", kind)
+	}
+
+	url := fmt.Sprintf(endpoint, project)
+	resp, err := getOseHTTPClient("POST", clusterId, url, bytes.NewReader(object.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		resp, err = getOseHTTPClient("PUT", clusterId, url+"/"+name, bytes.NewReader(object.Bytes()))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+	}
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error saving %v %v: %v %v", kind, name, resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package openshift
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	testProjectExpiresAnnotation = "openshift.io/expires"
+	testProjectWarnedAnnotation  = "openshift.io/expiry-warned"
+	defaultTestProjectDays       = 30
+	defaultTestProjectWarnDays   = 3
+	testProjectJanitorLease      = "test-project-janitor"
+)
+
+func testProjectConfig() (days, warnDays int) {
+	cfg := config.Config()
+	days = cfg.GetInt("test_projects.days")
+	if days <= 0 {
+		days = defaultTestProjectDays
+	}
+	warnDays = cfg.GetInt("test_projects.warn_days")
+	if warnDays <= 0 {
+		warnDays = defaultTestProjectWarnDays
+	}
+	return days, warnDays
+}
+
+// setTestProjectExpiry marks a new test project to be deleted after
+// 'test_projects.days'
+func setTestProjectExpiry(annotations *gabs.Container, now time.Time) {
+	days, _ := testProjectConfig()
+	expires := now.AddDate(0, 0, days)
+	annotations.Set(strconv.Itoa(days), testProjectDeletionAnnotation)
+	annotations.Set(expires.Format(time.RFC3339), testProjectExpiresAnnotation)
+	annotations.Set(fmt.Sprintf("Dieses Testprojekt wird am %v automatisch gelöscht!", expires.Format("02.01.2006")), "openshift.io/description")
+}
+
+// StartTestProjectJanitor warns the requesters of expiring test projects and
+// deletes the expired ones every hour on workdays. Only the instance holding
+// the lease cleans up, so the warnings aren't sent once per instance
+func StartTestProjectJanitor() {
+	go func() {
+		for {
+			leader, err := store.TryLease(testProjectJanitorLease, common.InstanceID(), 2*time.Hour)
+			if err != nil {
+				log.Printf("Error acquiring the lease of the test project janitor: %v", err)
+			}
+			if at := common.Now(); leader && common.IsWorkday(at) {
+				cleanupTestProjects(at)
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+func cleanupTestProjects(now time.Time) {
+	_, warnDays := testProjectConfig()
+
+	for _, cluster := range getOpenshiftClusters("") {
+		namespaces, err := getAllNamespaces(cluster.ID)
+		if err != nil {
+			log.Printf("Error getting namespaces of cluster %v: %v", cluster.ID, err)
+			continue
+		}
+
+		for _, n := range namespaces {
+			annotations := n.Path("metadata.annotations")
+			expires, ok := annotations.S(testProjectExpiresAnnotation).Data().(string)
+			if !ok || annotations.Exists(legalHoldAnnotation) || inRecycleBin(annotations) {
+				continue
+			}
+			expiresAt, err := time.Parse(time.RFC3339, expires)
+			if err != nil {
+				continue
+			}
+			project, _ := n.Path("metadata.name").Data().(string)
+
+			if !expiresAt.After(now) {
+				if err := deleteProject(cluster.ID, project); err != nil {
+					log.Printf("Error deleting expired test project %v on cluster %v: %v", project, cluster.ID, err)
+					continue
+				}
+				log.Printf("Deleted expired test project %v on cluster %v", project, cluster.ID)
+				continue
+			}
+
+			if annotations.Exists(testProjectWarnedAnnotation) || expiresAt.After(now.AddDate(0, 0, warnDays)) {
+				continue
+			}
+			requester := getAnnotation(annotations, annotationRequester)
+			if err := sendTestProjectExpiryMail(cluster.ID, project, requester, expiresAt); err != nil {
+				log.Printf("Can't send e-mail about expiring test project %v on cluster %v: %v", project, cluster.ID, err)
+				continue
+			}
+			err = updateNamespaceAnnotations(cluster.ID, project, func(annotations *gabs.Container) {
+				annotations.Set(now.Format(time.RFC3339), testProjectWarnedAnnotation)
+			})
+			if err != nil {
+				log.Printf("Error marking test project %v on cluster %v as warned: %v", project, cluster.ID, err)
+			}
+		}
+	}
+}
+
+func sendTestProjectExpiryMail(clusterId, project, requester string, expires time.Time) error {
+	return common.SendNotification(common.Notification{
+		Event: common.NotificationTestProjectExpires, Username: requester, ClusterId: clusterId, Project: project,
+		Details: map[string]string{"expires": expires.In(common.Location()).Format("02.01.2006")},
+	})
+}
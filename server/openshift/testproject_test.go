@@ -0,0 +1,37 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/gabs"
+)
+
+func TestCleanupTestProjects(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+
+	for _, project := range []string{"expired", "held"} {
+		if err := createNewProject(nil, "fake", project, "u123", "", "", true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	namespace, _ := api.Get("api/v1/namespaces/expired")
+	if _, ok := namespace.Path("metadata.annotations").S(testProjectExpiresAnnotation).Data().(string); !ok {
+		t.Fatalf("expected the test project to have an expiry, got %v", namespace)
+	}
+	err := updateNamespaceAnnotations("fake", "held", func(annotations *gabs.Container) {
+		annotations.Set("investigation", legalHoldAnnotation)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleanupTestProjects(time.Now().AddDate(0, 0, defaultTestProjectDays+1))
+	if _, ok := api.Get("api/v1/namespaces/expired"); ok {
+		t.Error("expected the expired test project to be deleted")
+	}
+	if _, ok := api.Get("api/v1/namespaces/held"); !ok {
+		t.Error("expected the test project under legal hold to be kept")
+	}
+}
@@ -0,0 +1,252 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/approval"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// Destructive admin actions have to be confirmed by a second portal admin
+// within 'two_person_rule.window_minutes' (see /admin/approvals)
+const (
+	projectDeletionKind      = "project-deletion"
+	offboardingKind          = "offboarding"
+	defaultTwoPersonWindow   = 60
+	twoPersonPendingResponse = "Die Aktion muss innerhalb von %v Minuten von einem zweiten Administrator bestätigt werden"
+)
+
+type twoPersonResponse struct {
+	Message string            `json:"message"`
+	Request *approval.Request `json:"request"`
+}
+
+type offboardingPayload struct {
+	User string `json:"user"`
+}
+
+func twoPersonWindow() int {
+	minutes := config.Config().GetInt("two_person_rule.window_minutes")
+	if minutes <= 0 {
+		return defaultTwoPersonWindow
+	}
+	return minutes
+}
+
+func registerTwoPersonApprovals() {
+	window := time.Duration(twoPersonWindow()) * time.Minute
+
+	approval.RegisterKind(approval.Kind{
+		Name: projectDeletionKind,
+		Apply: func(r approval.Request) error {
+			return deleteProject(r.ClusterId, r.Project)
+		},
+		Notify:           notifyTwoPersonRequest,
+		SLA:              window,
+		SeparateApprover: true,
+		AdminsOnly:       true,
+	})
+	approval.RegisterKind(approval.Kind{
+		Name: offboardingKind,
+		Apply: func(r approval.Request) error {
+			var payload offboardingPayload
+			if err := json.Unmarshal(r.Payload, &payload); err != nil {
+				return err
+			}
+			return offboardUser(payload.User)
+		},
+		Notify:           notifyTwoPersonRequest,
+		SLA:              window,
+		SeparateApprover: true,
+		AdminsOnly:       true,
+	})
+}
+
+// adminDeleteProjectHandler deletes a project as portal admin. The deletion of
+// projects of other teams has to be confirmed by a second admin
+func adminDeleteProjectHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.AdminProjectDeletionCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if data.ClusterId == "" || data.Project == "" || data.Reason == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Cluster, Projekt und Grund müssen angegeben werden"})
+		return
+	}
+
+	admins, _, err := getProjectAdminsAndOperators(data.ClusterId, data.Project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	ownProject := contains(admins, strings.ToLower(username))
+
+	if common.IsDryRun(c) {
+		if err := checkLegalHold(data.ClusterId, data.Project, "dry-run deletion"); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		change := common.PlannedChange{Action: common.DryRunActionDelete, Kind: "Project", Name: data.Project, ClusterId: data.ClusterId, Project: data.Project}
+		if !ownProject {
+			change.Action = common.DryRunActionApproval
+			change.Details = fmt.Sprintf(twoPersonPendingResponse, twoPersonWindow())
+		}
+		impact, err := getProjectImpact(data.ClusterId, data.Project)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		common.RespondDryRun(c, fmt.Sprintf("Das Projekt %v würde gelöscht", data.Project), append([]common.PlannedChange{change}, impactChanges(data.ClusterId, data.Project, impact)...)...)
+		return
+	}
+
+	if ownProject {
+		if err := deleteProject(data.ClusterId, data.Project); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		audit.Describe(c, "%v deleted the project %v on cluster %v: %v", username, data.Project, data.ClusterId, data.Reason)
+		c.JSON(http.StatusOK, common.ApiResponse{Message: fmt.Sprintf("Das Projekt %v wurde gelöscht", data.Project)})
+		return
+	}
+
+	request, err := approval.Create(projectDeletionKind, data.ClusterId, data.Project, username, data.Reason, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, twoPersonResponse{Message: fmt.Sprintf(twoPersonPendingResponse, twoPersonWindow()), Request: request})
+}
+
+// offboardingHandler removes a user from all projects on all clusters after
+// the confirmation of a second admin
+func offboardingHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.OffboardingCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if data.User == "" || data.Reason == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Benutzer und Grund müssen angegeben werden"})
+		return
+	}
+
+	request, err := approval.Create(offboardingKind, "", "", username, data.Reason, offboardingPayload{User: strings.ToLower(data.User)})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, twoPersonResponse{Message: fmt.Sprintf(twoPersonPendingResponse, twoPersonWindow()), Request: request})
+}
+
+// offboardUser removes the user from all rolebindings on all clusters
+func offboardUser(user string) error {
+	clusters := getOpenshiftClusters("")
+	var mutex sync.Mutex
+	failed := []string{}
+	common.ForEachParallel(len(clusters), func(i int) error {
+		clusterFailed := offboardUserFromCluster(clusters[i].ID, user)
+		mutex.Lock()
+		failed = append(failed, clusterFailed...)
+		mutex.Unlock()
+		return nil
+	})
+
+	if len(failed) > 0 {
+		log.Printf("Offboarding of %v failed for %v", user, strings.Join(failed, ", "))
+		return errors.New("Der Benutzer konnte nicht aus allen Projekten entfernt werden: " + strings.Join(failed, ", "))
+	}
+	log.Printf("Offboarded %v from all clusters", user)
+	return nil
+}
+
+// offboardUserFromCluster removes the user from all rolebindings of the
+// cluster concurrently and returns the projects where this failed
+func offboardUserFromCluster(clusterId, user string) []string {
+	roleBindings, err := listObjects(clusterId, "oapi/v1/rolebindings")
+	if err != nil {
+		return []string{clusterId}
+	}
+
+	affected := []*gabs.Container{}
+	for _, rb := range roleBindings {
+		names, _ := rb.S("userNames").Children()
+		for _, n := range names {
+			if name, ok := n.Data().(string); ok && strings.ToLower(name) == strings.ToLower(user) {
+				affected = append(affected, rb)
+				break
+			}
+		}
+	}
+
+	failed := []string{}
+	errs := common.ForEachParallel(len(affected), func(i int) error {
+		project, _ := affected[i].Path("metadata.namespace").Data().(string)
+		role, _ := affected[i].Path("metadata.name").Data().(string)
+		return removeUsersFromRoleBinding(clusterId, project, role, []string{user})
+	})
+	for i, err := range errs {
+		if err != nil {
+			project, _ := affected[i].Path("metadata.namespace").Data().(string)
+			failed = append(failed, clusterId+"/"+project)
+		}
+	}
+	return failed
+}
+
+// notifyTwoPersonRequest asks the other portal admins to confirm new requests
+func notifyTwoPersonRequest(r approval.Request) {
+	if r.State != approval.StatePending {
+		return
+	}
+
+	recipients := []string{}
+	for _, admin := range config.Config().GetStringSlice("portal_admins") {
+		admin = strings.TrimSpace(admin)
+		if strings.ToLower(admin) == strings.ToLower(r.RequestedBy) {
+			continue
+		}
+		if mail := common.GetMailForUser(admin); mail != "" {
+			recipients = append(recipients, mail)
+		}
+	}
+
+	target := fmt.Sprintf("Projekt %v auf Cluster %v", r.Project, r.ClusterId)
+	if r.Kind == offboardingKind {
+		var payload offboardingPayload
+		json.Unmarshal(r.Payload, &payload)
+		target = "Benutzer " + payload.User
+	}
+	err := common.SendMail(recipients, fmt.Sprintf("Bestätigung benötigt: %v", r.Kind), fmt.Sprintf(`
+	Hallo,
+	<br><br>
+	%v hat die Aktion %v für %v beantragt:
+	<br><br>
+	%v
+	<br><br>
+	Die Aktion wird erst ausgeführt, wenn ein zweiter Administrator sie bis %v im Cloud Self Service Portal bestätigt (Antrag %v).
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, r.RequestedBy, r.Kind, target, r.Reason, r.Deadline.Format("02.01.2006 15:04"), r.ID))
+	if err != nil {
+		log.Printf("Can't send e-mail about %v request %v: %v", r.Kind, r.ID, err)
+	}
+}
@@ -0,0 +1,186 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// upgradeImpactHandler notifies the admins of the projects affected by a
+// maintenance of the cluster. With ?dryRun=true only the projects are listed
+func upgradeImpactHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.UpgradeImpactCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateUpgradeImpact(&data); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	projects, err := findUpgradeImpact(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if common.IsDryRun(c) {
+		changes := []common.PlannedChange{}
+		for _, p := range projects {
+			changes = append(changes, common.PlannedChange{
+				Action: common.DryRunActionCreate, Kind: "Notification", Name: data.Subject, ClusterId: p.ClusterId, Project: p.Project,
+				Details: strings.Join(p.Reasons, ", "),
+			})
+		}
+		common.RespondDryRun(c, fmt.Sprintf("Die Admins von %v Projekten würden benachrichtigt", len(projects)), changes...)
+		return
+	}
+
+	notified := 0
+	common.ForEachParallel(len(projects), func(i int) error {
+		if err := sendUpgradeImpactMail(data, projects[i]); err != nil {
+			common.Logger(c).Printf("Error notifying the admins of project %v on cluster %v about the upgrade: %v", projects[i].Project, data.ClusterId, err)
+			projects[i].Message = err.Error()
+			return err
+		}
+		projects[i].Notified = true
+		return nil
+	})
+	for _, p := range projects {
+		if p.Notified {
+			notified++
+		}
+	}
+	audit.Describe(c, "%v notified the admins of %v of %v projects on cluster %v about '%v'", username, notified, len(projects), data.ClusterId, data.Subject)
+	c.JSON(http.StatusOK, common.UpgradeImpactResponse{
+		Message:  fmt.Sprintf("Die Admins von %v von %v Projekten wurden benachrichtigt", notified, len(projects)),
+		Projects: projects,
+	})
+}
+
+func validateUpgradeImpact(data *common.UpgradeImpactCommand) error {
+	if _, err := getOpenshiftCluster(data.ClusterId); err != nil {
+		return err
+	}
+	data.NodeSelector = strings.TrimSpace(data.NodeSelector)
+	if data.NodeSelector == "" && len(data.StorageClasses) == 0 && len(data.APIs) == 0 {
+		return errors.New("Es muss ein Node-Selector, eine Storage-Klasse oder eine API angegeben werden")
+	}
+	for i, api := range data.APIs {
+		data.APIs[i] = strings.Trim(api, "/ ")
+		if !strings.HasPrefix(data.APIs[i], "api/") && !strings.HasPrefix(data.APIs[i], "apis/") {
+			return fmt.Errorf("Die API %v muss mit api/ oder apis/ beginnen, z.B. apis/extensions/v1beta1/deployments", api)
+		}
+	}
+	if data.Subject == "" || data.Actions == "" {
+		return errors.New("Betreff und die nötigen Massnahmen müssen angegeben werden")
+	}
+	return nil
+}
+
+// findUpgradeImpact returns the affected projects with the reasons, sorted
+// by name
+func findUpgradeImpact(data common.UpgradeImpactCommand) ([]common.UpgradeImpactProject, error) {
+	reasons := make(map[string][]string)
+	add := func(project, reason string) {
+		if project != "" && !contains(reasons[project], reason) {
+			reasons[project] = append(reasons[project], reason)
+		}
+	}
+
+	if data.NodeSelector != "" {
+		nodes, err := listObjects(data.ClusterId, "api/v1/nodes?labelSelector="+url.QueryEscape(data.NodeSelector))
+		if err != nil {
+			return nil, err
+		}
+		names := make(map[string]bool)
+		for _, n := range nodes {
+			if name, ok := n.Path("metadata.name").Data().(string); ok {
+				names[name] = true
+			}
+		}
+		pods, err := listObjects(data.ClusterId, "api/v1/pods")
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pods {
+			if node, _ := p.Path("spec.nodeName").Data().(string); names[node] {
+				project, _ := p.Path("metadata.namespace").Data().(string)
+				add(project, "Pods auf Nodes "+data.NodeSelector)
+			}
+		}
+	}
+
+	if len(data.StorageClasses) > 0 {
+		pvcs, err := listObjects(data.ClusterId, "api/v1/persistentvolumeclaims")
+		if err != nil {
+			return nil, err
+		}
+		for _, pvc := range pvcs {
+			if class, _ := pvc.Path("spec.storageClassName").Data().(string); contains(data.StorageClasses, class) {
+				project, _ := pvc.Path("metadata.namespace").Data().(string)
+				add(project, "Volumes mit Storage-Klasse "+class)
+			}
+		}
+	}
+
+	for _, api := range data.APIs {
+		objects, err := listObjects(data.ClusterId, api)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objects {
+			project, _ := o.Path("metadata.namespace").Data().(string)
+			add(project, "Objekte von "+api)
+		}
+	}
+
+	projects := []common.UpgradeImpactProject{}
+	for project, r := range reasons {
+		projects = append(projects, common.UpgradeImpactProject{ClusterId: data.ClusterId, Project: project, Reasons: r})
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].Project < projects[j].Project
+	})
+	return projects, nil
+}
+
+func sendUpgradeImpactMail(data common.UpgradeImpactCommand, project common.UpgradeImpactProject) error {
+	admins, operators, err := getProjectAdminsAndOperators(project.ClusterId, project.Project)
+	if err != nil {
+		return err
+	}
+	recipients := []string{}
+	for _, user := range append(admins, operators...) {
+		if mail := common.GetMailForUser(user); mail != "" {
+			recipients = append(recipients, mail)
+		}
+	}
+	if len(recipients) == 0 {
+		return errors.New("Für das Projekt wurden keine Admins mit E-Mail gefunden")
+	}
+
+	return common.SendMail(common.RemoveDuplicates(recipients), fmt.Sprintf("%v: Projekt %v", data.Subject, project.Project), fmt.Sprintf(`
+	Sehr geehrte Damen und Herren,
+	<br><br>
+	Euer Projekt %v auf Cluster %v ist von Wartungsarbeiten betroffen:
+	<br><br>
+	%v
+	<br><br>
+	Betroffen sind: %v
+	<br><br>
+	Mit freundlichen Grüssen<br>
+	Euer Cloud Platforms Team<br>
+	IT-OM-SDL-CLP
+	`, project.Project, project.ClusterId, data.Actions, strings.Join(project.Reasons, ", ")))
+}
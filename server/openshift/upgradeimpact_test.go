@@ -0,0 +1,55 @@
+package openshift
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+func TestFindUpgradeImpact(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	object := func(path string, fields map[string]interface{}) {
+		o := gabs.New()
+		for k, v := range fields {
+			o.SetP(v, k)
+		}
+		api.Set(path, o)
+	}
+	object("api/v1/nodes/node1", map[string]interface{}{"metadata.name": "node1", "metadata.labels.pool": "gpu"})
+	object("api/v1/nodes/node2", map[string]interface{}{"metadata.name": "node2", "metadata.labels.pool": "default"})
+	object("api/v1/namespaces/shop/pods/web", map[string]interface{}{"metadata.namespace": "shop", "spec.nodeName": "node1"})
+	object("api/v1/namespaces/other/pods/web", map[string]interface{}{"metadata.namespace": "other", "spec.nodeName": "node2"})
+	object("api/v1/namespaces/other/persistentvolumeclaims/data", map[string]interface{}{"metadata.namespace": "other", "spec.storageClassName": "gluster"})
+	object("apis/extensions/v1beta1/namespaces/shop/deployments/web", map[string]interface{}{"metadata.namespace": "shop"})
+
+	data := common.UpgradeImpactCommand{
+		ClusterId:      "fake",
+		NodeSelector:   "pool=gpu",
+		StorageClasses: []string{"gluster"},
+		APIs:           []string{"/apis/extensions/v1beta1/deployments"},
+		Subject:        "Upgrade OpenShift 3.11",
+		Actions:        "Bitte die Deployments auf apps/v1 umstellen",
+	}
+	if err := validateUpgradeImpact(&data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	projects, err := findUpgradeImpact(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []common.UpgradeImpactProject{
+		{ClusterId: "fake", Project: "other", Reasons: []string{"Volumes mit Storage-Klasse gluster"}},
+		{ClusterId: "fake", Project: "shop", Reasons: []string{"Pods auf Nodes pool=gpu", "Objekte von apis/extensions/v1beta1/deployments"}},
+	}
+	if !reflect.DeepEqual(projects, expected) {
+		t.Errorf("expected %+v, got %+v", expected, projects)
+	}
+
+	data.APIs = []string{"extensions/v1beta1/deployments"}
+	if err := validateUpgradeImpact(&data); err == nil {
+		t.Error("expected an api without api/ or apis/ to be rejected")
+	}
+}
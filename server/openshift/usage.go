@@ -0,0 +1,193 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	usageTimelinesCollection = "usage_timelines"
+	usageDateFormat          = "2006-01-02"
+	defaultUsageRetention    = 400
+)
+
+// UsagePoint is the usage of a project on a day. CPU is in cores, memory and
+// storage in Gi
+type UsagePoint struct {
+	Date       string  `json:"date"`
+	CPU        float64 `json:"cpu"`
+	Memory     float64 `json:"memory"`
+	UsedCPU    float64 `json:"usedCpu"`
+	UsedMemory float64 `json:"usedMemory"`
+	Storage    float64 `json:"storage"`
+}
+
+// UsageTimeline are the daily usages of a project, the oldest first
+type UsageTimeline struct {
+	ClusterId string       `json:"clusterid"`
+	Project   string       `json:"project"`
+	Points    []UsagePoint `json:"points"`
+}
+
+func usageTimelineID(clusterId, project string) string {
+	return clusterId + "/" + project
+}
+
+// usageRetention is the number of days kept in 'usage_timeline.days'
+func usageRetention() int {
+	if days := config.Config().GetInt("usage_timeline.days"); days > 0 {
+		return days
+	}
+	return defaultUsageRetention
+}
+
+// StartUsageSnapshots records the usage of all projects once a day if
+// 'usage_timeline.enabled' is set
+func StartUsageSnapshots() {
+	if !config.Config().GetBool("usage_timeline.enabled") {
+		return
+	}
+
+	go func() {
+		for {
+			if err := recordUsageSnapshots(common.Now()); err != nil {
+				log.Printf("Error recording the usage of the projects: %v", err)
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
+
+// recordUsageSnapshots adds the current quotas, their usage and the storage
+// of every project to its timeline
+func recordUsageSnapshots(now time.Time) error {
+	clusters := getOpenshiftClusters("")
+	namespaces, failed, err := getNamespacesOfClusters(clusters)
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		log.Printf("WARNING: the usage of the projects on %v isn't recorded", failed)
+	}
+
+	for i, cluster := range clusters {
+		projects := []string{}
+		for _, n := range namespaces[i] {
+			if project, ok := n.Path("metadata.name").Data().(string); ok {
+				projects = append(projects, project)
+			}
+		}
+		clusterId := cluster.ID
+		common.ForEachParallel(len(projects), func(j int) error {
+			point, err := getProjectUsage(clusterId, projects[j])
+			if err == nil {
+				point.Date = now.In(common.Location()).Format(usageDateFormat)
+				err = addUsagePoint(clusterId, projects[j], *point, now)
+			}
+			if err != nil {
+				log.Printf("Error recording the usage of project %v on cluster %v: %v", projects[j], clusterId, err)
+			}
+			return err
+		})
+	}
+	return nil
+}
+
+func getProjectUsage(clusterId, project string) (*UsagePoint, error) {
+	quotas, err := getQuotas(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+	point := &UsagePoint{
+		CPU:        quotas.CPU,
+		Memory:     quotas.Memory,
+		UsedCPU:    quotas.UsedCPU,
+		UsedMemory: quotas.UsedMemory,
+	}
+
+	pvcs, err := listObjects(clusterId, fmt.Sprintf("api/v1/namespaces/%v/persistentvolumeclaims", project))
+	if err != nil {
+		return nil, err
+	}
+	for _, pvc := range pvcs {
+		size := pvc.Path("status.capacity.storage").Data()
+		if size == nil {
+			size = pvc.Path("spec.resources.requests.storage").Data()
+		}
+		point.Storage += parseMemoryQuantityGi(size)
+	}
+	return point, nil
+}
+
+// addUsagePoint replaces the point of the same day and drops the points
+// older than the retention
+func addUsagePoint(clusterId, project string, point UsagePoint, now time.Time) error {
+	id := usageTimelineID(clusterId, project)
+	timeline := UsageTimeline{ClusterId: clusterId, Project: project}
+	if _, err := store.Get(usageTimelinesCollection, id, &timeline); err != nil {
+		return err
+	}
+
+	oldest := now.In(common.Location()).AddDate(0, 0, -usageRetention()).Format(usageDateFormat)
+	points := []UsagePoint{point}
+	for _, p := range timeline.Points {
+		if p.Date != point.Date && p.Date > oldest {
+			points = append(points, p)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Date < points[j].Date
+	})
+	timeline.Points = points
+	return store.Put(usageTimelinesCollection, id, timeline)
+}
+
+// getUsageTimeline returns the points between from and to (YYYY-MM-DD),
+// both are optional
+func getUsageTimeline(clusterId, project, from, to string) (*UsageTimeline, error) {
+	for _, d := range []string{from, to} {
+		if _, err := time.Parse(usageDateFormat, d); d != "" && err != nil {
+			return nil, errors.New("Das Datum muss im Format YYYY-MM-DD angegeben werden")
+		}
+	}
+
+	var timeline UsageTimeline
+	if _, err := store.Get(usageTimelinesCollection, usageTimelineID(clusterId, project), &timeline); err != nil {
+		return nil, err
+	}
+	result := &UsageTimeline{ClusterId: clusterId, Project: project, Points: []UsagePoint{}}
+	for _, p := range timeline.Points {
+		if (from == "" || p.Date >= from) && (to == "" || p.Date <= to) {
+			result.Points = append(result.Points, p)
+		}
+	}
+	return result, nil
+}
+
+// getUsageTimelineHandler returns the daily usage of the project between
+// ?from and ?to
+func getUsageTimelineHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	timeline, err := getUsageTimeline(clusterId, project, c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, timeline)
+}
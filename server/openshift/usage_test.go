@@ -0,0 +1,54 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestRecordUsageSnapshots(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	config.Config().Set("usage_timeline.days", 2)
+	if err := createNewProject(nil, "fake", "shop", "u123", "12345", "", false); err != nil {
+		t.Fatal(err)
+	}
+	quota := gabs.New()
+	quota.SetP("4", "spec.hard.cpu")
+	quota.SetP("8Gi", "spec.hard.memory")
+	quota.SetP("1500m", "status.used.cpu")
+	api.Set("api/v1/namespaces/shop/resourcequotas/compute", quota)
+	pvc := gabs.New()
+	pvc.SetP("10Gi", "status.capacity.storage")
+	api.Set("api/v1/namespaces/shop/persistentvolumeclaims/data", pvc)
+
+	day := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		if err := recordUsageSnapshots(day.AddDate(0, 0, i)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// recorded twice on the same day
+	recordUsageSnapshots(day.AddDate(0, 0, 3))
+
+	timeline, err := getUsageTimeline("fake", "shop", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(timeline.Points) != 2 || timeline.Points[0].Date != "2019-03-03" || timeline.Points[1].Date != "2019-03-04" {
+		t.Fatalf("expected one point per day within the retention, got %+v", timeline.Points)
+	}
+	if p := timeline.Points[1]; p.CPU != 4 || p.Memory != 8 || p.UsedCPU != 1.5 || p.Storage != 10 {
+		t.Errorf("unexpected usage %+v", p)
+	}
+
+	timeline, _ = getUsageTimeline("fake", "shop", "2019-03-04", "")
+	if len(timeline.Points) != 1 {
+		t.Errorf("expected the points from 2019-03-04, got %+v", timeline.Points)
+	}
+	if _, err := getUsageTimeline("fake", "shop", "03.04.2019", ""); err == nil {
+		t.Error("expected an invalid date to be rejected")
+	}
+}
@@ -0,0 +1,314 @@
+package openshift
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	vanityURLsCollection    = "vanity_urls"
+	defaultVanityConfigMap  = "vanity-urls"
+	vanityMapKey            = "vanity.map"
+	vanityURLNotFoundError  = "Die Kurz-URL %v existiert nicht"
+	vanityURLsDisabledError = "Kurz-URLs sind nicht konfiguriert"
+)
+
+// vanityPathPattern are the allowed short paths, e.g. "fahrplan" or "shop-api"
+var vanityPathPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+
+// vanitySyncMutex serializes the updates of the map, so no alias gets lost
+var vanitySyncMutex sync.Mutex
+
+// VanityURL maps a short path under the shared domain to a route of a project
+type VanityURL struct {
+	Path      string    `json:"path"`
+	URL       string    `json:"url"`
+	ClusterId string    `json:"clusterid"`
+	Project   string    `json:"project"`
+	Route     string    `json:"route"`
+	Target    string    `json:"target"`
+	Owner     string    `json:"owner"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// vanityConfig is the configmap with the map of the proxy of the shared
+// domain in 'vanity_urls'
+type vanityConfig struct {
+	Domain    string
+	ClusterId string
+	Namespace string
+	ConfigMap string
+	Reserved  []string
+}
+
+func getVanityConfig() (*vanityConfig, error) {
+	cfg := config.Config()
+	c := &vanityConfig{
+		Domain:    cfg.GetString("vanity_urls.domain"),
+		ClusterId: cfg.GetString("vanity_urls.cluster"),
+		Namespace: cfg.GetString("vanity_urls.namespace"),
+		ConfigMap: cfg.GetString("vanity_urls.configmap"),
+		Reserved:  cfg.GetStringSlice("vanity_urls.reserved"),
+	}
+	if c.Domain == "" || c.ClusterId == "" || c.Namespace == "" {
+		return nil, errors.New(vanityURLsDisabledError)
+	}
+	if c.ConfigMap == "" {
+		c.ConfigMap = defaultVanityConfigMap
+	}
+	return c, nil
+}
+
+// claimVanityURL reserves the path for the route of the project. A path
+// belongs to one project until it's released
+func claimVanityURL(data common.VanityURLCommand, username string) (*VanityURL, error) {
+	cfg, err := getVanityConfig()
+	if err != nil {
+		return nil, err
+	}
+	path := strings.ToLower(strings.Trim(data.Path, "/ "))
+	if !vanityPathPattern.MatchString(path) {
+		return nil, errors.New("Der Pfad darf nur Kleinbuchstaben, Zahlen und '-' enthalten und muss 2 bis 63 Zeichen lang sein")
+	}
+	if contains(cfg.Reserved, path) {
+		return nil, fmt.Errorf("Der Pfad %v ist reserviert", path)
+	}
+
+	route, err := getRouteTarget(data.ClusterId, data.Project, data.Route)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing VanityURL
+	found, err := store.Get(vanityURLsCollection, path, &existing)
+	if err != nil {
+		return nil, err
+	}
+	if found && (existing.ClusterId != data.ClusterId || existing.Project != data.Project) {
+		return nil, fmt.Errorf("Der Pfad %v gehört bereits dem Projekt %v auf Cluster %v", path, existing.Project, existing.ClusterId)
+	}
+
+	alias := &VanityURL{
+		Path:      path,
+		URL:       fmt.Sprintf("https://%v/%v", cfg.Domain, path),
+		ClusterId: data.ClusterId,
+		Project:   data.Project,
+		Route:     data.Route,
+		Target:    route,
+		Owner:     username,
+		CreatedAt: common.Now(),
+	}
+	if found {
+		alias.Owner, alias.CreatedAt = existing.Owner, existing.CreatedAt
+	}
+	if err := store.Put(vanityURLsCollection, path, alias); err != nil {
+		return nil, err
+	}
+	if err := syncVanityURLs(cfg); err != nil {
+		return nil, err
+	}
+	log.Printf("%v mapped %v to route %v of project %v on cluster %v", username, alias.URL, data.Route, data.Project, data.ClusterId)
+	return alias, nil
+}
+
+func releaseVanityURL(path string) error {
+	cfg, err := getVanityConfig()
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(vanityURLsCollection, path); err != nil {
+		return err
+	}
+	return syncVanityURLs(cfg)
+}
+
+// getRouteTarget returns the url of the route
+func getRouteTarget(clusterId, project, route string) (string, error) {
+	if route == "" {
+		return "", errors.New("Es muss eine Route angegeben werden")
+	}
+	resp, err := getOseHTTPClient("GET", clusterId, fmt.Sprintf("oapi/v1/namespaces/%v/routes/%v", project, route), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("Die Route %v existiert nicht im Projekt %v", route, project)
+	}
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error getting route:", resp.StatusCode, string(errMsg))
+		return "", errors.New(genericAPIError)
+	}
+	var r struct {
+		Spec struct {
+			Host string `json:"host"`
+			Path string `json:"path"`
+		} `json:"spec"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil || r.Spec.Host == "" {
+		return "", fmt.Errorf("Die Route %v hat keinen Host", route)
+	}
+	return "https://" + r.Spec.Host + r.Spec.Path, nil
+}
+
+func getVanityURLs(filter func(VanityURL) bool) ([]VanityURL, error) {
+	aliases := []VanityURL{}
+	err := store.List(vanityURLsCollection, func(id string, data []byte) error {
+		var a VanityURL
+		if err := json.Unmarshal(data, &a); err != nil {
+			return err
+		}
+		if filter(a) {
+			aliases = append(aliases, a)
+		}
+		return nil
+	})
+	return aliases, err
+}
+
+// vanityMap is the map file of the proxy: one "/path target" per line
+func vanityMap(aliases []VanityURL) string {
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].Path < aliases[j].Path
+	})
+	var b strings.Builder
+	for _, a := range aliases {
+		fmt.Fprintf(&b, "/%v %v\n", a.Path, a.Target)
+	}
+	return b.String()
+}
+
+// syncVanityURLs writes all aliases to the configmap read by the proxy of
+// the shared domain
+func syncVanityURLs(cfg *vanityConfig) error {
+	vanitySyncMutex.Lock()
+	defer vanitySyncMutex.Unlock()
+
+	aliases, err := getVanityURLs(func(VanityURL) bool { return true })
+	if err != nil {
+		return err
+	}
+	configMap := newObjectRequest("ConfigMap", cfg.ConfigMap)
+	configMap.SetP(vanityMapKey+" is generated by the ssp, don't edit", "metadata.annotations.description")
+	configMap.Set(vanityMap(aliases), "data", vanityMapKey)
+
+	url := fmt.Sprintf("api/v1/namespaces/%v/configmaps", cfg.Namespace)
+	resp, err := getOseHTTPClient("PUT", cfg.ClusterId, url+"/"+cfg.ConfigMap, bytes.NewReader(configMap.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		resp, err = getOseHTTPClient("POST", cfg.ClusterId, url, bytes.NewReader(configMap.Bytes()))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error updating the vanity url configmap:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
+
+// getVanityURLsHandler lists the aliases of a project
+func getVanityURLsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	aliases, err := getVanityURLs(func(a VanityURL) bool {
+		return a.ClusterId == clusterId && a.Project == project
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, aliases)
+}
+
+// claimVanityURLHandler claims a path or changes the route of an own path
+func claimVanityURLHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.VanityURLCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	alias, err := claimVanityURL(data, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, alias)
+}
+
+// releaseVanityURLHandler releases ?path. It can be released by the admins
+// of the owning project and portal admins
+func releaseVanityURLHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	path := strings.ToLower(strings.Trim(c.Query("path"), "/ "))
+
+	var alias VanityURL
+	found, err := store.Get(vanityURLsCollection, path, &alias)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: fmt.Sprintf(vanityURLNotFoundError, path)})
+		return
+	}
+	if !common.IsPortalAdmin(username) {
+		if err := validateAdminAccess(alias.ClusterId, username, alias.Project); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	}
+
+	if err := releaseVanityURL(path); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	audit.Describe(c, "%v released the vanity url %v of project %v on cluster %v", username, path, alias.Project, alias.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: fmt.Sprintf("Die Kurz-URL %v wurde freigegeben", alias.URL)})
+}
+
+// getAllVanityURLsHandler lists all aliases with their owners
+func getAllVanityURLsHandler(c *gin.Context) {
+	aliases, err := getVanityURLs(func(VanityURL) bool { return true })
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, aliases)
+}
@@ -0,0 +1,72 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestClaimVanityURL(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	cfg := config.Config()
+	cfg.Set("vanity_urls.domain", "go.example.com")
+	cfg.Set("vanity_urls.cluster", "fake")
+	cfg.Set("vanity_urls.namespace", "vanity-proxy")
+	cfg.Set("vanity_urls.reserved", []string{"admin"})
+	for path, host := range map[string]string{
+		"oapi/v1/namespaces/shop/routes/web":  "shop.example.com",
+		"oapi/v1/namespaces/other/routes/web": "other.example.com",
+	} {
+		route := gabs.New()
+		route.SetP(host, "spec.host")
+		api.Set(path, route)
+	}
+	command := func(project, path, route string) common.VanityURLCommand {
+		return common.VanityURLCommand{OpenshiftBase: common.OpenshiftBase{ClusterId: "fake", Project: project}, Path: path, Route: route}
+	}
+
+	alias, err := claimVanityURL(command("shop", "/Shop", "web"), "u123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if alias.Path != "shop" || alias.URL != "https://go.example.com/shop" || alias.Target != "https://shop.example.com" {
+		t.Errorf("unexpected alias %+v", alias)
+	}
+
+	for _, c := range []common.VanityURLCommand{
+		command("other", "shop", "web"),
+		command("other", "admin", "web"),
+		command("other", "a/b", "web"),
+		command("other", "other", "missing"),
+	} {
+		if _, err := claimVanityURL(c, "u456"); err == nil {
+			t.Errorf("expected %+v to be rejected", c)
+		}
+	}
+	if _, err := claimVanityURL(command("other", "other", "web"), "u456"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	configMap, ok := api.Get("api/v1/namespaces/vanity-proxy/configmaps/vanity-urls")
+	if !ok {
+		t.Fatal("expected the configmap to be created")
+	}
+	expected := "/other https://other.example.com\n/shop https://shop.example.com\n"
+	if data, _ := configMap.S("data", vanityMapKey).Data().(string); data != expected {
+		t.Errorf("expected the map %q, got %q", expected, data)
+	}
+
+	if err := releaseVanityURL("shop"); err != nil {
+		t.Fatal(err)
+	}
+	configMap, _ = api.Get("api/v1/namespaces/vanity-proxy/configmaps/vanity-urls")
+	if data, _ := configMap.S("data", vanityMapKey).Data().(string); data != "/other https://other.example.com\n" {
+		t.Errorf("expected shop to be removed from the map, got %q", data)
+	}
+	if _, err := claimVanityURL(command("other", "shop", "web"), "u456"); err != nil {
+		t.Errorf("expected a released path to be claimable, got %v", err)
+	}
+}
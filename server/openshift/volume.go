@@ -3,11 +3,11 @@ package openshift
 import (
 	"errors"
 	"net/http"
+	"regexp"
 
 	"bytes"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"strings"
 	"time"
 
@@ -20,6 +20,7 @@ import (
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -28,42 +29,42 @@ const (
 	wrongSizeLimitError     = "Grösse nicht erlaubt. Mindestgrösse: 500M (1G für NFS). Maximale Grössen sind: M: %v, G: %v"
 	apiCreateWorkflowUuid   = "64b3b95b-0d79-4563-8b88-f8c4486b40a0"
 	apiChangeWorkflowUuid   = "186b1295-1b82-42e4-b04d-477da967e1d4"
+	wrongPvcNameError       = "Ungültiger PVC-Name. Erlaubt sind höchstens 63 Kleinbuchstaben, Zahlen und -, am Anfang und Ende keine -"
 )
 
+// pvcNamePattern is a dns label, as required by OpenShift for the pvc and
+// the services of the volume
+var pvcNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+var accessModes = []string{"ReadWriteOnce", "ReadWriteMany", "ReadOnlyMany"}
+
 func newVolumeHandler(c *gin.Context) {
 	username := common.GetUserName(c)
 
 	var data common.NewVolumeCommand
 	if c.BindJSON(&data) == nil {
-		if err := validateNewVolume(data.ClusterId, data.Project, data.Size, data.PvcName, data.Mode, data.Technology, username); err != nil {
+		technology, provider, err := getStorageProvider(data.ClusterId, data.Technology)
+		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 			return
 		}
 
-		// try to get storageclass
-		storageclass, err := getStorageClass(data.ClusterId, data.Technology)
-		if err != nil {
+		if err := validateNewVolume(data.ClusterId, data.Project, data.Size, data.PvcName, data.Mode, technology, username); err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 			return
 		}
 
-		newVolumeResponse, err := createNewVolume(data.ClusterId, data.Project, data.Size, data.PvcName, data.Mode, data.Technology, username, storageclass)
+		newVolumeResponse, err := createNewVolume(data.ClusterId, data.Project, data.Size, data.PvcName, data.Mode, provider, username)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 			return
 		}
-		if data.Technology == "nfs" {
-			// Don't send a message because this only starts a job
-			// and the client polls the server to get the current progress
-			c.JSON(http.StatusOK, common.NewVolumeApiResponse{
-				Data: *newVolumeResponse,
-			})
-		} else {
-			c.JSON(http.StatusOK, common.NewVolumeApiResponse{
-				Message: "Das Volume wurde erstellt. Deinem Projekt wurde das PVC, und der Gluster Service & Endpunkte hinzugefügt.",
-				Data:    *newVolumeResponse,
-			})
-		}
+		// Without message the creation only started a job
+		// and the client polls the server to get the current progress
+		c.JSON(http.StatusOK, common.NewVolumeApiResponse{
+			Message: provider.CreatedMessage(),
+			Data:    *newVolumeResponse,
+		})
 	} else {
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
 	}
@@ -143,6 +144,14 @@ func validateNewVolume(clusterId, project, size, pvcName, mode, technology, user
 		return errors.New("Es müssen alle Felder ausgefüllt werden")
 	}
 
+	if !pvcNamePattern.MatchString(pvcName) {
+		return errors.New(wrongPvcNameError)
+	}
+
+	if !contains(accessModes, mode) {
+		return fmt.Errorf("Ungültiger Modus. Erlaubt sind: %v", strings.Join(accessModes, ", "))
+	}
+
 	if err := validateSizeFormat(size, technology); err != nil {
 		return err
 	}
@@ -161,7 +170,7 @@ func validateNewVolume(clusterId, project, size, pvcName, mode, technology, user
 		return err
 	}
 
-	// Check if technology is nfs or gluster
+	// Check if there is a storage provider for the technology
 	if err := checkTechnology(technology); err != nil {
 		return err
 	}
@@ -229,7 +238,8 @@ func validateSize(size string) error {
 	maxMB := 1024
 	maxGB := config.Config().GetInt("max_volume_gb")
 	if maxGB <= 0 {
-		log.Fatal("Env variable 'MAX_VOLUME_GB' must be specified and a valid integer")
+		log.Println("Env variable 'MAX_VOLUME_GB' must be specified and a valid integer")
+		return errors.New(common.ConfigNotSetError)
 	}
 
 	// Size limits
@@ -289,50 +299,45 @@ func checkPvcName(clusterId, project, pvcName string) error {
 }
 
 func checkTechnology(technology string) error {
-	switch technology {
-	case
-		"nfs",
-		"gluster":
+	if _, ok := storageProviders[technology]; ok {
 		return nil
 	}
-	return errors.New("Invalid technology. Must be either nfs or gluster")
+	return fmt.Errorf("Invalid technology. Must be one of %v", strings.Join(storageTechnologies(), ", "))
 }
 
-func createNewVolume(clusterId, project, size, pvcName, mode, technology, username, storageclass string) (*common.NewVolumeResponse, error) {
-	var newVolumeResponse *common.NewVolumeResponse
-	var err error
-	if technology == "nfs" {
-		newVolumeResponse, err = createNfsVolume(clusterId, project, pvcName, size, username)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		newVolumeResponse, err = createGlusterVolume(clusterId, project, size, username)
-		if err != nil {
-			return nil, err
-		}
-
-		// Create Gluster Service & Endpoints in user project
-		if err := createOpenShiftGlusterService(clusterId, project, username); err != nil {
-			return nil, err
-		}
+func createNewVolume(clusterId, project, size, pvcName, mode string, provider StorageProvider, username string) (*common.NewVolumeResponse, error) {
+	cluster, err := getOpenshiftCluster(clusterId)
+	if err != nil {
+		return nil, err
+	}
+	storageclass := provider.StorageClass(cluster)
 
-		if err := createOpenShiftGlusterEndpoint(clusterId, project, username); err != nil {
-			return nil, err
-		}
+	newVolumeResponse, err := provider.Create(clusterId, project, pvcName, size, username)
+	if err != nil {
+		postVolumeFailure(clusterId, project, pvcName, username, err)
+		return nil, err
 	}
 
-	if err := createOpenShiftPV(clusterId, size, newVolumeResponse.PvName, newVolumeResponse.Server, newVolumeResponse.Path, mode, technology, username, storageclass); err != nil {
+	if err := createOpenShiftPV(clusterId, size, mode, provider, newVolumeResponse, username, storageclass); err != nil {
+		postVolumeFailure(clusterId, project, pvcName, username, err)
 		return nil, err
 	}
 
 	if err := createOpenShiftPVC(clusterId, project, size, pvcName, mode, username, storageclass); err != nil {
+		postVolumeFailure(clusterId, project, pvcName, username, err)
 		return nil, err
 	}
 
 	return newVolumeResponse, nil
 }
 
+// postVolumeFailure tells the platform operators in the chat that a volume
+// couldn't be created
+func postVolumeFailure(clusterId, project, pvcName, username string, err error) {
+	common.PostChat(common.ChatVolumeFailed, fmt.Sprintf("Creating the volume %v of %v in project %v on cluster %v failed: %v",
+		pvcName, username, project, clusterId, err))
+}
+
 func createGlusterVolume(clusterId, project string, size string, username string) (*common.NewVolumeResponse, error) {
 	cmd := models.CreateVolumeCommand{
 		Project: project,
@@ -517,19 +522,11 @@ func getJobProgress(job common.WorkflowJob) float64 {
 }
 
 func growExistingVolume(clusterId string, pv *gabs.Container, newSize string, username string) error {
-	if pv.ExistsP("spec.glusterfs") {
-		if err := growGlusterVolume(clusterId, pv, newSize, username); err != nil {
-			return err
-		}
-		return nil
-	}
-	if pv.ExistsP("spec.nfs") {
-		if err := growNfsVolume(clusterId, pv, newSize, username); err != nil {
-			return err
-		}
-		return nil
+	provider, err := getStorageProviderOf(pv)
+	if err != nil {
+		return err
 	}
-	return errors.New("Wrong pv name")
+	return provider.Grow(clusterId, pv, newSize, username)
 }
 
 func growNfsVolume(clusterId string, pv *gabs.Container, newSize string, username string) error {
@@ -636,18 +633,11 @@ func growGlusterVolume(clusterId string, pv *gabs.Container, newSize string, use
 	return nil
 }
 
-func createOpenShiftPV(clusterId, size, pvName, server, path, mode, technology, username, storageclass string) error {
+func createOpenShiftPV(clusterId, size, mode string, provider StorageProvider, volume *common.NewVolumeResponse, username, storageclass string) error {
+	pvName := volume.PvName
 	p := newObjectRequest("PersistentVolume", pvName)
 	p.SetP(size, "spec.capacity.storage")
-
-	if technology == "nfs" {
-		p.SetP(path, "spec.nfs.path")
-		p.SetP(server, "spec.nfs.server")
-	} else {
-		p.SetP("glusterfs-cluster", "spec.glusterfs.endpoints")
-		p.SetP(path, "spec.glusterfs.path")
-		p.SetP(false, "spec.glusterfs.readOnly")
-	}
+	provider.SetSource(p, volume)
 
 	p.SetP("Retain", "spec.persistentVolumeReclaimPolicy")
 	if storageclass != "" {
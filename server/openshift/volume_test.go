@@ -0,0 +1,88 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestValidateNewVolume(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	config.Config().Set("max_volume_gb", 100)
+	api.AddProject("own", "u123")
+
+	tests := []struct {
+		pvcName, size, mode, technology string
+		valid                           bool
+	}{
+		{"data", "10G", "ReadWriteOnce", "gluster", true},
+		{"data", "500M", "ReadWriteMany", "gluster", true},
+		{"data", "10G", "ReadWriteMany", "nfs", true},
+		{"Data_1", "10G", "ReadWriteOnce", "gluster", false},
+		{"-data", "10G", "ReadWriteOnce", "gluster", false},
+		{"data", "10G", "rw", "gluster", false},
+		{"data", "500M", "ReadWriteOnce", "nfs", false},
+		{"data", "400M", "ReadWriteOnce", "gluster", false},
+		{"data", "101G", "ReadWriteOnce", "gluster", false},
+		{"data", "10G", "ReadWriteOnce", "ceph", false},
+	}
+	for _, test := range tests {
+		err := validateNewVolume("fake", "own", test.size, test.pvcName, test.mode, test.technology, "u123")
+		if (err == nil) != test.valid {
+			t.Errorf("%+v: expected valid %v, got %v", test, test.valid, err)
+		}
+	}
+
+	config.Config().Set("max_volume_gb", 0)
+	if err := validateSize("10G"); err == nil {
+		t.Error("expected an error without 'max_volume_gb'")
+	}
+}
+
+func TestGetStorageProvider(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+
+	setCluster := func(values map[string]interface{}) {
+		cluster := api.Cluster("fake")
+		for k, v := range values {
+			cluster[k] = v
+		}
+		config.Config().Set("openshift", []map[string]interface{}{cluster})
+	}
+	gluster := map[string]interface{}{"url": "http://gluster", "storageclass": "gluster-sc"}
+	nfs := map[string]interface{}{"url": "http://nfs", "storageclass": "nfs-sc"}
+
+	tests := []struct {
+		cluster    map[string]interface{}
+		technology string
+		expected   string
+	}{
+		{map[string]interface{}{"glusterapi": gluster}, "", "gluster"},
+		{map[string]interface{}{"nfsapi": nfs}, "", "nfs"},
+		{map[string]interface{}{"nfsapi": nfs}, "gluster", ""},
+		{map[string]interface{}{"glusterapi": gluster, "nfsapi": nfs}, "", ""},
+		{map[string]interface{}{"glusterapi": gluster, "nfsapi": nfs, "storage": "nfs"}, "", "nfs"},
+		{map[string]interface{}{"glusterapi": gluster, "nfsapi": nfs, "storage": "nfs"}, "gluster", "gluster"},
+		{map[string]interface{}{"glusterapi": gluster, "nfsapi": nfs}, "ceph", ""},
+	}
+	for _, test := range tests {
+		setCluster(test.cluster)
+		technology, provider, err := getStorageProvider("fake", test.technology)
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("%+v: expected an error, got %v", test, technology)
+			}
+			continue
+		}
+		if err != nil || technology != test.expected {
+			t.Errorf("%+v: expected %v, got %v %v", test, test.expected, technology, err)
+			continue
+		}
+		cluster, _ := getOpenshiftCluster("fake")
+		if sc := provider.StorageClass(cluster); sc != test.expected+"-sc" {
+			t.Errorf("%+v: expected storage class %v-sc, got %v", test, test.expected, sc)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	workshopsCollection = "workshops"
+	maxWorkshopProjects = 100
+)
+
+var workshopNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Workshop is a set of numbered projects for a training
+type Workshop struct {
+	Name      string            `json:"name"`
+	ClusterId string            `json:"clusterid"`
+	Template  string            `json:"template,omitempty"`
+	Projects  []WorkshopProject `json:"projects"`
+	CreatedBy string            `json:"createdBy"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+type WorkshopProject struct {
+	Project  string `json:"project"`
+	Attendee string `json:"attendee,omitempty"`
+	// Created is set if the workshop created the project, even if a later
+	// step failed. Projects that already existed are never deleted
+	Created bool   `json:"created,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// createdByWorkshop checks if the teardown may delete the project. Projects
+// of workshops stored without 'created' were created if there's no error
+func (p WorkshopProject) createdByWorkshop() bool {
+	return p.Created || p.Error == ""
+}
+
+func newWorkshopHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.NewWorkshopCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if data.Count == 0 {
+		data.Count = len(data.Attendees)
+	}
+	if err := validateNewWorkshop(data); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v creates the workshop %v with %v projects on cluster %v", username, data.Name, data.Count, data.ClusterId)
+	workshop, err := createWorkshop(data, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, workshop)
+}
+
+func getWorkshopsHandler(c *gin.Context) {
+	workshops := []Workshop{}
+	err := store.List(workshopsCollection, func(id string, data []byte) error {
+		var w Workshop
+		if err := json.Unmarshal(data, &w); err != nil {
+			return err
+		}
+		workshops = append(workshops, w)
+		return nil
+	})
+	if err != nil {
+		common.Logger(c).Printf("Error reading workshops: %v", err)
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericAPIError})
+		return
+	}
+	c.JSON(http.StatusOK, workshops)
+}
+
+func deleteWorkshopHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	name := c.Param("name")
+
+	var workshop Workshop
+	found, err := store.Get(workshopsCollection, name, &workshop)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: "Der Workshop existiert nicht"})
+		return
+	}
+
+	audit.Describe(c, "%v tears down the workshop %v on cluster %v", username, name, workshop.ClusterId)
+	deleted, failed := deleteWorkshopProjects(workshop)
+	if len(failed) > 0 {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{
+			Message: fmt.Sprintf("Die folgenden Projekte konnten nicht gelöscht werden: %v", strings.Join(failed, ", ")),
+		})
+		return
+	}
+
+	if err := store.Delete(workshopsCollection, name); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Die %v Projekte des Workshops %v wurden gelöscht", deleted, name),
+	})
+}
+
+// deleteWorkshopProjects deletes the projects the workshop created and
+// returns the number of deleted projects and the ones that failed
+func deleteWorkshopProjects(workshop Workshop) (int, []string) {
+	deleted := 0
+	failed := []string{}
+	for _, p := range workshop.Projects {
+		if !p.createdByWorkshop() {
+			continue
+		}
+		if err := deleteProject(workshop.ClusterId, p.Project); err != nil {
+			failed = append(failed, p.Project)
+			continue
+		}
+		deleted++
+	}
+	return deleted, failed
+}
+
+func validateNewWorkshop(data common.NewWorkshopCommand) error {
+	if data.ClusterId == "" {
+		return errors.New("Cluster muss angegeben werden")
+	}
+	if !workshopNameRegex.MatchString(data.Name) {
+		return errors.New("Der Name des Workshops darf nur Kleinbuchstaben, Zahlen und '-' enthalten")
+	}
+	if data.Count <= 0 || data.Count > maxWorkshopProjects {
+		return fmt.Errorf("Die Anzahl Projekte muss zwischen 1 und %v liegen", maxWorkshopProjects)
+	}
+	if len(data.Attendees) > data.Count {
+		return errors.New("Es gibt mehr Teilnehmer als Projekte")
+	}
+
+	found, err := store.Get(workshopsCollection, data.Name, &Workshop{})
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("Der Workshop %v existiert bereits", data.Name)
+	}
+	return nil
+}
+
+// createWorkshop creates the projects <name>-01 to <name>-<count>. The n-th
+// attendee gets edit rights on the n-th project. Failures of single projects
+// are reported per project, so the workshop can still be torn down
+func createWorkshop(data common.NewWorkshopCommand, username string) (*Workshop, error) {
+	workshop := &Workshop{
+		Name:      data.Name,
+		ClusterId: data.ClusterId,
+		Template:  data.Template,
+		Projects:  []WorkshopProject{},
+		CreatedBy: username,
+		CreatedAt: time.Now(),
+	}
+	templateNamespace := data.TemplateNamespace
+	if templateNamespace == "" {
+		templateNamespace = "openshift"
+	}
+
+	for i := 0; i < data.Count; i++ {
+		p := WorkshopProject{Project: fmt.Sprintf("%v-%02d", data.Name, i+1)}
+		if i < len(data.Attendees) {
+			p.Attendee = strings.ToLower(data.Attendees[i])
+		}
+
+		created, err := createWorkshopProject(data.ClusterId, p, data.Template, templateNamespace, username)
+		p.Created = created
+		if err != nil {
+			p.Error = err.Error()
+		}
+		workshop.Projects = append(workshop.Projects, p)
+	}
+
+	if err := store.Put(workshopsCollection, workshop.Name, workshop); err != nil {
+		return nil, err
+	}
+	return workshop, nil
+}
+
+// createWorkshopProject returns if the project was created, also when
+// adding the attendee or the template failed afterwards
+func createWorkshopProject(clusterId string, p WorkshopProject, template, templateNamespace, username string) (bool, error) {
+	if err := createNewProject(nil, clusterId, p.Project, username, "keine-verrechnung", "", true); err != nil {
+		return false, err
+	}
+	if p.Attendee != "" {
+		if err := addUsersToRoleBinding(clusterId, p.Project, "edit", []string{p.Attendee}); err != nil {
+			return true, err
+		}
+	}
+	if template != "" {
+		return true, instantiateTemplate(clusterId, templateNamespace, template, p.Project, map[string]string{})
+	}
+	return true, nil
+}
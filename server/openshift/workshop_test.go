@@ -0,0 +1,33 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+func TestDeleteWorkshopProjects(t *testing.T) {
+	api, cleanup := newFakeCluster(t)
+	defer cleanup()
+	// The second project of the workshop already belongs to another team
+	api.AddProject("training-02", "u999")
+
+	workshop, err := createWorkshop(common.NewWorkshopCommand{ClusterId: "fake", Name: "training", Count: 2}, "u123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !workshop.Projects[0].Created || workshop.Projects[1].Created || workshop.Projects[1].Error == "" {
+		t.Fatalf("expected only the first project to be created, got %+v", workshop.Projects)
+	}
+
+	deleted, failed := deleteWorkshopProjects(*workshop)
+	if deleted != 1 || len(failed) != 0 {
+		t.Errorf("expected one deleted project, got %v %v", deleted, failed)
+	}
+	if _, ok := api.Get("api/v1/namespaces/training-01"); ok {
+		t.Error("expected the project of the workshop to be deleted")
+	}
+	if _, ok := api.Get("api/v1/namespaces/training-02"); !ok {
+		t.Error("expected the existing project to be kept")
+	}
+}
@@ -2,6 +2,7 @@ package otc
 
 import (
 	"fmt"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
 	"github.com/gin-gonic/gin"
@@ -18,9 +19,10 @@ import (
 	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/ssh"
-	"log"
 	"net/http"
 	"strings"
+
+	log "github.com/sirupsen/logrus"
 )
 
 func validateUserInput(data NewECSCommand) error {
@@ -113,19 +115,19 @@ func validateUserInput(data NewECSCommand) error {
 func newECSHandler(c *gin.Context) {
 	networkId := config.Config().GetString("otc_network_uuid")
 	if networkId == "" {
-		log.Println("Environment variable OTC_NETWORK_UUID must be set.")
+		common.Logger(c).Println("Environment variable OTC_NETWORK_UUID must be set.")
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 		return
 	}
 
 	username := common.GetUserName(c)
-	log.Printf("%v creates new ECS @ OTC.", username)
+	audit.Describe(c, "%v creates new ECS @ OTC.", username)
 
 	var data NewECSCommand
 	err := c.BindJSON(&data)
 
 	if err != nil {
-		log.Println("Binding request to Go struct failed.", err.Error())
+		common.Logger(c).Println("Binding request to Go struct failed.", err.Error())
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
 		return
 	}
@@ -133,7 +135,7 @@ func newECSHandler(c *gin.Context) {
 	err = validateUserInput(data)
 
 	if err != nil {
-		log.Println("User input validation failed.", err.Error())
+		common.Logger(c).Println("User input validation failed.", err.Error())
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 		return
 	}
@@ -141,7 +143,7 @@ func newECSHandler(c *gin.Context) {
 	client, err := getComputeClient()
 
 	if err != nil {
-		log.Println("Error getting compute client.", err.Error())
+		common.Logger(c).Println("Error getting compute client.", err.Error())
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 		return
 	}
@@ -149,28 +151,28 @@ func newECSHandler(c *gin.Context) {
 	serverName, err := generateECSName(data.ECSName)
 
 	if err != nil {
-		log.Println("Error generating server name.", err.Error())
+		common.Logger(c).Println("Error generating server name.", err.Error())
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 		return
 	}
 
 	uniqueId, err := uuid.NewV4()
 	if err != nil {
-		log.Println("Error getting UUID. That's incredible.", err.Error())
+		common.Logger(c).Println("Error getting UUID. That's incredible.", err.Error())
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 		return
 	}
 
 	blockDevices, err := createECSDisks(data, serverName, uniqueId.String(), username)
 	if err != nil {
-		log.Println("Error creating disks for ECS.", err.Error())
+		common.Logger(c).Println("Error creating disks for ECS.", err.Error())
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 		return
 	}
 
 	keyPair, err := createKeyPair(client, serverName+"-"+username+"-"+uniqueId.String(), data.PublicKey)
 	if err != nil {
-		log.Println("Error getting key.", err.Error())
+		common.Logger(c).Println("Error getting key.", err.Error())
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 		return
 	}
@@ -218,11 +220,11 @@ runcmd:
 	}).Extract()
 
 	if err != nil {
-		log.Println("Creating server failed.", err.Error())
+		common.Logger(c).Println("Creating server failed.", err.Error())
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Server konnte nicht erstellt werden."})
 		return
 	} else {
-		log.Println("Creating server succeeded.")
+		common.Logger(c).Println("Creating server succeeded.")
 		c.JSON(http.StatusOK, common.ApiResponse{Message: "Server erstellt."})
 		return
 	}
@@ -306,12 +308,12 @@ func createECSDisks(data NewECSCommand, serverName string, uniqueId string, user
 func listECSHandler(c *gin.Context) {
 	username := common.GetUserName(c)
 
-	log.Printf("%v lists ECS instances @ OTC.", username)
+	common.Logger(c).Printf("%v lists ECS instances @ OTC.", username)
 
 	client, err := getComputeClient()
 
 	if err != nil {
-		log.Println("Error getting compute client.", err.Error())
+		common.Logger(c).Println("Error getting compute client.", err.Error())
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 			return
@@ -321,7 +323,7 @@ func listECSHandler(c *gin.Context) {
 	allServers, err := getECServersByUsername(client, common.GetUserName(c))
 
 	if err != nil {
-		log.Println("Error getting ECS servers.", err.Error())
+		common.Logger(c).Println("Error getting ECS servers.", err.Error())
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 			return
@@ -333,7 +335,7 @@ func listECSHandler(c *gin.Context) {
 }
 
 func listFlavorsHandler(c *gin.Context) {
-	log.Println("Querying flavors @ OTC.")
+	common.Logger(c).Println("Querying flavors @ OTC.")
 
 	client, err := getComputeClient()
 
@@ -348,7 +350,7 @@ func listFlavorsHandler(c *gin.Context) {
 	allFlavors, err := getFlavors(client)
 
 	if err != nil {
-		log.Println("Error getting flavors.", err.Error())
+		common.Logger(c).Println("Error getting flavors.", err.Error())
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 			return
@@ -360,12 +362,12 @@ func listFlavorsHandler(c *gin.Context) {
 }
 
 func listImagesHandler(c *gin.Context) {
-	log.Println("Querying images @ OTC.")
+	common.Logger(c).Println("Querying images @ OTC.")
 
 	client, err := getImageClient()
 
 	if err != nil {
-		log.Println("Error getting compute client.", err.Error())
+		common.Logger(c).Println("Error getting compute client.", err.Error())
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 			return
@@ -375,7 +377,7 @@ func listImagesHandler(c *gin.Context) {
 	allImages, err := getImages(client)
 
 	if err != nil {
-		log.Println("Error getting images.", err.Error())
+		common.Logger(c).Println("Error getting images.", err.Error())
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 			return
@@ -387,7 +389,7 @@ func listImagesHandler(c *gin.Context) {
 }
 
 func listAvailabilityZonesHandler(c *gin.Context) {
-	log.Println("Querying availability zones @ OTC.")
+	common.Logger(c).Println("Querying availability zones @ OTC.")
 
 	client, err := getComputeClient()
 
@@ -402,7 +404,7 @@ func listAvailabilityZonesHandler(c *gin.Context) {
 	allAvailabilityZones, err := getAvailabilityZones(client)
 
 	if err != nil {
-		log.Println("Error getting availability zones.", err.Error())
+		common.Logger(c).Println("Error getting availability zones.", err.Error())
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 			return
@@ -414,7 +416,7 @@ func listAvailabilityZonesHandler(c *gin.Context) {
 }
 
 func listVolumeTypesHandler(c *gin.Context) {
-	log.Println("Querying volume types @ OTC.")
+	common.Logger(c).Println("Querying volume types @ OTC.")
 
 	client, err := getBlockStorageClient()
 
@@ -429,7 +431,7 @@ func listVolumeTypesHandler(c *gin.Context) {
 	allVolumeTypes, err := getVolumeTypes(client)
 
 	if err != nil {
-		log.Println("Error getting volume types.", err.Error())
+		common.Logger(c).Println("Error getting volume types.", err.Error())
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 			return
@@ -441,12 +443,12 @@ func listVolumeTypesHandler(c *gin.Context) {
 }
 
 func stopECSHandler(c *gin.Context) {
-	log.Println("Stopping ECS @ OTC.")
+	common.Logger(c).Println("Stopping ECS @ OTC.")
 
 	client, err := getComputeClient()
 
 	if err != nil {
-		log.Println("Error getting compute client.", err.Error())
+		common.Logger(c).Println("Error getting compute client.", err.Error())
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 			return
@@ -457,7 +459,7 @@ func stopECSHandler(c *gin.Context) {
 	err = c.BindJSON(&data)
 
 	if err != nil {
-		log.Println("Binding request to Go struct failed.", err.Error())
+		common.Logger(c).Println("Binding request to Go struct failed.", err.Error())
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
 		return
 	}
@@ -466,7 +468,7 @@ func stopECSHandler(c *gin.Context) {
 		stopResult := startstop.Stop(client, server.Id)
 
 		if stopResult.Err != nil {
-			log.Println("Error while stopping server.", err.Error())
+			common.Logger(c).Println("Error while stopping server.", err.Error())
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Mindestens ein server konnte nicht gestoppt werden."})
 			return
 		}
@@ -477,12 +479,12 @@ func stopECSHandler(c *gin.Context) {
 }
 
 func startECSHandler(c *gin.Context) {
-	log.Println("Starting ECS @ OTC.")
+	common.Logger(c).Println("Starting ECS @ OTC.")
 
 	client, err := getComputeClient()
 
 	if err != nil {
-		log.Println("Error getting compute client.", err.Error())
+		common.Logger(c).Println("Error getting compute client.", err.Error())
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 			return
@@ -493,7 +495,7 @@ func startECSHandler(c *gin.Context) {
 	err = c.BindJSON(&data)
 
 	if err != nil {
-		log.Println("Binding request to Go struct failed.", err.Error())
+		common.Logger(c).Println("Binding request to Go struct failed.", err.Error())
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
 		return
 	}
@@ -502,7 +504,7 @@ func startECSHandler(c *gin.Context) {
 		stopResult := startstop.Start(client, server.Id)
 
 		if stopResult.Err != nil {
-			log.Println("Error while starting server.", err.Error())
+			common.Logger(c).Println("Error while starting server.", err.Error())
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Mindestens ein server konnte nicht gestartet werden."})
 			return
 		}
@@ -513,12 +515,12 @@ func startECSHandler(c *gin.Context) {
 }
 
 func rebootECSHandler(c *gin.Context) {
-	log.Println("Rebooting ECS @ OTC.")
+	common.Logger(c).Println("Rebooting ECS @ OTC.")
 
 	client, err := getComputeClient()
 
 	if err != nil {
-		log.Println("Error getting compute client.", err.Error())
+		common.Logger(c).Println("Error getting compute client.", err.Error())
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 			return
@@ -529,7 +531,7 @@ func rebootECSHandler(c *gin.Context) {
 	err = c.BindJSON(&data)
 
 	if err != nil {
-		log.Println("Binding request to Go struct failed.", err.Error())
+		common.Logger(c).Println("Binding request to Go struct failed.", err.Error())
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
 		return
 	}
@@ -542,7 +544,7 @@ func rebootECSHandler(c *gin.Context) {
 		rebootResult := servers.Reboot(client, server.Id, rebootOpts)
 
 		if rebootResult.Err != nil {
-			log.Println("Error while rebooting server.", err.Error())
+			common.Logger(c).Println("Error while rebooting server.", err.Error())
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Mindestens ein server konnte nicht rebootet werden."})
 			return
 		}
@@ -553,12 +555,12 @@ func rebootECSHandler(c *gin.Context) {
 }
 
 func deleteECSHandler(c *gin.Context) {
-	log.Println("Deleting ECS @ OTC.")
+	common.Logger(c).Println("Deleting ECS @ OTC.")
 
 	client, err := getComputeClient()
 
 	if err != nil {
-		log.Println("Error getting compute client.", err.Error())
+		common.Logger(c).Println("Error getting compute client.", err.Error())
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericOTCAPIError})
 			return
@@ -569,7 +571,7 @@ func deleteECSHandler(c *gin.Context) {
 	err = c.BindJSON(&data)
 
 	if err != nil {
-		log.Println("Binding request to Go struct failed.", err.Error())
+		common.Logger(c).Println("Binding request to Go struct failed.", err.Error())
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
 		return
 	}
@@ -578,7 +580,7 @@ func deleteECSHandler(c *gin.Context) {
 		deleteResult := servers.Delete(client, server.Id)
 
 		if deleteResult.Err != nil {
-			log.Println("Error while deleting server.", err.Error())
+			common.Logger(c).Println("Error while deleting server.", err.Error())
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Mindestens ein server konnte nicht gelöscht werden."})
 			return
 		}
@@ -3,6 +3,7 @@ package otc
 import (
 	"errors"
 	"fmt"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
 	"github.com/gin-gonic/gin"
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
@@ -32,10 +33,14 @@ func getProvider() (*gophercloud.ProviderClient, error) {
 		return nil, err
 	}
 
-	provider, err := openstack.AuthenticatedClient(opts)
+	provider, err := openstack.NewClient(opts.IdentityEndpoint)
 	if err != nil {
 		return nil, err
 	}
+	provider.HTTPClient = *common.HTTPClient("otc")
+	if err := openstack.Authenticate(provider, opts); err != nil {
+		return nil, err
+	}
 	return provider, nil
 }
 
@@ -0,0 +1,154 @@
+// Package selftest validates the configuration and the connectivity to the
+// dependencies of the portal (clusters, store, mail server). The packages
+// register their checks, which run on startup and on /api/admin/selftest.
+// With 'selftest.strict' the portal refuses to start if a critical check fails.
+package selftest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const dialTimeout = 5 * time.Second
+
+// Check is one validation. Critical checks prevent the start in strict mode
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func() error
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Name     string `json:"name"`
+	Critical bool   `json:"critical"`
+	OK       bool   `json:"ok"`
+	Message  string `json:"message,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report are the results of all checks
+type Report struct {
+	OK        bool      `json:"ok"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Results   []Result  `json:"results"`
+}
+
+var (
+	checks      []Check
+	checksMutex sync.Mutex
+)
+
+// Register adds a check. Has to be called before RunOnStartup
+func Register(name string, critical bool, run func() error) {
+	checksMutex.Lock()
+	defer checksMutex.Unlock()
+	checks = append(checks, Check{Name: name, Critical: critical, Run: run})
+}
+
+// RegisterDefaultChecks adds the checks of the store and the mail server
+func RegisterDefaultChecks() {
+	Register("store", true, checkStore)
+	Register("mail", false, checkMailServer)
+}
+
+// Run executes all checks concurrently. The report is OK if no critical
+// check failed
+func Run() Report {
+	checksMutex.Lock()
+	all := append([]Check{}, checks...)
+	checksMutex.Unlock()
+
+	report := Report{
+		OK:        true,
+		CheckedAt: time.Now(),
+		Results:   make([]Result, len(all)),
+	}
+	common.ForEachParallel(len(all), func(i int) error {
+		report.Results[i] = run(all[i])
+		return nil
+	})
+
+	for _, r := range report.Results {
+		if r.Critical && !r.OK {
+			report.OK = false
+		}
+	}
+	return report
+}
+
+func run(check Check) (result Result) {
+	start := time.Now()
+	result = Result{Name: check.Name, Critical: check.Critical, OK: true}
+	defer func() {
+		if r := recover(); r != nil {
+			result.OK = false
+			result.Message = fmt.Sprintf("%v", r)
+		}
+		result.Duration = time.Since(start).String()
+	}()
+
+	if err := check.Run(); err != nil {
+		result.OK = false
+		result.Message = err.Error()
+	}
+	return result
+}
+
+// RunOnStartup logs the failed checks. Exits if a critical check failed and
+// 'selftest.strict' is set
+func RunOnStartup() {
+	report := Run()
+	for _, r := range report.Results {
+		if !r.OK {
+			log.Printf("WARNING: selftest %v failed: %v", r.Name, r.Message)
+		}
+	}
+	if !report.OK && config.Config().GetBool("selftest.strict") {
+		log.Fatal("Critical selftests failed, refusing to start in strict mode ('selftest.strict')")
+	}
+}
+
+func RegisterRoutes(r *gin.RouterGroup) {
+	admin := r.Group("/admin", common.RequirePortalAdmin())
+	admin.GET("/selftest", selftestHandler)
+}
+
+func selftestHandler(c *gin.Context) {
+	report := Run()
+	status := http.StatusOK
+	if !report.OK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// checkStore writes and deletes a document
+func checkStore() error {
+	if err := store.Put("selftest", "probe", time.Now()); err != nil {
+		return err
+	}
+	return store.Delete("selftest", "probe")
+}
+
+func checkMailServer() error {
+	mailServer := config.Config().GetString("mail_server")
+	if mailServer == "" {
+		return errors.New("MAIL_SERVER ist nicht konfiguriert")
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(mailServer, "25"), dialTimeout)
+	if err != nil {
+		return fmt.Errorf("Der Mailserver %v ist nicht erreichbar: %v", mailServer, err)
+	}
+	return conn.Close()
+}
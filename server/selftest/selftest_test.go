@@ -0,0 +1,29 @@
+package selftest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestRun(t *testing.T) {
+	config.Init("test")
+	checks = nil
+	Register("ok", true, func() error { return nil })
+	Register("optional", false, func() error { return errors.New("unreachable") })
+
+	report := Run()
+	if !report.OK || len(report.Results) != 2 {
+		t.Fatalf("expected a failed optional check not to fail the report, got %+v", report)
+	}
+	if report.Results[1].OK || report.Results[1].Message != "unreachable" {
+		t.Errorf("expected the error of the check, got %+v", report.Results[1])
+	}
+
+	Register("critical", true, func() error { panic("nil config") })
+	report = Run()
+	if report.OK || report.Results[2].Message != "nil config" {
+		t.Errorf("expected the panicking critical check to fail the report, got %+v", report)
+	}
+}
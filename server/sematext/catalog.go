@@ -0,0 +1,49 @@
+package sematext
+
+import (
+	"fmt"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/catalog"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+var logseneAppOffering = catalog.Offering{
+	Name:        "logsene-app",
+	Title:       "Sematext Logsene App",
+	Description: "Logsene App für die Logs der Applikationen. Du wirst als Administrator eingeladen",
+	Schema: []byte(`{
+		"type": "object",
+		"required": ["appName", "planId", "limit", "project", "billing"],
+		"properties": {
+			"appName": {"type": "string", "title": "App-Name"},
+			"planId": {"type": "integer", "title": "Plan", "minimum": 1},
+			"limit": {"type": "integer", "title": "Tageslimite", "minimum": 1},
+			"discountCode": {"type": "string", "title": "Rabattcode"},
+			"project": {"type": "string", "title": "Projekt"},
+			"billing": {"type": "string", "title": "Kontierungsnummer"}
+		}
+	}`),
+}
+
+type logseneAppProvisioner struct {
+	catalog.Unsupported
+}
+
+func (logseneAppProvisioner) Provision(r catalog.Request) (*catalog.Result, error) {
+	var data common.CreateLogseneAppCommand
+	if err := r.Decode(&data); err != nil {
+		return nil, err
+	}
+	if err := newLogseneApp(r.Username, r.Mail, data); err != nil {
+		return nil, err
+	}
+	return &catalog.Result{
+		ID:      data.AppName,
+		Message: fmt.Sprintf("Die Logsene App (%v) wurde erstellt. %v wurde als Administrator eingeladen.", data.AppName, r.Mail),
+	}, nil
+}
+
+// RegisterCatalogOfferings adds the Sematext services to the self-service catalog
+func RegisterCatalogOfferings() {
+	catalog.Register(logseneAppOffering, logseneAppProvisioner{})
+}
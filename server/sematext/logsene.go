@@ -26,7 +26,7 @@ func getLogseneAppsHandler(c *gin.Context) {
 	mail := common.GetUserMail(c)
 	username := common.GetUserName(c)
 
-	fmt.Sprintf("User %v listed all his sematext logsene apps", username)
+	common.Logger(c).Printf("User %v listed all his sematext logsene apps", username)
 
 	if appList, err := getAllLogseneAppsForUser(mail); err != nil {
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
@@ -111,12 +111,7 @@ func createLogseneAppHandler(c *gin.Context) {
 
 	var data common.CreateLogseneAppCommand
 	if c.BindJSON(&data) == nil {
-		if err := validateNewLogseneApp(data.AppName, data.PlanId, data.Limit, data.Project, data.Billing); err != nil {
-			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
-			return
-		}
-
-		if err := createLogseneAppAndInviteUser(username, mail, data); err != nil {
+		if err := newLogseneApp(username, mail, data); err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 		} else {
 			c.JSON(http.StatusOK, common.ApiResponse{
@@ -128,6 +123,14 @@ func createLogseneAppHandler(c *gin.Context) {
 	}
 }
 
+// newLogseneApp validates and creates the app and invites the user as admin
+func newLogseneApp(username, mail string, data common.CreateLogseneAppCommand) error {
+	if err := validateNewLogseneApp(data.AppName, data.PlanId, data.Limit, data.Project, data.Billing); err != nil {
+		return err
+	}
+	return createLogseneAppAndInviteUser(username, mail, data)
+}
+
 func validateNewLogseneApp(appName string, planId int, limit int, project string, billing string) error {
 	if len(appName) == 0 {
 		return errors.New("App-Name muss angegeben werden!")
@@ -348,7 +351,7 @@ func createLogseneAppAndInviteUser(username string, mail string, data common.Cre
 }
 
 func createLogseneApp(username string, data common.CreateLogseneAppCommand) (int, error) {
-	fmt.Sprintf("User %v creates a new logsene app, name: %v, planId: %v, limit: %v, project: %v, billing: %v",
+	log.Printf("User %v creates a new logsene app, name: %v, planId: %v, limit: %v, project: %v, billing: %v",
 		username, data.AppName, data.PlanId, data.Limit, data.Project, data.Billing)
 
 	j := gabs.New()
@@ -394,7 +397,7 @@ func createLogseneApp(username string, data common.CreateLogseneAppCommand) (int
 }
 
 func inviteUserToApp(mail string, appId int) error {
-	fmt.Sprintf("Inviting %v to logsene app %v.", mail, appId)
+	log.Printf("Inviting %v to logsene app %v.", mail, appId)
 
 	j := gabs.New()
 	j.Set(mail, "inviteeEmail")
@@ -426,7 +429,7 @@ func inviteUserToApp(mail string, appId int) error {
 }
 
 func updateLogseneBilling(username string, billing string, project string, appId int) error {
-	fmt.Sprintf("User %v updated logsene app billing to %v / %v.", username, billing, project)
+	log.Printf("User %v updated logsene app billing to %v / %v.", username, billing, project)
 
 	j := gabs.New()
 	j.Set(billing+" / "+project, "description")
@@ -464,7 +467,7 @@ func updateLogsenePlanAndLimit(username string, planId int, limit int, appId int
 }
 
 func updateLogseneLimit(username string, limit int, appId int) error {
-	fmt.Sprintf("User %v updated logsene app limit to: %v", username, limit)
+	log.Printf("User %v updated logsene app limit to: %v", username, limit)
 
 	j := gabs.New()
 	j.Set(limit, "maxLimitMB")
@@ -490,7 +493,7 @@ func updateLogseneLimit(username string, limit int, appId int) error {
 }
 
 func updateLogsenePlan(username string, planId int, appId int) error {
-	fmt.Sprintf("User %v updated logsene app plan to planId: %v", username, planId)
+	log.Printf("User %v updated logsene app plan to planId: %v", username, planId)
 
 	j := gabs.New()
 	j.Set(planId, "planId")
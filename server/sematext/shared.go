@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
 	"github.com/gin-gonic/gin"
 	"strings"
@@ -35,7 +36,7 @@ func getSematextHTTPClient(method string, urlPart string, body io.Reader) (*http
 		baseUrl += "/"
 	}
 
-	client := &http.Client{}
+	client := common.HTTPClient("sematext")
 	req, _ := http.NewRequest(method, baseUrl+urlPart, body)
 
 	log.Debugf("Calling %v", req.URL.String())
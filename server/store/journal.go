@@ -0,0 +1,93 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxJournalLine limits the size of a document in a journal
+const maxJournalLine = 1024 * 1024
+
+// Journals are collections which only grow, e.g. the audit log. A document is
+// appended as one line of json instead of writing the whole collection, so
+// appending doesn't get slower with the size of the journal
+
+func journalFile(journal string) string {
+	return filepath.Join(storePath(), filepath.Base(journal)+".jsonl")
+}
+
+// Append adds the document at the end of the journal. The instances share the
+// store, so a file lock serializes them
+func Append(journal string, document interface{}) error {
+	data, err := json.Marshal(document)
+	if err != nil {
+		log.Printf("Error marshalling document of journal %v: %v", journal, err)
+		return errors.New(storeError)
+	}
+	data = append(data, '\n')
+
+	l := lock(journal)
+	l.Lock()
+	defer l.Unlock()
+
+	if err := os.MkdirAll(storePath(), 0700); err != nil {
+		log.Printf("Error creating store directory: %v", err)
+		return errors.New(storeError)
+	}
+	file, err := os.OpenFile(journalFile(journal), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		log.Printf("Error opening journal %v: %v", journal, err)
+		return errors.New(storeError)
+	}
+	defer file.Close()
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		log.Printf("Error locking journal %v: %v", journal, err)
+		return errors.New(storeError)
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	if _, err := file.Write(data); err != nil {
+		log.Printf("Error writing journal %v: %v", journal, err)
+		return errors.New(storeError)
+	}
+	return nil
+}
+
+// Scan calls fn with the documents of the journal in the order they were
+// appended. It stops at the first error of fn and returns it. A line which
+// isn't complete, e.g. after a crash, is skipped
+func Scan(journal string, fn func(data []byte) error) error {
+	file, err := os.Open(journalFile(journal))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		log.Printf("Error reading journal %v: %v", journal, err)
+		return errors.New(storeError)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxJournalLine)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !json.Valid(line) {
+			log.Printf("Skipping an incomplete line of journal %v", journal)
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error reading journal %v: %v", journal, err)
+		return errors.New(storeError)
+	}
+	return nil
+}
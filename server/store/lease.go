@@ -0,0 +1,30 @@
+package store
+
+import "time"
+
+const leasesCollection = "leases"
+
+type lease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// TryLease acquires or renews the lease with the name for the holder, e.g. to
+// run a background job on one instance of the portal only. Returns false
+// while another holder has the lease. The instances share the store, so
+// Update serializes them
+func TryLease(name, holder string, ttl time.Duration) (bool, error) {
+	acquired := false
+	var l lease
+	err := Update(leasesCollection, name, &l, func(exists bool) error {
+		now := time.Now()
+		if exists && l.Holder != holder && l.Expires.After(now) {
+			return nil
+		}
+		l.Holder = holder
+		l.Expires = now.Add(ttl)
+		acquired = true
+		return nil
+	})
+	return acquired, err
+}
@@ -0,0 +1,28 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store/storetest"
+)
+
+func TestTryLease(t *testing.T) {
+	defer storetest.Setup(t)()
+
+	if ok, err := TryLease("job", "a", time.Hour); err != nil || !ok {
+		t.Fatalf("expected a to acquire the lease, got %v %v", ok, err)
+	}
+	if ok, _ := TryLease("job", "b", time.Hour); ok {
+		t.Error("expected b not to get the lease of a")
+	}
+	if ok, _ := TryLease("other", "b", time.Hour); !ok {
+		t.Error("expected b to acquire another lease")
+	}
+	if ok, _ := TryLease("job", "a", -time.Second); !ok {
+		t.Error("expected a to renew its lease")
+	}
+	if ok, _ := TryLease("job", "b", time.Hour); !ok {
+		t.Error("expected b to take over the expired lease")
+	}
+}
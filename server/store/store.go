@@ -0,0 +1,311 @@
+// Package store persists the state of the portal (e.g. acknowledgments,
+// favorites, audit entries) as json documents in the directory 'store_path'.
+// Every collection is one file containing the documents by id. The
+// collections are cached in memory and only read again when the file changed.
+// Journals (e.g. the audit log) only grow and are appended line by line.
+//
+// Several instances of the portal can share the directory. Writes of a
+// collection are serialized by a file lock, so no instance loses the writes
+// of another.
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultStorePath = "data"
+	storeError       = "Fehler beim Zugriff auf die Datenbank des Portals. Bitte erstelle ein Ticket"
+)
+
+var (
+	// mutex protects locks and collections
+	mutex       sync.Mutex
+	locks       = make(map[string]*sync.Mutex)
+	collections = make(map[string]cachedCollection)
+)
+
+// cachedCollection is the parsed file of a collection. The map is never
+// changed after it was cached, writers copy it
+type cachedCollection struct {
+	file      string
+	modTime   time.Time
+	size      int64
+	documents map[string]json.RawMessage
+}
+
+// lock returns the lock of the collection, so writes to one collection
+// don't block the others
+func lock(collection string) *sync.Mutex {
+	mutex.Lock()
+	defer mutex.Unlock()
+	l, ok := locks[collection]
+	if !ok {
+		l = &sync.Mutex{}
+		locks[collection] = l
+	}
+	return l
+}
+
+// lockFile takes the file lock of the collection, which serializes the
+// writes of all instances. The returned func releases it
+func lockFile(collection string) (func(), error) {
+	if err := os.MkdirAll(storePath(), 0700); err != nil {
+		log.Printf("Error creating store directory: %v", err)
+		return nil, errors.New(storeError)
+	}
+	file, err := os.OpenFile(filepath.Join(storePath(), filepath.Base(collection)+".lock"), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		log.Printf("Error opening the lock of collection %v: %v", collection, err)
+		return nil, errors.New(storeError)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		log.Printf("Error locking collection %v: %v", collection, err)
+		return nil, errors.New(storeError)
+	}
+	return func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
+}
+
+func storePath() string {
+	path := config.Config().GetString("store_path")
+	if path == "" {
+		return defaultStorePath
+	}
+	return path
+}
+
+// Dir is the directory of the store. Files which aren't json documents,
+// e.g. attachments, are kept in subdirectories
+func Dir() string {
+	return storePath()
+}
+
+func collectionFile(collection string) string {
+	return filepath.Join(storePath(), filepath.Base(collection)+".json")
+}
+
+// readCollection returns the documents of the collection. The map must not
+// be changed, use copyDocuments before writing
+func readCollection(collection string) (map[string]json.RawMessage, error) {
+	file := collectionFile(collection)
+	info, err := os.Stat(file)
+	if os.IsNotExist(err) {
+		return make(map[string]json.RawMessage), nil
+	}
+	if err != nil {
+		log.Printf("Error reading collection %v: %v", collection, err)
+		return nil, errors.New(storeError)
+	}
+
+	mutex.Lock()
+	cached, ok := collections[collection]
+	mutex.Unlock()
+	if ok && cached.file == file && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.documents, nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Printf("Error reading collection %v: %v", collection, err)
+		return nil, errors.New(storeError)
+	}
+	documents := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &documents); err != nil {
+		log.Printf("Error parsing collection %v: %v", collection, err)
+		return nil, errors.New(storeError)
+	}
+	cacheCollection(collection, file, info, documents)
+	return documents, nil
+}
+
+func cacheCollection(collection, file string, info os.FileInfo, documents map[string]json.RawMessage) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	collections[collection] = cachedCollection{file: file, modTime: info.ModTime(), size: info.Size(), documents: documents}
+}
+
+func copyDocuments(documents map[string]json.RawMessage) map[string]json.RawMessage {
+	documentsCopy := make(map[string]json.RawMessage, len(documents)+1)
+	for id, data := range documents {
+		documentsCopy[id] = data
+	}
+	return documentsCopy
+}
+
+func writeCollection(collection string, documents map[string]json.RawMessage) error {
+	if err := os.MkdirAll(storePath(), 0700); err != nil {
+		log.Printf("Error creating store directory: %v", err)
+		return errors.New(storeError)
+	}
+
+	data, err := json.Marshal(documents)
+	if err != nil {
+		log.Printf("Error marshalling collection %v: %v", collection, err)
+		return errors.New(storeError)
+	}
+
+	// Write to a temporary file first, so a crash can't corrupt the collection
+	tmp := collectionFile(collection) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		log.Printf("Error writing collection %v: %v", collection, err)
+		return errors.New(storeError)
+	}
+	file := collectionFile(collection)
+	if err := os.Rename(tmp, file); err != nil {
+		log.Printf("Error writing collection %v: %v", collection, err)
+		return errors.New(storeError)
+	}
+	if info, err := os.Stat(file); err == nil {
+		cacheCollection(collection, file, info, documents)
+	}
+	return nil
+}
+
+// Put creates or replaces the document with the id in the collection
+func Put(collection, id string, document interface{}) error {
+	l := lock(collection)
+	l.Lock()
+	defer l.Unlock()
+	unlock, err := lockFile(collection)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return put(collection, id, document)
+}
+
+func put(collection, id string, document interface{}) error {
+	documents, err := readCollection(collection)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		log.Printf("Error marshalling document %v/%v: %v", collection, id, err)
+		return errors.New(storeError)
+	}
+	if existing, ok := documents[id]; ok && bytes.Equal(existing, data) {
+		return nil
+	}
+	documents = copyDocuments(documents)
+	documents[id] = data
+
+	return writeCollection(collection, documents)
+}
+
+// Update reads the document with the id into target, calls fn with whether
+// it exists and writes target back if fn returns no error and changed it.
+// No instance can write the collection in between, so fn can check and
+// change the state of the document. The error of fn is returned unchanged
+func Update(collection, id string, target interface{}, fn func(exists bool) error) error {
+	l := lock(collection)
+	l.Lock()
+	defer l.Unlock()
+	unlock, err := lockFile(collection)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	exists, err := get(collection, id, target)
+	if err != nil {
+		return err
+	}
+	if err := fn(exists); err != nil {
+		return err
+	}
+	return put(collection, id, target)
+}
+
+// Get reads the document with the id into target. Returns false if it doesn't exist
+func Get(collection, id string, target interface{}) (bool, error) {
+	l := lock(collection)
+	l.Lock()
+	defer l.Unlock()
+
+	return get(collection, id, target)
+}
+
+func get(collection, id string, target interface{}) (bool, error) {
+	documents, err := readCollection(collection)
+	if err != nil {
+		return false, err
+	}
+
+	data, ok := documents[id]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		log.Printf("Error parsing document %v/%v: %v", collection, id, err)
+		return false, errors.New(storeError)
+	}
+	return true, nil
+}
+
+// Delete removes the document with the id from the collection
+func Delete(collection, id string) error {
+	l := lock(collection)
+	l.Lock()
+	defer l.Unlock()
+	unlock, err := lockFile(collection)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	documents, err := readCollection(collection)
+	if err != nil {
+		return err
+	}
+	if _, ok := documents[id]; !ok {
+		return nil
+	}
+	documents = copyDocuments(documents)
+	delete(documents, id)
+
+	return writeCollection(collection, documents)
+}
+
+// List calls fn for every document in the collection ordered by id.
+// fn gets the raw json and should unmarshal it into its own type
+func List(collection string, fn func(id string, data []byte) error) error {
+	l := lock(collection)
+	l.Lock()
+	documents, err := readCollection(collection)
+	l.Unlock()
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(documents))
+	for id := range documents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := fn(id, documents[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store/storetest"
+)
+
+type document struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestPutGetDelete(t *testing.T) {
+	defer storetest.Setup(t)()
+
+	var d document
+	if found, err := Get("test", "a", &d); err != nil || found {
+		t.Fatalf("expected no document, got %v %v", found, err)
+	}
+	if err := Put("test", "a", document{Name: "a", Count: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := Get("test", "a", &d); err != nil || !found || d.Name != "a" || d.Count != 1 {
+		t.Fatalf("expected document a, got %+v %v %v", d, found, err)
+	}
+	if err := Delete("test", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := Get("test", "a", &d); found {
+		t.Error("expected the document to be deleted")
+	}
+	if err := Delete("test", "a"); err != nil {
+		t.Errorf("expected deleting a missing document to succeed, got %v", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	defer storetest.Setup(t)()
+
+	for _, id := range []string{"c", "a", "b"} {
+		if err := Put("test", id, document{Name: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []string
+	err := List("test", func(id string, data []byte) error {
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil || len(ids) != 3 || ids[0] != "a" || ids[2] != "c" {
+		t.Errorf("expected the ids ordered, got %v %v", ids, err)
+	}
+
+	stop := errors.New("stop")
+	if err := List("test", func(id string, data []byte) error { return stop }); err != stop {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	defer storetest.Setup(t)()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var d document
+			err := Update("test", "counter", &d, func(exists bool) error {
+				d.Count++
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var d document
+	if _, err := Get("test", "counter", &d); err != nil || d.Count != 20 {
+		t.Errorf("expected every update to be kept, got %+v %v", d, err)
+	}
+
+	failed := errors.New("failed")
+	err := Update("test", "counter", &d, func(exists bool) error {
+		if !exists {
+			t.Error("expected the document to exist")
+		}
+		d.Count = 0
+		return failed
+	})
+	if err != failed {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+	if _, err := Get("test", "counter", &d); err != nil || d.Count != 20 {
+		t.Errorf("expected a failed update not to be written, got %+v %v", d, err)
+	}
+}
+
+// TestUpdateOfInstances runs the test binary as other instances of the
+// portal, which update the same document
+func TestUpdateOfInstances(t *testing.T) {
+	if path := os.Getenv("STORE_INSTANCE_PATH"); path != "" {
+		config.Init("test")
+		config.Config().Set("store_path", path)
+		for i := 0; i < 20; i++ {
+			var d document
+			Update("test", "counter", &d, func(exists bool) error {
+				d.Count++
+				return nil
+			})
+		}
+		return
+	}
+	defer storetest.Setup(t)()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestUpdateOfInstances$")
+			cmd.Env = append(os.Environ(), "STORE_INSTANCE_PATH="+storePath())
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("instance failed: %v %s", err, out)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var d document
+	if _, err := Get("test", "counter", &d); err != nil || d.Count != 60 {
+		t.Errorf("expected the updates of all instances to be kept, got %+v %v", d, err)
+	}
+}
+
+func TestReadChangedCollection(t *testing.T) {
+	defer storetest.Setup(t)()
+
+	if err := Put("test", "a", document{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance of the portal writes the file
+	later := time.Now().Add(time.Minute)
+	if err := ioutil.WriteFile(collectionFile("test"), []byte(`{"a":{"name":"changed"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(collectionFile("test"), later, later)
+
+	var d document
+	if _, err := Get("test", "a", &d); err != nil || d.Name != "changed" {
+		t.Errorf("expected the changed file to be read, got %+v %v", d, err)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	defer storetest.Setup(t)()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := Append("journal", document{Count: i}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// A line written by a crashed instance
+	file, _ := os.OpenFile(journalFile("journal"), os.O_WRONLY|os.O_APPEND, 0600)
+	file.WriteString(`{"name":"cut`)
+	file.Close()
+
+	sum := 0
+	lines := 0
+	err := Scan("journal", func(data []byte) error {
+		var d document
+		lines++
+		if err := json.Unmarshal(data, &d); err != nil {
+			return err
+		}
+		sum += d.Count
+		return nil
+	})
+	if err != nil || lines != 20 || sum != 190 {
+		t.Errorf("expected the 20 documents, got %v documents with sum %v, %v", lines, sum, err)
+	}
+	if err := Scan("missing", func(data []byte) error { return errors.New("unexpected") }); err != nil {
+		t.Errorf("expected a missing journal to be empty, got %v", err)
+	}
+}
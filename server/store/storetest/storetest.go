@@ -0,0 +1,25 @@
+// Package storetest provides an empty store for tests
+package storetest
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+// Setup initializes the test config with 'store_path' in a temporary
+// directory. The returned func removes the directory
+func Setup(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "ssp-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Init("test")
+	config.Config().Set("store_path", dir)
+
+	return func() {
+		os.RemoveAll(dir)
+	}
+}
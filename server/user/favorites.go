@@ -0,0 +1,172 @@
+package user
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	favoritesCollection = "favorites"
+	maxRecentItems      = 20
+	wrongAPIUsageError  = "Invalid api call - parameters did not match to method definition"
+)
+
+// RegisterRoutes registers the routes of the current user
+func RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/me/favorites", getFavoritesHandler)
+	r.POST("/me/favorites", addFavoriteHandler)
+	r.DELETE("/me/favorites", deleteFavoriteHandler)
+	r.POST("/me/recent", addRecentHandler)
+	r.GET("/me/sessions", getMySessionsHandler)
+	r.DELETE("/me/sessions", revokeMySessionHandler)
+	r.GET("/admin/sessions", common.RequirePortalAdmin(), getSessionsHandler)
+	r.DELETE("/admin/sessions", common.RequirePortalAdmin(), revokeSessionsHandler)
+}
+
+// Favorites is the personalized start page of a user
+type Favorites struct {
+	Projects []FavoriteProject `json:"projects"`
+	Recent   []RecentItem      `json:"recent"`
+}
+
+type FavoriteProject struct {
+	ClusterId string `json:"clusterid"`
+	Project   string `json:"project"`
+}
+
+// RecentItem is a resource the user accessed, e.g. a project or a volume
+type RecentItem struct {
+	Type       string    `json:"type"`
+	ClusterId  string    `json:"clusterid,omitempty"`
+	Name       string    `json:"name"`
+	AccessedAt time.Time `json:"accessedAt"`
+}
+
+func getFavoritesHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	favorites, err := getFavorites(username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, favorites)
+}
+
+func addFavoriteHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data FavoriteProject
+	if c.BindJSON(&data) != nil || data.ClusterId == "" || data.Project == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	favorites, err := updateFavorites(username, func(f *Favorites) {
+		for _, p := range f.Projects {
+			if p == data {
+				return
+			}
+		}
+		f.Projects = append(f.Projects, data)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, favorites)
+}
+
+func deleteFavoriteHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	data := FavoriteProject{
+		ClusterId: c.Query("clusterid"),
+		Project:   c.Query("project"),
+	}
+	if data.ClusterId == "" || data.Project == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	favorites, err := updateFavorites(username, func(f *Favorites) {
+		projects := []FavoriteProject{}
+		for _, p := range f.Projects {
+			if p != data {
+				projects = append(projects, p)
+			}
+		}
+		f.Projects = projects
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, favorites)
+}
+
+func addRecentHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data RecentItem
+	if c.BindJSON(&data) != nil || data.Type == "" || data.Name == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	data.AccessedAt = time.Now()
+
+	favorites, err := updateFavorites(username, func(f *Favorites) {
+		f.Recent = addRecentItem(f.Recent, data)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, favorites)
+}
+
+// addRecentItem moves the item to the front of the list and drops the oldest
+// items if there are more than maxRecentItems
+func addRecentItem(recent []RecentItem, item RecentItem) []RecentItem {
+	items := []RecentItem{item}
+	for _, r := range recent {
+		if r.Type == item.Type && r.ClusterId == item.ClusterId && r.Name == item.Name {
+			continue
+		}
+		items = append(items, r)
+	}
+	if len(items) > maxRecentItems {
+		items = items[:maxRecentItems]
+	}
+	return items
+}
+
+func getFavorites(username string) (*Favorites, error) {
+	favorites := &Favorites{
+		Projects: []FavoriteProject{},
+		Recent:   []RecentItem{},
+	}
+	if _, err := store.Get(favoritesCollection, strings.ToLower(username), favorites); err != nil {
+		return nil, err
+	}
+	return favorites, nil
+}
+
+func updateFavorites(username string, update func(*Favorites)) (*Favorites, error) {
+	favorites, err := getFavorites(username)
+	if err != nil {
+		return nil, err
+	}
+	update(favorites)
+
+	if err := store.Put(favoritesCollection, strings.ToLower(username), favorites); err != nil {
+		log.Printf("Error saving favorites of %v: %v", username, err)
+		return nil, err
+	}
+	return favorites, nil
+}
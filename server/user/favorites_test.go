@@ -0,0 +1,24 @@
+package user
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAddRecentItem(t *testing.T) {
+	recent := []RecentItem{}
+	for i := 0; i < maxRecentItems+5; i++ {
+		recent = addRecentItem(recent, RecentItem{Type: "project", Name: fmt.Sprintf("p%v", i)})
+	}
+	if len(recent) != maxRecentItems {
+		t.Fatalf("expected %v items, got %v", maxRecentItems, len(recent))
+	}
+
+	recent = addRecentItem(recent, RecentItem{Type: "project", Name: "p10"})
+	if recent[0].Name != "p10" {
+		t.Errorf("expected p10 to be first, got %v", recent[0].Name)
+	}
+	if len(recent) != maxRecentItems {
+		t.Errorf("expected no duplicates, got %v items", len(recent))
+	}
+}
@@ -0,0 +1,91 @@
+package user
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const sessionNotFoundError = "Die Sitzung existiert nicht oder ist nicht mehr aktiv"
+
+// getMySessionsHandler lists the active sessions of the user
+func getMySessionsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	now := time.Now()
+	sessions, err := common.GetSessions(func(s common.Session) bool {
+		return s.Username == username && s.Active(now)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// revokeMySessionHandler ends the session ?id of the user, without id the
+// current session (logout)
+func revokeMySessionHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	id := c.Query("id")
+	if id == "" {
+		id = common.GetSessionID(c)
+	}
+
+	revoked, err := common.RevokeSessions(func(s common.Session) bool {
+		return s.ID == id && s.Username == username
+	}, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if len(revoked) == 0 {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: sessionNotFoundError})
+		return
+	}
+	c.JSON(http.StatusOK, common.ApiResponse{Message: "Die Sitzung wurde beendet"})
+}
+
+// getSessionsHandler lists the active sessions of all users or of ?username
+func getSessionsHandler(c *gin.Context) {
+	user := strings.ToLower(c.Query("username"))
+	now := time.Now()
+	sessions, err := common.GetSessions(func(s common.Session) bool {
+		return (user == "" || s.Username == user) && s.Active(now)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// revokeSessionsHandler ends the session ?id or all sessions of ?username,
+// e.g. after an account was compromised
+func revokeSessionsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	id := c.Query("id")
+	user := strings.ToLower(c.Query("username"))
+	if id == "" && user == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	revoked, err := common.RevokeSessions(func(s common.Session) bool {
+		return (id == "" || s.ID == id) && (user == "" || s.Username == user)
+	}, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if id != "" && len(revoked) == 0 {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: sessionNotFoundError})
+		return
+	}
+	audit.Describe(c, "%v revoked %v sessions (id '%v', user '%v')", username, len(revoked), id, user)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: fmt.Sprintf("%v Sitzungen wurden beendet", len(revoked))})
+}
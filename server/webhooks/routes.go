@@ -0,0 +1,186 @@
+package webhooks
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/audit"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+const wrongAPIUsageError = "Ungültiger API-Aufruf: Die Argumente stimmen nicht mit der definition überein. Bitte erstelle eine Ticket"
+
+// SubscriptionCommand creates or updates a subscription. An empty secret
+// keeps the current secret on updates
+type SubscriptionCommand struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"eventTypes"`
+	ClusterId  string   `json:"clusterid"`
+	Project    string   `json:"project"`
+}
+
+func RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/webhooks", getSubscriptionsHandler)
+	r.POST("/webhooks", newSubscriptionHandler)
+	r.PUT("/webhooks/:id", updateSubscriptionHandler)
+	r.DELETE("/webhooks/:id", deleteSubscriptionHandler)
+	r.GET("/webhooks/:id/deliveries", getDeliveriesHandler)
+	r.POST("/webhooks/:id/deliveries/:delivery/redeliver", redeliverHandler)
+}
+
+// ownSubscription returns the subscription if the user is its owner or a
+// portal admin
+func ownSubscription(c *gin.Context) (*storedSubscription, bool) {
+	username := common.GetUserName(c)
+	s, found, err := getSubscription(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return nil, false
+	}
+	if !found || (s.Owner != username && !common.IsPortalAdmin(username)) {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: fmt.Sprintf("Der Webhook %v existiert nicht", c.Param("id"))})
+		return nil, false
+	}
+	return s, true
+}
+
+// getSubscriptionsHandler lists the own subscriptions. Portal admins see all
+func getSubscriptionsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	admin := common.IsPortalAdmin(username)
+
+	stored, err := listSubscriptions(func(s Subscription) bool { return admin || s.Owner == username })
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	subscriptions := []Subscription{}
+	for _, s := range stored {
+		subscriptions = append(subscriptions, s.Subscription)
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+func newSubscriptionHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data SubscriptionCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	s := &storedSubscription{
+		Subscription: Subscription{
+			ID:         id.String(),
+			URL:        data.URL,
+			EventTypes: data.EventTypes,
+			ClusterId:  data.ClusterId,
+			Project:    data.Project,
+			Owner:      username,
+			CreatedAt:  common.Now(),
+		},
+		Secret: data.Secret,
+	}
+	if err := validateSubscription(s.Subscription, username); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err := saveSubscription(s); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v subscribed %v to %v of project %v", username, s.URL, s.EventTypes, s.Project)
+	c.JSON(http.StatusOK, s.Subscription)
+}
+
+func updateSubscriptionHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data SubscriptionCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	s, ok := ownSubscription(c)
+	if !ok {
+		return
+	}
+	s.URL, s.EventTypes, s.ClusterId, s.Project = data.URL, data.EventTypes, data.ClusterId, data.Project
+	if data.Secret != "" {
+		s.Secret = data.Secret
+	}
+	if err := validateSubscription(s.Subscription, username); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err := saveSubscription(s); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	audit.Describe(c, "%v updated the webhook %v", username, s.ID)
+	c.JSON(http.StatusOK, s.Subscription)
+}
+
+func deleteSubscriptionHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	s, ok := ownSubscription(c)
+	if !ok {
+		return
+	}
+	if err := store.Delete(subscriptionsCollection, s.ID); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err := deleteDeliveries(s.ID); err != nil {
+		common.Logger(c).Printf("Error deleting the deliveries of webhook %v: %v", s.ID, err)
+	}
+
+	audit.Describe(c, "%v deleted the webhook %v", username, s.ID)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: "Der Webhook wurde gelöscht"})
+}
+
+func getDeliveriesHandler(c *gin.Context) {
+	s, ok := ownSubscription(c)
+	if !ok {
+		return
+	}
+	deliveries, err := getDeliveries(s.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// redeliverHandler sends the payload of a delivery again and returns the
+// new delivery
+func redeliverHandler(c *gin.Context) {
+	s, ok := ownSubscription(c)
+	if !ok {
+		return
+	}
+	var previous Delivery
+	found, err := store.Get(deliveriesCollection, c.Param("delivery"), &previous)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if !found || previous.SubscriptionID != s.ID {
+		c.JSON(http.StatusNotFound, common.ApiResponse{Message: fmt.Sprintf("Die Zustellung %v existiert nicht", c.Param("delivery"))})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliver(*s, previous.EventType, previous.Payload, true))
+}
@@ -0,0 +1,309 @@
+// Package webhooks lets teams subscribe their own urls to the events of the
+// portal, e.g. the creation of a project. Every delivery is logged and can
+// be redelivered. Subscriptions are limited to projects the owner is
+// allowed to manage, only portal admins can subscribe to all projects.
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store"
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	EventProjectCreated = "project.created"
+	EventProjectDeleted = "project.deleted"
+	EventQuotaChanged   = "quota.changed"
+	EventApproval       = "approval"
+
+	subscriptionsCollection = "webhook_subscriptions"
+	deliveriesCollection    = "webhook_deliveries"
+	// maxDeliveries are kept per subscription
+	maxDeliveries   = 100
+	deliveryTimeout = 10 * time.Second
+	maxResponseBody = 1024
+)
+
+// EventTypes can be subscribed to. The approval events are the states of
+// the approval requests, e.g. approval.pending
+var EventTypes = []string{EventProjectCreated, EventProjectDeleted, EventQuotaChanged, EventApproval}
+
+// Subscription posts the events of EventTypes to URL. Without Project the
+// events of all projects are sent
+type Subscription struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	HasSecret  bool      `json:"hasSecret"`
+	EventTypes []string  `json:"eventTypes"`
+	ClusterId  string    `json:"clusterid,omitempty"`
+	Project    string    `json:"project,omitempty"`
+	Owner      string    `json:"owner"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// storedSubscription keeps the secret which is never returned by the api
+type storedSubscription struct {
+	Subscription
+	Secret string `json:"secret"`
+}
+
+// Event is sent as json body
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	ClusterId string      `json:"clusterid,omitempty"`
+	Project   string      `json:"project,omitempty"`
+	Time      time.Time   `json:"time"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// Delivery is an attempt to send an event to a subscription
+type Delivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscriptionId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	At             time.Time       `json:"at"`
+	DurationMs     int64           `json:"durationMs"`
+	Status         int             `json:"status,omitempty"`
+	Response       string          `json:"response,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	Redelivery     bool            `json:"redelivery,omitempty"`
+}
+
+// Succeeded is true if the url answered with 2xx
+func (d Delivery) Succeeded() bool {
+	return d.Error == "" && d.Status >= 200 && d.Status < 300
+}
+
+// CanManageProject checks if the user may subscribe to the events of the
+// project. It's set by the openshift package
+var CanManageProject = func(clusterId, username, project string) error {
+	return errors.New("Webhooks für Projekte sind nicht verfügbar")
+}
+
+func (s Subscription) matches(e Event) bool {
+	if s.Project != "" && (s.ClusterId != e.ClusterId || s.Project != e.Project) {
+		return false
+	}
+	for _, t := range s.EventTypes {
+		if t == e.Type || strings.HasPrefix(e.Type, t+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func validateSubscription(s Subscription, username string) error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New("Die URL muss mit http:// oder https:// beginnen")
+	}
+	if len(s.EventTypes) == 0 {
+		return fmt.Errorf("Es muss mindestens ein Event angegeben werden: %v", strings.Join(EventTypes, ", "))
+	}
+	for _, t := range s.EventTypes {
+		if !contains(EventTypes, t) {
+			return fmt.Errorf("Ungültiges Event %v. Erlaubt sind: %v", t, strings.Join(EventTypes, ", "))
+		}
+	}
+	if s.Project == "" {
+		if !common.IsPortalAdmin(username) {
+			return errors.New("Es muss ein Projekt angegeben werden")
+		}
+		return nil
+	}
+	return CanManageProject(s.ClusterId, username, s.Project)
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func getSubscription(id string) (*storedSubscription, bool, error) {
+	var s storedSubscription
+	found, err := store.Get(subscriptionsCollection, id, &s)
+	return &s, found, err
+}
+
+func saveSubscription(s *storedSubscription) error {
+	s.HasSecret = s.Secret != ""
+	return store.Put(subscriptionsCollection, s.ID, s)
+}
+
+func listSubscriptions(filter func(Subscription) bool) ([]storedSubscription, error) {
+	subscriptions := []storedSubscription{}
+	err := store.List(subscriptionsCollection, func(id string, data []byte) error {
+		var s storedSubscription
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if filter(s.Subscription) {
+			subscriptions = append(subscriptions, s)
+		}
+		return nil
+	})
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+	return subscriptions, err
+}
+
+// Publish sends the event to all matching subscriptions in the background
+func Publish(e Event) {
+	if e.ID == "" {
+		id, _ := uuid.NewV4()
+		e.ID = id.String()
+	}
+	if e.Time.IsZero() {
+		e.Time = common.Now()
+	}
+	subscriptions, err := listSubscriptions(func(s Subscription) bool { return s.matches(e) })
+	if err != nil {
+		log.Printf("Error reading the webhook subscriptions: %v", err)
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("Error marshalling webhook event %v: %v", e.Type, err)
+		return
+	}
+	for _, s := range subscriptions {
+		go deliver(s, e.Type, payload, false)
+	}
+}
+
+// sign returns the hmac of the payload with the secret of the subscription
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver posts the payload and logs the delivery
+func deliver(s storedSubscription, eventType string, payload []byte, redelivery bool) Delivery {
+	id, _ := uuid.NewV4()
+	delivery := Delivery{
+		ID:             id.String(),
+		SubscriptionID: s.ID,
+		EventType:      eventType,
+		Payload:        payload,
+		At:             common.Now(),
+		Redelivery:     redelivery,
+	}
+
+	client := common.HTTPClient("webhook")
+	client.Timeout = deliveryTimeout
+	start := time.Now()
+	err := func() error {
+		req, err := http.NewRequest("POST", s.URL, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-SSP-Event", eventType)
+		req.Header.Set("X-SSP-Delivery", delivery.ID)
+		if s.Secret != "" {
+			req.Header.Set("X-SSP-Signature", sign(s.Secret, payload))
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+		delivery.Status = resp.StatusCode
+		delivery.Response = string(body)
+		return nil
+	}()
+	delivery.DurationMs = time.Since(start).Nanoseconds() / int64(time.Millisecond)
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	if !delivery.Succeeded() {
+		log.Printf("Webhook %v (%v) of %v failed: %v %v", s.ID, eventType, s.Owner, delivery.Status, delivery.Error)
+	}
+	if err := saveDelivery(delivery); err != nil {
+		log.Printf("Error saving the delivery of webhook %v: %v", s.ID, err)
+	}
+	return delivery
+}
+
+func saveDelivery(d Delivery) error {
+	if err := store.Put(deliveriesCollection, d.ID, d); err != nil {
+		return err
+	}
+	deliveries, err := getDeliveries(d.SubscriptionID)
+	if err != nil {
+		return err
+	}
+	for _, old := range deliveries[min(len(deliveries), maxDeliveries):] {
+		if err := store.Delete(deliveriesCollection, old.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// getDeliveries returns the deliveries of the subscription, the newest first
+func getDeliveries(subscriptionID string) ([]Delivery, error) {
+	deliveries := []Delivery{}
+	err := store.List(deliveriesCollection, func(id string, data []byte) error {
+		var d Delivery
+		if err := json.Unmarshal(data, &d); err != nil {
+			return err
+		}
+		if d.SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, d)
+		}
+		return nil
+	})
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].At.After(deliveries[j].At)
+	})
+	return deliveries, err
+}
+
+func deleteDeliveries(subscriptionID string) error {
+	deliveries, err := getDeliveries(subscriptionID)
+	if err != nil {
+		return err
+	}
+	for _, d := range deliveries {
+		if err := store.Delete(deliveriesCollection, d.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/store/storetest"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSubscriptions(t *testing.T) {
+	defer storetest.Setup(t)()
+	CanManageProject = func(clusterId, username, project string) error {
+		if project != "own" {
+			return errors.New("not admin")
+		}
+		return nil
+	}
+
+	var mutex sync.Mutex
+	received := []*http.Request{}
+	status := http.StatusInternalServerError
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		received = append(received, r)
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(gin.AuthUserKey, c.GetHeader("X-User")) })
+	RegisterRoutes(router.Group("/api"))
+	request := func(method, path, user, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("X-User", user)
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	invalid := []string{
+		`{"url": "ftp://hooks", "eventTypes": ["project.created"], "clusterid": "fake", "project": "own"}`,
+		`{"url": "URL", "eventTypes": [], "clusterid": "fake", "project": "own"}`,
+		`{"url": "URL", "eventTypes": ["project.renamed"], "clusterid": "fake", "project": "own"}`,
+		`{"url": "URL", "eventTypes": ["project.created"], "clusterid": "fake", "project": "other"}`,
+		`{"url": "URL", "eventTypes": ["project.created"]}`,
+	}
+	for _, body := range invalid {
+		if w := request("POST", "/api/webhooks", "u123", strings.Replace(body, "URL", receiver.URL, 1)); w.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %v", body, w.Code)
+		}
+	}
+
+	w := request("POST", "/api/webhooks", "u123", `{"url": "`+receiver.URL+`", "secret": "s3cr3t", "eventTypes": ["project.created", "approval"], "clusterid": "fake", "project": "own"}`)
+	var subscription Subscription
+	if err := json.Unmarshal(w.Body.Bytes(), &subscription); err != nil || w.Code != http.StatusOK || !subscription.HasSecret {
+		t.Fatalf("expected the subscription, got %v %v", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "s3cr3t") {
+		t.Error("expected the secret not to be returned")
+	}
+
+	stored, _, _ := getSubscription(subscription.ID)
+	if !stored.matches(Event{Type: "approval.pending", ClusterId: "fake", Project: "own"}) ||
+		stored.matches(Event{Type: EventProjectDeleted, ClusterId: "fake", Project: "own"}) ||
+		stored.matches(Event{Type: EventProjectCreated, ClusterId: "fake", Project: "other"}) {
+		t.Error("unexpected matching of events")
+	}
+
+	delivery := deliver(*stored, EventProjectCreated, []byte(`{"type": "project.created"}`), false)
+	if delivery.Succeeded() || delivery.Status != http.StatusInternalServerError {
+		t.Errorf("expected the delivery to fail, got %+v", delivery)
+	}
+	if signature := received[0].Header.Get("X-SSP-Signature"); signature != sign("s3cr3t", []byte(`{"type": "project.created"}`)) {
+		t.Errorf("expected the payload to be signed, got %v", signature)
+	}
+
+	if w := request("GET", "/api/webhooks/"+subscription.ID+"/deliveries", "u456", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected other users not to see the deliveries, got %v", w.Code)
+	}
+	status = http.StatusOK
+	w = request("POST", "/api/webhooks/"+subscription.ID+"/deliveries/"+delivery.ID+"/redeliver", "u123", "")
+	var redelivery Delivery
+	if json.Unmarshal(w.Body.Bytes(), &redelivery); !redelivery.Succeeded() || !redelivery.Redelivery {
+		t.Errorf("expected the redelivery to succeed, got %v", w.Body.String())
+	}
+	if deliveries, _ := getDeliveries(subscription.ID); len(deliveries) != 2 || deliveries[0].ID != redelivery.ID {
+		t.Errorf("expected 2 deliveries, the newest first, got %+v", deliveries)
+	}
+
+	if w := request("DELETE", "/api/webhooks/"+subscription.ID, "u123", ""); w.Code != http.StatusOK {
+		t.Errorf("expected the subscription to be deleted, got %v", w.Code)
+	}
+	if deliveries, _ := getDeliveries(subscription.ID); len(deliveries) != 0 {
+		t.Errorf("expected the deliveries to be deleted, got %+v", deliveries)
+	}
+}